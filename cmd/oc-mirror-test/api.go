@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/telco-core/ngc-495/pkg/api"
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// newAPICommand creates the api subcommand, which serves the control API
+// remote orchestrators start, follow and cancel runs on this host with
+func newAPICommand() *cobra.Command {
+	opts := &serveOptions{defaultPort: 8090}
+	var workDir, runsDir string
+	var agent api.AgentConfig
+
+	cmd := &cobra.Command{
+		Use:   "api",
+		Short: "Serve a REST API to start, follow and cancel runs remotely",
+		Long: "Serves a REST control API so an orchestrator such as Jenkins can drive tests on this host without SSH: " +
+			"POST /api/v1/runs starts a run from a scenario, GET /api/v1/runs/<id> reports its status and progress, " +
+			"GET /api/v1/runs/<id>/logs?follow=true streams its output and POST /api/v1/runs/<id>/cancel stops it. " +
+			"One run executes at a time. With --coordinator, the server also registers as an agent of a coordinator.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			auth, err := opts.authConfig(cmd)
+			if err != nil {
+				return err
+			}
+			executable, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("failed to locate the oc-mirror-test binary: %w", err)
+			}
+			if workDir == "" {
+				if workDir, err = os.Getwd(); err != nil {
+					return err
+				}
+			}
+
+			server := api.NewServer(opts.port, executable, workDir, runsDir)
+			server.SetBindAddress(opts.bindAddress)
+			server.SetTLS(opts.tls)
+			server.SetAuth(auth)
+			if agent.Enabled() {
+				if agent.AdvertiseURL == "" {
+					agent.AdvertiseURL = opts.dashboardURL()
+				}
+				agent.Token = auth.Token
+				go api.RunAgent(context.Background(), agent)
+			}
+			return server.Start()
+		},
+	}
+
+	opts.addFlags(cmd)
+	cmd.Flags().StringVar(&workDir, "work-dir", "", "Directory runs execute in, holding the oc-mirror workspaces and results (default: current directory)")
+	cmd.Flags().StringVar(&runsDir, "runs-dir", api.DefaultRunsDir, "Directory keeping the scenario, log and heartbeat of each run, relative to --work-dir")
+	cmd.Flags().StringVar(&agent.CoordinatorURL, "coordinator", "", "Register as an agent with the coordinator at this URL, which dispatches runs to all its agents at once")
+	cmd.Flags().StringVar(&agent.Name, "agent-name", "", "Agent name reported to the coordinator (default: hostname)")
+	cmd.Flags().StringVar(&agent.AdvertiseURL, "advertise-url", "", "URL the coordinator reaches this API at (default: scheme, hostname and --port)")
+	cmd.Flags().BoolVar(&agent.SkipTLS, "coordinator-skip-tls", false, "Skip TLS verification for the coordinator")
+	return cmd
+}
+
+// newCoordinatorCommand creates the coordinator subcommand, which dispatches
+// runs to the registered agents and aggregates their results
+func newCoordinatorCommand() *cobra.Command {
+	opts := &serveOptions{defaultPort: 8091}
+	var resultsDir string
+	var agentTimeout time.Duration
+	var agentSkipTLS bool
+
+	cmd := &cobra.Command{
+		Use:   "coordinator",
+		Short: "Dispatch a scenario to many agents at once and aggregate their results",
+		Long: "Serves the coordinator of a distributed test. Agents (api --coordinator <url>) register with it; " +
+			"POST /api/v1/dispatches starts the same scenario on all alive agents concurrently, simulating many mirror clients pushing to one registry. " +
+			"When every run ended, the per-agent and registry-wide metrics are written to one report in --results-dir.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			auth, err := opts.authConfig(cmd)
+			if err != nil {
+				return err
+			}
+			coordinator := api.NewCoordinator(opts.port, resultsDir)
+			coordinator.SetBindAddress(opts.bindAddress)
+			coordinator.SetTLS(opts.tls)
+			coordinator.SetAuth(auth)
+			coordinator.SetAgentTimeout(agentTimeout)
+			coordinator.SetAgentSkipTLS(agentSkipTLS)
+			return coordinator.Start()
+		},
+	}
+
+	opts.addFlags(cmd)
+	cmd.Flags().StringVar(&resultsDir, "results-dir", runner.DefaultResultsDir, "Directory distributed reports are written to")
+	cmd.Flags().DurationVar(&agentTimeout, "agent-timeout", api.DefaultAgentTimeout, "Time after its last registration an agent is considered gone")
+	cmd.Flags().BoolVar(&agentSkipTLS, "agent-skip-tls", false, "Skip TLS verification for the agents")
+	return cmd
+}
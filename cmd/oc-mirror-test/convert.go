@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/telco-core/ngc-495/internal/config"
+)
+
+// newConvertOperatorsCommand creates the convert-operators subcommand
+func newConvertOperatorsCommand() *cobra.Command {
+	var output string
+	var format string
+	var apiVersion string
+	var catalogImage string
+	var ocpVersion string
+
+	cmd := &cobra.Command{
+		Use:   "convert-operators <list-file|->",
+		Short: "Convert a CSV or plain operator list into an ImageSetConfiguration",
+		Long: `Converts a flat operator list into an ImageSetConfiguration or a content file for --content-file and scenarios.
+
+Each line holds package, channel, minVersion and maxVersion separated by commas or whitespace; only the package is required. An optional header line names the columns, and a "catalog" column overrides the catalog per line. Lines starting with # are ignored.
+
+  package,channel,minVersion,maxVersion
+  odf-operator,stable-4.19,4.19.6-rhodf,4.19.6-rhodf
+  local-storage-operator,stable`,
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "imageset" && format != "content" {
+				return fmt.Errorf("unknown format %q (valid: imageset, content)", format)
+			}
+			if catalogImage == "" {
+				catalogImage = config.RedHatOperatorCatalog(ocpVersion)
+			}
+
+			var input io.Reader = os.Stdin
+			if args[0] != "-" {
+				file, err := os.Open(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to open operator list: %w", err)
+				}
+				defer file.Close()
+				input = file
+			}
+
+			content, err := config.ParseOperatorList(input, catalogImage)
+			if err != nil {
+				return fmt.Errorf("invalid operator list %s: %w", args[0], err)
+			}
+
+			var data []byte
+			if format == "imageset" {
+				data = []byte(config.RenderImageSetConfig(apiVersion, content))
+			} else {
+				var buf bytes.Buffer
+				encoder := yaml.NewEncoder(&buf)
+				encoder.SetIndent(2)
+				if err := encoder.Encode(content); err != nil {
+					return fmt.Errorf("failed to encode content: %w", err)
+				}
+				encoder.Close()
+				data = buf.Bytes()
+			}
+
+			if output == "-" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+			if err := os.WriteFile(output, data, 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", output, err)
+			}
+
+			packages := 0
+			for _, catalog := range content.Catalogs {
+				packages += len(catalog.Packages)
+			}
+			fmt.Printf("✅ Wrote %s (%d package(s) from %d catalog(s))\n", output, packages, len(content.Catalogs))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "imageset-config.yaml", "Output file (- for stdout)")
+	cmd.Flags().StringVar(&format, "format", "imageset", "Output format: imageset (ImageSetConfiguration) or content (--content-file / scenario content)")
+	cmd.Flags().StringVar(&apiVersion, "api-version", "v2alpha1", "ImageSetConfiguration API version (v1alpha2 for oc-mirror v1)")
+	cmd.Flags().StringVar(&catalogImage, "catalog", "", "Operator catalog for lines without a catalog column (default: Red Hat operator index for --ocp-version)")
+	cmd.Flags().StringVar(&ocpVersion, "ocp-version", "4.19", "OpenShift version used for the default catalog")
+
+	return cmd
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// newResumeCommand creates the resume subcommand
+func newResumeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resume <state-file>",
+		Short: "Continue a run that stopped before it finished",
+		Long: `Continues the run of a state file written next to its results file (results_<...>.state).
+
+The run is recreated from the flags it was started with, in the directory it was started from. Iterations already in the results file are skipped; the remaining ones run with the workspaces and cache the run left and are added to the same results file. Earlier runs are not pruned. A staged iteration restarts from its first stage.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := resumeRun(args[0]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+// resumeRun parses the run flags of the state file and runs them with the state
+func resumeRun(path string) error {
+	state, err := runner.LoadRunState(path)
+	if err != nil {
+		return err
+	}
+	if state.Finished {
+		return fmt.Errorf("the run of %s already finished", state.ResultsFile)
+	}
+	if err := os.Chdir(state.WorkingDir); err != nil {
+		return fmt.Errorf("failed to change to the directory of the run: %w", err)
+	}
+
+	opts := &runOptions{runArgs: state.Args, resume: state}
+	runCmd := &cobra.Command{Use: "run"}
+	opts.addFlags(runCmd)
+	if err := runCmd.ParseFlags(state.Args); err != nil {
+		return fmt.Errorf("failed to parse the flags of the run: %w", err)
+	}
+	return opts.execute(runCmd)
+}
@@ -0,0 +1,1229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/telco-core/ngc-495/internal/config"
+	"github.com/telco-core/ngc-495/pkg/client"
+	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/events"
+	"github.com/telco-core/ngc-495/pkg/integrity"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+	"github.com/telco-core/ngc-495/pkg/netshape"
+	"github.com/telco-core/ngc-495/pkg/notify"
+	"github.com/telco-core/ngc-495/pkg/pacing"
+	"github.com/telco-core/ngc-495/pkg/plugin"
+	"github.com/telco-core/ngc-495/pkg/proxy"
+	"github.com/telco-core/ngc-495/pkg/registry"
+	"github.com/telco-core/ngc-495/pkg/runner"
+	"github.com/telco-core/ngc-495/pkg/scenario"
+	"github.com/telco-core/ngc-495/pkg/sink"
+	"github.com/telco-core/ngc-495/pkg/store"
+	"github.com/telco-core/ngc-495/pkg/ticket"
+	"github.com/telco-core/ngc-495/pkg/tracing"
+	"github.com/telco-core/ngc-495/pkg/webui"
+)
+
+// runOptions holds the flags shared by the root command and the run subcommand
+type runOptions struct {
+	scenarioFile        string
+	registryURL         string
+	compareRegistries   []string
+	registryOrder       string
+	iterations          int
+	warmup              int
+	compareV1V2         bool
+	skipTLS             bool
+	shaping             netshape.Config
+	pacing              pacing.Config
+	notify              notify.Config
+	tracing             tracing.Config
+	otlpHeaders         []string
+	delete              runner.DeleteConfig
+	memoryCeiling       monitor.MemoryCeilingConfig
+	registryMetrics     monitor.RegistryServerConfig
+	adaptivePolling     monitor.AdaptivePolling
+	ticket              ticket.Config
+	signatures          runner.SignatureConfig
+	store               runner.StoreConfig
+	kubeconfig          string
+	gates               runner.GateConfig
+	gateMaxErrors       int
+	clusterValidation   runner.ClusterValidationConfig
+	pullThrough         runner.PullThroughConfig
+	airGap              runner.AirGapConfig
+	replication         runner.ReplicationConfig
+	noTUI               bool
+	eventsFile          string
+	sinks               []string
+	heartbeatFile       string
+	heartbeatURL        string
+	heartbeatInterval   time.Duration
+	registryStoragePath string
+	localRegistry       registry.LocalRegistryConfig
+	workspaceDir        string
+	cacheDir            string
+	cleanup             runner.CleanupPolicy
+	diskSpace           runner.DiskSpaceCheck
+	registryAccessLog   string
+	uploadDebugLog      bool
+	accountingProxy     bool
+	rateLimit           proxy.RateLimit
+	catalogDiff         bool
+	validateContent     bool
+	verifyUpload        bool
+	packageSizes        bool
+	chaos               runner.ChaosConfig
+	proxy               runner.ProxyConfig
+	matrix              runner.MatrixConfig
+	comparePinning      bool
+	logRetention        runner.LogRetention
+	networkAccounting   string
+	resourceScope       string
+	sampleStorage       string
+	retryFailed         int
+	retryBackoff        time.Duration
+	downloadTimeout     time.Duration
+	uploadTimeout       time.Duration
+	workspaceCleanup    []string
+	repeatClean         bool
+	contentScenario     string
+	contentFile         string
+	updateContentFile   string
+	stages              []string
+	monitorPlugins      []string
+	sinkPlugins         []string
+	additionalImages    []string
+	helmCharts          []string
+	signingKeyFile      string
+	resultsDir          string
+	binDir              string
+	toolsVersion        string
+	toolsFromDir        string
+	runName             string
+	tags                []string
+	notes               []string
+	withUI              bool
+	ui                  serveOptions
+
+	// Flags of the command line the run is recreated from, and the state of
+	// the interrupted run when it runs through resume
+	runArgs []string
+	resume  *runner.RunState
+}
+
+// addFlags registers the test run flags on a command
+func (o *runOptions) addFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.BoolVar(&o.withUI, "with-ui", false, "Serve the web UI with live metrics while the run executes, shutting it down when the run ends")
+	o.ui.prefix = "ui-"
+	o.ui.addFlags(cmd)
+	flags.StringVar(&o.scenarioFile, "scenario", "", "Scenario YAML file describing registry, iterations, workflow, content, flags and thresholds")
+	flags.StringVarP(&o.registryURL, "registry", "r", "", "Registry URL (e.g., docker://infra.5g-deployment.lab:8443/ocp/) or OCI layout directory (oci:///path, v2 only)")
+	flags.IntVarP(&o.iterations, "iterations", "i", 2, "Number of iterations to run (minimum 2 for clean vs cached comparison)")
+	flags.IntVar(&o.warmup, "warmup", 0, "Warm-up iterations run before the measured ones of each version, recorded with warmup=true and left out of the comparisons and statistics")
+	flags.StringArrayVar(&o.compareRegistries, "compare-registry", nil, "Also push the same content to this registry and compare upload performance (repeatable)")
+	flags.StringVar(&o.registryOrder, "registry-order", runner.RegistryOrderSequential, "Registry comparison order: sequential (all iterations per registry) or round-robin (every registry each iteration)")
+	flags.BoolVar(&o.compareV1V2, "compare-v1-v2", false, "Compare v1 and v2 runs of the same imageset configuration")
+	flags.BoolVar(&o.skipTLS, "skip-tls", false, "Skip TLS verification for destination registry (--dest-tls-verify=false)")
+	flags.IntVar(&o.retryFailed, "retry-failed", 0, "Retry a failed download or upload phase up to N times with backoff; iterations that still fail are recorded and the run continues")
+	flags.DurationVar(&o.retryBackoff, "retry-backoff", 30*time.Second, "Wait before the first phase retry, doubled for each further retry (max 5m)")
+	flags.DurationVar(&o.downloadTimeout, "download-timeout", 0, "Kill oc-mirror when a download phase runs longer (0 for no limit); the phase fails with its metrics recorded")
+	flags.DurationVar(&o.uploadTimeout, "upload-timeout", 0, "Kill oc-mirror when an upload phase runs longer (0 for no limit); the phase fails with its metrics recorded")
+	flags.StringSliceVar(&o.workspaceCleanup, "workspace-cleanup", nil, "Cleanup strategy before each iteration, the last repeating: keep, mirror (workspace), cache (workspace and v2 cache) or registry (also the content mirrored to the target); default mirror for the first iteration, keep for the others")
+	flags.BoolVar(&o.repeatClean, "repeat-clean", false, "Run every iteration clean, from an empty workspace and v2 cache, and summarize the spread of the clean runs")
+	flags.StringVar(&o.networkAccounting, "network-accounting", runner.NetworkAccountingInterface, "Network traffic attribution: interface (all host traffic) or process (oc-mirror TCP sockets only)")
+	flags.StringVar(&o.logRetention.Mode, "log-retention", command.LogRetentionFile, "oc-mirror output lines each phase keeps in the results besides the log file: file (none), head-tail (first and last lines) or errors (error and warning lines)")
+	flags.IntVar(&o.logRetention.Lines, "log-retention-lines", command.DefaultLogRetentionLines, "Lines kept at each end with --log-retention head-tail, or error and warning lines kept with errors")
+	flags.StringVar(&o.resourceScope, "resource-scope", monitor.ResourceScopeProcess, "Processes the oc-mirror CPU and memory cover: process (oc-mirror only), tree (oc-mirror and its children, from /proc) or cgroup (a transient cgroup v2 oc-mirror is moved into)")
+	flags.StringVar(&o.sampleStorage, "sample-storage", runner.SampleStorageInline, "Where monitor samples are stored: inline (in the results file), delta or delta-gzip (delta-encoded sidecar next to the results file, for long runs)")
+	flags.StringVar(&o.workspaceDir, "workspace-dir", runner.DefaultWorkspaceDir, "Directory holding the oc-mirror workspaces (operators-v1, operators-v2); wiped by clean runs")
+	flags.StringVar(&o.cacheDir, "cache-dir", runner.DefaultCacheDir, "oc-mirror v2 cache directory, kept across iterations; a warning is printed when it is on NFS or other network storage")
+	flags.IntVar(&o.cleanup.KeepLast, "keep-last", 0, "Before the run, remove all but the newest N results files with their sidecars and logs (0 keeps all)")
+	flags.StringVar(&o.cleanup.MaxResultsSize, "max-results-size", "", "Before the run, remove the oldest results files until the rest fit in this size (e.g. 20Gi)")
+	flags.BoolVar(&o.cleanup.Workspaces, "prune-workspaces", false, "Before the run, remove the oc-mirror workspaces left by earlier runs")
+	flags.BoolVar(&o.cleanup.Cache, "prune-cache", false, "Before the run, remove the oc-mirror v2 cache so the clean iteration starts cold")
+	flags.StringVar(&o.diskSpace.Estimate, "disk-estimate", "", "Peak disk footprint of workspace, cache and OCI layout checked against the free space before the run, e.g. 120Gi (default: from earlier results of the same scenario or content)")
+	flags.BoolVar(&o.diskSpace.Skip, "skip-disk-check", false, "Do not check the free disk space before the run")
+	flags.StringVar(&o.registryStoragePath, "registry-storage-path", "", "Local path backing the registry storage, recorded in the environment snapshot and measured around the delete phase")
+	flags.BoolVar(&o.localRegistry.Enabled, "local-registry", false, "Start a disposable registry container on localhost as the target instead of --registry, removed when the run ends")
+	flags.StringVar(&o.localRegistry.Runtime, "local-registry-runtime", "", "Container runtime of the local registry: podman or docker (default: the first installed)")
+	flags.StringVar(&o.localRegistry.Image, "local-registry-image", registry.DefaultLocalRegistryImage, "Image of the local registry, compatible with the distribution registry config")
+	flags.IntVar(&o.localRegistry.Port, "local-registry-port", 0, "Host port of the local registry (0 picks a free one)")
+	flags.StringVar(&o.localRegistry.Storage, "local-registry-storage", registry.LocalStorageFilesystem, "Storage backend of the local registry: filesystem or inmemory")
+	flags.StringVar(&o.localRegistry.StoragePath, "local-registry-storage-path", "", "Host directory of the filesystem storage, kept after the run (default: a temporary directory removed with the registry)")
+	flags.StringVar(&o.registryAccessLog, "registry-access-log", "", "Registry or reverse proxy access log (combined log format or distribution registry log) read for the response status codes of each upload")
+	flags.BoolVar(&o.uploadDebugLog, "upload-debug-log", false, "Run oc-mirror uploads with --log-level debug so the response status code distribution covers every registry request")
+	flags.BoolVar(&o.accountingProxy, "accounting-proxy", false, "Push uploads through a local reverse proxy counting the exact bytes, API calls and status codes per repository")
+	flags.Float64Var(&o.rateLimit.ThrottleRate, "rate-limit-429", 0, "Answer this fraction (0-1) of the upload requests with 429 in the accounting proxy, which it enables")
+	flags.Float64Var(&o.rateLimit.RequestsPerSecond, "rate-limit-rps", 0, "Answer upload requests above this many per second with 429 in the accounting proxy, which it enables")
+	flags.DurationVar(&o.rateLimit.RetryAfter, "rate-limit-retry-after", 0, "Retry-After sent with the 429s of --rate-limit-429 and --rate-limit-rps (default: none)")
+	flags.StringVar(&o.rateLimit.Bandwidth, "rate-limit-bandwidth", "", "Cap each connection to the accounting proxy to this many bytes per second, e.g. 10Mi, which enables the proxy")
+	flags.StringVar(&o.registryMetrics.URL, "registry-metrics-url", "", "Scrape the registry's Prometheus endpoint (distribution, Harbor or Quay) during uploads, e.g. http://registry:5001/metrics; bearer token from "+monitor.EnvRegistryMetricsToken)
+	flags.DurationVar(&o.registryMetrics.Interval, "registry-metrics-interval", monitor.DefaultRegistryScrapeInterval, "Interval between registry metrics scrapes")
+	flags.DurationVar(&o.adaptivePolling.After, "adaptive-poll-after", 0, "Back the poll interval of the phase monitors off once a phase runs longer than this, e.g. 30m (0 disables)")
+	flags.Float64Var(&o.adaptivePolling.Factor, "adaptive-poll-factor", monitor.DefaultAdaptiveFactor, "Poll interval multiplier each time the phase length doubles past --adaptive-poll-after")
+	flags.DurationVar(&o.adaptivePolling.MaxInterval, "adaptive-poll-max", monitor.DefaultAdaptiveMaxInterval, "Longest poll interval adaptive polling backs off to")
+	flags.BoolVar(&o.catalogDiff, "catalog-diff", false, "List the registry catalog (/v2/_catalog, or the Quay/Harbor repository API) before and after each upload and record the repositories and tags added")
+	flags.BoolVar(&o.airGap.Enabled, "air-gap", false, "Test the fully disconnected flow: mirror to a tar archive, transfer it to a separate directory and mirror from the transferred archive to the registry")
+	flags.Float64Var(&o.airGap.TransferRateMBs, "air-gap-transfer-rate", 0, "Throttle the archive transfer of --air-gap to this many MB/s (default: disk speed)")
+	flags.StringArrayVar(&o.replication.Registries, "replicate-to", nil, "After each upload, replicate the content with skopeo sync to this registry and time the hop (repeatable, in hop order)")
+	flags.StringVar(&o.replication.Topology, "replication-topology", runner.TopologyChain, "Replication topology: chain (each registry fed from the one before) or hub-spoke (every registry fed from --registry)")
+	flags.BoolVar(&o.delete.Enabled, "delete", false, "After the iterations, delete the mirrored content with oc-mirror delete (v2) and measure time, API calls and reclaimed space")
+	flags.StringVar(&o.delete.ConfigFile, "delete-config", "", "DeleteImageSetConfiguration for the delete phase (default: delete all mirrored content)")
+	flags.StringVar(&o.delete.GCCommand, "delete-gc-command", "", "Shell command run after the delete to garbage-collect registry blobs (e.g. \"podman exec registry registry garbage-collect -m /etc/docker/registry/config.yml\")")
+	flags.BoolVar(&o.delete.ForceCacheDelete, "force-cache-delete", false, "Also delete the images from the local oc-mirror cache during the delete phase")
+	flags.StringVar(&o.contentScenario, "content", config.ContentOperators, "Content scenario to mirror: "+strings.Join(config.ContentScenarios(), ", "))
+	flags.StringVar(&o.contentFile, "content-file", "", "YAML file describing operators/additionalImages/helm content (overrides --content)")
+	flags.StringArrayVar(&o.stages, "stage", nil, "Priority-ordered mirroring: stage as name=package[,package...] mirrored before the following stages and the remaining content, by its own oc-mirror invocations (repeatable, in priority order)")
+	flags.StringVar(&o.updateContentFile, "update-content-file", "", "Day-2 update: content file mirrored from the second iteration on, e.g. the same operators with a newer maxVersion, measuring the incremental update")
+	flags.StringArrayVar(&o.additionalImages, "additional-image", nil, "Additional image to mirror (repeatable)")
+	flags.StringArrayVar(&o.helmCharts, "helm-chart", nil, "Helm chart to mirror as <repo-url>/<chart>@<version> (repeatable)")
+	flags.StringVar(&o.shaping.Rate, "limit-bandwidth", "", "Limit bandwidth on the test interface using tc/netem (e.g., 100mbit)")
+	flags.StringVar(&o.shaping.Latency, "latency", "", "Add latency on the test interface using tc/netem (e.g., 50ms)")
+	flags.StringVar(&o.shaping.Jitter, "jitter", "", "Latency variation when --latency is set (e.g., 10ms)")
+	flags.StringVar(&o.shaping.Loss, "packet-loss", "", "Packet loss on the test interface using tc/netem (e.g., 0.5%)")
+	flags.StringVar(&o.shaping.Interface, "shape-interface", "", "Interface to apply network shaping to (default: interface with the default route)")
+	flags.BoolVar(&o.shaping.Ingress, "shape-ingress", false, "Also shape inbound traffic (requires the ifb kernel module)")
+	flags.StringVar(&o.resultsDir, "results-dir", runner.DefaultResultsDir, "Directory to write results files to")
+	flags.StringVar(&o.binDir, "bin-dir", "./bin", "Directory the client tools are run from; missing oc-mirror, opm and oc are downloaded into it")
+	flags.StringVar(&o.toolsVersion, "tools-version", client.DefaultOCPVersion, "OpenShift version the missing client tools are downloaded for")
+	flags.StringVar(&o.toolsFromDir, "tools-from-dir", "", "Install the missing client tools from the tarballs and sha256sum.txt staged in this directory instead of downloading them")
+	flags.StringVar(&o.runName, "run-name", "", "Run name embedded in the results file name (default: scenario name)")
+	flags.StringArrayVar(&o.tags, "tag", nil, "Tag the run as key=value or key, e.g. registry=quay-3.12 (repeatable); kept in the results header and filterable in the web UI")
+	flags.StringArrayVar(&o.notes, "note", nil, "Free-text note about the run, e.g. \"new NIC\" (repeatable)")
+	flags.IntVar(&o.pacing.MaxConcurrentPushes, "max-concurrent-pushes", 0, "Cap parallel pushes to the registry during upload (v2: --parallel-images N --parallel-layers 1, v1: --max-per-registry N)")
+	flags.StringArrayVar(&o.pacing.Windows, "upload-window", nil, "Allowed upload window, e.g. \"Mon-Fri 18:00-07:00\" or \"Sat,Sun\" (repeatable); uploads wait for the next window")
+	flags.DurationVar(&o.pacing.MaxWait, "max-window-wait", 0, "Fail the upload phase instead of waiting longer than this for a window (default: wait indefinitely)")
+	flags.StringVar(&o.memoryCeiling.Budget, "memory-budget", "", "Host memory budget (e.g. 16Gi); oc-mirror RSS plus dirty page cache is checked against it and OOM kills are recorded")
+	flags.Float64Var(&o.memoryCeiling.WarnWithinPercent, "memory-warn-within", 10, "Warn when memory usage is within this percentage of --memory-budget")
+	flags.BoolVar(&o.signatures.Enabled, "verify-signatures", false, "After each upload, count the cosign signatures, attestations and SBOMs in the registry and check that they belong to the mirrored images")
+	flags.StringVar(&o.signatures.KeyFile, "signature-key", "", "cosign public key (PEM) the signatures and attestations are verified against")
+	flags.StringVar(&o.proxy.HTTPProxy, "http-proxy", "", "HTTP proxy oc-mirror runs through (HTTP_PROXY), e.g. http://proxy.example.com:3128")
+	flags.StringVar(&o.proxy.HTTPSProxy, "https-proxy", "", "HTTPS proxy oc-mirror runs through (HTTPS_PROXY)")
+	flags.StringVar(&o.proxy.NoProxy, "no-proxy", "", "Comma-separated hosts oc-mirror reaches without the proxy (NO_PROXY), typically the target registry")
+	flags.BoolVar(&o.proxy.Compare, "compare-proxy", false, "Run the iterations through the proxy, then again without it, each from an empty cache, and compare the downloads")
+	flags.DurationVar(&o.chaos.KillAfter, "chaos-kill-after", 0, "Kill oc-mirror this long into the download of each clean iteration, then run the download again and measure how much it resumes from the cache")
+	flags.StringVar(&o.chaos.KillAfterBytes, "chaos-kill-after-bytes", "", "Kill oc-mirror once the download of each clean iteration wrote this much to the workspace and cache, e.g. 2Gi (see --chaos-kill-after)")
+	flags.StringSliceVar(&o.matrix.Versions, "matrix-versions", nil, "oc-mirror versions of the iteration matrix (v1, v2); each combination runs all iterations")
+	flags.StringSliceVar(&o.matrix.Workflows, "matrix-workflows", nil, "Workflows of the iteration matrix (mirror, airgap)")
+	flags.StringSliceVar(&o.matrix.CacheStates, "matrix-cache", nil, "Cache states of the iteration matrix (clean, cached): the first iteration of each combination runs the first state, the others the last")
+	flags.IntSliceVar(&o.matrix.Concurrency, "matrix-concurrency", nil, "Max concurrent pushes of the iteration matrix, 0 for the oc-mirror default")
+	flags.StringSliceVar(&o.matrix.References, "matrix-references", nil, "Image references of the iteration matrix (tag, digest): content by tag, or pinned to the digests the tags resolve to when the run starts")
+	flags.BoolVar(&o.comparePinning, "compare-pinning", false, "Compare mirroring the content by tag with the same content pinned to digests")
+	flags.BoolVar(&o.validateContent, "validate-content", false, "Render the operator catalogs with opm before mirroring and report the bundle and related images missing from the registry after each upload")
+	flags.BoolVar(&o.packageSizes, "package-sizes", false, "Render the operator catalogs with opm and attribute the bytes in the registry after each upload to the operator packages")
+	flags.BoolVar(&o.verifyUpload, "verify-upload", false, "After each upload, compare the blob digests of the local mirror with the blobs the registry references and record a verdict")
+	flags.StringVar(&o.signingKeyFile, "signing-key-file", "", "Key file used to HMAC-sign results files (a sha256 checksum is always written)")
+	flags.BoolVar(&o.noTUI, "no-tui", false, "Do not show the live progress line during oc-mirror invocations, e.g. for CI logs (it is only shown on a terminal)")
+	flags.StringVar(&o.eventsFile, "output-events", "", "Write the run events (phase_start, sample, phase_end, iteration_summary, run_summary) to this file as NDJSON while the run executes")
+	flags.StringArrayVar(&o.sinks, "sink", nil, "Send the results and monitor samples to a metric sink while the run executes: json:<path>, ndjson[:<path>], pushgateway:<url> or influx:<write-url|path> (repeatable) [env "+sink.EnvInfluxToken+" for InfluxDB]")
+	flags.StringVar(&o.heartbeatFile, "heartbeat-file", "", "Write a JSON heartbeat with current phase and progress to this file")
+	flags.StringVar(&o.heartbeatURL, "heartbeat-url", "", "POST the JSON heartbeat to this monitoring URL")
+	flags.DurationVar(&o.heartbeatInterval, "heartbeat-interval", 30*time.Second, "Interval between heartbeats")
+	flags.StringArrayVar(&o.notify.URLs, "notify-url", nil, "POST a run summary to this webhook when the run ends (repeatable; Slack incoming webhooks get a Slack message, prefix slack+ for Slack-compatible hooks) [env "+notify.EnvURL+"]")
+	flags.StringVar(&o.notify.On, "notify-on", notify.OnAlways, "When to notify: always or failure [env "+notify.EnvOn+"]")
+	flags.StringVar(&o.notify.DashboardURL, "dashboard-url", "", "Web UI link included in notifications [env "+notify.EnvDashboardURL+"]")
+	flags.StringVar(&o.tracing.Endpoint, "otlp-endpoint", "", "Export a trace per iteration (phases, monitors and oc-mirror runs) to this OTLP/HTTP endpoint, e.g. http://tempo:4318 [env "+tracing.EnvEndpoint+"]")
+	flags.StringArrayVar(&o.otlpHeaders, "otlp-header", nil, "Header sent with every trace export, as key=value (repeatable) [env "+tracing.EnvHeaders+"]")
+	flags.StringArrayVar(&o.monitorPlugins, "monitor-plugin", nil, "Run a collector as name=command during each phase; it writes JSON lines such as {\"values\":{\"san_read_mbs\":412.5}} to stdout, recorded as samples (repeatable)")
+	flags.StringArrayVar(&o.sinkPlugins, "sink-plugin", nil, "Run a consumer as name=command for the whole run; it reads the run events as JSON lines on stdin (repeatable)")
+	flags.StringVar(&o.ticket.ID, "ticket", "", "Attach the run report and results bundle to this Jira issue (e.g. MIRROR-123) or ServiceNow ticket (e.g. INC0012345) when the run ends; API token from "+ticket.EnvToken)
+	flags.StringVar(&o.ticket.System, "ticket-system", "", "Ticket system: jira or servicenow (default: detected from --ticket)")
+	flags.StringVar(&o.ticket.URL, "ticket-url", "", "Base URL of the Jira or ServiceNow instance [env "+ticket.EnvURL+"]")
+	flags.StringVar(&o.ticket.User, "ticket-user", "", "Jira account email or ServiceNow user; without it the token is sent as a bearer token [env "+ticket.EnvUser+"]")
+	flags.StringVar(&o.ticket.Table, "ticket-table", "", "ServiceNow table of the ticket (default: derived from the number prefix, e.g. INC -> incident)")
+	flags.StringVar(&o.store.Location, "results-store", "", "Upload the results file and its sidecars to s3://bucket/prefix (or a directory) when the run ends, e.g. for a central web UI")
+	flags.BoolVar(&o.store.Logs, "results-store-logs", false, "Also upload the oc-mirror logs to --results-store under logs/")
+	flags.StringVar(&o.store.S3.Endpoint, "s3-endpoint", "", "S3-compatible endpoint for an s3:// results store, e.g. https://minio.lab:9000 (default: AWS S3) [env "+store.EnvS3Endpoint+"]")
+	flags.BoolVar(&o.store.S3.SkipTLS, "s3-skip-tls", false, "Skip TLS verification for the S3 endpoint")
+	flags.DurationVar(&o.gates.MaxDownloadTime, "gate-max-download-time", 0, "Fail an iteration whose download phase takes longer than this")
+	flags.Float64Var(&o.gates.MinAvgSpeedMBs, "gate-min-avg-speed", 0, "Fail an iteration whose average download speed is below this many MB/s")
+	flags.Float64Var(&o.gates.MaxMemoryMB, "gate-max-memory-mb", 0, "Fail an iteration whose oc-mirror peak memory exceeds this many MB")
+	flags.IntVar(&o.gateMaxErrors, "gate-max-errors", 0, "Fail an iteration with more oc-mirror errors than this (only checked when set)")
+	flags.StringVar(&o.kubeconfig, "kubeconfig", "", "After the run, compare the generated IDMS/ITMS/ICSP and CatalogSource manifests with the state applied on this cluster and report drift")
+	flags.BoolVar(&o.clusterValidation.Enabled, "validate-cluster", false, "After the iterations, apply the generated cluster resources to the disposable test cluster of --kubeconfig, deploy a sample workload and record whether its images are pulled from the mirror")
+	flags.StringArrayVar(&o.clusterValidation.Images, "validate-image", nil, "Image the validation runs as a pod (repeatable; default: the additional images of the content)")
+	flags.StringVar(&o.clusterValidation.Operator, "validate-operator", "", "Operator package the validation subscribes to (default: a package of the mirrored catalogs)")
+	flags.StringVar(&o.clusterValidation.Channel, "validate-channel", "", "Channel of --validate-operator (default: the package default channel)")
+	flags.DurationVar(&o.clusterValidation.Timeout, "validate-timeout", 0, "Time allowed for the mirror rollout and the image pulls of the validation (default 30m)")
+	flags.BoolVar(&o.pullThrough.Enabled, "verify-pull-through", false, "After each upload, create a CatalogSource for each mirrored catalog on the cluster of --kubeconfig and record whether its packages resolve")
+	flags.DurationVar(&o.pullThrough.Timeout, "pull-through-timeout", 0, "Time allowed for the catalog sources of --verify-pull-through to serve their packages (default 10m)")
+}
+
+// buildConfig creates the runner configuration. Values from a scenario file are
+// used unless the corresponding flag was set explicitly on the command line.
+func (o *runOptions) buildConfig(cmd *cobra.Command) (*runner.Config, *scenario.Scenario, error) {
+	var sc *scenario.Scenario
+	var content *config.ContentSpec
+	var contentName string
+
+	if o.scenarioFile != "" {
+		var err error
+		sc, err = scenario.Load(o.scenarioFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		o.applyScenario(cmd, sc)
+
+		if !cmd.Flags().Changed("content") && !cmd.Flags().Changed("content-file") {
+			content, contentName, err = sc.ResolveContent()
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if cmd.Flags().Changed("gate-max-errors") {
+		o.gates.MaxErrors = &o.gateMaxErrors
+	}
+
+	if content == nil {
+		var err error
+		content, contentName, err = buildContent(o.contentScenario, o.contentFile)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	updateContent, err := o.buildUpdateContent(sc)
+	if err != nil {
+		return nil, nil, err
+	}
+	stages, err := o.buildStages(cmd, sc)
+	if err != nil {
+		return nil, nil, err
+	}
+	plugins, err := o.buildPlugins(cmd, sc)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags, notes, err := o.buildAnnotations(sc)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range []*config.ContentSpec{content, updateContent} {
+		if c == nil {
+			continue
+		}
+		c.AdditionalImages = append(c.AdditionalImages, o.additionalImages...)
+		for _, ref := range o.helmCharts {
+			if err := c.AddHelmChart(ref); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if o.localRegistry.Enabled {
+		if o.registryURL != "" {
+			return nil, nil, fmt.Errorf("--local-registry replaces --registry; pass only one")
+		}
+		if err := o.localRegistry.Validate(); err != nil {
+			return nil, nil, err
+		}
+		// The port is fixed now so the URL is known before the registry starts
+		if err := o.localRegistry.AssignPort(); err != nil {
+			return nil, nil, err
+		}
+		o.registryURL = o.localRegistry.URL()
+		o.skipTLS = true // Self-signed certificate
+	}
+	if o.registryURL == "" {
+		return nil, nil, fmt.Errorf("registry URL is required (--registry, --local-registry or scenario registry)")
+	}
+	o.applyNotifyEnv(cmd)
+	if err := o.applyTracingEnv(cmd); err != nil {
+		return nil, nil, err
+	}
+	o.applyTicketEnv(cmd)
+	o.applyStoreEnv(cmd)
+
+	cfg := &runner.Config{
+		RegistryURL: o.registryURL,
+		Iterations:  o.iterations,
+		Warmup:      o.warmup,
+		CompareV1V2: o.compareV1V2,
+		SkipTLS:     o.skipTLS,
+		Shaping:     o.shaping,
+		Pacing:      o.pacing,
+
+		CompareRegistries: o.compareRegistries,
+		RegistryOrder:     o.registryOrder,
+
+		RegistryStoragePath: o.registryStoragePath,
+		WorkspaceDir:        o.workspaceDir,
+		CacheDir:            o.cacheDir,
+		Cleanup:             o.cleanup,
+		DiskSpace:           o.diskSpace,
+		RegistryAccessLog:   o.registryAccessLog,
+		UploadDebugLog:      o.uploadDebugLog,
+		AccountingProxy:     o.accountingProxy,
+		RateLimit:           o.rateLimit,
+		CatalogDiff:         o.catalogDiff,
+		ValidateContent:     o.validateContent,
+		VerifyUpload:        o.verifyUpload,
+		PackageSizes:        o.packageSizes,
+		Chaos:               o.chaos,
+		Proxy:               o.proxy,
+		Matrix:              o.matrix,
+		ComparePinning:      o.comparePinning,
+		LogRetention:        o.logRetention,
+		RegistryMetrics:     o.registryMetrics,
+		AdaptivePolling:     o.adaptivePolling,
+		NetworkAccounting:   o.networkAccounting,
+		ResourceScope:       o.resourceScope,
+		SampleStorage:       o.sampleStorage,
+		RetryFailed:         o.retryFailed,
+		RetryBackoff:        o.retryBackoff,
+		DownloadTimeout:     o.downloadTimeout,
+		UploadTimeout:       o.uploadTimeout,
+		WorkspaceCleanup:    o.workspaceCleanup,
+		RepeatClean:         o.repeatClean,
+		ResultsDir:          o.resultsDir,
+		BinDir:              o.binDir,
+		ToolsVersion:        o.toolsVersion,
+		ToolsFromDir:        o.toolsFromDir,
+		RunName:             o.runName,
+		Tags:                tags,
+		Notes:               notes,
+
+		ContentScenario: contentName,
+		Content:         content,
+		UpdateContent:   updateContent,
+		Stages:          stages,
+
+		NoTUI:             o.noTUI,
+		EventsFile:        o.eventsFile,
+		HeartbeatFile:     o.heartbeatFile,
+		HeartbeatURL:      o.heartbeatURL,
+		HeartbeatInterval: o.heartbeatInterval,
+
+		Notify:        o.notify,
+		Tracing:       o.tracing,
+		Plugins:       plugins,
+		Delete:        o.delete,
+		MemoryCeiling: o.memoryCeiling,
+		Ticket:        o.ticket,
+		Signatures:    o.signatures,
+		Store:         o.store,
+		Kubeconfig:    o.kubeconfig,
+		Gates:         o.gates,
+
+		ClusterValidation: o.clusterValidation,
+		PullThrough:       o.pullThrough,
+		AirGap:            o.airGap,
+		Replication:       o.replication,
+	}
+	cfg.ToolVersion = Version
+	cfg.RunArgs, cfg.Resume = o.runArgs, o.resume
+	if cfg.RunArgs == nil {
+		cfg.RunArgs = commandLineFlags()
+	}
+	if o.signingKeyFile != "" {
+		key, err := integrity.LoadKey(o.signingKeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.SigningKey = key
+	}
+	if sc != nil {
+		cfg.ScenarioName = sc.Name
+		cfg.ScenarioHash = sc.Hash()
+		cfg.ExtraArgs = sc.Flags
+		if cfg.RunName == "" {
+			cfg.RunName = sc.Name
+		}
+	}
+
+	if err := cfg.Shaping.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Pacing.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Content.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.ValidateUpdate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.ValidateStages(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Notify.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Tracing.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.RegistryMetrics.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.AdaptivePolling.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Plugins.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.ValidateRegistryComparison(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.ValidateDelete(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.ValidateStorage(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.ValidateWorkspaceCleanup(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.ValidateWarmup(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Cleanup.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.DiskSpace.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.MemoryCeiling.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Ticket.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Signatures.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Chaos.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.ValidateProxyComparison(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Store.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.ValidateKubeconfig(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Gates.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.ValidateClusterValidation(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.ValidatePullThrough(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.ValidateAccountingProxy(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.ValidateAirGap(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.ValidateReplication(); err != nil {
+		return nil, nil, err
+	}
+	if cfg.RetryFailed < 0 {
+		return nil, nil, fmt.Errorf("--retry-failed must not be negative")
+	}
+	if cfg.DownloadTimeout < 0 || cfg.UploadTimeout < 0 {
+		return nil, nil, fmt.Errorf("--download-timeout and --upload-timeout must not be negative")
+	}
+	if mode := cfg.GetNetworkAccounting(); mode != runner.NetworkAccountingInterface && mode != runner.NetworkAccountingProcess {
+		return nil, nil, fmt.Errorf("unknown network accounting mode %q (valid: %s, %s)", mode, runner.NetworkAccountingInterface, runner.NetworkAccountingProcess)
+	}
+	switch scope := cfg.GetResourceScope(); scope {
+	case monitor.ResourceScopeProcess, monitor.ResourceScopeTree, monitor.ResourceScopeCgroup:
+	default:
+		return nil, nil, fmt.Errorf("unknown resource scope %q (valid: %s, %s, %s)", scope, monitor.ResourceScopeProcess, monitor.ResourceScopeTree, monitor.ResourceScopeCgroup)
+	}
+	switch storage := cfg.GetSampleStorage(); storage {
+	case runner.SampleStorageInline, runner.SampleStorageDelta, runner.SampleStorageDeltaGzip:
+	default:
+		return nil, nil, fmt.Errorf("unknown sample storage %q (valid: %s, %s, %s)", storage, runner.SampleStorageInline, runner.SampleStorageDelta, runner.SampleStorageDeltaGzip)
+	}
+
+	return cfg, sc, nil
+}
+
+// applyScenario copies scenario values into options that were not set on the command line
+func (o *runOptions) applyScenario(cmd *cobra.Command, sc *scenario.Scenario) {
+	flags := cmd.Flags()
+	if !flags.Changed("registry") && sc.Registry != "" {
+		o.registryURL = sc.Registry
+	}
+	if !flags.Changed("iterations") && sc.Iterations > 0 {
+		o.iterations = sc.Iterations
+	}
+	if !flags.Changed("warmup") && sc.Warmup > 0 {
+		o.warmup = sc.Warmup
+	}
+	if !flags.Changed("compare-registry") && len(sc.CompareRegistries) > 0 {
+		o.compareRegistries = sc.CompareRegistries
+	}
+	if !flags.Changed("sink") && len(sc.Sinks) > 0 {
+		o.sinks = sc.Sinks
+	}
+	if !flags.Changed("registry-order") && sc.RegistryOrder != "" {
+		o.registryOrder = sc.RegistryOrder
+	}
+	if !flags.Changed("compare-v1-v2") {
+		o.compareV1V2 = sc.Workflow == scenario.WorkflowCompareV1V2
+	}
+	if !flags.Changed("skip-tls") {
+		o.skipTLS = sc.SkipTLS
+	}
+	if !flags.Changed("local-registry") && sc.LocalRegistry.Enabled {
+		o.localRegistry.Enabled = true
+	}
+	if !flags.Changed("local-registry-runtime") && sc.LocalRegistry.Runtime != "" {
+		o.localRegistry.Runtime = sc.LocalRegistry.Runtime
+	}
+	if !flags.Changed("local-registry-image") && sc.LocalRegistry.Image != "" {
+		o.localRegistry.Image = sc.LocalRegistry.Image
+	}
+	if !flags.Changed("local-registry-port") && sc.LocalRegistry.Port > 0 {
+		o.localRegistry.Port = sc.LocalRegistry.Port
+	}
+	if !flags.Changed("local-registry-storage") && sc.LocalRegistry.Storage != "" {
+		o.localRegistry.Storage = sc.LocalRegistry.Storage
+	}
+	if !flags.Changed("local-registry-storage-path") && sc.LocalRegistry.StoragePath != "" {
+		o.localRegistry.StoragePath = sc.LocalRegistry.StoragePath
+	}
+	if !flags.Changed("retry-failed") && sc.RetryFailed > 0 {
+		o.retryFailed = sc.RetryFailed
+	}
+	if !flags.Changed("download-timeout") && sc.DownloadTimeout > 0 {
+		o.downloadTimeout = sc.DownloadTimeout
+	}
+	if !flags.Changed("upload-timeout") && sc.UploadTimeout > 0 {
+		o.uploadTimeout = sc.UploadTimeout
+	}
+	if !flags.Changed("workspace-cleanup") && len(sc.WorkspaceCleanup) > 0 {
+		o.workspaceCleanup = sc.WorkspaceCleanup
+	}
+	if !flags.Changed("repeat-clean") && sc.RepeatClean {
+		o.repeatClean = true
+	}
+	if !flags.Changed("max-concurrent-pushes") && sc.Pacing.MaxConcurrentPushes > 0 {
+		o.pacing.MaxConcurrentPushes = sc.Pacing.MaxConcurrentPushes
+	}
+	if !flags.Changed("upload-window") && len(sc.Pacing.Windows) > 0 {
+		o.pacing.Windows = sc.Pacing.Windows
+	}
+	if !flags.Changed("max-window-wait") && sc.Pacing.MaxWait > 0 {
+		o.pacing.MaxWait = sc.Pacing.MaxWait
+	}
+	if !flags.Changed("limit-bandwidth") && sc.Network.Rate != "" {
+		o.shaping.Rate = sc.Network.Rate
+	}
+	if !flags.Changed("latency") && sc.Network.Latency != "" {
+		o.shaping.Latency = sc.Network.Latency
+	}
+	if !flags.Changed("jitter") && sc.Network.Jitter != "" {
+		o.shaping.Jitter = sc.Network.Jitter
+	}
+	if !flags.Changed("packet-loss") && sc.Network.Loss != "" {
+		o.shaping.Loss = sc.Network.Loss
+	}
+	if !flags.Changed("shape-interface") && sc.Network.Interface != "" {
+		o.shaping.Interface = sc.Network.Interface
+	}
+	if !flags.Changed("shape-ingress") && sc.Network.Ingress {
+		o.shaping.Ingress = true
+	}
+	if !flags.Changed("air-gap") && sc.AirGap.Enabled {
+		o.airGap.Enabled = true
+	}
+	if !flags.Changed("air-gap-transfer-rate") && sc.AirGap.TransferRateMBs > 0 {
+		o.airGap.TransferRateMBs = sc.AirGap.TransferRateMBs
+	}
+	if !flags.Changed("replicate-to") && len(sc.Replication.Registries) > 0 {
+		o.replication.Registries = sc.Replication.Registries
+	}
+	if !flags.Changed("replication-topology") && sc.Replication.Topology != "" {
+		o.replication.Topology = sc.Replication.Topology
+	}
+	if !flags.Changed("delete") && sc.Delete.Enabled {
+		o.delete.Enabled = true
+	}
+	if !flags.Changed("delete-config") && sc.Delete.ConfigFile != "" {
+		o.delete.ConfigFile = sc.Delete.ConfigFile
+	}
+	if !flags.Changed("delete-gc-command") && sc.Delete.GCCommand != "" {
+		o.delete.GCCommand = sc.Delete.GCCommand
+	}
+	if !flags.Changed("force-cache-delete") && sc.Delete.ForceCacheDelete {
+		o.delete.ForceCacheDelete = true
+	}
+	if !flags.Changed("verify-signatures") && sc.Signatures.Enabled {
+		o.signatures.Enabled = true
+	}
+	if !flags.Changed("signature-key") && sc.Signatures.KeyFile != "" {
+		o.signatures.KeyFile = sc.Signatures.KeyFile
+	}
+	if !flags.Changed("validate-content") && sc.ValidateContent {
+		o.validateContent = true
+	}
+	if !flags.Changed("verify-upload") && sc.VerifyUpload {
+		o.verifyUpload = true
+	}
+	if !flags.Changed("package-sizes") && sc.PackageSizes {
+		o.packageSizes = true
+	}
+	if !flags.Changed("bin-dir") && sc.BinDir != "" {
+		o.binDir = sc.BinDir
+	}
+	if !flags.Changed("tools-version") && sc.ToolsVersion != "" {
+		o.toolsVersion = sc.ToolsVersion
+	}
+	if !flags.Changed("tools-from-dir") && sc.ToolsFromDir != "" {
+		o.toolsFromDir = sc.ToolsFromDir
+	}
+	if !flags.Changed("accounting-proxy") && sc.AccountingProxy {
+		o.accountingProxy = true
+	}
+	if !flags.Changed("rate-limit-429") && sc.RateLimit.ThrottleRate > 0 {
+		o.rateLimit.ThrottleRate = sc.RateLimit.ThrottleRate
+	}
+	if !flags.Changed("rate-limit-rps") && sc.RateLimit.RequestsPerSecond > 0 {
+		o.rateLimit.RequestsPerSecond = sc.RateLimit.RequestsPerSecond
+	}
+	if !flags.Changed("rate-limit-retry-after") && sc.RateLimit.RetryAfter > 0 {
+		o.rateLimit.RetryAfter = sc.RateLimit.RetryAfter
+	}
+	if !flags.Changed("rate-limit-bandwidth") && sc.RateLimit.Bandwidth != "" {
+		o.rateLimit.Bandwidth = sc.RateLimit.Bandwidth
+	}
+	if !flags.Changed("chaos-kill-after") && sc.Chaos.KillAfter > 0 {
+		o.chaos.KillAfter = sc.Chaos.KillAfter
+	}
+	if !flags.Changed("chaos-kill-after-bytes") && sc.Chaos.KillAfterBytes != "" {
+		o.chaos.KillAfterBytes = sc.Chaos.KillAfterBytes
+	}
+	if !flags.Changed("http-proxy") && sc.Proxy.HTTPProxy != "" {
+		o.proxy.HTTPProxy = sc.Proxy.HTTPProxy
+	}
+	if !flags.Changed("https-proxy") && sc.Proxy.HTTPSProxy != "" {
+		o.proxy.HTTPSProxy = sc.Proxy.HTTPSProxy
+	}
+	if !flags.Changed("no-proxy") && sc.Proxy.NoProxy != "" {
+		o.proxy.NoProxy = sc.Proxy.NoProxy
+	}
+	if !flags.Changed("compare-proxy") && sc.Proxy.Compare {
+		o.proxy.Compare = true
+	}
+	if !flags.Changed("matrix-versions") && len(sc.Matrix.Versions) > 0 {
+		o.matrix.Versions = sc.Matrix.Versions
+	}
+	if !flags.Changed("matrix-workflows") && len(sc.Matrix.Workflows) > 0 {
+		o.matrix.Workflows = sc.Matrix.Workflows
+	}
+	if !flags.Changed("matrix-cache") && len(sc.Matrix.CacheStates) > 0 {
+		o.matrix.CacheStates = sc.Matrix.CacheStates
+	}
+	if !flags.Changed("matrix-concurrency") && len(sc.Matrix.Concurrency) > 0 {
+		o.matrix.Concurrency = sc.Matrix.Concurrency
+	}
+	if !flags.Changed("matrix-references") && len(sc.Matrix.References) > 0 {
+		o.matrix.References = sc.Matrix.References
+	}
+	if !flags.Changed("compare-pinning") && sc.ComparePinning {
+		o.comparePinning = true
+	}
+	if !flags.Changed("log-retention") && sc.LogRetention.Mode != "" {
+		o.logRetention.Mode = sc.LogRetention.Mode
+	}
+	if !flags.Changed("log-retention-lines") && sc.LogRetention.Lines > 0 {
+		o.logRetention.Lines = sc.LogRetention.Lines
+	}
+	if !flags.Changed("results-store") && sc.ResultsStore.Location != "" {
+		o.store.Location = sc.ResultsStore.Location
+	}
+	if !flags.Changed("results-store-logs") && sc.ResultsStore.Logs {
+		o.store.Logs = true
+	}
+	if !flags.Changed("memory-budget") && sc.MemoryCeiling.Budget != "" {
+		o.memoryCeiling.Budget = sc.MemoryCeiling.Budget
+	}
+	if !flags.Changed("memory-warn-within") && sc.MemoryCeiling.WarnWithinPercent > 0 {
+		o.memoryCeiling.WarnWithinPercent = sc.MemoryCeiling.WarnWithinPercent
+	}
+	if !flags.Changed("registry-metrics-url") && sc.RegistryMetrics.URL != "" {
+		o.registryMetrics.URL = sc.RegistryMetrics.URL
+	}
+	if !flags.Changed("registry-metrics-interval") && sc.RegistryMetrics.Interval > 0 {
+		o.registryMetrics.Interval = sc.RegistryMetrics.Interval
+	}
+	if !flags.Changed("keep-last") && sc.Cleanup.KeepLast > 0 {
+		o.cleanup.KeepLast = sc.Cleanup.KeepLast
+	}
+	if !flags.Changed("max-results-size") && sc.Cleanup.MaxResultsSize != "" {
+		o.cleanup.MaxResultsSize = sc.Cleanup.MaxResultsSize
+	}
+	if !flags.Changed("prune-workspaces") && sc.Cleanup.Workspaces {
+		o.cleanup.Workspaces = true
+	}
+	if !flags.Changed("prune-cache") && sc.Cleanup.Cache {
+		o.cleanup.Cache = true
+	}
+	if !flags.Changed("disk-estimate") {
+		// The disk budget of the scenario is the expected footprint
+		if sc.DiskSpace.Estimate != "" {
+			o.diskSpace.Estimate = sc.DiskSpace.Estimate
+		} else if sc.Budget.MaxDiskGB > 0 {
+			o.diskSpace.Estimate = strconv.FormatFloat(sc.Budget.MaxDiskGB, 'f', -1, 64) + "Gi"
+		}
+	}
+	if !flags.Changed("skip-disk-check") && sc.DiskSpace.Skip {
+		o.diskSpace.Skip = true
+	}
+	if !flags.Changed("adaptive-poll-after") && sc.AdaptivePolling.After > 0 {
+		o.adaptivePolling.After = sc.AdaptivePolling.After
+	}
+	if !flags.Changed("adaptive-poll-factor") && sc.AdaptivePolling.Factor > 0 {
+		o.adaptivePolling.Factor = sc.AdaptivePolling.Factor
+	}
+	if !flags.Changed("adaptive-poll-max") && sc.AdaptivePolling.MaxInterval > 0 {
+		o.adaptivePolling.MaxInterval = sc.AdaptivePolling.MaxInterval
+	}
+	if !flags.Changed("sample-storage") && sc.SampleStorage != "" {
+		o.sampleStorage = sc.SampleStorage
+	}
+	if !flags.Changed("gate-max-download-time") && sc.Gates.MaxDownloadTime > 0 {
+		o.gates.MaxDownloadTime = sc.Gates.MaxDownloadTime
+	}
+	if !flags.Changed("gate-min-avg-speed") && sc.Gates.MinAvgSpeedMBs > 0 {
+		o.gates.MinAvgSpeedMBs = sc.Gates.MinAvgSpeedMBs
+	}
+	if !flags.Changed("gate-max-memory-mb") && sc.Gates.MaxMemoryMB > 0 {
+		o.gates.MaxMemoryMB = sc.Gates.MaxMemoryMB
+	}
+	if !flags.Changed("gate-max-errors") && sc.Gates.MaxErrors != nil {
+		o.gates.MaxErrors = sc.Gates.MaxErrors
+	}
+	if !flags.Changed("validate-cluster") && sc.ClusterValidation.Enabled {
+		o.clusterValidation.Enabled = true
+	}
+	if !flags.Changed("validate-image") && len(sc.ClusterValidation.Images) > 0 {
+		o.clusterValidation.Images = sc.ClusterValidation.Images
+	}
+	if !flags.Changed("validate-operator") && sc.ClusterValidation.Operator != "" {
+		o.clusterValidation.Operator = sc.ClusterValidation.Operator
+	}
+	if !flags.Changed("validate-channel") && sc.ClusterValidation.Channel != "" {
+		o.clusterValidation.Channel = sc.ClusterValidation.Channel
+	}
+	if !flags.Changed("validate-timeout") && sc.ClusterValidation.Timeout > 0 {
+		o.clusterValidation.Timeout = sc.ClusterValidation.Timeout
+	}
+	if !flags.Changed("verify-pull-through") && sc.PullThrough.Enabled {
+		o.pullThrough.Enabled = true
+	}
+	if !flags.Changed("pull-through-timeout") && sc.PullThrough.Timeout > 0 {
+		o.pullThrough.Timeout = sc.PullThrough.Timeout
+	}
+}
+
+// applyNotifyEnv fills notification options that were not set on the command line from the environment
+func (o *runOptions) applyNotifyEnv(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	env := notify.ConfigFromEnv()
+	if !flags.Changed("notify-url") && len(env.URLs) > 0 {
+		o.notify.URLs = env.URLs
+	}
+	if !flags.Changed("notify-on") && env.On != "" {
+		o.notify.On = env.On
+	}
+	if !flags.Changed("dashboard-url") && env.DashboardURL != "" {
+		o.notify.DashboardURL = env.DashboardURL
+	}
+}
+
+// applyTracingEnv fills the OTLP exporter options that were not set on the command line from the environment
+func (o *runOptions) applyTracingEnv(cmd *cobra.Command) error {
+	flags := cmd.Flags()
+	env := tracing.ConfigFromEnv()
+	if !flags.Changed("otlp-endpoint") {
+		o.tracing.Endpoint = env.Endpoint
+	}
+	if flags.Changed("otlp-header") {
+		headers, err := tracing.ParseHeaders(o.otlpHeaders)
+		if err != nil {
+			return err
+		}
+		o.tracing.Headers = headers
+	} else {
+		o.tracing.Headers = env.Headers
+	}
+	o.tracing.ServiceName = env.ServiceName
+	return nil
+}
+
+// applyTicketEnv fills the ticket connection from the environment; the API token is never taken from a flag
+func (o *runOptions) applyTicketEnv(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	env := ticket.ConfigFromEnv()
+	if !flags.Changed("ticket-url") && env.URL != "" {
+		o.ticket.URL = env.URL
+	}
+	if !flags.Changed("ticket-user") && env.User != "" {
+		o.ticket.User = env.User
+	}
+	o.ticket.Token = env.Token
+}
+
+// applyStoreEnv fills the S3 credentials and region of the results store from the environment
+func (o *runOptions) applyStoreEnv(cmd *cobra.Command) {
+	env := store.S3OptionsFromEnv()
+	if cmd.Flags().Changed("s3-endpoint") {
+		env.Endpoint = o.store.S3.Endpoint
+	}
+	env.SkipTLS = o.store.S3.SkipTLS
+	o.store.S3 = env
+}
+
+// execute runs the tests and checks scenario thresholds
+func (o *runOptions) execute(cmd *cobra.Command) error {
+	cfg, sc, err := o.buildConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	if sc != nil {
+		logging.Printf("Scenario: %s\n", sc.Name)
+		if sc.Description != "" {
+			logging.Printf("  %s\n", sc.Description)
+		}
+		if sc.Budget.Enabled() {
+			logging.Printf("  Resource budget: %s\n", sc.Budget.String())
+		}
+	}
+
+	if o.ui.changed(cmd) && !o.withUI {
+		return fmt.Errorf("--ui-* flags require --with-ui")
+	}
+	if o.withUI && cfg.Notify.DashboardURL == "" {
+		cfg.Notify.DashboardURL = o.ui.dashboardURL()
+	}
+
+	// The one signal handler of the run: host changes are pushed on cleanups
+	// and undone whether the run ends or is interrupted
+	cleanups := &runner.Cleanups{}
+	defer cleanups.Run()
+	defer handleInterrupts(cleanups)()
+
+	if o.localRegistry.Enabled {
+		if err := startLocalRegistry(o.localRegistry, cfg, cleanups); err != nil {
+			return err
+		}
+	}
+
+	testRunner := runner.NewTestRunner(cfg)
+	testRunner.SetCleanups(cleanups)
+	if len(o.sinks) > 0 {
+		dispatcher, err := o.startSinks(testRunner)
+		if err != nil {
+			return err
+		}
+		defer dispatcher.Close()
+	}
+	if o.withUI {
+		stopUI, err := o.startUI(cmd, cfg, testRunner)
+		if err != nil {
+			return err
+		}
+		defer stopUI()
+	}
+	if err := testRunner.Run(); err != nil {
+		return err
+	}
+	if sc == nil {
+		return nil
+	}
+	logging.Summary(func() {
+		err = errors.Join(checkThresholds(sc, testRunner.GetResults()), checkBudget(sc, testRunner.GetResults()))
+	})
+	return err
+}
+
+// handleInterrupts runs cleanups and exits with 130 when the process gets
+// SIGINT or SIGTERM. The returned function stops handling the signals.
+func handleInterrupts(cleanups *runner.Cleanups) func() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		select {
+		case <-done:
+			return // The run ended
+		default:
+		}
+		stop() // A second interrupt ends the process at once
+		logging.Printf("\nInterrupted, cleaning up...\n")
+		cleanups.Run()
+		os.Exit(130)
+	}()
+	return func() {
+		close(done)
+		stop()
+	}
+}
+
+// startLocalRegistry starts the --local-registry container the run mirrors
+// to and pushes its removal on cleanups, which run when the run ends or is
+// interrupted
+func startLocalRegistry(localConfig registry.LocalRegistryConfig, cfg *runner.Config, cleanups *runner.Cleanups) error {
+	local, err := registry.StartLocalRegistry(localConfig)
+	if err != nil {
+		return err
+	}
+	logging.Printf("Local registry ready: %s\n", local.URL())
+	if cfg.RegistryStoragePath == "" {
+		cfg.RegistryStoragePath = local.StoragePath()
+	}
+	if cfg.Delete.Enabled && cfg.Delete.GCCommand == "" {
+		cfg.Delete.GCCommand = local.GCCommand()
+	}
+
+	cleanups.Push(func() {
+		logging.Printf("Removing the local registry...\n")
+		if err := local.Stop(); err != nil {
+			logging.Printf("Warning: %v\n", err)
+		}
+	})
+	return nil
+}
+
+// startSinks opens the --sink sinks and feeds them the samples and finished
+// iterations of the run, labelled with the results file name
+func (o *runOptions) startSinks(testRunner *runner.TestRunner) (*sink.Dispatcher, error) {
+	var specs []sink.Spec
+	for _, value := range o.sinks {
+		spec, err := sink.ParseSpec(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --sink: %w", err)
+		}
+		specs = append(specs, spec)
+	}
+	run := strings.TrimSuffix(filepath.Base(testRunner.ResultsPath()), ".json")
+	dispatcher, err := sink.Start(specs, sink.Options{Run: run}, testRunner.Events())
+	if err != nil {
+		return nil, err
+	}
+	testRunner.SetResultHandler(dispatcher.Write)
+	return dispatcher, nil
+}
+
+// checkThresholds reports the scenario thresholds and returns an error on violations
+func checkThresholds(sc *scenario.Scenario, results []runner.TestResult) error {
+	if len(sc.Thresholds) == 0 {
+		return nil
+	}
+	violations := sc.Evaluate(results)
+	logging.Printf("\nScenario thresholds (%s):\n", sc.Name)
+	if len(violations) == 0 {
+		logging.Printf("  ✅ All %d threshold(s) met\n", len(sc.Thresholds))
+		return nil
+	}
+	for _, v := range violations {
+		logging.Printf("  ❌ %s\n", v.String())
+	}
+	return fmt.Errorf("scenario %s: %d threshold violation(s)", sc.Name, len(violations))
+}
+
+// checkBudget reports the scenario resource budget. Violations fail the run
+// unless the budget only flags them.
+func checkBudget(sc *scenario.Scenario, results []runner.TestResult) error {
+	if !sc.Budget.Enabled() {
+		return nil
+	}
+	violations := sc.Budget.Evaluate(results)
+	logging.Printf("\nResource budget (%s): %s\n", sc.Name, sc.Budget.String())
+	if len(violations) == 0 {
+		logging.Printf("  ✅ All iterations within budget\n")
+		return nil
+	}
+	marker := "❌"
+	if sc.Budget.GetAction() == scenario.BudgetFlag {
+		marker = "⚠️ "
+	}
+	for _, v := range violations {
+		logging.Printf("  %s %s\n", marker, v.String())
+	}
+	if sc.Budget.GetAction() == scenario.BudgetFlag {
+		logging.Printf("  Warning: %d budget violation(s) flagged; the run is not failed\n", len(violations))
+		return nil
+	}
+	return fmt.Errorf("scenario %s: %d resource budget violation(s)", sc.Name, len(violations))
+}
+
+// uiShutdownTimeout is how long run --with-ui waits for open web UI requests when the run ends
+const uiShutdownTimeout = 5 * time.Second
+
+// startUI serves the web UI for the duration of the run: it reads the run's
+// results directory, follows the registry monitor of the active run and keeps
+// the recent samples of its monitors in memory. The returned function shuts
+// the server down.
+func (o *runOptions) startUI(cmd *cobra.Command, cfg *runner.Config, testRunner *runner.TestRunner) (func(), error) {
+	if o.ui.liveSamples <= 0 {
+		return nil, fmt.Errorf("--ui-live-samples must be positive")
+	}
+	server, err := o.ui.newServer(cmd, cfg.GetResultsDir(), cfg.SigningKey)
+	if err != nil {
+		return nil, err
+	}
+	live := events.NewLiveBuffer(o.ui.liveSamples)
+	server.RegisterRun(webui.RunInfo{Name: cfg.RunName, ResultsFile: testRunner.ResultsPath()}, live, testRunner.GetRegistryMonitor())
+	if err := server.Listen(); err != nil {
+		return nil, err
+	}
+	stopLive := live.Follow(testRunner.Events())
+
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		if err := server.Serve(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: web UI server stopped: %v\n", err)
+		}
+	}()
+	logging.Printf("Web UI: %s\n", server.URL())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), uiShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: web UI did not shut down cleanly: %v\n", err)
+		}
+		<-served
+		stopLive()
+	}, nil
+}
+
+// buildUpdateContent returns the day-2 update content of the update content
+// file or the scenario, or nil without a day-2 update
+func (o *runOptions) buildUpdateContent(sc *scenario.Scenario) (*config.ContentSpec, error) {
+	if o.updateContentFile != "" {
+		return config.LoadContentFile(o.updateContentFile)
+	}
+	if sc != nil {
+		return sc.UpdateContent, nil
+	}
+	return nil, nil
+}
+
+// buildStages returns the stages of the --stage flags or the scenario. A
+// --stage flag selects operator packages; scenario stages also select images and charts.
+func (o *runOptions) buildStages(cmd *cobra.Command, sc *scenario.Scenario) ([]runner.StageConfig, error) {
+	if !cmd.Flags().Changed("stage") {
+		if sc != nil {
+			return sc.Stages, nil
+		}
+		return nil, nil
+	}
+	stages := make([]runner.StageConfig, 0, len(o.stages))
+	for _, value := range o.stages {
+		name, packages, ok := strings.Cut(value, "=")
+		if !ok || name == "" || packages == "" {
+			return nil, fmt.Errorf("invalid --stage %q (expected name=package[,package...])", value)
+		}
+		stage := runner.StageConfig{Name: name}
+		for _, pkg := range strings.Split(packages, ",") {
+			if pkg = strings.TrimSpace(pkg); pkg != "" {
+				stage.Packages = append(stage.Packages, pkg)
+			}
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+// buildAnnotations returns the tags and notes of the scenario and the --tag
+// and --note flags; a --tag replaces the scenario tag of the same key
+func (o *runOptions) buildAnnotations(sc *scenario.Scenario) (map[string]string, []string, error) {
+	tags, err := runner.ParseTags(o.tags)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sc == nil {
+		return tags, o.notes, nil
+	}
+	merged := runner.Annotations{Tags: sc.Tags}.Merge(runner.Annotations{Tags: tags})
+	return merged.Tags, append(append([]string(nil), sc.Notes...), o.notes...), nil
+}
+
+// buildPlugins returns the plugins of the --monitor-plugin and --sink-plugin
+// flags, or of the scenario for the kinds not set on the command line
+func (o *runOptions) buildPlugins(cmd *cobra.Command, sc *scenario.Scenario) (plugin.Config, error) {
+	var plugins plugin.Config
+	if sc != nil {
+		plugins = sc.Plugins
+	}
+	for _, kind := range []struct {
+		flag   string
+		values []string
+		specs  *[]plugin.Spec
+	}{{"monitor-plugin", o.monitorPlugins, &plugins.Monitors}, {"sink-plugin", o.sinkPlugins, &plugins.Sinks}} {
+		if !cmd.Flags().Changed(kind.flag) {
+			continue
+		}
+		*kind.specs = nil
+		for _, value := range kind.values {
+			spec, err := plugin.ParseSpec(value)
+			if err != nil {
+				return plugin.Config{}, fmt.Errorf("invalid --%s: %w", kind.flag, err)
+			}
+			*kind.specs = append(*kind.specs, spec)
+		}
+	}
+	return plugins, nil
+}
+
+// buildContent resolves the mirrored content from the scenario name or content file
+func buildContent(scenarioName, contentFile string) (*config.ContentSpec, string, error) {
+	if contentFile != "" {
+		content, err := config.LoadContentFile(contentFile)
+		return content, "custom", err
+	}
+	content, err := config.ContentForScenario(scenarioName)
+	return content, scenarioName, err
+}
+
+// commandLineFlags returns the flags of this invocation, without the run subcommand
+func commandLineFlags() []string {
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "run" {
+		args = args[1:]
+	}
+	return args
+}
+
+// newRunCommand creates the run subcommand
+func newRunCommand() *cobra.Command {
+	opts := &runOptions{}
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run oc-mirror tests, optionally from a scenario file",
+		Long:  "Runs oc-mirror tests with metrics collection. With --scenario, registry, iterations, workflow, content, oc-mirror flags and expected thresholds are read from a YAML file; explicit flags override scenario values.",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := opts.execute(cmd); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	opts.addFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/telco-core/ngc-495/pkg/api"
+	"github.com/telco-core/ngc-495/pkg/events"
+	"github.com/telco-core/ngc-495/pkg/integrity"
+	"github.com/telco-core/ngc-495/pkg/runner"
+	"github.com/telco-core/ngc-495/pkg/store"
+	"github.com/telco-core/ngc-495/pkg/webui"
+)
+
+// serveOptions holds the web server flags shared by serve and run --with-ui
+type serveOptions struct {
+	prefix      string // Flag name prefix, "ui-" when embedded in run
+	defaultPort int    // 8080 when unset
+	port        int
+	bindAddress string
+	tls         webui.TLSConfig
+	authUser    string
+	liveSamples int    // Samples kept per monitor for /api/live; run --with-ui only
+	assetsDir   string // Dashboard files served instead of the embedded ones
+}
+
+// addFlags registers the web server flags, prefixed with o.prefix
+func (o *serveOptions) addFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	if o.defaultPort == 0 {
+		o.defaultPort = 8080
+	}
+	if o.prefix == "" {
+		flags.IntVarP(&o.port, "port", "p", o.defaultPort, "Port to run the web server on")
+	} else {
+		flags.IntVar(&o.port, o.prefix+"port", o.defaultPort, "Port to run the web server on")
+	}
+	flags.StringVar(&o.bindAddress, o.prefix+"bind", "", "Address to listen on, e.g. 127.0.0.1 (default: all interfaces)")
+	flags.StringVar(&o.tls.CertFile, o.prefix+"tls-cert", "", "Certificate file (PEM) to serve HTTPS with")
+	flags.StringVar(&o.tls.KeyFile, o.prefix+"tls-key", "", "Private key file (PEM) of --"+o.prefix+"tls-cert")
+	flags.StringVar(&o.authUser, o.prefix+"auth-user", "", "Require HTTP basic auth as this user; password from "+webui.EnvAuthPassword+" [env "+webui.EnvAuthUser+"]. Set "+webui.EnvAuthToken+" to also accept a bearer token")
+	flags.StringVar(&o.assetsDir, o.prefix+"assets-dir", "", "Serve the dashboard files (index.html, app.js, styles.css) from this directory, re-read on every request; missing files come from the binary")
+	if o.prefix != "" {
+		flags.IntVar(&o.liveSamples, o.prefix+"live-samples", events.DefaultLiveSamples, "Recent samples kept in memory per monitor for live views")
+	}
+}
+
+// changed reports whether any web server flag was set on the command line
+func (o *serveOptions) changed(cmd *cobra.Command) bool {
+	for _, name := range []string{"port", "bind", "tls-cert", "tls-key", "auth-user", "live-samples", "assets-dir"} {
+		if cmd.Flags().Changed(o.prefix + name) {
+			return true
+		}
+	}
+	return false
+}
+
+// newServer creates a web server for a local results directory with the TLS
+// and authentication settings applied
+func (o *serveOptions) newServer(cmd *cobra.Command, resultsDir string, signingKey []byte) (*webui.Server, error) {
+	auth, err := o.authConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	server := webui.NewServer(o.port, resultsDir)
+	server.SetSigningKey(signingKey)
+	server.SetBindAddress(o.bindAddress)
+	server.SetTLS(o.tls)
+	server.SetAuth(auth)
+	if err := server.SetAssetsDir(o.assetsDir); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// authConfig validates the TLS settings and returns the credentials from the
+// environment, with the user of --auth-user
+func (o *serveOptions) authConfig(cmd *cobra.Command) (webui.AuthConfig, error) {
+	if err := o.tls.Validate(); err != nil {
+		return webui.AuthConfig{}, err
+	}
+	auth := webui.AuthConfigFromEnv()
+	if cmd.Flags().Changed(o.prefix + "auth-user") {
+		auth.User = o.authUser
+	}
+	if err := auth.Validate(); err != nil {
+		return webui.AuthConfig{}, err
+	}
+	return auth, nil
+}
+
+// dashboardURL returns the link to the web UI included in notifications
+func (o *serveOptions) dashboardURL() string {
+	scheme := "http"
+	if o.tls.Enabled() {
+		scheme = "https"
+	}
+	host := o.bindAddress
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host, _ = os.Hostname()
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(o.port)))
+}
+
+// newServeCommand creates the serve subcommand, which only serves the web UI
+func newServeCommand() *cobra.Command {
+	opts := &serveOptions{}
+	var resultsDirs []string
+	var s3Endpoint, signingKeyFile string
+	var s3SkipTLS, enableRuns, enableRetention, enableAnnotations bool
+	var rootTimeout time.Duration
+	var runsDir string
+
+	cmd := &cobra.Command{
+		Use:     "serve",
+		Aliases: []string{"webui"},
+		Short:   "Start the web UI server to view mirroring metrics",
+		Long: "Starts a web server that displays mirroring metrics from test results in a browser-based dashboard. " +
+			"Repeat --results-dir to browse several results roots, such as NFS mounts from different runners, in one listing. " +
+			"With --enable-runs, the dashboard also starts runs on this host from a Start Run form (POST /api/runs). " +
+			"With --enable-annotations, tags and notes can be added to results files (POST /api/annotations/<file>). " +
+			"With --enable-retention, results can be deleted (DELETE /api/results/<file>) and archived (POST /api/archive with {\"older_than\": \"30d\"}). " +
+			"To watch a run live, use run --with-ui instead.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var signingKey []byte
+			if signingKeyFile != "" {
+				key, err := integrity.LoadKey(signingKeyFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				signingKey = key
+			}
+
+			server, err := opts.newServer(cmd, resultsDirs[0], signingKey)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			s3Options := store.S3OptionsFromEnv()
+			if s3Endpoint != "" {
+				s3Options.Endpoint = s3Endpoint
+			}
+			s3Options.SkipTLS = s3SkipTLS
+			if len(resultsDirs) > 1 {
+				backend, err := openFederated(resultsDirs, s3Options, rootTimeout)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				server.SetBackend(backend)
+			} else if store.IsRemote(resultsDirs[0]) {
+				backend, err := store.NewS3(resultsDirs[0], s3Options)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				server.SetBackend(backend)
+			}
+			if enableRuns {
+				controller, err := newRunController(resultsDirs, runsDir)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				server.SetRunController(controller.RunsHandler(), controller.DashboardRuns)
+			}
+			server.SetRetention(enableRetention)
+			server.SetAnnotations(enableAnnotations)
+
+			if err := server.Start(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	opts.addFlags(cmd)
+	cmd.Flags().StringArrayVar(&resultsDirs, "results-dir", []string{runner.DefaultResultsDir}, "Directory containing test results JSON files, or s3://bucket/prefix to read results from an object store. "+
+		"Repeat to merge several roots into one listing, each labelled label=location or by its last path element")
+	cmd.Flags().DurationVar(&rootTimeout, "results-root-timeout", store.DefaultRootTimeout, "Time a results root may take to answer before it is listed as unavailable, with several --results-dir")
+	cmd.Flags().StringVar(&s3Endpoint, "s3-endpoint", "", "S3-compatible endpoint for s3:// results, e.g. https://minio.lab:9000 (default: AWS S3) [env "+store.EnvS3Endpoint+"]")
+	cmd.Flags().BoolVar(&s3SkipTLS, "s3-skip-tls", false, "Skip TLS verification for the S3 endpoint")
+	cmd.Flags().StringVar(&signingKeyFile, "signing-key-file", "", "Key file used to verify results signatures")
+	cmd.Flags().BoolVar(&enableRuns, "enable-runs", false, "Let the dashboard start, follow and cancel runs on this host; use with --auth-user")
+	cmd.Flags().BoolVar(&enableAnnotations, "enable-annotations", false, "Let the dashboard and API add tags and notes to results files; use with --auth-user")
+	cmd.Flags().BoolVar(&enableRetention, "enable-retention", false, "Let the dashboard and API delete results files and archive old ones; use with --auth-user")
+	cmd.Flags().StringVar(&runsDir, "runs-dir", api.DefaultRunsDir, "Directory keeping the scenario, log and heartbeat of each run started from the dashboard")
+	return cmd
+}
+
+// newRunController creates the control API runs of the dashboard execute
+// with: this binary, in the current directory, writing results where the
+// dashboard reads them
+func newRunController(resultsDirs []string, runsDir string) (*api.Server, error) {
+	if len(resultsDirs) > 1 || store.IsRemote(resultsDirs[0]) {
+		return nil, fmt.Errorf("--enable-runs needs a single local --results-dir")
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the oc-mirror-test binary: %w", err)
+	}
+	workDir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	controller := api.NewServer(0, executable, workDir, runsDir)
+	controller.SetRunArgs("--results-dir", resultsDirs[0])
+	return controller, nil
+}
+
+// openFederated opens every results root of specs, given as label=location
+// or location, and merges them into one store
+func openFederated(specs []string, s3Options store.S3Options, timeout time.Duration) (*store.Federated, error) {
+	var roots []store.Root
+	for _, spec := range specs {
+		label, location := store.ParseRoot(spec)
+		backend, err := store.Open(location, s3Options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open results root %s: %w", spec, err)
+		}
+		roots = append(roots, store.Root{Label: label, Store: backend})
+	}
+	federated, err := store.NewFederated(roots)
+	if err != nil {
+		return nil, err
+	}
+	federated.SetTimeout(timeout)
+	return federated, nil
+}
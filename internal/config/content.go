@@ -0,0 +1,347 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Content scenario names
+const (
+	ContentOperators        = "operators"
+	ContentAdditionalImages = "additional-images"
+	ContentHelm             = "helm"
+	ContentMixed            = "mixed"
+)
+
+// ContentSpec describes what goes into the mirror section of the imageset configuration
+type ContentSpec struct {
+	Operators        bool              `yaml:"operators" json:"operators"`                   // Include the default operator catalog
+	Catalogs         []OperatorCatalog `yaml:"catalogs,omitempty" json:"catalogs,omitempty"` // Explicit operator catalogs and packages
+	AdditionalImages []string          `yaml:"additionalImages,omitempty" json:"additional_images,omitempty"`
+	Helm             HelmSpec          `yaml:"helm,omitempty" json:"helm,omitempty"`
+}
+
+// OperatorCatalog is an operator index image and the packages to mirror from it
+type OperatorCatalog struct {
+	Catalog  string            `yaml:"catalog" json:"catalog"`
+	Packages []OperatorPackage `yaml:"packages" json:"packages"`
+}
+
+// OperatorPackage is an operator package with optional channel and version pinning
+type OperatorPackage struct {
+	Name           string            `yaml:"name" json:"name"`
+	DefaultChannel string            `yaml:"defaultChannel,omitempty" json:"default_channel,omitempty"`
+	Channels       []OperatorChannel `yaml:"channels,omitempty" json:"channels,omitempty"`
+}
+
+// OperatorChannel is a package channel with an optional version range
+type OperatorChannel struct {
+	Name       string `yaml:"name" json:"name"`
+	MinVersion string `yaml:"minVersion,omitempty" json:"min_version,omitempty"`
+	MaxVersion string `yaml:"maxVersion,omitempty" json:"max_version,omitempty"`
+}
+
+// HelmSpec describes the helm section of the imageset configuration
+type HelmSpec struct {
+	Repositories []HelmRepository `yaml:"repositories,omitempty" json:"repositories,omitempty"`
+	Local        []HelmLocalChart `yaml:"local,omitempty" json:"local,omitempty"`
+}
+
+// HelmRepository is a remote chart repository and the charts to mirror from it
+type HelmRepository struct {
+	Name   string      `yaml:"name" json:"name"`
+	URL    string      `yaml:"url" json:"url"`
+	Charts []HelmChart `yaml:"charts" json:"charts"`
+}
+
+// HelmChart is a chart name and version
+type HelmChart struct {
+	Name    string `yaml:"name" json:"name"`
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+}
+
+// HelmLocalChart is a chart archive on the local filesystem
+type HelmLocalChart struct {
+	Name string `yaml:"name" json:"name"`
+	Path string `yaml:"path" json:"path"`
+}
+
+// defaultAdditionalImages are mirrored by the additional-images and mixed scenarios
+var defaultAdditionalImages = []string{
+	"registry.redhat.io/ubi9/ubi:latest",
+	"registry.redhat.io/ubi9/ubi-minimal:latest",
+	"registry.redhat.io/rhel9/support-tools:latest",
+}
+
+// defaultHelmRepositories are mirrored by the helm and mixed scenarios
+var defaultHelmRepositories = []HelmRepository{
+	{
+		Name: "openshift-charts",
+		URL:  "https://charts.openshift.io/",
+		Charts: []HelmChart{
+			{Name: "redhat-developer-hub", Version: "1.4.0"},
+		},
+	},
+}
+
+// RedHatOperatorCatalog returns the Red Hat operator index image for an OpenShift version (e.g. 4.19)
+func RedHatOperatorCatalog(ocpVersion string) string {
+	return "registry.redhat.io/redhat/redhat-operator-index:v" + strings.TrimPrefix(ocpVersion, "v")
+}
+
+// ContentScenarios returns the names of the built-in content scenarios
+func ContentScenarios() []string {
+	return []string{ContentOperators, ContentAdditionalImages, ContentHelm, ContentMixed}
+}
+
+// DefaultContent returns the operator-only content used by the standard test
+func DefaultContent() *ContentSpec {
+	return &ContentSpec{Operators: true}
+}
+
+// ContentForScenario returns the built-in content for a scenario name
+func ContentForScenario(name string) (*ContentSpec, error) {
+	switch name {
+	case "", ContentOperators:
+		return DefaultContent(), nil
+	case ContentAdditionalImages:
+		return &ContentSpec{
+			AdditionalImages: append([]string(nil), defaultAdditionalImages...),
+		}, nil
+	case ContentHelm:
+		return &ContentSpec{
+			Helm: HelmSpec{Repositories: copyRepositories(defaultHelmRepositories)},
+		}, nil
+	case ContentMixed:
+		return &ContentSpec{
+			Operators:        true,
+			AdditionalImages: append([]string(nil), defaultAdditionalImages...),
+			Helm:             HelmSpec{Repositories: copyRepositories(defaultHelmRepositories)},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown content scenario %q (valid: %s)", name, strings.Join(ContentScenarios(), ", "))
+	}
+}
+
+// LoadContentFile reads a content specification from a YAML file
+func LoadContentFile(path string) (*ContentSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content file: %w", err)
+	}
+
+	content := &ContentSpec{}
+	if err := yaml.Unmarshal(data, content); err != nil {
+		return nil, fmt.Errorf("failed to parse content file %s: %w", path, err)
+	}
+	if err := content.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid content file %s: %w", path, err)
+	}
+	return content, nil
+}
+
+// AddHelmChart adds a chart from a "<repo-url>/<chart>@<version>" reference,
+// grouping charts from the same repository
+func (c *ContentSpec) AddHelmChart(ref string) error {
+	chartRef, version, _ := strings.Cut(ref, "@")
+	idx := strings.LastIndex(chartRef, "/")
+	if idx <= 0 || idx == len(chartRef)-1 || !strings.Contains(chartRef, "://") {
+		return fmt.Errorf("invalid helm chart reference %q (expected <repo-url>/<chart>@<version>)", ref)
+	}
+	repoURL := chartRef[:idx+1]
+	chart := HelmChart{Name: chartRef[idx+1:], Version: version}
+
+	for i := range c.Helm.Repositories {
+		if strings.TrimRight(c.Helm.Repositories[i].URL, "/") == strings.TrimRight(repoURL, "/") {
+			c.Helm.Repositories[i].Charts = append(c.Helm.Repositories[i].Charts, chart)
+			return nil
+		}
+	}
+
+	c.Helm.Repositories = append(c.Helm.Repositories, HelmRepository{
+		Name:   repoNameFromURL(repoURL),
+		URL:    repoURL,
+		Charts: []HelmChart{chart},
+	})
+	return nil
+}
+
+// SampleOperator returns an operator package of the content and the catalog it
+// is mirrored from, to deploy as a sample workload: the first package of the
+// explicit catalogs, otherwise a package of the default catalog
+func (c *ContentSpec) SampleOperator() (string, OperatorPackage, bool) {
+	for _, catalog := range c.Catalogs {
+		if len(catalog.Packages) > 0 {
+			return catalog.Catalog, catalog.Packages[0], true
+		}
+	}
+	if c.Operators {
+		return defaultSampleCatalog, OperatorPackage{Name: defaultSampleOperator, DefaultChannel: defaultSampleChannel}, true
+	}
+	return "", OperatorPackage{}, false
+}
+
+// OperatorCatalogs returns the operator catalogs of the content with their
+// packages, the default catalog first when included
+func (c *ContentSpec) OperatorCatalogs() ([]OperatorCatalog, error) {
+	var catalogs []OperatorCatalog
+	if c.Operators {
+		if err := yaml.Unmarshal([]byte(defaultOperatorCatalog), &catalogs); err != nil {
+			return nil, fmt.Errorf("failed to parse the default operator catalog: %w", err)
+		}
+	}
+	return append(catalogs, c.Catalogs...), nil
+}
+
+// IsEmpty returns true if nothing would be mirrored
+func (c *ContentSpec) IsEmpty() bool {
+	return !c.Operators && len(c.Catalogs) == 0 && len(c.AdditionalImages) == 0 &&
+		len(c.Helm.Repositories) == 0 && len(c.Helm.Local) == 0
+}
+
+// Validate checks that the content can be rendered into a usable configuration
+func (c *ContentSpec) Validate() error {
+	if c.IsEmpty() {
+		return fmt.Errorf("no content to mirror (operators, catalogs, additionalImages or helm required)")
+	}
+	for _, catalog := range c.Catalogs {
+		if catalog.Catalog == "" {
+			return fmt.Errorf("operator catalog requires an image")
+		}
+		if len(catalog.Packages) == 0 {
+			return fmt.Errorf("operator catalog %s has no packages", catalog.Catalog)
+		}
+		for _, pkg := range catalog.Packages {
+			if pkg.Name == "" {
+				return fmt.Errorf("operator package in %s requires a name", catalog.Catalog)
+			}
+			for _, channel := range pkg.Channels {
+				if channel.Name == "" {
+					return fmt.Errorf("channel of operator package %s requires a name", pkg.Name)
+				}
+			}
+		}
+	}
+	for _, repo := range c.Helm.Repositories {
+		if repo.Name == "" || repo.URL == "" {
+			return fmt.Errorf("helm repository requires name and url")
+		}
+		if len(repo.Charts) == 0 {
+			return fmt.Errorf("helm repository %s has no charts", repo.Name)
+		}
+	}
+	for _, chart := range c.Helm.Local {
+		if chart.Name == "" || chart.Path == "" {
+			return fmt.Errorf("local helm chart requires name and path")
+		}
+	}
+	return nil
+}
+
+// Kinds returns the content types included, for reporting
+func (c *ContentSpec) Kinds() []string {
+	var kinds []string
+	if c.Operators || len(c.Catalogs) > 0 {
+		kinds = append(kinds, "operators")
+	}
+	if len(c.AdditionalImages) > 0 {
+		kinds = append(kinds, "additionalImages")
+	}
+	if len(c.Helm.Repositories) > 0 || len(c.Helm.Local) > 0 {
+		kinds = append(kinds, "helm")
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// render returns the body of the mirror section
+func (c *ContentSpec) render() string {
+	var b strings.Builder
+
+	if c.Operators || len(c.Catalogs) > 0 {
+		b.WriteString("  operators:\n")
+	}
+	if c.Operators {
+		b.WriteString(defaultOperatorCatalog)
+	}
+	for _, catalog := range c.Catalogs {
+		fmt.Fprintf(&b, "    - catalog: %s\n", catalog.Catalog)
+		b.WriteString("      packages:\n")
+		for _, pkg := range catalog.Packages {
+			fmt.Fprintf(&b, "        - name: %s\n", pkg.Name)
+			if pkg.DefaultChannel != "" {
+				fmt.Fprintf(&b, "          defaultChannel: %s\n", pkg.DefaultChannel)
+			}
+			if len(pkg.Channels) == 0 {
+				continue
+			}
+			b.WriteString("          channels:\n")
+			for _, channel := range pkg.Channels {
+				fmt.Fprintf(&b, "            - name: %s\n", channel.Name)
+				if channel.MinVersion != "" {
+					fmt.Fprintf(&b, "              minVersion: %s\n", channel.MinVersion)
+				}
+				if channel.MaxVersion != "" {
+					fmt.Fprintf(&b, "              maxVersion: %s\n", channel.MaxVersion)
+				}
+			}
+		}
+	}
+
+	if len(c.AdditionalImages) > 0 {
+		b.WriteString("  additionalImages:\n")
+		for _, image := range c.AdditionalImages {
+			fmt.Fprintf(&b, "    - name: %s\n", image)
+		}
+	}
+
+	if len(c.Helm.Repositories) > 0 || len(c.Helm.Local) > 0 {
+		b.WriteString("  helm:\n")
+		if len(c.Helm.Repositories) > 0 {
+			b.WriteString("    repositories:\n")
+			for _, repo := range c.Helm.Repositories {
+				fmt.Fprintf(&b, "      - name: %s\n", repo.Name)
+				fmt.Fprintf(&b, "        url: %s\n", repo.URL)
+				b.WriteString("        charts:\n")
+				for _, chart := range repo.Charts {
+					fmt.Fprintf(&b, "          - name: %s\n", chart.Name)
+					if chart.Version != "" {
+						fmt.Fprintf(&b, "            version: %s\n", chart.Version)
+					}
+				}
+			}
+		}
+		if len(c.Helm.Local) > 0 {
+			b.WriteString("    local:\n")
+			for _, chart := range c.Helm.Local {
+				fmt.Fprintf(&b, "      - name: %s\n", chart.Name)
+				fmt.Fprintf(&b, "        path: %s\n", chart.Path)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func copyRepositories(repos []HelmRepository) []HelmRepository {
+	out := make([]HelmRepository, len(repos))
+	for i, repo := range repos {
+		out[i] = repo
+		out[i].Charts = append([]HelmChart(nil), repo.Charts...)
+	}
+	return out
+}
+
+// repoNameFromURL derives a repository name from its host, e.g. charts.openshift.io -> charts-openshift-io
+func repoNameFromURL(repoURL string) string {
+	host := repoURL
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+	host, _, _ = strings.Cut(host, "/")
+	return strings.NewReplacer(".", "-", ":", "-").Replace(host)
+}
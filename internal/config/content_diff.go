@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContentChanges lists how content to differs from content from: added and
+// removed packages, channel version ranges, images and charts
+func ContentChanges(from, to *ContentSpec) []string {
+	var changes []string
+	if from.Operators != to.Operators {
+		changes = append(changes, fmt.Sprintf("default operator catalog: %t -> %t", from.Operators, to.Operators))
+	}
+
+	before := contentPackages(from)
+	after := contentPackages(to)
+	for _, key := range after.keys {
+		pkg := after.packages[key]
+		old, ok := before.packages[key]
+		if !ok {
+			changes = append(changes, "added package "+key)
+			continue
+		}
+		if old.DefaultChannel != pkg.DefaultChannel {
+			changes = append(changes, fmt.Sprintf("%s default channel: %s -> %s", key, valueOrNone(old.DefaultChannel), valueOrNone(pkg.DefaultChannel)))
+		}
+		changes = append(changes, channelChanges(key, old.Channels, pkg.Channels)...)
+	}
+	for _, key := range before.keys {
+		if _, ok := after.packages[key]; !ok {
+			changes = append(changes, "removed package "+key)
+		}
+	}
+
+	changes = append(changes, listChanges("image", from.AdditionalImages, to.AdditionalImages)...)
+	changes = append(changes, listChanges("helm chart", helmCharts(from), helmCharts(to))...)
+	return changes
+}
+
+// packageSet indexes the packages of a content by catalog/package, in content order
+type packageSet struct {
+	keys     []string
+	packages map[string]OperatorPackage
+}
+
+func contentPackages(c *ContentSpec) packageSet {
+	set := packageSet{packages: make(map[string]OperatorPackage)}
+	for _, catalog := range c.Catalogs {
+		for _, pkg := range catalog.Packages {
+			key := catalog.Catalog + "/" + pkg.Name
+			if _, ok := set.packages[key]; !ok {
+				set.keys = append(set.keys, key)
+			}
+			set.packages[key] = pkg
+		}
+	}
+	return set
+}
+
+// channelChanges compares the channels and version ranges of a package
+func channelChanges(pkg string, from, to []OperatorChannel) []string {
+	var changes []string
+	old := make(map[string]OperatorChannel, len(from))
+	for _, channel := range from {
+		old[channel.Name] = channel
+	}
+	current := make(map[string]bool, len(to))
+	for _, channel := range to {
+		current[channel.Name] = true
+		previous, ok := old[channel.Name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("%s added channel %s %s", pkg, channel.Name, versionRange(channel)))
+		case previous.MinVersion != channel.MinVersion || previous.MaxVersion != channel.MaxVersion:
+			changes = append(changes, fmt.Sprintf("%s channel %s: %s -> %s", pkg, channel.Name, versionRange(previous), versionRange(channel)))
+		}
+	}
+	for _, channel := range from {
+		if !current[channel.Name] {
+			changes = append(changes, fmt.Sprintf("%s removed channel %s", pkg, channel.Name))
+		}
+	}
+	return changes
+}
+
+// listChanges returns the values added to and removed from a list
+func listChanges(kind string, from, to []string) []string {
+	var changes []string
+	old := make(map[string]bool, len(from))
+	for _, value := range from {
+		old[value] = true
+	}
+	current := make(map[string]bool, len(to))
+	for _, value := range to {
+		current[value] = true
+		if !old[value] {
+			changes = append(changes, fmt.Sprintf("added %s %s", kind, value))
+		}
+	}
+	for _, value := range from {
+		if !current[value] {
+			changes = append(changes, fmt.Sprintf("removed %s %s", kind, value))
+		}
+	}
+	return changes
+}
+
+// helmCharts returns the charts of a content as repository/chart:version
+func helmCharts(c *ContentSpec) []string {
+	var charts []string
+	for _, repo := range c.Helm.Repositories {
+		for _, chart := range repo.Charts {
+			ref := repo.Name + "/" + chart.Name
+			if chart.Version != "" {
+				ref += ":" + chart.Version
+			}
+			charts = append(charts, ref)
+		}
+	}
+	for _, chart := range c.Helm.Local {
+		charts = append(charts, chart.Path)
+	}
+	return charts
+}
+
+// versionRange formats the version range of a channel, e.g. [4.15.0, 4.15.1]
+func versionRange(channel OperatorChannel) string {
+	if channel.MinVersion == "" && channel.MaxVersion == "" {
+		return "(all versions)"
+	}
+	return "[" + strings.Join([]string{valueOrNone(channel.MinVersion), valueOrNone(channel.MaxVersion)}, ", ") + "]"
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContentSelection names part of a content: operator packages of its explicit
+// catalogs, additional images and helm charts
+type ContentSelection struct {
+	Packages []string `yaml:"packages,omitempty" json:"packages,omitempty"`
+	Images   []string `yaml:"images,omitempty" json:"images,omitempty"`
+	Charts   []string `yaml:"charts,omitempty" json:"charts,omitempty"` // Chart names of repositories and local charts
+}
+
+// IsEmpty returns true if nothing is selected
+func (s ContentSelection) IsEmpty() bool {
+	return len(s.Packages) == 0 && len(s.Images) == 0 && len(s.Charts) == 0
+}
+
+// Select splits the content into the part named by the selection and the
+// rest. Every selected item must be part of the content; the default operator
+// catalog always stays in the rest.
+func (c *ContentSpec) Select(sel ContentSelection) (selected, rest *ContentSpec, err error) {
+	selected = &ContentSpec{}
+	rest = &ContentSpec{Operators: c.Operators}
+	var missing []string
+
+	packages := toSet(sel.Packages)
+	for _, catalog := range c.Catalogs {
+		in := OperatorCatalog{Catalog: catalog.Catalog}
+		out := OperatorCatalog{Catalog: catalog.Catalog}
+		for _, pkg := range catalog.Packages {
+			if packages[pkg.Name] {
+				in.Packages = append(in.Packages, pkg)
+				delete(packages, pkg.Name)
+			} else {
+				out.Packages = append(out.Packages, pkg)
+			}
+		}
+		if len(in.Packages) > 0 {
+			selected.Catalogs = append(selected.Catalogs, in)
+		}
+		if len(out.Packages) > 0 {
+			rest.Catalogs = append(rest.Catalogs, out)
+		}
+	}
+	missing = appendMissing(missing, "package", sel.Packages, packages)
+
+	images := toSet(sel.Images)
+	for _, image := range c.AdditionalImages {
+		if images[image] {
+			selected.AdditionalImages = append(selected.AdditionalImages, image)
+			delete(images, image)
+		} else {
+			rest.AdditionalImages = append(rest.AdditionalImages, image)
+		}
+	}
+	missing = appendMissing(missing, "image", sel.Images, images)
+
+	charts := toSet(sel.Charts)
+	for _, repo := range c.Helm.Repositories {
+		in := HelmRepository{Name: repo.Name, URL: repo.URL}
+		out := HelmRepository{Name: repo.Name, URL: repo.URL}
+		for _, chart := range repo.Charts {
+			if charts[chart.Name] {
+				in.Charts = append(in.Charts, chart)
+			} else {
+				out.Charts = append(out.Charts, chart)
+			}
+		}
+		if len(in.Charts) > 0 {
+			selected.Helm.Repositories = append(selected.Helm.Repositories, in)
+		}
+		if len(out.Charts) > 0 {
+			rest.Helm.Repositories = append(rest.Helm.Repositories, out)
+		}
+	}
+	for _, chart := range c.Helm.Local {
+		if charts[chart.Name] {
+			selected.Helm.Local = append(selected.Helm.Local, chart)
+		} else {
+			rest.Helm.Local = append(rest.Helm.Local, chart)
+		}
+	}
+	for _, name := range selected.chartNames() {
+		delete(charts, name)
+	}
+	missing = appendMissing(missing, "chart", sel.Charts, charts)
+
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("not part of the content: %s", strings.Join(missing, ", "))
+	}
+	return selected, rest, nil
+}
+
+// chartNames returns the names of the repository and local charts
+func (c *ContentSpec) chartNames() []string {
+	var names []string
+	for _, repo := range c.Helm.Repositories {
+		for _, chart := range repo.Charts {
+			names = append(names, chart.Name)
+		}
+	}
+	for _, chart := range c.Helm.Local {
+		names = append(names, chart.Name)
+	}
+	return names
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+// appendMissing adds the values still left in unmatched, in selection order
+func appendMissing(missing []string, kind string, values []string, unmatched map[string]bool) []string {
+	for _, value := range values {
+		if unmatched[value] {
+			missing = append(missing, kind+" "+value)
+			delete(unmatched, value)
+		}
+	}
+	return missing
+}
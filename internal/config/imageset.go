@@ -2,24 +2,15 @@ package config
 
 import "os"
 
-// CreateImageSetConfig creates the imageset configuration file
-func CreateImageSetConfig(configPath string) error {
-	return CreateImageSetConfigWithVersion(configPath, "v2alpha1")
-}
+// Package of the default operator catalog deployed as a sample workload
+const (
+	defaultSampleCatalog  = "registry.redhat.io/redhat/redhat-operator-index:v4.19"
+	defaultSampleOperator = "local-storage-operator"
+	defaultSampleChannel  = "stable"
+)
 
-// CreateImageSetConfigWithVersion creates the imageset configuration file with specified API version
-func CreateImageSetConfigWithVersion(configPath string, apiVersion string) error {
-	// Default to v2alpha1 if not specified
-	if apiVersion == "" {
-		apiVersion = "v2alpha1"
-	}
-	
-	configContent := `---
-apiVersion: mirror.openshift.io/` + apiVersion + `
-kind: ImageSetConfiguration
-mirror:
-  operators:
-    - catalog: registry.redhat.io/redhat/redhat-operator-index:v4.19
+// defaultOperatorCatalog is the operator catalog mirrored by the default scenario
+const defaultOperatorCatalog = `    - catalog: registry.redhat.io/redhat/redhat-operator-index:v4.19
       packages:
         - name: local-storage-operator
           channels:
@@ -92,98 +83,69 @@ mirror:
             - name: stable-6.4
 `
 
-	return os.WriteFile(configPath, []byte(configContent), 0644)
+// CreateImageSetConfig creates the imageset configuration file
+func CreateImageSetConfig(configPath string) error {
+	return CreateImageSetConfigWithVersion(configPath, "v2alpha1")
 }
 
-// CreatePlatformConfig creates the platform configuration file for upload
-func CreatePlatformConfig(path string) error {
-	return CreatePlatformConfigWithVersion(path, "v2alpha1")
+// CreateImageSetConfigWithVersion creates the imageset configuration file with specified API version
+func CreateImageSetConfigWithVersion(configPath string, apiVersion string) error {
+	return CreateImageSetConfigForContent(configPath, apiVersion, DefaultContent())
 }
 
-// CreatePlatformConfigWithVersion creates the platform configuration file with specified API version
-func CreatePlatformConfigWithVersion(path string, apiVersion string) error {
+// CreateImageSetConfigForContent creates the imageset configuration file for the given content
+func CreateImageSetConfigForContent(configPath string, apiVersion string, content *ContentSpec) error {
+	return os.WriteFile(configPath, []byte(RenderImageSetConfig(apiVersion, content)), 0644)
+}
+
+// RenderImageSetConfig returns the imageset configuration for the given content
+func RenderImageSetConfig(apiVersion string, content *ContentSpec) string {
 	// Default to v2alpha1 if not specified
 	if apiVersion == "" {
 		apiVersion = "v2alpha1"
 	}
-	
-	configContent := `---
+	if content == nil {
+		content = DefaultContent()
+	}
+
+	return `---
 apiVersion: mirror.openshift.io/` + apiVersion + `
 kind: ImageSetConfiguration
 mirror:
-  operators:
-    - catalog: registry.redhat.io/redhat/redhat-operator-index:v4.19
-      packages:
-        - name: local-storage-operator
-          channels:
-            - name: stable
-              minVersion: 4.19.0-202510142112
-              maxVersion: 4.19.0-202510142112
-        - name: odf-operator
-          channels:
-            - name: stable-4.19
-              minVersion: 4.19.6-rhodf
-              maxVersion: 4.19.6-rhodf
-        - name: odf-dependencies
-          channels:
-            - name: stable-4.19
-              minVersion: 4.19.6-rhodf
-              maxVersion: 4.19.6-rhodf
-        - name: cephcsi-operator
-          channels:
-            - name: stable-4.19
-              minVersion: 4.19.6-rhodf
-              maxVersion: 4.19.6-rhodf
-        - name: mcg-operator
-          channels:
-            - name: stable-4.19
-              minVersion: 4.19.6-rhodf
-              maxVersion: 4.19.6-rhodf
-        - name: ocs-client-operator
-          channels:
-            - name: stable-4.19
-              minVersion: 4.19.6-rhodf
-              maxVersion: 4.19.6-rhodf
-        - name: ocs-operator
-          channels:
-            - name: stable-4.19
-              minVersion: 4.19.6-rhodf
-              maxVersion: 4.19.6-rhodf
-        - name: odf-csi-addons-operator
-          channels:
-            - name: stable-4.19
-              minVersion: 4.19.6-rhodf
-              maxVersion: 4.19.6-rhodf
-        - name: odf-prometheus-operator
-          channels:
-            - name: stable-4.19
-              minVersion: 4.19.6-rhodf
-              maxVersion: 4.19.6-rhodf
-        - name: rook-ceph-operator
-          channels:
-            - name: stable-4.19
-              minVersion: 4.19.6-rhodf
-              maxVersion: 4.19.6-rhodf
-        - name: recipe
-          channels:
-            - name: stable-4.19
-              minVersion: 4.19.6-rhodf
-              maxVersion: 4.19.6-rhodf
-        - name: cluster-logging
-          defaultChannel: stable-6.3
-          channels:
-            - name: stable-6.3
-              minVersion: 6.3.1
-              maxVersion: 6.3.1
-            - name: stable-6.4
-        - name: loki-operator
-          defaultChannel: stable-6.3
-          channels:
-            - name: stable-6.3
-              minVersion: 6.3.1
-              maxVersion: 6.3.1
-            - name: stable-6.4
-`
+` + content.render()
+}
+
+// CreatePlatformConfig creates the platform configuration file for upload
+func CreatePlatformConfig(path string) error {
+	return CreatePlatformConfigWithVersion(path, "v2alpha1")
+}
+
+// CreatePlatformConfigWithVersion creates the platform configuration file with specified API version
+func CreatePlatformConfigWithVersion(path string, apiVersion string) error {
+	return CreatePlatformConfigForContent(path, apiVersion, DefaultContent())
+}
+
+// CreatePlatformConfigForContent creates the platform configuration file for upload of the given content
+func CreatePlatformConfigForContent(path string, apiVersion string, content *ContentSpec) error {
+	return CreateImageSetConfigForContent(path, apiVersion, content)
+}
+
+// CreateDeleteImageSetConfigForContent creates a DeleteImageSetConfiguration
+// removing the given content from the registry (oc-mirror v2 only)
+func CreateDeleteImageSetConfigForContent(path string, content *ContentSpec) error {
+	return os.WriteFile(path, []byte(RenderDeleteImageSetConfig(content)), 0644)
+}
+
+// RenderDeleteImageSetConfig returns the DeleteImageSetConfiguration for the
+// given content. The delete section uses the same structure as the mirror section.
+func RenderDeleteImageSetConfig(content *ContentSpec) string {
+	if content == nil {
+		content = DefaultContent()
+	}
 
-	return os.WriteFile(path, []byte(configContent), 0644)
+	return `---
+apiVersion: mirror.openshift.io/v2alpha1
+kind: DeleteImageSetConfiguration
+delete:
+` + content.render()
 }
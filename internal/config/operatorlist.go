@@ -0,0 +1,197 @@
+package config
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// operatorListColumns are the recognized operator list columns, in default order
+var operatorListColumns = []string{"package", "channel", "minversion", "maxversion", "catalog"}
+
+// ParseOperatorList reads a flat operator list and returns the content to mirror.
+// Each line holds package, channel, minVersion and maxVersion separated by commas
+// or whitespace; only the package is required. An optional header line names the
+// columns, and a "catalog" column overrides defaultCatalog per line. Blank lines
+// and lines starting with # are ignored. Repeated packages collect their channels.
+func ParseOperatorList(r io.Reader, defaultCatalog string) (*ContentSpec, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operator list: %w", err)
+	}
+
+	content := &ContentSpec{}
+	columns := operatorListColumns
+	headerAllowed := true
+
+	for lineNum, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields, err := splitOperatorListLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum+1, err)
+		}
+
+		if headerAllowed {
+			headerAllowed = false
+			if header, ok := parseOperatorListHeader(fields); ok {
+				columns = header
+				continue
+			}
+		}
+
+		if len(fields) > len(columns) {
+			return nil, fmt.Errorf("line %d: expected at most %d columns (%s), got %d",
+				lineNum+1, len(columns), strings.Join(columns, ", "), len(fields))
+		}
+		values := make(map[string]string, len(columns))
+		for i, field := range fields {
+			values[columns[i]] = field
+		}
+
+		if values["package"] == "" {
+			return nil, fmt.Errorf("line %d: package name is required", lineNum+1)
+		}
+		if values["channel"] == "" && (values["minversion"] != "" || values["maxversion"] != "") {
+			return nil, fmt.Errorf("line %d: version range for %s requires a channel", lineNum+1, values["package"])
+		}
+
+		catalog := values["catalog"]
+		if catalog == "" {
+			catalog = defaultCatalog
+		}
+		if catalog == "" {
+			return nil, fmt.Errorf("line %d: no catalog for %s (set a catalog column or default catalog)", lineNum+1, values["package"])
+		}
+
+		content.addOperatorChannel(catalog, values["package"], OperatorChannel{
+			Name:       values["channel"],
+			MinVersion: values["minversion"],
+			MaxVersion: values["maxversion"],
+		})
+	}
+
+	if len(content.Catalogs) == 0 {
+		return nil, fmt.Errorf("operator list contains no packages")
+	}
+	if err := content.Validate(); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// LoadOperatorList reads an operator list file; see ParseOperatorList for the format
+func LoadOperatorList(path, defaultCatalog string) (*ContentSpec, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open operator list: %w", err)
+	}
+	defer file.Close()
+
+	content, err := ParseOperatorList(file, defaultCatalog)
+	if err != nil {
+		return nil, fmt.Errorf("invalid operator list %s: %w", path, err)
+	}
+	return content, nil
+}
+
+// ConvertOperatorList converts an operator list file into an ImageSetConfiguration
+func ConvertOperatorList(listPath, configPath, apiVersion, defaultCatalog string) (*ContentSpec, error) {
+	content, err := LoadOperatorList(listPath, defaultCatalog)
+	if err != nil {
+		return nil, err
+	}
+	if err := CreateImageSetConfigForContent(configPath, apiVersion, content); err != nil {
+		return nil, fmt.Errorf("failed to write imageset configuration: %w", err)
+	}
+	return content, nil
+}
+
+// addOperatorChannel adds a package channel, grouping by catalog and package.
+// An empty channel name mirrors the package's default channel.
+func (c *ContentSpec) addOperatorChannel(catalog, pkgName string, channel OperatorChannel) {
+	var cat *OperatorCatalog
+	for i := range c.Catalogs {
+		if c.Catalogs[i].Catalog == catalog {
+			cat = &c.Catalogs[i]
+			break
+		}
+	}
+	if cat == nil {
+		c.Catalogs = append(c.Catalogs, OperatorCatalog{Catalog: catalog})
+		cat = &c.Catalogs[len(c.Catalogs)-1]
+	}
+
+	var pkg *OperatorPackage
+	for i := range cat.Packages {
+		if cat.Packages[i].Name == pkgName {
+			pkg = &cat.Packages[i]
+			break
+		}
+	}
+	if pkg == nil {
+		cat.Packages = append(cat.Packages, OperatorPackage{Name: pkgName})
+		pkg = &cat.Packages[len(cat.Packages)-1]
+	}
+
+	if channel.Name == "" {
+		return
+	}
+	for _, existing := range pkg.Channels {
+		if existing == channel {
+			return
+		}
+	}
+	pkg.Channels = append(pkg.Channels, channel)
+}
+
+// splitOperatorListLine splits a CSV line, or a whitespace-separated line when it has no commas
+func splitOperatorListLine(line string) ([]string, error) {
+	if !strings.Contains(line, ",") {
+		return strings.Fields(line), nil
+	}
+	reader := csv.NewReader(strings.NewReader(line))
+	reader.TrimLeadingSpace = true
+	fields, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	// Trailing empty columns (e.g. "odf-operator,stable,,") are allowed
+	for len(fields) > 0 && strings.TrimSpace(fields[len(fields)-1]) == "" {
+		fields = fields[:len(fields)-1]
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	return fields, nil
+}
+
+// parseOperatorListHeader returns the column order if fields form a header line
+func parseOperatorListHeader(fields []string) ([]string, bool) {
+	columns := make([]string, 0, len(fields))
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		name := strings.ToLower(strings.NewReplacer("_", "", "-", "", " ", "").Replace(field))
+		if name == "name" {
+			name = "package"
+		}
+		known := false
+		for _, column := range operatorListColumns {
+			if name == column {
+				known = true
+				break
+			}
+		}
+		if !known || seen[name] {
+			return nil, false
+		}
+		seen[name] = true
+		columns = append(columns, name)
+	}
+	return columns, seen["package"]
+}
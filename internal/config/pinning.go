@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PinnedImage is an image reference and the digest reference it was pinned to
+type PinnedImage struct {
+	Image  string `json:"image"`
+	Pinned string `json:"pinned"`
+}
+
+// PinDigests returns a copy of the content with the operator catalogs,
+// including the default catalog, and the additional images referenced by the
+// digest resolve returns for their tag. References that already carry a
+// digest are kept; helm charts are left as they are.
+func (c *ContentSpec) PinDigests(resolve func(image string) (string, error)) (*ContentSpec, []PinnedImage, error) {
+	catalogs, err := c.OperatorCatalogs()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pinned := &ContentSpec{Helm: HelmSpec{Repositories: copyRepositories(c.Helm.Repositories), Local: append([]HelmLocalChart(nil), c.Helm.Local...)}}
+	var images []PinnedImage
+	pin := func(image string) (string, error) {
+		ref, err := pinImage(image, resolve)
+		if err != nil {
+			return "", err
+		}
+		if ref != image {
+			images = append(images, PinnedImage{Image: image, Pinned: ref})
+		}
+		return ref, nil
+	}
+
+	for _, catalog := range catalogs {
+		if catalog.Catalog, err = pin(catalog.Catalog); err != nil {
+			return nil, nil, err
+		}
+		pinned.Catalogs = append(pinned.Catalogs, catalog)
+	}
+	for _, image := range c.AdditionalImages {
+		ref, err := pin(image)
+		if err != nil {
+			return nil, nil, err
+		}
+		pinned.AdditionalImages = append(pinned.AdditionalImages, ref)
+	}
+	return pinned, images, nil
+}
+
+// pinImage replaces the tag of an image reference, latest when none is given,
+// with the digest it resolves to
+func pinImage(image string, resolve func(image string) (string, error)) (string, error) {
+	if strings.Contains(image, "@") {
+		return image, nil
+	}
+	name, tag := SplitImageTag(image)
+	digest, err := resolve(name + ":" + tag)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s to a digest: %w", image, err)
+	}
+	return name + "@" + digest, nil
+}
+
+// SplitImageTag splits an image reference without digest into its name and
+// tag, latest when the reference has none
+func SplitImageTag(image string) (name, tag string) {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
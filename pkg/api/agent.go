@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+// DefaultRegisterInterval is how often an agent announces itself to the coordinator
+const DefaultRegisterInterval = 15 * time.Second
+
+// AgentInfo is how an agent announces itself to the coordinator
+type AgentInfo struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"` // Control API of the agent, as reachable from the coordinator
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// AgentConfig makes an API server an agent of a coordinator
+type AgentConfig struct {
+	CoordinatorURL string
+	Name           string // Default: hostname
+	AdvertiseURL   string // URL the coordinator reaches this agent at
+	Token          string // Bearer token for the coordinator
+	SkipTLS        bool
+	Interval       time.Duration
+}
+
+// Enabled reports whether the server registers with a coordinator
+func (c AgentConfig) Enabled() bool {
+	return c.CoordinatorURL != ""
+}
+
+// RunAgent registers the agent with the coordinator and keeps registering
+// until ctx is done, so the coordinator sees it as alive and learns about it
+// again after a coordinator restart
+func RunAgent(ctx context.Context, cfg AgentConfig) {
+	hostname, _ := os.Hostname()
+	agent := AgentInfo{Name: cfg.Name, URL: cfg.AdvertiseURL, Hostname: hostname}
+	if agent.Name == "" {
+		agent.Name = hostname
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = DefaultRegisterInterval
+	}
+	client := NewClient(cfg.CoordinatorURL, cfg.Token, cfg.SkipTLS)
+
+	registered := false
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := client.Register(ctx, agent)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Printf("Warning: failed to register with coordinator %s: %v", cfg.CoordinatorURL, err)
+			registered = false
+		case err == nil && !registered:
+			log.Printf("Registered as agent %s (%s) with coordinator %s", agent.Name, agent.URL, cfg.CoordinatorURL)
+			registered = true
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
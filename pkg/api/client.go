@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxResultsBytes bounds a results file fetched from an agent
+const maxResultsBytes = 256 << 20
+
+// Client calls the control API of an agent or the coordinator
+type Client struct {
+	baseURL    string
+	token      string // Bearer token, when the server requires one
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the API at baseURL
+func NewClient(baseURL, token string, skipTLS bool) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout:   60 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: skipTLS}},
+		},
+	}
+}
+
+// StartRun starts a run on the agent
+func (c *Client) StartRun(ctx context.Context, req StartRequest) (RunStatus, error) {
+	var run RunStatus
+	err := c.call(ctx, http.MethodPost, "/api/v1/runs", req, &run)
+	return run, err
+}
+
+// Run returns the status of a run
+func (c *Client) Run(ctx context.Context, id string) (RunStatus, error) {
+	var run RunStatus
+	err := c.call(ctx, http.MethodGet, "/api/v1/runs/"+id, nil, &run)
+	return run, err
+}
+
+// CancelRun stops a run
+func (c *Client) CancelRun(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodPost, "/api/v1/runs/"+id+"/cancel", nil, nil)
+}
+
+// Results returns the results file of a finished run
+func (c *Client) Results(ctx context.Context, id string) ([]byte, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/api/v1/runs/"+id+"/results", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResultsBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results of run %s: %w", id, err)
+	}
+	return data, nil
+}
+
+// Register announces an agent to the coordinator
+func (c *Client) Register(ctx context.Context, agent AgentInfo) error {
+	return c.call(ctx, http.MethodPost, "/api/v1/agents", agent, nil)
+}
+
+// call sends a JSON request and decodes the JSON response into out
+func (c *Client) call(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	resp, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response of %s %s: %w", method, c.baseURL+path, err)
+	}
+	return nil
+}
+
+// send sends a request, turning error statuses into errors with the message
+// of the API
+func (c *Client) send(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil || method != http.MethodGet {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", method, c.baseURL+path, err)
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&apiErr) == nil && apiErr.Error != "" {
+		return nil, fmt.Errorf("%s %s returned %s: %s", method, c.baseURL+path, resp.Status, apiErr.Error)
+	}
+	return nil, fmt.Errorf("%s %s returned %s", method, c.baseURL+path, resp.Status)
+}
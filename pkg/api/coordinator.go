@@ -0,0 +1,485 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/heartbeat"
+	"github.com/telco-core/ngc-495/pkg/runner"
+	"github.com/telco-core/ngc-495/pkg/webui"
+)
+
+// DefaultAgentTimeout is how long an agent stays alive without registering again
+const DefaultAgentTimeout = 60 * time.Second
+
+// pollInterval is how often the coordinator checks the runs of a dispatch
+const pollInterval = 5 * time.Second
+
+// Dispatch states
+const (
+	DispatchRunning   = "running"
+	DispatchCompleted = "completed"
+)
+
+// DispatchRequest is the body of POST /api/v1/dispatches: a run request sent
+// to every alive agent, or to the named ones
+type DispatchRequest struct {
+	StartRequest
+	Agents []string `json:"agents,omitempty"`
+}
+
+// AgentStatus is an agent known to the coordinator
+type AgentStatus struct {
+	AgentInfo
+	LastSeen time.Time `json:"last_seen"`
+	Alive    bool      `json:"alive"` // Registered within the agent timeout
+}
+
+// AgentRun is the run of one agent in a dispatch
+type AgentRun struct {
+	Agent       string            `json:"agent"`
+	URL         string            `json:"url"`
+	RunID       string            `json:"run_id,omitempty"`
+	State       string            `json:"state"` // Run state on the agent
+	Error       string            `json:"error,omitempty"`
+	ResultsFile string            `json:"results_file,omitempty"`
+	Progress    *heartbeat.Status `json:"progress,omitempty"`
+}
+
+// DispatchStatus is a scenario dispatched to several agents
+type DispatchStatus struct {
+	ID         string             `json:"id"`
+	Name       string             `json:"name,omitempty"`
+	State      string             `json:"state"` // running or completed
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+	Runs       []AgentRun         `json:"runs"`
+	ReportFile string             `json:"report_file,omitempty"`
+	Report     *DistributedReport `json:"report,omitempty"`
+}
+
+// dispatch tracks the agent runs of a dispatch
+type dispatch struct {
+	mu      sync.Mutex
+	status  DispatchStatus
+	results [][]runner.TestResult // Parallel to status.Runs
+}
+
+// Coordinator keeps track of the agents that registered with it, dispatches
+// the same scenario to all of them at once, simulating many mirror clients
+// pushing to one registry, and aggregates their results into one report
+type Coordinator struct {
+	port         int
+	bindAddress  string
+	auth         webui.AuthConfig
+	tls          webui.TLSConfig
+	resultsDir   string
+	agentTimeout time.Duration
+	agentSkipTLS bool
+
+	mu         sync.Mutex
+	agents     map[string]*AgentStatus
+	dispatches map[string]*dispatch
+	httpServer *http.Server
+}
+
+// NewCoordinator creates a coordinator writing distributed reports to resultsDir
+func NewCoordinator(port int, resultsDir string) *Coordinator {
+	return &Coordinator{
+		port:         port,
+		resultsDir:   resultsDir,
+		agentTimeout: DefaultAgentTimeout,
+		agents:       make(map[string]*AgentStatus),
+		dispatches:   make(map[string]*dispatch),
+	}
+}
+
+// SetBindAddress sets the interface address the coordinator listens on
+func (c *Coordinator) SetBindAddress(address string) {
+	c.bindAddress = address
+}
+
+// SetAuth requires requests to authenticate with the given credentials. The
+// token is also sent to the agents.
+func (c *Coordinator) SetAuth(auth webui.AuthConfig) {
+	c.auth = auth
+}
+
+// SetTLS serves HTTPS with the given certificate
+func (c *Coordinator) SetTLS(config webui.TLSConfig) {
+	c.tls = config
+}
+
+// SetAgentTimeout sets how long an agent stays alive without registering again
+func (c *Coordinator) SetAgentTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		c.agentTimeout = timeout
+	}
+}
+
+// SetAgentSkipTLS skips TLS verification when calling agents
+func (c *Coordinator) SetAgentSkipTLS(skip bool) {
+	c.agentSkipTLS = skip
+}
+
+// Handler returns the coordinator routes, authenticated when credentials are set
+func (c *Coordinator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/agents", c.handleAgents)
+	mux.HandleFunc("/api/v1/dispatches", c.handleDispatches)
+	mux.HandleFunc("/api/v1/dispatches/", c.handleDispatch)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	if !c.auth.Enabled() {
+		return mux
+	}
+	return webui.RequireAuth(c.auth, c.tls.Enabled(), mux)
+}
+
+// Start listens and serves requests until the coordinator fails or is shut down
+func (c *Coordinator) Start() error {
+	if err := os.MkdirAll(c.resultsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create results directory: %w", err)
+	}
+	c.httpServer = &http.Server{
+		Addr:              net.JoinHostPort(c.bindAddress, strconv.Itoa(c.port)),
+		Handler:           c.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("Starting coordinator on %s", c.httpServer.Addr)
+	log.Printf("Reports: %s", c.resultsDir)
+	if c.auth.Enabled() {
+		log.Printf("Authentication: %s", c.auth)
+	}
+	return listenAndServe(c.httpServer, c.tls)
+}
+
+// Shutdown stops the coordinator, waiting for open requests until ctx
+// expires. Dispatched runs keep running on the agents.
+func (c *Coordinator) Shutdown(ctx context.Context) error {
+	if c.httpServer == nil {
+		return nil
+	}
+	return c.httpServer.Shutdown(ctx)
+}
+
+// agentClient returns a client for the control API of an agent
+func (c *Coordinator) agentClient(url string) *Client {
+	return NewClient(url, c.auth.Token, c.agentSkipTLS)
+}
+
+// handleAgents registers an agent (POST) or lists the agents (GET)
+func (c *Coordinator) handleAgents(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		if !requireJSON(w, r) {
+			return
+		}
+		var agent AgentInfo
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBytes)).Decode(&agent); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+			return
+		}
+		if agent.Name == "" || agent.URL == "" {
+			writeError(w, http.StatusBadRequest, "agent name and url are required")
+			return
+		}
+		c.mu.Lock()
+		if _, known := c.agents[agent.Name]; !known {
+			log.Printf("Agent %s registered (%s)", agent.Name, agent.URL)
+		}
+		c.agents[agent.Name] = &AgentStatus{AgentInfo: agent, LastSeen: time.Now()}
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, c.agentList())
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// agentList returns the known agents by name, with their liveness
+func (c *Coordinator) agentList() []AgentStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	agents := make([]AgentStatus, 0, len(c.agents))
+	for _, agent := range c.agents {
+		status := *agent
+		status.Alive = time.Since(agent.LastSeen) <= c.agentTimeout
+		agents = append(agents, status)
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Name < agents[j].Name })
+	return agents
+}
+
+// handleDispatches lists dispatches (GET) or dispatches a run to the agents (POST)
+func (c *Coordinator) handleDispatches(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		c.mu.Lock()
+		list := make([]DispatchStatus, 0, len(c.dispatches))
+		for _, d := range c.dispatches {
+			list = append(list, d.snapshot(false))
+		}
+		c.mu.Unlock()
+		sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.After(list[j].StartedAt) })
+		writeJSON(w, http.StatusOK, list)
+	case http.MethodPost:
+		if !requireJSON(w, r) {
+			return
+		}
+		c.startDispatch(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// startDispatch starts the requested run on every selected agent at once
+func (c *Coordinator) startDispatch(w http.ResponseWriter, r *http.Request) {
+	var req DispatchRequest
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range req.Agents {
+		selected[name] = true
+	}
+	var agents []AgentStatus
+	for _, agent := range c.agentList() {
+		if (len(selected) == 0 && agent.Alive) || selected[agent.Name] {
+			agents = append(agents, agent)
+			delete(selected, agent.Name)
+		}
+	}
+	if len(selected) > 0 {
+		var unknown []string
+		for name := range selected {
+			unknown = append(unknown, name)
+		}
+		sort.Strings(unknown)
+		writeError(w, http.StatusBadRequest, "unknown agents: "+strings.Join(unknown, ", "))
+		return
+	}
+	if len(agents) == 0 {
+		writeError(w, http.StatusConflict, "no alive agents")
+		return
+	}
+
+	now := time.Now()
+	ctx := context.Background()
+	d := &dispatch{
+		status: DispatchStatus{
+			ID:        newRunID(now),
+			Name:      req.Name,
+			State:     DispatchRunning,
+			StartedAt: now,
+			Runs:      make([]AgentRun, len(agents)),
+		},
+		results: make([][]runner.TestResult, len(agents)),
+	}
+	// Start all runs before answering, so a rejected run is reported right away
+	var wg sync.WaitGroup
+	for i, agent := range agents {
+		d.status.Runs[i] = AgentRun{Agent: agent.Name, URL: agent.URL, State: StateRunning}
+		wg.Add(1)
+		go func(i int, agent AgentStatus) {
+			defer wg.Done()
+			// Each agent names its results after the dispatch and itself
+			start := req.StartRequest
+			start.Name = agentRunName(req.Name, agent.Name)
+			run, err := c.agentClient(agent.URL).StartRun(ctx, start)
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			if err != nil {
+				d.status.Runs[i].State = StateFailed
+				d.status.Runs[i].Error = err.Error()
+				return
+			}
+			d.status.Runs[i].RunID = run.ID
+		}(i, agent)
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	c.dispatches[d.status.ID] = d
+	c.mu.Unlock()
+	log.Printf("Dispatched %s to %d agents", d.status.ID, len(agents))
+	go c.follow(ctx, d)
+	writeJSON(w, http.StatusCreated, d.snapshot(false))
+}
+
+// agentRunName names the run of an agent after the dispatch and the agent
+func agentRunName(name, agent string) string {
+	if name == "" {
+		return agent
+	}
+	return name + "-" + agent
+}
+
+// follow polls the agent runs of a dispatch until all ended, then collects
+// their results and writes the distributed report
+func (c *Coordinator) follow(ctx context.Context, d *dispatch) {
+	var wg sync.WaitGroup
+	for i := range d.status.Runs {
+		d.mu.Lock()
+		run := d.status.Runs[i]
+		d.mu.Unlock()
+		if run.RunID == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, run AgentRun) {
+			defer wg.Done()
+			c.followRun(ctx, d, i, run)
+		}(i, run)
+	}
+	wg.Wait()
+
+	d.mu.Lock()
+	now := time.Now()
+	d.status.State = DispatchCompleted
+	d.status.FinishedAt = &now
+	report := &DistributedReport{
+		DispatchID: d.status.ID,
+		Name:       d.status.Name,
+		StartedAt:  d.status.StartedAt,
+		FinishedAt: now,
+	}
+	for i, run := range d.status.Runs {
+		report.Agents = append(report.Agents, newAgentReport(run, d.results[i]))
+	}
+	report.Iterations = aggregateIterations(report.Agents)
+	d.status.Report = report
+	d.mu.Unlock()
+
+	path := filepath.Join(c.resultsDir, "distributed_"+d.status.ID+".json")
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err == nil {
+		err = os.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		log.Printf("Warning: failed to write distributed report: %v", err)
+	} else {
+		d.mu.Lock()
+		d.status.ReportFile = path
+		d.mu.Unlock()
+	}
+	report.PrintSummary()
+}
+
+// followRun polls one agent run until it ends and fetches its results. An
+// agent unreachable for longer than the agent timeout is given up on.
+func (c *Coordinator) followRun(ctx context.Context, d *dispatch, i int, run AgentRun) {
+	client := c.agentClient(run.URL)
+	lastContact := time.Now()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := client.Run(ctx, run.RunID)
+		d.mu.Lock()
+		switch {
+		case err == nil:
+			lastContact = time.Now()
+			d.status.Runs[i].State = status.State
+			d.status.Runs[i].Error = status.Error
+			d.status.Runs[i].ResultsFile = status.ResultsFile
+			d.status.Runs[i].Progress = status.Progress
+		case time.Since(lastContact) > c.agentTimeout:
+			d.status.Runs[i].State = StateFailed
+			d.status.Runs[i].Error = "agent unreachable: " + err.Error()
+		}
+		state := d.status.Runs[i].State
+		d.mu.Unlock()
+		if state != StateRunning {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+
+	data, err := client.Results(ctx, run.RunID)
+	if err == nil {
+		var file *runner.ResultsFile
+		if file, err = runner.ParseResultsFile(data); err == nil {
+			d.mu.Lock()
+			d.results[i] = file.Results
+			d.mu.Unlock()
+			return
+		}
+	}
+	log.Printf("Warning: no results from agent %s: %v", run.Agent, err)
+}
+
+// handleDispatch serves /api/v1/dispatches/<id> and /api/v1/dispatches/<id>/cancel
+func (c *Coordinator) handleDispatch(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/v1/dispatches/"), "/")
+	c.mu.Lock()
+	d := c.dispatches[id]
+	c.mu.Unlock()
+	if d == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("dispatch %q not found", id))
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, d.snapshot(true))
+	case action == "cancel" && r.Method == http.MethodPost:
+		if !requireJSON(w, r) {
+			return
+		}
+		// Runs are cancelled on the agents; following continues so the
+		// report covers what they completed
+		var errs []string
+		for _, run := range d.snapshot(false).Runs {
+			if run.RunID == "" || run.State != StateRunning {
+				continue
+			}
+			if err := c.agentClient(run.URL).CancelRun(r.Context(), run.RunID); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		if len(errs) > 0 {
+			writeError(w, http.StatusBadGateway, strings.Join(errs, "; "))
+			return
+		}
+		log.Printf("Cancelling dispatch %s", id)
+		writeJSON(w, http.StatusAccepted, d.snapshot(false))
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// snapshot returns a copy of the dispatch status, with the report when requested
+func (d *dispatch) snapshot(withReport bool) DispatchStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	status := d.status
+	status.Runs = append([]AgentRun(nil), d.status.Runs...)
+	if !withReport {
+		status.Report = nil
+	}
+	return status
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/monitor"
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// DistributedReport aggregates the results of one scenario run concurrently
+// by several agents against the same registry
+type DistributedReport struct {
+	DispatchID string              `json:"dispatch_id"`
+	Name       string              `json:"name,omitempty"`
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt time.Time           `json:"finished_at"`
+	Agents     []AgentReport       `json:"agents"`
+	Iterations []RegistryIteration `json:"iterations"` // Registry-wide, per iteration across the agents
+}
+
+// AgentReport is the outcome of the run of one agent
+type AgentReport struct {
+	Agent       string           `json:"agent"`
+	URL         string           `json:"url"`
+	RunID       string           `json:"run_id,omitempty"`
+	State       string           `json:"state"`
+	Error       string           `json:"error,omitempty"`
+	ResultsFile string           `json:"results_file,omitempty"` // On the agent host
+	Iterations  []AgentIteration `json:"iterations,omitempty"`
+}
+
+// AgentIteration is the headline metrics of one iteration of an agent
+type AgentIteration struct {
+	Iteration     int           `json:"iteration"`
+	Version       string        `json:"version"`
+	Failed        bool          `json:"failed,omitempty"`
+	DownloadTime  time.Duration `json:"download_time_seconds"`
+	UploadTime    time.Duration `json:"upload_time_seconds"`
+	BytesUploaded int64         `json:"bytes_uploaded"`
+	UploadMBs     float64       `json:"upload_mbs"`
+	Throttled     int           `json:"throttled,omitempty"`     // 429 responses during the upload
+	ServerErrors  int           `json:"server_errors,omitempty"` // 5xx responses during the upload
+}
+
+// RegistryIteration is the registry-wide view of one iteration: every agent
+// pushing the same content at the same time
+type RegistryIteration struct {
+	Iteration     int           `json:"iteration"`
+	Version       string        `json:"version"`
+	Agents        int           `json:"agents"`
+	Failed        int           `json:"failed"`
+	BytesUploaded int64         `json:"bytes_uploaded"` // All agents
+	FastestUpload time.Duration `json:"fastest_upload_seconds"`
+	SlowestUpload time.Duration `json:"slowest_upload_seconds"`
+	MeanUpload    time.Duration `json:"mean_upload_seconds"`
+	AggregateMBs  float64       `json:"aggregate_mbs"` // Bytes of all agents over the slowest upload
+	Throttled     int           `json:"throttled"`
+	ServerErrors  int           `json:"server_errors"`
+}
+
+// newAgentReport summarizes the results of one agent
+func newAgentReport(run AgentRun, results []runner.TestResult) AgentReport {
+	report := AgentReport{
+		Agent:       run.Agent,
+		URL:         run.URL,
+		RunID:       run.RunID,
+		State:       run.State,
+		Error:       run.Error,
+		ResultsFile: run.ResultsFile,
+	}
+	for _, r := range results {
+		iteration := AgentIteration{
+			Iteration:     r.Iteration,
+			Version:       r.Version,
+			Failed:        r.Failed,
+			DownloadTime:  r.DownloadPhase.WallTime,
+			UploadTime:    r.UploadPhase.WallTime,
+			BytesUploaded: r.UploadPhase.BytesUploaded,
+		}
+		if seconds := r.UploadPhase.WallTime.Seconds(); seconds > 0 {
+			iteration.UploadMBs = float64(r.UploadPhase.BytesUploaded) / (1024 * 1024) / seconds
+		}
+		if status := r.UploadPhase.HTTPStatus; status != nil {
+			iteration.Throttled = status.Throttled
+			iteration.ServerErrors = status.ServerErrors
+		}
+		report.Iterations = append(report.Iterations, iteration)
+	}
+	return report
+}
+
+// aggregateIterations combines the same iteration of every agent
+func aggregateIterations(agents []AgentReport) []RegistryIteration {
+	type key struct {
+		iteration int
+		version   string
+	}
+	byKey := make(map[key]*RegistryIteration)
+	totals := make(map[key]time.Duration)
+	for _, agent := range agents {
+		for _, it := range agent.Iterations {
+			k := key{it.Iteration, it.Version}
+			agg := byKey[k]
+			if agg == nil {
+				agg = &RegistryIteration{Iteration: it.Iteration, Version: it.Version}
+				byKey[k] = agg
+			}
+			agg.Agents++
+			if it.Failed {
+				agg.Failed++
+				continue
+			}
+			agg.BytesUploaded += it.BytesUploaded
+			agg.Throttled += it.Throttled
+			agg.ServerErrors += it.ServerErrors
+			if agg.FastestUpload == 0 || it.UploadTime < agg.FastestUpload {
+				agg.FastestUpload = it.UploadTime
+			}
+			agg.SlowestUpload = max(agg.SlowestUpload, it.UploadTime)
+			totals[k] += it.UploadTime
+		}
+	}
+
+	iterations := make([]RegistryIteration, 0, len(byKey))
+	for k, agg := range byKey {
+		if completed := agg.Agents - agg.Failed; completed > 0 {
+			agg.MeanUpload = totals[k] / time.Duration(completed)
+		}
+		if seconds := agg.SlowestUpload.Seconds(); seconds > 0 {
+			agg.AggregateMBs = float64(agg.BytesUploaded) / (1024 * 1024) / seconds
+		}
+		iterations = append(iterations, *agg)
+	}
+	sort.Slice(iterations, func(i, j int) bool {
+		if iterations[i].Iteration != iterations[j].Iteration {
+			return iterations[i].Iteration < iterations[j].Iteration
+		}
+		return iterations[i].Version < iterations[j].Version
+	})
+	return iterations
+}
+
+// PrintSummary prints the per-agent and registry-wide results
+func (r *DistributedReport) PrintSummary() {
+	fmt.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
+	fmt.Printf("║  Distributed Run %s (%d agents)\n", r.DispatchID, len(r.Agents))
+	fmt.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+
+	fmt.Printf("\nAgents:\n")
+	for _, agent := range r.Agents {
+		mark := "✅"
+		if agent.State != StateSucceeded {
+			mark = "❌"
+		}
+		fmt.Printf("  %s %s: %s", mark, agent.Agent, agent.State)
+		if agent.Error != "" {
+			fmt.Printf(" (%s)", agent.Error)
+		}
+		fmt.Printf("\n")
+		for _, it := range agent.Iterations {
+			fmt.Printf("       Iteration %d (%s): upload %v, %s, %.2f MB/s\n",
+				it.Iteration, it.Version, it.UploadTime.Round(time.Second), monitor.FormatBytesHuman(it.BytesUploaded), it.UploadMBs)
+		}
+	}
+
+	if len(r.Iterations) == 0 {
+		return
+	}
+	fmt.Printf("\nRegistry-Wide:\n")
+	for _, it := range r.Iterations {
+		fmt.Printf("  Iteration %d (%s): %d agents, %s pushed at %.2f MB/s aggregate | upload fastest %v, mean %v, slowest %v\n",
+			it.Iteration, it.Version, it.Agents-it.Failed, monitor.FormatBytesHuman(it.BytesUploaded), it.AggregateMBs,
+			it.FastestUpload.Round(time.Second), it.MeanUpload.Round(time.Second), it.SlowestUpload.Round(time.Second))
+		if it.Failed > 0 {
+			fmt.Printf("  Warning: %d agent(s) failed iteration %d (%s)\n", it.Failed, it.Iteration, it.Version)
+		}
+		if it.Throttled+it.ServerErrors > 0 {
+			fmt.Printf("  Warning: The registry throttled %d and failed %d requests across the agents\n", it.Throttled, it.ServerErrors)
+		}
+	}
+}
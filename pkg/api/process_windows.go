@@ -0,0 +1,23 @@
+package api
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup does nothing: Windows has no process groups to signal
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// stopProcessGroup kills pid; there is no SIGTERM to let it finish first
+func stopProcessGroup(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Kill()
+}
+
+// killProcessGroup kills pid, as stopProcessGroup already did
+func killProcessGroup(pid int) error {
+	return stopProcessGroup(pid)
+}
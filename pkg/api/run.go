@@ -0,0 +1,354 @@
+package api
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/heartbeat"
+	"github.com/telco-core/ngc-495/pkg/scenario"
+)
+
+// Run states
+const (
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+// Files written to the directory of each run
+const (
+	scenarioFileName  = "scenario.yaml"
+	logFileName       = "run.log"
+	heartbeatFileName = "heartbeat.json"
+)
+
+// progressInterval is how often a run started through the API writes its heartbeat
+const progressInterval = 5 * time.Second
+
+// cancelGracePeriod is how long a cancelled run may take to exit before it is killed
+const cancelGracePeriod = 30 * time.Second
+
+// requestFlags are the run flags a start request may pass in args. Flags that
+// run commands or read and write paths on the host (plugins, binaries, the
+// delete GC command, kubeconfig, results and heartbeat files) or send the
+// results elsewhere are left to the server's own run arguments, so a request
+// cannot execute anything but oc-mirror-test.
+var requestFlags = map[string]bool{
+	"registry": true, "compare-registry": true, "registry-order": true, "compare-v1-v2": true,
+	"iterations": true, "warmup": true, "skip-tls": true, "retry-failed": true, "retry-backoff": true,
+	"download-timeout": true, "upload-timeout": true, "workspace-cleanup": true, "repeat-clean": true,
+	"network-accounting": true, "log-retention": true, "log-retention-lines": true, "resource-scope": true,
+	"sample-storage": true, "disk-estimate": true, "skip-disk-check": true,
+	"local-registry": true, "local-registry-port": true, "local-registry-storage": true,
+	"upload-debug-log": true, "accounting-proxy": true,
+	"rate-limit-retry-after": true, "rate-limit-bandwidth": true,
+	"registry-metrics-url": true, "registry-metrics-interval": true, "adaptive-poll-after": true, "adaptive-poll-max": true,
+	"catalog-diff": true, "air-gap": true, "replicate-to": true, "replication-topology": true,
+	"delete": true, "force-cache-delete": true,
+	"content": true, "stage": true, "additional-image": true, "helm-chart": true,
+	"limit-bandwidth": true, "latency": true, "jitter": true, "packet-loss": true, "shape-interface": true, "shape-ingress": true,
+	"tools-version": true, "run-name": true, "tag": true, "note": true,
+	"max-concurrent-pushes": true, "upload-window": true, "max-window-wait": true, "memory-budget": true,
+	"verify-signatures": true, "http-proxy": true, "https-proxy": true, "no-proxy": true, "compare-proxy": true,
+	"chaos-kill-after": true, "chaos-kill-after-bytes": true,
+	"matrix-versions": true, "matrix-workflows": true, "matrix-cache": true, "matrix-concurrency": true, "matrix-references": true,
+	"compare-pinning": true, "validate-content": true, "package-sizes": true, "verify-upload": true, "no-tui": true,
+	"gate-max-download-time": true, "gate-max-errors": true,
+	"validate-cluster": true, "validate-image": true, "validate-operator": true, "validate-channel": true, "validate-timeout": true,
+	"verify-pull-through": true, "pull-through-timeout": true,
+	"log-level": true, "quiet": true, "verbose": true,
+}
+
+// checkRequestArgs rejects the flags of args that are not in requestFlags.
+// Values are passed as they are, but one that looks like a flag is checked
+// too, so a flag cannot hide behind the flag before it.
+func checkRequestArgs(args []string) error {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "--") || !requestFlags[name] {
+			return fmt.Errorf("flag %q cannot be set through the API", arg)
+		}
+	}
+	return nil
+}
+
+// checkRequestScenario rejects a scenario sent with the request that runs
+// commands on the host
+func checkRequestScenario(sc *scenario.Scenario) error {
+	switch {
+	case sc.Plugins.Enabled():
+		return fmt.Errorf("scenario plugins cannot be set through the API")
+	case sc.Delete.GCCommand != "":
+		return fmt.Errorf("scenario delete.gcCommand cannot be set through the API")
+	case sc.BinDir != "" || sc.ToolsFromDir != "":
+		return fmt.Errorf("scenario binDir and toolsFromDir cannot be set through the API")
+	case sc.LocalRegistry.Runtime != "" || sc.LocalRegistry.Image != "":
+		return fmt.Errorf("scenario localRegistry runtime and image cannot be set through the API")
+	}
+	return nil
+}
+
+// StartRequest is the body of POST /api/v1/runs
+type StartRequest struct {
+	Scenario     string   `json:"scenario,omitempty"`      // Scenario YAML content
+	ScenarioFile string   `json:"scenario_file,omitempty"` // Scenario file on the API host
+	Name         string   `json:"name,omitempty"`          // Run name, part of the results file name
+	Args         []string `json:"args,omitempty"`          // Further run flags, e.g. ["--iterations", "3"], see requestFlags
+}
+
+// RunStatus is the state of a test run started through the API
+type RunStatus struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name,omitempty"`
+	State       string            `json:"state"` // running, succeeded, failed or cancelled
+	Args        []string          `json:"args"`  // Arguments of oc-mirror-test
+	PID         int               `json:"pid,omitempty"`
+	StartedAt   time.Time         `json:"started_at"`
+	FinishedAt  *time.Time        `json:"finished_at,omitempty"`
+	ExitCode    *int              `json:"exit_code,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	ResultsFile string            `json:"results_file,omitempty"` // Read from the run output, which names it at the start
+	LogBytes    int64             `json:"log_bytes"`
+	Progress    *heartbeat.Status `json:"progress,omitempty"` // Latest heartbeat of a running run
+}
+
+// trackedRun is a run process and its files
+type trackedRun struct {
+	RunStatus
+
+	dir       string
+	cmd       *exec.Cmd
+	cancelled bool
+	done      chan struct{}
+	mu        sync.Mutex
+}
+
+// newRunID returns a sortable, unique run ID
+func newRunID(now time.Time) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// newRun creates the directory of a run and its arguments. The scenario is
+// validated here, so a broken one is rejected before anything starts.
+func newRun(runsDir string, defaultArgs []string, req StartRequest) (*trackedRun, error) {
+	now := time.Now()
+	run := &trackedRun{
+		RunStatus: RunStatus{ID: newRunID(now), Name: req.Name, State: StateRunning, StartedAt: now},
+		done:      make(chan struct{}),
+	}
+	run.dir = filepath.Join(runsDir, run.ID)
+	if err := os.MkdirAll(run.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create run directory: %w", err)
+	}
+
+	args, err := run.buildArgs(defaultArgs, req)
+	if err != nil {
+		os.RemoveAll(run.dir)
+		return nil, err
+	}
+	run.Args = args
+	return run, nil
+}
+
+// start launches oc-mirror-test run in its own process group, with its
+// output written to the log file of the run directory
+func (r *trackedRun) start(executable, workDir string) error {
+	logFile, err := os.Create(r.logPath())
+	if err != nil {
+		return fmt.Errorf("failed to create run log: %w", err)
+	}
+	r.cmd = exec.Command(executable, r.Args...)
+	r.cmd.Dir = workDir
+	r.cmd.Stdout = logFile
+	r.cmd.Stderr = logFile
+	// oc-mirror runs as a child of the run; a process group lets cancel stop both
+	setProcessGroup(r.cmd)
+	if err := r.cmd.Start(); err != nil {
+		logFile.Close()
+		return fmt.Errorf("failed to start run: %w", err)
+	}
+	r.PID = r.cmd.Process.Pid
+
+	go func() {
+		err := r.cmd.Wait()
+		logFile.Close()
+		r.finish(err)
+	}()
+	return nil
+}
+
+// buildArgs returns the oc-mirror-test arguments of a request, after the
+// default arguments of the server. The heartbeat flags come last so the API
+// can always read the progress.
+func (r *trackedRun) buildArgs(defaultArgs []string, req StartRequest) ([]string, error) {
+	args := append([]string{"run"}, defaultArgs...)
+	switch {
+	case req.Scenario != "" && req.ScenarioFile != "":
+		return nil, fmt.Errorf("set either scenario or scenario_file, not both")
+	case req.Scenario != "":
+		path := filepath.Join(r.dir, scenarioFileName)
+		if err := os.WriteFile(path, []byte(req.Scenario), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write scenario: %w", err)
+		}
+		sc, err := scenario.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkRequestScenario(sc); err != nil {
+			return nil, err
+		}
+		args = append(args, "--scenario", path)
+	case req.ScenarioFile != "":
+		if _, err := scenario.Load(req.ScenarioFile); err != nil {
+			return nil, err
+		}
+		args = append(args, "--scenario", req.ScenarioFile)
+	}
+	if req.Name != "" {
+		args = append(args, "--run-name", req.Name)
+	}
+	if err := checkRequestArgs(req.Args); err != nil {
+		return nil, err
+	}
+	args = append(args, req.Args...)
+	args = append(args,
+		"--heartbeat-file", filepath.Join(r.dir, heartbeatFileName),
+		"--heartbeat-interval", progressInterval.String())
+	return args, nil
+}
+
+// finish records how the run process ended
+func (r *trackedRun) finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.FinishedAt = &now
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		exitCode = -1
+	}
+	r.ExitCode = &exitCode
+
+	switch {
+	case r.cancelled:
+		r.State = StateCancelled
+	case err != nil:
+		r.State = StateFailed
+		r.Error = err.Error()
+	default:
+		r.State = StateSucceeded
+	}
+	r.ResultsFile = resultsFileFromLog(filepath.Join(r.dir, logFileName))
+	close(r.done)
+}
+
+// cancel stops the run: SIGTERM to its process group, SIGKILL when it has
+// not exited after the grace period
+func (r *trackedRun) cancel() error {
+	r.mu.Lock()
+	if r.State != StateRunning {
+		r.mu.Unlock()
+		return fmt.Errorf("run %s is not running (%s)", r.ID, r.State)
+	}
+	r.cancelled = true
+	pid := r.PID
+	r.mu.Unlock()
+
+	if err := stopProcessGroup(pid); err != nil {
+		return fmt.Errorf("failed to cancel run %s: %w", r.ID, err)
+	}
+	go func() {
+		select {
+		case <-r.done:
+		case <-time.After(cancelGracePeriod):
+			killProcessGroup(pid)
+		}
+	}()
+	return nil
+}
+
+// running reports whether the run process has not exited yet
+func (r *trackedRun) running() bool {
+	select {
+	case <-r.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// logPath returns the combined stdout and stderr of the run
+func (r *trackedRun) logPath() string {
+	return filepath.Join(r.dir, logFileName)
+}
+
+// snapshot returns a copy of the run for a response, with the log size and
+// the latest heartbeat of a running run
+func (r *trackedRun) snapshot() RunStatus {
+	r.mu.Lock()
+	s := r.RunStatus
+	r.mu.Unlock()
+
+	if info, err := os.Stat(r.logPath()); err == nil {
+		s.LogBytes = info.Size()
+	}
+	if s.State == StateRunning {
+		s.ResultsFile = r.announcedResultsFile()
+		if data, err := os.ReadFile(filepath.Join(r.dir, heartbeatFileName)); err == nil {
+			var status heartbeat.Status
+			if json.Unmarshal(data, &status) == nil {
+				s.Progress = &status
+			}
+		}
+	}
+	return s
+}
+
+// announcedResultsFile returns the results file a running run named in its
+// header, reading the log until the name is found
+func (r *trackedRun) announcedResultsFile() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ResultsFile == "" {
+		r.ResultsFile = resultsFileFromLog(r.logPath())
+	}
+	return r.ResultsFile
+}
+
+// resultsFileFromLog returns the results file a run reported in its output
+func resultsFileFromLog(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	var resultsFile string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(scanner.Text(), "Results: "); ok {
+			resultsFile = strings.TrimSpace(name)
+		}
+	}
+	return resultsFile
+}
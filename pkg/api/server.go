@@ -0,0 +1,369 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/webui"
+)
+
+// DefaultRunsDir is where the scenario, log and heartbeat of each run are kept
+const DefaultRunsDir = "api-runs"
+
+// logPollInterval is how often a followed log is checked for new output
+const logPollInterval = 500 * time.Millisecond
+
+// maxRequestBytes bounds a start request, scenario included
+const maxRequestBytes = 1 << 20
+
+// Server is the control API: it starts oc-mirror-test runs on this host,
+// reports their status and progress, streams their logs and cancels them.
+// One run executes at a time, since runs share the oc-mirror workspaces of
+// the working directory.
+type Server struct {
+	port        int
+	bindAddress string
+	auth        webui.AuthConfig
+	tls         webui.TLSConfig
+	executable  string // oc-mirror-test binary started for each run
+	workDir     string // Directory runs execute in
+	runsDir     string
+	runArgs     []string // Arguments every run starts with, before those of the request
+
+	mu         sync.Mutex
+	runs       map[string]*trackedRun
+	active     *trackedRun
+	httpServer *http.Server
+}
+
+// NewServer creates a control API server starting runs with executable in
+// workDir, keeping their files under runsDir
+func NewServer(port int, executable, workDir, runsDir string) *Server {
+	if !filepath.IsAbs(runsDir) {
+		runsDir = filepath.Join(workDir, runsDir)
+	}
+	return &Server{
+		port:       port,
+		executable: executable,
+		workDir:    workDir,
+		runsDir:    runsDir,
+		runs:       make(map[string]*trackedRun),
+	}
+}
+
+// SetBindAddress sets the interface address the server listens on
+func (s *Server) SetBindAddress(address string) {
+	s.bindAddress = address
+}
+
+// SetAuth requires requests to authenticate with the given credentials
+func (s *Server) SetAuth(auth webui.AuthConfig) {
+	s.auth = auth
+}
+
+// SetTLS serves HTTPS with the given certificate
+func (s *Server) SetTLS(config webui.TLSConfig) {
+	s.tls = config
+}
+
+// SetRunArgs sets arguments every run starts with. The arguments of a start
+// request follow, so they take precedence.
+func (s *Server) SetRunArgs(args ...string) {
+	s.runArgs = args
+}
+
+// RunsHandler returns the run routes alone, for mounting under another
+// prefix: <prefix>/runs lists and starts runs, <prefix>/runs/<id> serves one.
+// It is not authenticated, the mounting server is.
+func (s *Server) RunsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/runs") {
+			s.handleRuns(w, r)
+			return
+		}
+		s.handleRun(w, r)
+	})
+}
+
+// DashboardRuns lists the runs for the run registry of the web UI
+func (s *Server) DashboardRuns() []webui.RunInfo {
+	s.mu.Lock()
+	runs := make([]*trackedRun, 0, len(s.runs))
+	for _, run := range s.runs {
+		runs = append(runs, run)
+	}
+	s.mu.Unlock()
+
+	infos := make([]webui.RunInfo, 0, len(runs))
+	for _, run := range runs {
+		status := run.snapshot()
+		infos = append(infos, webui.RunInfo{
+			ID:          status.ID,
+			Name:        status.Name,
+			State:       status.State,
+			StartedAt:   status.StartedAt,
+			ResultsFile: status.ResultsFile,
+			Args:        status.Args,
+			Error:       status.Error,
+			Progress:    status.Progress,
+		})
+	}
+	return infos
+}
+
+// Handler returns the API routes, authenticated when credentials are set
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/runs", s.handleRuns)
+	mux.HandleFunc("/api/v1/runs/", s.handleRun)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	if !s.auth.Enabled() {
+		return mux
+	}
+	return webui.RequireAuth(s.auth, s.tls.Enabled(), mux)
+}
+
+// Start listens and serves requests until the server fails or is shut down
+func (s *Server) Start() error {
+	if err := os.MkdirAll(s.runsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create runs directory: %w", err)
+	}
+	s.httpServer = &http.Server{
+		Addr:              net.JoinHostPort(s.bindAddress, strconv.Itoa(s.port)),
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Printf("Starting control API on %s", s.httpServer.Addr)
+	log.Printf("Runs: %s (working directory %s)", s.runsDir, s.workDir)
+	if s.auth.Enabled() {
+		log.Printf("Authentication: %s", s.auth)
+		if !s.tls.Enabled() {
+			log.Printf("Warning: credentials are sent in clear text; use --tls-cert and --tls-key")
+		}
+	} else {
+		log.Printf("Warning: the control API runs commands on this host without authentication; use --auth-user or %s", webui.EnvAuthToken)
+	}
+
+	return listenAndServe(s.httpServer, s.tls)
+}
+
+// listenAndServe serves HTTP or HTTPS; a shutdown is not an error
+func listenAndServe(server *http.Server, tls webui.TLSConfig) error {
+	var err error
+	if tls.Enabled() {
+		err = server.ListenAndServeTLS(tls.CertFile, tls.KeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops the server, waiting for open requests until ctx expires.
+// A run in progress keeps running.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Shutdown(ctx)
+}
+
+// handleRuns lists runs (GET) or starts one (POST)
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		runs := make([]RunStatus, 0, len(s.runs))
+		for _, run := range s.runs {
+			runs = append(runs, run.snapshot())
+		}
+		s.mu.Unlock()
+		sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+		writeJSON(w, http.StatusOK, runs)
+	case http.MethodPost:
+		if !requireJSON(w, r) {
+			return
+		}
+		s.startRun(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// startRun validates a start request and launches the run
+func (s *Server) startRun(w http.ResponseWriter, r *http.Request) {
+	var req StartRequest
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active != nil && s.active.running() {
+		writeError(w, http.StatusConflict, fmt.Sprintf("run %s is still running", s.active.ID))
+		return
+	}
+	run, err := newRun(s.runsDir, s.runArgs, req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := run.start(s.executable, s.workDir); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.runs[run.ID] = run
+	s.active = run
+	log.Printf("Started run %s (PID %d): %s", run.ID, run.PID, strings.Join(run.Args, " "))
+	writeJSON(w, http.StatusCreated, run.snapshot())
+}
+
+// handleRun serves /api/v1/runs/<id>, /api/v1/runs/<id>/logs,
+// /api/v1/runs/<id>/results and /api/v1/runs/<id>/cancel
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	_, path, _ := strings.Cut(r.URL.Path, "/runs/")
+	id, action, _ := strings.Cut(path, "/")
+	s.mu.Lock()
+	run := s.runs[id]
+	s.mu.Unlock()
+	if run == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("run %q not found", id))
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, run.snapshot())
+	case action == "" && r.Method == http.MethodDelete, action == "cancel" && r.Method == http.MethodPost:
+		if !requireJSON(w, r) {
+			return
+		}
+		if err := run.cancel(); err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		log.Printf("Cancelling run %s", run.ID)
+		writeJSON(w, http.StatusAccepted, run.snapshot())
+	case action == "logs" && r.Method == http.MethodGet:
+		s.streamLog(w, r, run)
+	case action == "results" && r.Method == http.MethodGet:
+		s.serveResults(w, r, run)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// streamLog writes the run output from ?offset= (bytes). With ?follow=true
+// it keeps streaming new output until the run ends or the client disconnects.
+func (s *Server) streamLog(w http.ResponseWriter, r *http.Request, run *trackedRun) {
+	var offset int64
+	if value := r.URL.Query().Get("offset"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			writeError(w, http.StatusBadRequest, "invalid offset")
+			return
+		}
+		offset = parsed
+	}
+	follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+
+	file, err := os.Open(run.logPath())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to read run log: %v", err))
+		return
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid offset")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	flusher, _ := w.(http.Flusher)
+	for {
+		// Checked before copying, so the output written before the exit is sent
+		ended := !run.running()
+		if _, err := io.Copy(w, file); err != nil {
+			return
+		}
+		if !follow || ended {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-run.done:
+		case <-time.After(logPollInterval):
+		}
+	}
+}
+
+// serveResults sends the results file of a finished run
+func (s *Server) serveResults(w http.ResponseWriter, r *http.Request, run *trackedRun) {
+	status := run.snapshot()
+	if status.State == StateRunning {
+		writeError(w, http.StatusConflict, fmt.Sprintf("run %s is still running", run.ID))
+		return
+	}
+	if status.ResultsFile == "" {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("run %s reported no results file", run.ID))
+		return
+	}
+	path := status.ResultsFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.workDir, path)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	http.ServeFile(w, r, path)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// requireJSON rejects a request that is not JSON with 415. Browsers send
+// other content types cross-site without a preflight, so this keeps a page on
+// another site from starting or cancelling runs as its visitor.
+func requireJSON(w http.ResponseWriter, r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		return false
+	}
+	return true
+}
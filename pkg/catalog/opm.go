@@ -0,0 +1,258 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/telco-core/ngc-495/pkg/command"
+)
+
+// Package is an operator package declared in a file-based catalog
+type Package struct {
+	Name           string    `json:"name"`
+	DefaultChannel string    `json:"default_channel"`
+	Channels       []Channel `json:"channels"`
+}
+
+// Channel is a package channel and the bundle versions it contains
+type Channel struct {
+	Name    string   `json:"name"`
+	Bundles []string `json:"bundles"`        // Bundle names in catalog order (e.g. odf-operator.v4.19.6-rhodf)
+	Head    string   `json:"head,omitempty"` // Bundle no other entry replaces or skips
+}
+
+// Bundle is an operator bundle and the images it deploys
+type Bundle struct {
+	Name          string   `json:"name"`
+	Package       string   `json:"package"`
+	Version       string   `json:"version"`
+	Image         string   `json:"image"`
+	RelatedImages []string `json:"related_images,omitempty"`
+}
+
+// Images returns the bundle image and its related images, each listed once
+func (b Bundle) Images() []string {
+	seen := make(map[string]bool)
+	var images []string
+	for _, image := range append([]string{b.Image}, b.RelatedImages...) {
+		if image != "" && !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// ChannelNames returns the channel names of the package, default channel first
+func (p *Package) ChannelNames() []string {
+	names := make([]string, 0, len(p.Channels))
+	for _, channel := range p.Channels {
+		if channel.Name == p.DefaultChannel {
+			names = append([]string{channel.Name}, names...)
+		} else {
+			names = append(names, channel.Name)
+		}
+	}
+	return names
+}
+
+// Channel returns the named channel, or nil
+func (p *Package) Channel(name string) *Channel {
+	for i := range p.Channels {
+		if p.Channels[i].Name == name {
+			return &p.Channels[i]
+		}
+	}
+	return nil
+}
+
+// Index is the package list of a rendered catalog
+type Index struct {
+	Image    string    `json:"image"`
+	Packages []Package `json:"packages"`
+
+	bundles map[string]Bundle // By bundle name
+}
+
+// Search returns the packages whose name contains the term (case-insensitive)
+func (idx *Index) Search(term string) []Package {
+	term = strings.ToLower(strings.TrimSpace(term))
+	var matches []Package
+	for _, pkg := range idx.Packages {
+		if strings.Contains(strings.ToLower(pkg.Name), term) {
+			matches = append(matches, pkg)
+		}
+	}
+	return matches
+}
+
+// Package returns the named package, or nil
+func (idx *Index) Package(name string) *Package {
+	for i := range idx.Packages {
+		if idx.Packages[i].Name == name {
+			return &idx.Packages[i]
+		}
+	}
+	return nil
+}
+
+// Bundle returns the named bundle
+func (idx *Index) Bundle(name string) (Bundle, bool) {
+	bundle, ok := idx.bundles[name]
+	return bundle, ok
+}
+
+// FindOPM returns the opm binary from the bin directory (where the download command installs it) or PATH
+func FindOPM() (string, error) {
+	local := filepath.Join(command.BinDir(), "opm")
+	if info, err := os.Stat(local); err == nil && !info.IsDir() {
+		return local, nil
+	}
+	path, err := exec.LookPath("opm")
+	if err != nil {
+		return "", fmt.Errorf("opm not found in %s or PATH (install it with: oc-mirror-test download --tools opm)", command.BinDir())
+	}
+	return path, nil
+}
+
+// Render runs `opm render` against a catalog image and returns its packages.
+// Rendering pulls the catalog image, which can take a minute for the Red Hat index.
+func Render(image string) (*Index, error) {
+	opm, err := FindOPM()
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(opm, "render", image, "--output=json")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to render catalog %s: %w: %s", image, err, strings.TrimSpace(stderr.String()))
+	}
+
+	idx, err := Parse(&stdout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse catalog %s: %w", image, err)
+	}
+	idx.Image = image
+	return idx, nil
+}
+
+// declarativeConfig is the subset of an FBC blob needed to list packages,
+// channels and the images of the bundles
+type declarativeConfig struct {
+	Schema         string `json:"schema"`
+	Name           string `json:"name"`
+	Package        string `json:"package"`
+	DefaultChannel string `json:"defaultChannel"`
+	Entries        []struct {
+		Name     string   `json:"name"`
+		Replaces string   `json:"replaces"`
+		Skips    []string `json:"skips"`
+	} `json:"entries"`
+	Image      string `json:"image"`
+	Properties []struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	} `json:"properties"`
+	RelatedImages []struct {
+		Image string `json:"image"`
+	} `json:"relatedImages"`
+}
+
+// Parse reads the stream of file-based catalog JSON objects produced by `opm render`
+func Parse(r io.Reader) (*Index, error) {
+	packages := make(map[string]*Package)
+	getPackage := func(name string) *Package {
+		if pkg, ok := packages[name]; ok {
+			return pkg
+		}
+		pkg := &Package{Name: name}
+		packages[name] = pkg
+		return pkg
+	}
+
+	bundles := make(map[string]Bundle)
+
+	decoder := json.NewDecoder(r)
+	for {
+		var blob declarativeConfig
+		if err := decoder.Decode(&blob); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		switch blob.Schema {
+		case "olm.package":
+			getPackage(blob.Name).DefaultChannel = blob.DefaultChannel
+		case "olm.channel":
+			channel := Channel{Name: blob.Name}
+			replaced := make(map[string]bool)
+			for _, entry := range blob.Entries {
+				channel.Bundles = append(channel.Bundles, entry.Name)
+				if entry.Replaces != "" {
+					replaced[entry.Replaces] = true
+				}
+				for _, skipped := range entry.Skips {
+					replaced[skipped] = true
+				}
+			}
+			for _, name := range channel.Bundles {
+				if !replaced[name] && (channel.Head == "" || CompareVersions(BundleVersion(name), BundleVersion(channel.Head)) > 0) {
+					channel.Head = name
+				}
+			}
+			pkg := getPackage(blob.Package)
+			pkg.Channels = append(pkg.Channels, channel)
+		case "olm.bundle":
+			bundle := Bundle{Name: blob.Name, Package: blob.Package, Image: blob.Image}
+			for _, property := range blob.Properties {
+				if property.Type != "olm.package" {
+					continue
+				}
+				var value struct {
+					Version string `json:"version"`
+				}
+				if err := json.Unmarshal(property.Value, &value); err == nil {
+					bundle.Version = value.Version
+				}
+			}
+			if bundle.Version == "" {
+				bundle.Version = BundleVersion(blob.Name)
+			}
+			for _, related := range blob.RelatedImages {
+				bundle.RelatedImages = append(bundle.RelatedImages, related.Image)
+			}
+			bundles[bundle.Name] = bundle
+		}
+	}
+
+	idx := &Index{Packages: make([]Package, 0, len(packages)), bundles: bundles}
+	for _, pkg := range packages {
+		sort.Slice(pkg.Channels, func(i, j int) bool { return pkg.Channels[i].Name < pkg.Channels[j].Name })
+		idx.Packages = append(idx.Packages, *pkg)
+	}
+	sort.Slice(idx.Packages, func(i, j int) bool { return idx.Packages[i].Name < idx.Packages[j].Name })
+	return idx, nil
+}
+
+// BundleVersion returns the version part of a bundle name (odf-operator.v4.19.6-rhodf -> 4.19.6-rhodf)
+func BundleVersion(bundle string) string {
+	if idx := strings.Index(bundle, ".v"); idx >= 0 {
+		return bundle[idx+2:]
+	}
+	if _, version, ok := strings.Cut(bundle, "."); ok {
+		return version
+	}
+	return bundle
+}
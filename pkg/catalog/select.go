@@ -0,0 +1,124 @@
+package catalog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ChannelSelection is a channel of a package in an imageset configuration,
+// with its optional version range
+type ChannelSelection struct {
+	Name       string
+	MinVersion string
+	MaxVersion string
+}
+
+// SelectBundles returns the bundles oc-mirror mirrors for a package of an
+// imageset configuration: per channel the bundles in the version range, or
+// the channel head without a range. Without channels the head of the default
+// channel is mirrored; defaultChannel overrides the one of the catalog.
+func (idx *Index) SelectBundles(name, defaultChannel string, channels []ChannelSelection) ([]Bundle, error) {
+	pkg := idx.Package(name)
+	if pkg == nil {
+		return nil, fmt.Errorf("package %s not found in %s", name, idx.Image)
+	}
+	if len(channels) == 0 {
+		if defaultChannel == "" {
+			defaultChannel = pkg.DefaultChannel
+		}
+		channels = []ChannelSelection{{Name: defaultChannel}}
+	}
+
+	seen := make(map[string]bool)
+	var bundles []Bundle
+	for _, selection := range channels {
+		channel := pkg.Channel(selection.Name)
+		if channel == nil {
+			return nil, fmt.Errorf("channel %s of package %s not found in %s", selection.Name, name, idx.Image)
+		}
+		if channel.Head == "" {
+			return nil, fmt.Errorf("channel %s of package %s has no bundles", selection.Name, name)
+		}
+		names := []string{channel.Head}
+		if selection.MinVersion != "" || selection.MaxVersion != "" {
+			names = channel.Bundles
+		}
+		selected := 0
+		for _, bundleName := range names {
+			bundle, ok := idx.Bundle(bundleName)
+			if !ok {
+				return nil, fmt.Errorf("bundle %s of package %s not found in %s", bundleName, name, idx.Image)
+			}
+			if selection.MinVersion != "" && CompareVersions(bundle.Version, selection.MinVersion) < 0 {
+				continue
+			}
+			if selection.MaxVersion != "" && CompareVersions(bundle.Version, selection.MaxVersion) > 0 {
+				continue
+			}
+			selected++
+			if !seen[bundle.Name] {
+				seen[bundle.Name] = true
+				bundles = append(bundles, bundle)
+			}
+		}
+		if selected == 0 {
+			return nil, fmt.Errorf("no bundle of channel %s of package %s in the version range", selection.Name, name)
+		}
+	}
+	return bundles, nil
+}
+
+// CompareVersions compares two semantic versions such as 4.19.0-202510142112,
+// returning -1, 0 or 1. A pre-release sorts before its release; build
+// metadata is ignored.
+func CompareVersions(a, b string) int {
+	a, _, _ = strings.Cut(strings.TrimPrefix(a, "v"), "+")
+	b, _, _ = strings.Cut(strings.TrimPrefix(b, "v"), "+")
+	coreA, preA, hasPreA := strings.Cut(a, "-")
+	coreB, preB, hasPreB := strings.Cut(b, "-")
+
+	if c := compareIdentifiers(strings.Split(coreA, "."), strings.Split(coreB, ".")); c != 0 {
+		return c
+	}
+	switch {
+	case hasPreA && !hasPreB:
+		return -1
+	case !hasPreA && hasPreB:
+		return 1
+	}
+	return compareIdentifiers(strings.Split(preA, "."), strings.Split(preB, "."))
+}
+
+// compareIdentifiers compares dot-separated version parts, numerically when
+// both are numbers; a shorter list sorts first
+func compareIdentifiers(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		numA, errA := strconv.ParseUint(a[i], 10, 64)
+		numB, errB := strconv.ParseUint(b[i], 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			if numA != numB {
+				if numA < numB {
+					return -1
+				}
+				return 1
+			}
+		case errA == nil:
+			return -1 // Numeric identifiers sort before alphanumeric ones
+		case errB == nil:
+			return 1
+		default:
+			if c := strings.Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
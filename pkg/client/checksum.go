@@ -0,0 +1,122 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// checksumFile is the digest list published next to the tarballs of each
+// release directory of the mirror
+const checksumFile = "sha256sum.txt"
+
+// releaseChecksums returns the sha256 digests of the files of a release
+// directory, or of the staged tarballs, by file name, reading its
+// sha256sum.txt once per downloader
+func (d *Downloader) releaseChecksums(ctx context.Context, dirURL string) (map[string]string, error) {
+	if d.SourceDir != "" {
+		dirURL = d.SourceDir
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if sums, ok := d.checksums[dirURL]; ok {
+		return sums, nil
+	}
+
+	body, err := d.openChecksums(ctx, dirURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	sums, err := parseChecksums(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", checksumFile, err)
+	}
+
+	if d.checksums == nil {
+		d.checksums = make(map[string]map[string]string)
+	}
+	d.checksums[dirURL] = sums
+	return sums, nil
+}
+
+// openChecksums opens the sha256sum.txt of a release directory on the mirror,
+// or of the staged tarballs
+func (d *Downloader) openChecksums(ctx context.Context, dirURL string) (io.ReadCloser, error) {
+	if d.SourceDir != "" {
+		file, err := os.Open(filepath.Join(d.SourceDir, checksumFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", checksumFile, err)
+		}
+		return file, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", dirURL+"/"+checksumFile, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", checksumFile, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to fetch %s: HTTP %d: %s", checksumFile, resp.StatusCode, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// fileSHA256 returns the sha256 of a file
+func fileSHA256(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// parseChecksums reads sha256sum output: a digest and a file name per line,
+// the name prefixed with * for files read in binary mode
+func parseChecksums(r io.Reader) (map[string]string, error) {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return sums, scanner.Err()
+}
+
+// verifyChecksum checks the sha256 digest of a downloaded tarball against the
+// sha256sum.txt of its release directory
+func (d *Downloader) verifyChecksum(ctx context.Context, url, digest string) error {
+	dirURL, name := path.Split(url)
+	sums, err := d.releaseChecksums(ctx, strings.TrimSuffix(dirURL, "/"))
+	if err != nil {
+		return err
+	}
+	expected, ok := sums[name]
+	if !ok {
+		return fmt.Errorf("%s is not listed in %s", name, checksumFile)
+	}
+	if expected != digest {
+		return fmt.Errorf("checksum mismatch for %s: expected sha256:%s, got sha256:%s", name, expected, digest)
+	}
+	return nil
+}
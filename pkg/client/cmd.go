@@ -3,25 +3,32 @@ package client
 import (
 	"context"
 	"fmt"
-	"os"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
 )
 
+// DefaultOCPVersion is the OpenShift version the client tools are downloaded for
+const DefaultOCPVersion = "4.20"
+
 // NewDownloadCommand creates a cobra command for downloading client tools
 func NewDownloadCommand() *cobra.Command {
 	var ocpVersion string
 	var binDir string
 	var tools []string
+	var skipChecksum bool
+	var targetArch, targetOS string
+	var sourceDir string
+	var maxAttempts int
 
 	cmd := &cobra.Command{
 		Use:   "download",
 		Short: "Download OpenShift client tools (oc, opm, oc-mirror)",
 		Long:  "Downloads and installs OpenShift client tools from the official mirror. Supports concurrent downloads and automatic system detection.",
+		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if ocpVersion == "" {
-				ocpVersion = "4.20"
+				ocpVersion = DefaultOCPVersion
 			}
 			if binDir == "" {
 				binDir = "./bin"
@@ -35,6 +42,12 @@ func NewDownloadCommand() *cobra.Command {
 				return fmt.Errorf("failed to create downloader: %w", err)
 			}
 			defer downloader.Cleanup()
+			downloader.SkipChecksum = skipChecksum
+			downloader.SourceDir = sourceDir
+			downloader.MaxAttempts = maxAttempts
+			if err := downloader.SetTarget(targetArch, targetOS); err != nil {
+				return err
+			}
 
 			// Set progress callback
 			downloader.SetProgressFunc(func(tool string, downloaded, total int64) {
@@ -54,6 +67,12 @@ func NewDownloadCommand() *cobra.Command {
 			fmt.Printf("    RHEL Version: %s\n", downloader.RHELVersion)
 			fmt.Printf("    OpenShift Version: %s\n", downloader.OCPVersion)
 			fmt.Printf("    Target Directory: %s\n", downloader.BinDir)
+			if downloader.SourceDir != "" {
+				fmt.Printf("    Source: staged tarballs in %s\n", downloader.SourceDir)
+			}
+			if !downloader.native() {
+				fmt.Printf("    Cross-target: the binaries do not run on this host and are not executed to verify them\n")
+			}
 			fmt.Printf("\n")
 
 			ctx := context.Background()
@@ -74,6 +93,17 @@ func NewDownloadCommand() *cobra.Command {
 					fmt.Printf("║  ✅ %s: SUCCESS\n", result.Tool)
 					fmt.Printf("║     Version: %s\n", result.Version)
 					fmt.Printf("║     Location: %s\n", result.Path)
+					if len(result.Attempts) > 1 {
+						fmt.Printf("║     Attempts: %d, %d resumed\n", len(result.Attempts), result.Resumed())
+					}
+					switch {
+					case result.Verified:
+						fmt.Printf("║     Checksum: sha256:%s (verified)\n", result.Checksum)
+					case result.Checksum != "":
+						fmt.Printf("║     Checksum: sha256:%s (not verified)\n", result.Checksum)
+					default:
+						fmt.Printf("║     Checksum: already installed, not downloaded\n")
+					}
 				} else {
 					fmt.Printf("║  ❌ %s: FAILED\n", result.Tool)
 					if result.Error != nil {
@@ -101,69 +131,66 @@ func NewDownloadCommand() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&ocpVersion, "version", "v", "4.20", "OpenShift version to download")
+	cmd.Flags().StringVarP(&ocpVersion, "version", "v", DefaultOCPVersion, "OpenShift version to download")
+	// -v stays --version here; this shadows the global -v/--verbose, so it is long-form only
+	cmd.Flags().Bool("verbose", false, "Print debug output (--log-level debug)")
 	cmd.Flags().StringVarP(&binDir, "bin-dir", "b", "./bin", "Directory to install binaries")
+	cmd.Flags().StringVar(&targetArch, "target-arch", "", "Architecture to download the tools for: amd64 (x86_64), arm64 (aarch64), ppc64le or s390x (default: the host's)")
+	cmd.Flags().StringVar(&targetOS, "target-os", "", "Operating system to download the tools for: linux or mac (default: the host's)")
+	cmd.Flags().StringVar(&sourceDir, "from-dir", "", "Install from the tarballs and sha256sum.txt staged in this directory instead of downloading them (disconnected hosts)")
+	cmd.Flags().IntVar(&maxAttempts, "download-attempts", defaultMaxAttempts, "Attempts of each download; a retry resumes from the bytes already downloaded when the server supports ranges")
+	cmd.Flags().BoolVar(&skipChecksum, "skip-checksum", false, "Install the tools without verifying the tarballs against the sha256sum.txt of the release")
 	cmd.Flags().StringSliceVarP(&tools, "tools", "t", []string{"oc", "opm", "oc-mirror"}, "Tools to download (oc, opm, oc-mirror)")
 
 	return cmd
 }
 
-// EnsureTools ensures required tools are available, downloading if necessary
-func EnsureTools(ctx context.Context, binDir string, tools []string) error {
-	// First check if tools are in PATH
-	var toolsToDownload []string
-	for _, tool := range tools {
-		if path, err := CheckToolInPath(tool); err == nil {
-			// Tool found in PATH, verify it works
-			downloader, _ := NewDownloader("4.20", binDir)
-			if downloader != nil {
-				if _, err := downloader.verifyTool(path, tool); err == nil {
-					continue // Tool is available and working
-				}
-			}
-		}
-		toolsToDownload = append(toolsToDownload, tool)
-	}
-
-	if len(toolsToDownload) == 0 {
-		return nil // All tools already available in PATH
+// EnsureTools ensures required tools are available for an OpenShift version,
+// downloading the missing ones into binDir, or installing them from the
+// tarballs staged in sourceDir when it is set. A tool in binDir is preferred to
+// one in PATH, as the commands run with binDir first in their PATH. It
+// returns the tools it downloaded.
+func EnsureTools(ctx context.Context, ocpVersion, binDir, sourceDir string, tools []string) ([]DownloadResult, error) {
+	if ocpVersion == "" {
+		ocpVersion = DefaultOCPVersion
 	}
-
-	downloader, err := NewDownloader("4.20", binDir)
+	downloader, err := NewDownloader(ocpVersion, binDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer downloader.Cleanup()
+	downloader.SourceDir = sourceDir
 
-	// Check which tools need downloading from binDir
 	var toolsNeedingDownload []string
-	for _, tool := range toolsToDownload {
-		toolPath := filepath.Join(binDir, tool)
-		if _, err := os.Stat(toolPath); os.IsNotExist(err) {
-			toolsNeedingDownload = append(toolsNeedingDownload, tool)
-		} else if _, err := downloader.verifyTool(toolPath, tool); err != nil {
-			// Tool exists but verification failed, re-download
-			toolsNeedingDownload = append(toolsNeedingDownload, tool)
+	for _, tool := range tools {
+		if _, err := downloader.verifyTool(filepath.Join(binDir, tool), tool); err == nil {
+			continue // Tool is available in binDir
+		}
+		if path, err := CheckToolInPath(tool); err == nil {
+			if _, err := downloader.verifyTool(path, tool); err == nil {
+				continue // Tool is available and working
+			}
 		}
+		toolsNeedingDownload = append(toolsNeedingDownload, tool)
 	}
 
 	if len(toolsNeedingDownload) == 0 {
-		return nil // All tools already available in binDir
+		return nil, nil // All tools already available
 	}
 
 	// Download missing tools
 	results, err := downloader.DownloadAll(ctx, toolsNeedingDownload)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Check results
 	for _, result := range results {
 		if !result.Success {
-			return fmt.Errorf("failed to download %s: %w", result.Tool, result.Error)
+			return results, fmt.Errorf("failed to download %s: %w", result.Tool, result.Error)
 		}
 	}
 
-	return nil
+	return results, nil
 }
 
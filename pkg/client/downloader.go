@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -26,8 +27,13 @@ type Downloader struct {
 	OS           string
 	RHELVersion  string
 	HTTPClient   *http.Client
+	SkipChecksum bool   // Install tarballs without checking them against sha256sum.txt
+	SourceDir    string // Pre-staged tarballs and sha256sum.txt installed instead of downloading
+	MaxAttempts  int           // Attempts of a download, resumed where the previous one stopped
+	RetryBackoff time.Duration // Wait before the first retry, doubled for each following one
 	mu           sync.Mutex
 	progressFunc func(tool string, downloaded, total int64)
+	checksums    map[string]map[string]string // sha256sum.txt of each release directory
 }
 
 // Tool represents a client tool to download
@@ -45,6 +51,14 @@ type DownloadResult struct {
 	Version string
 	Path    string
 	Error   error
+
+	// sha256 of the downloaded tarball and whether it matched the
+	// sha256sum.txt of the release; empty for a tool that was already installed
+	Checksum string
+	Verified bool
+
+	// Attempts of the download, more than one when it was retried or resumed
+	Attempts []DownloadAttempt
 }
 
 // NewDownloader creates a new downloader instance
@@ -65,12 +79,14 @@ func NewDownloader(ocpVersion, binDir string) (*Downloader, error) {
 
 	return &Downloader{
 		OCPVersion:  ocpVersion,
-		BaseURL:     "https://mirror.openshift.com/pub/openshift-v4/x86_64/clients",
+		BaseURL:     clientsURL(arch, osName),
 		BinDir:      binDir,
 		DownloadDir: downloadDir,
 		Arch:        arch,
 		OS:          osName,
 		RHELVersion: rhelVersion,
+		MaxAttempts:  defaultMaxAttempts,
+		RetryBackoff: defaultRetryBackoff,
 		HTTPClient: &http.Client{
 			Timeout: 30 * time.Minute,
 			Transport: &http.Transport{
@@ -214,11 +230,12 @@ func (d *Downloader) DownloadTool(ctx context.Context, toolName string) Download
 		Tool: toolName,
 	}
 
-	// Check if tool already exists
+	// Check if tool already exists; a binary of another target cannot be
+	// told apart from a host one, so it is downloaded again
 	toolPath := filepath.Join(d.BinDir, toolName)
-	if info, err := os.Stat(toolPath); err == nil && info.Mode().IsRegular() {
+	if info, err := os.Stat(toolPath); err == nil && info.Mode().IsRegular() && d.native() {
 		// Tool exists, verify it
-		if version, err := d.verifyTool(toolPath, toolName); err == nil {
+		if version, err := d.checkTool(toolPath, toolName); err == nil {
 			result.Success = true
 			result.Version = version
 			result.Path = toolPath
@@ -226,25 +243,10 @@ func (d *Downloader) DownloadTool(ctx context.Context, toolName string) Download
 		}
 	}
 
-	// Determine download URL based on tool
-	var downloadURL string
-	var extractBinaryName string
-
-	switch toolName {
-	case "oc":
-		downloadURL = fmt.Sprintf("%s/ocp/stable-%s/openshift-client-%s-%s-%s.tar.gz",
-			d.BaseURL, d.OCPVersion, d.OS, d.Arch, d.RHELVersion)
-		extractBinaryName = "oc"
-	case "opm":
-		downloadURL = fmt.Sprintf("%s/ocp/stable-%s/opm-%s-%s.tar.gz",
-			d.BaseURL, d.OCPVersion, d.OS, d.RHELVersion)
-		extractBinaryName = "opm"
-	case "oc-mirror":
-		downloadURL = fmt.Sprintf("%s/ocp/stable-%s/oc-mirror.tar.gz",
-			d.BaseURL, d.OCPVersion)
-		extractBinaryName = "oc-mirror"
-	default:
-		result.Error = fmt.Errorf("unknown tool: %s", toolName)
+	// Determine download URL based on tool and target
+	downloadURL, extractBinaryName, err := d.toolURL(toolName)
+	if err != nil {
+		result.Error = err
 		return result
 	}
 
@@ -253,16 +255,20 @@ func (d *Downloader) DownloadTool(ctx context.Context, toolName string) Download
 		downloadURL,
 		fmt.Sprintf("%s/ocp/latest/%s", d.BaseURL, filepath.Base(downloadURL)),
 	}
+	if d.SourceDir != "" {
+		fallbackURLs = fallbackURLs[:1] // Both name the same staged tarball
+	}
 
 	var downloadErr error
 	for _, url := range fallbackURLs {
-		if err := d.downloadAndExtract(ctx, url, toolName, extractBinaryName); err != nil {
+		if err := d.downloadAndExtract(ctx, url, toolName, extractBinaryName, &result); err != nil {
 			downloadErr = err
 			continue
 		}
+		result.Verified = !d.SkipChecksum
 
 		// Verify installation
-		if version, err := d.verifyTool(toolPath, toolName); err == nil {
+		if version, err := d.checkTool(toolPath, toolName); err == nil {
 			result.Success = true
 			result.Version = version
 			result.Path = toolPath
@@ -274,76 +280,40 @@ func (d *Downloader) DownloadTool(ctx context.Context, toolName string) Download
 	return result
 }
 
-// downloadAndExtract downloads and extracts a tool
-func (d *Downloader) downloadAndExtract(ctx context.Context, url, toolName, extractBinaryName string) error {
+// downloadAndExtract downloads a tool, verifies the tarball against the
+// sha256sum.txt of its release and extracts it. It records the sha256 of the
+// tarball and the download attempts in result.
+func (d *Downloader) downloadAndExtract(ctx context.Context, url, toolName, extractBinaryName string, result *DownloadResult) error {
 	tempFile := filepath.Join(d.DownloadDir, fmt.Sprintf("%s.tar.gz", toolName))
 	defer os.Remove(tempFile)
 
-	// Download file
-	if err := d.downloadFile(ctx, url, tempFile, toolName); err != nil {
-		return fmt.Errorf("download failed: %w", err)
-	}
-
-	// Extract binary
-	if err := d.extractBinary(tempFile, extractBinaryName, toolName); err != nil {
-		return fmt.Errorf("extraction failed: %w", err)
-	}
-
-	return nil
-}
-
-// downloadFile downloads a file with progress reporting
-func (d *Downloader) downloadFile(ctx context.Context, url, destPath, toolName string) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return err
-	}
-
-	resp, err := d.HTTPClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	// Download file, or take it from the staged tarballs
+	tarball := tempFile
+	if d.SourceDir != "" {
+		tarball = filepath.Join(d.SourceDir, path.Base(url))
+	} else {
+		attempts, err := d.downloadFile(ctx, url, tarball, toolName)
+		result.Attempts = append(result.Attempts, attempts...)
+		if err != nil {
+			return fmt.Errorf("download failed: %w", err)
+		}
 	}
-
-	file, err := os.Create(destPath)
+	checksum, err := fileSHA256(tarball)
 	if err != nil {
-		return err
+		return fmt.Errorf("download failed: %w", err)
 	}
-	defer file.Close()
+	result.Checksum = checksum
 
-	// Copy with progress reporting using io.Copy for better performance
-	total := resp.ContentLength
-	var downloaded int64
-	
-	// Use io.Copy with custom writer for progress tracking
-	writer := &progressWriter{
-		writer: file,
-		onWrite: func(n int64) {
-			downloaded += n
-			if d.progressFunc != nil {
-				d.progressFunc(toolName, downloaded, total)
-			}
-		},
+	// Verify the tarball before anything is extracted from it
+	if !d.SkipChecksum {
+		if err := d.verifyChecksum(ctx, url, checksum); err != nil {
+			return fmt.Errorf("checksum verification failed: %w", err)
+		}
 	}
 
-	// Copy in background with context cancellation
-	done := make(chan error, 1)
-	go func() {
-		_, err := io.Copy(writer, resp.Body)
-		done <- err
-	}()
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case err := <-done:
-		if err != nil {
-			return err
-		}
+	// Extract binary
+	if err := d.extractBinary(tarball, extractBinaryName, toolName); err != nil {
+		return fmt.Errorf("extraction failed: %w", err)
 	}
 
 	return nil
@@ -446,6 +416,22 @@ func (pw *progressWriter) Write(p []byte) (n int, err error) {
 	return n, err
 }
 
+// checkTool verifies a tool installation; the binaries of another target
+// cannot be run, so only their file is checked
+func (d *Downloader) checkTool(toolPath, toolName string) (string, error) {
+	if d.native() {
+		return d.verifyTool(toolPath, toolName)
+	}
+	info, err := os.Stat(toolPath)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a regular file", toolPath)
+	}
+	return fmt.Sprintf("not run (%s/%s target)", d.OS, d.Arch), nil
+}
+
 // verifyTool verifies a tool installation by running version command
 func (d *Downloader) verifyTool(toolPath, toolName string) (string, error) {
 	// Check if file exists and is executable
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultMaxAttempts is the number of attempts of a download
+	defaultMaxAttempts = 5
+
+	// defaultRetryBackoff is the wait before the first retry of a download
+	defaultRetryBackoff = 2 * time.Second
+
+	// maxRetryBackoff caps the doubled wait between attempts
+	maxRetryBackoff = time.Minute
+)
+
+// DownloadAttempt is one request of a download. A retry asks for the bytes
+// after those already on disk with a Range header.
+type DownloadAttempt struct {
+	Attempt  int
+	Offset   int64 // Bytes already on disk when the attempt started
+	Bytes    int64 // Bytes received by the attempt
+	Status   int   // HTTP status, 0 if no response was received
+	Resumed  bool  // The server answered the range with 206
+	Duration time.Duration
+	Error    string
+}
+
+// errPermanent marks a download failure that retrying cannot fix
+var errPermanent = errors.New("not retried")
+
+// downloadFile downloads a file with progress reporting. Interrupted
+// downloads are retried with backoff and resumed from the bytes already
+// written when the server supports ranges.
+func (d *Downloader) downloadFile(ctx context.Context, url, destPath, toolName string) ([]DownloadAttempt, error) {
+	file, err := os.Create(destPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	maxAttempts := max(1, d.MaxAttempts)
+	backoff := d.RetryBackoff
+	var attempts []DownloadAttempt
+	for attempt := 1; ; attempt++ {
+		offset, err := file.Seek(0, io.SeekEnd)
+		if err != nil {
+			return attempts, err
+		}
+		record := DownloadAttempt{Attempt: attempt, Offset: offset}
+		started := time.Now()
+		err = d.downloadAttempt(ctx, url, file, toolName, &record)
+		record.Duration = time.Since(started)
+		if err == nil {
+			attempts = append(attempts, record)
+			return attempts, nil
+		}
+		record.Error = err.Error()
+		attempts = append(attempts, record)
+		if errors.Is(err, errPermanent) || ctx.Err() != nil || attempt == maxAttempts {
+			return attempts, err
+		}
+
+		if d.progressFunc != nil {
+			fmt.Println() // Ends the progress line
+		}
+		fmt.Printf("  │ Warning: %s download attempt %d/%d failed: %v, retrying in %v\n", toolName, attempt, maxAttempts, err, backoff)
+		select {
+		case <-ctx.Done():
+			return attempts, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+// downloadAttempt requests the bytes of url after those already in file and
+// appends them. A server that ignores the range sends the whole file, which
+// then replaces the partial one.
+func (d *Downloader) downloadAttempt(ctx context.Context, url string, file *os.File, toolName string, record *DownloadAttempt) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("%v (%w)", err, errPermanent)
+	}
+	if record.Offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", record.Offset))
+	}
+
+	resp, err := d.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	record.Status = resp.StatusCode
+
+	total := resp.ContentLength
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && contentRangeStart(resp.Header.Get("Content-Range")) == record.Offset:
+		record.Resumed = true
+		if total >= 0 {
+			total += record.Offset
+		}
+	case resp.StatusCode == http.StatusOK:
+		if err := file.Truncate(0); err != nil {
+			return fmt.Errorf("%v (%w)", err, errPermanent)
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("%v (%w)", err, errPermanent)
+		}
+		record.Offset = 0
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable || resp.StatusCode == http.StatusPartialContent:
+		// The partial file does not match the server's: start over
+		if err := file.Truncate(0); err != nil {
+			return fmt.Errorf("%v (%w)", err, errPermanent)
+		}
+		return fmt.Errorf("HTTP %d: range from byte %d not usable, restarting", resp.StatusCode, record.Offset)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	default:
+		return fmt.Errorf("HTTP %d: %s (%w)", resp.StatusCode, resp.Status, errPermanent)
+	}
+
+	// Copy with progress reporting
+	downloaded := record.Offset
+	writer := &progressWriter{
+		writer: file,
+		onWrite: func(n int64) {
+			record.Bytes += n
+			downloaded += n
+			if d.progressFunc != nil {
+				d.progressFunc(toolName, downloaded, total)
+			}
+		},
+	}
+	if _, err := io.Copy(writer, resp.Body); err != nil {
+		return err
+	}
+	if resp.ContentLength >= 0 && record.Bytes < resp.ContentLength {
+		return fmt.Errorf("connection closed after %d of %d bytes", record.Bytes, resp.ContentLength)
+	}
+	return nil
+}
+
+// contentRangeStart returns the first byte of a Content-Range header
+// (bytes 100-199/200), -1 if it cannot be parsed
+func contentRangeStart(header string) int64 {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return -1
+	}
+	first, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return -1
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return start
+}
+
+// Resumed returns the number of attempts that continued a partial download
+func (r DownloadResult) Resumed() int {
+	resumed := 0
+	for _, a := range r.Attempts {
+		if a.Resumed {
+			resumed++
+		}
+	}
+	return resumed
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// mirrorURL is the root of the OpenShift client downloads; each architecture
+// has its own clients directory under it
+const mirrorURL = "https://mirror.openshift.com/pub/openshift-v4"
+
+// mirrorArches maps the architecture names of the downloader to the
+// directories of the mirror
+var mirrorArches = map[string]string{
+	"amd64":   "x86_64",
+	"arm64":   "aarch64",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+}
+
+// clientsURL returns the clients directory of a target on the mirror. The
+// mac clients of both architectures are published with the x86_64 ones.
+func clientsURL(arch, osName string) string {
+	if osName == "mac" {
+		arch = "amd64"
+	}
+	return fmt.Sprintf("%s/%s/clients", mirrorURL, mirrorArches[arch])
+}
+
+// normalizeArch accepts the Go and the mirror name of an architecture
+func normalizeArch(arch string) (string, error) {
+	arch = strings.ToLower(arch)
+	for name, dir := range mirrorArches {
+		if arch == name || arch == dir {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported target architecture %q (expected amd64, arm64, ppc64le or s390x)", arch)
+}
+
+// normalizeOS accepts the Go and the mirror name of an operating system
+func normalizeOS(osName string) (string, error) {
+	switch strings.ToLower(osName) {
+	case "linux":
+		return "linux", nil
+	case "darwin", "mac", "macos":
+		return "mac", nil
+	}
+	return "", fmt.Errorf("unsupported target OS %q (expected linux or mac)", osName)
+}
+
+// SetTarget makes the downloader fetch the tools of another architecture or
+// operating system than the host's, e.g. arm64 Linux binaries staged from an
+// amd64 Mac for an edge box. Empty values keep the detected ones. The RHEL
+// version of a Linux target on another host is rhel9.
+func (d *Downloader) SetTarget(arch, osName string) error {
+	if arch != "" {
+		normalized, err := normalizeArch(arch)
+		if err != nil {
+			return err
+		}
+		d.Arch = normalized
+	}
+	if osName != "" {
+		normalized, err := normalizeOS(osName)
+		if err != nil {
+			return err
+		}
+		d.OS = normalized
+	}
+	if d.OS == "mac" && d.Arch != "amd64" && d.Arch != "arm64" {
+		return fmt.Errorf("no mac clients are published for %s", d.Arch)
+	}
+	if !d.native() {
+		d.RHELVersion = "rhel9"
+	}
+	d.BaseURL = clientsURL(d.Arch, d.OS)
+	return nil
+}
+
+// native returns true if the target is the host, so downloaded tools can be
+// run to verify them
+func (d *Downloader) native() bool {
+	hostOS := runtime.GOOS
+	if hostOS == "darwin" {
+		hostOS = "mac"
+	}
+	return d.Arch == runtime.GOARCH && d.OS == hostOS
+}
+
+// toolURL returns the tarball of a tool for the target and the binary to
+// extract from it. The client and opm are published per OS and architecture;
+// oc-mirror only for Linux, in the directory of each architecture.
+func (d *Downloader) toolURL(toolName string) (string, string, error) {
+	release := fmt.Sprintf("%s/ocp/stable-%s", d.BaseURL, d.OCPVersion)
+	switch toolName {
+	case "oc":
+		if d.OS == "mac" {
+			if d.Arch == "arm64" {
+				return release + "/openshift-client-mac-arm64.tar.gz", "oc", nil
+			}
+			return release + "/openshift-client-mac.tar.gz", "oc", nil
+		}
+		return fmt.Sprintf("%s/openshift-client-linux-%s-%s.tar.gz", release, d.Arch, d.RHELVersion), "oc", nil
+	case "opm":
+		if d.OS == "mac" {
+			return release + "/opm-mac.tar.gz", "opm", nil
+		}
+		return fmt.Sprintf("%s/opm-linux-%s.tar.gz", release, d.RHELVersion), "opm", nil
+	case "oc-mirror":
+		if d.OS != "linux" {
+			return "", "", fmt.Errorf("oc-mirror is only published for linux")
+		}
+		return release + "/oc-mirror.tar.gz", "oc-mirror", nil
+	}
+	return "", "", fmt.Errorf("unknown tool: %s", toolName)
+}
@@ -1,5 +1,7 @@
 package command
 
+import "time"
+
 // OCMirrorCommandBuilder provides a fluent interface for building oc-mirror commands
 // This implements the Builder pattern for better OOP design
 type OCMirrorCommandBuilder struct {
@@ -67,6 +69,18 @@ func (b *OCMirrorCommandBuilder) WithWorkspace(workspace string) *OCMirrorComman
 	return b
 }
 
+// WithExtraArgs sets additional oc-mirror arguments and returns the builder
+func (b *OCMirrorCommandBuilder) WithExtraArgs(args []string) *OCMirrorCommandBuilder {
+	b.cmd.SetExtraArgs(args)
+	return b
+}
+
+// WithTimeout sets how long oc-mirror may run and returns the builder
+func (b *OCMirrorCommandBuilder) WithTimeout(timeout time.Duration) *OCMirrorCommandBuilder {
+	b.cmd.SetTimeout(timeout)
+	return b
+}
+
 // Build returns the configured OCMirrorCommand
 func (b *OCMirrorCommandBuilder) Build() *OCMirrorCommand {
 	return b.cmd
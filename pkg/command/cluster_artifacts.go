@@ -0,0 +1,147 @@
+package command
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// maxListedMismatches limits the mismatching references stored; the count covers all of them
+const maxListedMismatches = 50
+
+// ClusterArtifactValidation checks that the cluster resources of an upload
+// send the cluster to the registry that was mirrored to
+type ClusterArtifactValidation struct {
+	Registry       string             `json:"Registry"`       // Expected prefix of every mirror and catalog image
+	Paths          []string           `json:"Paths"`          // Manifest files validated
+	MirrorSets     int                `json:"MirrorSets"`     // IDMS, ITMS and ICSP objects
+	Mirrors        int                `json:"Mirrors"`        // Mirror references of the mirror sets
+	CatalogSources int                `json:"CatalogSources"` // CatalogSource and ClusterCatalog objects
+	Checked        int                `json:"Checked"`        // Mirror and catalog image references checked
+	MismatchCount  int                `json:"MismatchCount"`
+	Mismatches     []ArtifactMismatch `json:"Mismatches,omitempty"` // First references outside the registry
+	Errors         []string           `json:"Errors,omitempty"`
+	Valid          bool               `json:"Valid"`
+}
+
+// ArtifactMismatch is a reference of a cluster resource that does not point at the registry
+type ArtifactMismatch struct {
+	File      string `json:"File"`
+	Kind      string `json:"Kind"`
+	Name      string `json:"Name"`
+	Reference string `json:"Reference"`
+}
+
+// ValidateClusterArtifacts parses the manifests listed in resources and checks
+// that the mirrors of the IDMS, ITMS and ICSP objects and the images of the
+// CatalogSource and ClusterCatalog objects are below registry, given as
+// host[:port][/path] with or without the docker:// transport
+func ValidateClusterArtifacts(resources *ClusterResourcesMetrics, registry string) *ClusterArtifactValidation {
+	prefix := strings.TrimSuffix(strings.TrimPrefix(registry, "docker://"), "/")
+	v := &ClusterArtifactValidation{Registry: prefix, Paths: make([]string, 0)}
+
+	for _, file := range resources.Files {
+		path := filepath.Join(resources.Directory, file.Name)
+		docs, err := readManifests(path)
+		if err != nil {
+			v.Errors = append(v.Errors, fmt.Sprintf("%s: %v", file.Name, err))
+			continue
+		}
+		v.Paths = append(v.Paths, path)
+		for _, doc := range docs {
+			spec, _ := doc.spec.(map[string]interface{})
+			var references []string
+			switch doc.kind {
+			case "ImageDigestMirrorSet", "ImageTagMirrorSet", "ImageContentSourcePolicy":
+				v.MirrorSets++
+				references = mirrorReferences(spec)
+				v.Mirrors += len(references)
+			case "CatalogSource":
+				v.CatalogSources++
+				if image, _ := spec["image"].(string); image != "" {
+					references = append(references, image)
+				}
+			case "ClusterCatalog":
+				v.CatalogSources++
+				source, _ := spec["source"].(map[string]interface{})
+				image, _ := source["image"].(map[string]interface{})
+				if ref, _ := image["ref"].(string); ref != "" {
+					references = append(references, ref)
+				}
+			default:
+				continue
+			}
+			for _, reference := range references {
+				v.Checked++
+				if underRegistry(reference, prefix) {
+					continue
+				}
+				v.MismatchCount++
+				if len(v.Mismatches) < maxListedMismatches {
+					v.Mismatches = append(v.Mismatches, ArtifactMismatch{File: file.Name, Kind: doc.kind, Name: doc.name, Reference: reference})
+				}
+			}
+		}
+	}
+
+	if v.Checked == 0 {
+		v.Errors = append(v.Errors, "no mirror set or catalog source found")
+	}
+	v.Valid = len(v.Errors) == 0 && v.MismatchCount == 0
+	return v
+}
+
+// mirrorReferences returns the mirrors of every entry of a mirror set spec:
+// imageDigestMirrors (IDMS), imageTagMirrors (ITMS) or repositoryDigestMirrors (ICSP)
+func mirrorReferences(spec map[string]interface{}) []string {
+	var references []string
+	for _, field := range []string{"imageDigestMirrors", "imageTagMirrors", "repositoryDigestMirrors"} {
+		entries, _ := spec[field].([]interface{})
+		for _, entry := range entries {
+			object, _ := entry.(map[string]interface{})
+			mirrors, _ := object["mirrors"].([]interface{})
+			for _, mirror := range mirrors {
+				if reference, ok := mirror.(string); ok {
+					references = append(references, reference)
+				}
+			}
+		}
+	}
+	return references
+}
+
+// underRegistry reports whether an image or repository reference is the
+// registry prefix or below it
+func underRegistry(reference, prefix string) bool {
+	return reference == prefix || strings.HasPrefix(reference, prefix+"/") ||
+		strings.HasPrefix(reference, prefix+":") || strings.HasPrefix(reference, prefix+"@")
+}
+
+// PrintSummary prints the artifact counts and the references outside the registry
+func (v *ClusterArtifactValidation) PrintSummary() {
+	if v == nil {
+		return
+	}
+	logging.Printf("  │ ─── Cluster Artifacts ────────────────────────────────────────\n")
+	logging.Printf("  │   Files: %d | Mirror sets: %d (%d mirrors) | Catalog sources: %d\n",
+		len(v.Paths), v.MirrorSets, v.Mirrors, v.CatalogSources)
+	for _, mismatch := range v.Mismatches {
+		logging.Printf("  │   ❌ %s %s/%s: %s\n", mismatch.File, mismatch.Kind, mismatch.Name, mismatch.Reference)
+	}
+	if v.MismatchCount > len(v.Mismatches) {
+		logging.Printf("  │   ... and %d more\n", v.MismatchCount-len(v.Mismatches))
+	}
+	for _, err := range v.Errors {
+		logging.Printf("  │ Warning: %s\n", err)
+	}
+	switch {
+	case v.Valid:
+		logging.Printf("  │   ✅ All %d references point at %s\n", v.Checked, v.Registry)
+	case v.MismatchCount > 0:
+		logging.Printf("  │   ❌ %d of %d references outside %s\n", v.MismatchCount, v.Checked, v.Registry)
+	default:
+		logging.Printf("  │   ❌ Cluster artifacts could not be validated\n")
+	}
+}
@@ -0,0 +1,287 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// Drift status of one generated resource
+const (
+	DriftInSync  = "in_sync" // Applied spec contains the generated spec
+	DriftMissing = "missing" // Not applied on the cluster
+	DriftChanged = "drifted" // Applied, but the spec differs
+	DriftError   = "error"   // Could not be read from the cluster
+)
+
+// driftKinds are the generated kinds whose applied state is compared: the
+// mirror sets and catalog sources that point the cluster at the mirror
+var driftKinds = map[string]bool{
+	"ImageDigestMirrorSet":     true,
+	"ImageTagMirrorSet":        true,
+	"ImageContentSourcePolicy": true,
+	"CatalogSource":            true,
+	"ClusterCatalog":           true,
+}
+
+// ClusterDriftReport compares the cluster resources oc-mirror generated with
+// the state applied on a cluster
+type ClusterDriftReport struct {
+	Kubeconfig string          `json:"Kubeconfig"`
+	Directory  string          `json:"Directory"` // Directory of the generated manifests
+	CheckedAt  time.Time       `json:"CheckedAt"`
+	Resources  []ResourceDrift `json:"Resources"`
+	InSync     int             `json:"InSync"`
+	Missing    int             `json:"Missing"`
+	Drifted    int             `json:"Drifted"`
+	Errors     int             `json:"Errors"`
+}
+
+// ResourceDrift is the comparison of one generated resource
+type ResourceDrift struct {
+	Kind        string   `json:"Kind"`
+	Namespace   string   `json:"Namespace,omitempty"`
+	Name        string   `json:"Name"`
+	File        string   `json:"File"`
+	Status      string   `json:"Status"`
+	Differences []string `json:"Differences,omitempty"` // Spec paths that differ from the generated manifest
+	Error       string   `json:"Error,omitempty"`
+}
+
+// HasDrift reports whether any generated resource is missing, differs or could not be checked
+func (r *ClusterDriftReport) HasDrift() bool {
+	return r.Missing > 0 || r.Drifted > 0 || r.Errors > 0
+}
+
+// generatedResource is one manifest document of a generated file
+type generatedResource struct {
+	file      string
+	kind      string
+	namespace string
+	name      string
+	spec      interface{}
+}
+
+// CompareClusterResources reads the generated manifests listed in resources
+// and compares each mirror set and catalog source with the object of the same
+// name on the cluster of kubeconfig, using oc. Only the generated spec is
+// compared: fields the cluster adds, such as defaults, are not drift.
+func CompareClusterResources(kubeconfig string, resources *ClusterResourcesMetrics) (*ClusterDriftReport, error) {
+	report := &ClusterDriftReport{Kubeconfig: kubeconfig, Directory: resources.Directory, CheckedAt: time.Now(), Resources: make([]ResourceDrift, 0)}
+
+	var generated []generatedResource
+	for _, file := range resources.Files {
+		docs, err := readManifests(filepath.Join(resources.Directory, file.Name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		for _, doc := range docs {
+			if driftKinds[doc.kind] {
+				doc.file = file.Name
+				generated = append(generated, doc)
+			}
+		}
+	}
+
+	for _, resource := range generated {
+		drift := ResourceDrift{Kind: resource.kind, Namespace: resource.namespace, Name: resource.name, File: resource.file}
+		applied, err := getClusterObject(kubeconfig, resource.kind, resource.namespace, resource.name)
+		switch {
+		case errors.Is(err, errNotFound):
+			drift.Status = DriftMissing
+			report.Missing++
+		case err != nil:
+			drift.Status = DriftError
+			drift.Error = err.Error()
+			report.Errors++
+		default:
+			drift.Differences = diffSpec("spec", resource.spec, applied["spec"])
+			if len(drift.Differences) > 0 {
+				drift.Status = DriftChanged
+				report.Drifted++
+			} else {
+				drift.Status = DriftInSync
+				report.InSync++
+			}
+		}
+		report.Resources = append(report.Resources, drift)
+	}
+	return report, nil
+}
+
+// readManifests decodes every YAML document of a manifest file, with values
+// normalized to their JSON types so they compare with objects read from the cluster
+func readManifests(path string) ([]generatedResource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []generatedResource
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc map[string]interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if doc == nil {
+			continue
+		}
+		normalized, err := normalizeJSON(doc)
+		if err != nil {
+			return nil, err
+		}
+		object, _ := normalized.(map[string]interface{})
+		metadata, _ := object["metadata"].(map[string]interface{})
+		kind, _ := object["kind"].(string)
+		name, _ := metadata["name"].(string)
+		namespace, _ := metadata["namespace"].(string)
+		if kind == "" || name == "" {
+			continue
+		}
+		resources = append(resources, generatedResource{kind: kind, namespace: namespace, name: name, spec: object["spec"]})
+	}
+	return resources, nil
+}
+
+// normalizeJSON converts a decoded YAML value to the types encoding/json decodes into
+func normalizeJSON(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
+
+// getClusterObject reads an object from the cluster with oc get
+func getClusterObject(kubeconfig, kind, namespace, name string) (map[string]interface{}, error) {
+	args := []string{"get", strings.ToLower(kind), name, "-o", "json"}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	output, err := runOC(kubeconfig, nil, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(output, &object); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return object, nil
+}
+
+// diffSpec returns the paths at which the applied value does not contain the
+// generated one. Objects may carry additional fields; lists are compared as
+// sets, so reordered entries are not drift but added or removed ones are.
+func diffSpec(path string, generated, applied interface{}) []string {
+	switch gen := generated.(type) {
+	case map[string]interface{}:
+		app, ok := applied.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: generated an object, applied %s", path, describeValue(applied))}
+		}
+		var diffs []string
+		keys := make([]string, 0, len(gen))
+		for key := range gen {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if _, ok := app[key]; !ok {
+				diffs = append(diffs, fmt.Sprintf("%s.%s: not applied", path, key))
+				continue
+			}
+			diffs = append(diffs, diffSpec(path+"."+key, gen[key], app[key])...)
+		}
+		return diffs
+	case []interface{}:
+		app, ok := applied.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: generated a list, applied %s", path, describeValue(applied))}
+		}
+		var diffs []string
+		for _, item := range setDifference(gen, app) {
+			diffs = append(diffs, fmt.Sprintf("%s[]: %s not applied", path, item))
+		}
+		for _, item := range setDifference(app, gen) {
+			diffs = append(diffs, fmt.Sprintf("%s[]: %s only on the cluster", path, item))
+		}
+		return diffs
+	default:
+		if describeValue(generated) != describeValue(applied) {
+			return []string{fmt.Sprintf("%s: generated %s, applied %s", path, describeValue(generated), describeValue(applied))}
+		}
+		return nil
+	}
+}
+
+// setDifference returns the items of a, as JSON, that are not in b
+func setDifference(a, b []interface{}) []string {
+	inB := make(map[string]bool, len(b))
+	for _, item := range b {
+		inB[describeValue(item)] = true
+	}
+	var missing []string
+	for _, item := range a {
+		if key := describeValue(item); !inB[key] {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// describeValue returns the JSON form of a value, which is also its identity
+// when comparing; encoding/json sorts object keys
+func describeValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// PrintSummary prints the drift of every compared resource
+func (r *ClusterDriftReport) PrintSummary() {
+	logging.Printf("\nCluster Drift (%s):\n", r.Kubeconfig)
+	if len(r.Resources) == 0 {
+		logging.Printf("  No mirror sets or catalog sources generated in %s\n", r.Directory)
+		return
+	}
+	for _, resource := range r.Resources {
+		name := resource.Kind + "/" + resource.Name
+		if resource.Namespace != "" {
+			name = resource.Kind + "/" + resource.Namespace + "/" + resource.Name
+		}
+		switch resource.Status {
+		case DriftInSync:
+			logging.Printf("  ✅ %s: in sync\n", name)
+		case DriftMissing:
+			logging.Printf("  ❌ %s: not applied\n", name)
+		case DriftChanged:
+			logging.Printf("  ❌ %s: %d difference(s)\n", name, len(resource.Differences))
+			for _, diff := range resource.Differences {
+				logging.Printf("       %s\n", diff)
+			}
+		default:
+			logging.Printf("  Warning: %s: %s\n", name, resource.Error)
+		}
+	}
+	logging.Printf("  In sync: %d | Missing: %d | Drifted: %d | Errors: %d\n", r.InSync, r.Missing, r.Drifted, r.Errors)
+}
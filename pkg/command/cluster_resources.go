@@ -0,0 +1,182 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// clusterResourcePatterns match the lines oc-mirror logs while it writes the
+// cluster resources: v2 "Generating IDMS file..." and "... file created", v1
+// "Writing ICSP manifests to ..." and "Writing CatalogSource manifests to ..."
+var clusterResourcePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)generating\s+(IDMS|ITMS|CatalogSource|ClusterCatalog|UpdateService|signature)`),
+	regexp.MustCompile(`(?i)skipping\s+(IDMS|ITMS)\s+generation`),
+	regexp.MustCompile(`(?i)cluster-resources/\S+\s+file created`),
+	regexp.MustCompile(`(?i)writing\s+(ICSP|CatalogSource|UpdateService|image mapping)`),
+}
+
+// yamlKindPattern matches the top-level kind of a manifest document
+var yamlKindPattern = regexp.MustCompile(`^kind:\s*(\S+)`)
+
+// ClusterResourcesMetrics describes the cluster resources (IDMS/ITMS/ICSP,
+// CatalogSource, ClusterCatalog, UpdateService, signature ConfigMaps) oc-mirror
+// generated at the end of a phase
+type ClusterResourcesMetrics struct {
+	GenerationTime time.Duration         `json:"GenerationTime"` // From the first to the last generation log line, until oc-mirror logs something else
+	Directory      string                `json:"Directory"`
+	TotalFiles     int                   `json:"TotalFiles"`
+	TotalBytes     int64                 `json:"TotalBytes"`
+	Kinds          map[string]int        `json:"Kinds"` // Manifest count per kind
+	Files          []ClusterResourceFile `json:"Files"`
+}
+
+// ClusterResourceFile is one generated manifest file
+type ClusterResourceFile struct {
+	Name  string   `json:"Name"`
+	Bytes int64    `json:"Bytes"`
+	Kinds []string `json:"Kinds"`
+}
+
+// clusterResourcesTiming tracks the generation log lines while output streams in
+type clusterResourcesTiming struct {
+	first time.Time // First generation line
+	last  time.Time // Last generation line
+	end   time.Time // First other line after the last generation line
+}
+
+func (t *clusterResourcesTiming) observe(line LogLine) {
+	if matchesAny(clusterResourcePatterns, line.Text) {
+		if t.first.IsZero() {
+			t.first = line.Time
+		}
+		t.last = line.Time
+		t.end = time.Time{}
+		return
+	}
+	if !t.last.IsZero() && t.end.IsZero() {
+		t.end = line.Time
+	}
+}
+
+// duration returns the generation time, zero when no generation was logged
+func (t *clusterResourcesTiming) duration() time.Duration {
+	if t.first.IsZero() {
+		return 0
+	}
+	end := t.end
+	if end.IsZero() {
+		end = t.last
+	}
+	return end.Sub(t.first)
+}
+
+// ExtractClusterResources combines the generation time from the logs with the
+// files written to dir during this command. It returns nil if neither the logs
+// nor the directory show generated resources.
+func (out *CommandOutput) ExtractClusterResources(dir string) *ClusterResourcesMetrics {
+	metrics := &ClusterResourcesMetrics{Directory: dir, Kinds: make(map[string]int), Files: make([]ClusterResourceFile, 0)}
+	if out != nil && out.analysis != nil {
+		metrics.GenerationTime = out.analysis.clusterResources.duration()
+	}
+
+	var since time.Time
+	if out != nil {
+		since = out.StartTime
+	}
+	if dir != "" {
+		scanClusterResources(dir, since, metrics)
+	}
+	if metrics.GenerationTime == 0 && metrics.TotalFiles == 0 {
+		return nil
+	}
+	return metrics
+}
+
+// scanClusterResources records the manifest files in dir written since start
+func scanClusterResources(dir string, since time.Time, metrics *ClusterResourcesMetrics) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".json")) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().Before(since) {
+			continue // Left over from an earlier run
+		}
+
+		file := ClusterResourceFile{Name: name, Bytes: info.Size(), Kinds: manifestKinds(filepath.Join(dir, name))}
+		metrics.Files = append(metrics.Files, file)
+		metrics.TotalFiles++
+		metrics.TotalBytes += file.Bytes
+		for _, kind := range file.Kinds {
+			metrics.Kinds[kind]++
+		}
+	}
+}
+
+// manifestKinds returns the kind of every YAML document in a manifest file
+func manifestKinds(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var kinds []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		if matches := yamlKindPattern.FindStringSubmatch(scanner.Text()); len(matches) > 1 {
+			kinds = append(kinds, strings.Trim(matches[1], `"'`))
+		}
+	}
+	return kinds
+}
+
+// LatestResultsDir returns the newest oc-mirror v1 results-* directory in
+// workspace, where v1 writes its ICSP and CatalogSource manifests
+func LatestResultsDir(workspace string) string {
+	matches, _ := filepath.Glob(filepath.Join(workspace, "results-*"))
+	var latest string
+	var latestTime time.Time
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		if info.ModTime().After(latestTime) {
+			latest, latestTime = match, info.ModTime()
+		}
+	}
+	return latest
+}
+
+// PrintSummary prints the generated resources and how long generation took
+func (m *ClusterResourcesMetrics) PrintSummary() {
+	if m == nil {
+		return
+	}
+	logging.Printf("  │ ─── Cluster Resources ────────────────────────────────────────\n")
+	logging.Printf("  │   Generation: %v | Files: %d | Size: %.1f KB\n",
+		m.GenerationTime.Round(time.Millisecond), m.TotalFiles, float64(m.TotalBytes)/1024)
+	if len(m.Kinds) > 0 {
+		kinds := make([]string, 0, len(m.Kinds))
+		for kind, count := range m.Kinds {
+			kinds = append(kinds, fmt.Sprintf("%s: %d", kind, count))
+		}
+		sort.Strings(kinds)
+		logging.Printf("  │   %s\n", strings.Join(kinds, " | "))
+	}
+}
@@ -0,0 +1,512 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+const (
+	// validationPollInterval is the interval between checks of the rollout and the pulls
+	validationPollInterval = 5 * time.Second
+	// rolloutGrace is how long machine config pools that report updated are
+	// trusted to not have picked up the applied mirror sets yet
+	rolloutGrace = 30 * time.Second
+	// validationLabel marks the objects created for the sample workload
+	validationLabel = "app.kubernetes.io/managed-by"
+)
+
+// ClusterValidationOptions describes the sample workload deployed to check
+// that a test cluster pulls its images from the mirror
+type ClusterValidationOptions struct {
+	Kubeconfig string
+	Namespace  string            // Created for the workload and deleted afterwards
+	Images     []string          // Each image is run as a pod, by its source reference
+	Operator   *OperatorWorkload // Optional operator subscribed from the mirrored catalog
+	Timeout    time.Duration     // For the mirror rollout and the pulls together
+}
+
+// OperatorWorkload is an operator package installed from a generated CatalogSource
+type OperatorWorkload struct {
+	Package string
+	Channel string // Empty subscribes to the default channel
+	Catalog string // Operator index the package was mirrored from, to pick its CatalogSource
+}
+
+// ClusterValidationReport is the outcome of applying the generated cluster
+// resources to a test cluster and running a workload from the mirror
+type ClusterValidationReport struct {
+	Kubeconfig  string        `json:"Kubeconfig"`
+	Namespace   string        `json:"Namespace"`
+	StartedAt   time.Time     `json:"StartedAt"`
+	Duration    time.Duration `json:"Duration"`
+	Applied     []string      `json:"Applied"` // Generated manifest files applied
+	ApplyTime   time.Duration `json:"ApplyTime"`
+	RolloutTime time.Duration `json:"RolloutTime"` // Until the machine config pools were updated with the mirror sets
+	Workloads   []string      `json:"Workloads"`
+	Pulls       []ImagePull   `json:"Pulls"`
+	Pulled      int           `json:"Pulled"`
+	Cached      int           `json:"Cached"`
+	Failed      int           `json:"Failed"`
+	Pending     int           `json:"Pending"`
+	AvgPullTime time.Duration `json:"AvgPullTime"`
+	MaxPullTime time.Duration `json:"MaxPullTime"`
+	Operator    string        `json:"Operator,omitempty"` // Installed CSV and its phase
+	Errors      []string      `json:"Errors,omitempty"`
+	Passed      bool          `json:"Passed"`
+}
+
+// catalogSource is a generated CatalogSource the cluster runs a catalog pod for
+type catalogSource struct {
+	namespace string
+	name      string
+	image     string
+}
+
+// generatedCatalogSources returns the CatalogSources among the generated manifests
+func generatedCatalogSources(resources *ClusterResourcesMetrics) ([]catalogSource, error) {
+	var catalogs []catalogSource
+	for _, file := range resources.Files {
+		docs, err := readManifests(filepath.Join(resources.Directory, file.Name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		for _, doc := range docs {
+			if doc.kind == "CatalogSource" {
+				spec, _ := doc.spec.(map[string]interface{})
+				image, _ := spec["image"].(string)
+				catalogs = append(catalogs, catalogSource{namespace: doc.namespace, name: doc.name, image: image})
+			}
+		}
+	}
+	return catalogs, nil
+}
+
+// ValidateOnCluster applies the generated manifests listed in resources to
+// the cluster of opts.Kubeconfig, waits for the machine config pools to pick
+// up the mirror sets and deploys the sample workload. Image pulls of the
+// workload and of the catalog source pods are read from the kubelet events
+// until every pod has pulled or failed, or the timeout expires. The applied
+// manifests are left in place; the workload namespace is deleted.
+func ValidateOnCluster(opts ClusterValidationOptions, resources *ClusterResourcesMetrics) (*ClusterValidationReport, error) {
+	report := &ClusterValidationReport{
+		Kubeconfig: opts.Kubeconfig,
+		Namespace:  opts.Namespace,
+		StartedAt:  time.Now(),
+		Applied:    make([]string, 0),
+		Workloads:  make([]string, 0),
+		Pulls:      make([]ImagePull, 0),
+	}
+	deadline := report.StartedAt.Add(opts.Timeout)
+
+	catalogs, err := generatedCatalogSources(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range resources.Files {
+		if _, err := runOC(opts.Kubeconfig, nil, "apply", "-f", filepath.Join(resources.Directory, file.Name)); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("apply %s: %v", file.Name, err))
+			continue
+		}
+		report.Applied = append(report.Applied, file.Name)
+	}
+	report.ApplyTime = time.Since(report.StartedAt)
+
+	appliedAt := time.Now()
+	if err := waitForMachineConfigPools(opts.Kubeconfig, appliedAt, deadline); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	report.RolloutTime = time.Since(appliedAt)
+
+	workload, err := validationWorkload(opts, catalogs)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	if _, err := runOC(opts.Kubeconfig, workload.manifest, "apply", "-f", "-"); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("deploy workload: %v", err))
+	} else {
+		report.Workloads = workload.names
+		pulls, operator, err := waitForPulls(opts, catalogs, workload, appliedAt, deadline)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+		report.Pulls = pulls
+		report.Operator = operator
+	}
+
+	if _, err := runOC(opts.Kubeconfig, nil, "delete", "namespace", opts.Namespace, "--wait=false"); err != nil && !errors.Is(err, errNotFound) {
+		logging.Printf("  Warning: Failed to delete validation namespace %s: %v\n", opts.Namespace, err)
+	}
+
+	report.summarize()
+	report.Duration = time.Since(report.StartedAt)
+	return report, nil
+}
+
+// waitForMachineConfigPools waits until every machine config pool is updated.
+// Pools that report updated right after the apply may not have seen the new
+// mirror sets yet, so they are only trusted once an update was observed or
+// the grace period passed. Clusters without machine config pools are not waited on.
+func waitForMachineConfigPools(kubeconfig string, appliedAt, deadline time.Time) error {
+	sawUpdate := false
+	for {
+		updated, err := machineConfigPoolsUpdated(kubeconfig)
+		if errors.Is(err, errNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check machine config pools: %w", err)
+		}
+		if !updated {
+			sawUpdate = true
+		} else if sawUpdate || time.Since(appliedAt) >= rolloutGrace {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("machine config pools not updated after %v", time.Since(appliedAt).Round(time.Second))
+		}
+		time.Sleep(validationPollInterval)
+	}
+}
+
+// machineConfigPoolsUpdated reports whether every machine config pool has all its machines updated
+func machineConfigPoolsUpdated(kubeconfig string) (bool, error) {
+	output, err := runOC(kubeconfig, nil, "get", "machineconfigpools", "-o", "json")
+	if err != nil {
+		return false, err
+	}
+	var list struct {
+		Items []struct {
+			Status struct {
+				MachineCount        int `json:"machineCount"`
+				UpdatedMachineCount int `json:"updatedMachineCount"`
+				Conditions          []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return false, fmt.Errorf("failed to parse machine config pools: %w", err)
+	}
+	for _, pool := range list.Items {
+		if pool.Status.UpdatedMachineCount < pool.Status.MachineCount {
+			return false, nil
+		}
+		for _, condition := range pool.Status.Conditions {
+			if condition.Type == "Updating" && condition.Status == "True" {
+				return false, nil
+			}
+			if condition.Type == "Updated" && condition.Status != "True" {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// sampleWorkload is the manifest of the sample workload and what it contains
+type sampleWorkload struct {
+	manifest     []byte
+	names        []string       // kind/name of each object, for the report
+	pods         []string       // Pods running the images
+	subscription string         // Subscription of the operator, if any
+	catalog      *catalogSource // CatalogSource the operator is installed from
+}
+
+// validationWorkload builds the namespace, image pods and operator subscription
+// of the sample workload as a List applied in one go
+func validationWorkload(opts ClusterValidationOptions, catalogs []catalogSource) (sampleWorkload, error) {
+	labels := map[string]string{validationLabel: "oc-mirror-test"}
+	metadata := func(name string) map[string]interface{} {
+		return map[string]interface{}{"name": name, "namespace": opts.Namespace, "labels": labels}
+	}
+
+	var workload sampleWorkload
+	items := []interface{}{map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]interface{}{"name": opts.Namespace, "labels": labels},
+	}}
+	for i, image := range opts.Images {
+		name := fmt.Sprintf("pull-check-%d", i+1)
+		items = append(items, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata":   metadata(name),
+			"spec": map[string]interface{}{
+				"restartPolicy": "Never",
+				"containers": []interface{}{map[string]interface{}{
+					"name":            "pull-check",
+					"image":           image,
+					"imagePullPolicy": "Always", // Pull from the mirror even if a node has the image
+				}},
+			},
+		})
+		workload.pods = append(workload.pods, name)
+		workload.names = append(workload.names, "pod/"+name)
+	}
+
+	var err error
+	if opts.Operator != nil {
+		source, ok := selectCatalogSource(catalogs, opts.Operator.Catalog)
+		if !ok {
+			err = fmt.Errorf("no generated CatalogSource for %s to install %s from", opts.Operator.Catalog, opts.Operator.Package)
+		} else {
+			subscription := map[string]interface{}{
+				"name":                opts.Operator.Package,
+				"source":              source.name,
+				"sourceNamespace":     source.namespace,
+				"installPlanApproval": "Automatic",
+			}
+			if opts.Operator.Channel != "" {
+				subscription["channel"] = opts.Operator.Channel
+			}
+			items = append(items,
+				map[string]interface{}{
+					"apiVersion": "operators.coreos.com/v1",
+					"kind":       "OperatorGroup",
+					"metadata":   metadata("oc-mirror-test"),
+					"spec":       map[string]interface{}{"targetNamespaces": []string{opts.Namespace}},
+				},
+				map[string]interface{}{
+					"apiVersion": "operators.coreos.com/v1alpha1",
+					"kind":       "Subscription",
+					"metadata":   metadata(opts.Operator.Package),
+					"spec":       subscription,
+				})
+			workload.subscription = opts.Operator.Package
+			workload.catalog = &source
+			workload.names = append(workload.names, "subscription/"+opts.Operator.Package)
+		}
+	}
+
+	workload.manifest, _ = json.Marshal(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items})
+	return workload, err
+}
+
+// selectCatalogSource returns the generated CatalogSource serving a catalog:
+// the one whose mirrored image has the catalog repository, or the only one
+func selectCatalogSource(catalogs []catalogSource, catalog string) (catalogSource, bool) {
+	repository := catalog
+	if _, path, ok := strings.Cut(catalog, "/"); ok {
+		repository = path
+	}
+	repository, _, _ = strings.Cut(repository, "@")
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository = repository[:i]
+	}
+	for _, source := range catalogs {
+		if repository != "" && strings.Contains(source.image, "/"+repository) {
+			return source, true
+		}
+	}
+	if len(catalogs) == 1 {
+		return catalogs[0], true
+	}
+	return catalogSource{}, false
+}
+
+// waitForPulls reads the image pulls of the workload and catalog pods until
+// the workload has settled or the deadline passes. It returns the pulls and
+// the installed operator CSV with its phase.
+func waitForPulls(opts ClusterValidationOptions, catalogs []catalogSource, workload sampleWorkload, since, deadline time.Time) ([]ImagePull, string, error) {
+	for {
+		pulls, err := collectPulls(opts, catalogs, since)
+		if err != nil {
+			return pulls, "", err
+		}
+		operator, operatorDone, err := operatorStatus(opts, workload)
+		if err != nil {
+			return pulls, operator, err
+		}
+
+		waiting := workloadWaiting(opts, catalogs, workload, pulls)
+		if !operatorDone {
+			waiting = append(waiting, "subscription/"+workload.subscription)
+		}
+		if len(waiting) == 0 {
+			return pulls, operator, nil
+		}
+		if time.Now().After(deadline) {
+			return pulls, operator, fmt.Errorf("timed out waiting for %s", strings.Join(waiting, ", "))
+		}
+		time.Sleep(validationPollInterval)
+	}
+}
+
+// collectPulls returns the image pulls of the workload namespace and of the
+// catalog source pods since the manifests were applied
+func collectPulls(opts ClusterValidationOptions, catalogs []catalogSource, since time.Time) ([]ImagePull, error) {
+	events, err := getPodEvents(opts.Kubeconfig, opts.Namespace, time.Time{})
+	if err != nil && !errors.Is(err, errNotFound) {
+		return nil, fmt.Errorf("failed to read events of %s: %w", opts.Namespace, err)
+	}
+
+	namespaces := make(map[string]bool)
+	for _, catalog := range catalogs {
+		if catalog.namespace == "" || catalog.namespace == opts.Namespace || namespaces[catalog.namespace] {
+			continue
+		}
+		namespaces[catalog.namespace] = true
+		catalogEvents, err := getPodEvents(opts.Kubeconfig, catalog.namespace, since.Truncate(time.Second))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read events of %s: %w", catalog.namespace, err)
+		}
+		for _, event := range catalogEvents {
+			if catalogPod(catalogs, event.InvolvedObject.Namespace, event.InvolvedObject.Name) {
+				events = append(events, event)
+			}
+		}
+	}
+	return parseImagePulls(events), nil
+}
+
+// catalogPod reports whether a pod serves one of the generated catalog sources
+func catalogPod(catalogs []catalogSource, namespace, pod string) bool {
+	for _, catalog := range catalogs {
+		if catalog.namespace == namespace && strings.HasPrefix(pod, catalog.name+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// workloadWaiting returns the pods and catalog sources without a pull outcome
+// yet. A catalog source that is already serving needs no new pull.
+func workloadWaiting(opts ClusterValidationOptions, catalogs []catalogSource, workload sampleWorkload, pulls []ImagePull) []string {
+	done := make(map[string]bool)
+	for _, pull := range pulls {
+		if pull.Status == PullPending {
+			return []string{"pod/" + pull.Pod}
+		}
+		done[pull.Namespace+"/"+pull.Pod] = true
+	}
+
+	var waiting []string
+	for _, pod := range workload.pods {
+		if !done[opts.Namespace+"/"+pod] {
+			waiting = append(waiting, "pod/"+pod)
+		}
+	}
+	for _, catalog := range catalogs {
+		pulled := false
+		for key := range done {
+			if strings.HasPrefix(key, catalog.namespace+"/"+catalog.name+"-") {
+				pulled = true
+				break
+			}
+		}
+		if !pulled && !catalogReady(opts.Kubeconfig, catalog) {
+			waiting = append(waiting, "catalogsource/"+catalog.name)
+		}
+	}
+	return waiting
+}
+
+// catalogReady reports whether the catalog source is serving its catalog
+func catalogReady(kubeconfig string, catalog catalogSource) bool {
+	object, err := getClusterObject(kubeconfig, "CatalogSource", catalog.namespace, catalog.name)
+	if err != nil {
+		return false
+	}
+	status, _ := object["status"].(map[string]interface{})
+	connection, _ := status["connectionState"].(map[string]interface{})
+	return connection["lastObservedState"] == "READY"
+}
+
+// operatorStatus returns the CSV installed by the workload subscription with
+// its phase, and whether the install finished, successfully or not
+func operatorStatus(opts ClusterValidationOptions, workload sampleWorkload) (string, bool, error) {
+	if workload.subscription == "" {
+		return "", true, nil
+	}
+	subscription, err := getClusterObject(opts.Kubeconfig, "Subscription.operators.coreos.com", opts.Namespace, workload.subscription)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read subscription %s: %w", workload.subscription, err)
+	}
+	status, _ := subscription["status"].(map[string]interface{})
+	name, _ := status["installedCSV"].(string)
+	if name == "" {
+		return "", false, nil
+	}
+	csv, err := getClusterObject(opts.Kubeconfig, "ClusterServiceVersion", opts.Namespace, name)
+	if errors.Is(err, errNotFound) {
+		return name + ": Pending", false, nil
+	}
+	if err != nil {
+		return name, false, fmt.Errorf("failed to read CSV %s: %w", name, err)
+	}
+	csvStatus, _ := csv["status"].(map[string]interface{})
+	phase, _ := csvStatus["phase"].(string)
+	return name + ": " + phase, phase == "Succeeded" || phase == "Failed", nil
+}
+
+// summarize counts the pull outcomes and decides whether the validation passed:
+// something was pulled, nothing failed or was left pulling, and the operator,
+// if any, installed
+func (r *ClusterValidationReport) summarize() {
+	var total time.Duration
+	for _, pull := range r.Pulls {
+		switch pull.Status {
+		case PullSucceeded:
+			r.Pulled++
+			total += pull.PullTime
+			r.MaxPullTime = max(r.MaxPullTime, pull.PullTime)
+		case PullCached:
+			r.Cached++
+		case PullFailed:
+			r.Failed++
+		default:
+			r.Pending++
+		}
+	}
+	if r.Pulled > 0 {
+		r.AvgPullTime = total / time.Duration(r.Pulled)
+	}
+	operatorFailed := r.Operator != "" && !strings.HasSuffix(r.Operator, ": Succeeded")
+	r.Passed = len(r.Errors) == 0 && r.Failed == 0 && r.Pending == 0 && r.Pulled+r.Cached > 0 && !operatorFailed
+}
+
+// PrintSummary prints the apply, rollout and the outcome of every image pull
+func (r *ClusterValidationReport) PrintSummary() {
+	logging.Printf("\nCluster Validation (%s):\n", r.Kubeconfig)
+	logging.Printf("  Applied %d manifest file(s) in %v, mirror rollout took %v\n", len(r.Applied), r.ApplyTime.Round(time.Millisecond), r.RolloutTime.Round(time.Second))
+	for _, pull := range r.Pulls {
+		name := pull.Namespace + "/" + pull.Pod
+		switch pull.Status {
+		case PullSucceeded:
+			logging.Printf("  ✅ %s: %s pulled in %v", name, pull.Image, pull.PullTime)
+			if pull.Node != "" {
+				logging.Printf(" on %s", pull.Node)
+			}
+			logging.Printf("\n")
+		case PullCached:
+			logging.Printf("  ✅ %s: %s already present on the node\n", name, pull.Image)
+		case PullFailed:
+			logging.Printf("  ❌ %s: %s\n", name, pull.Message)
+		default:
+			logging.Printf("  ❌ %s: %s still pulling\n", name, pull.Image)
+		}
+	}
+	if r.Operator != "" {
+		logging.Printf("  Operator: %s\n", r.Operator)
+	}
+	for _, err := range r.Errors {
+		logging.Printf("  Warning: %s\n", err)
+	}
+	logging.Printf("  Pulled: %d | Cached: %d | Failed: %d | Pending: %d | Avg pull: %v | Max pull: %v\n",
+		r.Pulled, r.Cached, r.Failed, r.Pending, r.AvgPullTime.Round(time.Millisecond), r.MaxPullTime.Round(time.Millisecond))
+	if r.Passed {
+		logging.Printf("  ✅ Images pulled from the mirror\n")
+	} else {
+		logging.Printf("  ❌ Validation failed\n")
+	}
+}
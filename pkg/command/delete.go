@@ -0,0 +1,140 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// Patterns matched against oc-mirror delete output
+var (
+	// registryRequestPattern matches the registry requests containers/image logs
+	// at debug level, e.g. "DELETE https://registry:8443/v2/ocp/ubi9/manifests/sha256:..."
+	registryRequestPattern = regexp.MustCompile(`\b(GET|HEAD|PUT|POST|PATCH|DELETE)\s+https?://[^\s/]+/v2/`)
+
+	deletedImagePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^\s*✓\s+\d+\s*/\s*\d+`), // Batch progress line of a deleted image
+		regexp.MustCompile(`(?i)(image|manifest)\s+\S*\s*deleted`),
+		regexp.MustCompile(`(?i)successfully\s+deleted`),
+	}
+
+	deleteFailedPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^\s*✗\s+\d+\s*/\s*\d+`),
+		regexp.MustCompile(`(?i)failed\s+to\s+delete`),
+	}
+)
+
+// DeleteMetrics describes one oc-mirror delete execution
+type DeleteMetrics struct {
+	ImagesDeleted    int            `json:"ImagesDeleted"`
+	ImagesFailed     int            `json:"ImagesFailed"`
+	APICalls         int            `json:"APICalls"`         // Registry requests logged at debug level
+	APICallsByMethod map[string]int `json:"APICallsByMethod"` // e.g. HEAD, GET, DELETE
+}
+
+// DeleteImageList is the list of images "oc-mirror delete --generate" writes
+// to working-dir/delete/delete-images-<id>.yaml
+type DeleteImageList struct {
+	Items []struct {
+		ImageName      string `yaml:"imageName"`
+		ImageReference string `yaml:"imageReference"`
+		Type           string `yaml:"type"`
+	} `yaml:"items"`
+}
+
+// deleteTally counts delete progress and registry requests while output streams in
+type deleteTally struct {
+	deleted  int
+	failed   int
+	requests map[string]int
+}
+
+func (t *deleteTally) observe(line string) {
+	if matches := registryRequestPattern.FindStringSubmatch(line); len(matches) > 1 {
+		if t.requests == nil {
+			t.requests = make(map[string]int)
+		}
+		t.requests[matches[1]]++
+	}
+	if matchesAny(deleteFailedPatterns, line) {
+		t.failed++
+	} else if matchesAny(deletedImagePatterns, line) {
+		t.deleted++
+	}
+}
+
+// ExtractDeleteMetrics returns the deleted images and registry requests of a delete command
+func (out *CommandOutput) ExtractDeleteMetrics() DeleteMetrics {
+	metrics := DeleteMetrics{APICallsByMethod: make(map[string]int)}
+	if out == nil || out.analysis == nil {
+		return metrics
+	}
+	tally := out.analysis.deletes
+	metrics.ImagesDeleted = tally.deleted
+	metrics.ImagesFailed = tally.failed
+	for method, count := range tally.requests {
+		metrics.APICallsByMethod[method] = count
+		metrics.APICalls += count
+	}
+	return metrics
+}
+
+// DeleteImagesFile returns the file "oc-mirror delete --generate" writes for
+// deleteID in a v2 workspace directory
+func DeleteImagesFile(workspaceDir, deleteID string) string {
+	name := "delete-images.yaml"
+	if deleteID != "" {
+		name = "delete-images-" + deleteID + ".yaml"
+	}
+	return filepath.Join(workspaceDir, "working-dir", "delete", name)
+}
+
+// LoadDeleteImageList reads a generated list of images to delete
+func LoadDeleteImageList(path string) (*DeleteImageList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read delete images file: %w", err)
+	}
+	list := &DeleteImageList{}
+	if err := yaml.Unmarshal(data, list); err != nil {
+		return nil, fmt.Errorf("failed to parse delete images file %s: %w", path, err)
+	}
+	return list, nil
+}
+
+// CountByType returns the number of images to delete per image type
+// (operatorBundle, operatorRelatedImage, generic, ...)
+func (l *DeleteImageList) CountByType() map[string]int {
+	counts := make(map[string]int)
+	for _, item := range l.Items {
+		imageType := item.Type
+		if imageType == "" {
+			imageType = "unknown"
+		}
+		counts[imageType]++
+	}
+	return counts
+}
+
+// PrintSummary prints the deleted images and registry requests
+func (m *DeleteMetrics) PrintSummary() {
+	logging.Printf("  │ ─── Delete Metrics ───────────────────────────────────────────\n")
+	logging.Printf("  │   Images deleted: %d | Failed: %d\n", m.ImagesDeleted, m.ImagesFailed)
+	if m.APICalls == 0 {
+		logging.Printf("  │   API calls: not logged\n")
+		return
+	}
+	methods := make([]string, 0, len(m.APICallsByMethod))
+	for method, count := range m.APICallsByMethod {
+		methods = append(methods, fmt.Sprintf("%s: %d", method, count))
+	}
+	sort.Strings(methods)
+	logging.Printf("  │   API calls: %d (%s)\n", m.APICalls, strings.Join(methods, " | "))
+}
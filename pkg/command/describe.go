@@ -4,10 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
 )
 
 // MirrorMetadata represents the JSON output from oc-mirror describe
@@ -82,14 +82,10 @@ type DescribeMetrics struct {
 // DescribeMirror runs oc-mirror describe and parses the output
 func DescribeMirror(mirrorPath string) (*DescribeMetrics, error) {
 	// Run oc-mirror describe
-	cmd := exec.Command("oc-mirror", "describe", mirrorPath)
+	cmd := exec.Command(toolPath("oc-mirror"), "describe", mirrorPath)
 
-	// Set PATH to include ./bin directory for downloaded binaries
-	binDir, pathErr := getBinDirectory()
-	if pathErr == nil {
-		binPath := filepath.Join(binDir, "bin")
-		cmd.Env = updateCommandEnv(os.Environ(), binPath)
-	}
+	// Set PATH to include the bin directory of the downloaded binaries
+	cmd.Env = commandEnv()
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -170,14 +166,14 @@ func extractMetrics(metadata *MirrorMetadata) *DescribeMetrics {
 
 // PrintSummary prints a summary of the describe metrics
 func (m *DescribeMetrics) PrintSummary() {
-	fmt.Printf("  │ ─── Mirror Content (from oc-mirror describe) ─────────────────\n")
-	fmt.Printf("  │   Total Images: %d\n", m.TotalImages)
-	fmt.Printf("  │   Total Layers: %d\n", m.TotalLayers)
-	fmt.Printf("  │   Total Manifests: %d\n", m.TotalManifests)
-	fmt.Printf("  │   Total Associations: %d\n", m.TotalAssociations)
-	fmt.Printf("  │   Operator Packages: %d\n", m.OperatorPackages)
+	logging.Printf("  │ ─── Mirror Content (from oc-mirror describe) ─────────────────\n")
+	logging.Printf("  │   Total Images: %d\n", m.TotalImages)
+	logging.Printf("  │   Total Layers: %d\n", m.TotalLayers)
+	logging.Printf("  │   Total Manifests: %d\n", m.TotalManifests)
+	logging.Printf("  │   Total Associations: %d\n", m.TotalAssociations)
+	logging.Printf("  │   Operator Packages: %d\n", m.OperatorPackages)
 	if len(m.Catalogs) > 0 {
-		fmt.Printf("  │   Catalogs: %d\n", len(m.Catalogs))
+		logging.Printf("  │   Catalogs: %d\n", len(m.Catalogs))
 	}
 }
 
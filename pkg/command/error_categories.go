@@ -0,0 +1,110 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Categories of oc-mirror errors
+const (
+	ErrorCategoryAuth            = "auth"
+	ErrorCategoryRateLimited     = "rate_limited"
+	ErrorCategoryTLS             = "tls"
+	ErrorCategoryManifestUnknown = "manifest_unknown"
+	ErrorCategoryTimeout         = "timeout"
+	ErrorCategoryDiskFull        = "disk_full"
+	ErrorCategoryOther           = "other" // Errors no pattern table matched
+)
+
+// errorCategoryPatterns classifies error lines. The first category with a
+// matching pattern wins, so a TLS handshake timeout counts as a timeout.
+var errorCategoryPatterns = []struct {
+	category string
+	patterns []*regexp.Regexp
+}{
+	{ErrorCategoryDiskFull, []*regexp.Regexp{
+		regexp.MustCompile(`(?i)no space left on device`),
+		regexp.MustCompile(`(?i)disk quota exceeded`),
+		regexp.MustCompile(`\bENOSPC\b`),
+	}},
+	{ErrorCategoryRateLimited, []*regexp.Regexp{
+		regexp.MustCompile(`(?i)too\s?many\s?requests`),
+		regexp.MustCompile(`(?i)rate[\s_-]?limit`),
+		regexp.MustCompile(`(?i)(?:http status|status\s?code(?: from registry)?)[:=\s]+429\b`),
+	}},
+	{ErrorCategoryAuth, []*regexp.Regexp{
+		regexp.MustCompile(`(?i)unauthorized`),
+		regexp.MustCompile(`(?i)authentication required`),
+		regexp.MustCompile(`(?i)no basic auth credentials`),
+		regexp.MustCompile(`(?i)access to the requested resource is not authorized`),
+		regexp.MustCompile(`(?i)requested access to the resource is denied`),
+		regexp.MustCompile(`(?i)\bdenied:`),
+		regexp.MustCompile(`(?i)(?:http status|status\s?code(?: from registry)?)[:=\s]+40[13]\b`),
+	}},
+	{ErrorCategoryTimeout, []*regexp.Regexp{
+		regexp.MustCompile(`(?i)timed?\s?out`),
+		regexp.MustCompile(`(?i)deadline exceeded`),
+	}},
+	{ErrorCategoryTLS, []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bx509:`),
+		regexp.MustCompile(`(?i)\btls:`),
+		regexp.MustCompile(`(?i)certificate (?:signed by unknown authority|has expired|is not valid)`),
+		regexp.MustCompile(`(?i)server gave HTTP response to HTTPS client`),
+	}},
+	{ErrorCategoryManifestUnknown, []*regexp.Regexp{
+		regexp.MustCompile(`(?i)manifest[\s_]unknown`),
+		regexp.MustCompile(`(?i)manifest\b.*\bnot found`),
+	}},
+}
+
+// errorCategoryOrder is the order categories are reported in
+var errorCategoryOrder = []string{
+	ErrorCategoryAuth,
+	ErrorCategoryRateLimited,
+	ErrorCategoryTLS,
+	ErrorCategoryManifestUnknown,
+	ErrorCategoryTimeout,
+	ErrorCategoryDiskFull,
+	ErrorCategoryOther,
+}
+
+// classifyError returns the category of an error line
+func classifyError(line string) string {
+	for _, class := range errorCategoryPatterns {
+		if matchesAny(class.patterns, line) {
+			return class.category
+		}
+	}
+	return ErrorCategoryOther
+}
+
+// ErrorBreakdown returns the error counts per category, e.g.
+// "rate_limited 12 | timeout 2", or "" without errors
+func (m *ExtendedMetrics) ErrorBreakdown() string {
+	return FormatErrorCategories(m.ErrorCategories)
+}
+
+// FormatErrorCategories formats error counts per category in report order,
+// omitting empty categories
+func FormatErrorCategories(categories map[string]int) string {
+	var parts []string
+	for _, category := range errorCategoryOrder {
+		if n := categories[category]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", category, n))
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// MergeErrorCategories adds the error counts of several metrics, e.g. the
+// download and upload phases of an iteration
+func MergeErrorCategories(metrics ...ExtendedMetrics) map[string]int {
+	merged := make(map[string]int)
+	for _, m := range metrics {
+		for category, n := range m.ErrorCategories {
+			merged[category] += n
+		}
+	}
+	return merged
+}
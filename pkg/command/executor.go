@@ -1,5 +1,10 @@
 package command
 
+import (
+	"context"
+	"errors"
+)
+
 // CommandExecutor defines an interface for executing commands
 // This enables dependency injection and improves testability
 type CommandExecutor interface {
@@ -8,6 +13,9 @@ type CommandExecutor interface {
 	
 	// ExecuteWithCallback runs the command with a callback for process start
 	ExecuteWithCallback(onStart func(pid int)) (*CommandOutput, error)
+
+	// ExecuteContext runs the command until it completes or ctx is done
+	ExecuteContext(ctx context.Context, onStart func(pid int)) (*CommandOutput, error)
 }
 
 // Ensure OCMirrorCommand implements CommandExecutor
@@ -35,6 +43,14 @@ func (m *MockCommandExecutor) ExecuteWithCallback(onStart func(pid int)) (*Comma
 	return m.Execute()
 }
 
+// ExecuteContext implements CommandExecutor interface
+func (m *MockCommandExecutor) ExecuteContext(ctx context.Context, onStart func(pid int)) (*CommandOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return &CommandOutput{ExitCode: -1, TimedOut: errors.Is(err, context.DeadlineExceeded)}, err
+	}
+	return m.ExecuteWithCallback(onStart)
+}
+
 
 
 
@@ -0,0 +1,70 @@
+package command
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// errorStatusPattern matches the error statuses containers/image reports for
+// registry responses, e.g. "received unexpected HTTP status: 429 Too Many Requests"
+// or "invalid status code from registry 503 (Service Unavailable)"
+var errorStatusPattern = regexp.MustCompile(`(?i)(?:http status|status\s?code(?: from registry)?)[:=\s]+(\d{3})\b|\b(\d{3})\s+(?:too many requests|service unavailable|bad gateway|gateway timeout|internal server error)`)
+
+// ResponseStatus is a registry response seen in the oc-mirror output
+type ResponseStatus struct {
+	Time time.Time
+	Code int
+}
+
+// responseTally counts registry requests and error statuses while output streams in
+type responseTally struct {
+	requests map[int64]int // Requests logged at debug level, per Unix second
+	errors   []ResponseStatus
+}
+
+func (t *responseTally) observe(line LogLine) {
+	if registryRequestPattern.MatchString(line.Text) {
+		if t.requests == nil {
+			t.requests = make(map[int64]int)
+		}
+		t.requests[line.Time.Unix()]++
+		return
+	}
+	if m := errorStatusPattern.FindStringSubmatch(line.Text); m != nil {
+		code := m[1]
+		if code == "" {
+			code = m[2]
+		}
+		if status, _ := strconv.Atoi(code); status >= 400 && status <= 599 {
+			t.errors = append(t.errors, ResponseStatus{Time: line.Time, Code: status})
+		}
+	}
+}
+
+// RegistryResponses returns the registry responses of the command. oc-mirror
+// only logs error statuses; when it ran at debug level its requests are
+// logged too, and the requests without an error status are returned as 200
+// responses with inferred set.
+func (out *CommandOutput) RegistryResponses() (responses []ResponseStatus, inferred bool) {
+	if out == nil || out.analysis == nil {
+		return nil, false
+	}
+	tally := out.analysis.responses
+	responses = append(responses, tally.errors...)
+	if len(tally.requests) == 0 {
+		return responses, false
+	}
+
+	errorsPerSecond := make(map[int64]int)
+	for _, status := range tally.errors {
+		errorsPerSecond[status.Time.Unix()]++
+	}
+	for second, requests := range tally.requests {
+		at := time.Unix(second, 0)
+		for i := errorsPerSecond[second]; i < requests; i++ {
+			responses = append(responses, ResponseStatus{Time: at, Code: 200})
+		}
+	}
+	return responses, true
+}
@@ -0,0 +1,160 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Outcome of an image pull observed in the kubelet events of a pod
+const (
+	PullSucceeded = "pulled"  // Pulled from the registry
+	PullCached    = "cached"  // Already present on the node
+	PullFailed    = "failed"  // Failed to pull
+	PullPending   = "pending" // Pulling, without an outcome yet
+)
+
+// ImagePull is the pull of one image by one pod, from the kubelet events
+type ImagePull struct {
+	Namespace string        `json:"Namespace"`
+	Pod       string        `json:"Pod"`
+	Image     string        `json:"Image"`
+	Node      string        `json:"Node,omitempty"`
+	Status    string        `json:"Status"`
+	PullTime  time.Duration `json:"PullTime,omitempty"`  // As reported by the kubelet
+	WaitTime  time.Duration `json:"WaitTime,omitempty"`  // Including waiting for other pulls on the node
+	SizeBytes int64         `json:"SizeBytes,omitempty"` // Reported by newer kubelets
+	Attempts  int           `json:"Attempts"`
+	Message   string        `json:"Message,omitempty"` // Pull error
+}
+
+// clusterEvent is the part of a Kubernetes event the pull timings are read from
+type clusterEvent struct {
+	Reason         string `json:"reason"`
+	Message        string `json:"message"`
+	Count          int    `json:"count"`
+	InvolvedObject struct {
+		Kind      string `json:"kind"`
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"involvedObject"`
+	Source struct {
+		Host string `json:"host"`
+	} `json:"source"`
+	ReportingInstance string    `json:"reportingInstance"`
+	FirstTimestamp    time.Time `json:"firstTimestamp"`
+	LastTimestamp     time.Time `json:"lastTimestamp"`
+	EventTime         time.Time `json:"eventTime"`
+}
+
+// time returns when the event last occurred
+func (e clusterEvent) time() time.Time {
+	if !e.LastTimestamp.IsZero() {
+		return e.LastTimestamp
+	}
+	if !e.EventTime.IsZero() {
+		return e.EventTime
+	}
+	return e.FirstTimestamp
+}
+
+var (
+	// Image reference quoted in kubelet pull messages
+	pullImagePattern = regexp.MustCompile(`image "([^"]+)"`)
+	// Successfully pulled image "x" in 1.2s (3.4s including waiting). Image size: 123 bytes.
+	pullTimePattern = regexp.MustCompile(`" in ((?:[0-9.]+[a-zµ]+)+)`)
+	pullWaitPattern = regexp.MustCompile(`\(((?:[0-9.]+[a-zµ]+)+) including waiting\)`)
+	pullSizePattern = regexp.MustCompile(`Image size: ([0-9]+) bytes`)
+)
+
+// getPodEvents lists the pod events of a namespace that occurred since a point in time
+func getPodEvents(kubeconfig, namespace string, since time.Time) ([]clusterEvent, error) {
+	output, err := runOC(kubeconfig, nil, "get", "events", "-n", namespace, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []clusterEvent `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse events: %w", err)
+	}
+	var events []clusterEvent
+	for _, event := range list.Items {
+		if event.InvolvedObject.Kind == "Pod" && !event.time().Before(since) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// parseImagePulls returns the pull of each image by each pod. A pod that
+// pulled an image after failing is recorded as pulled, with every attempt counted.
+func parseImagePulls(events []clusterEvent) []ImagePull {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].time().Before(events[j].time()) })
+
+	pulls := make(map[string]*ImagePull)
+	var keys []string
+	for _, event := range events {
+		match := pullImagePattern.FindStringSubmatch(event.Message)
+		if match == nil {
+			continue
+		}
+		key := event.InvolvedObject.Namespace + "/" + event.InvolvedObject.Name + "/" + match[1]
+		pull, ok := pulls[key]
+		if !ok {
+			pull = &ImagePull{Namespace: event.InvolvedObject.Namespace, Pod: event.InvolvedObject.Name, Image: match[1], Status: PullPending}
+			pulls[key] = pull
+			keys = append(keys, key)
+		}
+		if node := event.Source.Host; node != "" {
+			pull.Node = node
+		} else if event.ReportingInstance != "" {
+			pull.Node = event.ReportingInstance
+		}
+
+		switch {
+		case event.Reason == "Pulling":
+			pull.Attempts += max(event.Count, 1)
+		case event.Reason == "Pulled" && strings.Contains(event.Message, "already present on machine"):
+			pull.Status = PullCached
+			pull.Message = ""
+		case event.Reason == "Pulled":
+			pull.Status = PullSucceeded
+			pull.Message = ""
+			pull.PullTime = parsePullDuration(pullTimePattern, event.Message)
+			pull.WaitTime = parsePullDuration(pullWaitPattern, event.Message)
+			if size := pullSizePattern.FindStringSubmatch(event.Message); size != nil {
+				pull.SizeBytes, _ = strconv.ParseInt(size[1], 10, 64)
+			}
+		case event.Reason == "Failed" && strings.HasPrefix(event.Message, "Failed to pull image"):
+			if pull.Status != PullSucceeded && pull.Status != PullCached {
+				pull.Status = PullFailed
+				pull.Message = event.Message
+			}
+		}
+	}
+
+	result := make([]ImagePull, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *pulls[key])
+	}
+	return result
+}
+
+// parsePullDuration returns the duration matched by pattern in a kubelet message, or 0
+func parsePullDuration(pattern *regexp.Regexp, message string) time.Duration {
+	match := pattern.FindStringSubmatch(message)
+	if match == nil {
+		return 0
+	}
+	d, err := time.ParseDuration(match[1])
+	if err != nil {
+		return 0
+	}
+	return d
+}
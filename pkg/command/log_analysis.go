@@ -0,0 +1,253 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	maxStoredErrors   = 100 // Limit stored error lines; ErrorCount keeps counting
+	maxStoredWarnings = 20  // Limit stored warning lines; WarningCount keeps counting
+)
+
+// Patterns matched against every output line while oc-mirror runs
+var (
+	cacheSkipPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)skipped.*image`),
+		regexp.MustCompile(`(?i)image.*skipped`),
+		regexp.MustCompile(`(?i)already.*exists`),
+		regexp.MustCompile(`(?i)using.*cached`),
+	}
+
+	bytesUploadedPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)(\d+)\s*(?:bytes|B)\s*(?:uploaded|transferred|sent)`),
+		regexp.MustCompile(`(?i)uploaded.*?(\d+)\s*(?:bytes|B)`),
+		regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(?:MB|GB|KB)`),
+		regexp.MustCompile(`(?i)transferred.*?(\d+)\s*(?:bytes|B)`),
+	}
+
+	imageSizePattern = regexp.MustCompile(`(?i)size[:\s]+(\d+(?:\.\d+)?)\s*(MB|GB|KB|bytes?)`)
+
+	imagePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)copying\s+image`),
+		regexp.MustCompile(`(?i)mirroring\s+image`),
+		regexp.MustCompile(`(?i)processing\s+image`),
+		regexp.MustCompile(`(?i)image.*copied`),
+	}
+
+	layerPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)copying\s+blob`),
+		regexp.MustCompile(`(?i)layer\s+sha256`),
+		regexp.MustCompile(`(?i)blob\s+sha256`),
+		regexp.MustCompile(`(?i)uploading.*blob`),
+	}
+
+	manifestPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)copying\s+manifest`),
+		regexp.MustCompile(`(?i)manifest.*copied`),
+		regexp.MustCompile(`(?i)writing\s+manifest`),
+	}
+
+	errorPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)^error:`),
+		regexp.MustCompile(`(?i)\berror\b.*:`),
+		regexp.MustCompile(`(?i)failed\s+to`),
+		regexp.MustCompile(`(?i)unable\s+to`),
+	}
+
+	warningPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)^warn`),
+		regexp.MustCompile(`(?i)^W\d+`),
+		regexp.MustCompile(`(?i)warning:`),
+	}
+
+	skipPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)skipping`),
+		regexp.MustCompile(`(?i)already\s+exists`),
+		regexp.MustCompile(`(?i)exists.*skipping`),
+	}
+
+	operatorPattern = regexp.MustCompile(`(?i)operator[:\s]+([a-zA-Z0-9_-]+)`)
+	catalogPattern  = regexp.MustCompile(`(?i)catalog.*mirrored|mirroring.*catalog`)
+)
+
+// logAnalysis accumulates metrics from output lines as they stream in, so the
+// output itself never has to be held in memory
+type logAnalysis struct {
+	extended         ExtendedMetrics
+	skippedImages    int
+	maxBytes         int64 // Largest byte count reported in a single line
+	estimatedBytes   int64 // Sum of reported image sizes, used when no byte count was reported
+	retries          []timedRetry
+	clusterResources clusterResourcesTiming
+	deletes          deleteTally
+	responses        responseTally
+	logs             logRetention
+}
+
+// timedRetry is a retry line parsed without a registry host; the source is
+// resolved when the timeline is built
+type timedRetry struct {
+	time  time.Time
+	event RetryEvent
+}
+
+func newLogAnalysis() *logAnalysis {
+	return &logAnalysis{
+		extended: ExtendedMetrics{
+			Errors:         make([]string, 0),
+			Warnings:       make([]string, 0),
+			OperatorsFound: make([]string, 0),
+		},
+	}
+}
+
+// observe updates the metrics with one output line
+func (a *logAnalysis) observe(line LogLine) {
+	text := line.Text
+	a.observeCache(text)
+	a.observeBytes(text)
+	a.observeExtended(text)
+	a.clusterResources.observe(line)
+	a.deletes.observe(text)
+	a.responses.observe(line)
+	a.logs.observe(text)
+	if isRetryLine(text) {
+		a.retries = append(a.retries, timedRetry{time: line.Time, event: parseRetryEvent(text, "")})
+	}
+}
+
+func (a *logAnalysis) observeCache(line string) {
+	if matchesAny(cacheSkipPatterns, line) {
+		a.skippedImages++
+	}
+}
+
+func (a *logAnalysis) observeBytes(line string) {
+	lower := strings.ToLower(line)
+	for _, pattern := range bytesUploadedPatterns {
+		matches := pattern.FindStringSubmatch(line)
+		if len(matches) > 1 {
+			var bytes int64
+			fmt.Sscanf(matches[1], "%d", &bytes)
+
+			// Check if it's MB/GB/KB and convert
+			if strings.Contains(lower, "mb") {
+				bytes *= 1024 * 1024
+			} else if strings.Contains(lower, "gb") {
+				bytes *= 1024 * 1024 * 1024
+			} else if strings.Contains(lower, "kb") {
+				bytes *= 1024
+			}
+
+			if bytes > a.maxBytes {
+				a.maxBytes = bytes
+			}
+		}
+	}
+
+	// Fallback estimation - look for image size patterns
+	if matches := imageSizePattern.FindStringSubmatch(line); len(matches) >= 3 {
+		var size float64
+		fmt.Sscanf(matches[1], "%f", &size)
+
+		unit := strings.ToLower(matches[2])
+		switch {
+		case strings.Contains(unit, "gb"):
+			a.estimatedBytes += int64(size * 1024 * 1024 * 1024)
+		case strings.Contains(unit, "mb"):
+			a.estimatedBytes += int64(size * 1024 * 1024)
+		case strings.Contains(unit, "kb"):
+			a.estimatedBytes += int64(size * 1024)
+		default:
+			a.estimatedBytes += int64(size)
+		}
+	}
+}
+
+func (a *logAnalysis) observeExtended(line string) {
+	metrics := &a.extended
+
+	// Count images
+	if matchesAny(imagePatterns, line) {
+		metrics.ImagesProcessed++
+		if !containsSkip(line) {
+			metrics.ImagesCopied++
+		}
+	}
+
+	// Count layers/blobs
+	if matchesAny(layerPatterns, line) {
+		metrics.LayersProcessed++
+		if !containsSkip(line) {
+			metrics.LayersCopied++
+		} else {
+			metrics.LayersSkipped++
+		}
+	}
+
+	// Count manifests
+	if matchesAny(manifestPatterns, line) {
+		metrics.ManifestsProcessed++
+	}
+
+	// Count blobs
+	if strings.Contains(strings.ToLower(line), "blob") {
+		metrics.BlobsProcessed++
+	}
+
+	// Count errors
+	if matchesAny(errorPatterns, line) {
+		metrics.ErrorCount++
+		if metrics.ErrorCategories == nil {
+			metrics.ErrorCategories = make(map[string]int)
+		}
+		metrics.ErrorCategories[classifyError(line)]++
+		if len(metrics.Errors) < maxStoredErrors {
+			metrics.Errors = append(metrics.Errors, truncateString(line, 200))
+		}
+	}
+
+	// Count retries
+	if isRetryLine(line) {
+		metrics.RetryCount++
+	}
+
+	// Count warnings
+	if matchesAny(warningPatterns, line) {
+		metrics.WarningCount++
+		if len(metrics.Warnings) < maxStoredWarnings {
+			metrics.Warnings = append(metrics.Warnings, truncateString(line, 200))
+		}
+	}
+
+	// Count skipped
+	if matchesAny(skipPatterns, line) && strings.Contains(strings.ToLower(line), "image") {
+		metrics.ImagesSkipped++
+	}
+
+	// Extract operator names
+	if matches := operatorPattern.FindStringSubmatch(line); len(matches) > 1 {
+		opName := matches[1]
+		if !containsString(metrics.OperatorsFound, opName) {
+			metrics.OperatorsFound = append(metrics.OperatorsFound, opName)
+		}
+	}
+
+	// Count catalogs
+	if catalogPattern.MatchString(line) {
+		metrics.CatalogsMirrored++
+	}
+}
+
+// matchesAny reports whether any of the patterns matches line
+func matchesAny(patterns []*regexp.Regexp, line string) bool {
+	for _, p := range patterns {
+		if p.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package command
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// Log retention modes: which output lines a phase keeps besides the log file
+const (
+	LogRetentionFile     = "file"      // Only the log file reference and the message summary
+	LogRetentionHeadTail = "head-tail" // The first and last lines
+	LogRetentionErrors   = "errors"    // The error and warning lines
+)
+
+// DefaultLogRetentionLines is the number of lines kept at each end, or of
+// error and warning lines, when none is configured
+const DefaultLogRetentionLines = 50
+
+// maxSummaryMessages limits the distinct messages of the summary; lines of
+// further messages are only counted
+const maxSummaryMessages = 100
+
+// Patterns replaced by placeholders so repeated messages that differ only in
+// digests, timestamps or counts are summarized together
+var (
+	messageTimestampPattern = regexp.MustCompile(`^(?:time="[^"]*"\s*|\d{4}[-/]\d{2}[-/]\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?\s*)`)
+	messageDigestPattern    = regexp.MustCompile(`sha256:[0-9a-f]{12,64}|\b[0-9a-f]{12,64}\b`)
+	messageNumberPattern    = regexp.MustCompile(`\d+(?:\.\d+)?`)
+)
+
+// LogExcerpt holds the output lines a phase retained
+type LogExcerpt struct {
+	Mode       string
+	TotalLines int
+	Head       []string `json:",omitempty"` // First lines in head-tail mode
+	Tail       []string `json:",omitempty"` // Last lines in head-tail mode
+	Lines      []string `json:",omitempty"` // First error and warning lines in errors mode
+	Omitted    int      // Lines not retained
+}
+
+// LogMessage is a distinct error or warning message and how often it occurred
+type LogMessage struct {
+	Level   string // error or warning
+	Message string // First occurrence
+	Count   int
+}
+
+// LogSummary lists the distinct error and warning messages of a phase, most frequent first
+type LogSummary struct {
+	Messages []LogMessage
+	Dropped  int // Error and warning lines of messages beyond maxSummaryMessages
+}
+
+// logRetention keeps a bounded set of output lines and the distinct error and
+// warning messages while the output streams in
+type logRetention struct {
+	mode     string
+	lines    int
+	total    int
+	head     []string
+	tail     []string // Ring of the latest lines after the head
+	next     int      // Oldest entry of tail once it is full
+	matched  []string
+	messages map[string]*LogMessage
+	dropped  int
+}
+
+// configure sets the retention mode and the number of lines kept
+func (r *logRetention) configure(mode string, lines int) {
+	if lines <= 0 {
+		lines = DefaultLogRetentionLines
+	}
+	r.mode, r.lines = mode, lines
+}
+
+// observe records one output line
+func (r *logRetention) observe(line string) {
+	r.total++
+	level := ""
+	switch {
+	case matchesAny(errorPatterns, line):
+		level = "error"
+	case matchesAny(warningPatterns, line):
+		level = "warning"
+	}
+
+	switch r.mode {
+	case LogRetentionHeadTail:
+		if len(r.head) < r.lines {
+			r.head = append(r.head, line)
+		} else if len(r.tail) < r.lines {
+			r.tail = append(r.tail, line)
+		} else {
+			r.tail[r.next] = line
+			r.next = (r.next + 1) % r.lines
+		}
+	case LogRetentionErrors:
+		if level != "" && len(r.matched) < r.lines {
+			r.matched = append(r.matched, line)
+		}
+	}
+
+	if level != "" {
+		r.summarize(level, line)
+	}
+}
+
+// summarize counts a line under its normalized message
+func (r *logRetention) summarize(level, line string) {
+	key := level + " " + normalizeMessage(line)
+	if message, ok := r.messages[key]; ok {
+		message.Count++
+		return
+	}
+	if len(r.messages) >= maxSummaryMessages {
+		r.dropped++
+		return
+	}
+	if r.messages == nil {
+		r.messages = make(map[string]*LogMessage)
+	}
+	r.messages[key] = &LogMessage{Level: level, Message: truncateString(line, 200), Count: 1}
+}
+
+// normalizeMessage strips the parts of a line that vary between occurrences of the same message
+func normalizeMessage(line string) string {
+	line = messageTimestampPattern.ReplaceAllString(strings.TrimSpace(line), "")
+	line = messageDigestPattern.ReplaceAllString(line, "<digest>")
+	return messageNumberPattern.ReplaceAllString(line, "<n>")
+}
+
+// excerpt returns the retained lines, nil in file mode
+func (r *logRetention) excerpt() *LogExcerpt {
+	excerpt := &LogExcerpt{Mode: r.mode, TotalLines: r.total}
+	switch r.mode {
+	case LogRetentionHeadTail:
+		excerpt.Head = append([]string(nil), r.head...)
+		excerpt.Tail = append(append([]string(nil), r.tail[r.next:]...), r.tail[:r.next]...)
+		excerpt.Omitted = r.total - len(excerpt.Head) - len(excerpt.Tail)
+	case LogRetentionErrors:
+		excerpt.Lines = append([]string(nil), r.matched...)
+		excerpt.Omitted = r.total - len(excerpt.Lines)
+	default:
+		return nil
+	}
+	return excerpt
+}
+
+// summary returns the distinct messages, nil without errors and warnings
+func (r *logRetention) summary() *LogSummary {
+	if len(r.messages) == 0 {
+		return nil
+	}
+	summary := &LogSummary{Dropped: r.dropped}
+	for _, message := range r.messages {
+		summary.Messages = append(summary.Messages, *message)
+	}
+	sort.Slice(summary.Messages, func(i, j int) bool {
+		a, b := summary.Messages[i], summary.Messages[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Message < b.Message
+	})
+	return summary
+}
+
+// LogExcerpt returns the output lines retained in the configured mode, nil in file mode
+func (out *CommandOutput) LogExcerpt() *LogExcerpt {
+	if out == nil || out.analysis == nil {
+		return nil
+	}
+	return out.analysis.logs.excerpt()
+}
+
+// LogSummary returns the distinct error and warning messages with their occurrence counts
+func (out *CommandOutput) LogSummary() *LogSummary {
+	if out == nil || out.analysis == nil {
+		return nil
+	}
+	return out.analysis.logs.summary()
+}
+
+// PrintSummary prints the most frequent messages
+func (s *LogSummary) PrintSummary() {
+	if s == nil {
+		return
+	}
+	logging.Printf("  │ ─── Log Summary ──────────────────────────────────────────────\n")
+	for i, message := range s.Messages {
+		if i == 5 {
+			logging.Printf("  │   ... %d more distinct message(s)\n", len(s.Messages)-i)
+			break
+		}
+		logging.Printf("  │   %dx %s: %s\n", message.Count, message.Level, truncateString(message.Message, 120))
+	}
+	if s.Dropped > 0 {
+		logging.Printf("  │   %d line(s) beyond %d distinct messages not summarized\n", s.Dropped, maxSummaryMessages)
+	}
+}
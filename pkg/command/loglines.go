@@ -0,0 +1,116 @@
+package command
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// maxTailLines is the number of trailing output lines kept for error reporting
+const maxTailLines = 20
+
+// LogLine is a single line of command output and the time it was received
+type LogLine struct {
+	Time time.Time
+	Text string
+}
+
+// logScanner tees stdout and stderr to an optional log file and splits them
+// into timestamped lines as they arrive, feeding each line to the analysis.
+// Only the last maxTailLines lines are kept in memory. The verbose output
+// also passes each line through to the console.
+type logScanner struct {
+	mu          sync.Mutex
+	file        io.Writer // nil when the output is not teed to a file
+	fileErr     error     // First write error; the file is not written after it
+	analysis    *logAnalysis
+	tail        []string
+	streams     []*logScannerStream
+	passthrough bool
+}
+
+func newLogScanner(file io.Writer) *logScanner {
+	return &logScanner{
+		file:        file,
+		analysis:    newLogAnalysis(),
+		tail:        make([]string, 0, maxTailLines),
+		passthrough: logging.Enabled(slog.LevelDebug),
+	}
+}
+
+// writer returns a writer for one output stream; partial lines are buffered per stream
+func (s *logScanner) writer() io.Writer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stream := &logScannerStream{scanner: s}
+	s.streams = append(s.streams, stream)
+	return stream
+}
+
+// finish processes unterminated trailing output of each stream
+func (s *logScanner) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stream := range s.streams {
+		if len(stream.partial) > 0 {
+			s.observe(LogLine{Time: stream.lastWrite, Text: string(bytes.TrimRight(stream.partial, "\r"))})
+			stream.partial = nil
+		}
+	}
+}
+
+// lastLines returns the trailing output lines, oldest first
+func (s *logScanner) lastLines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.tail...)
+}
+
+// observe records one complete line; the caller holds mu
+func (s *logScanner) observe(line LogLine) {
+	s.analysis.observe(line)
+	if len(s.tail) == maxTailLines {
+		copy(s.tail, s.tail[1:])
+		s.tail = s.tail[:maxTailLines-1]
+	}
+	s.tail = append(s.tail, line.Text)
+	if s.passthrough {
+		logging.Debugf("  │ > %s\n", line.Text)
+	}
+}
+
+type logScannerStream struct {
+	scanner   *logScanner
+	partial   []byte
+	lastWrite time.Time
+}
+
+func (st *logScannerStream) Write(p []byte) (int, error) {
+	now := time.Now()
+	s := st.scanner
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file != nil && s.fileErr == nil {
+		if _, err := s.file.Write(p); err != nil {
+			s.fileErr = err
+		}
+	}
+
+	data := append(st.partial, p...)
+	for {
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			break
+		}
+		s.observe(LogLine{Time: now, Text: string(bytes.TrimRight(data[:idx], "\r"))})
+		data = data[idx+1:]
+	}
+	st.partial = append([]byte(nil), data...)
+	st.lastWrite = now
+	return len(p), nil
+}
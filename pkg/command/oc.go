@@ -0,0 +1,36 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// errNotFound is returned by runOC when the object or resource type does not exist
+var errNotFound = errors.New("not found")
+
+// runOC runs oc against the cluster of kubeconfig and returns its stdout.
+// stdin, if set, is passed to oc, e.g. manifests for oc apply -f -.
+func runOC(kubeconfig string, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command(toolPath("oc"), append(args, "--kubeconfig", kubeconfig)...)
+
+	// Set PATH to include the bin directory of the downloaded binaries
+	cmd.Env = commandEnv()
+
+	var stdout, stderr bytes.Buffer
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if strings.Contains(message, "NotFound") || strings.Contains(message, "doesn't have a resource type") {
+			return nil, errNotFound
+		}
+		return nil, fmt.Errorf("oc %s failed: %w: %s", args[0], err, message)
+	}
+	return stdout.Bytes(), nil
+}
@@ -1,13 +1,17 @@
 package command
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
 )
 
 // OCMirrorCommand wraps oc-mirror CLI execution
@@ -21,16 +25,36 @@ type OCMirrorCommand struct {
 	skipMissing     bool
 	continueOnError bool
 	skipTLS         bool
+	extraArgs       []string
+	logFile         string
+	dryRun          bool
+	env             []string // KEY=value added to the environment, overriding inherited values
+	logRetention    string   // Output lines kept besides the log file: file, head-tail or errors
+	retainedLines   int
+	timeout         time.Duration // oc-mirror is killed when it runs longer, zero for no limit
+
+	// delete subcommand (v2 only)
+	deleteMode       bool
+	generate         bool
+	deleteID         string
+	deleteYAMLFile   string
+	forceCacheDelete bool
 }
 
 // CommandOutput contains the output from oc-mirror execution
 type CommandOutput struct {
-	Logs     []string
-	Stdout   string
-	Stderr   string
-	ExitCode int
+	LogFile   string // File holding the full stdout and stderr, empty when not teed to a file
+	StartTime time.Time
+	Tail      []string // Last output lines, oldest first
+	ExitCode  int
+	TimedOut  bool         // Killed because the timeout or the context deadline passed
+	analysis  *logAnalysis // Metrics accumulated while the output streamed in
 }
 
+// killWaitDelay bounds the wait for the output pipes after oc-mirror was
+// killed, in case a child process still holds them open
+const killWaitDelay = 10 * time.Second
+
 // NewOCMirrorCommand creates a new oc-mirror command wrapper
 func NewOCMirrorCommand() *OCMirrorCommand {
 	return &OCMirrorCommand{
@@ -78,11 +102,70 @@ func (cmd *OCMirrorCommand) SetSkipTLS(skip bool) {
 	cmd.skipTLS = skip
 }
 
+// SetExtraArgs sets additional arguments passed to oc-mirror before the destination
+func (cmd *OCMirrorCommand) SetExtraArgs(args []string) {
+	cmd.extraArgs = args
+}
+
 // SetWorkspace sets the workspace directory (--workspace flag, v2 only)
 func (cmd *OCMirrorCommand) SetWorkspace(workspace string) {
 	cmd.workspace = workspace
 }
 
+// SetLogFile sets the file stdout and stderr are written to while the command runs
+func (cmd *OCMirrorCommand) SetLogFile(path string) {
+	cmd.logFile = path
+}
+
+// SetDryRun sets the --dry-run flag: v2 only lists the images it would mirror
+// in working-dir/dry-run, and those missing from the cache, without copying them
+func (cmd *OCMirrorCommand) SetDryRun(dryRun bool) {
+	cmd.dryRun = dryRun
+}
+
+// SetDelete runs the delete subcommand instead of mirroring (v2 only)
+func (cmd *OCMirrorCommand) SetDelete(deleteMode bool) {
+	cmd.deleteMode = deleteMode
+}
+
+// SetGenerate sets the --generate flag: delete only writes the list of images to delete
+func (cmd *OCMirrorCommand) SetGenerate(generate bool) {
+	cmd.generate = generate
+}
+
+// SetDeleteID sets the --delete-id suffix of the generated delete-images file
+func (cmd *OCMirrorCommand) SetDeleteID(id string) {
+	cmd.deleteID = id
+}
+
+// SetDeleteYAMLFile sets the --delete-yaml-file flag: the generated list of images to delete
+func (cmd *OCMirrorCommand) SetDeleteYAMLFile(path string) {
+	cmd.deleteYAMLFile = path
+}
+
+// SetForceCacheDelete sets the --force-cache-delete flag: also delete from the local cache
+func (cmd *OCMirrorCommand) SetForceCacheDelete(force bool) {
+	cmd.forceCacheDelete = force
+}
+
+// SetEnv sets KEY=value pairs added to the environment of oc-mirror. They
+// override inherited values; an empty value clears one.
+func (cmd *OCMirrorCommand) SetEnv(env []string) {
+	cmd.env = env
+}
+
+// SetLogRetention sets which output lines are kept in memory besides the log
+// file, and how many (0 for DefaultLogRetentionLines)
+func (cmd *OCMirrorCommand) SetLogRetention(mode string, lines int) {
+	cmd.logRetention = mode
+	cmd.retainedLines = lines
+}
+
+// SetTimeout sets how long oc-mirror may run before it is killed, zero for no limit
+func (cmd *OCMirrorCommand) SetTimeout(timeout time.Duration) {
+	cmd.timeout = timeout
+}
+
 // Execute runs the oc-mirror command
 // Execute runs the oc-mirror command and returns the output
 func (cmd *OCMirrorCommand) Execute() (*CommandOutput, error) {
@@ -92,29 +175,63 @@ func (cmd *OCMirrorCommand) Execute() (*CommandOutput, error) {
 // ExecuteWithCallback runs the oc-mirror command with a callback that receives the child PID
 // The callback is called immediately after the process starts, allowing external monitoring
 func (cmd *OCMirrorCommand) ExecuteWithCallback(onStart func(pid int)) (*CommandOutput, error) {
+	return cmd.ExecuteContext(context.Background(), onStart)
+}
+
+// ExecuteContext runs the oc-mirror command like ExecuteWithCallback and kills
+// it when ctx is done or the timeout passes. The output read until then is
+// returned with TimedOut set for a deadline, and the error wraps
+// context.DeadlineExceeded.
+func (cmd *OCMirrorCommand) ExecuteContext(ctx context.Context, onStart func(pid int)) (*CommandOutput, error) {
 	args := cmd.buildArgs()
 
-	fmt.Printf("Executing: oc-mirror %s\n", strings.Join(args, " "))
+	logging.Printf("Executing: oc-mirror %s\n", strings.Join(args, " "))
+
+	if cmd.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cmd.timeout)
+		defer cancel()
+	}
+	execCmd := exec.CommandContext(ctx, toolPath("oc-mirror"), args...)
+	execCmd.WaitDelay = killWaitDelay
 
-	execCmd := exec.Command("oc-mirror", args...)
+	// Set PATH to include the bin directory of the downloaded binaries
+	execCmd.Env = commandEnv()
+	if len(cmd.env) > 0 {
+		if execCmd.Env == nil {
+			execCmd.Env = os.Environ()
+		}
+		// The last value of a duplicate key is used
+		execCmd.Env = append(execCmd.Env, cmd.env...)
+	}
 
-	// Set PATH to include ./bin directory for downloaded binaries
-	binDir, pathErr := getBinDirectory()
-	if pathErr == nil {
-		binPath := filepath.Join(binDir, "bin")
-		execCmd.Env = updateCommandEnv(os.Environ(), binPath)
+	var logWriter io.Writer
+	if cmd.logFile != "" {
+		if err := os.MkdirAll(filepath.Dir(cmd.logFile), 0755); err != nil {
+			return &CommandOutput{ExitCode: -1}, fmt.Errorf("failed to create log directory: %w", err)
+		}
+		file, err := os.Create(cmd.logFile)
+		if err != nil {
+			return &CommandOutput{ExitCode: -1}, fmt.Errorf("failed to create log file: %w", err)
+		}
+		defer file.Close()
+		fmt.Fprintf(file, "$ oc-mirror %s\n", strings.Join(args, " "))
+		logWriter = file
 	}
 
-	var stdout, stderr bytes.Buffer
-	execCmd.Stdout = &stdout
-	execCmd.Stderr = &stderr
+	scanner := newLogScanner(logWriter)
+	scanner.analysis.logs.configure(cmd.logRetention, cmd.retainedLines)
+	execCmd.Stdout = scanner.writer()
+	execCmd.Stderr = scanner.writer()
+	startTime := time.Now()
 
 	// Use Start/Wait to get the PID for external monitoring
 	if err := execCmd.Start(); err != nil {
 		return &CommandOutput{
-			Stdout:   "",
-			Stderr:   err.Error(),
-			ExitCode: -1,
+			LogFile:   cmd.logFile,
+			StartTime: startTime,
+			Tail:      []string{err.Error()},
+			ExitCode:  -1,
 		}, fmt.Errorf("failed to start oc-mirror: %w", err)
 	}
 
@@ -125,31 +242,66 @@ func (cmd *OCMirrorCommand) ExecuteWithCallback(onStart func(pid int)) (*Command
 
 	// Wait for the command to complete
 	err := execCmd.Wait()
+	scanner.finish()
 
 	output := &CommandOutput{
-		Stdout:   stdout.String(),
-		Stderr:   stderr.String(),
-		ExitCode: 0,
+		LogFile:   cmd.logFile,
+		StartTime: startTime,
+		Tail:      scanner.lastLines(),
+		ExitCode:  0,
+		analysis:  scanner.analysis,
 	}
 
 	if execCmd.ProcessState != nil {
 		output.ExitCode = execCmd.ProcessState.ExitCode()
 	}
+	logging.Event("oc-mirror exited", "args", strings.Join(args, " "), "exit_code", output.ExitCode,
+		"duration_seconds", time.Since(startTime).Seconds(), "log_file", cmd.logFile)
 
-	// Combine stdout and stderr for log parsing
-	combinedOutput := stdout.String() + "\n" + stderr.String()
-	output.Logs = strings.Split(combinedOutput, "\n")
+	if scanner.fileErr != nil {
+		logging.Printf("  │ Warning: Failed to write log file %s: %v\n", cmd.logFile, scanner.fileErr)
+	}
 
 	if err != nil {
-		return output, fmt.Errorf("oc-mirror command failed: %w\nStdout: %s\nStderr: %s", err, stdout.String(), stderr.String())
+		logNote := ""
+		if output.LogFile != "" {
+			logNote = "\nFull log: " + output.LogFile
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			output.TimedOut = errors.Is(ctxErr, context.DeadlineExceeded)
+			return output, fmt.Errorf("oc-mirror killed after %v: %w\nLast output:\n%s%s",
+				time.Since(startTime).Round(time.Second), ctxErr, strings.Join(output.Tail, "\n"), logNote)
+		}
+		return output, fmt.Errorf("oc-mirror command failed: %w\nLast output:\n%s%s", err, strings.Join(output.Tail, "\n"), logNote)
 	}
 
 	return output, nil
 }
 
+// Args returns the oc-mirror arguments the command runs with
+func (cmd *OCMirrorCommand) Args() []string {
+	return cmd.buildArgs()
+}
+
 func (cmd *OCMirrorCommand) buildArgs() []string {
 	args := []string{}
 
+	if cmd.deleteMode {
+		args = append(args, "delete")
+		if cmd.generate {
+			args = append(args, "--generate")
+		}
+		if cmd.deleteID != "" {
+			args = append(args, "--delete-id", cmd.deleteID)
+		}
+		if cmd.deleteYAMLFile != "" {
+			args = append(args, "--delete-yaml-file", cmd.deleteYAMLFile)
+		}
+		if cmd.forceCacheDelete {
+			args = append(args, "--force-cache-delete")
+		}
+	}
+
 	if cmd.v2 {
 		args = append(args, "--v2")
 		// v2-specific flags
@@ -159,6 +311,9 @@ func (cmd *OCMirrorCommand) buildArgs() []string {
 		if cmd.workspace != "" {
 			args = append(args, "--workspace", cmd.workspace)
 		}
+		if cmd.dryRun {
+			args = append(args, "--dry-run")
+		}
 	} else {
 		// v1 requires explicit --v1 flag (mandatory starting with oc-mirror 4.21)
 		args = append(args, "--v1")
@@ -194,6 +349,8 @@ func (cmd *OCMirrorCommand) buildArgs() []string {
 		}
 	}
 
+	args = append(args, cmd.extraArgs...)
+
 	if cmd.output != "" {
 		args = append(args, cmd.output)
 	}
@@ -203,122 +360,22 @@ func (cmd *OCMirrorCommand) buildArgs() []string {
 
 // CountSkippedImages counts images skipped due to cache
 func (out *CommandOutput) CountSkippedImages() int {
-	count := 0
-	skipPatterns := []*regexp.Regexp{
-		regexp.MustCompile(`(?i)skipped.*image`),
-		regexp.MustCompile(`(?i)image.*skipped`),
-		regexp.MustCompile(`(?i)already.*exists`),
-		regexp.MustCompile(`(?i)using.*cached`),
-	}
-
-	for _, line := range out.Logs {
-		for _, pattern := range skipPatterns {
-			if pattern.MatchString(line) {
-				count++
-				break
-			}
-		}
+	if out.analysis == nil {
+		return 0
 	}
-
-	return count
-}
-
-// CountCacheHits counts cache hit messages in logs
-func (out *CommandOutput) CountCacheHits() int {
-	count := 0
-	cachePatterns := []*regexp.Regexp{
-		regexp.MustCompile(`(?i)cache.*hit`),
-		regexp.MustCompile(`(?i)using.*cache`),
-		regexp.MustCompile(`(?i)cached.*image`),
-		regexp.MustCompile(`(?i)found.*cache`),
-	}
-
-	for _, line := range out.Logs {
-		for _, pattern := range cachePatterns {
-			if pattern.MatchString(line) {
-				count++
-				break
-			}
-		}
-	}
-
-	return count
+	return out.analysis.skippedImages
 }
 
 // ExtractBytesUploaded extracts bytes uploaded from logs
 func (out *CommandOutput) ExtractBytesUploaded() int64 {
-	// Patterns to match bytes uploaded
-	patterns := []*regexp.Regexp{
-		regexp.MustCompile(`(?i)(\d+)\s*(?:bytes|B)\s*(?:uploaded|transferred|sent)`),
-		regexp.MustCompile(`(?i)uploaded.*?(\d+)\s*(?:bytes|B)`),
-		regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(?:MB|GB|KB)`),
-		regexp.MustCompile(`(?i)transferred.*?(\d+)\s*(?:bytes|B)`),
+	if out.analysis == nil {
+		return 0
 	}
-
-	var totalBytes int64
-
-	for _, line := range out.Logs {
-		for _, pattern := range patterns {
-			matches := pattern.FindStringSubmatch(line)
-			if len(matches) > 1 {
-				// Try to extract number
-				var bytes int64
-				fmt.Sscanf(matches[1], "%d", &bytes)
-
-				// Check if it's MB/GB/KB and convert
-				if strings.Contains(strings.ToLower(line), "mb") {
-					bytes *= 1024 * 1024
-				} else if strings.Contains(strings.ToLower(line), "gb") {
-					bytes *= 1024 * 1024 * 1024
-				} else if strings.Contains(strings.ToLower(line), "kb") {
-					bytes *= 1024
-				}
-
-				if bytes > totalBytes {
-					totalBytes = bytes
-				}
-			}
-		}
+	// If we couldn't extract from logs, fall back to the reported image sizes
+	if out.analysis.maxBytes == 0 {
+		return out.analysis.estimatedBytes
 	}
-
-	// If we couldn't extract from logs, try to get from registry logs or docker stats
-	if totalBytes == 0 {
-		totalBytes = out.estimateBytesFromLogs()
-	}
-
-	return totalBytes
-}
-
-func (out *CommandOutput) estimateBytesFromLogs() int64 {
-	// Fallback estimation - look for image size patterns
-	sizePattern := regexp.MustCompile(`(?i)size[:\s]+(\d+(?:\.\d+)?)\s*(MB|GB|KB|bytes?)`)
-	var totalBytes int64
-
-	for _, line := range out.Logs {
-		matches := sizePattern.FindStringSubmatch(line)
-		if len(matches) >= 3 {
-			var size float64
-			fmt.Sscanf(matches[1], "%f", &size)
-
-			unit := strings.ToLower(matches[2])
-			var bytes int64
-
-			switch {
-			case strings.Contains(unit, "gb"):
-				bytes = int64(size * 1024 * 1024 * 1024)
-			case strings.Contains(unit, "mb"):
-				bytes = int64(size * 1024 * 1024)
-			case strings.Contains(unit, "kb"):
-				bytes = int64(size * 1024)
-			default:
-				bytes = int64(size)
-			}
-
-			totalBytes += bytes
-		}
-	}
-
-	return totalBytes
+	return out.analysis.maxBytes
 }
 
 // ExtendedMetrics contains all extracted metrics from logs
@@ -332,6 +389,7 @@ type ExtendedMetrics struct {
 	ManifestsProcessed int
 	BlobsProcessed     int
 	ErrorCount         int
+	ErrorCategories    map[string]int // Errors per category, e.g. auth or rate_limited
 	RetryCount         int
 	WarningCount       int
 	Errors             []string
@@ -342,172 +400,33 @@ type ExtendedMetrics struct {
 
 // ExtractExtendedMetrics extracts comprehensive metrics from command output
 func (out *CommandOutput) ExtractExtendedMetrics() ExtendedMetrics {
-	metrics := ExtendedMetrics{
-		Errors:         make([]string, 0),
-		Warnings:       make([]string, 0),
-		OperatorsFound: make([]string, 0),
-	}
-
-	// Patterns for counting
-	imagePatterns := []*regexp.Regexp{
-		regexp.MustCompile(`(?i)copying\s+image`),
-		regexp.MustCompile(`(?i)mirroring\s+image`),
-		regexp.MustCompile(`(?i)processing\s+image`),
-		regexp.MustCompile(`(?i)image.*copied`),
+	if out.analysis == nil {
+		return newLogAnalysis().extended
 	}
-
-	layerPatterns := []*regexp.Regexp{
-		regexp.MustCompile(`(?i)copying\s+blob`),
-		regexp.MustCompile(`(?i)layer\s+sha256`),
-		regexp.MustCompile(`(?i)blob\s+sha256`),
-		regexp.MustCompile(`(?i)uploading.*blob`),
-	}
-
-	manifestPatterns := []*regexp.Regexp{
-		regexp.MustCompile(`(?i)copying\s+manifest`),
-		regexp.MustCompile(`(?i)manifest.*copied`),
-		regexp.MustCompile(`(?i)writing\s+manifest`),
-	}
-
-	errorPatterns := []*regexp.Regexp{
-		regexp.MustCompile(`(?i)^error:`),
-		regexp.MustCompile(`(?i)\berror\b.*:`),
-		regexp.MustCompile(`(?i)failed\s+to`),
-		regexp.MustCompile(`(?i)unable\s+to`),
-	}
-
-	retryPatterns := []*regexp.Regexp{
-		regexp.MustCompile(`(?i)retry`),
-		regexp.MustCompile(`(?i)retrying`),
-		regexp.MustCompile(`(?i)attempt\s+\d+`),
-	}
-
-	warningPatterns := []*regexp.Regexp{
-		regexp.MustCompile(`(?i)^warn`),
-		regexp.MustCompile(`(?i)^W\d+`),
-		regexp.MustCompile(`(?i)warning:`),
-	}
-
-	skipPatterns := []*regexp.Regexp{
-		regexp.MustCompile(`(?i)skipping`),
-		regexp.MustCompile(`(?i)already\s+exists`),
-		regexp.MustCompile(`(?i)exists.*skipping`),
-	}
-
-	operatorPattern := regexp.MustCompile(`(?i)operator[:\s]+([a-zA-Z0-9_-]+)`)
-	catalogPattern := regexp.MustCompile(`(?i)catalog.*mirrored|mirroring.*catalog`)
-
-	for _, line := range out.Logs {
-		// Count images
-		for _, p := range imagePatterns {
-			if p.MatchString(line) {
-				metrics.ImagesProcessed++
-				if !containsSkip(line) {
-					metrics.ImagesCopied++
-				}
-				break
-			}
-		}
-
-		// Count layers/blobs
-		for _, p := range layerPatterns {
-			if p.MatchString(line) {
-				metrics.LayersProcessed++
-				if !containsSkip(line) {
-					metrics.LayersCopied++
-				} else {
-					metrics.LayersSkipped++
-				}
-				break
-			}
-		}
-
-		// Count manifests
-		for _, p := range manifestPatterns {
-			if p.MatchString(line) {
-				metrics.ManifestsProcessed++
-				break
-			}
-		}
-
-		// Count blobs
-		if strings.Contains(strings.ToLower(line), "blob") {
-			metrics.BlobsProcessed++
-		}
-
-		// Count errors
-		for _, p := range errorPatterns {
-			if p.MatchString(line) {
-				metrics.ErrorCount++
-				metrics.Errors = append(metrics.Errors, truncateString(line, 200))
-				break
-			}
-		}
-
-		// Count retries
-		for _, p := range retryPatterns {
-			if p.MatchString(line) {
-				metrics.RetryCount++
-				break
-			}
-		}
-
-		// Count warnings
-		for _, p := range warningPatterns {
-			if p.MatchString(line) {
-				metrics.WarningCount++
-				if len(metrics.Warnings) < 20 { // Limit stored warnings
-					metrics.Warnings = append(metrics.Warnings, truncateString(line, 200))
-				}
-				break
-			}
-		}
-
-		// Count skipped
-		for _, p := range skipPatterns {
-			if p.MatchString(line) {
-				if strings.Contains(strings.ToLower(line), "image") {
-					metrics.ImagesSkipped++
-				}
-				break
-			}
-		}
-
-		// Extract operator names
-		if matches := operatorPattern.FindStringSubmatch(line); len(matches) > 1 {
-			opName := matches[1]
-			if !containsString(metrics.OperatorsFound, opName) {
-				metrics.OperatorsFound = append(metrics.OperatorsFound, opName)
-			}
-		}
-
-		// Count catalogs
-		if catalogPattern.MatchString(line) {
-			metrics.CatalogsMirrored++
-		}
-	}
-
-	return metrics
+	return out.analysis.extended
 }
 
 // PrintSummary prints a summary of extended metrics
 func (m *ExtendedMetrics) PrintSummary() {
-	fmt.Printf("  │ ─── Image/Layer Metrics ──────────────────────────────────────\n")
+	logging.Printf("  │ ─── Image/Layer Metrics ──────────────────────────────────────\n")
 	// Only print if we have non-zero values (log parsing often doesn't capture these)
 	if m.ImagesProcessed > 0 || m.ImagesCopied > 0 || m.ImagesSkipped > 0 {
-		fmt.Printf("  │   Images: %d processed | %d copied | %d skipped\n",
+		logging.Printf("  │   Images: %d processed | %d copied | %d skipped\n",
 			m.ImagesProcessed, m.ImagesCopied, m.ImagesSkipped)
 	}
 	if m.LayersProcessed > 0 || m.LayersCopied > 0 || m.LayersSkipped > 0 {
-		fmt.Printf("  │   Layers/Blobs: %d processed | %d copied | %d skipped\n",
+		logging.Printf("  │   Layers/Blobs: %d processed | %d copied | %d skipped\n",
 			m.LayersProcessed, m.LayersCopied, m.LayersSkipped)
 	}
 	if m.ManifestsProcessed > 0 || m.CatalogsMirrored > 0 {
-		fmt.Printf("  │   Manifests: %d | Catalogs: %d\n", m.ManifestsProcessed, m.CatalogsMirrored)
+		logging.Printf("  │   Manifests: %d | Catalogs: %d\n", m.ManifestsProcessed, m.CatalogsMirrored)
 	}
 	// Always print errors/retries/warnings as they're important
-	fmt.Printf("  │   Errors: %d | Retries: %d | Warnings: %d\n",
+	logging.Printf("  │   Errors: %d | Retries: %d | Warnings: %d\n",
 		m.ErrorCount, m.RetryCount, m.WarningCount)
+	if breakdown := m.ErrorBreakdown(); breakdown != "" {
+		logging.Printf("  │   Error Types: %s\n", breakdown)
+	}
 	// Note: Operator count from log parsing can be inaccurate - oc-mirror describe provides accurate counts
 }
 
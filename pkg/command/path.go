@@ -6,13 +6,39 @@ import (
 	"strings"
 )
 
-// getBinDirectory returns the bin directory path relative to the working directory
-func getBinDirectory() (string, error) {
-	wd, err := os.Getwd()
-	if err != nil {
-		return "", err
+// binDir is the directory the download command and the runner install the
+// client binaries into
+var binDir = "bin"
+
+// SetBinDir sets the directory the client binaries are run from
+func SetBinDir(dir string) {
+	if dir != "" {
+		binDir = dir
+	}
+}
+
+// BinDir returns the absolute path of the bin directory
+func BinDir() string {
+	if abs, err := filepath.Abs(binDir); err == nil {
+		return abs
 	}
-	return wd, nil
+	return binDir
+}
+
+// toolPath returns the binary of a tool in the bin directory, or its name to
+// be looked up in PATH when it is not there
+func toolPath(name string) string {
+	path := filepath.Join(BinDir(), name)
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return path
+	}
+	return name
+}
+
+// commandEnv returns the environment of a child process, with the bin
+// directory first in PATH
+func commandEnv() []string {
+	return updateCommandEnv(os.Environ(), BinDir())
 }
 
 // updateCommandEnv updates the command environment to include bin directory in PATH
@@ -0,0 +1,292 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// pullThroughPrefix names the CatalogSources created for the pull-through check,
+// so they never replace the generated ones applied to the cluster
+const pullThroughPrefix = "oc-mirror-test-"
+
+// PullThroughOptions describes the pull-through check of one upload: a
+// CatalogSource per mirrored catalog is created on the cluster and the
+// package manifests it serves are compared with the mirrored packages
+type PullThroughOptions struct {
+	Kubeconfig string
+	Namespace  string               // Where the CatalogSources are created, normally openshift-marketplace
+	Catalogs   []PullThroughCatalog // Catalogs of the content and the packages mirrored from them
+	Timeout    time.Duration        // For the catalog pods to serve and the package manifests to resolve
+}
+
+// PullThroughCatalog is an operator catalog of the content and the packages mirrored from it
+type PullThroughCatalog struct {
+	Catalog  string
+	Packages []string
+}
+
+// PullThroughReport records whether the cluster could consume the mirrored catalogs
+type PullThroughReport struct {
+	Kubeconfig string                   `json:"Kubeconfig"`
+	StartedAt  time.Time                `json:"StartedAt"`
+	Duration   time.Duration            `json:"Duration"`
+	Catalogs   []PullThroughSourceState `json:"Catalogs"`
+	Errors     []string                 `json:"Errors,omitempty"`
+	Consumable bool                     `json:"Consumable"` // Every catalog served and resolved its mirrored packages
+}
+
+// PullThroughSourceState is the outcome for one mirrored catalog
+type PullThroughSourceState struct {
+	CatalogSource string        `json:"CatalogSource"`     // Namespace/name of the CatalogSource created for the check
+	Image         string        `json:"Image"`             // Mirrored catalog image, from the generated CatalogSource
+	Catalog       string        `json:"Catalog,omitempty"` // Source catalog of the content it serves
+	State         string        `json:"State"`             // Last observed connection state, READY when served
+	ReadyTime     time.Duration `json:"ReadyTime"`         // From creation until READY was observed
+	Packages      int           `json:"Packages"`          // Package manifests the cluster resolved from it
+	Expected      int           `json:"Expected"`          // Packages mirrored from its catalog
+	Missing       []string      `json:"Missing,omitempty"`
+}
+
+// VerifyPullThrough creates a copy of each generated CatalogSource of
+// resources on the cluster, waits until the catalog pods serve the mirrored
+// catalog images and checks that the package manifests of the mirrored
+// packages resolve. The CatalogSources are deleted afterwards.
+func VerifyPullThrough(opts PullThroughOptions, resources *ClusterResourcesMetrics) (*PullThroughReport, error) {
+	report := &PullThroughReport{
+		Kubeconfig: opts.Kubeconfig,
+		StartedAt:  time.Now(),
+		Catalogs:   make([]PullThroughSourceState, 0),
+	}
+	deadline := report.StartedAt.Add(opts.Timeout)
+
+	generated, err := generatedCatalogSources(resources)
+	if err != nil {
+		return nil, err
+	}
+	if len(generated) == 0 {
+		return nil, fmt.Errorf("the upload generated no CatalogSource")
+	}
+
+	sources := make([]catalogSource, 0, len(generated))
+	for _, source := range generated {
+		check := catalogSource{namespace: opts.Namespace, name: pullThroughPrefix + source.name, image: source.image}
+		if _, err := runOC(opts.Kubeconfig, pullThroughManifest(check), "apply", "-f", "-"); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("create catalogsource/%s: %v", check.name, err))
+			continue
+		}
+		sources = append(sources, check)
+		report.Catalogs = append(report.Catalogs, PullThroughSourceState{
+			CatalogSource: check.namespace + "/" + check.name,
+			Image:         check.image,
+		})
+	}
+
+	// Expected packages are matched to a source through its mirrored image
+	for _, catalog := range opts.Catalogs {
+		source, ok := selectCatalogSource(sources, catalog.Catalog)
+		if !ok {
+			report.Errors = append(report.Errors, fmt.Sprintf("no generated CatalogSource serves %s", catalog.Catalog))
+			continue
+		}
+		for i := range report.Catalogs {
+			if report.Catalogs[i].CatalogSource == source.namespace+"/"+source.name {
+				report.Catalogs[i].Catalog = catalog.Catalog
+			}
+		}
+	}
+
+	for i, source := range sources {
+		state := &report.Catalogs[i]
+		ready, err := waitForCatalogSource(opts.Kubeconfig, source, deadline)
+		state.State = ready
+		state.ReadyTime = time.Since(report.StartedAt)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+
+		expected := expectedPackages(opts.Catalogs, state.Catalog)
+		resolved, missing, err := waitForPackageManifests(opts.Kubeconfig, source, expected, deadline)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+		state.Packages = len(resolved)
+		state.Expected = len(expected)
+		state.Missing = missing
+	}
+
+	for _, source := range sources {
+		if _, err := runOC(opts.Kubeconfig, nil, "delete", "catalogsource", source.name, "-n", source.namespace, "--wait=false"); err != nil && !errors.Is(err, errNotFound) {
+			logging.Printf("  │ Warning: Failed to delete catalogsource/%s: %v\n", source.name, err)
+		}
+	}
+
+	report.summarize()
+	report.Duration = time.Since(report.StartedAt)
+	return report, nil
+}
+
+// pullThroughManifest returns a grpc CatalogSource serving the mirrored catalog image
+func pullThroughManifest(source catalogSource) []byte {
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": "operators.coreos.com/v1alpha1",
+		"kind":       "CatalogSource",
+		"metadata": map[string]interface{}{
+			"name":      source.name,
+			"namespace": source.namespace,
+			"labels":    map[string]string{validationLabel: "oc-mirror-test"},
+		},
+		"spec": map[string]interface{}{
+			"sourceType":  "grpc",
+			"image":       source.image,
+			"displayName": "oc-mirror-test pull-through check",
+			"publisher":   "oc-mirror-test",
+		},
+	})
+	return manifest
+}
+
+// expectedPackages returns the packages mirrored from catalog
+func expectedPackages(catalogs []PullThroughCatalog, catalog string) []string {
+	for _, c := range catalogs {
+		if c.Catalog == catalog && catalog != "" {
+			return c.Packages
+		}
+	}
+	return nil
+}
+
+// waitForCatalogSource waits until the catalog source is READY and returns
+// its last observed connection state
+func waitForCatalogSource(kubeconfig string, source catalogSource, deadline time.Time) (string, error) {
+	for {
+		object, err := getClusterObject(kubeconfig, "CatalogSource", source.namespace, source.name)
+		state := ""
+		if err == nil {
+			status, _ := object["status"].(map[string]interface{})
+			connection, _ := status["connectionState"].(map[string]interface{})
+			state, _ = connection["lastObservedState"].(string)
+		}
+		if state == "READY" {
+			return state, nil
+		}
+		if time.Now().After(deadline) {
+			if state == "" {
+				state = "UNKNOWN"
+			}
+			return state, fmt.Errorf("catalogsource/%s not serving %s: %s", source.name, source.image, state)
+		}
+		time.Sleep(validationPollInterval)
+	}
+}
+
+// waitForPackageManifests lists the package manifests the catalog source
+// serves until every expected package resolved or the deadline passes. It
+// returns the resolved packages and the expected ones still missing.
+func waitForPackageManifests(kubeconfig string, source catalogSource, expected []string, deadline time.Time) ([]string, []string, error) {
+	for {
+		resolved, err := packageManifests(kubeconfig, source)
+		if err != nil && !errors.Is(err, errNotFound) {
+			return nil, expected, fmt.Errorf("failed to list package manifests of %s: %w", source.name, err)
+		}
+		have := make(map[string]bool, len(resolved))
+		for _, name := range resolved {
+			have[name] = true
+		}
+		var missing []string
+		for _, name := range expected {
+			if !have[name] {
+				missing = append(missing, name)
+			}
+		}
+		if len(resolved) > 0 && len(missing) == 0 {
+			return resolved, nil, nil
+		}
+		if time.Now().After(deadline) {
+			if len(resolved) == 0 {
+				return resolved, missing, fmt.Errorf("catalogsource/%s serves no package manifests", source.name)
+			}
+			return resolved, missing, nil
+		}
+		time.Sleep(validationPollInterval)
+	}
+}
+
+// packageManifests returns the names of the package manifests of a catalog source
+func packageManifests(kubeconfig string, source catalogSource) ([]string, error) {
+	output, err := runOC(kubeconfig, nil, "get", "packagemanifests", "-n", source.namespace,
+		"-l", "catalog="+source.name+",catalog-namespace="+source.namespace, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse package manifests: %w", err)
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		names = append(names, item.Metadata.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// summarize decides whether the mirror is consumable: every catalog source
+// served and resolved package manifests, with no mirrored package missing
+func (r *PullThroughReport) summarize() {
+	r.Consumable = len(r.Errors) == 0 && len(r.Catalogs) > 0
+	for _, catalog := range r.Catalogs {
+		if catalog.State != "READY" || catalog.Packages == 0 || len(catalog.Missing) > 0 {
+			r.Consumable = false
+		}
+	}
+}
+
+// MissingCount returns the mirrored packages the cluster did not resolve
+func (r *PullThroughReport) MissingCount() int {
+	count := 0
+	for _, catalog := range r.Catalogs {
+		count += len(catalog.Missing)
+	}
+	return count
+}
+
+// PrintSummary prints the state and resolved packages of every catalog source
+func (r *PullThroughReport) PrintSummary() {
+	logging.Printf("  │ Pull-Through Check (%s):\n", r.Kubeconfig)
+	for _, catalog := range r.Catalogs {
+		mark := "✅"
+		if catalog.State != "READY" || catalog.Packages == 0 || len(catalog.Missing) > 0 {
+			mark = "❌"
+		}
+		logging.Printf("  │   %s %s: %s after %v, %d package(s) resolved", mark, catalog.CatalogSource, catalog.State,
+			catalog.ReadyTime.Round(time.Second), catalog.Packages)
+		if catalog.Expected > 0 {
+			logging.Printf(", %d of %d mirrored", catalog.Expected-len(catalog.Missing), catalog.Expected)
+		}
+		logging.Printf("\n")
+		if len(catalog.Missing) > 0 {
+			logging.Printf("  │     Missing: %s\n", strings.Join(catalog.Missing, ", "))
+		}
+	}
+	for _, err := range r.Errors {
+		logging.Printf("  │ Warning: %s\n", err)
+	}
+	if r.Consumable {
+		logging.Printf("  │ ✅ Mirror is consumable by the cluster (%v)\n", r.Duration.Round(time.Second))
+	} else {
+		logging.Printf("  │ ❌ Mirror is not consumable by the cluster (%v)\n", r.Duration.Round(time.Second))
+	}
+}
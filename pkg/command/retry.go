@@ -0,0 +1,323 @@
+package command
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// Retry sources, telling retries against the destination registry apart from upstream ones
+const (
+	RetrySourceRegistry = "registry"
+	RetrySourceUpstream = "upstream"
+	RetrySourceUnknown  = "unknown"
+)
+
+const (
+	maxRetryEvents       = 500 // Limit stored events per phase
+	maxRetryImages       = 20  // Limit per-image retry counts per phase
+	maxRetryBucketImages = 5   // Limit images listed per bucket
+)
+
+// retryPatterns match log lines reporting a retried operation
+var retryPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)retry`),
+	regexp.MustCompile(`(?i)retrying`),
+	regexp.MustCompile(`(?i)attempt\s+\d+`),
+}
+
+var (
+	retryURLPattern       = regexp.MustCompile(`https?://([^/\s"']+)/v2/([^\s"']+?)/(?:blobs|manifests|tags)/`)
+	retryImagePattern     = regexp.MustCompile(`(?:docker://)?((?:[a-zA-Z0-9-]+\.)+[a-zA-Z0-9-]+(?::\d+)?|localhost(?::\d+)?|[a-zA-Z0-9-]+:\d+)/([a-z0-9][a-z0-9._/-]*[a-z0-9])(?:@[a-z0-9]+:[a-f0-9]+|:\w[\w.-]*)?`)
+	retryStatusPattern    = regexp.MustCompile(`(?i)(?:status(?:\s+code)?|http)[:=\s]+(\d{3})\b|\b(\d{3})\s+(?:too many requests|service unavailable|bad gateway|gateway timeout|internal server error|unauthorized|forbidden|not found)`)
+	retryAttemptPattern   = regexp.MustCompile(`(?i)\((\d+)/\d+\)|attempt\s+(\d+)`)
+	retryThrottledPattern = regexp.MustCompile(`(?i)toomanyrequests|too many requests|rate.?limit`)
+)
+
+// RetryEvent is a single retry reported in the oc-mirror logs
+type RetryEvent struct {
+	OffsetSeconds float64 `json:"OffsetSeconds"` // Seconds since the phase started
+	Image         string  `json:"Image,omitempty"`
+	Host          string  `json:"Host,omitempty"`
+	StatusCode    int     `json:"StatusCode,omitempty"`
+	Attempt       int     `json:"Attempt,omitempty"`
+	Source        string  `json:"Source"`    // registry, upstream or unknown
+	Throttled     bool    `json:"Throttled"` // HTTP 429 or rate limit message
+	Message       string  `json:"Message"`
+}
+
+// RetryBucket aggregates the retries of one time slice of a phase
+type RetryBucket struct {
+	OffsetSeconds int      `json:"OffsetSeconds"`
+	Registry      int      `json:"Registry"`
+	Upstream      int      `json:"Upstream"`
+	Unknown       int      `json:"Unknown"`
+	Throttled     int      `json:"Throttled"`
+	Images        []string `json:"Images,omitempty"`
+	ThroughputMBs float64  `json:"ThroughputMBs"` // Average phase throughput during the slice
+}
+
+// RetryImage is the number of retries for one image
+type RetryImage struct {
+	Image string `json:"Image"`
+	Count int    `json:"Count"`
+}
+
+// RetryTimeline is the per-phase retry timeline, charted against throughput
+type RetryTimeline struct {
+	StartTime                   time.Time     `json:"StartTime"`
+	BucketSeconds               int           `json:"BucketSeconds"`
+	Total                       int           `json:"Total"`
+	Registry                    int           `json:"Registry"`
+	Upstream                    int           `json:"Upstream"`
+	Unknown                     int           `json:"Unknown"`
+	Throttled                   int           `json:"Throttled"`
+	RegistryHost                string        `json:"RegistryHost,omitempty"`
+	ThroughputSource            string        `json:"ThroughputSource,omitempty"`
+	ThroughputWithRetriesMBs    float64       `json:"ThroughputWithRetriesMBs"`    // Average throughput in slices with retries
+	ThroughputWithoutRetriesMBs float64       `json:"ThroughputWithoutRetriesMBs"` // Average throughput in slices without retries
+	Images                      []RetryImage  `json:"Images,omitempty"`
+	Buckets                     []RetryBucket `json:"Buckets"`
+	Events                      []RetryEvent  `json:"Events,omitempty"`
+}
+
+// ThroughputSample is a throughput measurement used to chart retries against transfer rate
+type ThroughputSample struct {
+	Timestamp time.Time
+	RateMBs   float64
+}
+
+// ExtractRetryTimeline builds the retry timeline of the command output, grouping
+// retries into slices of the given length. Retries against registryHost count as
+// registry-induced, retries against any other host as upstream. Returns nil when
+// the output contains no retries.
+func (out *CommandOutput) ExtractRetryTimeline(registryHost string, bucket time.Duration) *RetryTimeline {
+	if out.analysis == nil || len(out.analysis.retries) == 0 {
+		return nil
+	}
+	if bucket < time.Second {
+		bucket = time.Second
+	}
+
+	timeline := &RetryTimeline{
+		StartTime:     out.StartTime,
+		BucketSeconds: int(bucket / time.Second),
+		RegistryHost:  registryHost,
+	}
+	imageCounts := make(map[string]int)
+
+	for _, retry := range out.analysis.retries {
+		event := retry.event
+		event.Source = retrySource(event.Host, registryHost)
+		offset := retry.time.Sub(out.StartTime)
+		if offset < 0 {
+			offset = 0
+		}
+		event.OffsetSeconds = offset.Seconds()
+
+		b := timeline.bucketAt(offset)
+		switch event.Source {
+		case RetrySourceRegistry:
+			timeline.Registry++
+			b.Registry++
+		case RetrySourceUpstream:
+			timeline.Upstream++
+			b.Upstream++
+		default:
+			timeline.Unknown++
+			b.Unknown++
+		}
+		if event.Throttled {
+			timeline.Throttled++
+			b.Throttled++
+		}
+		if event.Image != "" {
+			imageCounts[event.Image]++
+			if len(b.Images) < maxRetryBucketImages && !containsString(b.Images, event.Image) {
+				b.Images = append(b.Images, event.Image)
+			}
+		}
+
+		timeline.Total++
+		if len(timeline.Events) < maxRetryEvents {
+			timeline.Events = append(timeline.Events, event)
+		}
+	}
+
+	if timeline.Total == 0 {
+		return nil
+	}
+
+	for image, count := range imageCounts {
+		timeline.Images = append(timeline.Images, RetryImage{Image: image, Count: count})
+	}
+	sort.Slice(timeline.Images, func(i, j int) bool {
+		if timeline.Images[i].Count != timeline.Images[j].Count {
+			return timeline.Images[i].Count > timeline.Images[j].Count
+		}
+		return timeline.Images[i].Image < timeline.Images[j].Image
+	})
+	if len(timeline.Images) > maxRetryImages {
+		timeline.Images = timeline.Images[:maxRetryImages]
+	}
+
+	return timeline
+}
+
+// AttachThroughput records the average throughput of each slice from the given
+// samples, adding empty slices so the timeline covers all samples
+func (t *RetryTimeline) AttachThroughput(source string, samples []ThroughputSample) {
+	if t == nil || len(samples) == 0 {
+		return
+	}
+	t.ThroughputSource = source
+
+	sums := make(map[int]float64)
+	counts := make(map[int]int)
+	for _, sample := range samples {
+		offset := sample.Timestamp.Sub(t.StartTime)
+		if offset < 0 {
+			continue
+		}
+		b := t.bucketAt(offset)
+		sums[b.OffsetSeconds] += sample.RateMBs
+		counts[b.OffsetSeconds]++
+	}
+
+	var withSum, withoutSum float64
+	var withCount, withoutCount int
+	for i := range t.Buckets {
+		b := &t.Buckets[i]
+		if counts[b.OffsetSeconds] == 0 {
+			continue
+		}
+		b.ThroughputMBs = sums[b.OffsetSeconds] / float64(counts[b.OffsetSeconds])
+		if b.Registry+b.Upstream+b.Unknown > 0 {
+			withSum += b.ThroughputMBs
+			withCount++
+		} else {
+			withoutSum += b.ThroughputMBs
+			withoutCount++
+		}
+	}
+	if withCount > 0 {
+		t.ThroughputWithRetriesMBs = withSum / float64(withCount)
+	}
+	if withoutCount > 0 {
+		t.ThroughputWithoutRetriesMBs = withoutSum / float64(withoutCount)
+	}
+}
+
+// bucketAt returns the slice containing offset, extending the timeline as needed
+func (t *RetryTimeline) bucketAt(offset time.Duration) *RetryBucket {
+	index := int(offset / (time.Duration(t.BucketSeconds) * time.Second))
+	for len(t.Buckets) <= index {
+		t.Buckets = append(t.Buckets, RetryBucket{OffsetSeconds: len(t.Buckets) * t.BucketSeconds})
+	}
+	return &t.Buckets[index]
+}
+
+// PrintSummary prints where retries came from and how throughput behaved around them
+func (t *RetryTimeline) PrintSummary() {
+	if t == nil || t.Total == 0 {
+		return
+	}
+	logging.Printf("  │ ─── Retry Timeline ───────────────────────────────────────────\n")
+	logging.Printf("  │   Retries: %d | Registry: %d | Upstream: %d (throttled: %d) | Unknown: %d\n",
+		t.Total, t.Registry, t.Upstream, t.Throttled, t.Unknown)
+
+	busiest := t.Buckets[0]
+	for _, b := range t.Buckets {
+		if b.Registry+b.Upstream+b.Unknown > busiest.Registry+busiest.Upstream+busiest.Unknown {
+			busiest = b
+		}
+	}
+	logging.Printf("  │   Busiest %ds window: +%ds with %d retries",
+		t.BucketSeconds, busiest.OffsetSeconds, busiest.Registry+busiest.Upstream+busiest.Unknown)
+	if t.ThroughputSource != "" {
+		logging.Printf(" at %.2f MB/s", busiest.ThroughputMBs)
+	}
+	logging.Printf("\n")
+
+	if t.ThroughputSource != "" {
+		logging.Printf("  │   Throughput (%s): %.2f MB/s with retries | %.2f MB/s without\n",
+			t.ThroughputSource, t.ThroughputWithRetriesMBs, t.ThroughputWithoutRetriesMBs)
+	}
+	for i, image := range t.Images {
+		if i == 3 {
+			break
+		}
+		logging.Printf("  │   %4d× %s\n", image.Count, truncateString(image.Image, 70))
+	}
+}
+
+// isRetryLine reports whether a log line reports a retry
+func isRetryLine(line string) bool {
+	for _, p := range retryPatterns {
+		if p.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetryEvent extracts the image, host, status and source of a retry log line
+func parseRetryEvent(line, registryHost string) RetryEvent {
+	event := RetryEvent{Message: truncateString(strings.TrimSpace(line), 200)}
+
+	if m := retryURLPattern.FindStringSubmatch(line); m != nil {
+		event.Host = m[1]
+		event.Image = m[1] + "/" + m[2]
+	} else if m := retryImagePattern.FindStringSubmatch(line); m != nil {
+		event.Host = m[1]
+		event.Image = strings.TrimPrefix(m[0], "docker://")
+	}
+
+	if m := retryStatusPattern.FindStringSubmatch(line); m != nil {
+		code := m[1]
+		if code == "" {
+			code = m[2]
+		}
+		event.StatusCode, _ = strconv.Atoi(code)
+	}
+	if m := retryAttemptPattern.FindStringSubmatch(line); m != nil {
+		attempt := m[1]
+		if attempt == "" {
+			attempt = m[2]
+		}
+		event.Attempt, _ = strconv.Atoi(attempt)
+	}
+	event.Throttled = event.StatusCode == 429 || retryThrottledPattern.MatchString(line)
+
+	event.Source = retrySource(event.Host, registryHost)
+	return event
+}
+
+// retrySource classifies a retry by the host it was made against
+func retrySource(host, registryHost string) string {
+	switch {
+	case host == "":
+		return RetrySourceUnknown
+	case sameRegistryHost(host, registryHost):
+		return RetrySourceRegistry
+	default:
+		return RetrySourceUpstream
+	}
+}
+
+// sameRegistryHost compares registry hosts, ignoring the port when only one side has one
+func sameRegistryHost(host, registryHost string) bool {
+	if host == "" || registryHost == "" {
+		return false
+	}
+	if strings.EqualFold(host, registryHost) {
+		return true
+	}
+	hostName, _, hostHasPort := strings.Cut(host, ":")
+	registryName, _, registryHasPort := strings.Cut(registryHost, ":")
+	return hostHasPort != registryHasPort && strings.EqualFold(hostName, registryName)
+}
@@ -0,0 +1,40 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// SkopeoSync copies every tag of a repository between registries with
+// skopeo sync. source is host[:port]/repository; the repository's last path
+// component is created under destination, host[:port]/path. Output goes to log.
+func SkopeoSync(source, destination string, skipTLS bool, log io.Writer) error {
+	args := []string{"sync", "--all", "--src", "docker", "--dest", "docker"}
+	if skipTLS {
+		args = append(args, "--src-tls-verify=false", "--dest-tls-verify=false")
+	}
+	args = append(args, source, destination)
+
+	cmd := exec.Command(toolPath("skopeo"), args...)
+
+	// Set PATH to include the bin directory of the downloaded binaries
+	cmd.Env = commandEnv()
+
+	fmt.Fprintf(log, "$ skopeo %s\n", strings.Join(args, " "))
+	cmd.Stdout = log
+	cmd.Stderr = log
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("skopeo sync %s failed: %w", source, err)
+	}
+	return nil
+}
+
+// SkopeoAvailable returns an error if skopeo is not in the bin directory or PATH
+func SkopeoAvailable() error {
+	if _, err := exec.LookPath(toolPath("skopeo")); err != nil {
+		return fmt.Errorf("skopeo not found in %s or PATH", BinDir())
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ocMirrorVersionInfo is the JSON output of oc-mirror version --output=json
+type ocMirrorVersionInfo struct {
+	ClientVersion struct {
+		GitVersion string `json:"gitVersion"`
+		GitCommit  string `json:"gitCommit"`
+	} `json:"clientVersion"`
+}
+
+// OCMirrorVersion returns the version of the oc-mirror binary in PATH or ./bin
+func OCMirrorVersion() (string, error) {
+	cmd := exec.Command(toolPath("oc-mirror"), "version", "--output=json")
+
+	// Set PATH to include the bin directory of the downloaded binaries
+	cmd.Env = commandEnv()
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("oc-mirror version failed: %w\nStderr: %s", err, stderr.String())
+	}
+
+	// Skip the deprecation warning printed before the JSON
+	output := stdout.String()
+	jsonStart := strings.Index(output, "{")
+	if jsonStart == -1 {
+		return "", fmt.Errorf("no JSON found in oc-mirror version output")
+	}
+
+	var info ocMirrorVersionInfo
+	if err := json.Unmarshal([]byte(output[jsonStart:]), &info); err != nil {
+		return "", fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if info.ClientVersion.GitVersion == "" {
+		return "", fmt.Errorf("no client version in oc-mirror version output")
+	}
+	return info.ClientVersion.GitVersion, nil
+}
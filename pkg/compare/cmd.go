@@ -0,0 +1,87 @@
+package compare
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/telco-core/ngc-495/pkg/integrity"
+	"github.com/telco-core/ngc-495/pkg/results"
+)
+
+// NewCompareRunsCommand creates a cobra command for comparing result files
+func NewCompareRunsCommand() *cobra.Command {
+	thresholds := DefaultThresholds()
+	var jsonOutput bool
+	var signingKeyFile string
+	var allowMonitorMismatch bool
+
+	cmd := &cobra.Command{
+		Use:   "compare-runs <results.json|dir>...",
+		Short: "Compare result files and report performance regressions",
+		Long: "Loads two or more results files, aligns iterations by version and clean/cached state, and reports time, bytes, CPU and memory deltas. " +
+			"The last file is the candidate; earlier files are averaged into the baseline. Directories expand to their results files, oldest first. " +
+			"Exits non-zero when any metric exceeds its threshold.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, err := results.Resolve(args)
+			if err != nil {
+				return err
+			}
+
+			var key []byte
+			if signingKeyFile != "" {
+				if key, err = integrity.LoadKey(signingKeyFile); err != nil {
+					return err
+				}
+			}
+
+			runs, err := results.LoadAll(files, key)
+			if err != nil {
+				return err
+			}
+			for _, run := range runs {
+				if run.Integrity.IsTampered() {
+					fmt.Fprintf(os.Stderr, "Warning: %s failed integrity check (%s): %s\n", run.Path, run.Integrity.Status, run.Integrity.Message)
+				}
+			}
+
+			report, err := Compare(runs, thresholds)
+			if err != nil {
+				return err
+			}
+			if len(report.MonitorMismatches) > 0 && !allowMonitorMismatch {
+				return fmt.Errorf("runs were monitored differently, deltas could reflect sampling rather than performance (use --allow-monitor-mismatch to compare anyway):\n  %s",
+					strings.Join(report.MonitorMismatches, "\n  "))
+			}
+
+			if jsonOutput {
+				out, err := report.FormatJSON()
+				if err != nil {
+					return fmt.Errorf("failed to format report: %w", err)
+				}
+				fmt.Println(out)
+			} else {
+				report.PrintSummary()
+			}
+
+			if report.HasRegressions() {
+				cmd.SilenceUsage = true
+				cmd.SilenceErrors = true
+				return fmt.Errorf("performance regression detected in %d metric(s)", report.Regressions)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Float64Var(&thresholds.TimePercent, "time-threshold", thresholds.TimePercent, "Allowed wall time increase in percent (0 disables)")
+	cmd.Flags().Float64Var(&thresholds.BytesPercent, "bytes-threshold", thresholds.BytesPercent, "Allowed bytes transferred increase in percent (0 disables)")
+	cmd.Flags().Float64Var(&thresholds.CPUPercent, "cpu-threshold", thresholds.CPUPercent, "Allowed average CPU increase in percent (0 disables)")
+	cmd.Flags().Float64Var(&thresholds.MemoryPercent, "memory-threshold", thresholds.MemoryPercent, "Allowed peak memory increase in percent (0 disables)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the report as JSON")
+	cmd.Flags().StringVar(&signingKeyFile, "signing-key-file", "", "Key file used to verify results file signatures")
+	cmd.Flags().BoolVar(&allowMonitorMismatch, "allow-monitor-mismatch", false, "Compare runs whose monitor poll intervals, network accounting or interface differ, with a warning")
+
+	return cmd
+}
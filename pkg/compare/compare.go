@@ -0,0 +1,309 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/telco-core/ngc-495/pkg/results"
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// Thresholds are the allowed increases, in percent, before a metric counts
+// as a regression. A zero threshold disables the check for that metric.
+type Thresholds struct {
+	TimePercent   float64 `json:"time_percent"`
+	BytesPercent  float64 `json:"bytes_percent"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryPercent float64 `json:"memory_percent"`
+}
+
+// DefaultThresholds returns the thresholds used by the nightly perf gate
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		TimePercent:   10,
+		BytesPercent:  10,
+		CPUPercent:    20,
+		MemoryPercent: 20,
+	}
+}
+
+// GroupKey aligns iterations across runs by version and cache state
+type GroupKey struct {
+	Version    string `json:"version"`
+	IsCleanRun bool   `json:"is_clean_run"`
+}
+
+// String returns a short label like "v2/clean"
+func (k GroupKey) String() string {
+	state := "cached"
+	if k.IsCleanRun {
+		state = "clean"
+	}
+	return k.Version + "/" + state
+}
+
+// GroupStats holds averaged metrics for one group of iterations
+type GroupStats struct {
+	Iterations   int     `json:"iterations"`
+	TimeSeconds  float64 `json:"time_seconds"`
+	Bytes        float64 `json:"bytes"`
+	CPUPercent   float64 `json:"cpu_percent"`
+	MemoryPeakMB float64 `json:"memory_peak_mb"`
+
+	ClusterResourcesSeconds float64 `json:"cluster_resources_seconds"` // IDMS/ITMS/CatalogSource generation
+}
+
+// MetricDelta is the change of one metric between baseline and candidate
+type MetricDelta struct {
+	Group        GroupKey `json:"group"`
+	Metric       string   `json:"metric"`
+	Baseline     float64  `json:"baseline"`
+	Candidate    float64  `json:"candidate"`
+	DeltaPercent float64  `json:"delta_percent"`
+	Threshold    float64  `json:"threshold_percent"`
+	Regressed    bool     `json:"regressed"`
+}
+
+// Report is the regression report for a candidate run against its baseline
+type Report struct {
+	BaselineRuns  []string      `json:"baseline_runs"`
+	CandidateRun  string        `json:"candidate_run"`
+	Thresholds    Thresholds    `json:"thresholds"`
+	Deltas        []MetricDelta `json:"deltas"`
+	MissingGroups []string      `json:"missing_groups,omitempty"`
+	Regressions   int           `json:"regressions"`
+
+	// Differences in how baseline runs and the candidate were sampled, which
+	// can masquerade as performance changes
+	MonitorMismatches []string `json:"monitor_mismatches,omitempty"`
+}
+
+// HasRegressions returns true if any metric exceeded its threshold
+func (r *Report) HasRegressions() bool {
+	return r.Regressions > 0
+}
+
+// FormatJSON returns the report as indented JSON
+func (r *Report) FormatJSON() (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Compare builds a regression report. The last run is the candidate; all
+// earlier runs are averaged into the baseline.
+func Compare(runs []*results.Run, thresholds Thresholds) (*Report, error) {
+	if len(runs) < 2 {
+		return nil, fmt.Errorf("at least two result files are required, got %d", len(runs))
+	}
+
+	baselineRuns := runs[:len(runs)-1]
+	candidateRun := runs[len(runs)-1]
+
+	report := &Report{
+		CandidateRun: candidateRun.Name,
+		Thresholds:   thresholds,
+		Deltas:       make([]MetricDelta, 0),
+	}
+
+	var baselineResults []runner.TestResult
+	for _, run := range baselineRuns {
+		report.BaselineRuns = append(report.BaselineRuns, run.Name)
+		baselineResults = append(baselineResults, run.Results...)
+	}
+
+	report.MonitorMismatches = monitorMismatches(baselineRuns, candidateRun)
+
+	baseline := groupResults(baselineResults)
+	candidate := groupResults(candidateRun.Results)
+
+	for _, key := range sortedKeys(baseline, candidate) {
+		base, inBase := baseline[key]
+		cand, inCand := candidate[key]
+		if !inBase || !inCand {
+			report.MissingGroups = append(report.MissingGroups, key.String())
+			continue
+		}
+
+		report.addDelta(key, "wall_time_seconds", base.TimeSeconds, cand.TimeSeconds, thresholds.TimePercent)
+		report.addDelta(key, "total_bytes", base.Bytes, cand.Bytes, thresholds.BytesPercent)
+		report.addDelta(key, "cpu_avg_percent", base.CPUPercent, cand.CPUPercent, thresholds.CPUPercent)
+		report.addDelta(key, "memory_peak_mb", base.MemoryPeakMB, cand.MemoryPeakMB, thresholds.MemoryPercent)
+		if base.ClusterResourcesSeconds > 0 || cand.ClusterResourcesSeconds > 0 {
+			report.addDelta(key, "cluster_resources_seconds", base.ClusterResourcesSeconds, cand.ClusterResourcesSeconds, thresholds.TimePercent)
+		}
+	}
+
+	return report, nil
+}
+
+func (r *Report) addDelta(key GroupKey, metric string, baseline, candidate, threshold float64) {
+	delta := MetricDelta{
+		Group:     key,
+		Metric:    metric,
+		Baseline:  baseline,
+		Candidate: candidate,
+		Threshold: threshold,
+	}
+	if baseline > 0 {
+		delta.DeltaPercent = (candidate - baseline) / baseline * 100
+	}
+	if threshold > 0 && delta.DeltaPercent > threshold {
+		delta.Regressed = true
+		r.Regressions++
+	}
+	r.Deltas = append(r.Deltas, delta)
+}
+
+// groupResults averages iterations per version and cache state
+func groupResults(testResults []runner.TestResult) map[GroupKey]GroupStats {
+	sums := make(map[GroupKey]GroupStats)
+	for i := range testResults {
+		result := &testResults[i]
+		if result.Failed || result.Warmup {
+			continue // Partial timings of failed iterations and warm-up noise would skew the averages
+		}
+		key := GroupKey{Version: result.Version, IsCleanRun: result.IsCleanRun}
+
+		stats := sums[key]
+		stats.Iterations++
+		stats.TimeSeconds += result.GetTotalTime().Seconds()
+		stats.Bytes += float64(result.GetTotalBytes())
+		stats.CPUPercent += phaseCPU(result)
+		stats.MemoryPeakMB += max(result.DownloadPhase.ResourceMetrics.MemoryPeakMB, result.UploadPhase.ResourceMetrics.MemoryPeakMB)
+		if cr := result.UploadPhase.ClusterResources; cr != nil {
+			stats.ClusterResourcesSeconds += cr.GenerationTime.Seconds()
+		}
+		sums[key] = stats
+	}
+
+	for key, stats := range sums {
+		n := float64(stats.Iterations)
+		stats.TimeSeconds /= n
+		stats.Bytes /= n
+		stats.CPUPercent /= n
+		stats.MemoryPeakMB /= n
+		stats.ClusterResourcesSeconds /= n
+		sums[key] = stats
+	}
+	return sums
+}
+
+// monitorMismatches lists the monitoring differences between each baseline run
+// and the candidate, per version. Runs recorded before monitor settings were
+// persisted are not checked.
+func monitorMismatches(baselineRuns []*results.Run, candidate *results.Run) []string {
+	candidateSettings := settingsByVersion(candidate.Results)
+	var mismatches []string
+	for _, run := range baselineRuns {
+		settings := settingsByVersion(run.Results)
+		versions := make([]string, 0, len(settings))
+		for version := range settings {
+			versions = append(versions, version)
+		}
+		sort.Strings(versions)
+		for _, version := range versions {
+			other, ok := candidateSettings[version]
+			if !ok {
+				continue
+			}
+			for _, diff := range settings[version].Diff(other) {
+				mismatches = append(mismatches, fmt.Sprintf("%s (%s): %s", run.Name, version, diff))
+			}
+		}
+	}
+	return mismatches
+}
+
+// settingsByVersion returns the monitor settings of the first iteration of each version
+func settingsByVersion(testResults []runner.TestResult) map[string]*runner.MonitorSettings {
+	settings := make(map[string]*runner.MonitorSettings)
+	for _, result := range testResults {
+		if result.MonitorSettings != nil && settings[result.Version] == nil {
+			settings[result.Version] = result.MonitorSettings
+		}
+	}
+	return settings
+}
+
+// phaseCPU returns the oc-mirror CPU usage weighted by phase duration
+func phaseCPU(result *runner.TestResult) float64 {
+	download := result.DownloadPhase.WallTime.Seconds()
+	upload := result.UploadPhase.WallTime.Seconds()
+	if download+upload == 0 {
+		return 0
+	}
+	return (result.DownloadPhase.ResourceMetrics.CPUAvgPercent*download +
+		result.UploadPhase.ResourceMetrics.CPUAvgPercent*upload) / (download + upload)
+}
+
+func sortedKeys(groups ...map[GroupKey]GroupStats) []GroupKey {
+	seen := make(map[GroupKey]bool)
+	var keys []GroupKey
+	for _, group := range groups {
+		for key := range group {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Version != keys[j].Version {
+			return keys[i].Version < keys[j].Version
+		}
+		return keys[i].IsCleanRun && !keys[j].IsCleanRun
+	})
+	return keys
+}
+
+// PrintSummary prints the regression report to the console
+func (r *Report) PrintSummary() {
+	fmt.Printf("╔═══════════════════════════════════════════════════════════════╗\n")
+	fmt.Printf("║              Run Comparison / Regression Report               ║\n")
+	fmt.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+	fmt.Printf("Baseline:  %v\n", r.BaselineRuns)
+	fmt.Printf("Candidate: %s\n", r.CandidateRun)
+	fmt.Printf("Thresholds: time +%.0f%% | bytes +%.0f%% | cpu +%.0f%% | memory +%.0f%%\n\n",
+		r.Thresholds.TimePercent, r.Thresholds.BytesPercent, r.Thresholds.CPUPercent, r.Thresholds.MemoryPercent)
+	for _, mismatch := range r.MonitorMismatches {
+		fmt.Printf("Warning: monitoring differs from the candidate: %s\n", mismatch)
+	}
+	if len(r.MonitorMismatches) > 0 {
+		fmt.Printf("\n")
+	}
+
+	var current GroupKey
+	for i, delta := range r.Deltas {
+		if i == 0 || delta.Group != current {
+			if i > 0 {
+				fmt.Printf("  └─────────────────────────────────────────────────────────────┘\n")
+			}
+			current = delta.Group
+			fmt.Printf("  ┌─ %s ─────────────────────────────────────────────────────\n", current.String())
+		}
+		status := "ok"
+		if delta.Regressed {
+			status = "REGRESSION"
+		}
+		fmt.Printf("  │ %-18s %14.2f → %14.2f  %+7.1f%%  %s\n",
+			delta.Metric, delta.Baseline, delta.Candidate, delta.DeltaPercent, status)
+	}
+	if len(r.Deltas) > 0 {
+		fmt.Printf("  └─────────────────────────────────────────────────────────────┘\n")
+	}
+
+	for _, group := range r.MissingGroups {
+		fmt.Printf("Warning: group %s is not present in both baseline and candidate\n", group)
+	}
+
+	fmt.Printf("\n")
+	if r.HasRegressions() {
+		fmt.Printf("❌ %d metric(s) exceeded thresholds\n", r.Regressions)
+	} else {
+		fmt.Printf("✅ No regressions detected\n")
+	}
+}
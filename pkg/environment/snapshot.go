@@ -0,0 +1,366 @@
+package environment
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// Snapshot records host and storage details that explain run-to-run variance
+type Snapshot struct {
+	CapturedAt    time.Time     `json:"captured_at"`
+	Hostname      string        `json:"hostname"`
+	OS            string        `json:"os"`
+	Arch          string        `json:"arch"`
+	KernelVersion string        `json:"kernel_version,omitempty"`
+	CPUCount      int           `json:"cpu_count"`
+	Containerized bool          `json:"containerized"`
+	Container     ContainerInfo `json:"container,omitempty"`
+	Storage       []StorageInfo `json:"storage"`
+	Warnings      []string      `json:"warnings,omitempty"` // Storage placements that skew the results
+}
+
+// ContainerInfo describes the container runtime the runner is executing under
+type ContainerInfo struct {
+	Runtime       string `json:"runtime,omitempty"`        // docker, podman, kubernetes
+	StorageDriver string `json:"storage_driver,omitempty"` // containers-storage driver (overlay, vfs, ...)
+	RootFSType    string `json:"root_fs_type,omitempty"`
+}
+
+// StorageInfo describes the filesystem backing a path used by the test
+type StorageInfo struct {
+	Role         string   `json:"role"` // workspace, cache, registry, ...
+	Path         string   `json:"path"`
+	MountPoint   string   `json:"mount_point,omitempty"`
+	FSType       string   `json:"fs_type,omitempty"`
+	Class        string   `json:"class,omitempty"`  // network, memory, ssd, hdd or local
+	Source       string   `json:"source,omitempty"` // Block device or remote export
+	MountOptions []string `json:"mount_options,omitempty"`
+	SuperOptions []string `json:"super_options,omitempty"` // Filesystem-specific options (e.g. NFS vers, rsize)
+	DeviceModel  string   `json:"device_model,omitempty"`
+	Rotational   *bool    `json:"rotational,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// mountEntry is a parsed line of /proc/self/mountinfo
+type mountEntry struct {
+	majorMinor   string
+	mountPoint   string
+	options      []string
+	fsType       string
+	source       string
+	superOptions []string
+}
+
+// Capture collects a snapshot for the given storage paths, keyed by role
+func Capture(paths map[string]string) *Snapshot {
+	hostname, _ := os.Hostname()
+	snapshot := &Snapshot{
+		CapturedAt:    time.Now(),
+		Hostname:      hostname,
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		KernelVersion: readTrimmed("/proc/sys/kernel/osrelease"),
+		CPUCount:      runtime.NumCPU(),
+		Storage:       make([]StorageInfo, 0, len(paths)),
+	}
+
+	mounts, mountErr := readMountInfo("/proc/self/mountinfo")
+
+	snapshot.Container = detectContainer(mounts)
+	snapshot.Containerized = snapshot.Container.Runtime != ""
+
+	for _, role := range sortedRoles(paths) {
+		info := StorageInfo{Role: role, Path: paths[role]}
+		if mountErr != nil {
+			info.Error = mountErr.Error()
+		} else {
+			describeStorage(&info, mounts)
+		}
+		snapshot.Storage = append(snapshot.Storage, info)
+	}
+
+	return snapshot
+}
+
+// PrintSummary prints the storage layout
+func (s *Snapshot) PrintSummary() {
+	line := fmt.Sprintf("Environment: %s %s/%s kernel %s, %d CPUs", s.Hostname, s.OS, s.Arch, s.KernelVersion, s.CPUCount)
+	if s.Containerized {
+		line += ", container=" + s.Container.Runtime
+	}
+	if s.Container.StorageDriver != "" {
+		line += ", storage-driver=" + s.Container.StorageDriver
+	}
+	logging.Printf("%s\n", line)
+	for _, st := range s.Storage {
+		if st.Error != "" {
+			logging.Printf("  %-10s %s (unknown: %s)\n", st.Role+":", st.Path, st.Error)
+			continue
+		}
+		device := st.Source
+		if st.DeviceModel != "" {
+			device += " [" + st.DeviceModel + "]"
+		}
+		logging.Printf("  %-10s %s → %s [%s] on %s (%s) %s\n", st.Role+":", st.Path, st.FSType, st.Class, st.MountPoint,
+			strings.Join(st.MountOptions, ","), device)
+	}
+}
+
+// Storage classes
+const (
+	ClassNetwork = "network"
+	ClassMemory  = "memory"
+	ClassSSD     = "ssd"
+	ClassHDD     = "hdd"
+	ClassLocal   = "local" // Local filesystem on a device of unknown type
+)
+
+// networkFSTypes are the filesystems served over the network
+var networkFSTypes = map[string]bool{
+	"nfs": true, "nfs4": true, "cifs": true, "smb3": true, "smbfs": true,
+	"ceph": true, "fuse.cephfs": true, "glusterfs": true, "fuse.glusterfs": true,
+	"fuse.sshfs": true, "lustre": true, "gpfs": true, "beegfs": true,
+}
+
+// storageClass classifies the storage behind a filesystem
+func storageClass(fsType string, rotational *bool) string {
+	switch {
+	case networkFSTypes[fsType]:
+		return ClassNetwork
+	case fsType == "tmpfs" || fsType == "ramfs":
+		return ClassMemory
+	case rotational != nil && *rotational:
+		return ClassHDD
+	case rotational != nil:
+		return ClassSSD
+	}
+	return ClassLocal
+}
+
+// StorageWarnings returns a warning for each of the given roles on network or
+// memory-backed storage. Network storage adds a round trip to every blob
+// lookup and memory makes cached runs look faster than on disk, so results
+// from such placements do not compare with runs on local disks.
+func (s *Snapshot) StorageWarnings(roles ...string) []string {
+	var warnings []string
+	for _, role := range roles {
+		for _, st := range s.Storage {
+			if st.Role != role {
+				continue
+			}
+			switch st.Class {
+			case ClassNetwork:
+				warnings = append(warnings, fmt.Sprintf("%s %s is on %s from %s; network storage drastically skews the timings, use local storage for comparable results",
+					role, st.Path, st.FSType, st.Source))
+			case ClassMemory:
+				warnings = append(warnings, fmt.Sprintf("%s %s is on %s; memory-backed storage makes the timings look faster than on disk",
+					role, st.Path, st.FSType))
+			}
+		}
+	}
+	return warnings
+}
+
+func describeStorage(info *StorageInfo, mounts []mountEntry) {
+	resolved, err := resolvePath(info.Path)
+	if err != nil {
+		info.Error = err.Error()
+		return
+	}
+
+	mount := findMount(resolved, mounts)
+	if mount == nil {
+		info.Error = "no mount found"
+		return
+	}
+
+	info.MountPoint = mount.mountPoint
+	info.FSType = mount.fsType
+	info.Source = mount.source
+	info.MountOptions = mount.options
+	info.SuperOptions = mount.superOptions
+	info.DeviceModel, info.Rotational = blockDeviceInfo(mount.majorMinor)
+	info.Class = storageClass(info.FSType, info.Rotational)
+}
+
+// resolvePath returns the absolute, symlink-resolved path of the deepest existing ancestor,
+// since workspace and cache directories may not exist before the first run
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for candidate := abs; ; candidate = filepath.Dir(candidate) {
+		if resolved, err := filepath.EvalSymlinks(candidate); err == nil {
+			return resolved, nil
+		}
+		if candidate == filepath.Dir(candidate) {
+			return abs, nil
+		}
+	}
+}
+
+// findMount returns the mount with the longest mount point containing path
+func findMount(path string, mounts []mountEntry) *mountEntry {
+	var best *mountEntry
+	for i := range mounts {
+		mp := mounts[i].mountPoint
+		if path != mp && !strings.HasPrefix(path, strings.TrimRight(mp, "/")+"/") {
+			continue
+		}
+		if best == nil || len(mp) >= len(best.mountPoint) {
+			best = &mounts[i]
+		}
+	}
+	return best
+}
+
+func readMountInfo(path string) ([]mountEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mount table: %w", err)
+	}
+	defer file.Close()
+
+	var mounts []mountEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i, field := range fields {
+			if field == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 6 || len(fields) < sep+3 {
+			continue
+		}
+		entry := mountEntry{
+			majorMinor: fields[2],
+			mountPoint: unescapeMountPath(fields[4]),
+			options:    strings.Split(fields[5], ","),
+			fsType:     fields[sep+1],
+			source:     fields[sep+2],
+		}
+		if len(fields) > sep+3 {
+			entry.superOptions = strings.Split(fields[sep+3], ",")
+		}
+		mounts = append(mounts, entry)
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPath decodes the octal escapes used in mountinfo (e.g. \040 for space)
+func unescapeMountPath(path string) string {
+	return strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`).Replace(path)
+}
+
+// blockDeviceInfo looks up the model and rotational flag of a block device by major:minor
+func blockDeviceInfo(majorMinor string) (string, *bool) {
+	devPath, err := filepath.EvalSymlinks(filepath.Join("/sys/dev/block", majorMinor))
+	if err != nil {
+		return "", nil
+	}
+	// Partitions have no device/ or queue/ of their own; use the parent disk
+	if _, err := os.Stat(filepath.Join(devPath, "partition")); err == nil {
+		devPath = filepath.Dir(devPath)
+	}
+
+	model := readTrimmed(filepath.Join(devPath, "device", "model"))
+	if vendor := readTrimmed(filepath.Join(devPath, "device", "vendor")); vendor != "" && model != "" {
+		model = vendor + " " + model
+	}
+
+	var rotational *bool
+	if value := readTrimmed(filepath.Join(devPath, "queue", "rotational")); value != "" {
+		r := value == "1"
+		rotational = &r
+	}
+	return model, rotational
+}
+
+var storageDriverPattern = regexp.MustCompile(`(?m)^\s*driver\s*=\s*"([^"]+)"`)
+
+// detectContainer identifies the container runtime and containers-storage driver
+func detectContainer(mounts []mountEntry) ContainerInfo {
+	info := ContainerInfo{}
+
+	switch {
+	case fileExists("/run/.containerenv"):
+		info.Runtime = "podman"
+	case fileExists("/.dockerenv"):
+		info.Runtime = "docker"
+	case os.Getenv("KUBERNETES_SERVICE_HOST") != "":
+		info.Runtime = "kubernetes"
+	default:
+		cgroup := readTrimmed("/proc/1/cgroup")
+		switch {
+		case strings.Contains(cgroup, "kubepods"):
+			info.Runtime = "kubernetes"
+		case strings.Contains(cgroup, "libpod"):
+			info.Runtime = "podman"
+		case strings.Contains(cgroup, "docker"):
+			info.Runtime = "docker"
+		}
+	}
+
+	if root := findMount("/", mounts); root != nil {
+		info.RootFSType = root.fsType
+	}
+
+	// containers-storage driver: env override first, then user and system config
+	info.StorageDriver = os.Getenv("STORAGE_DRIVER")
+	if info.StorageDriver == "" {
+		candidates := []string{"/etc/containers/storage.conf", "/usr/share/containers/storage.conf"}
+		if home, err := os.UserHomeDir(); err == nil {
+			candidates = append([]string{filepath.Join(home, ".config", "containers", "storage.conf")}, candidates...)
+		}
+		for _, candidate := range candidates {
+			data, err := os.ReadFile(candidate)
+			if err != nil {
+				continue
+			}
+			if match := storageDriverPattern.FindSubmatch(data); match != nil {
+				info.StorageDriver = string(match[1])
+				break
+			}
+		}
+	}
+
+	return info
+}
+
+func sortedRoles(paths map[string]string) []string {
+	roles := make([]string, 0, len(paths))
+	for role, path := range paths {
+		if path != "" {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+func readTrimmed(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,84 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	TypeSample    = "sample"    // A monitor took a sample; Data is the monitor's sample type
+	TypePhase     = "phase"     // The run entered a phase; Data is a PhaseChange
+	TypeIteration = "iteration" // An iteration finished; Data is the runner's iteration summary
+)
+
+// Event is something that happened during a run, published on a Bus
+type Event struct {
+	Type   string      `json:"type"`
+	Source string      `json:"source,omitempty"` // Monitor that emitted a sample, e.g. "resource"
+	Time   time.Time   `json:"time"`
+	Data   interface{} `json:"data,omitempty"`
+}
+
+// PhaseChange is the data of a phase event
+type PhaseChange struct {
+	Phase     string `json:"phase"`
+	Version   string `json:"version,omitempty"`
+	Iteration int    `json:"iteration,omitempty"`
+}
+
+// Bus delivers run events to subscribers. Publishing never blocks the run: a
+// subscriber that does not keep up misses events.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[int]chan Event
+	nextID      int
+}
+
+// NewBus creates an event bus without subscribers
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[int]chan Event)}
+}
+
+// Publish sends an event to every subscriber. Publishing on a nil bus does
+// nothing, so callers need not check whether events are wanted.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default: // Subscriber is behind; drop rather than stall a monitor
+		}
+	}
+}
+
+// Subscribe returns a channel receiving the events published from now on,
+// buffering up to buffer events, and a function that ends the subscription
+// and closes the channel
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = ch
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
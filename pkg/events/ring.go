@@ -0,0 +1,111 @@
+package events
+
+import "sync"
+
+// DefaultLiveSamples is the number of samples kept per monitor by default:
+// ten minutes at the default one second poll interval
+const DefaultLiveSamples = 600
+
+// followBacklog is how many events a following buffer may fall behind the
+// bus before it misses some
+const followBacklog = 256
+
+// ring holds the most recent events of one source, overwriting the oldest
+type ring struct {
+	events []Event
+	next   int
+	full   bool
+}
+
+func (r *ring) add(event Event) {
+	r.events[r.next] = event
+	r.next++
+	if r.next == len(r.events) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// snapshot returns the events from oldest to newest
+func (r *ring) snapshot() []Event {
+	if !r.full {
+		return append([]Event(nil), r.events[:r.next]...)
+	}
+	events := make([]Event, 0, len(r.events))
+	events = append(events, r.events[r.next:]...)
+	return append(events, r.events[:r.next]...)
+}
+
+// LiveBuffer keeps the recent samples of each monitor in memory for live
+// views. Memory is bounded by the size per source however long the run is.
+type LiveBuffer struct {
+	mu    sync.RWMutex
+	size  int
+	rings map[string]*ring
+	phase *Event
+}
+
+// LiveSnapshot is the content of a live buffer at one point in time
+type LiveSnapshot struct {
+	Size    int                `json:"size"`            // Samples kept per source
+	Phase   *Event             `json:"phase,omitempty"` // Latest phase event
+	Samples map[string][]Event `json:"samples"`         // Per source, oldest first
+}
+
+// NewLiveBuffer creates a buffer keeping size samples per source
+func NewLiveBuffer(size int) *LiveBuffer {
+	if size <= 0 {
+		size = DefaultLiveSamples
+	}
+	return &LiveBuffer{size: size, rings: make(map[string]*ring)}
+}
+
+// Add records an event: samples go to the ring of their source, phase
+// events replace the current phase
+func (l *LiveBuffer) Add(event Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch event.Type {
+	case TypeSample:
+		r, ok := l.rings[event.Source]
+		if !ok {
+			r = &ring{events: make([]Event, l.size)}
+			l.rings[event.Source] = r
+		}
+		r.add(event)
+	case TypePhase:
+		l.phase = &event
+	}
+}
+
+// Follow adds the events of bus until the returned function is called
+func (l *LiveBuffer) Follow(bus *Bus) func() {
+	events, unsubscribe := bus.Subscribe(followBacklog)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range events {
+			l.Add(event)
+		}
+	}()
+	return func() {
+		unsubscribe()
+		<-done
+	}
+}
+
+// Snapshot returns a copy of the buffer content
+func (l *LiveBuffer) Snapshot() LiveSnapshot {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	snapshot := LiveSnapshot{Size: l.size, Samples: make(map[string][]Event, len(l.rings))}
+	if l.phase != nil {
+		phase := *l.phase
+		snapshot.Phase = &phase
+	}
+	for source, r := range l.rings {
+		snapshot.Samples[source] = r.snapshot()
+	}
+	return snapshot
+}
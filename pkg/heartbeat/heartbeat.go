@@ -0,0 +1,274 @@
+package heartbeat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// Status is the liveness snapshot written on every beat
+type Status struct {
+	PID             int       `json:"pid"`
+	Hostname        string    `json:"hostname"`
+	Phase           string    `json:"phase"`
+	Version         string    `json:"version,omitempty"`
+	Iteration       int       `json:"iteration"`
+	TotalIterations int       `json:"total_iterations"`
+	BytesProcessed  int64     `json:"bytes_processed"`
+	StartedAt       time.Time `json:"started_at"`
+	PhaseStartedAt  time.Time `json:"phase_started_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	Sequence        int64     `json:"sequence"`
+}
+
+// Heartbeat periodically writes a status file and optionally pings a
+// monitoring URL so external watchdogs can detect a hung run
+type Heartbeat struct {
+	path         string
+	pingURL      string
+	interval     time.Duration
+	status       Status
+	progressFunc func() int64
+	client       *http.Client
+	running      bool
+	stopChan     chan struct{}
+	doneChan     chan struct{}
+	pings        chan []byte   // Latest status waiting for the ping goroutine
+	pingDone     chan struct{} // Closed when the ping goroutine exits
+	mu           sync.Mutex
+	writeMu      sync.Mutex // Serializes beats so an older status never replaces a newer one
+}
+
+// NewHeartbeat creates a heartbeat writing to the given file every interval
+func NewHeartbeat(path string, interval time.Duration) *Heartbeat {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	return &Heartbeat{
+		path:     path,
+		interval: interval,
+		status: Status{
+			PID:            os.Getpid(),
+			Hostname:       hostname,
+			Phase:          "starting",
+			StartedAt:      now,
+			PhaseStartedAt: now,
+		},
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SetPingURL sets a URL that receives the status as a JSON POST on every beat
+func (h *Heartbeat) SetPingURL(url string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pingURL = url
+}
+
+// SetProgressFunc sets a function used to sample bytes processed on every beat
+func (h *Heartbeat) SetProgressFunc(fn func() int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.progressFunc = fn
+}
+
+// SetTotalIterations sets the number of iterations planned for the run
+func (h *Heartbeat) SetTotalIterations(total int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status.TotalIterations = total
+}
+
+// SetPhase records a phase transition and beats immediately. The status file
+// is written before it returns; the ping is sent in the background.
+func (h *Heartbeat) SetPhase(phase, version string, iteration int) {
+	h.mu.Lock()
+	h.status.Phase = phase
+	h.status.Version = version
+	h.status.Iteration = iteration
+	h.status.PhaseStartedAt = time.Now()
+	h.mu.Unlock()
+
+	h.beat()
+}
+
+// GetInterval returns the interval between heartbeats
+func (h *Heartbeat) GetInterval() time.Duration {
+	return h.interval
+}
+
+// GetStatus returns the most recent status snapshot
+func (h *Heartbeat) GetStatus() Status {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.status
+}
+
+// Start begins writing heartbeats in the background
+func (h *Heartbeat) Start() error {
+	h.mu.Lock()
+	if h.running {
+		h.mu.Unlock()
+		return fmt.Errorf("heartbeat already running")
+	}
+	if h.path != "" {
+		if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+			h.mu.Unlock()
+			return fmt.Errorf("failed to create heartbeat directory: %w", err)
+		}
+	}
+	h.running = true
+	h.stopChan = make(chan struct{})
+	h.doneChan = make(chan struct{})
+	h.pings = make(chan []byte, 1)
+	h.pingDone = make(chan struct{})
+	go h.sendPings(h.pings, h.pingDone)
+	h.mu.Unlock()
+
+	h.beat()
+	go h.loop()
+	return nil
+}
+
+// Stop records the final phase, writes a last heartbeat and stops the loop
+func (h *Heartbeat) Stop(finalPhase string) {
+	h.mu.Lock()
+	if !h.running {
+		h.mu.Unlock()
+		return
+	}
+	h.running = false
+	close(h.stopChan)
+	h.mu.Unlock()
+
+	<-h.doneChan
+	h.SetPhase(finalPhase, "", h.GetStatus().Iteration)
+
+	// Wait for the ping of the final phase
+	h.mu.Lock()
+	close(h.pings)
+	h.pings = nil
+	h.mu.Unlock()
+	<-h.pingDone
+}
+
+func (h *Heartbeat) loop() {
+	defer close(h.doneChan)
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stopChan:
+			return
+		case <-ticker.C:
+			h.beat()
+		}
+	}
+}
+
+// beat writes the status file and queues the optional ping. Beats of the loop
+// and of SetPhase are serialized, so the file always holds the newest status.
+// Failures are reported but never interrupt the run.
+func (h *Heartbeat) beat() {
+	h.writeMu.Lock()
+	defer h.writeMu.Unlock()
+
+	h.mu.Lock()
+	if h.progressFunc != nil {
+		h.status.BytesProcessed = h.progressFunc()
+	}
+	h.status.UpdatedAt = time.Now()
+	h.status.Sequence++
+	status := h.status
+	path := h.path
+	h.mu.Unlock()
+
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		logging.Printf("Warning: Failed to marshal heartbeat: %v\n", err)
+		return
+	}
+
+	if path != "" {
+		if err := writeFileAtomic(path, data); err != nil {
+			logging.Printf("Warning: Failed to write heartbeat file: %v\n", err)
+		}
+	}
+	h.queuePing(data)
+}
+
+// queuePing hands the status to the ping goroutine, replacing a status it has
+// not sent yet, so a slow monitoring URL never holds up the run
+func (h *Heartbeat) queuePing(data []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pings == nil || h.pingURL == "" {
+		return
+	}
+	select {
+	case <-h.pings:
+	default:
+	}
+	h.pings <- data
+}
+
+// sendPings posts the queued statuses to the ping URL until pings is closed
+func (h *Heartbeat) sendPings(pings <-chan []byte, done chan<- struct{}) {
+	defer close(done)
+	for data := range pings {
+		h.mu.Lock()
+		pingURL := h.pingURL
+		h.mu.Unlock()
+		if err := h.ping(pingURL, data); err != nil {
+			logging.Printf("Warning: Failed to send heartbeat ping: %v\n", err)
+		}
+	}
+}
+
+func (h *Heartbeat) ping(url string, data []byte) error {
+	resp, err := h.client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// writeFileAtomic writes to a temp file and renames it so readers never see a
+// partial file. The temp file is unique, in the directory of path, so that
+// concurrent writers never share it.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,146 @@
+package integrity
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Verification statuses
+const (
+	StatusVerified         = "verified"          // Checksum matches
+	StatusSigned           = "signed"            // Checksum and signature match
+	StatusUnverified       = "unverified"        // No checksum sidecar (e.g. written by an older version)
+	StatusModified         = "modified"          // Content changed after the run
+	StatusInvalidSignature = "invalid_signature" // Signature missing or does not match the key
+)
+
+// Result is the outcome of verifying a results file
+type Result struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// IsTampered returns true if the file cannot be trusted
+func (r Result) IsTampered() bool {
+	return r.Status == StatusModified || r.Status == StatusInvalidSignature
+}
+
+// ChecksumPath returns the sha256 sidecar path for a file
+func ChecksumPath(path string) string {
+	return path + ".sha256"
+}
+
+// SignaturePath returns the HMAC signature sidecar path for a file
+func SignaturePath(path string) string {
+	return path + ".sig"
+}
+
+// LoadKey reads a signing key from a file
+func LoadKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	key := []byte(strings.TrimSpace(string(data)))
+	if len(key) == 0 {
+		return nil, fmt.Errorf("signing key file %s is empty", path)
+	}
+	return key, nil
+}
+
+// WriteSidecars writes a sha256sum-compatible checksum file and, when a key
+// is given, an HMAC-SHA256 signature next to the file
+func WriteSidecars(path string, key []byte) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file for checksum: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	line := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), filepath.Base(path))
+	if err := writeAtomic(ChecksumPath(path), []byte(line)); err != nil {
+		return fmt.Errorf("failed to write checksum: %w", err)
+	}
+
+	if len(key) > 0 {
+		if err := writeAtomic(SignaturePath(path), []byte(sign(data, key)+"\n")); err != nil {
+			return fmt.Errorf("failed to write signature: %w", err)
+		}
+	}
+	return nil
+}
+
+// Verify checks a file against its sidecars. A key enables signature
+// verification; without a key only the checksum is checked.
+func Verify(path string, key []byte) Result {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Result{Status: StatusUnverified, Message: err.Error()}
+	}
+	// Missing sidecars are passed as nil
+	checksum, _ := os.ReadFile(ChecksumPath(path))
+	signature, _ := os.ReadFile(SignaturePath(path))
+	return VerifyData(data, checksum, signature, key)
+}
+
+// VerifyData checks file contents against the contents of its checksum and
+// signature sidecars, for files that are not on the local filesystem. A nil
+// sidecar is treated as missing.
+func VerifyData(data, checksum, signature, key []byte) Result {
+	if checksum == nil {
+		if len(key) > 0 {
+			return Result{Status: StatusInvalidSignature, Message: "checksum and signature missing"}
+		}
+		return Result{Status: StatusUnverified, Message: "no checksum file"}
+	}
+
+	fields := strings.Fields(string(checksum))
+	if len(fields) == 0 {
+		return Result{Status: StatusModified, Message: "checksum file is empty"}
+	}
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(fields[0], hex.EncodeToString(sum[:])) {
+		return Result{Status: StatusModified, Message: "sha256 does not match checksum recorded after the run"}
+	}
+
+	if len(key) == 0 {
+		return Result{Status: StatusVerified}
+	}
+
+	if signature == nil {
+		return Result{Status: StatusInvalidSignature, Message: "signature missing"}
+	}
+	expected, err := hex.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return Result{Status: StatusInvalidSignature, Message: "signature is not valid hex"}
+	}
+	actual, _ := hex.DecodeString(sign(data, key))
+	if !hmac.Equal(expected, actual) {
+		return Result{Status: StatusInvalidSignature, Message: "signature does not match signing key"}
+	}
+
+	return Result{Status: StatusSigned}
+}
+
+func sign(data, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func writeAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
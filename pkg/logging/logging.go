@@ -0,0 +1,333 @@
+// Package logging routes the console output of the runner, monitors,
+// commands and web UI through log/slog. The console format keeps the
+// box-drawing output for humans; the JSON format emits one record per line
+// for log pipelines, with the structured events the console leaves out.
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Output formats
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+)
+
+// LevelSummary is the level of the final summary of a run, above errors so
+// the quiet output keeps it
+const LevelSummary = slog.LevelError + 4
+
+// kindKey is the context key telling the handlers how a record was produced
+type kindKey struct{}
+
+const (
+	kindRaw   = 1 // Console text from Printf, possibly a partial line
+	kindEvent = 2 // Structured event the console output already shows
+)
+
+var (
+	mu       sync.RWMutex
+	current  slog.Handler = newConsoleHandler(os.Stdout, slog.LevelInfo)
+	format                = FormatConsole
+	minLevel              = slog.LevelInfo
+
+	// summaries counts the Summary calls in progress
+	summaries atomic.Int32
+)
+
+// Setup installs the handler of a level (debug, info, warn or error) and
+// format (console or json) as the default slog handler, so the standard log
+// package and slog calls go through it too
+func Setup(level, outputFormat string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	var handler slog.Handler
+	switch outputFormat {
+	case "", FormatConsole:
+		outputFormat = FormatConsole
+		handler = newConsoleHandler(os.Stdout, lvl)
+	case FormatJSON:
+		handler = newJSONHandler(os.Stdout, lvl)
+	default:
+		return fmt.Errorf("invalid log format %q (expected %s or %s)", outputFormat, FormatConsole, FormatJSON)
+	}
+
+	mu.Lock()
+	current = handler
+	format = outputFormat
+	minLevel = lvl
+	mu.Unlock()
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	var lvl slog.Level
+	if level == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q (expected debug, info, warn or error)", level)
+	}
+	return lvl, nil
+}
+
+// Interactive returns true if the output is the console format at info
+// level, where the live progress line belongs: JSON records, the quiet output
+// and the verbose passthrough leave it out
+func Interactive() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return format == FormatConsole && minLevel == slog.LevelInfo
+}
+
+// Enabled returns true if records of a level are written
+func Enabled(lvl slog.Level) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return lvl >= minLevel
+}
+
+// handler returns the installed handler
+func handler() slog.Handler {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Printf writes console text. Its level is taken from the text: warnings and
+// errors can be kept while the rest is filtered out.
+func Printf(format string, args ...any) {
+	emit(fmt.Sprintf(format, args...))
+}
+
+// Println writes console text like fmt.Println
+func Println(args ...any) {
+	emit(fmt.Sprintln(args...))
+}
+
+// Print writes console text like fmt.Print
+func Print(args ...any) {
+	emit(fmt.Sprint(args...))
+}
+
+// Debugf writes console text at debug level, shown by the verbose output
+func Debugf(format string, args ...any) {
+	emitAt(slog.LevelDebug, fmt.Sprintf(format, args...))
+}
+
+// Debug records a structured debug record with its attributes
+func Debug(msg string, args ...any) {
+	slog.New(handler()).Debug(msg, args...)
+}
+
+// Summary runs fn with its console text raised to LevelSummary, for the
+// final summary of a run the quiet output keeps
+func Summary(fn func()) {
+	summaries.Add(1)
+	defer summaries.Add(-1)
+	fn()
+}
+
+// Event records a structured event with its attributes. The console format
+// only shows it at debug level, since the console text already covers it.
+func Event(msg string, args ...any) {
+	ctx := context.WithValue(context.Background(), kindKey{}, kindEvent)
+	slog.New(handler()).Log(ctx, slog.LevelInfo, msg, args...)
+}
+
+// emit hands console text to the handler at the level of its content
+func emit(text string) {
+	if summaries.Load() > 0 {
+		emitAt(LevelSummary, text)
+		return
+	}
+	emitAt(levelOf(text), text)
+}
+
+// emitAt hands console text to the handler at a level
+func emitAt(level slog.Level, text string) {
+	h := handler()
+	ctx := context.WithValue(context.Background(), kindKey{}, kindRaw)
+	if !h.Enabled(ctx, level) {
+		return
+	}
+	_ = h.Handle(ctx, slog.NewRecord(time.Now(), level, text, 0))
+}
+
+// levelOf classifies console text by the markers the output uses
+func levelOf(text string) slog.Level {
+	switch {
+	case strings.Contains(text, "Error:") || strings.Contains(text, "❌"):
+		return slog.LevelError
+	case strings.Contains(text, "Warning") || strings.Contains(text, "⚠"):
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// recordKind returns how the record of ctx was produced, 0 for slog and log calls
+func recordKind(ctx context.Context) int {
+	kind, _ := ctx.Value(kindKey{}).(int)
+	return kind
+}
+
+// consoleHandler writes console text as it is and other records as a
+// message followed by its attributes
+type consoleHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Level
+	attrs []slog.Attr
+}
+
+func newConsoleHandler(w io.Writer, level slog.Level) *consoleHandler {
+	return &consoleHandler{mu: &sync.Mutex{}, w: w, level: level}
+}
+
+func (h *consoleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if recordKind(ctx) == kindEvent {
+		return h.level <= slog.LevelDebug
+	}
+	return level >= h.level
+}
+
+func (h *consoleHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if recordKind(ctx) == kindRaw {
+		_, err := io.WriteString(h.w, r.Message)
+		return err
+	}
+
+	var b strings.Builder
+	if r.Level != slog.LevelInfo {
+		b.WriteString(levelName(r.Level) + " ")
+	}
+	b.WriteString(r.Message)
+	appendAttr := func(a slog.Attr) bool {
+		if a.Value.Kind() == slog.KindAny {
+			if data, err := json.Marshal(a.Value.Any()); err == nil {
+				fmt.Fprintf(&b, " %s=%s", a.Key, data)
+				return true
+			}
+		}
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		return true
+	}
+	for _, a := range h.attrs {
+		appendAttr(a)
+	}
+	r.Attrs(appendAttr)
+	b.WriteString("\n")
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &clone
+}
+
+func (h *consoleHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// jsonHandler emits console text as records of whole lines, without the
+// box-drawing decoration, and passes other records to slog's JSON handler
+type jsonHandler struct {
+	mu      *sync.Mutex
+	partial *strings.Builder // Console text not ended by a newline yet
+	next    slog.Handler
+}
+
+func newJSONHandler(w io.Writer, level slog.Level) *jsonHandler {
+	return &jsonHandler{
+		mu:      &sync.Mutex{},
+		partial: &strings.Builder{},
+		next: slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if lvl, ok := a.Value.Any().(slog.Level); ok && a.Key == slog.LevelKey && len(groups) == 0 {
+				return slog.String(slog.LevelKey, levelName(lvl))
+			}
+			return a
+		}}),
+	}
+}
+
+func (h *jsonHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *jsonHandler) Handle(ctx context.Context, r slog.Record) error {
+	if recordKind(ctx) != kindRaw {
+		return h.next.Handle(ctx, r)
+	}
+	if strings.Contains(r.Message, "\r") {
+		return nil // Live progress redraws
+	}
+
+	h.mu.Lock()
+	h.partial.WriteString(r.Message)
+	text := h.partial.String()
+	end := strings.LastIndex(text, "\n")
+	if end < 0 {
+		h.mu.Unlock()
+		return nil
+	}
+	h.partial.Reset()
+	h.partial.WriteString(text[end+1:])
+	h.mu.Unlock()
+
+	for _, line := range strings.Split(text[:end], "\n") {
+		line = strings.TrimSpace(strings.Trim(line, decoration))
+		level := r.Level
+		if level != slog.LevelDebug && level != LevelSummary {
+			level = levelOf(line) // A chunk of text may mix info and warning lines
+		}
+		if line == "" || !h.next.Enabled(ctx, level) {
+			continue
+		}
+		record := slog.NewRecord(r.Time, level, line, 0)
+		if err := h.next.Handle(ctx, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *jsonHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithAttrs(attrs)
+	return &clone
+}
+
+func (h *jsonHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithGroup(name)
+	return &clone
+}
+
+// levelName returns the name of a level, SUMMARY for LevelSummary
+func levelName(lvl slog.Level) string {
+	if lvl == LevelSummary {
+		return "SUMMARY"
+	}
+	return lvl.String()
+}
+
+// decoration is the box-drawing trimmed from console lines in JSON records
+const decoration = " \t│║╔╗╚╝═─┌┐└┘├┤╠╣╟╢━┃┏┓┗┛"
@@ -0,0 +1,112 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Access log formats the registry response status is read from
+var (
+	// Common and combined log format, as written by nginx, HAProxy and Apache in
+	// front of Quay or Harbor, and by the distribution registry's own access log:
+	// 10.0.0.1 - - [16/Oct/2026:10:00:00 +0000] "PUT /v2/ocp/blobs/uploads/... HTTP/1.1" 201 0 ...
+	combinedLogPattern = regexp.MustCompile(`\[([^\]]+)\]\s+"[A-Z]+\s+(\S+)\s+HTTP/[\d.]+"\s+(\d{3})\b`)
+
+	// distribution registry application log, logfmt or JSON:
+	// time="2026-10-16T10:00:00Z" ... http.request.uri="/v2/..." http.response.status=201
+	registryStatusPattern = regexp.MustCompile(`"?http\.response\.status"?\s*[=:]\s*"?(\d{3})\b`)
+	registryURIPattern    = regexp.MustCompile(`"?http\.request\.uri"?\s*[=:]\s*"([^"]*)"`)
+	registryTimePattern   = regexp.MustCompile(`"?time"?\s*[=:]\s*"([^"]+)"`)
+)
+
+const combinedLogTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogMonitor reads the registry responses of a phase from an access log:
+// the lines appended between Start and Stop
+type AccessLogMonitor struct {
+	path    string
+	offset  int64
+	started time.Time
+}
+
+// NewAccessLogMonitor creates a monitor for the access log at path
+func NewAccessLogMonitor(path string) *AccessLogMonitor {
+	return &AccessLogMonitor{path: path}
+}
+
+// Start remembers where the log ends
+func (m *AccessLogMonitor) Start() error {
+	info, err := os.Stat(m.path)
+	if err != nil {
+		return fmt.Errorf("failed to read access log: %w", err)
+	}
+	m.offset = info.Size()
+	m.started = time.Now()
+	return nil
+}
+
+// Stop tallies the registry API responses logged since Start. A log that
+// shrank was rotated and is read from the beginning.
+func (m *AccessLogMonitor) Stop() (*HTTPStatusTally, error) {
+	file, err := os.Open(m.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access log: %w", err)
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err == nil && info.Size() < m.offset {
+		m.offset = 0
+	}
+	if _, err := file.Seek(m.offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read access log: %w", err)
+	}
+
+	tally := NewHTTPStatusTally(m.started)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if at, code, ok := ParseAccessLogLine(scanner.Text()); ok {
+			if at.IsZero() {
+				at = m.started
+			}
+			tally.Add(at, code)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return tally, fmt.Errorf("failed to read access log: %w", err)
+	}
+	return tally, nil
+}
+
+// ParseAccessLogLine returns the time and status of a registry API (/v2/)
+// response line; the time is zero when the line has none that parses
+func ParseAccessLogLine(line string) (time.Time, int, bool) {
+	if m := combinedLogPattern.FindStringSubmatch(line); m != nil {
+		if !strings.HasPrefix(m[2], "/v2/") {
+			return time.Time{}, 0, false
+		}
+		code, _ := strconv.Atoi(m[3])
+		at, _ := time.Parse(combinedLogTimeFormat, m[1])
+		return at, code, true
+	}
+
+	m := registryStatusPattern.FindStringSubmatch(line)
+	if m == nil {
+		return time.Time{}, 0, false
+	}
+	if uri := registryURIPattern.FindStringSubmatch(line); uri != nil && !strings.HasPrefix(uri[1], "/v2/") {
+		return time.Time{}, 0, false
+	}
+	code, _ := strconv.Atoi(m[1])
+	var at time.Time
+	if t := registryTimePattern.FindStringSubmatch(line); t != nil {
+		at, _ = time.Parse(time.RFC3339Nano, t[1])
+	}
+	return at, code, true
+}
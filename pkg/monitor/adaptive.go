@@ -0,0 +1,210 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Defaults of adaptive polling
+const (
+	DefaultAdaptiveFactor      = 2.0
+	DefaultAdaptiveMaxInterval = 30 * time.Second
+)
+
+// AdaptivePolling backs the poll interval of a phase's monitors off on long
+// phases. Up to After, monitors sample at their configured interval; from
+// then on the interval is multiplied by Factor each time the phase length
+// doubles (at After, 2×After, 4×After, ...), up to MaxInterval.
+type AdaptivePolling struct {
+	After       time.Duration `json:"after,omitempty" yaml:"after,omitempty"` // Phase length before backing off; 0 disables
+	Factor      float64       `json:"factor,omitempty" yaml:"factor,omitempty"`
+	MaxInterval time.Duration `json:"max_interval,omitempty" yaml:"maxInterval,omitempty"`
+}
+
+// Enabled returns true if the poll interval backs off
+func (a AdaptivePolling) Enabled() bool {
+	return a.After > 0
+}
+
+// Validate checks the back-off settings
+func (a AdaptivePolling) Validate() error {
+	if a.After < 0 || a.MaxInterval < 0 {
+		return fmt.Errorf("adaptive polling durations must not be negative")
+	}
+	if a.Factor != 0 && a.Factor <= 1 {
+		return fmt.Errorf("adaptive polling factor must be greater than 1")
+	}
+	return nil
+}
+
+// limits returns the factor and maximum interval, defaulted
+func (a AdaptivePolling) limits() (float64, time.Duration) {
+	factor, ceiling := a.Factor, a.MaxInterval
+	if factor == 0 {
+		factor = DefaultAdaptiveFactor
+	}
+	if ceiling == 0 {
+		ceiling = DefaultAdaptiveMaxInterval
+	}
+	return factor, ceiling
+}
+
+// String describes the back-off, e.g. "after 30m0s, x2 up to 30s"
+func (a AdaptivePolling) String() string {
+	if !a.Enabled() {
+		return "off"
+	}
+	factor, ceiling := a.limits()
+	return fmt.Sprintf("after %v, x%g up to %v", a.After, factor, ceiling)
+}
+
+// IntervalAt returns the poll interval of a monitor configured with base
+// after elapsed of the phase
+func (a AdaptivePolling) IntervalAt(base, elapsed time.Duration) time.Duration {
+	if !a.Enabled() || elapsed < a.After {
+		return base
+	}
+	factor, ceiling := a.limits()
+	if base >= ceiling {
+		return base
+	}
+	steps := math.Floor(math.Log2(float64(elapsed)/float64(a.After))) + 1
+	interval := float64(base) * math.Pow(factor, steps)
+	if interval >= float64(ceiling) {
+		return ceiling
+	}
+	return time.Duration(interval)
+}
+
+// PollStep is a change of a monitor's poll interval during a phase
+type PollStep struct {
+	OffsetMs   int64 `json:"offset_ms"` // Since the monitor started
+	IntervalMs int64 `json:"interval_ms"`
+}
+
+// Poller delivers the ticks of a monitor loop like a time.Ticker, backing
+// the interval off according to an AdaptivePolling
+type Poller struct {
+	C <-chan time.Time
+
+	c       chan time.Time
+	base    time.Duration
+	policy  AdaptivePolling
+	start   time.Time
+	flush   chan struct{}
+	stop    chan struct{}
+	stopped sync.Once
+
+	mu      sync.Mutex
+	current time.Duration
+	steps   []PollStep
+}
+
+// NewPoller starts a poller ticking every base until policy backs it off
+func NewPoller(base time.Duration, policy AdaptivePolling) *Poller {
+	c := make(chan time.Time, 1)
+	p := &Poller{
+		C:       c,
+		c:       c,
+		base:    base,
+		policy:  policy,
+		start:   time.Now(),
+		flush:   make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		current: base,
+	}
+	go p.run()
+	return p
+}
+
+func (p *Poller) run() {
+	timer := time.NewTimer(p.base)
+	defer timer.Stop()
+	for {
+		select {
+		case now := <-timer.C:
+			p.tick(now)
+			next := p.policy.IntervalAt(p.base, now.Sub(p.start))
+			p.mu.Lock()
+			if next != p.current {
+				p.current = next
+				p.steps = append(p.steps, PollStep{OffsetMs: now.Sub(p.start).Milliseconds(), IntervalMs: next.Milliseconds()})
+			}
+			p.mu.Unlock()
+			timer.Reset(next)
+		case <-p.flush:
+			p.tick(time.Now())
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// tick delivers a tick, dropping it when the loop has not taken the last one
+func (p *Poller) tick(now time.Time) {
+	select {
+	case p.c <- now:
+	default:
+	}
+}
+
+// Flush delivers a tick right away when the poller has backed off, so the end
+// of a phase is still captured at the configured resolution
+func (p *Poller) Flush() {
+	p.mu.Lock()
+	backedOff := p.current != p.base
+	p.mu.Unlock()
+	if !backedOff {
+		return
+	}
+	select {
+	case p.flush <- struct{}{}:
+	default:
+	}
+}
+
+// Stop stops the poller; no more ticks are delivered
+func (p *Poller) Stop() {
+	p.stopped.Do(func() { close(p.stop) })
+}
+
+// Steps returns the interval changes so far
+func (p *Poller) Steps() []PollStep {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PollStep(nil), p.steps...)
+}
+
+// adaptivePoller is embedded by the monitors whose poll interval can back off
+type adaptivePoller struct {
+	adaptive AdaptivePolling
+	poller   *Poller
+}
+
+// SetAdaptivePolling sets the back-off of the poll interval; call it before Start
+func (a *adaptivePoller) SetAdaptivePolling(policy AdaptivePolling) {
+	a.adaptive = policy
+}
+
+// PollSteps returns the poll interval changes of the monitor
+func (a *adaptivePoller) PollSteps() []PollStep {
+	if a.poller == nil {
+		return nil
+	}
+	return a.poller.Steps()
+}
+
+// startPoller starts the poller of a monitor loop
+func (a *adaptivePoller) startPoller(base time.Duration) *Poller {
+	a.poller = NewPoller(base, a.adaptive)
+	return a.poller
+}
+
+// flushPoller takes the final sample of a backed-off monitor when it stops
+func (a *adaptivePoller) flushPoller() {
+	if a.poller != nil {
+		a.poller.Flush()
+	}
+}
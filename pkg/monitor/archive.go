@@ -0,0 +1,135 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// ArchiveMonitor watches the tar archives oc-mirror writes to a mirror-to-disk
+// directory. oc-mirror only writes them after mirroring, so the time from the
+// first archive write to the end of the phase is the archive creation time.
+type ArchiveMonitor struct {
+	dir          string
+	pattern      string
+	pollInterval time.Duration
+	mu           sync.Mutex
+	firstWrite   time.Time
+	done         chan struct{}
+	stopped      chan struct{}
+}
+
+// ArchiveFile is one archive of a mirror-to-disk run
+type ArchiveFile struct {
+	Name  string `json:"Name"`
+	Bytes int64  `json:"Bytes"`
+}
+
+// ArchiveMetrics describes the archives created by a phase
+type ArchiveMetrics struct {
+	Dir          string        `json:"Dir"`
+	Files        []ArchiveFile `json:"Files"`
+	TotalBytes   int64         `json:"TotalBytes"`
+	FirstWrite   time.Time     `json:"FirstWrite,omitempty"`
+	CreationTime time.Duration `json:"CreationTime"` // From the first archive write to the end of the phase
+	WriteRateMBs float64       `json:"WriteRateMBs"`
+}
+
+// NewArchiveMonitor creates a monitor for the archives matching a glob pattern in dir
+func NewArchiveMonitor(dir, pattern string) *ArchiveMonitor {
+	return &ArchiveMonitor{dir: dir, pattern: pattern, pollInterval: DefaultPollInterval}
+}
+
+// Start polls for the first archive write
+func (am *ArchiveMonitor) Start() error {
+	if _, err := filepath.Match(am.pattern, ""); err != nil {
+		return fmt.Errorf("invalid archive pattern %q: %w", am.pattern, err)
+	}
+	am.done = make(chan struct{})
+	am.stopped = make(chan struct{})
+	go am.monitorLoop()
+	return nil
+}
+
+func (am *ArchiveMonitor) monitorLoop() {
+	defer close(am.stopped)
+	ticker := time.NewTicker(am.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-am.done:
+			return
+		case <-ticker.C:
+			files, _ := FindArchives(am.dir, am.pattern)
+			if len(files) > 0 {
+				am.mu.Lock()
+				am.firstWrite = time.Now()
+				am.mu.Unlock()
+				return
+			}
+		}
+	}
+}
+
+// Stop ends monitoring and returns the archives found
+func (am *ArchiveMonitor) Stop() ArchiveMetrics {
+	if am.done != nil {
+		close(am.done)
+		<-am.stopped
+		am.done = nil
+	}
+	end := time.Now()
+
+	metrics := ArchiveMetrics{Dir: am.dir}
+	files, err := FindArchives(am.dir, am.pattern)
+	if err != nil {
+		logging.Printf("  │ Warning: Failed to list archives: %v\n", err)
+	}
+	metrics.Files = files
+	for _, file := range files {
+		metrics.TotalBytes += file.Bytes
+	}
+
+	am.mu.Lock()
+	metrics.FirstWrite = am.firstWrite
+	am.mu.Unlock()
+	if !metrics.FirstWrite.IsZero() {
+		metrics.CreationTime = end.Sub(metrics.FirstWrite)
+		if seconds := metrics.CreationTime.Seconds(); seconds > 0 {
+			metrics.WriteRateMBs = float64(metrics.TotalBytes) / seconds / (1024 * 1024)
+		}
+	}
+	return metrics
+}
+
+// FindArchives returns the archives matching a glob pattern in dir, by name
+func FindArchives(dir, pattern string) ([]ArchiveFile, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var files []ArchiveFile
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, ArchiveFile{Name: filepath.Base(path), Bytes: info.Size()})
+	}
+	return files, nil
+}
+
+// PrintSummary prints a formatted summary of the archives
+func (m *ArchiveMetrics) PrintSummary() {
+	logging.Printf("  │ ─── Archive ──────────────────────────────────────────────────\n")
+	logging.Printf("  │   %d archive(s), %s in %s\n", len(m.Files), FormatBytesHuman(m.TotalBytes), m.Dir)
+	if m.CreationTime > 0 {
+		logging.Printf("  │   Created in %v (%.2f MB/s)\n", m.CreationTime.Round(time.Millisecond), m.WriteRateMBs)
+	}
+}
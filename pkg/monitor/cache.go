@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// CacheMonitor measures the oc-mirror v2 cache directory before and after a
+// phase. The cache is a registry storage layout (docker/registry/v2/blobs/...)
+// below <cache-dir>/.oc-mirror/.cache, so its growth is what the phase had to
+// fetch and the blobs already present are what it could reuse.
+type CacheMonitor struct {
+	cacheDir string
+	before   CacheSnapshot
+	started  time.Time
+}
+
+// CacheSnapshot is the size of the cache at one point in time
+type CacheSnapshot struct {
+	SizeBytes int64 `json:"SizeBytes"`
+	Files     int   `json:"Files"`
+	Blobs     int   `json:"Blobs"` // Layer and config blobs: data files below a blobs directory
+}
+
+// CacheMetrics represents the cache change over a phase
+type CacheMetrics struct {
+	CacheDir    string        `json:"CacheDir"`
+	Duration    time.Duration `json:"Duration"`
+	Before      CacheSnapshot `json:"Before"`
+	After       CacheSnapshot `json:"After"`
+	GrowthBytes int64         `json:"GrowthBytes"`
+	NewFiles    int           `json:"NewFiles"`
+	NewBlobs    int           `json:"NewBlobs"`
+
+	// Inferred against the clean run of the same version: the bytes the clean
+	// run added to the cache that this phase did not have to fetch again.
+	// Unset when the clean run found the cache already warm.
+	BytesNotDownloaded int64    `json:"BytesNotDownloaded,omitempty"`
+	HitRatio           *float64 `json:"HitRatio,omitempty"` // 0-1
+}
+
+// NewCacheMonitor creates a cache monitor for the oc-mirror --cache-dir
+func NewCacheMonitor(cacheDir string) *CacheMonitor {
+	return &CacheMonitor{cacheDir: cacheDir}
+}
+
+// Start measures the cache before the phase
+func (cm *CacheMonitor) Start() error {
+	snapshot, err := MeasureCache(cm.cacheDir)
+	if err != nil {
+		return err
+	}
+	cm.before = snapshot
+	cm.started = time.Now()
+	return nil
+}
+
+// Stop measures the cache after the phase and returns the change
+func (cm *CacheMonitor) Stop() CacheMetrics {
+	after, err := MeasureCache(cm.cacheDir)
+	if err != nil {
+		logging.Printf("  │ Warning: Failed to measure oc-mirror cache: %v\n", err)
+	}
+	return CacheMetrics{
+		CacheDir:    cm.cacheDir,
+		Duration:    time.Since(cm.started),
+		Before:      cm.before,
+		After:       after,
+		GrowthBytes: after.SizeBytes - cm.before.SizeBytes,
+		NewFiles:    after.Files - cm.before.Files,
+		NewBlobs:    after.Blobs - cm.before.Blobs,
+	}
+}
+
+// MeasureCache walks the cache directory. A missing directory is an empty
+// cache; oc-mirror creates it on first use.
+func MeasureCache(cacheDir string) (CacheSnapshot, error) {
+	var snapshot CacheSnapshot
+	err := filepath.WalkDir(cacheDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil // Missing cache, or removed while walking
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		snapshot.SizeBytes += info.Size()
+		snapshot.Files++
+		if entry.Name() == "data" && isBlobPath(path) {
+			snapshot.Blobs++
+		}
+		return nil
+	})
+	return snapshot, err
+}
+
+// isBlobPath reports whether a data file belongs to the blob store
+// (.../blobs/sha256/ab/<digest>/data) rather than a repository link
+func isBlobPath(path string) bool {
+	for dir := filepath.Dir(path); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if filepath.Base(dir) == "blobs" {
+			return true
+		}
+		if filepath.Base(dir) == "repositories" {
+			return false
+		}
+	}
+	return false
+}
+
+// InferHitRatio sets the bytes not downloaded and the hit ratio against the
+// cache growth of the clean run. The clean run itself has a ratio of 0.
+func (m *CacheMetrics) InferHitRatio(cleanGrowthBytes int64) {
+	if cleanGrowthBytes <= 0 {
+		return
+	}
+	m.BytesNotDownloaded = max(cleanGrowthBytes-max(m.GrowthBytes, 0), 0)
+	ratio := float64(m.BytesNotDownloaded) / float64(cleanGrowthBytes)
+	m.HitRatio = &ratio
+}
+
+// PrintSummary prints a formatted summary of the cache change
+func (m *CacheMetrics) PrintSummary() {
+	logging.Printf("  │ ─── oc-mirror Cache ──────────────────────────────────────────\n")
+	logging.Printf("  │   Size: %s -> %s (%+d files, %+d blobs)\n",
+		FormatBytesHuman(m.Before.SizeBytes), FormatBytesHuman(m.After.SizeBytes), m.NewFiles, m.NewBlobs)
+	if m.HitRatio != nil {
+		logging.Printf("  │   Hit ratio: %.1f%% (%s not downloaded vs clean run)\n", *m.HitRatio*100, FormatBytesHuman(m.BytesNotDownloaded))
+	}
+}
@@ -10,6 +10,8 @@ import (
 
 // DiskWriteMonitor monitors data being written to a directory
 type DiskWriteMonitor struct {
+	adaptivePoller
+
 	targetDir    string
 	startTime    time.Time
 	stopTime     time.Time
@@ -17,6 +19,7 @@ type DiskWriteMonitor struct {
 	samples      []DiskWriteSample
 	mu           sync.RWMutex
 	pollInterval time.Duration
+	onSample     SampleHandler
 }
 
 // DiskWriteSample represents a single disk write measurement
@@ -42,7 +45,7 @@ func NewDiskWriteMonitor(targetDir string) *DiskWriteMonitor {
 	return &DiskWriteMonitor{
 		targetDir:    targetDir,
 		samples:      make([]DiskWriteSample, 0),
-		pollInterval: 1 * time.Second,
+		pollInterval: DefaultPollInterval,
 	}
 }
 
@@ -51,6 +54,11 @@ func (dm *DiskWriteMonitor) SetPollInterval(interval time.Duration) {
 	dm.pollInterval = interval
 }
 
+// SetSampleHandler sets a handler called with each sample
+func (dm *DiskWriteMonitor) SetSampleHandler(handler SampleHandler) {
+	dm.onSample = handler
+}
+
 // Start begins monitoring the directory
 func (dm *DiskWriteMonitor) Start() error {
 	dm.mu.Lock()
@@ -64,6 +72,8 @@ func (dm *DiskWriteMonitor) Start() error {
 	dm.monitoring = true
 	dm.samples = make([]DiskWriteSample, 0)
 
+	dm.startPoller(dm.pollInterval)
+
 	// Start background monitoring goroutine
 	go dm.monitorLoop()
 
@@ -74,6 +84,7 @@ func (dm *DiskWriteMonitor) Start() error {
 func (dm *DiskWriteMonitor) Stop() DiskWriteMetrics {
 	dm.mu.Lock()
 	dm.monitoring = false
+	dm.flushPoller()
 	dm.stopTime = time.Now()
 	dm.mu.Unlock()
 
@@ -118,7 +129,7 @@ func (dm *DiskWriteMonitor) GetCurrentStats() DiskWriteSample {
 }
 
 func (dm *DiskWriteMonitor) monitorLoop() {
-	ticker := time.NewTicker(dm.pollInterval)
+	ticker := dm.poller
 	defer ticker.Stop()
 
 	var lastBytes int64
@@ -150,6 +161,9 @@ func (dm *DiskWriteMonitor) monitorLoop() {
 			dm.mu.Lock()
 			dm.samples = append(dm.samples, sample)
 			dm.mu.Unlock()
+			if dm.onSample != nil {
+				dm.onSample(sample)
+			}
 
 			lastBytes = sample.TotalBytes
 			lastSampleTime = sample.Timestamp
@@ -0,0 +1,368 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diskSectorSize is the unit of the sector counters in /proc/diskstats
+const diskSectorSize = 512
+
+// DiskIOMonitor samples /proc/diskstats for the block devices backing a set of
+// directories. Unlike DiskWriteMonitor it sees reads and actual device pressure,
+// including writes that have not yet landed in the monitored directories.
+type DiskIOMonitor struct {
+	adaptivePoller
+
+	paths        []string
+	devices      map[string][]string // device name -> monitored paths on it
+	startTime    time.Time
+	stopTime     time.Time
+	monitoring   bool
+	baseline     map[string]diskCounters
+	last         map[string]diskCounters
+	samples      []DiskIOSample
+	mu           sync.RWMutex
+	pollInterval time.Duration
+	statsPath    string
+	onSample     SampleHandler
+}
+
+// DiskIOSample represents a single per-device I/O measurement
+type DiskIOSample struct {
+	Timestamp   time.Time `json:"Timestamp"`
+	Device      string    `json:"Device"`
+	ReadIOPS    float64   `json:"ReadIOPS"`
+	WriteIOPS   float64   `json:"WriteIOPS"`
+	ReadMBs     float64   `json:"ReadMBs"`
+	WriteMBs    float64   `json:"WriteMBs"`
+	UtilPercent float64   `json:"UtilPercent"` // Share of wall time the device had I/O in flight
+}
+
+// DiskIODeviceMetrics represents aggregated I/O metrics for one device
+type DiskIODeviceMetrics struct {
+	Device          string   `json:"Device"`
+	Paths           []string `json:"Paths"`
+	ReadOps         int64    `json:"ReadOps"`
+	WriteOps        int64    `json:"WriteOps"`
+	BytesRead       int64    `json:"BytesRead"`
+	BytesWritten    int64    `json:"BytesWritten"`
+	AvgReadIOPS     float64  `json:"AvgReadIOPS"`
+	AvgWriteIOPS    float64  `json:"AvgWriteIOPS"`
+	PeakReadIOPS    float64  `json:"PeakReadIOPS"`
+	PeakWriteIOPS   float64  `json:"PeakWriteIOPS"`
+	AvgReadMBs      float64  `json:"AvgReadMBs"`
+	AvgWriteMBs     float64  `json:"AvgWriteMBs"`
+	PeakReadMBs     float64  `json:"PeakReadMBs"`
+	PeakWriteMBs    float64  `json:"PeakWriteMBs"`
+	AvgUtilPercent  float64  `json:"AvgUtilPercent"`
+	PeakUtilPercent float64  `json:"PeakUtilPercent"`
+}
+
+// DiskIOMetrics represents aggregated disk I/O metrics for a phase
+type DiskIOMetrics struct {
+	Duration        time.Duration         `json:"Duration"`
+	Devices         []DiskIODeviceMetrics `json:"Devices"`
+	UnresolvedPaths []string              `json:"UnresolvedPaths,omitempty"` // Paths not on a block device (tmpfs, overlay, NFS)
+	Samples         []DiskIOSample        `json:"Samples"`
+}
+
+// diskCounters holds the cumulative counters of one /proc/diskstats line
+type diskCounters struct {
+	readOps       int64
+	readSectors   int64
+	writeOps      int64
+	writeSectors  int64
+	ioTicksMillis int64
+	timestamp     time.Time
+}
+
+// NewDiskIOMonitor creates a disk I/O monitor for the devices backing the given paths
+func NewDiskIOMonitor(paths ...string) *DiskIOMonitor {
+	return &DiskIOMonitor{
+		paths:        paths,
+		devices:      make(map[string][]string),
+		samples:      make([]DiskIOSample, 0),
+		pollInterval: DefaultPollInterval,
+		statsPath:    "/proc/diskstats",
+	}
+}
+
+// SetPollInterval sets the polling interval for monitoring
+func (dm *DiskIOMonitor) SetPollInterval(interval time.Duration) {
+	dm.pollInterval = interval
+}
+
+// SetSampleHandler sets a handler called with each sample
+func (dm *DiskIOMonitor) SetSampleHandler(handler SampleHandler) {
+	dm.onSample = handler
+}
+
+// GetPollInterval implements PollingMonitor interface
+func (dm *DiskIOMonitor) GetPollInterval() time.Duration {
+	return dm.pollInterval
+}
+
+// Devices returns the block devices being monitored
+func (dm *DiskIOMonitor) Devices() []string {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return sortedDeviceNames(dm.devices)
+}
+
+// Start resolves the backing devices and begins sampling
+func (dm *DiskIOMonitor) Start() error {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	if dm.monitoring {
+		return nil
+	}
+
+	dm.devices = make(map[string][]string)
+	for _, path := range dm.paths {
+		device, err := blockDeviceForPath(path)
+		if err != nil {
+			continue
+		}
+		dm.devices[device] = append(dm.devices[device], path)
+	}
+
+	counters, err := readDiskStats(dm.statsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read disk stats: %w", err)
+	}
+
+	dm.startTime = time.Now()
+	dm.baseline = counters
+	dm.last = counters
+	dm.samples = make([]DiskIOSample, 0)
+	dm.monitoring = true
+
+	dm.startPoller(dm.pollInterval)
+	go dm.monitorLoop()
+
+	return nil
+}
+
+// Stop stops monitoring and returns the collected metrics
+func (dm *DiskIOMonitor) Stop() DiskIOMetrics {
+	dm.mu.Lock()
+	wasMonitoring := dm.monitoring
+	dm.monitoring = false
+	dm.flushPoller()
+	dm.stopTime = time.Now()
+	dm.mu.Unlock()
+
+	if !wasMonitoring {
+		return dm.calculateMetrics(nil)
+	}
+
+	// Use context timeout instead of blocking sleep
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	<-ctx.Done()
+	cancel()
+
+	final, err := readDiskStats(dm.statsPath)
+	if err != nil {
+		final = nil
+	}
+	return dm.calculateMetrics(final)
+}
+
+// StopInterface implements Monitor interface
+func (dm *DiskIOMonitor) StopInterface() interface{} {
+	return dm.Stop()
+}
+
+// IsMonitoring implements Monitor interface
+func (dm *DiskIOMonitor) IsMonitoring() bool {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.monitoring
+}
+
+// GetDuration implements Monitor interface
+func (dm *DiskIOMonitor) GetDuration() time.Duration {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	if !dm.monitoring {
+		return dm.stopTime.Sub(dm.startTime)
+	}
+	return time.Since(dm.startTime)
+}
+
+func (dm *DiskIOMonitor) monitorLoop() {
+	ticker := dm.poller
+	defer ticker.Stop()
+
+	for {
+		dm.mu.RLock()
+		monitoring := dm.monitoring
+		dm.mu.RUnlock()
+
+		if !monitoring {
+			break
+		}
+
+		select {
+		case <-ticker.C:
+			counters, err := readDiskStats(dm.statsPath)
+			if err != nil {
+				continue
+			}
+
+			dm.mu.Lock()
+			var taken []DiskIOSample
+			for _, device := range sortedDeviceNames(dm.devices) {
+				current, ok := counters[device]
+				previous, hasPrevious := dm.last[device]
+				if !ok || !hasPrevious {
+					continue
+				}
+				taken = append(taken, diskIORates(device, previous, current))
+			}
+			dm.samples = append(dm.samples, taken...)
+			dm.last = counters
+			dm.mu.Unlock()
+			if dm.onSample != nil {
+				for _, sample := range taken {
+					dm.onSample(sample)
+				}
+			}
+		}
+	}
+}
+
+// diskIORates converts the counter deltas between two readings into rates
+func diskIORates(device string, previous, current diskCounters) DiskIOSample {
+	sample := DiskIOSample{
+		Timestamp: current.timestamp,
+		Device:    device,
+	}
+
+	elapsed := current.timestamp.Sub(previous.timestamp).Seconds()
+	if elapsed <= 0 {
+		return sample
+	}
+
+	sample.ReadIOPS = float64(current.readOps-previous.readOps) / elapsed
+	sample.WriteIOPS = float64(current.writeOps-previous.writeOps) / elapsed
+	sample.ReadMBs = float64((current.readSectors-previous.readSectors)*diskSectorSize) / elapsed / (1024 * 1024)
+	sample.WriteMBs = float64((current.writeSectors-previous.writeSectors)*diskSectorSize) / elapsed / (1024 * 1024)
+	sample.UtilPercent = min(float64(current.ioTicksMillis-previous.ioTicksMillis)/(elapsed*1000)*100, 100)
+	return sample
+}
+
+func (dm *DiskIOMonitor) calculateMetrics(final map[string]diskCounters) DiskIOMetrics {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	metrics := DiskIOMetrics{
+		Duration: dm.stopTime.Sub(dm.startTime),
+		Samples:  make([]DiskIOSample, len(dm.samples)),
+	}
+	copy(metrics.Samples, dm.samples)
+
+	resolved := make(map[string]bool)
+	for _, paths := range dm.devices {
+		for _, path := range paths {
+			resolved[path] = true
+		}
+	}
+	for _, path := range dm.paths {
+		if !resolved[path] {
+			metrics.UnresolvedPaths = append(metrics.UnresolvedPaths, path)
+		}
+	}
+
+	for _, device := range sortedDeviceNames(dm.devices) {
+		deviceMetrics := DiskIODeviceMetrics{
+			Device: device,
+			Paths:  dm.devices[device],
+		}
+
+		// Totals come from the counters at start and stop so short bursts between samples are not lost
+		start, hasStart := dm.baseline[device]
+		end, hasEnd := final[device]
+		if !hasEnd {
+			end, hasEnd = dm.last[device]
+		}
+		if hasStart && hasEnd {
+			total := diskIORates(device, start, end)
+			deviceMetrics.ReadOps = end.readOps - start.readOps
+			deviceMetrics.WriteOps = end.writeOps - start.writeOps
+			deviceMetrics.BytesRead = (end.readSectors - start.readSectors) * diskSectorSize
+			deviceMetrics.BytesWritten = (end.writeSectors - start.writeSectors) * diskSectorSize
+			deviceMetrics.AvgReadIOPS = total.ReadIOPS
+			deviceMetrics.AvgWriteIOPS = total.WriteIOPS
+			deviceMetrics.AvgReadMBs = total.ReadMBs
+			deviceMetrics.AvgWriteMBs = total.WriteMBs
+			deviceMetrics.AvgUtilPercent = total.UtilPercent
+		}
+
+		for _, sample := range dm.samples {
+			if sample.Device != device {
+				continue
+			}
+			deviceMetrics.PeakReadIOPS = max(deviceMetrics.PeakReadIOPS, sample.ReadIOPS)
+			deviceMetrics.PeakWriteIOPS = max(deviceMetrics.PeakWriteIOPS, sample.WriteIOPS)
+			deviceMetrics.PeakReadMBs = max(deviceMetrics.PeakReadMBs, sample.ReadMBs)
+			deviceMetrics.PeakWriteMBs = max(deviceMetrics.PeakWriteMBs, sample.WriteMBs)
+			deviceMetrics.PeakUtilPercent = max(deviceMetrics.PeakUtilPercent, sample.UtilPercent)
+		}
+
+		metrics.Devices = append(metrics.Devices, deviceMetrics)
+	}
+
+	return metrics
+}
+
+// readDiskStats parses /proc/diskstats into counters keyed by device name
+func readDiskStats(path string) (map[string]diskCounters, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	now := time.Now()
+	counters := make(map[string]diskCounters)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 259 0 nvme0n1 reads merged sectors ms writes merged sectors ms in-flight io_ms weighted_ms ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 13 {
+			continue
+		}
+		parse := func(i int) int64 {
+			value, _ := strconv.ParseInt(fields[i], 10, 64)
+			return value
+		}
+		counters[fields[2]] = diskCounters{
+			readOps:       parse(3),
+			readSectors:   parse(5),
+			writeOps:      parse(7),
+			writeSectors:  parse(9),
+			ioTicksMillis: parse(12),
+			timestamp:     now,
+		}
+	}
+	return counters, scanner.Err()
+}
+
+func sortedDeviceNames(devices map[string][]string) []string {
+	names := make([]string, 0, len(devices))
+	for name := range devices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,41 @@
+//go:build linux
+
+package monitor
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+)
+
+// blockDeviceForPath returns the /proc/diskstats name of the block device holding path.
+// Paths that do not exist yet are resolved through their deepest existing parent.
+func blockDeviceForPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	var stat syscall.Stat_t
+	for candidate := abs; ; candidate = filepath.Dir(candidate) {
+		if err = syscall.Stat(candidate, &stat); err == nil {
+			break
+		}
+		if candidate == filepath.Dir(candidate) {
+			return "", fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+	}
+
+	dev := uint64(stat.Dev)
+	major := (dev>>8)&0xfff | (dev>>32)&^uint64(0xfff)
+	minor := dev&0xff | (dev>>12)&^uint64(0xff)
+	if major == 0 {
+		return "", fmt.Errorf("%s is not on a block device", path)
+	}
+
+	devPath, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", major, minor))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve block device %d:%d: %w", major, minor, err)
+	}
+	return filepath.Base(devPath), nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package monitor
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// blockDeviceForPath has no /proc/diskstats to map path to on this OS
+func blockDeviceForPath(path string) (string, error) {
+	return "", fmt.Errorf("block devices cannot be resolved on %s", runtime.GOOS)
+}
@@ -3,14 +3,19 @@ package monitor
 import (
 	"context"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
 )
 
 // DownloadMonitor monitors the download progress by tracking data written to the mirror directory
 type DownloadMonitor struct {
+	adaptivePoller
+
 	targetDir      string
 	startTime      time.Time
 	stopTime       time.Time
@@ -21,6 +26,8 @@ type DownloadMonitor struct {
 	initialBytes   int64
 	progressChan   chan DownloadProgress
 	showProgress   bool
+	onSample       SampleHandler
+	expectedBytes  int64 // Size of the target directory once the download completes; 0 when unknown
 }
 
 // DownloadSample represents a single download measurement
@@ -30,6 +37,8 @@ type DownloadSample struct {
 	BytesDelta     int64     `json:"BytesDelta"`     // Bytes downloaded since last sample
 	DownloadRateMB float64   `json:"DownloadRateMB"` // Download rate in MB/s
 	FileCount      int       `json:"FileCount"`
+	ExpectedBytes  int64     `json:"ExpectedBytes,omitempty"` // Estimated size of the complete download
+	ETASeconds     float64   `json:"ETASeconds,omitempty"`    // Estimated time remaining at the average rate
 }
 
 // DownloadProgress represents real-time progress for display
@@ -39,6 +48,9 @@ type DownloadProgress struct {
 	CurrentRateMBs float64        `json:"CurrentRateMBs"`
 	AverageRateMBs float64       `json:"AverageRateMBs"`
 	FileCount      int           `json:"FileCount"`
+	ExpectedBytes  int64         `json:"ExpectedBytes"`   // 0 when no estimate is set
+	PercentDone    float64       `json:"PercentDone"`     // Of ExpectedBytes
+	ETA            time.Duration `json:"ETA"`             // Estimated time remaining; 0 when unknown
 }
 
 // DownloadMetrics represents the final download metrics
@@ -52,6 +64,7 @@ type DownloadMetrics struct {
 	Samples              []DownloadSample  `json:"Samples"`
 	StartTime            time.Time         `json:"StartTime"`
 	EndTime              time.Time         `json:"EndTime"`
+	ExpectedBytes        int64             `json:"ExpectedBytes,omitempty"` // Size the ETA was estimated against
 }
 
 // NewDownloadMonitor creates a new download monitor for the specified directory
@@ -59,7 +72,7 @@ func NewDownloadMonitor(targetDir string) *DownloadMonitor {
 	return &DownloadMonitor{
 		targetDir:    targetDir,
 		samples:      make([]DownloadSample, 0),
-		pollInterval: 1 * time.Second,
+		pollInterval: DefaultPollInterval,
 		showProgress: true,
 	}
 }
@@ -69,6 +82,18 @@ func (dm *DownloadMonitor) SetPollInterval(interval time.Duration) {
 	dm.pollInterval = interval
 }
 
+// SetSampleHandler sets a handler called with each sample
+func (dm *DownloadMonitor) SetSampleHandler(handler SampleHandler) {
+	dm.onSample = handler
+}
+
+// SetExpectedBytes sets the size the target directory reaches when the
+// download completes, from which samples and progress updates estimate the
+// time remaining. 0 disables the estimate.
+func (dm *DownloadMonitor) SetExpectedBytes(bytes int64) {
+	dm.expectedBytes = bytes
+}
+
 // SetShowProgress enables or disables real-time progress display
 func (dm *DownloadMonitor) SetShowProgress(show bool) {
 	dm.showProgress = show
@@ -104,6 +129,8 @@ func (dm *DownloadMonitor) Start() error {
 		dm.progressChan = make(chan DownloadProgress, 100)
 	}
 
+	dm.startPoller(dm.pollInterval)
+
 	// Start background monitoring goroutine
 	go dm.monitorLoop()
 
@@ -114,6 +141,7 @@ func (dm *DownloadMonitor) Start() error {
 func (dm *DownloadMonitor) Stop() DownloadMetrics {
 	dm.mu.Lock()
 	dm.monitoring = false
+	dm.flushPoller()
 	dm.stopTime = time.Now()
 	if dm.progressChan != nil {
 		close(dm.progressChan)
@@ -157,7 +185,7 @@ func (dm *DownloadMonitor) GetPollInterval() time.Duration {
 }
 
 func (dm *DownloadMonitor) monitorLoop() {
-	ticker := time.NewTicker(dm.pollInterval)
+	ticker := dm.poller
 	defer ticker.Stop()
 
 	var lastBytes int64 = dm.initialBytes
@@ -191,33 +219,41 @@ func (dm *DownloadMonitor) monitorLoop() {
 				BytesDelta:     bytesDelta,
 				DownloadRateMB: downloadRate,
 				FileCount:      fileCount,
+				ExpectedBytes:  dm.expectedBytes,
 			}
+			percentDone, eta := dm.estimateRemaining(currentBytes, currentTime)
+			sample.ETASeconds = eta.Seconds()
 
 			dm.mu.Lock()
 			dm.samples = append(dm.samples, sample)
 			dm.mu.Unlock()
+			if dm.onSample != nil {
+				dm.onSample(sample)
+			}
 
 			// Send progress update
 			if dm.showProgress {
+				avgRate := dm.calculateCurrentAverageRate()
+				progress := DownloadProgress{
+					ElapsedTime:    currentTime.Sub(dm.startTime),
+					TotalBytes:     currentBytes - dm.initialBytes,
+					CurrentRateMBs: downloadRate,
+					AverageRateMBs: avgRate,
+					FileCount:      fileCount,
+					ExpectedBytes:  dm.expectedBytes,
+					PercentDone:    percentDone,
+					ETA:            eta,
+				}
+				// Sent under the lock, so Stop cannot close the channel meanwhile
 				dm.mu.RLock()
-				progressChan := dm.progressChan
-				dm.mu.RUnlock()
-
-				if progressChan != nil {
-					avgRate := dm.calculateCurrentAverageRate()
-					progress := DownloadProgress{
-						ElapsedTime:    currentTime.Sub(dm.startTime),
-						TotalBytes:     currentBytes - dm.initialBytes,
-						CurrentRateMBs: downloadRate,
-						AverageRateMBs: avgRate,
-						FileCount:      fileCount,
-					}
+				if dm.progressChan != nil {
 					select {
-					case progressChan <- progress:
+					case dm.progressChan <- progress:
 					default:
 						// Channel full, skip this update
 					}
 				}
+				dm.mu.RUnlock()
 			}
 
 			lastBytes = currentBytes
@@ -268,15 +304,35 @@ func (dm *DownloadMonitor) calculateCurrentAverageRate() float64 {
 	return float64(lastSample.TotalBytes) / elapsed / (1024 * 1024)
 }
 
+// estimateRemaining returns how much of the expected size the target
+// directory holds and the time the rest takes at the average rate since the
+// start. The ETA is 0 without an estimate, before any data arrived, or once
+// the expected size is reached.
+func (dm *DownloadMonitor) estimateRemaining(currentBytes int64, now time.Time) (float64, time.Duration) {
+	if dm.expectedBytes <= 0 {
+		return 0, 0
+	}
+	percentDone := math.Min(float64(currentBytes)/float64(dm.expectedBytes)*100, 100)
+	remaining := dm.expectedBytes - currentBytes
+	downloaded := currentBytes - dm.initialBytes
+	elapsed := now.Sub(dm.startTime).Seconds()
+	if remaining <= 0 || downloaded <= 0 || elapsed <= 0 {
+		return percentDone, 0
+	}
+	rate := float64(downloaded) / elapsed // bytes/s
+	return percentDone, time.Duration(float64(remaining) / rate * float64(time.Second)).Round(time.Second)
+}
+
 func (dm *DownloadMonitor) calculateMetrics() DownloadMetrics {
 	dm.mu.RLock()
 	defer dm.mu.RUnlock()
 
 	metrics := DownloadMetrics{
-		Duration:  dm.stopTime.Sub(dm.startTime),
-		Samples:   make([]DownloadSample, len(dm.samples)),
-		StartTime: dm.startTime,
-		EndTime:   dm.stopTime,
+		Duration:      dm.stopTime.Sub(dm.startTime),
+		Samples:       make([]DownloadSample, len(dm.samples)),
+		StartTime:     dm.startTime,
+		EndTime:       dm.stopTime,
+		ExpectedBytes: dm.expectedBytes,
 	}
 
 	copy(metrics.Samples, dm.samples)
@@ -334,15 +390,19 @@ func (dm *DownloadMonitor) calculateMetrics() DownloadMetrics {
 
 // PrintSummary prints a formatted summary of the download metrics
 func (m *DownloadMetrics) PrintSummary() {
-	fmt.Printf("  │ ═══════════════════════════════════════════════════════════\n")
-	fmt.Printf("  │ Download Summary:\n")
-	fmt.Printf("  │   Total Downloaded: %s (%d bytes)\n", FormatBytesHuman(m.TotalBytesDownloaded), m.TotalBytesDownloaded)
-	fmt.Printf("  │   Total Files: %d\n", m.TotalFiles)
-	fmt.Printf("  │   Duration: %v\n", m.Duration.Round(time.Second))
-	fmt.Printf("  │   Average Speed: %.2f MB/s\n", m.AverageSpeedMBs)
-	fmt.Printf("  │   Peak Speed: %.2f MB/s\n", m.PeakSpeedMBs)
-	fmt.Printf("  │   Min Speed: %.2f MB/s\n", m.MinSpeedMBs)
-	fmt.Printf("  │ ═══════════════════════════════════════════════════════════\n")
+	logging.Printf("  │ ═══════════════════════════════════════════════════════════\n")
+	logging.Printf("  │ Download Summary:\n")
+	logging.Printf("  │   Total Downloaded: %s (%d bytes)\n", FormatBytesHuman(m.TotalBytesDownloaded), m.TotalBytesDownloaded)
+	logging.Printf("  │   Total Files: %d\n", m.TotalFiles)
+	logging.Printf("  │   Duration: %v\n", m.Duration.Round(time.Second))
+	logging.Printf("  │   Average Speed: %.2f MB/s\n", m.AverageSpeedMBs)
+	logging.Printf("  │   Peak Speed: %.2f MB/s\n", m.PeakSpeedMBs)
+	logging.Printf("  │   Min Speed: %.2f MB/s\n", m.MinSpeedMBs)
+	if m.ExpectedBytes > 0 {
+		logging.Printf("  │   Expected Size: %s (downloaded %.0f%% of it)\n", FormatBytesHuman(m.ExpectedBytes),
+			float64(m.TotalBytesDownloaded)/float64(m.ExpectedBytes)*100)
+	}
+	logging.Printf("  │ ═══════════════════════════════════════════════════════════\n")
 }
 
 // FormatBytesHuman formats bytes to a human-readable string with proper units
@@ -35,6 +35,11 @@ func (f *MonitorFactory) CreateDiskWriteMonitor(targetDir string) *DiskWriteMoni
 	return NewDiskWriteMonitor(targetDir)
 }
 
+// CreateDiskIOMonitor creates a new DiskIOMonitor for the devices backing the given paths
+func (f *MonitorFactory) CreateDiskIOMonitor(paths ...string) *DiskIOMonitor {
+	return NewDiskIOMonitor(paths...)
+}
+
 // CreateOutputVerifier creates a new OutputVerifier
 func (f *MonitorFactory) CreateOutputVerifier(directory string) *OutputVerifier {
 	return NewOutputVerifier(directory)
@@ -0,0 +1,48 @@
+package monitor
+
+import "net"
+
+// hostStats reads the process and network counters the resource, network,
+// registry and memory ceiling monitors sample. Each OS has its own
+// implementation: Linux reads /proc and /sys, darwin asks the kernel through
+// proc_pidinfo, sysctl and the routing socket, and other systems report
+// errGatherUnsupported, so the monitors still run but record no usage.
+type hostStats interface {
+	// processUsage reads the CPU time, memory and thread count of one process.
+	// With children, the CPU time of reaped children is included.
+	processUsage(pid int, children bool) (resourceUsage, error)
+	// processTree returns pid and all of its descendants
+	processTree(pid int) []int
+	// totalMemory returns the physical memory of the host in bytes, 0 if unknown
+	totalMemory() int64
+	// interfaceCounters returns the bytes received and sent by a network interface
+	interfaceCounters(name string) (rx, tx int64, err error)
+	// defaultInterface returns the network interface carrying the default route
+	defaultInterface() string
+	// tcpConnections lists the IPv4 and IPv6 TCP sockets of the host
+	tcpConnections() ([]tcpConnection, error)
+}
+
+// tcpConnection is one TCP socket of the host
+type tcpConnection struct {
+	remote      net.IP
+	remotePort  int
+	established bool
+}
+
+// host is the hostStats of the OS the tool was built for
+var host hostStats = newHostStats()
+
+// HostLimitations describes the monitors that cannot measure on this OS; none on Linux
+func HostLimitations() []string {
+	return hostLimitations
+}
+
+// childTree walks a parent map from pid down, returning pid and its descendants
+func childTree(pid int, children map[int][]int) []int {
+	tree := []int{pid}
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i]]...)
+	}
+	return tree
+}
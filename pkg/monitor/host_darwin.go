@@ -0,0 +1,233 @@
+//go:build darwin
+
+package monitor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/net/route"
+	"golang.org/x/sys/unix"
+)
+
+// hostLimitations lists the Linux-only monitors; cgroup scope and per-process
+// network accounting report their own fallback when requested
+var hostLimitations = []string{
+	"disk I/O, page cache and memory pressure monitoring need Linux and are skipped on darwin",
+}
+
+// proc_info call numbers, flavors and buffer sizes (sys/proc_info.h)
+const (
+	procInfoCallPIDInfo   = 2  // PROC_INFO_CALL_PIDINFO
+	procInfoCallPIDRusage = 9  // PROC_INFO_CALL_PIDRUSAGE
+	procPIDTaskInfo       = 4  // PROC_PIDTASKINFO, struct proc_taskinfo
+	rusageInfoV2          = 2  // RUSAGE_INFO_V2, struct rusage_info_v2
+	procTaskInfoSize      = 96 // sizeof(struct proc_taskinfo)
+	rusageInfoV2Size      = 160
+)
+
+// pcblist_n record kinds and TCP states (netinet/in_pcb.h, netinet/tcp_fsm.h)
+const (
+	xsoInpcb         = 0x10
+	xsoTcpcb         = 0x20
+	inpIPv4          = 0x1
+	tcpsEstablished  = 4
+	xinpgenSize      = 24 // sizeof(struct xinpgen)
+	xinpcbForeignOff = 52 // inp_dependfaddr in struct xinpcb_n
+	xtcpcbStateOff   = 36 // t_state in struct xtcpcb_n
+)
+
+// darwinHost asks the kernel directly: proc_pidinfo for process usage,
+// sysctl for the process table, physical memory and TCP sockets, and the
+// routing socket for interface counters and the default route. Nothing is
+// run per poll, and no cgo or extra tools are needed.
+type darwinHost struct {
+	defaultOnce sync.Once
+	defaultName string
+}
+
+func newHostStats() hostStats {
+	return &darwinHost{}
+}
+
+// machTicksToSeconds converts the Mach absolute time units of proc_pidinfo
+// CPU times. The timebase is 1ns on Intel and 125/3ns, a 24 MHz counter, on
+// Apple silicon.
+func machTicksToSeconds(ticks uint64) float64 {
+	if runtime.GOARCH == "arm64" {
+		return float64(ticks) * 125 / 3 / 1e9
+	}
+	return float64(ticks) / 1e9
+}
+
+// procInfo calls proc_info, the system call behind proc_pidinfo and
+// proc_pid_rusage, filling buf
+func procInfo(call, pid, flavor int, buf []byte) error {
+	n, _, errno := unix.Syscall6(unix.SYS_PROC_INFO, uintptr(call), uintptr(pid), uintptr(flavor), 0,
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	if errno != 0 {
+		return errno
+	}
+	if call == procInfoCallPIDInfo && int(n) < len(buf) {
+		return fmt.Errorf("short proc_info reply of %d bytes", n)
+	}
+	return nil
+}
+
+// processUsage reads the CPU time, RSS, VSZ and thread count of pid from
+// PROC_PIDTASKINFO; with children, the CPU time of reaped children comes from
+// its rusage_info_v2
+func (*darwinHost) processUsage(pid int, children bool) (resourceUsage, error) {
+	usage := resourceUsage{}
+	info := make([]byte, procTaskInfoSize)
+	if err := procInfo(procInfoCallPIDInfo, pid, procPIDTaskInfo, info); err != nil {
+		return usage, fmt.Errorf("failed to read process %d: %w", pid, err)
+	}
+	usage.vms = int64(binary.LittleEndian.Uint64(info[0:8]))
+	usage.rss = int64(binary.LittleEndian.Uint64(info[8:16]))
+	usage.cpuSeconds = machTicksToSeconds(binary.LittleEndian.Uint64(info[16:24]) + binary.LittleEndian.Uint64(info[24:32]))
+	usage.threads = int(int32(binary.LittleEndian.Uint32(info[84:88])))
+	usage.processes = 1
+
+	if children {
+		rusage := make([]byte, rusageInfoV2Size)
+		if err := procInfo(procInfoCallPIDRusage, pid, rusageInfoV2, rusage); err == nil {
+			// ri_child_user_time and ri_child_system_time
+			usage.cpuSeconds += machTicksToSeconds(binary.LittleEndian.Uint64(rusage[96:104]) + binary.LittleEndian.Uint64(rusage[104:112]))
+		}
+	}
+	return usage, nil
+}
+
+// processTree links the processes of kern.proc.all through their parent PID
+func (*darwinHost) processTree(pid int) []int {
+	procs, err := unix.SysctlKinfoProcSlice("kern.proc.all")
+	if err != nil {
+		return []int{pid}
+	}
+	children := make(map[int][]int)
+	for _, p := range procs {
+		children[int(p.Eproc.Ppid)] = append(children[int(p.Eproc.Ppid)], int(p.Proc.P_pid))
+	}
+	return childTree(pid, children)
+}
+
+// totalMemory reads hw.memsize
+func (*darwinHost) totalMemory() int64 {
+	total, err := unix.SysctlUint64("hw.memsize")
+	if err != nil {
+		return 0
+	}
+	return int64(total)
+}
+
+// interfaceCounters reads the 64-bit byte counters of the if_data64 in the
+// RTM_IFINFO2 message of the interface, from an NET_RT_IFLIST2 dump
+func (*darwinHost) interfaceCounters(name string) (int64, int64, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read interface %s: %w", name, err)
+	}
+	rib, err := route.FetchRIB(syscall.AF_UNSPEC, route.RIBType(unix.NET_RT_IFLIST2), iface.Index)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read interface %s: %w", name, err)
+	}
+	// if_msghdr2: the header up to ifm_data is 32 bytes; ifi_ibytes and
+	// ifi_obytes are at 64 and 72 of if_data64
+	for len(rib) >= 4 {
+		length := int(binary.LittleEndian.Uint16(rib[0:2]))
+		if length < 4 || length > len(rib) {
+			break
+		}
+		if rib[3] == unix.RTM_IFINFO2 && length >= 112 && int(binary.LittleEndian.Uint16(rib[12:14])) == iface.Index {
+			rx := binary.LittleEndian.Uint64(rib[96:104])
+			tx := binary.LittleEndian.Uint64(rib[104:112])
+			return int64(rx), int64(tx), nil
+		}
+		rib = rib[length:]
+	}
+	return 0, 0, fmt.Errorf("interface %s not found in the routing table", name)
+}
+
+// defaultInterface returns the interface of the unscoped IPv4 default route,
+// looked up once
+func (h *darwinHost) defaultInterface() string {
+	h.defaultOnce.Do(func() {
+		h.defaultName = "en0"
+		rib, err := route.FetchRIB(syscall.AF_INET, route.RIBTypeRoute, 0)
+		if err != nil {
+			return
+		}
+		messages, err := route.ParseRIB(route.RIBTypeRoute, rib)
+		if err != nil {
+			return
+		}
+		for _, m := range messages {
+			r, ok := m.(*route.RouteMessage)
+			if !ok || r.Flags&unix.RTF_GATEWAY == 0 || r.Flags&unix.RTF_IFSCOPE != 0 || len(r.Addrs) <= unix.RTAX_DST {
+				continue
+			}
+			if dst, ok := r.Addrs[unix.RTAX_DST].(*route.Inet4Addr); !ok || dst.IP != [4]byte{} {
+				continue
+			}
+			if iface, err := net.InterfaceByIndex(r.Index); err == nil {
+				h.defaultName = iface.Name
+				return
+			}
+		}
+	})
+	return h.defaultName
+}
+
+// tcpConnections walks the xinpcb_n and xtcpcb_n records of
+// net.inet.tcp.pcblist_n, which netstat reads, for the peer and state of
+// every TCP socket
+func (*darwinHost) tcpConnections() ([]tcpConnection, error) {
+	data, err := unix.SysctlRaw("net.inet.tcp.pcblist_n")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list TCP connections: %w", err)
+	}
+	if len(data) < xinpgenSize {
+		return nil, nil
+	}
+
+	var connections []tcpConnection
+	var current *tcpConnection
+	// Records are padded to 8 bytes; a closing xinpgen ends the list
+	for rest := data[(binary.LittleEndian.Uint32(data[0:4])+7)&^7:]; len(rest) > xinpgenSize; {
+		length := int(binary.LittleEndian.Uint32(rest[0:4]))
+		if length < 8 || length > len(rest) {
+			break
+		}
+		switch binary.LittleEndian.Uint32(rest[4:8]) {
+		case xsoInpcb:
+			current = nil
+			if length < xinpcbForeignOff+16 {
+				break
+			}
+			port := int(binary.BigEndian.Uint16(rest[16:18]))
+			if port == 0 {
+				break // Listening
+			}
+			foreign := rest[xinpcbForeignOff : xinpcbForeignOff+16]
+			ip := net.IP(append([]byte(nil), foreign...))
+			if rest[48]&inpIPv4 != 0 {
+				ip = net.IP(append([]byte(nil), foreign[12:16]...))
+			}
+			current = &tcpConnection{remote: ip, remotePort: port}
+		case xsoTcpcb:
+			if current != nil && length >= xtcpcbStateOff+4 {
+				current.established = int32(binary.LittleEndian.Uint32(rest[xtcpcbStateOff:])) == tcpsEstablished
+				connections = append(connections, *current)
+			}
+			current = nil
+		}
+		rest = rest[min((length+7)&^7, len(rest)):]
+	}
+	return connections, nil
+}
@@ -0,0 +1,259 @@
+//go:build linux
+
+package monitor
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// hostLimitations is empty: every monitor is implemented on Linux
+var hostLimitations []string
+
+// linuxHost reads /proc and /sys
+type linuxHost struct{}
+
+func newHostStats() hostStats {
+	return linuxHost{}
+}
+
+// processUsage reads /proc/<pid>/stat and /proc/<pid>/status
+func (linuxHost) processUsage(pid int, children bool) (resourceUsage, error) {
+	usage := resourceUsage{}
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return usage, err
+	}
+	// The command name may contain spaces; fields after the closing paren are fixed
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	if len(fields) < 15 {
+		return usage, fmt.Errorf("short /proc/%d/stat", pid)
+	}
+	// utime, stime, cutime and cstime are fields 14-17 of the stat line
+	for i, field := range fields[11:15] {
+		if i >= 2 && !children {
+			break
+		}
+		ticks, _ := strconv.ParseFloat(field, 64)
+		usage.cpuSeconds += ticks / 100.0 // Clock ticks at 100 Hz
+	}
+
+	file, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return usage, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, _ := strconv.ParseInt(fields[1], 10, 64)
+		switch fields[0] {
+		case "VmRSS:":
+			usage.rss = value * 1024 // Convert from KB to bytes
+		case "VmSize:":
+			usage.vms = value * 1024
+		case "Threads:":
+			usage.threads = int(value)
+		}
+	}
+	usage.processes = 1
+	return usage, nil
+}
+
+// processTree links the processes of /proc through the parent PID of their stat line
+func (linuxHost) processTree(pid int) []int {
+	children := make(map[int][]int)
+	entries, _ := os.ReadDir("/proc")
+	for _, entry := range entries {
+		child, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		stat := string(data)
+		idx := strings.LastIndex(stat, ")")
+		if idx < 0 {
+			continue
+		}
+		fields := strings.Fields(stat[idx+1:])
+		if len(fields) < 2 {
+			continue
+		}
+		parent, _ := strconv.Atoi(fields[1])
+		children[parent] = append(children[parent], child)
+	}
+	return childTree(pid, children)
+}
+
+// totalMemory reads MemTotal from /proc/meminfo
+func (linuxHost) totalMemory() int64 {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			total, _ := strconv.ParseInt(fields[1], 10, 64)
+			return total * 1024
+		}
+	}
+	return 0
+}
+
+// interfaceCounters reads /sys/class/net/<name>/statistics, falling back to /proc/net/dev
+func (linuxHost) interfaceCounters(name string) (int64, int64, error) {
+	rx, rxErr := readCounterFile(fmt.Sprintf("/sys/class/net/%s/statistics/rx_bytes", name))
+	tx, txErr := readCounterFile(fmt.Sprintf("/sys/class/net/%s/statistics/tx_bytes", name))
+	if rxErr == nil && txErr == nil {
+		return rx, tx, nil
+	}
+
+	data, err := os.ReadFile("/proc/net/dev")
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		device, counters, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(device) != name {
+			continue
+		}
+		// Format: interface: rx_bytes rx_packets ... tx_bytes tx_packets ...
+		parts := strings.Fields(counters)
+		if len(parts) < 9 {
+			break
+		}
+		rx, _ = strconv.ParseInt(parts[0], 10, 64)
+		tx, _ = strconv.ParseInt(parts[8], 10, 64)
+		return rx, tx, nil
+	}
+	return 0, 0, fmt.Errorf("interface %s not found in /proc/net/dev", name)
+}
+
+func readCounterFile(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// defaultInterface asks ip for the default route, then looks for a common
+// interface that is up, then takes the first entry of /proc/net/route
+func (linuxHost) defaultInterface() string {
+	if output, err := exec.Command("ip", "route", "show", "default").Output(); err == nil {
+		for _, line := range strings.Split(string(output), "\n") {
+			parts := strings.Fields(line)
+			for i, part := range parts {
+				if part == "dev" && i+1 < len(parts) {
+					return parts[i+1]
+				}
+			}
+		}
+	}
+
+	if output, err := exec.Command("ip", "link", "show").Output(); err == nil {
+		for _, line := range strings.Split(string(output), "\n") {
+			for _, iface := range []string{"eth0", "ens33", "enp0s3", "wlan0"} {
+				if strings.Contains(line, iface+":") && strings.Contains(line, "state UP") {
+					return iface
+				}
+			}
+		}
+	}
+
+	if data, err := os.ReadFile("/proc/net/route"); err == nil {
+		lines := strings.Split(string(data), "\n")
+		if len(lines) > 1 {
+			// First non-header line usually has default route interface
+			if parts := strings.Fields(lines[1]); len(parts) > 0 {
+				return parts[0]
+			}
+		}
+	}
+
+	return "eth0" // Ultimate fallback
+}
+
+// tcpEstablished is the ESTABLISHED state in the st column of /proc/net/tcp
+const tcpEstablished = "01"
+
+// tcpConnections parses /proc/net/tcp and /proc/net/tcp6, which need no
+// tools and are readable in minimal containers
+func (linuxHost) tcpConnections() ([]tcpConnection, error) {
+	var connections []tcpConnection
+	var firstErr error
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		// sl local_address rem_address st ..., e.g.
+		// 0: 0500000A:A112 0900000A:1388 01 ...
+		for _, line := range strings.Split(string(data), "\n")[1:] {
+			fields := strings.Fields(line)
+			if len(fields) < 4 {
+				continue
+			}
+			remote, port, err := parseProcAddress(fields[2])
+			if err != nil {
+				continue
+			}
+			connections = append(connections, tcpConnection{
+				remote:      remote,
+				remotePort:  port,
+				established: fields[3] == tcpEstablished,
+			})
+		}
+	}
+	if connections == nil && firstErr != nil {
+		return nil, firstErr
+	}
+	return connections, nil
+}
+
+// parseProcAddress parses a /proc/net/tcp address: the IP in hex as 32-bit
+// words in host byte order, a colon and the port in hex
+func parseProcAddress(value string) (net.IP, int, error) {
+	addr, portHex, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, 0, fmt.Errorf("invalid address %q", value)
+	}
+	raw, err := hex.DecodeString(addr)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("invalid address %q", value)
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid port in %q", value)
+	}
+	// Each word is little-endian on the architectures the tool ships for
+	ip := make(net.IP, len(raw))
+	for word := 0; word < len(raw); word += 4 {
+		for i := 0; i < 4; i++ {
+			ip[word+i] = raw[word+3-i]
+		}
+	}
+	return ip, int(port), nil
+}
@@ -0,0 +1,48 @@
+//go:build !linux && !darwin
+
+package monitor
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// hostLimitations tells the user which measurements are missing
+var hostLimitations = []string{
+	"CPU, memory and network monitoring are not implemented on " + runtime.GOOS +
+		"; only timings, download progress and disk usage are measured",
+}
+
+// errGatherUnsupported is returned by the collectors of unsupportedHost
+var errGatherUnsupported = fmt.Errorf("process and interface statistics are not supported on %s", runtime.GOOS)
+
+// unsupportedHost reports errGatherUnsupported, so the monitors run but record no usage
+type unsupportedHost struct{}
+
+func newHostStats() hostStats {
+	return unsupportedHost{}
+}
+
+func (unsupportedHost) processUsage(pid int, children bool) (resourceUsage, error) {
+	return resourceUsage{}, errGatherUnsupported
+}
+
+func (unsupportedHost) processTree(pid int) []int {
+	return []int{pid}
+}
+
+func (unsupportedHost) totalMemory() int64 {
+	return 0
+}
+
+func (unsupportedHost) interfaceCounters(name string) (int64, int64, error) {
+	return 0, 0, errGatherUnsupported
+}
+
+func (unsupportedHost) defaultInterface() string {
+	return ""
+}
+
+func (unsupportedHost) tcpConnections() ([]tcpConnection, error) {
+	return nil, errGatherUnsupported
+}
@@ -0,0 +1,227 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// Sources of registry response status codes
+const (
+	HTTPStatusSourceAccessLog = "access-log" // Registry or reverse proxy access log
+	HTTPStatusSourceDebugLog  = "debug-log"  // oc-mirror output; 2xx inferred from requests without an error status
+)
+
+// A time slice is a storm when at least stormMinResponses of its responses,
+// and at least stormMinPercent of them, were throttled (429) or server errors (5xx)
+const (
+	stormMinResponses = 10
+	stormMinPercent   = 5.0
+	maxStorms         = 20
+)
+
+// HTTPStatusMetrics is the distribution of registry response status codes over a phase
+type HTTPStatusMetrics struct {
+	Source          string            `json:"Source"` // access-log or debug-log
+	Total           int               `json:"Total"`
+	ByClass         map[string]int    `json:"ByClass"` // 2xx, 3xx, 4xx, 5xx
+	ByCode          map[int]int       `json:"ByCode"`
+	Throttled       int               `json:"Throttled"`    // 429 Too Many Requests
+	ServerErrors    int               `json:"ServerErrors"` // 5xx
+	SuccessInferred bool              `json:"SuccessInferred,omitempty"`
+	BucketSeconds   int               `json:"BucketSeconds"`
+	Storms          []HTTPStatusStorm `json:"Storms,omitempty"`
+}
+
+// HTTPStatusStorm is a stretch of consecutive time slices dominated by 429 or 5xx responses
+type HTTPStatusStorm struct {
+	OffsetSeconds   int `json:"OffsetSeconds"` // Since the phase started
+	DurationSeconds int `json:"DurationSeconds"`
+	Responses       int `json:"Responses"`
+	Throttled       int `json:"Throttled"`
+	ServerErrors    int `json:"ServerErrors"`
+}
+
+// HTTPStatusTally counts response status codes per second of a phase
+type HTTPStatusTally struct {
+	start   time.Time
+	codes   map[int]int
+	seconds map[int]*statusCounts // Keyed by seconds since start
+}
+
+type statusCounts struct {
+	responses    int
+	throttled    int
+	serverErrors int
+}
+
+// NewHTTPStatusTally creates a tally for a phase that started at start
+func NewHTTPStatusTally(start time.Time) *HTTPStatusTally {
+	return &HTTPStatusTally{start: start, codes: make(map[int]int), seconds: make(map[int]*statusCounts)}
+}
+
+// Add records one response; responses before the phase start count at its start
+func (t *HTTPStatusTally) Add(at time.Time, code int) {
+	if code < 100 || code > 599 {
+		return
+	}
+	t.codes[code]++
+	second := int(at.Sub(t.start) / time.Second)
+	if second < 0 {
+		second = 0
+	}
+	counts := t.seconds[second]
+	if counts == nil {
+		counts = &statusCounts{}
+		t.seconds[second] = counts
+	}
+	counts.responses++
+	switch {
+	case code == 429:
+		counts.throttled++
+	case code >= 500:
+		counts.serverErrors++
+	}
+}
+
+// Metrics returns the distribution with storms detected in slices of the
+// given length, or nil when no response was recorded
+func (t *HTTPStatusTally) Metrics(source string, bucket time.Duration) *HTTPStatusMetrics {
+	if t == nil || len(t.codes) == 0 {
+		return nil
+	}
+	if bucket < time.Second {
+		bucket = time.Second
+	}
+	m := &HTTPStatusMetrics{
+		Source:        source,
+		ByClass:       make(map[string]int),
+		ByCode:        make(map[int]int),
+		BucketSeconds: int(bucket / time.Second),
+	}
+	for code, count := range t.codes {
+		m.ByCode[code] = count
+		m.ByClass[fmt.Sprintf("%dxx", code/100)] += count
+		m.Total += count
+		switch {
+		case code == 429:
+			m.Throttled += count
+		case code >= 500:
+			m.ServerErrors += count
+		}
+	}
+	m.Storms = t.storms(m.BucketSeconds)
+	return m
+}
+
+// storms merges consecutive stormy slices
+func (t *HTTPStatusTally) storms(bucketSeconds int) []HTTPStatusStorm {
+	buckets := make(map[int]*statusCounts)
+	for second, counts := range t.seconds {
+		index := second / bucketSeconds
+		b := buckets[index]
+		if b == nil {
+			b = &statusCounts{}
+			buckets[index] = b
+		}
+		b.responses += counts.responses
+		b.throttled += counts.throttled
+		b.serverErrors += counts.serverErrors
+	}
+	indexes := make([]int, 0, len(buckets))
+	for index := range buckets {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	var storms []HTTPStatusStorm
+	last := -2
+	for _, index := range indexes {
+		b := buckets[index]
+		failed := b.throttled + b.serverErrors
+		if failed < stormMinResponses || float64(failed)/float64(b.responses)*100 < stormMinPercent {
+			continue
+		}
+		if index == last+1 && len(storms) > 0 {
+			s := &storms[len(storms)-1]
+			s.DurationSeconds += bucketSeconds
+			s.Responses += b.responses
+			s.Throttled += b.throttled
+			s.ServerErrors += b.serverErrors
+		} else if len(storms) < maxStorms {
+			storms = append(storms, HTTPStatusStorm{
+				OffsetSeconds:   index * bucketSeconds,
+				DurationSeconds: bucketSeconds,
+				Responses:       b.responses,
+				Throttled:       b.throttled,
+				ServerErrors:    b.serverErrors,
+			})
+		} else {
+			break
+		}
+		last = index
+	}
+	return storms
+}
+
+// Codes returns the status codes seen, most frequent first
+func (m *HTTPStatusMetrics) Codes() []int {
+	codes := make([]int, 0, len(m.ByCode))
+	for code := range m.ByCode {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if m.ByCode[codes[i]] != m.ByCode[codes[j]] {
+			return m.ByCode[codes[i]] > m.ByCode[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+	return codes
+}
+
+// Degraded reports whether the registry throttled or failed requests
+func (m *HTTPStatusMetrics) Degraded() bool {
+	return m != nil && (m.Throttled > 0 || m.ServerErrors > 0)
+}
+
+// String returns a one-line description of the 429 and 5xx responses
+func (m *HTTPStatusMetrics) String() string {
+	s := fmt.Sprintf("%d throttled (429), %d server errors (5xx) of %d responses", m.Throttled, m.ServerErrors, m.Total)
+	if len(m.Storms) > 0 {
+		s += fmt.Sprintf(", %d storm(s)", len(m.Storms))
+	}
+	return s
+}
+
+// PrintSummary prints the status code distribution; 429 and 5xx storms stand out
+func (m *HTTPStatusMetrics) PrintSummary() {
+	if m == nil {
+		return
+	}
+	logging.Printf("  │ ─── Registry Responses (%s) ──────────────────────────────\n", m.Source)
+	logging.Printf("  │   Responses: %d | 2xx: %d | 3xx: %d | 4xx: %d | 5xx: %d | 429: %d\n",
+		m.Total, m.ByClass["2xx"], m.ByClass["3xx"], m.ByClass["4xx"], m.ByClass["5xx"], m.Throttled)
+	if m.SuccessInferred {
+		logging.Printf("  │   (2xx inferred from requests without a logged error status)\n")
+	}
+	var codes []string
+	for _, code := range m.Codes() {
+		if code >= 400 {
+			codes = append(codes, fmt.Sprintf("%d×%d", code, m.ByCode[code]))
+		}
+	}
+	if len(codes) > 0 {
+		logging.Printf("  │   Error codes: %s\n", strings.Join(codes, ", "))
+	}
+	if !m.Degraded() {
+		return
+	}
+	logging.Printf("  │   ⚠ Registry throttled or failed %.1f%% of the responses\n", float64(m.Throttled+m.ServerErrors)/float64(m.Total)*100)
+	for _, s := range m.Storms {
+		logging.Printf("  │   ⚠ Storm at +%ds for %ds: %d throttled, %d server errors of %d responses\n",
+			s.OffsetSeconds, s.DurationSeconds, s.Throttled, s.ServerErrors, s.Responses)
+	}
+}
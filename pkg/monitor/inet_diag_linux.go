@@ -0,0 +1,135 @@
+//go:build linux
+
+package monitor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// sock_diag netlink constants (linux/sock_diag.h, linux/inet_diag.h)
+const (
+	netlinkSockDiag   = 4  // NETLINK_SOCK_DIAG
+	sockDiagByFamily  = 20 // SOCK_DIAG_BY_FAMILY
+	inetDiagInfo      = 2  // INET_DIAG_INFO attribute, carrying struct tcp_info
+	tcpListen         = 10 // TCP_LISTEN state
+	inetDiagReqSize   = 72 // nlmsghdr + inet_diag_req_v2
+	inetDiagMsgSize   = 72 // struct inet_diag_msg
+	tcpInfoBytesAcked = 120
+	tcpInfoBytesRecvd = 128
+)
+
+// readInetDiag returns tcp_info byte counters for all TCP sockets, keyed by
+// inode, from a sock_diag netlink dump. This is what `ss -ti` reads, without
+// needing iproute2 in the container.
+func readInetDiag() (map[string]socketTraffic, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, netlinkSockDiag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sock_diag socket: %w", err)
+	}
+	defer syscall.Close(fd)
+	timeout := syscall.NsecToTimeval(int64(5 * time.Second))
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		return nil, fmt.Errorf("failed to configure sock_diag socket: %w", err)
+	}
+
+	stats := make(map[string]socketTraffic)
+	for seq, family := range []uint8{syscall.AF_INET, syscall.AF_INET6} {
+		if err := dumpInetDiag(fd, family, uint32(seq+1), stats); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// dumpInetDiag requests the TCP sockets of one address family and adds their
+// counters to stats
+func dumpInetDiag(fd int, family uint8, seq uint32, stats map[string]socketTraffic) error {
+	req := make([]byte, inetDiagReqSize)
+	binary.NativeEndian.PutUint32(req[0:4], inetDiagReqSize)
+	binary.NativeEndian.PutUint16(req[4:6], sockDiagByFamily)
+	binary.NativeEndian.PutUint16(req[6:8], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	binary.NativeEndian.PutUint32(req[8:12], seq)
+	req[16] = family
+	req[17] = syscall.IPPROTO_TCP
+	req[18] = 1 << (inetDiagInfo - 1)
+	binary.NativeEndian.PutUint32(req[20:24], ^uint32(1<<tcpListen)) // Every state but listening
+	if err := syscall.Sendto(fd, req, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return fmt.Errorf("failed to query sock_diag: %w", err)
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return fmt.Errorf("failed to read sock_diag: %w", err)
+		}
+		messages, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return fmt.Errorf("failed to parse sock_diag: %w", err)
+		}
+		for _, m := range messages {
+			if m.Header.Seq != seq {
+				continue
+			}
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				return nil
+			case syscall.NLMSG_ERROR:
+				if len(m.Data) >= 4 {
+					if errno := int32(binary.NativeEndian.Uint32(m.Data[0:4])); errno != 0 {
+						return fmt.Errorf("sock_diag: %w", syscall.Errno(-errno))
+					}
+				}
+				return nil
+			}
+			if inode, stat, ok := parseInetDiagMsg(m.Data); ok {
+				stats[inode] = stat
+			}
+		}
+	}
+}
+
+// parseInetDiagMsg decodes one inet_diag_msg with its tcp_info attribute
+func parseInetDiagMsg(data []byte) (string, socketTraffic, bool) {
+	if len(data) < inetDiagMsgSize {
+		return "", socketTraffic{}, false
+	}
+	inode := binary.NativeEndian.Uint32(data[68:72])
+	if inode == 0 {
+		return "", socketTraffic{}, false
+	}
+
+	// inet_diag_sockid: ports and addresses in network byte order
+	port := binary.BigEndian.Uint16(data[6:8])
+	var ip net.IP
+	if data[0] == syscall.AF_INET {
+		ip = net.IP(append([]byte(nil), data[24:28]...))
+	} else {
+		ip = net.IP(append([]byte(nil), data[24:40]...))
+	}
+	stat := socketTraffic{
+		remote:   net.JoinHostPort(ip.String(), strconv.Itoa(int(port))),
+		loopback: ip.IsLoopback(),
+	}
+
+	for attrs := data[inetDiagMsgSize:]; len(attrs) >= 4; {
+		length := int(binary.NativeEndian.Uint16(attrs[0:2]))
+		if length < 4 || length > len(attrs) {
+			break
+		}
+		if binary.NativeEndian.Uint16(attrs[2:4]) == inetDiagInfo {
+			// The byte counters exist since Linux 4.1
+			if info := attrs[4:length]; len(info) >= tcpInfoBytesRecvd+8 {
+				stat.txBytes = int64(binary.NativeEndian.Uint64(info[tcpInfoBytesAcked:]))
+				stat.rxBytes = int64(binary.NativeEndian.Uint64(info[tcpInfoBytesRecvd:]))
+			}
+		}
+		attrs = attrs[min((length+3)&^3, len(attrs)):]
+	}
+	return strconv.FormatUint(uint64(inode), 10), stat, true
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package monitor
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// readInetDiag has no sock_diag netlink to read on this OS
+func readInetDiag() (map[string]socketTraffic, error) {
+	return nil, fmt.Errorf("sock_diag is not available on %s", runtime.GOOS)
+}
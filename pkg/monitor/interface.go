@@ -5,6 +5,10 @@ import (
 	"time"
 )
 
+// DefaultPollInterval is the sampling interval of every monitor unless the
+// caller sets its own
+const DefaultPollInterval = 1 * time.Second
+
 // Monitor defines the common interface for all monitoring types
 // This enables polymorphism and makes the code more flexible and testable
 type Monitor interface {
@@ -36,6 +40,26 @@ type PollingMonitor interface {
 	GetPollInterval() time.Duration
 }
 
+// AdaptiveMonitor is a monitor whose poll interval can back off on long phases
+type AdaptiveMonitor interface {
+	// SetAdaptivePolling sets the back-off; call it before Start
+	SetAdaptivePolling(policy AdaptivePolling)
+
+	// PollSteps returns the poll interval changes since Start
+	PollSteps() []PollStep
+}
+
+// SampleHandler receives each sample a monitor takes, e.g. to feed live
+// views while the monitor runs. It is called from the monitor goroutine and
+// must not block.
+type SampleHandler func(sample interface{})
+
+// SampleObserver is a monitor that reports its samples as they are taken
+type SampleObserver interface {
+	// SetSampleHandler sets the handler; call it before Start
+	SetSampleHandler(handler SampleHandler)
+}
+
 // MetricsCalculator defines interface for types that can calculate metrics
 type MetricsCalculator interface {
 	// CalculateMetrics computes and returns aggregated metrics
@@ -60,7 +84,10 @@ var (
 	_ Monitor = (*ResourceMonitor)(nil)
 	_ Monitor = (*DownloadMonitor)(nil)
 	_ Monitor = (*DiskWriteMonitor)(nil)
+	_ Monitor = (*DiskIOMonitor)(nil)
+	_ Monitor = (*ProcessNetworkMonitor)(nil)
 	_ Monitor = (*RegistryMonitor)(nil)
+	_ Monitor = (*RegistryServerMonitor)(nil)
 )
 
 // Ensure monitors implement PollingMonitor where applicable
@@ -68,6 +95,31 @@ var (
 	_ PollingMonitor = (*ResourceMonitor)(nil)
 	_ PollingMonitor = (*DownloadMonitor)(nil)
 	_ PollingMonitor = (*DiskWriteMonitor)(nil)
+	_ PollingMonitor = (*DiskIOMonitor)(nil)
+	_ PollingMonitor = (*ProcessNetworkMonitor)(nil)
 	_ PollingMonitor = (*RegistryMonitor)(nil)
+	_ PollingMonitor = (*RegistryServerMonitor)(nil)
+)
+
+// Ensure per-phase sampling monitors can back off their poll interval
+var (
+	_ AdaptiveMonitor = (*NetworkMonitor)(nil)
+	_ AdaptiveMonitor = (*ResourceMonitor)(nil)
+	_ AdaptiveMonitor = (*DownloadMonitor)(nil)
+	_ AdaptiveMonitor = (*DiskWriteMonitor)(nil)
+	_ AdaptiveMonitor = (*DiskIOMonitor)(nil)
+	_ AdaptiveMonitor = (*ProcessNetworkMonitor)(nil)
+)
+
+// Ensure sampling monitors report their samples
+var (
+	_ SampleObserver = (*NetworkMonitor)(nil)
+	_ SampleObserver = (*ResourceMonitor)(nil)
+	_ SampleObserver = (*DownloadMonitor)(nil)
+	_ SampleObserver = (*DiskWriteMonitor)(nil)
+	_ SampleObserver = (*DiskIOMonitor)(nil)
+	_ SampleObserver = (*ProcessNetworkMonitor)(nil)
+	_ SampleObserver = (*RegistryMonitor)(nil)
+	_ SampleObserver = (*RegistryServerMonitor)(nil)
 )
 
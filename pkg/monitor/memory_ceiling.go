@@ -0,0 +1,530 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// Memory ceiling levels, raised as usage approaches and crosses the budget
+const (
+	memoryLevelNormal = iota
+	memoryLevelNear
+	memoryLevelExceeded
+)
+
+// memoryRearmPercent is how far below the warning threshold usage must drop
+// before the next approach is reported again
+const memoryRearmPercent = 5.0
+
+// byteSizePattern matches sizes such as 16Gi, 512M or 1.5GB
+var byteSizePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([KMGT]?)(i?)B?$`)
+
+// Kernel messages written when the OOM killer runs
+var (
+	oomInvokedPattern = regexp.MustCompile(`(?i)invoked oom-killer|out of memory`)
+	oomKilledPattern  = regexp.MustCompile(`(?i)killed process (\d+) \(([^)]+)\)`)
+	dmesgTimePattern  = regexp.MustCompile(`^\[\s*(\d+\.\d+)\]\s*`)
+)
+
+// MemoryCeilingConfig is the memory budget of the host oc-mirror runs on
+type MemoryCeilingConfig struct {
+	Budget            string  `json:"budget,omitempty" yaml:"budget,omitempty"`                         // e.g. 16Gi or 16G
+	WarnWithinPercent float64 `json:"warn_within_percent,omitempty" yaml:"warnWithinPercent,omitempty"` // Warn when usage is within this percentage of the budget
+}
+
+// Enabled returns true if a memory budget is configured
+func (c MemoryCeilingConfig) Enabled() bool {
+	return c.Budget != ""
+}
+
+// Validate checks the budget size and warning percentage
+func (c MemoryCeilingConfig) Validate() error {
+	if c.WarnWithinPercent < 0 || c.WarnWithinPercent >= 100 {
+		return fmt.Errorf("memory warning percentage must be between 0 and 100")
+	}
+	if !c.Enabled() {
+		return nil
+	}
+	budget, err := ParseByteSize(c.Budget)
+	if err != nil {
+		return fmt.Errorf("invalid memory budget: %w", err)
+	}
+	if budget <= 0 {
+		return fmt.Errorf("memory budget must be positive")
+	}
+	return nil
+}
+
+// BudgetBytes returns the budget in bytes, zero when unset or invalid
+func (c MemoryCeilingConfig) BudgetBytes() int64 {
+	budget, _ := ParseByteSize(c.Budget)
+	return budget
+}
+
+// GetWarnWithinPercent returns the warning distance to the budget, defaulting to 10%
+func (c MemoryCeilingConfig) GetWarnWithinPercent() float64 {
+	if c.WarnWithinPercent <= 0 {
+		return 10
+	}
+	return c.WarnWithinPercent
+}
+
+// String returns a human-readable description of the budget
+func (c MemoryCeilingConfig) String() string {
+	if !c.Enabled() {
+		return "none"
+	}
+	return fmt.Sprintf("%s (%s), warn within %.0f%%", c.Budget, FormatBytesHuman(c.BudgetBytes()), c.GetWarnWithinPercent())
+}
+
+// ParseByteSize parses a size with an optional unit. K, M, G and T are decimal,
+// Ki, Mi, Gi and Ti binary, as in Kubernetes resource quantities.
+func ParseByteSize(s string) (int64, error) {
+	matches := byteSizePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if matches == nil {
+		return 0, fmt.Errorf("invalid size %q (e.g. 16Gi, 16G, 512Mi)", s)
+	}
+	value, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+	base := 1000.0
+	if matches[3] != "" {
+		if matches[2] == "" {
+			return 0, fmt.Errorf("invalid size %q: binary suffix needs a unit", s)
+		}
+		base = 1024
+	}
+	exponent := strings.Index("KMGT", matches[2]) + 1
+	if matches[2] == "" {
+		exponent = 0
+	}
+	for i := 0; i < exponent; i++ {
+		value *= base
+	}
+	return int64(value), nil
+}
+
+// MemoryWarning is a point in the phase where memory usage approached or exceeded the budget
+type MemoryWarning struct {
+	Timestamp       time.Time `json:"Timestamp"`
+	OffsetSeconds   float64   `json:"OffsetSeconds"` // Seconds since the phase started
+	UsageBytes      int64     `json:"UsageBytes"`    // RSS plus page cache pressure
+	RSSBytes        int64     `json:"RSSBytes"`
+	PageCacheBytes  int64     `json:"PageCacheBytes"` // Dirty and writeback page cache
+	PercentOfBudget float64   `json:"PercentOfBudget"`
+	Exceeded        bool      `json:"Exceeded"`
+	Message         string    `json:"Message"`
+}
+
+// OOMEvent is a process killed by the kernel OOM killer
+type OOMEvent struct {
+	Timestamp time.Time `json:"Timestamp"`
+	PID       int       `json:"PID"`
+	Process   string    `json:"Process"`
+	Message   string    `json:"Message"`
+}
+
+// MemoryCeilingMetrics compares memory usage of a phase against the budget
+type MemoryCeilingMetrics struct {
+	BudgetBytes        int64           `json:"BudgetBytes"`
+	WarnWithinPercent  float64         `json:"WarnWithinPercent"`
+	PeakUsageBytes     int64           `json:"PeakUsageBytes"`
+	PeakRSSBytes       int64           `json:"PeakRSSBytes"`
+	PeakPageCacheBytes int64           `json:"PeakPageCacheBytes"`
+	PeakPercent        float64         `json:"PeakPercent"`
+	PeakPressureAvg10  float64         `json:"PeakPressureAvg10"` // Highest /proc/pressure/memory "some avg10", when available
+	Warnings           []MemoryWarning `json:"Warnings"`
+	OOMKillerInvoked   bool            `json:"OOMKillerInvoked"`
+	OOMKills           []OOMEvent      `json:"OOMKills"`
+	CgroupOOMKills     int             `json:"CgroupOOMKills"` // oom_kill count of the cgroup during the phase
+	OOMSource          string          `json:"OOMSource"`      // journal or dmesg
+	OOMCheckError      string          `json:"OOMCheckError,omitempty"`
+}
+
+// MemoryCeilingMonitor compares the RSS of a process tree plus page cache
+// pressure against a memory budget while the process runs, and scans the kernel
+// log for OOM kills when stopped. Page cache pressure is the dirty and
+// writeback page cache, which the kernel cannot reclaim without flushing.
+type MemoryCeilingMonitor struct {
+	startTime      time.Time
+	stopTime       time.Time
+	monitoring     bool
+	pid            int
+	budget         int64
+	warnWithin     float64
+	level          int
+	cgroupOOMStart int
+	metrics        MemoryCeilingMetrics
+	mu             sync.RWMutex
+	pollInterval   time.Duration
+}
+
+// NewMemoryCeilingMonitor creates a memory ceiling monitor; set the PID before starting
+func NewMemoryCeilingMonitor(cfg MemoryCeilingConfig) *MemoryCeilingMonitor {
+	return &MemoryCeilingMonitor{
+		budget:       cfg.BudgetBytes(),
+		warnWithin:   cfg.GetWarnWithinPercent(),
+		pollInterval: DefaultPollInterval,
+	}
+}
+
+// SetTargetPID sets the root of the process tree whose memory is counted
+func (mm *MemoryCeilingMonitor) SetTargetPID(pid int) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.pid = pid
+}
+
+// SetPollInterval sets the polling interval for monitoring
+func (mm *MemoryCeilingMonitor) SetPollInterval(interval time.Duration) {
+	mm.pollInterval = interval
+}
+
+// GetPollInterval implements PollingMonitor interface
+func (mm *MemoryCeilingMonitor) GetPollInterval() time.Duration {
+	return mm.pollInterval
+}
+
+// Start begins comparing memory usage against the budget
+func (mm *MemoryCeilingMonitor) Start() error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	if mm.monitoring {
+		return nil
+	}
+	if mm.pid <= 0 {
+		return fmt.Errorf("no target PID set")
+	}
+	if mm.budget <= 0 {
+		return fmt.Errorf("no memory budget set")
+	}
+
+	mm.startTime = time.Now()
+	mm.monitoring = true
+	mm.level = memoryLevelNormal
+	mm.cgroupOOMStart = cgroupOOMKills()
+	mm.metrics = MemoryCeilingMetrics{
+		BudgetBytes:       mm.budget,
+		WarnWithinPercent: mm.warnWithin,
+		Warnings:          make([]MemoryWarning, 0),
+		OOMKills:          make([]OOMEvent, 0),
+	}
+
+	go mm.monitorLoop()
+
+	return nil
+}
+
+// Stop stops monitoring, scans the kernel log for OOM kills since the start and returns the metrics
+func (mm *MemoryCeilingMonitor) Stop() MemoryCeilingMetrics {
+	mm.mu.Lock()
+	mm.monitoring = false
+	mm.stopTime = time.Now()
+	mm.mu.Unlock()
+
+	// Use context timeout instead of blocking sleep
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	<-ctx.Done()
+	cancel()
+
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	metrics := mm.metrics
+	metrics.CgroupOOMKills = max(cgroupOOMKills()-mm.cgroupOOMStart, 0)
+
+	scan, err := ScanOOMKills(mm.startTime)
+	if err != nil {
+		metrics.OOMCheckError = err.Error()
+	} else {
+		metrics.OOMSource = scan.Source
+		metrics.OOMKillerInvoked = scan.Invoked
+		metrics.OOMKills = scan.Kills
+	}
+	if metrics.CgroupOOMKills > 0 {
+		metrics.OOMKillerInvoked = true
+	}
+	return metrics
+}
+
+// StopInterface implements Monitor interface
+func (mm *MemoryCeilingMonitor) StopInterface() interface{} {
+	return mm.Stop()
+}
+
+// IsMonitoring implements Monitor interface
+func (mm *MemoryCeilingMonitor) IsMonitoring() bool {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	return mm.monitoring
+}
+
+// GetDuration implements Monitor interface
+func (mm *MemoryCeilingMonitor) GetDuration() time.Duration {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	if !mm.monitoring {
+		return mm.stopTime.Sub(mm.startTime)
+	}
+	return time.Since(mm.startTime)
+}
+
+func (mm *MemoryCeilingMonitor) monitorLoop() {
+	ticker := time.NewTicker(mm.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		mm.mu.RLock()
+		monitoring := mm.monitoring
+		pid := mm.pid
+		mm.mu.RUnlock()
+
+		if !monitoring {
+			break
+		}
+
+		select {
+		case <-ticker.C:
+			rss := processTreeRSS(pid)
+			pageCache := pageCachePressure()
+			pressure := memoryPressureAvg10()
+
+			mm.mu.Lock()
+			mm.observe(time.Now(), rss, pageCache, pressure)
+			mm.mu.Unlock()
+		}
+	}
+}
+
+// observe records one measurement and emits a warning when usage enters a higher level
+func (mm *MemoryCeilingMonitor) observe(now time.Time, rss, pageCache int64, pressure float64) {
+	usage := rss + pageCache
+	percent := float64(usage) / float64(mm.budget) * 100
+
+	m := &mm.metrics
+	if usage > m.PeakUsageBytes {
+		m.PeakUsageBytes = usage
+		m.PeakPercent = percent
+	}
+	m.PeakRSSBytes = max(m.PeakRSSBytes, rss)
+	m.PeakPageCacheBytes = max(m.PeakPageCacheBytes, pageCache)
+	m.PeakPressureAvg10 = max(m.PeakPressureAvg10, pressure)
+
+	warnAt := 100 - mm.warnWithin
+	level := memoryLevelNormal
+	switch {
+	case percent >= 100:
+		level = memoryLevelExceeded
+	case percent >= warnAt:
+		level = memoryLevelNear
+	}
+
+	if level <= mm.level {
+		if percent < warnAt-memoryRearmPercent {
+			mm.level = memoryLevelNormal
+		}
+		return
+	}
+	mm.level = level
+
+	warning := MemoryWarning{
+		Timestamp:       now,
+		OffsetSeconds:   now.Sub(mm.startTime).Seconds(),
+		UsageBytes:      usage,
+		RSSBytes:        rss,
+		PageCacheBytes:  pageCache,
+		PercentOfBudget: percent,
+		Exceeded:        level == memoryLevelExceeded,
+	}
+	if warning.Exceeded {
+		warning.Message = fmt.Sprintf("memory usage %s exceeds the %s budget (%.0f%%)",
+			FormatBytesHuman(usage), FormatBytesHuman(mm.budget), percent)
+	} else {
+		warning.Message = fmt.Sprintf("memory usage %s is within %.0f%% of the %s budget (%.0f%%), OOM risk",
+			FormatBytesHuman(usage), mm.warnWithin, FormatBytesHuman(mm.budget), percent)
+	}
+	m.Warnings = append(m.Warnings, warning)
+	logging.Printf("  │ Warning: %s (RSS %s, page cache %s)\n", warning.Message, FormatBytesHuman(rss), FormatBytesHuman(pageCache))
+}
+
+// processTreeRSS returns the resident memory of pid and its descendants
+func processTreeRSS(pid int) int64 {
+	return procUsage(host.processTree(pid), false).rss
+}
+
+// pageCachePressure returns the dirty and writeback page cache from /proc/meminfo
+func pageCachePressure() int64 {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	var total int64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || (fields[0] != "Dirty:" && fields[0] != "Writeback:") {
+			continue
+		}
+		kb, _ := strconv.ParseInt(fields[1], 10, 64)
+		total += kb * 1024
+	}
+	return total
+}
+
+// memoryPressureAvg10 returns the "some avg10" memory pressure stall percentage, 0 without PSI
+func memoryPressureAvg10() float64 {
+	data, err := os.ReadFile("/proc/pressure/memory")
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "some ") {
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			if value, ok := strings.CutPrefix(field, "avg10="); ok {
+				avg, _ := strconv.ParseFloat(value, 64)
+				return avg
+			}
+		}
+	}
+	return 0
+}
+
+// cgroupOOMKills returns the oom_kill counter of the cgroup v2 this process runs in
+func cgroupOOMKills() int {
+	data, err := os.ReadFile("/sys/fs/cgroup/memory.events")
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "oom_kill "); ok {
+			count, _ := strconv.Atoi(strings.TrimSpace(value))
+			return count
+		}
+	}
+	return 0
+}
+
+// OOMScan is the result of scanning the kernel log for OOM killer activity
+type OOMScan struct {
+	Source  string // journal or dmesg
+	Invoked bool
+	Kills   []OOMEvent
+}
+
+// ScanOOMKills scans the kernel log for OOM killer activity since a point in
+// time, using the journal and falling back to dmesg
+func ScanOOMKills(since time.Time) (OOMScan, error) {
+	if output, err := exec.Command("journalctl", "-k", "-o", "short-unix", "--no-pager",
+		"--since", fmt.Sprintf("@%d", since.Unix())).Output(); err == nil {
+		return parseKernelLog(output, "journal", since, journalTime), nil
+	}
+
+	output, err := exec.Command("dmesg").Output()
+	if err != nil {
+		return OOMScan{}, fmt.Errorf("failed to read kernel log (journalctl and dmesg): %w", err)
+	}
+	bootTime, err := readBootTime()
+	if err != nil {
+		return OOMScan{}, err
+	}
+	return parseKernelLog(output, "dmesg", since, func(line string) (time.Time, string, bool) {
+		matches := dmesgTimePattern.FindStringSubmatch(line)
+		if matches == nil {
+			return time.Time{}, line, false
+		}
+		seconds, _ := strconv.ParseFloat(matches[1], 64)
+		return bootTime.Add(time.Duration(seconds * float64(time.Second))), line[len(matches[0]):], true
+	}), nil
+}
+
+// parseKernelLog collects the OOM messages logged at or after since
+func parseKernelLog(output []byte, source string, since time.Time, parseTime func(line string) (time.Time, string, bool)) OOMScan {
+	scan := OOMScan{Source: source, Kills: make([]OOMEvent, 0)}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		timestamp, message, ok := parseTime(scanner.Text())
+		if !ok || timestamp.Before(since.Truncate(time.Second)) {
+			continue
+		}
+		if oomInvokedPattern.MatchString(message) {
+			scan.Invoked = true
+		}
+		if matches := oomKilledPattern.FindStringSubmatch(message); matches != nil {
+			pid, _ := strconv.Atoi(matches[1])
+			scan.Kills = append(scan.Kills, OOMEvent{Timestamp: timestamp, PID: pid, Process: matches[2], Message: strings.TrimSpace(message)})
+		}
+	}
+	return scan
+}
+
+// journalTime parses a "short-unix" journal line: "<epoch.usec> <host> kernel: <message>"
+func journalTime(line string) (time.Time, string, bool) {
+	epoch, message, ok := strings.Cut(line, " ")
+	if !ok {
+		return time.Time{}, line, false
+	}
+	seconds, err := strconv.ParseFloat(epoch, 64)
+	if err != nil {
+		return time.Time{}, line, false
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), message, true
+}
+
+// readBootTime returns the boot time from /proc/stat, used to place dmesg timestamps
+func readBootTime() (time.Time, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read boot time: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("failed to parse boot time: %w", err)
+			}
+			return time.Unix(seconds, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("boot time not found in /proc/stat")
+}
+
+// PrintSummary prints the peak usage against the budget and any OOM kills
+func (m *MemoryCeilingMetrics) PrintSummary() {
+	logging.Printf("  │ ─── Memory Ceiling ───────────────────────────────────────────\n")
+	logging.Printf("  │   Peak: %s of %s (%.0f%%) | RSS %s | Page cache %s\n",
+		FormatBytesHuman(m.PeakUsageBytes), FormatBytesHuman(m.BudgetBytes), m.PeakPercent,
+		FormatBytesHuman(m.PeakRSSBytes), FormatBytesHuman(m.PeakPageCacheBytes))
+	if m.PeakPressureAvg10 > 0 {
+		logging.Printf("  │   Peak memory pressure (PSI some avg10): %.1f%%\n", m.PeakPressureAvg10)
+	}
+	logging.Printf("  │   Warnings: %d\n", len(m.Warnings))
+	switch {
+	case m.OOMCheckError != "" && m.CgroupOOMKills == 0:
+		logging.Printf("  │   OOM killer: not checked (%s)\n", m.OOMCheckError)
+	case len(m.OOMKills) > 0:
+		for _, kill := range m.OOMKills {
+			logging.Printf("  │   OOM killer: killed %s (PID %d) at %s\n", kill.Process, kill.PID, kill.Timestamp.Format("15:04:05"))
+		}
+	case m.OOMKillerInvoked:
+		logging.Printf("  │   OOM killer: invoked (cgroup kills: %d)\n", m.CgroupOOMKills)
+	default:
+		logging.Printf("  │   OOM killer: not activated\n")
+	}
+}
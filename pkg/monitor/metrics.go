@@ -3,7 +3,10 @@ package monitor
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
 )
 
 // NetworkMetrics methods
@@ -78,11 +81,14 @@ func (rm *ResourceMetrics) FormatJSON() (string, error) {
 
 // PrintSummary prints a formatted summary of resource metrics
 func (rm *ResourceMetrics) PrintSummary() {
-	fmt.Printf("  │ ─── Resource Usage ───────────────────────────────────────────\n")
-	fmt.Printf("  │   CPU Avg: %.2f%% | Peak: %.2f%%\n", rm.CPUAvgPercent, rm.CPUPeakPercent)
-	fmt.Printf("  │   Memory Avg: %.2f MB | Peak: %.2f MB\n", rm.MemoryAvgMB, rm.MemoryPeakMB)
-	fmt.Printf("  │   Goroutines Avg: %.0f | Peak: %d\n", rm.AvgGoroutines, rm.PeakGoroutines)
-	fmt.Printf("  │   Threads Avg: %.0f | Peak: %d\n", rm.AvgThreads, rm.PeakThreads)
+	logging.Printf("  │ ─── Resource Usage ───────────────────────────────────────────\n")
+	logging.Printf("  │   CPU Avg: %.2f%% | Peak: %.2f%%\n", rm.CPUAvgPercent, rm.CPUPeakPercent)
+	logging.Printf("  │   Memory Avg: %.2f MB | Peak: %.2f MB\n", rm.MemoryAvgMB, rm.MemoryPeakMB)
+	logging.Printf("  │   Goroutines Avg: %.0f | Peak: %d\n", rm.AvgGoroutines, rm.PeakGoroutines)
+	logging.Printf("  │   Threads Avg: %.0f | Peak: %d\n", rm.AvgThreads, rm.PeakThreads)
+	if rm.Scope == ResourceScopeTree || rm.Scope == ResourceScopeCgroup {
+		logging.Printf("  │   Scope: %s (peak %d processes)\n", rm.Scope, rm.PeakProcesses)
+	}
 }
 
 // DownloadMetrics methods
@@ -118,6 +124,63 @@ func (dm *DownloadMetrics) FormatJSON() (string, error) {
 	return string(data), nil
 }
 
+// DiskIOMetrics methods
+
+// Format returns a human-readable string representation
+func (dm *DiskIOMetrics) Format() string {
+	parts := make([]string, 0, len(dm.Devices))
+	for _, d := range dm.Devices {
+		parts = append(parts, fmt.Sprintf("%s: R %.0f IOPS %.2f MB/s | W %.0f IOPS %.2f MB/s | Util %.1f%%",
+			d.Device, d.AvgReadIOPS, d.AvgReadMBs, d.AvgWriteIOPS, d.AvgWriteMBs, d.AvgUtilPercent))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// FormatJSON returns JSON representation
+func (dm *DiskIOMetrics) FormatJSON() (string, error) {
+	data, err := json.MarshalIndent(dm, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// PrintSummary prints a formatted summary of disk I/O metrics
+func (dm *DiskIOMetrics) PrintSummary() {
+	logging.Printf("  │ ─── Disk I/O ─────────────────────────────────────────────────\n")
+	if len(dm.Devices) == 0 {
+		logging.Printf("  │   No block device found for %s\n", strings.Join(dm.UnresolvedPaths, ", "))
+		return
+	}
+	for _, d := range dm.Devices {
+		logging.Printf("  │   %s (%s)\n", d.Device, strings.Join(d.Paths, ", "))
+		logging.Printf("  │     Read:  %s | Avg %.0f IOPS %.2f MB/s | Peak %.0f IOPS %.2f MB/s\n",
+			FormatBytesHuman(d.BytesRead), d.AvgReadIOPS, d.AvgReadMBs, d.PeakReadIOPS, d.PeakReadMBs)
+		logging.Printf("  │     Write: %s | Avg %.0f IOPS %.2f MB/s | Peak %.0f IOPS %.2f MB/s\n",
+			FormatBytesHuman(d.BytesWritten), d.AvgWriteIOPS, d.AvgWriteMBs, d.PeakWriteIOPS, d.PeakWriteMBs)
+		logging.Printf("  │     Utilization: Avg %.1f%% | Peak %.1f%%\n", d.AvgUtilPercent, d.PeakUtilPercent)
+	}
+}
+
+// ProcessNetworkMetrics methods
+
+// PrintSummary prints a formatted summary of per-process network metrics
+func (pm *ProcessNetworkMetrics) PrintSummary() {
+	logging.Printf("  │ ─── Process Network (%s) ─────────────────────────────\n", pm.Method)
+	logging.Printf("  │   Received: %s | Sent: %s | Loopback excluded: %s\n",
+		FormatBytesHuman(pm.TotalRxBytes), FormatBytesHuman(pm.TotalTxBytes), FormatBytesHuman(pm.LoopbackBytes))
+	logging.Printf("  │   Avg Rx: %.2f Mbps | Avg Tx: %.2f Mbps | Peak: %.2f Mbps\n",
+		pm.AverageRxRateMbps, pm.AverageTxRateMbps, pm.PeakBandwidthMbps)
+	logging.Printf("  │   Connections: %d total | %d peak\n", pm.TotalConnections, pm.PeakConnections)
+	for i, remote := range pm.Remotes {
+		if i == 3 {
+			logging.Printf("  │   ... %d more remotes\n", len(pm.Remotes)-i)
+			break
+		}
+		logging.Printf("  │   %s: rx %s, tx %s\n", remote.Address, FormatBytesHuman(remote.RxBytes), FormatBytesHuman(remote.TxBytes))
+	}
+}
+
 // OutputMetrics methods
 
 // GetAverageFileSize returns average file size in bytes
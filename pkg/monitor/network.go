@@ -3,19 +3,19 @@ package monitor
 import (
 	"context"
 	"fmt"
-	"os/exec"
-	"strconv"
-	"strings"
 	"time"
 )
 
 // NetworkMonitor monitors network interface statistics
 type NetworkMonitor struct {
+	adaptivePoller
+
 	startTime     time.Time
 	stopTime      time.Time
 	monitoring    bool
 	interfaceName string
 	samples       []BandwidthSample
+	onSample      SampleHandler
 }
 
 // BandwidthSample represents a single bandwidth measurement
@@ -45,33 +45,23 @@ func NewNetworkMonitor() *NetworkMonitor {
 	}
 }
 
-func getDefaultInterface() string {
-	// Try to detect default network interface
-	cmd := exec.Command("ip", "route", "show", "default")
-	output, err := cmd.Output()
-	if err == nil {
-		// Parse output to find interface name
-		lines := strings.Split(string(output), "\n")
-		for _, line := range lines {
-			if strings.Contains(line, "dev") {
-				parts := strings.Fields(line)
-				for i, part := range parts {
-					if part == "dev" && i+1 < len(parts) {
-						return parts[i+1]
-					}
-				}
-			}
-		}
-	}
+// SetSampleHandler sets a handler called with each sample
+func (nm *NetworkMonitor) SetSampleHandler(handler SampleHandler) {
+	nm.onSample = handler
+}
 
-	// Try detectNetworkInterface as fallback
-	iface := detectNetworkInterface()
-	if iface != "" {
-		return iface
-	}
+// Interface returns the network interface whose counters are sampled
+func (nm *NetworkMonitor) Interface() string {
+	return nm.interfaceName
+}
 
-	// Ultimate fallback to common interface names
-	return "eth0"
+// DefaultInterface returns the network interface carrying the default route
+func DefaultInterface() string {
+	return getDefaultInterface()
+}
+
+func getDefaultInterface() string {
+	return host.defaultInterface()
 }
 
 // Start begins network monitoring
@@ -84,6 +74,8 @@ func (nm *NetworkMonitor) Start() error {
 	nm.monitoring = true
 	nm.samples = make([]BandwidthSample, 0)
 
+	nm.startPoller(DefaultPollInterval)
+
 	// Start background monitoring goroutine
 	go nm.monitorLoop()
 
@@ -98,6 +90,7 @@ func (nm *NetworkMonitor) Stop() NetworkMetrics {
 
 	nm.stopTime = time.Now()
 	nm.monitoring = false
+	nm.flushPoller()
 
 	// Use context timeout instead of blocking sleep
 	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
@@ -126,7 +119,7 @@ func (nm *NetworkMonitor) GetDuration() time.Duration {
 }
 
 func (nm *NetworkMonitor) monitorLoop() {
-	ticker := time.NewTicker(1 * time.Second)
+	ticker := nm.poller
 	defer ticker.Stop()
 
 	var lastRxBytes, lastTxBytes int64
@@ -147,6 +140,9 @@ func (nm *NetworkMonitor) monitorLoop() {
 					}
 				}
 				nm.samples = append(nm.samples, sample)
+				if nm.onSample != nil {
+					nm.onSample(sample)
+				}
 				lastRxBytes = sample.RxBytes
 				lastTxBytes = sample.TxBytes
 				lastSampleTime = sample.Timestamp
@@ -161,60 +157,7 @@ func (nm *NetworkMonitor) collectSample() BandwidthSample {
 		Timestamp: time.Now(),
 	}
 
-	// Try to read from /sys/class/net/<interface>/statistics/
-	rxPath := fmt.Sprintf("/sys/class/net/%s/statistics/rx_bytes", nm.interfaceName)
-	txPath := fmt.Sprintf("/sys/class/net/%s/statistics/tx_bytes", nm.interfaceName)
-
-	if rxData, err := exec.Command("cat", rxPath).Output(); err == nil {
-		if rxBytes, err := strconv.ParseInt(strings.TrimSpace(string(rxData)), 10, 64); err == nil {
-			sample.RxBytes = rxBytes
-		}
-	}
-
-	if txData, err := exec.Command("cat", txPath).Output(); err == nil {
-		if txBytes, err := strconv.ParseInt(strings.TrimSpace(string(txData)), 10, 64); err == nil {
-			sample.TxBytes = txBytes
-		}
-	}
-
-	// Fallback: try using iftop or other tools if sysfs not available
-	if sample.RxBytes == 0 && sample.TxBytes == 0 {
-		sample = nm.collectSampleFromIftop()
-	}
-
-	return sample
-}
-
-func (nm *NetworkMonitor) collectSampleFromIftop() BandwidthSample {
-	sample := BandwidthSample{
-		Timestamp: time.Now(),
-	}
-
-	// Try using iftop if available (requires sudo typically)
-	// For now, we'll use a simpler approach with /proc/net/dev
-	cmd := exec.Command("cat", "/proc/net/dev")
-	output, err := cmd.Output()
-	if err != nil {
-		return sample
-	}
-
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, nm.interfaceName+":") {
-			parts := strings.Fields(line)
-			if len(parts) >= 10 {
-				// Format: interface: rx_bytes rx_packets ... tx_bytes tx_packets ...
-				if rxBytes, err := strconv.ParseInt(parts[1], 10, 64); err == nil {
-					sample.RxBytes = rxBytes
-				}
-				if txBytes, err := strconv.ParseInt(parts[9], 10, 64); err == nil {
-					sample.TxBytes = txBytes
-				}
-			}
-			break
-		}
-	}
-
+	sample.RxBytes, sample.TxBytes, _ = host.interfaceCounters(nm.interfaceName)
 	return sample
 }
 
@@ -266,39 +209,3 @@ func (nm *NetworkMonitor) calculateMetrics() NetworkMetrics {
 
 	return metrics
 }
-
-// Try to detect network interface automatically
-func detectNetworkInterface() string {
-	// Try common methods
-	interfaces := []string{"eth0", "ens33", "enp0s3", "wlan0"}
-
-	cmd := exec.Command("ip", "link", "show")
-	output, err := cmd.Output()
-	if err == nil {
-		// Parse to find active interface
-		lines := strings.Split(string(output), "\n")
-		for _, line := range lines {
-			for _, iface := range interfaces {
-				if strings.Contains(line, iface+":") && strings.Contains(line, "state UP") {
-					return iface
-				}
-			}
-		}
-	}
-
-	// Fallback: try to read from /proc/net/route
-	cmd = exec.Command("cat", "/proc/net/route")
-	output, err = cmd.Output()
-	if err == nil {
-		lines := strings.Split(string(output), "\n")
-		if len(lines) > 1 {
-			// First non-header line usually has default route interface
-			parts := strings.Fields(lines[1])
-			if len(parts) > 0 {
-				return parts[0]
-			}
-		}
-	}
-
-	return "eth0" // Ultimate fallback
-}
@@ -3,14 +3,21 @@ package monitor
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
 )
 
+// cosignTagPattern matches the tags cosign attaches to an image digest, e.g. sha256-<hex>.sig
+var cosignTagPattern = regexp.MustCompile(`^sha256-[0-9a-f]{64}\.(sig|att|sbom)$`)
+
 // OutputVerifier verifies and compares mirror output directories
 type OutputVerifier struct {
 	directory string
@@ -28,6 +35,12 @@ type OutputMetrics struct {
 	LayerCount      int               `json:"LayerCount"`      // Number of blob layers
 	ManifestCount   int               `json:"ManifestCount"`    // Number of manifests
 	SignatureCount  int               `json:"SignatureCount"`  // Number of signatures
+	CosignSignatures   int            `json:"CosignSignatures"`   // cosign sha256-<digest>.sig tags
+	CosignAttestations int            `json:"CosignAttestations"` // cosign sha256-<digest>.att tags
+	CosignSBOMs        int            `json:"CosignSBOMs"`        // cosign sha256-<digest>.sbom tags
+
+	// blobCopies holds the sizes of the files named after each blob digest
+	blobCopies map[string][]int64
 }
 
 // FileInfo contains information about a single file
@@ -46,6 +59,9 @@ type OutputComparisonResult struct {
 	MissingInSecond  []string `json:"MissingInSecond"`
 	DifferentContent []string `json:"DifferentContent"`
 	HashMatch        bool     `json:"HashMatch"`
+
+	// Blobs compares the outputs by blob digest, which ignores where each layout stores them
+	Blobs BlobDedupComparison `json:"Blobs"`
 }
 
 // NewOutputVerifier creates a new output verifier for the given directory
@@ -61,6 +77,7 @@ func (ov *OutputVerifier) Analyze() (OutputMetrics, error) {
 		FileHashes:   make(map[string]string),
 		LargestFiles: make([]FileInfo, 0),
 		FileTypes:    make(map[string]int),
+		blobCopies:   make(map[string][]int64),
 	}
 
 	// Pre-allocate slices with estimated capacity to reduce reallocations
@@ -77,6 +94,8 @@ func (ov *OutputVerifier) Analyze() (OutputMetrics, error) {
 
 		if info.IsDir() {
 			metrics.TotalDirs++
+			// Registry storage keeps each tag as a directory under _manifests/tags
+			metrics.countCosignTag(info.Name())
 			return nil
 		}
 
@@ -98,12 +117,21 @@ func (ov *OutputVerifier) Analyze() (OutputMetrics, error) {
 		if strings.Contains(pathLower, "/blobs/") {
 			metrics.LayerCount++
 		}
+		if digest := BlobDigest(relPath); digest != "" {
+			metrics.blobCopies[digest] = append(metrics.blobCopies[digest], info.Size())
+		}
 		if strings.Contains(pathLower, "manifest") || strings.HasSuffix(pathLower, ".json") {
 			metrics.ManifestCount++
 		}
 		if strings.Contains(pathLower, "signature") || strings.HasSuffix(pathLower, ".sig") {
 			metrics.SignatureCount++
 		}
+		// OCI layouts name their tags in index.json
+		if info.Name() == "index.json" {
+			for _, tag := range layoutTags(path) {
+				metrics.countCosignTag(tag)
+			}
+		}
 
 		// Calculate file hash (for smaller files, skip very large ones for performance)
 		var hash string
@@ -208,6 +236,7 @@ func CompareOutputs(dir1, dir2 string) (OutputComparisonResult, error) {
 	result.SizeDifference = metrics1.TotalSize - metrics2.TotalSize
 	result.FileCountDiff = metrics1.TotalFiles - metrics2.TotalFiles
 	result.HashMatch = metrics1.DirectoryHash == metrics2.DirectoryHash
+	result.Blobs = compareBlobs(metrics1.blobCopies, metrics2.blobCopies)
 
 	// Pre-allocate slices with estimated capacity
 	missingInSecond := make([]string, 0, len(metrics1.FileHashes)/10)
@@ -243,6 +272,46 @@ func CompareOutputs(dir1, dir2 string) (OutputComparisonResult, error) {
 	return result, nil
 }
 
+// countCosignTag counts name if it is a cosign signature, attestation or SBOM tag
+func (m *OutputMetrics) countCosignTag(name string) {
+	matches := cosignTagPattern.FindStringSubmatch(name)
+	if matches == nil {
+		return
+	}
+	switch matches[1] {
+	case "sig":
+		m.CosignSignatures++
+	case "att":
+		m.CosignAttestations++
+	case "sbom":
+		m.CosignSBOMs++
+	}
+}
+
+// layoutTags returns the tags an OCI layout index.json references
+func layoutTags(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var index struct {
+		Manifests []struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"manifests"`
+	}
+	if json.Unmarshal(data, &index) != nil {
+		return nil
+	}
+	var tags []string
+	for _, manifest := range index.Manifests {
+		if ref := manifest.Annotations["org.opencontainers.image.ref.name"]; ref != "" {
+			// The annotation holds a tag or a full reference ending in :tag
+			tags = append(tags, ref[strings.LastIndex(ref, ":")+1:])
+		}
+	}
+	return tags
+}
+
 func hashFile(path string) (string, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -262,45 +331,50 @@ func hashFile(path string) (string, error) {
 
 // PrintSummary prints a formatted summary of the output metrics
 func (m *OutputMetrics) PrintSummary() {
-	fmt.Printf("  │ ─── Output Analysis ──────────────────────────────────────────\n")
-	fmt.Printf("  │   Total Size: %s\n", FormatBytesHuman(m.TotalSize))
-	fmt.Printf("  │   Total Files: %d | Directories: %d\n", m.TotalFiles, m.TotalDirs)
-	fmt.Printf("  │   Layers/Blobs: %d | Manifests: %d | Signatures: %d\n",
+	logging.Printf("  │ ─── Output Analysis ──────────────────────────────────────────\n")
+	logging.Printf("  │   Total Size: %s\n", FormatBytesHuman(m.TotalSize))
+	logging.Printf("  │   Total Files: %d | Directories: %d\n", m.TotalFiles, m.TotalDirs)
+	logging.Printf("  │   Layers/Blobs: %d | Manifests: %d | Signatures: %d\n",
 		m.LayerCount, m.ManifestCount, m.SignatureCount)
-	fmt.Printf("  │   Directory Hash: %s...\n", m.DirectoryHash[:16])
+	if m.CosignSignatures+m.CosignAttestations+m.CosignSBOMs > 0 {
+		logging.Printf("  │   Cosign Signatures: %d | Attestations: %d | SBOMs: %d\n",
+			m.CosignSignatures, m.CosignAttestations, m.CosignSBOMs)
+	}
+	logging.Printf("  │   Directory Hash: %s...\n", m.DirectoryHash[:16])
 
 	if len(m.LargestFiles) > 0 {
-		fmt.Printf("  │   Largest Files:\n")
+		logging.Printf("  │   Largest Files:\n")
 		for i, f := range m.LargestFiles {
 			if i >= 5 {
 				break
 			}
-			fmt.Printf("  │     %d. %s (%s)\n", i+1, truncatePath(f.Path, 40), FormatBytesHuman(f.Size))
+			logging.Printf("  │     %d. %s (%s)\n", i+1, truncatePath(f.Path, 40), FormatBytesHuman(f.Size))
 		}
 	}
 }
 
 // PrintComparisonSummary prints a comparison between two outputs
 func (r *OutputComparisonResult) PrintSummary(name1, name2 string) {
-	fmt.Printf("  │ ─── Output Comparison (%s vs %s) ─────────────────────\n", name1, name2)
+	logging.Printf("  │ ─── Output Comparison (%s vs %s) ─────────────────────\n", name1, name2)
 	if r.Match {
-		fmt.Printf("  │   ✓ Outputs MATCH\n")
+		logging.Printf("  │   ✓ Outputs MATCH\n")
 	} else {
-		fmt.Printf("  │   ✗ Outputs DIFFER\n")
+		logging.Printf("  │   ✗ Outputs DIFFER\n")
 	}
-	fmt.Printf("  │   Size Difference: %s\n", FormatBytesHuman(abs(r.SizeDifference)))
-	fmt.Printf("  │   File Count Difference: %d\n", abs64(int64(r.FileCountDiff)))
-	fmt.Printf("  │   Hash Match: %v\n", r.HashMatch)
+	logging.Printf("  │   Size Difference: %s\n", FormatBytesHuman(abs(r.SizeDifference)))
+	logging.Printf("  │   File Count Difference: %d\n", abs64(int64(r.FileCountDiff)))
+	logging.Printf("  │   Hash Match: %v\n", r.HashMatch)
 
 	if len(r.MissingInFirst) > 0 {
-		fmt.Printf("  │   Missing in %s: %d files\n", name1, len(r.MissingInFirst))
+		logging.Printf("  │   Missing in %s: %d files\n", name1, len(r.MissingInFirst))
 	}
 	if len(r.MissingInSecond) > 0 {
-		fmt.Printf("  │   Missing in %s: %d files\n", name2, len(r.MissingInSecond))
+		logging.Printf("  │   Missing in %s: %d files\n", name2, len(r.MissingInSecond))
 	}
 	if len(r.DifferentContent) > 0 {
-		fmt.Printf("  │   Different Content: %d files\n", len(r.DifferentContent))
+		logging.Printf("  │   Different Content: %d files\n", len(r.DifferentContent))
 	}
+	r.Blobs.PrintSummary(name1, name2)
 }
 
 func truncatePath(path string, maxLen int) string {
@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"path/filepath"
+	"regexp"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// blobPathPattern matches the blob paths of the layouts a local mirror holds:
+// registry storage (blobs/sha256/ab/<hex>/data), v1 archives
+// (blobs/sha256:<hex>) and OCI layouts (blobs/sha256/<hex>)
+var blobPathPattern = regexp.MustCompile(`(?:^|/)blobs/sha256(?:/[0-9a-f]{2}/([0-9a-f]{64})/data|[:/]([0-9a-f]{64}))$`)
+
+// BlobDigest returns the digest a blob path is named after, "" for other paths
+func BlobDigest(path string) string {
+	m := blobPathPattern.FindStringSubmatch(filepath.ToSlash(path))
+	if m == nil {
+		return ""
+	}
+	if m[1] != "" {
+		return "sha256:" + m[1]
+	}
+	return "sha256:" + m[2]
+}
+
+// BlobDedupStats groups the blob files of one output by digest
+type BlobDedupStats struct {
+	BlobFiles      int   `json:"BlobFiles"`      // Files named after a blob digest
+	UniqueBlobs    int   `json:"UniqueBlobs"`    // Distinct digests among them
+	UniqueBytes    int64 `json:"UniqueBytes"`    // Size of one copy of each digest
+	DuplicateFiles int   `json:"DuplicateFiles"` // Extra copies of a digest already stored
+	DuplicateBytes int64 `json:"DuplicateBytes"` // Wasted by the extra copies
+}
+
+// BlobDedupComparison compares two outputs by blob digest instead of file
+// path, so v1 and v2 storing the same blobs in different layouts still match
+type BlobDedupComparison struct {
+	First        BlobDedupStats `json:"First"`
+	Second       BlobDedupStats `json:"Second"`
+	SharedBlobs  int            `json:"SharedBlobs"` // Digests present in both outputs
+	SharedBytes  int64          `json:"SharedBytes"`
+	OnlyInFirst  int            `json:"OnlyInFirst"`
+	OnlyInSecond int            `json:"OnlyInSecond"`
+	SharedRatio  float64        `json:"SharedRatio"` // Shared digests over the digests of either output
+	SameBlobs    bool           `json:"SameBlobs"`   // Both outputs hold the same non-empty set of digests
+}
+
+// blobStats counts the unique and duplicate blobs of copies, the file sizes per digest
+func blobStats(copies map[string][]int64) BlobDedupStats {
+	stats := BlobDedupStats{UniqueBlobs: len(copies)}
+	for _, sizes := range copies {
+		stats.BlobFiles += len(sizes)
+		stats.UniqueBytes += sizes[0]
+		for _, size := range sizes[1:] {
+			stats.DuplicateFiles++
+			stats.DuplicateBytes += size
+		}
+	}
+	return stats
+}
+
+// compareBlobs compares the blob digests of two outputs
+func compareBlobs(first, second map[string][]int64) BlobDedupComparison {
+	c := BlobDedupComparison{First: blobStats(first), Second: blobStats(second)}
+	for digest, sizes := range first {
+		if _, ok := second[digest]; ok {
+			c.SharedBlobs++
+			c.SharedBytes += sizes[0]
+		} else {
+			c.OnlyInFirst++
+		}
+	}
+	c.OnlyInSecond = len(second) - c.SharedBlobs
+	if union := c.SharedBlobs + c.OnlyInFirst + c.OnlyInSecond; union > 0 {
+		c.SharedRatio = float64(c.SharedBlobs) / float64(union)
+	}
+	c.SameBlobs = c.SharedBlobs > 0 && c.OnlyInFirst == 0 && c.OnlyInSecond == 0
+	return c
+}
+
+// PrintSummary prints the blob counts and duplicate bytes of both outputs and their overlap
+func (c *BlobDedupComparison) PrintSummary(name1, name2 string) {
+	for _, side := range []struct {
+		name  string
+		stats BlobDedupStats
+	}{{name1, c.First}, {name2, c.Second}} {
+		logging.Printf("  │   Blobs (%s): %d unique in %d files (%s)", side.name,
+			side.stats.UniqueBlobs, side.stats.BlobFiles, FormatBytesHuman(side.stats.UniqueBytes))
+		if side.stats.DuplicateFiles > 0 {
+			logging.Printf(", %d duplicates wasting %s", side.stats.DuplicateFiles, FormatBytesHuman(side.stats.DuplicateBytes))
+		}
+		logging.Printf("\n")
+	}
+	logging.Printf("  │   Shared Blobs: %d (%.1f%%, %s) | Only in %s: %d | Only in %s: %d\n",
+		c.SharedBlobs, c.SharedRatio*100, FormatBytesHuman(c.SharedBytes), name1, c.OnlyInFirst, name2, c.OnlyInSecond)
+	if c.SameBlobs {
+		logging.Printf("  │   ✓ Both outputs hold the same blobs\n")
+	}
+}
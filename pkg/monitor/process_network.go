@@ -0,0 +1,457 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProcessNetworkMonitor attributes TCP traffic to a process tree instead of the whole
+// interface. Each poll maps the socket inodes held by the target PID and its children
+// to the kernel tcp_info byte counters, read over sock_diag netlink or, where that is
+// not permitted, from `ss`, so background host traffic is not counted. Sockets that open and close between two polls are missed, and loopback
+// traffic (e.g. the oc-mirror v2 local cache registry) is reported separately.
+type ProcessNetworkMonitor struct {
+	adaptivePoller
+
+	startTime    time.Time
+	stopTime     time.Time
+	monitoring   bool
+	pid          int
+	sockets      map[string]*socketTraffic // inode -> traffic
+	samples      []ProcessNetworkSample
+	mu           sync.RWMutex
+	pollInterval time.Duration
+	onSample     SampleHandler
+	method       string // Source of the counters of the last sample
+}
+
+// Sources of the per-socket tcp_info counters
+const (
+	ProcessNetworkInetDiag = "inet-diag"   // sock_diag netlink dump
+	ProcessNetworkSS       = "ss-tcp-info" // `ss -tieHn` output
+)
+
+// socketTraffic is the latest tcp_info counters of one socket
+type socketTraffic struct {
+	remote   string
+	loopback bool
+	rxBytes  int64
+	txBytes  int64
+}
+
+// ProcessNetworkSample represents a single per-process network measurement
+type ProcessNetworkSample struct {
+	Timestamp   time.Time `json:"Timestamp"`
+	RxBytes     int64     `json:"RxBytes"` // Cumulative, excluding loopback
+	TxBytes     int64     `json:"TxBytes"` // Cumulative, excluding loopback
+	RxRate      float64   `json:"RxRate"`  // Mbps
+	TxRate      float64   `json:"TxRate"`  // Mbps
+	Connections int       `json:"Connections"`
+	Processes   int       `json:"Processes"`
+}
+
+// RemoteTraffic is the traffic exchanged with one remote endpoint
+type RemoteTraffic struct {
+	Address     string `json:"Address"`
+	RxBytes     int64  `json:"RxBytes"`
+	TxBytes     int64  `json:"TxBytes"`
+	Connections int    `json:"Connections"`
+}
+
+// ProcessNetworkMetrics represents aggregated per-process network metrics
+type ProcessNetworkMetrics struct {
+	Method            string                 `json:"Method"` // How traffic was attributed
+	PID               int                    `json:"PID"`
+	Duration          time.Duration          `json:"Duration"`
+	TotalRxBytes      int64                  `json:"TotalRxBytes"`
+	TotalTxBytes      int64                  `json:"TotalTxBytes"`
+	LoopbackBytes     int64                  `json:"LoopbackBytes"` // Excluded from totals
+	AverageRxRateMbps float64                `json:"AverageRxRateMbps"`
+	AverageTxRateMbps float64                `json:"AverageTxRateMbps"`
+	PeakBandwidthMbps float64                `json:"PeakBandwidthMbps"`
+	TotalConnections  int                    `json:"TotalConnections"`
+	PeakConnections   int                    `json:"PeakConnections"`
+	Remotes           []RemoteTraffic        `json:"Remotes"`
+	Samples           []ProcessNetworkSample `json:"Samples"`
+}
+
+// NewProcessNetworkMonitor creates a per-process network monitor; set the PID before starting
+func NewProcessNetworkMonitor() *ProcessNetworkMonitor {
+	return &ProcessNetworkMonitor{
+		sockets:      make(map[string]*socketTraffic),
+		samples:      make([]ProcessNetworkSample, 0),
+		pollInterval: DefaultPollInterval,
+	}
+}
+
+// ProcessNetworkAvailable reports whether per-process accounting can run on
+// this host: it needs /proc and either sock_diag netlink or ss (iproute2)
+func ProcessNetworkAvailable() error {
+	if _, err := os.Stat("/proc/self/fd"); err != nil {
+		return fmt.Errorf("/proc is not available: %w", err)
+	}
+	_, diagErr := readInetDiag()
+	if diagErr == nil {
+		return nil
+	}
+	if _, err := exec.LookPath("ss"); err != nil {
+		return fmt.Errorf("%v, and ss (iproute2) not found", diagErr)
+	}
+	return nil
+}
+
+// SetTargetPID sets the root of the process tree to account traffic for
+func (pm *ProcessNetworkMonitor) SetTargetPID(pid int) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.pid = pid
+}
+
+// SetPollInterval sets the polling interval for monitoring
+func (pm *ProcessNetworkMonitor) SetPollInterval(interval time.Duration) {
+	pm.pollInterval = interval
+}
+
+// SetSampleHandler sets a handler called with each sample
+func (pm *ProcessNetworkMonitor) SetSampleHandler(handler SampleHandler) {
+	pm.onSample = handler
+}
+
+// GetPollInterval implements PollingMonitor interface
+func (pm *ProcessNetworkMonitor) GetPollInterval() time.Duration {
+	return pm.pollInterval
+}
+
+// Start begins per-process network monitoring
+func (pm *ProcessNetworkMonitor) Start() error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if pm.monitoring {
+		return nil
+	}
+	if pm.pid <= 0 {
+		return fmt.Errorf("no target PID set")
+	}
+	if err := ProcessNetworkAvailable(); err != nil {
+		return err
+	}
+
+	pm.startTime = time.Now()
+	pm.monitoring = true
+	pm.sockets = make(map[string]*socketTraffic)
+	pm.samples = make([]ProcessNetworkSample, 0)
+
+	pm.startPoller(pm.pollInterval)
+	go pm.monitorLoop()
+
+	return nil
+}
+
+// Stop stops monitoring and returns the collected metrics
+func (pm *ProcessNetworkMonitor) Stop() ProcessNetworkMetrics {
+	pm.mu.Lock()
+	pm.monitoring = false
+	pm.flushPoller()
+	pm.stopTime = time.Now()
+	pm.mu.Unlock()
+
+	// Use context timeout instead of blocking sleep
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	<-ctx.Done()
+	cancel()
+
+	return pm.calculateMetrics()
+}
+
+// StopInterface implements Monitor interface
+func (pm *ProcessNetworkMonitor) StopInterface() interface{} {
+	return pm.Stop()
+}
+
+// IsMonitoring implements Monitor interface
+func (pm *ProcessNetworkMonitor) IsMonitoring() bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.monitoring
+}
+
+// GetDuration implements Monitor interface
+func (pm *ProcessNetworkMonitor) GetDuration() time.Duration {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	if !pm.monitoring {
+		return pm.stopTime.Sub(pm.startTime)
+	}
+	return time.Since(pm.startTime)
+}
+
+func (pm *ProcessNetworkMonitor) monitorLoop() {
+	ticker := pm.poller
+	defer ticker.Stop()
+
+	var last ProcessNetworkSample
+	last.Timestamp = pm.startTime
+
+	for {
+		pm.mu.RLock()
+		monitoring := pm.monitoring
+		pid := pm.pid
+		pm.mu.RUnlock()
+
+		if !monitoring {
+			break
+		}
+
+		select {
+		case <-ticker.C:
+			sample, err := pm.collectSample(pid)
+			if err != nil {
+				continue
+			}
+
+			elapsed := sample.Timestamp.Sub(last.Timestamp).Seconds()
+			if elapsed > 0 {
+				sample.RxRate = float64(sample.RxBytes-last.RxBytes) * 8 / elapsed / 1e6
+				sample.TxRate = float64(sample.TxBytes-last.TxBytes) * 8 / elapsed / 1e6
+			}
+
+			pm.mu.Lock()
+			pm.samples = append(pm.samples, sample)
+			pm.mu.Unlock()
+			if pm.onSample != nil {
+				pm.onSample(sample)
+			}
+
+			last = sample
+		}
+	}
+}
+
+// collectSample updates the per-socket counters and returns the cumulative totals
+func (pm *ProcessNetworkMonitor) collectSample(pid int) (ProcessNetworkSample, error) {
+	pids := host.processTree(pid)
+	inodes := make(map[string]bool)
+	for _, p := range pids {
+		for _, inode := range socketInodes(p) {
+			inodes[inode] = true
+		}
+	}
+
+	stats, method, err := readTCPInfo()
+	if err != nil {
+		return ProcessNetworkSample{}, err
+	}
+
+	sample := ProcessNetworkSample{
+		Timestamp: time.Now(),
+		Processes: len(pids),
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.method = method
+
+	for inode, stat := range stats {
+		if !inodes[inode] {
+			continue
+		}
+		sample.Connections++
+		tracked, ok := pm.sockets[inode]
+		if !ok {
+			tracked = &socketTraffic{remote: stat.remote, loopback: stat.loopback}
+			pm.sockets[inode] = tracked
+		}
+		// Counters only grow for the life of a socket; keep the last value once it closes
+		tracked.rxBytes = max(tracked.rxBytes, stat.rxBytes)
+		tracked.txBytes = max(tracked.txBytes, stat.txBytes)
+	}
+
+	for _, tracked := range pm.sockets {
+		if tracked.loopback {
+			continue
+		}
+		sample.RxBytes += tracked.rxBytes
+		sample.TxBytes += tracked.txBytes
+	}
+
+	return sample, nil
+}
+
+func (pm *ProcessNetworkMonitor) calculateMetrics() ProcessNetworkMetrics {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	metrics := ProcessNetworkMetrics{
+		Method:           pm.method,
+		PID:              pm.pid,
+		Duration:         pm.stopTime.Sub(pm.startTime),
+		TotalConnections: len(pm.sockets),
+		Samples:          make([]ProcessNetworkSample, len(pm.samples)),
+	}
+	copy(metrics.Samples, pm.samples)
+
+	remotes := make(map[string]*RemoteTraffic)
+	for _, tracked := range pm.sockets {
+		if tracked.loopback {
+			metrics.LoopbackBytes += tracked.rxBytes + tracked.txBytes
+			continue
+		}
+		metrics.TotalRxBytes += tracked.rxBytes
+		metrics.TotalTxBytes += tracked.txBytes
+
+		remote, ok := remotes[tracked.remote]
+		if !ok {
+			remote = &RemoteTraffic{Address: tracked.remote}
+			remotes[tracked.remote] = remote
+		}
+		remote.RxBytes += tracked.rxBytes
+		remote.TxBytes += tracked.txBytes
+		remote.Connections++
+	}
+
+	metrics.Remotes = make([]RemoteTraffic, 0, len(remotes))
+	for _, remote := range remotes {
+		metrics.Remotes = append(metrics.Remotes, *remote)
+	}
+	sort.Slice(metrics.Remotes, func(i, j int) bool {
+		return metrics.Remotes[i].RxBytes+metrics.Remotes[i].TxBytes > metrics.Remotes[j].RxBytes+metrics.Remotes[j].TxBytes
+	})
+
+	for _, sample := range pm.samples {
+		metrics.PeakBandwidthMbps = max(metrics.PeakBandwidthMbps, sample.RxRate+sample.TxRate)
+		metrics.PeakConnections = max(metrics.PeakConnections, sample.Connections)
+	}
+	if seconds := metrics.Duration.Seconds(); seconds > 0 {
+		metrics.AverageRxRateMbps = float64(metrics.TotalRxBytes) * 8 / seconds / 1e6
+		metrics.AverageTxRateMbps = float64(metrics.TotalTxBytes) * 8 / seconds / 1e6
+	}
+
+	return metrics
+}
+
+// ToNetworkMetrics converts per-process totals into the interface metrics shape used in results
+func (pm *ProcessNetworkMetrics) ToNetworkMetrics() NetworkMetrics {
+	return NetworkMetrics{
+		AverageBandwidthMbps:  pm.AverageRxRateMbps + pm.AverageTxRateMbps,
+		PeakBandwidthMbps:     pm.PeakBandwidthMbps,
+		TotalBytesTransferred: pm.TotalRxBytes + pm.TotalTxBytes,
+		Duration:              pm.Duration,
+		AverageRxRateMbps:     pm.AverageRxRateMbps,
+		AverageTxRateMbps:     pm.AverageTxRateMbps,
+	}
+}
+
+// TxBytesToPort returns the bytes sent to remotes on the given port
+func (pm *ProcessNetworkMetrics) TxBytesToPort(port string) int64 {
+	var total int64
+	for _, remote := range pm.Remotes {
+		if _, remotePort, err := net.SplitHostPort(remote.Address); err == nil && remotePort == port {
+			total += remote.TxBytes
+		}
+	}
+	return total
+}
+
+// socketInodes returns the socket inodes held open by a process
+func socketInodes(pid int) []string {
+	fdDir := fmt.Sprintf("/proc/%d/fd", pid)
+	entries, err := os.ReadDir(fdDir)
+	if err != nil {
+		return nil
+	}
+	var inodes []string
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join(fdDir, entry.Name()))
+		if err != nil || !strings.HasPrefix(target, "socket:[") {
+			continue
+		}
+		inodes = append(inodes, strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]"))
+	}
+	return inodes
+}
+
+// readTCPInfo returns tcp_info byte counters for all TCP sockets, keyed by
+// inode, and where they were read from. sock_diag netlink is preferred; ss is
+// only run where the netlink socket is not permitted, e.g. by a seccomp profile.
+func readTCPInfo() (map[string]socketTraffic, string, error) {
+	stats, diagErr := readInetDiag()
+	if diagErr == nil {
+		return stats, ProcessNetworkInetDiag, nil
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command("ss", "-tieHn")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, "", fmt.Errorf("%v; failed to run ss: %w", diagErr, err)
+	}
+	return parseTCPInfo(&stdout), ProcessNetworkSS, nil
+}
+
+// parseTCPInfo parses `ss -tieHn` output: a socket line with the peer and inode,
+// followed by an indented tcp_info line with the byte counters
+func parseTCPInfo(output *bytes.Buffer) map[string]socketTraffic {
+	stats := make(map[string]socketTraffic)
+	var inode string
+	var current socketTraffic
+
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		if line[0] != ' ' && line[0] != '\t' {
+			// ESTAB 0 0 10.0.0.5:41234 10.0.0.9:8443 ... ino:41501 ...
+			fields := strings.Fields(line)
+			inode = ""
+			if len(fields) < 5 {
+				continue
+			}
+			current = socketTraffic{remote: fields[4]}
+			if host, _, err := net.SplitHostPort(fields[4]); err == nil {
+				if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+					current.loopback = ip.IsLoopback()
+				}
+			}
+			for _, field := range fields[5:] {
+				if strings.HasPrefix(field, "ino:") {
+					inode = strings.TrimPrefix(field, "ino:")
+				}
+			}
+			continue
+		}
+
+		if inode == "" || inode == "0" {
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			key, value, ok := strings.Cut(field, ":")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "bytes_received":
+				current.rxBytes, _ = strconv.ParseInt(value, 10, 64)
+			case "bytes_acked":
+				current.txBytes, _ = strconv.ParseInt(value, 10, 64)
+			}
+		}
+		stats[inode] = current
+		inode = ""
+	}
+	return stats
+}
@@ -3,7 +3,7 @@ package monitor
 import (
 	"context"
 	"fmt"
-	"os/exec"
+	"net"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,6 +15,7 @@ import (
 type RegistryMonitor struct {
 	registryHost   string
 	registryPort   string
+	registryIPs    []net.IP // Addresses of registryHost, resolved at start
 	startTime      time.Time
 	stopTime       time.Time
 	monitoring     bool
@@ -23,6 +24,7 @@ type RegistryMonitor struct {
 	pollInterval   time.Duration
 	initialTxBytes int64
 	interfaceName  string
+	onSample       SampleHandler
 }
 
 // RegistrySample represents a single measurement of bytes sent to registry
@@ -62,7 +64,7 @@ func NewRegistryMonitor(registryAddr string) *RegistryMonitor {
 		registryHost:  host,
 		registryPort:  port,
 		samples:       make([]RegistrySample, 0),
-		pollInterval:  1 * time.Second,
+		pollInterval:  DefaultPollInterval,
 		interfaceName: getDefaultInterface(),
 	}
 }
@@ -72,6 +74,11 @@ func (rm *RegistryMonitor) SetPollInterval(interval time.Duration) {
 	rm.pollInterval = interval
 }
 
+// SetSampleHandler sets a handler called with each sample
+func (rm *RegistryMonitor) SetSampleHandler(handler SampleHandler) {
+	rm.onSample = handler
+}
+
 // Start begins monitoring registry uploads
 func (rm *RegistryMonitor) Start() error {
 	rm.mu.Lock()
@@ -84,6 +91,7 @@ func (rm *RegistryMonitor) Start() error {
 	rm.startTime = time.Now()
 	rm.monitoring = true
 	rm.samples = make([]RegistrySample, 0)
+	rm.registryIPs = resolveHost(rm.registryHost)
 	
 	// Get initial TX bytes for the interface
 	rm.initialTxBytes = rm.getInterfaceTxBytes()
@@ -213,7 +221,7 @@ func (rm *RegistryMonitor) monitorLoop() {
 			currentTxBytes := rm.getInterfaceTxBytes()
 			currentTime := time.Now()
 			
-			// Also try to get registry-specific stats using netstat/ss
+			// Also count the connections open to the registry
 			connections := rm.getRegistryConnections()
 
 			bytesDelta := currentTxBytes - lastTxBytes
@@ -235,6 +243,9 @@ func (rm *RegistryMonitor) monitorLoop() {
 			rm.mu.Lock()
 			rm.samples = append(rm.samples, sample)
 			rm.mu.Unlock()
+			if rm.onSample != nil {
+				rm.onSample(sample)
+			}
 
 			lastTxBytes = currentTxBytes
 			lastSampleTime = currentTime
@@ -244,79 +255,42 @@ func (rm *RegistryMonitor) monitorLoop() {
 
 // getInterfaceTxBytes gets total TX bytes from the network interface
 func (rm *RegistryMonitor) getInterfaceTxBytes() int64 {
-	txPath := fmt.Sprintf("/sys/class/net/%s/statistics/tx_bytes", rm.interfaceName)
-	
-	cmd := exec.Command("cat", txPath)
-	output, err := cmd.Output()
-	if err != nil {
-		// Fallback to /proc/net/dev
-		return rm.getTxBytesFromProc()
-	}
-
-	if txBytes, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64); err == nil {
-		return txBytes
-	}
-
-	return rm.getTxBytesFromProc()
+	_, tx, _ := host.interfaceCounters(rm.interfaceName)
+	return tx
 }
 
-// getTxBytesFromProc gets TX bytes from /proc/net/dev
-func (rm *RegistryMonitor) getTxBytesFromProc() int64 {
-	cmd := exec.Command("cat", "/proc/net/dev")
-	output, err := cmd.Output()
+// getRegistryConnections gets the number of established connections to the registry
+func (rm *RegistryMonitor) getRegistryConnections() int {
+	connections, err := host.tcpConnections()
 	if err != nil {
 		return 0
 	}
-
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, rm.interfaceName+":") {
-			parts := strings.Fields(line)
-			if len(parts) >= 10 {
-				// Format: interface: rx_bytes rx_packets ... tx_bytes tx_packets ...
-				if txBytes, err := strconv.ParseInt(parts[9], 10, 64); err == nil {
-					return txBytes
-				}
-			}
-			break
+	port, _ := strconv.Atoi(rm.registryPort)
+	count := 0
+	for _, conn := range connections {
+		if !conn.established || conn.remotePort != port {
+			continue
 		}
-	}
-
-	return 0
-}
-
-// getRegistryConnections gets the number of active connections to the registry
-func (rm *RegistryMonitor) getRegistryConnections() int {
-	// Try using 'ss' command first (more modern)
-	cmd := exec.Command("sh", "-c", fmt.Sprintf("ss -tn state established 2>/dev/null | grep %s:%s", rm.registryHost, rm.registryPort))
-	output, err := cmd.Output()
-	if err == nil {
-		lines := strings.Split(string(output), "\n")
-		count := 0
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if line != "" && strings.Contains(line, rm.registryHost+":"+rm.registryPort) {
+		for _, ip := range rm.registryIPs {
+			if ip.Equal(conn.remote) {
 				count++
+				break
 			}
 		}
-		return count
 	}
+	return count
+}
 
-	// Fallback to netstat
-	cmd = exec.Command("sh", "-c", fmt.Sprintf("netstat -tn 2>/dev/null | grep %s:%s", rm.registryHost, rm.registryPort))
-	output, err = cmd.Output()
-	if err == nil {
-		lines := strings.Split(string(output), "\n")
-		count := 0
-		for _, line := range lines {
-			if strings.Contains(line, rm.registryHost+":"+rm.registryPort) {
-				count++
-			}
-		}
-		return count
+// resolveHost returns the addresses of a registry host name or literal IP
+func resolveHost(name string) []net.IP {
+	if ip := net.ParseIP(strings.Trim(name, "[]")); ip != nil {
+		return []net.IP{ip}
 	}
-
-	return 0
+	addrs, err := net.LookupIP(name)
+	if err != nil {
+		return nil
+	}
+	return addrs
 }
 
 func (rm *RegistryMonitor) calculateMetrics() RegistryMetrics {
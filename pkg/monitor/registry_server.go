@@ -0,0 +1,610 @@
+package monitor
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// EnvRegistryMetricsToken is a bearer token sent when scraping registry metrics
+const EnvRegistryMetricsToken = "OC_MIRROR_TEST_REGISTRY_METRICS_TOKEN"
+
+// DefaultRegistryScrapeInterval is the scrape interval of the registry metrics
+// endpoint; registries update their counters on every request, but scraping
+// them is not free
+const DefaultRegistryScrapeInterval = 5 * time.Second
+
+// Prometheus metric families the registry server metrics are read from
+var (
+	// Requests served, with the status in a code or status label
+	registryRequestFamilies = []string{
+		"registry_http_requests_total",        // distribution, and the registry of Harbor
+		"harbor_core_http_request_total",      // Harbor core API
+		"quay_request_duration_seconds_count", // Quay
+	}
+	// Storage driver operations, with the operation in an action label
+	registryStorageFamilies = []string{
+		"registry_storage_action_seconds_count", // distribution and Harbor
+	}
+	// Requests being served
+	registryInFlightFamilies = []string{
+		"registry_http_in_flight_requests", // distribution and Harbor
+	}
+)
+
+// RegistryServerConfig describes the Prometheus endpoint of the target
+// registry scraped during uploads
+type RegistryServerConfig struct {
+	URL      string        `json:"url,omitempty" yaml:"url,omitempty"` // e.g. http://registry:5001/metrics
+	Interval time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+	SkipTLS  bool          `json:"-" yaml:"-"`
+	Token    string        `json:"-" yaml:"-"` // Bearer token, from EnvRegistryMetricsToken
+}
+
+// Enabled returns true if a metrics endpoint is configured
+func (c RegistryServerConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+// ScrapeInterval returns the configured interval, or the default
+func (c RegistryServerConfig) ScrapeInterval() time.Duration {
+	if c.Interval <= 0 {
+		return DefaultRegistryScrapeInterval
+	}
+	return c.Interval
+}
+
+// Validate checks the endpoint URL and scrape interval
+func (c RegistryServerConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid registry metrics URL %q (expected http(s)://host:port/metrics)", c.URL)
+	}
+	if c.Interval < 0 {
+		return fmt.Errorf("registry metrics interval must not be negative")
+	}
+	return nil
+}
+
+// RegistryServerSample is the server-side view of one scrape interval: rates
+// are computed from the counter increases since the previous scrape
+type RegistryServerSample struct {
+	Timestamp       time.Time `json:"Timestamp"`
+	RequestRate     float64   `json:"RequestRate"`     // Requests per second
+	ClientErrorRate float64   `json:"ClientErrorRate"` // 4xx per second, 429 included
+	ServerErrorRate float64   `json:"ServerErrorRate"` // 5xx per second
+	StorageOpsRate  float64   `json:"StorageOpsRate"`  // Storage driver operations per second
+	InFlight        float64   `json:"InFlight"`        // Requests being served at the scrape
+}
+
+// RegistryServerSlowWindow compares what the registry did while the client
+// upload was slowest to the whole upload
+type RegistryServerSlowWindow struct {
+	ClientSamples      int     `json:"ClientSamples"`   // Slowest decile of the client upload rate samples
+	ClientRateMB       float64 `json:"ClientRateMB"`    // Mean client upload rate in the slow window
+	AvgClientRateMB    float64 `json:"AvgClientRateMB"` // Mean client upload rate over the upload
+	RequestRate        float64 `json:"RequestRate"`     // Mean server values in the slow window...
+	ServerErrorRate    float64 `json:"ServerErrorRate"`
+	StorageOpsRate     float64 `json:"StorageOpsRate"`
+	InFlight           float64 `json:"InFlight"`
+	AvgRequestRate     float64 `json:"AvgRequestRate"` // ...and over the upload
+	AvgServerErrorRate float64 `json:"AvgServerErrorRate"`
+	AvgStorageOpsRate  float64 `json:"AvgStorageOpsRate"`
+	AvgInFlight        float64 `json:"AvgInFlight"`
+}
+
+// RegistryServerMetrics is the registry server activity during a phase, as
+// reported by the registry's own metrics endpoint
+type RegistryServerMetrics struct {
+	URL                 string                    `json:"URL"`
+	StartTime           time.Time                 `json:"StartTime"`
+	Duration            time.Duration             `json:"Duration"`
+	Scrapes             int                       `json:"Scrapes"`
+	ScrapeErrors        int                       `json:"ScrapeErrors"`
+	LastError           string                    `json:"LastError,omitempty"`
+	Families            []string                  `json:"Families,omitempty"` // Metric families found on the endpoint
+	Requests            float64                   `json:"Requests"`           // Counter increases over the phase
+	ClientErrors        float64                   `json:"ClientErrors"`
+	ServerErrors        float64                   `json:"ServerErrors"`
+	StorageOps          float64                   `json:"StorageOps"`
+	StorageOpsByAction  map[string]float64        `json:"StorageOpsByAction,omitempty"`
+	AvgRequestRate      float64                   `json:"AvgRequestRate"`
+	PeakRequestRate     float64                   `json:"PeakRequestRate"`
+	PeakServerErrorRate float64                   `json:"PeakServerErrorRate"`
+	PeakStorageOpsRate  float64                   `json:"PeakStorageOpsRate"`
+	PeakInFlight        float64                   `json:"PeakInFlight"`
+	SlowWindow          *RegistryServerSlowWindow `json:"SlowWindow,omitempty"` // Set by Correlate
+	Samples             []RegistryServerSample    `json:"Samples"`
+}
+
+// registryCounters are the totals read from one scrape
+type registryCounters struct {
+	timestamp    time.Time
+	requests     float64
+	clientErrors float64
+	serverErrors float64
+	storageOps   float64
+	byAction     map[string]float64
+	inFlight     float64
+	families     map[string]bool
+}
+
+// RegistryServerMonitor scrapes the Prometheus endpoint of the target
+// registry (distribution, Harbor or Quay) while a phase runs
+type RegistryServerMonitor struct {
+	config     RegistryServerConfig
+	client     *http.Client
+	startTime  time.Time
+	stopTime   time.Time
+	monitoring bool
+	baseline   *registryCounters
+	last       *registryCounters
+	samples    []RegistryServerSample
+	totals     registryCounters // Counter increases since the baseline
+	scrapes    int
+	errors     int
+	lastError  string
+	families   map[string]bool
+	mu         sync.RWMutex
+	stop       chan struct{}
+	done       chan struct{}
+	onSample   SampleHandler
+}
+
+// NewRegistryServerMonitor creates a monitor for the metrics endpoint of config
+func NewRegistryServerMonitor(config RegistryServerConfig) *RegistryServerMonitor {
+	config.Interval = config.ScrapeInterval()
+	if config.Token == "" {
+		config.Token = os.Getenv(EnvRegistryMetricsToken)
+	}
+	return &RegistryServerMonitor{
+		config: config,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: config.SkipTLS}},
+		},
+		families: make(map[string]bool),
+	}
+}
+
+// SetPollInterval sets the scrape interval
+func (rs *RegistryServerMonitor) SetPollInterval(interval time.Duration) {
+	rs.config.Interval = interval
+}
+
+// GetPollInterval returns the scrape interval
+func (rs *RegistryServerMonitor) GetPollInterval() time.Duration {
+	return rs.config.Interval
+}
+
+// SetSampleHandler sets a handler called with each sample
+func (rs *RegistryServerMonitor) SetSampleHandler(handler SampleHandler) {
+	rs.onSample = handler
+}
+
+// Start takes the baseline scrape and begins scraping. An unreachable
+// endpoint fails Start, so a wrong URL is reported before the upload.
+func (rs *RegistryServerMonitor) Start() error {
+	baseline, err := rs.scrape()
+	if err != nil {
+		return fmt.Errorf("failed to scrape registry metrics: %w", err)
+	}
+	if baseline.families == nil {
+		return fmt.Errorf("no registry metrics found at %s", rs.config.URL)
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.startTime = baseline.timestamp
+	rs.baseline = baseline
+	rs.last = baseline
+	rs.scrapes = 1
+	for family := range baseline.families {
+		rs.families[family] = true
+	}
+	rs.monitoring = true
+	rs.stop = make(chan struct{})
+	rs.done = make(chan struct{})
+	go rs.monitorLoop()
+	return nil
+}
+
+// Stop takes a final scrape, so the totals cover the whole phase, and
+// returns the collected metrics
+func (rs *RegistryServerMonitor) Stop() RegistryServerMetrics {
+	rs.mu.Lock()
+	wasMonitoring := rs.monitoring
+	rs.monitoring = false
+	rs.mu.Unlock()
+
+	if wasMonitoring {
+		close(rs.stop)
+		<-rs.done
+		rs.record()
+	}
+
+	rs.mu.Lock()
+	rs.stopTime = time.Now()
+	rs.mu.Unlock()
+	return rs.calculateMetrics()
+}
+
+// StopInterface implements Monitor interface
+func (rs *RegistryServerMonitor) StopInterface() interface{} {
+	return rs.Stop()
+}
+
+// IsMonitoring implements Monitor interface
+func (rs *RegistryServerMonitor) IsMonitoring() bool {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return rs.monitoring
+}
+
+// GetDuration implements Monitor interface
+func (rs *RegistryServerMonitor) GetDuration() time.Duration {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	if !rs.monitoring {
+		return rs.stopTime.Sub(rs.startTime)
+	}
+	return time.Since(rs.startTime)
+}
+
+func (rs *RegistryServerMonitor) monitorLoop() {
+	defer close(rs.done)
+	ticker := time.NewTicker(rs.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rs.stop:
+			return
+		case <-ticker.C:
+			rs.record()
+		}
+	}
+}
+
+// record scrapes the endpoint and appends the rates since the last scrape
+func (rs *RegistryServerMonitor) record() {
+	current, err := rs.scrape()
+
+	rs.mu.Lock()
+	rs.scrapes++
+	if err != nil {
+		rs.errors++
+		rs.lastError = err.Error()
+		rs.mu.Unlock()
+		return
+	}
+	for family := range current.families {
+		rs.families[family] = true
+	}
+	sample := registryServerRates(rs.last, current)
+	rs.totals.requests += counterIncrease(rs.last.requests, current.requests)
+	rs.totals.clientErrors += counterIncrease(rs.last.clientErrors, current.clientErrors)
+	rs.totals.serverErrors += counterIncrease(rs.last.serverErrors, current.serverErrors)
+	rs.totals.storageOps += counterIncrease(rs.last.storageOps, current.storageOps)
+	rs.samples = append(rs.samples, sample)
+	rs.last = current
+	rs.mu.Unlock()
+
+	if rs.onSample != nil {
+		rs.onSample(sample)
+	}
+}
+
+// scrape reads the counters from the metrics endpoint
+func (rs *RegistryServerMonitor) scrape() (*registryCounters, error) {
+	req, err := http.NewRequest(http.MethodGet, rs.config.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+	if rs.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+rs.config.Token)
+	}
+	resp, err := rs.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", rs.config.URL, resp.Status)
+	}
+	return parseRegistryCounters(resp.Body, time.Now())
+}
+
+// parseRegistryCounters sums the known registry families of a Prometheus
+// text exposition
+func parseRegistryCounters(r io.Reader, at time.Time) (*registryCounters, error) {
+	counters := &registryCounters{timestamp: at, byAction: make(map[string]float64)}
+	err := parsePrometheusText(r, func(name string, labels map[string]string, value float64) {
+		switch {
+		case containsString(registryRequestFamilies, name):
+			counters.requests += value
+			code := labels["code"]
+			if code == "" {
+				code = labels["status"]
+			}
+			switch {
+			case strings.HasPrefix(code, "4"):
+				counters.clientErrors += value
+			case strings.HasPrefix(code, "5"):
+				counters.serverErrors += value
+			}
+		case containsString(registryStorageFamilies, name):
+			counters.storageOps += value
+			if action := labels["action"]; action != "" {
+				counters.byAction[action] += value
+			}
+		case containsString(registryInFlightFamilies, name):
+			counters.inFlight += value
+		default:
+			return
+		}
+		if counters.families == nil {
+			counters.families = make(map[string]bool)
+		}
+		counters.families[name] = true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counters, nil
+}
+
+// parsePrometheusText calls sample for every sample line of a Prometheus text
+// exposition: name{label="value",...} value [timestamp]
+func parsePrometheusText(r io.Reader, sample func(name string, labels map[string]string, value float64)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, labels, rest, ok := splitPrometheusLine(line)
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		sample(name, labels, value)
+	}
+	return scanner.Err()
+}
+
+// splitPrometheusLine splits a sample line into its name, labels and the
+// value and timestamp that follow
+func splitPrometheusLine(line string) (string, map[string]string, string, bool) {
+	brace := strings.IndexByte(line, '{')
+	space := strings.IndexAny(line, " \t")
+	if brace < 0 || (space >= 0 && space < brace) {
+		if space < 0 {
+			return "", nil, "", false
+		}
+		return line[:space], nil, line[space:], true
+	}
+
+	name := line[:brace]
+	labels := make(map[string]string)
+	i := brace + 1
+	for i < len(line) {
+		for i < len(line) && (line[i] == ' ' || line[i] == ',') {
+			i++
+		}
+		if i < len(line) && line[i] == '}' {
+			return name, labels, line[i+1:], true
+		}
+		eq := strings.IndexByte(line[i:], '=')
+		if eq < 0 || i+eq+1 >= len(line) || line[i+eq+1] != '"' {
+			return "", nil, "", false
+		}
+		key := strings.TrimSpace(line[i : i+eq])
+		i += eq + 2
+		var value strings.Builder
+		for ; i < len(line) && line[i] != '"'; i++ {
+			if line[i] == '\\' && i+1 < len(line) {
+				i++
+				if line[i] == 'n' {
+					value.WriteByte('\n')
+					continue
+				}
+			}
+			value.WriteByte(line[i])
+		}
+		if i >= len(line) {
+			return "", nil, "", false
+		}
+		labels[key] = value.String()
+		i++ // Closing quote
+	}
+	return "", nil, "", false
+}
+
+// registryServerRates converts the counter increases between two scrapes into
+// rates. A counter that went down was reset by a registry restart and counts
+// from zero.
+func registryServerRates(previous, current *registryCounters) RegistryServerSample {
+	sample := RegistryServerSample{Timestamp: current.timestamp, InFlight: current.inFlight}
+	elapsed := current.timestamp.Sub(previous.timestamp).Seconds()
+	if elapsed <= 0 {
+		return sample
+	}
+	sample.RequestRate = counterIncrease(previous.requests, current.requests) / elapsed
+	sample.ClientErrorRate = counterIncrease(previous.clientErrors, current.clientErrors) / elapsed
+	sample.ServerErrorRate = counterIncrease(previous.serverErrors, current.serverErrors) / elapsed
+	sample.StorageOpsRate = counterIncrease(previous.storageOps, current.storageOps) / elapsed
+	return sample
+}
+
+// counterIncrease returns how much a counter grew, handling resets
+func counterIncrease(previous, current float64) float64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+func (rs *RegistryServerMonitor) calculateMetrics() RegistryServerMetrics {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	metrics := RegistryServerMetrics{
+		URL:          rs.config.URL,
+		StartTime:    rs.startTime,
+		Duration:     rs.stopTime.Sub(rs.startTime),
+		Scrapes:      rs.scrapes,
+		ScrapeErrors: rs.errors,
+		LastError:    rs.lastError,
+		Samples:      make([]RegistryServerSample, len(rs.samples)),
+	}
+	copy(metrics.Samples, rs.samples)
+	for family := range rs.families {
+		metrics.Families = append(metrics.Families, family)
+	}
+	sort.Strings(metrics.Families)
+
+	// Totals sum the increases between scrapes, so registry restarts do not lose counts
+	metrics.Requests = rs.totals.requests
+	metrics.ClientErrors = rs.totals.clientErrors
+	metrics.ServerErrors = rs.totals.serverErrors
+	metrics.StorageOps = rs.totals.storageOps
+	for _, sample := range rs.samples {
+		metrics.PeakRequestRate = max(metrics.PeakRequestRate, sample.RequestRate)
+		metrics.PeakServerErrorRate = max(metrics.PeakServerErrorRate, sample.ServerErrorRate)
+		metrics.PeakStorageOpsRate = max(metrics.PeakStorageOpsRate, sample.StorageOpsRate)
+		metrics.PeakInFlight = max(metrics.PeakInFlight, sample.InFlight)
+	}
+	if rs.baseline != nil && rs.last != nil {
+		for action, value := range rs.last.byAction {
+			if increase := counterIncrease(rs.baseline.byAction[action], value); increase > 0 {
+				if metrics.StorageOpsByAction == nil {
+					metrics.StorageOpsByAction = make(map[string]float64)
+				}
+				metrics.StorageOpsByAction[action] = increase
+			}
+		}
+		if elapsed := rs.last.timestamp.Sub(rs.baseline.timestamp).Seconds(); elapsed > 0 {
+			metrics.AvgRequestRate = metrics.Requests / elapsed
+		}
+	}
+	return metrics
+}
+
+// Correlate compares the server activity while the client upload was slowest,
+// the slowest decile of the client upload rate samples, to the whole upload.
+// Each client sample is matched with the server sample covering it.
+func (m *RegistryServerMetrics) Correlate(client []RegistrySample) {
+	if m == nil || len(m.Samples) == 0 {
+		return
+	}
+	var inPhase []RegistrySample
+	start, end := m.StartTime, m.StartTime.Add(m.Duration)
+	for _, sample := range client {
+		if !sample.Timestamp.Before(start) && !sample.Timestamp.After(end) {
+			inPhase = append(inPhase, sample)
+		}
+	}
+	if len(inPhase) < 10 {
+		return
+	}
+
+	window := &RegistryServerSlowWindow{}
+	for _, sample := range inPhase {
+		window.AvgClientRateMB += sample.UploadRateMB
+	}
+	window.AvgClientRateMB /= float64(len(inPhase))
+	for _, sample := range m.Samples {
+		window.AvgRequestRate += sample.RequestRate
+		window.AvgServerErrorRate += sample.ServerErrorRate
+		window.AvgStorageOpsRate += sample.StorageOpsRate
+		window.AvgInFlight += sample.InFlight
+	}
+	n := float64(len(m.Samples))
+	window.AvgRequestRate /= n
+	window.AvgServerErrorRate /= n
+	window.AvgStorageOpsRate /= n
+	window.AvgInFlight /= n
+
+	sort.Slice(inPhase, func(i, j int) bool { return inPhase[i].UploadRateMB < inPhase[j].UploadRateMB })
+	slow := inPhase[:len(inPhase)/10]
+	window.ClientSamples = len(slow)
+	for _, sample := range slow {
+		window.ClientRateMB += sample.UploadRateMB
+		server := m.serverSampleAt(sample.Timestamp)
+		window.RequestRate += server.RequestRate
+		window.ServerErrorRate += server.ServerErrorRate
+		window.StorageOpsRate += server.StorageOpsRate
+		window.InFlight += server.InFlight
+	}
+	n = float64(len(slow))
+	window.ClientRateMB /= n
+	window.RequestRate /= n
+	window.ServerErrorRate /= n
+	window.StorageOpsRate /= n
+	window.InFlight /= n
+	m.SlowWindow = window
+}
+
+// serverSampleAt returns the server sample whose scrape interval covers at:
+// the first sample taken at or after it
+func (m *RegistryServerMetrics) serverSampleAt(at time.Time) RegistryServerSample {
+	i := sort.Search(len(m.Samples), func(i int) bool { return !m.Samples[i].Timestamp.Before(at) })
+	if i == len(m.Samples) {
+		i--
+	}
+	return m.Samples[i]
+}
+
+// PrintSummary prints the server-side view of the upload
+func (m *RegistryServerMetrics) PrintSummary() {
+	if m == nil {
+		return
+	}
+	logging.Printf("  │ Registry Server: %.0f requests (avg %.1f/s, peak %.1f/s), %.0f 4xx, %.0f 5xx, %.0f storage ops, peak %.0f in flight\n",
+		m.Requests, m.AvgRequestRate, m.PeakRequestRate, m.ClientErrors, m.ServerErrors, m.StorageOps, m.PeakInFlight)
+	if m.ServerErrors > 0 {
+		logging.Printf("  │ Warning: The registry reported %.0f server errors (peak %.1f/s) during the upload\n", m.ServerErrors, m.PeakServerErrorRate)
+	}
+	if m.ScrapeErrors > 0 {
+		logging.Printf("  │ Warning: %d of %d registry metrics scrapes failed: %s\n", m.ScrapeErrors, m.Scrapes, m.LastError)
+	}
+	if w := m.SlowWindow; w != nil {
+		logging.Printf("  │ Slowest 10%% of the upload (%.2f vs %.2f MB/s): registry %.1f req/s (avg %.1f), %.2f 5xx/s (avg %.2f), %.1f storage ops/s (avg %.1f), %.0f in flight (avg %.0f)\n",
+			w.ClientRateMB, w.AvgClientRateMB, w.RequestRate, w.AvgRequestRate, w.ServerErrorRate, w.AvgServerErrorRate,
+			w.StorageOpsRate, w.AvgStorageOpsRate, w.InFlight, w.AvgInFlight)
+	}
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
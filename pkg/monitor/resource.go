@@ -1,19 +1,19 @@
 package monitor
 
 import (
-	"bufio"
 	"context"
-	"fmt"
 	"os"
 	"runtime"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
 )
 
 // ResourceMonitor monitors CPU and memory usage during operations
 type ResourceMonitor struct {
+	adaptivePoller
+
 	startTime    time.Time
 	stopTime     time.Time
 	monitoring   bool
@@ -21,6 +21,10 @@ type ResourceMonitor struct {
 	mu           sync.RWMutex
 	pollInterval time.Duration
 	pid          int
+	onSample     SampleHandler
+	scope        string           // Requested scope, process by default
+	measured     string           // Scope sampled, tree when no cgroup could be created
+	cgroup       *transientCgroup // Set in cgroup scope
 }
 
 // ResourceSample represents a single resource measurement
@@ -32,6 +36,7 @@ type ResourceSample struct {
 	MemoryPercent float64   `json:"MemoryPercent"` // Memory usage percentage
 	NumGoroutines int       `json:"NumGoroutines"` // Number of goroutines (Go-specific)
 	NumThreads    int       `json:"NumThreads"`    // Number of OS threads
+	NumProcesses  int       `json:"NumProcesses,omitempty"` // Processes in the tree or cgroup scope
 }
 
 // ResourceMetrics represents aggregated resource metrics
@@ -46,6 +51,8 @@ type ResourceMetrics struct {
 	PeakGoroutines int                `json:"PeakGoroutines"`
 	AvgThreads     float64            `json:"AvgThreads"`
 	PeakThreads    int                `json:"PeakThreads"`
+	PeakProcesses  int                `json:"PeakProcesses,omitempty"`
+	Scope          string             `json:"Scope,omitempty"` // process, tree or cgroup
 	Samples        []ResourceSample   `json:"Samples"`
 	SampleCount    int                `json:"SampleCount"`
 }
@@ -54,7 +61,7 @@ type ResourceMetrics struct {
 func NewResourceMonitor() *ResourceMonitor {
 	return &ResourceMonitor{
 		samples:      make([]ResourceSample, 0),
-		pollInterval: 1 * time.Second,
+		pollInterval: DefaultPollInterval,
 		pid:          os.Getpid(),
 	}
 }
@@ -63,7 +70,7 @@ func NewResourceMonitor() *ResourceMonitor {
 func NewResourceMonitorForPID(pid int) *ResourceMonitor {
 	return &ResourceMonitor{
 		samples:      make([]ResourceSample, 0),
-		pollInterval: 1 * time.Second,
+		pollInterval: DefaultPollInterval,
 		pid:          pid,
 	}
 }
@@ -82,11 +89,24 @@ func (rm *ResourceMonitor) GetTargetPID() int {
 	return rm.pid
 }
 
+// SetScope sets which processes are measured: the target process (default),
+// its process tree, or a transient cgroup the target is moved into on Start
+func (rm *ResourceMonitor) SetScope(scope string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.scope = scope
+}
+
 // SetPollInterval sets the polling interval for monitoring
 func (rm *ResourceMonitor) SetPollInterval(interval time.Duration) {
 	rm.pollInterval = interval
 }
 
+// SetSampleHandler sets a handler called with each sample
+func (rm *ResourceMonitor) SetSampleHandler(handler SampleHandler) {
+	rm.onSample = handler
+}
+
 // Start begins resource monitoring
 func (rm *ResourceMonitor) Start() error {
 	rm.mu.Lock()
@@ -99,7 +119,17 @@ func (rm *ResourceMonitor) Start() error {
 	rm.startTime = time.Now()
 	rm.monitoring = true
 	rm.samples = make([]ResourceSample, 0)
+	rm.measured = rm.scope
+	if rm.scope == ResourceScopeCgroup {
+		cg, err := newTransientCgroup(rm.pid)
+		if err != nil {
+			logging.Printf("  │ Warning: cgroup resource monitoring unavailable, summing the process tree instead: %v\n", err)
+			rm.measured = ResourceScopeTree
+		}
+		rm.cgroup = cg
+	}
 
+	rm.startPoller(rm.pollInterval)
 	go rm.monitorLoop()
 
 	return nil
@@ -109,6 +139,7 @@ func (rm *ResourceMonitor) Start() error {
 func (rm *ResourceMonitor) Stop() ResourceMetrics {
 	rm.mu.Lock()
 	rm.monitoring = false
+	rm.flushPoller()
 	rm.stopTime = time.Now()
 	rm.mu.Unlock()
 
@@ -117,7 +148,13 @@ func (rm *ResourceMonitor) Stop() ResourceMetrics {
 	<-ctx.Done()
 	cancel()
 
-	return rm.calculateMetrics()
+	metrics := rm.calculateMetrics()
+	if rm.cgroup != nil {
+		if err := rm.cgroup.remove(); err != nil {
+			logging.Printf("  │ Warning: %v\n", err)
+		}
+	}
+	return metrics
 }
 
 // StopInterface implements Monitor interface
@@ -148,11 +185,11 @@ func (rm *ResourceMonitor) GetPollInterval() time.Duration {
 }
 
 func (rm *ResourceMonitor) monitorLoop() {
-	ticker := time.NewTicker(rm.pollInterval)
+	ticker := rm.poller
 	defer ticker.Stop()
 
 	// Get initial CPU times for delta calculation
-	lastCPUTime := rm.getCPUTime()
+	lastCPUTime := rm.readUsage().cpuSeconds
 	lastSampleTime := time.Now()
 
 	for {
@@ -167,10 +204,12 @@ func (rm *ResourceMonitor) monitorLoop() {
 		select {
 		case <-ticker.C:
 			currentTime := time.Now()
-			currentCPUTime := rm.getCPUTime()
+			usage := rm.readUsage()
+			currentCPUTime := usage.cpuSeconds
 
-			// Calculate CPU percentage
-			cpuDelta := currentCPUTime - lastCPUTime
+			// Calculate CPU percentage; a tree loses the CPU time of a
+			// child that exits until its parent reaps it
+			cpuDelta := max(currentCPUTime-lastCPUTime, 0)
 			timeDelta := currentTime.Sub(lastSampleTime).Seconds()
 			cpuPercent := 0.0
 			if timeDelta > 0 {
@@ -179,7 +218,7 @@ func (rm *ResourceMonitor) monitorLoop() {
 				cpuPercent = (cpuDelta / timeDelta) * 100.0 / float64(runtime.NumCPU())
 			}
 
-			memRSS, memVMS := rm.getMemoryUsage()
+			memRSS, memVMS := usage.rss, usage.vms
 			memPercent := rm.getMemoryPercent(memRSS)
 
 			sample := ResourceSample{
@@ -189,12 +228,16 @@ func (rm *ResourceMonitor) monitorLoop() {
 				MemoryVMS:     memVMS,
 				MemoryPercent: memPercent,
 				NumGoroutines: runtime.NumGoroutine(),
-				NumThreads:    rm.getThreadCount(),
+				NumThreads:    usage.threads,
+				NumProcesses:  usage.processes,
 			}
 
 			rm.mu.Lock()
 			rm.samples = append(rm.samples, sample)
 			rm.mu.Unlock()
+			if rm.onSample != nil {
+				rm.onSample(sample)
+			}
 
 			lastCPUTime = currentCPUTime
 			lastSampleTime = currentTime
@@ -202,105 +245,23 @@ func (rm *ResourceMonitor) monitorLoop() {
 	}
 }
 
-// getCPUTime reads CPU time from /proc/[pid]/stat
-func (rm *ResourceMonitor) getCPUTime() float64 {
-	statPath := fmt.Sprintf("/proc/%d/stat", rm.pid)
-	data, err := os.ReadFile(statPath)
-	if err != nil {
-		return 0
-	}
-
-	fields := strings.Fields(string(data))
-	if len(fields) < 15 {
-		return 0
-	}
-
-	// Fields 14 and 15 are utime and stime (user and system CPU time)
-	utime, _ := strconv.ParseFloat(fields[13], 64)
-	stime, _ := strconv.ParseFloat(fields[14], 64)
-
-	// Convert from clock ticks to seconds (assuming 100 Hz)
-	return (utime + stime) / 100.0
-}
-
-// getMemoryUsage reads memory usage from /proc/[pid]/status
-func (rm *ResourceMonitor) getMemoryUsage() (rss int64, vms int64) {
-	statusPath := fmt.Sprintf("/proc/%d/status", rm.pid)
-	file, err := os.Open(statusPath)
-	if err != nil {
-		return 0, 0
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "VmRSS:") {
-			fields := strings.Fields(line)
-			if len(fields) >= 2 {
-				val, _ := strconv.ParseInt(fields[1], 10, 64)
-				rss = val * 1024 // Convert from KB to bytes
-			}
-		} else if strings.HasPrefix(line, "VmSize:") {
-			fields := strings.Fields(line)
-			if len(fields) >= 2 {
-				val, _ := strconv.ParseInt(fields[1], 10, 64)
-				vms = val * 1024 // Convert from KB to bytes
-			}
-		}
+// readUsage reads the CPU time, memory and threads of the measured scope
+func (rm *ResourceMonitor) readUsage() resourceUsage {
+	switch {
+	case rm.cgroup != nil:
+		return rm.cgroup.read()
+	case rm.measured == ResourceScopeTree:
+		return procUsage(host.processTree(rm.pid), true)
 	}
-
-	return rss, vms
+	usage, _ := host.processUsage(rm.pid, false)
+	return usage
 }
 
 // getMemoryPercent calculates memory usage as percentage of total system memory
 func (rm *ResourceMonitor) getMemoryPercent(rss int64) float64 {
-	file, err := os.Open("/proc/meminfo")
-	if err != nil {
-		return 0
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "MemTotal:") {
-			fields := strings.Fields(line)
-			if len(fields) >= 2 {
-				total, _ := strconv.ParseInt(fields[1], 10, 64)
-				totalBytes := total * 1024
-				if totalBytes > 0 {
-					return float64(rss) / float64(totalBytes) * 100.0
-				}
-			}
-			break
-		}
+	if total := host.totalMemory(); total > 0 {
+		return float64(rss) / float64(total) * 100.0
 	}
-
-	return 0
-}
-
-// getThreadCount reads thread count from /proc/[pid]/status
-func (rm *ResourceMonitor) getThreadCount() int {
-	statusPath := fmt.Sprintf("/proc/%d/status", rm.pid)
-	file, err := os.Open(statusPath)
-	if err != nil {
-		return 0
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "Threads:") {
-			fields := strings.Fields(line)
-			if len(fields) >= 2 {
-				count, _ := strconv.Atoi(fields[1])
-				return count
-			}
-		}
-	}
-
 	return 0
 }
 
@@ -312,6 +273,7 @@ func (rm *ResourceMonitor) calculateMetrics() ResourceMetrics {
 		Duration:    rm.stopTime.Sub(rm.startTime),
 		Samples:     make([]ResourceSample, len(rm.samples)),
 		SampleCount: len(rm.samples),
+		Scope:       rm.measured,
 	}
 
 	copy(metrics.Samples, rm.samples)
@@ -341,6 +303,9 @@ func (rm *ResourceMonitor) calculateMetrics() ResourceMetrics {
 		if sample.NumThreads > metrics.PeakThreads {
 			metrics.PeakThreads = sample.NumThreads
 		}
+		if sample.NumProcesses > metrics.PeakProcesses {
+			metrics.PeakProcesses = sample.NumProcesses
+		}
 	}
 
 	count := float64(len(rm.samples))
@@ -0,0 +1,167 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Resource scopes: which processes the CPU and memory of a ResourceMonitor cover
+const (
+	ResourceScopeProcess = "process" // The monitored process only
+	ResourceScopeTree    = "tree"    // The process and its descendants, summed per process
+	ResourceScopeCgroup  = "cgroup"  // A transient cgroup v2 the process is moved into when it starts
+)
+
+// cgroupRoot is where the cgroup v2 hierarchy is mounted
+const cgroupRoot = "/sys/fs/cgroup"
+
+// resourceUsage is one reading of the monitored processes
+type resourceUsage struct {
+	cpuSeconds float64 // Cumulative CPU time
+	rss        int64
+	vms        int64
+	threads    int
+	processes  int
+}
+
+// transientCgroup is a cgroup v2 created for one monitored process. Processes
+// it starts inherit the cgroup, so its counters cover the whole tree,
+// including children that already exited.
+type transientCgroup struct {
+	path string
+}
+
+// CgroupAvailable reports whether a transient cgroup can be created on this host
+func CgroupAvailable() error {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return fmt.Errorf("cgroup v2 is not mounted at %s: %w", cgroupRoot, err)
+	}
+	parent, err := ownCgroup()
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(parent, "cgroup.procs"), os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("cgroup %s is not writable: %w", parent, err)
+	}
+	return file.Close()
+}
+
+// ownCgroup returns the directory of the cgroup v2 this process runs in
+func ownCgroup() (string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", fmt.Errorf("failed to read /proc/self/cgroup: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return filepath.Join(cgroupRoot, path), nil
+		}
+	}
+	return "", fmt.Errorf("no cgroup v2 entry in /proc/self/cgroup")
+}
+
+// newTransientCgroup creates a cgroup below the one of this process and moves
+// pid into it. Children pid started before the move stay where they are.
+func newTransientCgroup(pid int) (*transientCgroup, error) {
+	if err := CgroupAvailable(); err != nil {
+		return nil, err
+	}
+	parent, _ := ownCgroup()
+
+	// Delegate the memory and cpu controllers so the child has memory.stat;
+	// this fails when the parent holds processes and is not the root
+	controllers, _ := os.ReadFile(filepath.Join(parent, "cgroup.subtree_control"))
+	if !strings.Contains(string(controllers), "memory") {
+		os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+memory +cpu"), 0644)
+	}
+
+	cg := &transientCgroup{path: filepath.Join(parent, fmt.Sprintf("oc-mirror-test-%d", pid))}
+	if err := os.Mkdir(cg.path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(cg.path, "memory.stat")); err != nil {
+		cg.remove()
+		return nil, fmt.Errorf("the memory controller is not delegated to %s", parent)
+	}
+	if err := os.WriteFile(filepath.Join(cg.path, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+		cg.remove()
+		return nil, fmt.Errorf("failed to move PID %d into the cgroup: %w", pid, err)
+	}
+	return cg, nil
+}
+
+// read returns the usage of the cgroup: CPU time from cpu.stat, and anonymous
+// plus mapped file memory from memory.stat, the equivalent of the summed RSS
+// without page cache. Virtual memory and threads are summed per process.
+func (cg *transientCgroup) read() resourceUsage {
+	usage := procUsage(cg.pids(), false)
+
+	stat := readKeyedFile(filepath.Join(cg.path, "cpu.stat"))
+	usage.cpuSeconds = float64(stat["usage_usec"]) / 1e6
+
+	memory := readKeyedFile(filepath.Join(cg.path, "memory.stat"))
+	usage.rss = memory["anon"] + memory["file_mapped"]
+	return usage
+}
+
+// pids returns the processes in the cgroup
+func (cg *transientCgroup) pids() []int {
+	data, err := os.ReadFile(filepath.Join(cg.path, "cgroup.procs"))
+	if err != nil {
+		return nil
+	}
+	var pids []int
+	for _, field := range strings.Fields(string(data)) {
+		if pid, err := strconv.Atoi(field); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// remove deletes the cgroup. It fails while processes remain, such as
+// orphaned descendants; the cgroup is then left for the system to reclaim.
+func (cg *transientCgroup) remove() error {
+	if err := os.Remove(cg.path); err != nil {
+		return fmt.Errorf("failed to remove cgroup %s: %w", cg.path, err)
+	}
+	return nil
+}
+
+// readKeyedFile parses a cgroup file of "key value" lines
+func readKeyedFile(path string) map[string]int64 {
+	values := make(map[string]int64)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return values
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			values[fields[0]], _ = strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return values
+}
+
+// procUsage sums the stats of pids. With children, the CPU time of reaped
+// children is included, so the CPU of a tree does not drop when a child exits.
+func procUsage(pids []int, children bool) resourceUsage {
+	usage := resourceUsage{}
+	for _, pid := range pids {
+		process, err := host.processUsage(pid, children)
+		if err != nil {
+			continue // Exited since it was listed
+		}
+		usage.cpuSeconds += process.cpuSeconds
+		usage.rss += process.rss
+		usage.vms += process.vms
+		usage.threads += process.threads
+		usage.processes++
+	}
+	return usage
+}
@@ -0,0 +1,354 @@
+package netdiag
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// Timeouts of the individual checks; traceroute gets the remaining budget
+const (
+	stepTimeout       = 5 * time.Second
+	tracerouteTimeout = 45 * time.Second
+	maxTraceHops      = 20
+)
+
+// Check is the outcome of one diagnostic step
+type Check struct {
+	Name     string        `json:"name"` // dns, tcp, tls, http, firewall, traceroute
+	OK       bool          `json:"ok"`
+	Skipped  bool          `json:"skipped,omitempty"`
+	Duration time.Duration `json:"duration_seconds"`
+	Detail   string        `json:"detail,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Output   []string      `json:"output,omitempty"` // Firewall rules or traceroute hops
+}
+
+// Report holds the connectivity checks against a registry and the conclusion drawn from them
+type Report struct {
+	Host     string        `json:"host"`
+	Port     string        `json:"port"`
+	RanAt    time.Time     `json:"ran_at"`
+	Duration time.Duration `json:"duration_seconds"`
+	Checks   []Check       `json:"checks"`
+	Findings []string      `json:"findings"` // Most likely causes, in check order
+}
+
+// Diagnose runs DNS resolution, a TCP connect, a TLS handshake, a /v2/ ping and
+// a traceroute against the registry host[:port]. A TCP connect that times out
+// also looks for local firewall rules on the port.
+func Diagnose(ctx context.Context, hostPort string, skipTLS bool) *Report {
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		host, port = hostPort, "443"
+	}
+	report := &Report{Host: host, Port: port, RanAt: time.Now(), Checks: make([]Check, 0), Findings: make([]string, 0)}
+	defer func() { report.Duration = time.Since(report.RanAt) }()
+
+	dns := report.run("dns", func(c *Check) error { return checkDNS(ctx, host, c) })
+	if !dns.OK {
+		report.Findings = append(report.Findings, fmt.Sprintf("%s does not resolve: check DNS or /etc/hosts on this host", host))
+		report.skip("tcp", "tls", "http")
+		report.run("traceroute", func(c *Check) error { return traceroute(ctx, host, port, c) })
+		return report
+	}
+
+	tcp := report.run("tcp", func(c *Check) error { return checkTCP(ctx, net.JoinHostPort(host, port), c) })
+	if !tcp.OK {
+		report.Findings = append(report.Findings, tcpFinding(host, port, tcp.Error))
+		if isTimeout(tcp.Error) {
+			report.run("firewall", func(c *Check) error { return checkFirewall(ctx, port, c) })
+			if fw := report.check("firewall"); fw != nil && len(fw.Output) > 0 {
+				report.Findings = append(report.Findings, fmt.Sprintf("local firewall rules mention port %s (see the firewall check)", port))
+			}
+		}
+		report.skip("tls", "http")
+		report.run("traceroute", func(c *Check) error { return traceroute(ctx, host, port, c) })
+		return report
+	}
+
+	scheme := "https"
+	tlsCheck := report.run("tls", func(c *Check) error { return checkTLS(ctx, host, port, skipTLS, c) })
+	if !tlsCheck.OK {
+		report.Findings = append(report.Findings, tlsFinding(tlsCheck))
+		if strings.Contains(tlsCheck.Error, "first record does not look like a TLS handshake") {
+			scheme = "http" // Plain HTTP registry; the /v2/ ping tells whether it answers
+		} else {
+			report.skip("http")
+			return report
+		}
+	}
+
+	httpCheck := report.run("http", func(c *Check) error { return checkRegistryAPI(ctx, scheme, host, port, c) })
+	if !httpCheck.OK {
+		report.Findings = append(report.Findings, fmt.Sprintf("registry API did not answer on %s://%s:%s/v2/: %s", scheme, host, port, httpCheck.Error))
+	}
+	if len(report.Findings) == 0 {
+		report.Findings = append(report.Findings, "registry is reachable now: the failure was transient or happened mid-transfer (check the oc-mirror log and registry load)")
+	}
+	return report
+}
+
+// run times a check and appends it to the report
+func (r *Report) run(name string, step func(*Check) error) *Check {
+	check := Check{Name: name}
+	start := time.Now()
+	err := step(&check)
+	check.Duration = time.Since(start)
+	if err != nil {
+		check.Error = err.Error()
+	} else if !check.Skipped {
+		check.OK = true
+	}
+	r.Checks = append(r.Checks, check)
+	return &r.Checks[len(r.Checks)-1]
+}
+
+// skip records checks that could not run because an earlier one failed
+func (r *Report) skip(names ...string) {
+	for _, name := range names {
+		r.Checks = append(r.Checks, Check{Name: name, Skipped: true, Detail: "skipped after an earlier failure"})
+	}
+}
+
+func (r *Report) check(name string) *Check {
+	for i := range r.Checks {
+		if r.Checks[i].Name == name {
+			return &r.Checks[i]
+		}
+	}
+	return nil
+}
+
+func checkDNS(ctx context.Context, host string, c *Check) error {
+	if net.ParseIP(host) != nil {
+		c.Detail = "IP address"
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, stepTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return err
+	}
+	c.Detail = strings.Join(addrs, ", ")
+	return nil
+}
+
+func checkTCP(ctx context.Context, address string, c *Check) error {
+	dialer := &net.Dialer{Timeout: stepTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	c.Detail = fmt.Sprintf("connected from %s to %s", conn.LocalAddr(), conn.RemoteAddr())
+	return conn.Close()
+}
+
+// checkTLS completes a handshake. On a verification failure it handshakes
+// again without verification to describe the certificate the registry serves.
+func checkTLS(ctx context.Context, host, port string, skipTLS bool, c *Check) error {
+	state, err := handshake(ctx, host, port, skipTLS)
+	if err != nil {
+		var verifyErr *tls.CertificateVerificationError
+		if errors.As(err, &verifyErr) || strings.Contains(err.Error(), "x509:") {
+			if insecure, insecureErr := handshake(ctx, host, port, true); insecureErr == nil {
+				c.Detail = describeCertificate(insecure)
+			}
+		}
+		return err
+	}
+	c.Detail = describeCertificate(state)
+	return nil
+}
+
+func handshake(ctx context.Context, host, port string, insecure bool) (*tls.ConnectionState, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: stepTimeout},
+		Config:    &tls.Config{ServerName: host, InsecureSkipVerify: insecure},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	state := conn.(*tls.Conn).ConnectionState()
+	return &state, nil
+}
+
+func describeCertificate(state *tls.ConnectionState) string {
+	if len(state.PeerCertificates) == 0 {
+		return tls.VersionName(state.Version)
+	}
+	cert := state.PeerCertificates[0]
+	return fmt.Sprintf("%s, subject %q, issuer %q, SANs [%s], valid until %s",
+		tls.VersionName(state.Version), cert.Subject.CommonName, cert.Issuer.CommonName,
+		strings.Join(certificateNames(cert), " "), cert.NotAfter.Format("2006-01-02"))
+}
+
+func certificateNames(cert *x509.Certificate) []string {
+	names := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	return names
+}
+
+// checkRegistryAPI pings /v2/; 200 and 401 both mean a registry is answering
+func checkRegistryAPI(ctx context.Context, scheme, host, port string, c *Check) error {
+	client := &http.Client{
+		Timeout:   stepTimeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}, // TLS was checked separately
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s://%s/v2/", scheme, net.JoinHostPort(host, port)), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	c.Detail = resp.Status
+	if version := resp.Header.Get("Docker-Distribution-Api-Version"); version != "" {
+		c.Detail += ", " + version
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// checkFirewall lists the local nftables or iptables rules that mention the
+// port. Reading the rules usually needs root; the check is skipped otherwise.
+func checkFirewall(ctx context.Context, port string, c *Check) error {
+	ctx, cancel := context.WithTimeout(ctx, stepTimeout)
+	defer cancel()
+	for _, args := range [][]string{{"nft", "list", "ruleset"}, {"iptables-save"}} {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		out, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+		if err != nil {
+			continue // Not permitted or no such backend
+		}
+		c.Detail = "rules from " + args[0]
+		c.Output = rulesMentioningPort(out, port)
+		return nil
+	}
+	c.Skipped = true
+	c.Detail = "no readable nftables or iptables rules (requires root)"
+	return nil
+}
+
+func rulesMentioningPort(rules []byte, port string) []string {
+	var matches []string
+	scanner := bufio.NewScanner(bytes.NewReader(rules))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		for _, field := range strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == ',' || r == '{' || r == '}' || r == ':' }) {
+			if field == port {
+				matches = append(matches, line)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// traceroute records the path to the registry with traceroute, or tracepath
+// when traceroute is not installed. TCP probes are tried first since ICMP and
+// UDP are often filtered where the registry port is not.
+func traceroute(ctx context.Context, host, port string, c *Check) error {
+	ctx, cancel := context.WithTimeout(ctx, tracerouteTimeout)
+	defer cancel()
+	hops := fmt.Sprint(maxTraceHops)
+	candidates := [][]string{
+		{"traceroute", "-n", "-T", "-p", port, "-q", "1", "-w", "1", "-m", hops, host},
+		{"traceroute", "-n", "-q", "1", "-w", "1", "-m", hops, host},
+		{"tracepath", "-n", "-m", hops, host},
+	}
+	for _, args := range candidates {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+		if err != nil && len(out) == 0 || strings.Contains(string(out), "Operation not permitted") {
+			continue // TCP traceroute needs root; fall back to the next tool
+		}
+		c.Detail = strings.Join(args, " ")
+		c.Output = strings.Split(strings.TrimSpace(string(out)), "\n")
+		return nil
+	}
+	c.Skipped = true
+	c.Detail = "traceroute and tracepath are not installed"
+	return nil
+}
+
+func isTimeout(errText string) bool {
+	return strings.Contains(errText, "i/o timeout") || strings.Contains(errText, "timed out")
+}
+
+func tcpFinding(host, port, errText string) string {
+	switch {
+	case strings.Contains(errText, "connection refused"):
+		return fmt.Sprintf("%s:%s refused the connection: the registry is not listening on the port or a firewall rejects it", host, port)
+	case isTimeout(errText):
+		return fmt.Sprintf("%s:%s did not answer: a firewall is likely dropping traffic to the port", host, port)
+	case strings.Contains(errText, "no route to host") || strings.Contains(errText, "network is unreachable"):
+		return fmt.Sprintf("no route to %s: check routing, VPN or proxy settings", host)
+	default:
+		return fmt.Sprintf("TCP connect to %s:%s failed: %s", host, port, errText)
+	}
+}
+
+func tlsFinding(c *Check) string {
+	switch {
+	case strings.Contains(c.Error, "unknown authority"):
+		return "registry certificate is signed by an unknown CA: add the CA to the trust store or use --skip-tls"
+	case strings.Contains(c.Error, "expired") || strings.Contains(c.Error, "not yet valid"):
+		return "registry certificate is expired or not yet valid: " + c.Detail
+	case strings.Contains(c.Error, "not valid for") || strings.Contains(c.Error, "doesn't contain any IP SANs"):
+		return "registry certificate does not match the host name: " + c.Detail
+	case strings.Contains(c.Error, "first record does not look like a TLS handshake"):
+		return "registry speaks plain HTTP: use an http:// registry URL or enable TLS on the registry"
+	default:
+		return "TLS handshake failed: " + c.Error
+	}
+}
+
+// PrintSummary prints each check and the findings
+func (r *Report) PrintSummary() {
+	logging.Printf("  │ ─── Connectivity Diagnostics (%s:%s) ─────────────────────────\n", r.Host, r.Port)
+	for _, c := range r.Checks {
+		status := "✓"
+		detail := c.Detail
+		switch {
+		case c.Skipped:
+			status = "-"
+		case !c.OK:
+			status = "✗"
+			detail = c.Error
+		}
+		logging.Printf("  │   %s %-10s %s\n", status, c.Name, detail)
+		if c.Name == "firewall" {
+			for _, line := range c.Output {
+				logging.Printf("  │       %s\n", line)
+			}
+		}
+	}
+	for _, finding := range r.Findings {
+		logging.Printf("  │   → %s\n", finding)
+	}
+}
@@ -0,0 +1,214 @@
+package netshape
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// ifbDevice is the intermediate functional block device used to shape ingress traffic
+const ifbDevice = "ifb-ocmt0"
+
+var (
+	ratePattern    = regexp.MustCompile(`^\d+(\.\d+)?(bit|kbit|mbit|gbit|tbit|bps|kbps|mbps|gbps|tbps)$`)
+	latencyPattern = regexp.MustCompile(`^\d+(\.\d+)?(us|ms|s)$`)
+	lossPattern    = regexp.MustCompile(`^\d+(\.\d+)?%$`)
+)
+
+// Config describes the network constraints to apply during a test run
+type Config struct {
+	Interface string `json:"interface"`         // Interface to shape (defaults to the default route interface)
+	Rate      string `json:"rate,omitempty"`    // Bandwidth limit in tc syntax (e.g., 100mbit)
+	Latency   string `json:"latency,omitempty"` // Added delay in tc syntax (e.g., 50ms)
+	Jitter    string `json:"jitter,omitempty"`  // Delay variation in tc syntax (e.g., 10ms)
+	Loss      string `json:"loss,omitempty"`    // Packet loss percentage (e.g., 0.5%)
+	Ingress   bool   `json:"ingress,omitempty"` // Also shape inbound traffic via an IFB device
+}
+
+// Enabled returns true if any shaping rule is configured
+func (c Config) Enabled() bool {
+	return c.Rate != "" || c.Latency != "" || c.Loss != ""
+}
+
+// Validate checks the configured values against tc/netem syntax
+func (c Config) Validate() error {
+	if c.Rate != "" && !ratePattern.MatchString(strings.ToLower(c.Rate)) {
+		return fmt.Errorf("invalid bandwidth limit %q (expected e.g. 100mbit)", c.Rate)
+	}
+	if c.Latency != "" && !latencyPattern.MatchString(c.Latency) {
+		return fmt.Errorf("invalid latency %q (expected e.g. 50ms)", c.Latency)
+	}
+	if c.Jitter != "" {
+		if c.Latency == "" {
+			return fmt.Errorf("jitter requires a latency to be set")
+		}
+		if !latencyPattern.MatchString(c.Jitter) {
+			return fmt.Errorf("invalid jitter %q (expected e.g. 10ms)", c.Jitter)
+		}
+	}
+	if c.Loss != "" && !lossPattern.MatchString(c.Loss) {
+		return fmt.Errorf("invalid packet loss %q (expected e.g. 0.5%%)", c.Loss)
+	}
+	return nil
+}
+
+// String returns a human-readable description of the constraints
+func (c Config) String() string {
+	parts := make([]string, 0, 4)
+	if c.Rate != "" {
+		parts = append(parts, "rate "+c.Rate)
+	}
+	if c.Latency != "" {
+		delay := "delay " + c.Latency
+		if c.Jitter != "" {
+			delay += " ±" + c.Jitter
+		}
+		parts = append(parts, delay)
+	}
+	if c.Loss != "" {
+		parts = append(parts, "loss "+c.Loss)
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	direction := "egress"
+	if c.Ingress {
+		direction = "egress+ingress"
+	}
+	return fmt.Sprintf("%s on %s (%s)", strings.Join(parts, ", "), c.Interface, direction)
+}
+
+// netemArgs builds the netem qdisc parameters for the configuration
+func (c Config) netemArgs() []string {
+	args := []string{"netem"}
+	if c.Latency != "" {
+		args = append(args, "delay", c.Latency)
+		if c.Jitter != "" {
+			args = append(args, c.Jitter)
+		}
+	}
+	if c.Loss != "" {
+		args = append(args, "loss", c.Loss)
+	}
+	if c.Rate != "" {
+		args = append(args, "rate", c.Rate)
+	}
+	return args
+}
+
+// Shaper applies and removes tc/netem rules on a network interface
+type Shaper struct {
+	config  Config
+	applied bool
+	ingress bool // ingress redirection was set up and must be torn down
+	runner  func(name string, args ...string) error
+}
+
+// NewShaper creates a new shaper for the given configuration
+func NewShaper(cfg Config) *Shaper {
+	return &Shaper{
+		config: cfg,
+		runner: runCommand,
+	}
+}
+
+// Config returns the shaping configuration
+func (s *Shaper) Config() Config {
+	return s.config
+}
+
+// IsApplied returns whether shaping rules are currently installed
+func (s *Shaper) IsApplied() bool {
+	return s.applied
+}
+
+// Apply installs the netem rules on the configured interface.
+// Existing root qdiscs on the interface are replaced.
+func (s *Shaper) Apply() error {
+	if !s.config.Enabled() {
+		return nil
+	}
+	if err := s.config.Validate(); err != nil {
+		return err
+	}
+	if s.config.Interface == "" {
+		return fmt.Errorf("no interface configured for network shaping")
+	}
+	if _, err := exec.LookPath("tc"); err != nil {
+		return fmt.Errorf("tc not found in PATH (install iproute-tc): %w", err)
+	}
+
+	netem := s.config.netemArgs()
+	args := append([]string{"qdisc", "replace", "dev", s.config.Interface, "root"}, netem...)
+	if err := s.runner("tc", args...); err != nil {
+		return fmt.Errorf("failed to apply egress shaping on %s: %w", s.config.Interface, err)
+	}
+	s.applied = true
+
+	if s.config.Ingress {
+		if err := s.applyIngress(netem); err != nil {
+			s.Remove()
+			return fmt.Errorf("failed to apply ingress shaping on %s: %w", s.config.Interface, err)
+		}
+	}
+
+	return nil
+}
+
+// applyIngress redirects inbound traffic through an IFB device and shapes it there
+func (s *Shaper) applyIngress(netem []string) error {
+	// Ignore the error if the device is left over from a previous run
+	s.runner("ip", "link", "add", ifbDevice, "type", "ifb")
+	if err := s.runner("ip", "link", "set", "dev", ifbDevice, "up"); err != nil {
+		return err
+	}
+	s.ingress = true
+
+	if err := s.runner("tc", "qdisc", "replace", "dev", s.config.Interface, "handle", "ffff:", "ingress"); err != nil {
+		return err
+	}
+	if err := s.runner("tc", "filter", "replace", "dev", s.config.Interface, "parent", "ffff:",
+		"protocol", "all", "u32", "match", "u32", "0", "0",
+		"action", "mirred", "egress", "redirect", "dev", ifbDevice); err != nil {
+		return err
+	}
+	args := append([]string{"qdisc", "replace", "dev", ifbDevice, "root"}, netem...)
+	return s.runner("tc", args...)
+}
+
+// Remove deletes any rules installed by Apply.
+// It is safe to call multiple times.
+func (s *Shaper) Remove() error {
+	var errs []string
+
+	if s.ingress {
+		if err := s.runner("tc", "qdisc", "del", "dev", s.config.Interface, "ingress"); err != nil {
+			errs = append(errs, err.Error())
+		}
+		if err := s.runner("ip", "link", "del", ifbDevice); err != nil {
+			errs = append(errs, err.Error())
+		}
+		s.ingress = false
+	}
+
+	if s.applied {
+		if err := s.runner("tc", "qdisc", "del", "dev", s.config.Interface, "root"); err != nil {
+			errs = append(errs, err.Error())
+		}
+		s.applied = false
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to remove shaping rules: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func runCommand(name string, args ...string) error {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %w (%s)", name, strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Environment variables enabling notifications without flags
+const (
+	EnvURL          = "OC_MIRROR_TEST_NOTIFY_URL" // Comma-separated webhook URLs
+	EnvOn           = "OC_MIRROR_TEST_NOTIFY_ON"
+	EnvDashboardURL = "OC_MIRROR_TEST_DASHBOARD_URL"
+)
+
+// When notifications are sent
+const (
+	OnAlways  = "always"
+	OnFailure = "failure"
+)
+
+// slackPrefix forces the Slack payload for Slack-compatible webhooks on other hosts
+const slackPrefix = "slack+"
+
+// Config describes where and when run summaries are posted
+type Config struct {
+	URLs         []string // Webhook URLs; Slack hooks get a Slack message, others the JSON summary
+	On           string   // always (default) or failure
+	DashboardURL string   // Web UI link included in the summary
+}
+
+// Summary is the payload describing a finished run
+type Summary struct {
+	Status           string       `json:"status"` // completed or failed
+	RunName          string       `json:"run_name,omitempty"`
+	Scenario         string       `json:"scenario,omitempty"`
+	Registry         string       `json:"registry"`
+	Hostname         string       `json:"hostname,omitempty"`
+	StartedAt        time.Time    `json:"started_at"`
+	DurationSeconds  float64      `json:"duration_seconds"`
+	Iterations       int          `json:"iterations"`
+	FailedIterations int          `json:"failed_iterations"`
+	Improvement      *Improvement `json:"improvement,omitempty"`
+	Errors           []string     `json:"errors,omitempty"`
+	ResultsFile      string       `json:"results_file,omitempty"`
+	DashboardURL     string       `json:"dashboard_url,omitempty"`
+}
+
+// Improvement is the total time saved by cached runs, or by v2 over v1
+type Improvement struct {
+	Comparison string  `json:"comparison"` // e.g. "cached vs clean" or "v2 vs v1"
+	Percent    float64 `json:"percent"`
+}
+
+// ConfigFromEnv reads the notification settings from the environment
+func ConfigFromEnv() Config {
+	var cfg Config
+	for _, u := range strings.Split(os.Getenv(EnvURL), ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			cfg.URLs = append(cfg.URLs, u)
+		}
+	}
+	cfg.On = os.Getenv(EnvOn)
+	cfg.DashboardURL = os.Getenv(EnvDashboardURL)
+	return cfg
+}
+
+// Enabled returns true if any webhook is configured
+func (c Config) Enabled() bool {
+	return len(c.URLs) > 0
+}
+
+// Validate checks the webhook URLs and notification condition
+func (c Config) Validate() error {
+	if c.On != "" && c.On != OnAlways && c.On != OnFailure {
+		return fmt.Errorf("unknown notify condition %q (valid: %s, %s)", c.On, OnAlways, OnFailure)
+	}
+	for _, raw := range c.URLs {
+		u, err := url.Parse(strings.TrimPrefix(raw, slackPrefix))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid notify URL %q (expected http:// or https://)", raw)
+		}
+	}
+	return nil
+}
+
+// String returns a human-readable description that does not leak webhook secrets
+func (c Config) String() string {
+	hosts := make([]string, len(c.URLs))
+	for i, raw := range c.URLs {
+		hosts[i] = raw
+		if u, err := url.Parse(strings.TrimPrefix(raw, slackPrefix)); err == nil {
+			hosts[i] = u.Host
+		}
+	}
+	on := c.On
+	if on == "" {
+		on = OnAlways
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(hosts, ", "), on)
+}
+
+// ShouldNotify reports whether a run with the given outcome is notified
+func (c Config) ShouldNotify(failed bool) bool {
+	return c.Enabled() && (failed || c.On != OnFailure)
+}
+
+// Send posts the summary to every configured webhook
+func Send(cfg Config, summary Summary) error {
+	client := &http.Client{Timeout: 15 * time.Second}
+	var errs []error
+	for _, raw := range cfg.URLs {
+		target, payload := raw, interface{}(summary)
+		if isSlack(raw) {
+			target = strings.TrimPrefix(raw, slackPrefix)
+			payload = map[string]string{"text": summary.Text()}
+		}
+		if err := post(client, target, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func post(client *http.Client, target string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	resp, err := client.Post(target, "application/json", bytes.NewReader(data))
+	if err != nil {
+		// The URL may embed a secret token, so only the host is reported
+		return fmt.Errorf("failed to notify %s: %w", hostOf(target), errors.Unwrap(err))
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notification to %s returned %s", hostOf(target), resp.Status)
+	}
+	return nil
+}
+
+// isSlack reports whether a webhook expects a Slack message
+func isSlack(raw string) bool {
+	if strings.HasPrefix(raw, slackPrefix) {
+		return true
+	}
+	return hostOf(raw) == "hooks.slack.com"
+}
+
+func hostOf(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "webhook"
+	}
+	return u.Host
+}
+
+// Text returns the summary as a short chat message
+func (s Summary) Text() string {
+	var b strings.Builder
+	icon := "✅"
+	if s.Status != "completed" {
+		icon = "❌"
+	}
+	name := s.RunName
+	if name == "" {
+		name = s.Registry
+	}
+	fmt.Fprintf(&b, "%s oc-mirror-test run *%s* %s in %v\n", icon, name, s.Status,
+		(time.Duration(s.DurationSeconds * float64(time.Second))).Round(time.Second))
+	fmt.Fprintf(&b, "Registry: %s", s.Registry)
+	if s.Hostname != "" {
+		fmt.Fprintf(&b, " | Host: %s", s.Hostname)
+	}
+	fmt.Fprintf(&b, "\nIterations: %d", s.Iterations)
+	if s.FailedIterations > 0 {
+		fmt.Fprintf(&b, " (%d failed)", s.FailedIterations)
+	}
+	if s.Improvement != nil {
+		fmt.Fprintf(&b, " | Improvement (%s): %.1f%%", s.Improvement.Comparison, s.Improvement.Percent)
+	}
+	for i, e := range s.Errors {
+		if i == 3 {
+			fmt.Fprintf(&b, "\n… %d more errors", len(s.Errors)-3)
+			break
+		}
+		fmt.Fprintf(&b, "\nError: %s", e)
+	}
+	if s.ResultsFile != "" {
+		fmt.Fprintf(&b, "\nResults: %s", s.ResultsFile)
+	}
+	if s.DashboardURL != "" {
+		fmt.Fprintf(&b, "\nDashboard: %s", s.DashboardURL)
+	}
+	return b.String()
+}
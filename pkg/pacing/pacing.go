@@ -0,0 +1,245 @@
+package pacing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchHorizon bounds the search for the next allowed window
+const searchHorizon = 8 * 24 * time.Hour
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Config describes client-side pacing of the upload phase, protecting shared registries
+type Config struct {
+	MaxConcurrentPushes int           `json:"max_concurrent_pushes,omitempty" yaml:"maxConcurrentPushes,omitempty"` // Cap on parallel pushes to the registry
+	Windows             []string      `json:"windows,omitempty" yaml:"windows,omitempty"`                           // Allowed upload windows, e.g. "Mon-Fri 18:00-07:00"
+	MaxWait             time.Duration `json:"max_wait,omitempty" yaml:"maxWait,omitempty"`                          // Longest wait for a window, zero waits indefinitely
+}
+
+// Applied records the pacing applied to one upload phase
+type Applied struct {
+	Config        Config        `json:"config"`
+	Args          []string      `json:"args,omitempty"`           // oc-mirror arguments enforcing the concurrency cap
+	Waited        time.Duration `json:"waited_seconds,omitempty"` // Time spent waiting for an allowed window
+	Window        string        `json:"window,omitempty"`         // Window the upload started in
+	StartedAt     time.Time     `json:"started_at"`
+	OverranWindow bool          `json:"overran_window,omitempty"` // Upload was still running when the window closed
+}
+
+// Window is a parsed upload window; a range ending before it starts runs past midnight
+type Window struct {
+	spec  string
+	days  [7]bool
+	start int // Minutes since midnight
+	end   int // Minutes since midnight, 24*60 for end of day
+}
+
+// Enabled returns true if any pacing rule is configured
+func (c Config) Enabled() bool {
+	return c.MaxConcurrentPushes > 0 || len(c.Windows) > 0
+}
+
+// Validate checks the concurrency cap and window syntax
+func (c Config) Validate() error {
+	if c.MaxConcurrentPushes < 0 {
+		return fmt.Errorf("max concurrent pushes must not be negative")
+	}
+	if c.MaxWait < 0 {
+		return fmt.Errorf("max window wait must not be negative")
+	}
+	_, err := c.ParseWindows()
+	return err
+}
+
+// String returns a human-readable description of the pacing
+func (c Config) String() string {
+	parts := make([]string, 0, 3)
+	if c.MaxConcurrentPushes > 0 {
+		parts = append(parts, fmt.Sprintf("max %d concurrent pushes", c.MaxConcurrentPushes))
+	}
+	if len(c.Windows) > 0 {
+		parts = append(parts, "windows "+strings.Join(c.Windows, ", "))
+	}
+	if c.MaxWait > 0 {
+		parts = append(parts, fmt.Sprintf("max wait %v", c.MaxWait))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Args returns the oc-mirror arguments enforcing the concurrency cap. v2 copies
+// N images with one layer at a time each, so at most N blobs are pushed at once;
+// v1 limits requests per registry.
+func (c Config) Args(version string) []string {
+	if c.MaxConcurrentPushes <= 0 {
+		return nil
+	}
+	n := strconv.Itoa(c.MaxConcurrentPushes)
+	if version == "v1" {
+		return []string{"--max-per-registry", n}
+	}
+	return []string{"--parallel-images", n, "--parallel-layers", "1"}
+}
+
+// ParseWindows parses the configured windows
+func (c Config) ParseWindows() ([]Window, error) {
+	windows := make([]Window, 0, len(c.Windows))
+	for _, spec := range c.Windows {
+		w, err := ParseWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// NextAllowed returns the earliest time at or after now that falls in a window,
+// and that window. Without windows every time is allowed.
+func (c Config) NextAllowed(now time.Time) (time.Time, *Window, error) {
+	windows, err := c.ParseWindows()
+	if err != nil || len(windows) == 0 {
+		return now, nil, err
+	}
+
+	// Windows have minute granularity, so checking minute boundaries is exact
+	for t := now; t.Sub(now) <= searchHorizon; t = t.Truncate(time.Minute).Add(time.Minute) {
+		for i := range windows {
+			if windows[i].Contains(t) {
+				return t, &windows[i], nil
+			}
+		}
+	}
+	return time.Time{}, nil, fmt.Errorf("no upload window within the next %v", searchHorizon)
+}
+
+// AllowedUntil returns when uploading stops being allowed after t, taking
+// adjacent and overlapping windows together. Without windows it returns zero.
+func (c Config) AllowedUntil(t time.Time) time.Time {
+	windows, err := c.ParseWindows()
+	if err != nil || len(windows) == 0 {
+		return time.Time{}
+	}
+	end := t.Truncate(time.Minute)
+	for end.Sub(t) <= searchHorizon {
+		open := false
+		for i := range windows {
+			if windows[i].Contains(end) {
+				open = true
+				break
+			}
+		}
+		if !open {
+			break
+		}
+		end = end.Add(time.Minute)
+	}
+	return end
+}
+
+// ParseWindow parses "[days] [HH:MM-HH:MM]", where days is a day range
+// (Mon-Fri), a list (Sat,Sun) or "*". Either part may be omitted: "Sat,Sun" is
+// the whole weekend and "18:00-07:00" applies every day.
+func ParseWindow(spec string) (Window, error) {
+	w := Window{spec: spec, start: 0, end: 24 * 60}
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 2 {
+		return w, fmt.Errorf("invalid upload window %q (expected e.g. \"Mon-Fri 18:00-07:00\")", spec)
+	}
+
+	dayField, timeField := "*", ""
+	switch {
+	case len(fields) == 2:
+		dayField, timeField = fields[0], fields[1]
+	case strings.Contains(fields[0], ":"):
+		timeField = fields[0]
+	default:
+		dayField = fields[0]
+	}
+
+	if err := w.parseDays(dayField); err != nil {
+		return w, fmt.Errorf("invalid upload window %q: %w", spec, err)
+	}
+	if timeField != "" {
+		start, end, ok := strings.Cut(timeField, "-")
+		if !ok {
+			return w, fmt.Errorf("invalid upload window %q: time range must be HH:MM-HH:MM", spec)
+		}
+		var err error
+		if w.start, err = parseClock(start); err != nil {
+			return w, fmt.Errorf("invalid upload window %q: %w", spec, err)
+		}
+		if w.end, err = parseClock(end); err != nil {
+			return w, fmt.Errorf("invalid upload window %q: %w", spec, err)
+		}
+		if w.start == w.end {
+			return w, fmt.Errorf("invalid upload window %q: empty time range", spec)
+		}
+	}
+	return w, nil
+}
+
+// Contains reports whether t falls in the window
+func (w Window) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	if w.start < w.end {
+		return w.days[day] && minute >= w.start && minute < w.end
+	}
+	// Range past midnight: the evening of a listed day or the morning after it
+	previous := (day + 6) % 7
+	return (w.days[day] && minute >= w.start) || (w.days[previous] && minute < w.end)
+}
+
+// String returns the window as configured
+func (w Window) String() string {
+	return w.spec
+}
+
+func (w *Window) parseDays(field string) error {
+	if field == "*" {
+		for i := range w.days {
+			w.days[i] = true
+		}
+		return nil
+	}
+	for _, part := range strings.Split(field, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := weekdays[strings.ToLower(from)]
+		if !ok {
+			return fmt.Errorf("unknown day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdays[strings.ToLower(to)]; !ok {
+				return fmt.Errorf("unknown day %q", to)
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			w.days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseClock parses HH:MM into minutes since midnight; 24:00 is the end of the day
+func parseClock(s string) (int, error) {
+	hour, minute, ok := strings.Cut(s, ":")
+	h, errH := strconv.Atoi(hour)
+	m, errM := strconv.Atoi(minute)
+	if !ok || errH != nil || errM != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", s)
+	}
+	return h*60 + m, nil
+}
@@ -0,0 +1,87 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// NewPlanCommand creates a cobra command that sizes the next update of a mirror
+func NewPlanCommand() *cobra.Command {
+	opts := DefaultOptions()
+	var jsonOutput bool
+	var outputFile string
+
+	cmd := &cobra.Command{
+		Use:   "plan --config <imageset-config>",
+		Short: "Size what a changed ImageSetConfiguration would add to an existing mirror",
+		Long: "Runs oc-mirror v2 --dry-run for the configuration against the existing cache to list the images the cache does not hold, " +
+			"then reads their manifests from the source registries and sums the blobs that are not cached yet. No image content is transferred. " +
+			"The result is the size of the next update, to plan the maintenance window before mirroring.\n\n" +
+			"Example: plan --config imageset-4.17.yaml --cache-dir operators-v2",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+
+			imagesFile, images := opts.ImagesFile, 0
+			if imagesFile == "" {
+				var err error
+				if imagesFile, images, err = DryRun(opts); err != nil {
+					return err
+				}
+			}
+
+			var missing []string
+			if imagesFile != "" {
+				var err error
+				if missing, err = LoadImages(imagesFile); err != nil {
+					return err
+				}
+			}
+
+			plan := Build(context.Background(), missing, opts)
+			plan.Config = opts.Config
+			plan.ImagesFile = imagesFile
+			plan.Images = images
+
+			if jsonOutput || outputFile != "" {
+				out, err := plan.FormatJSON()
+				if err != nil {
+					return fmt.Errorf("failed to format plan: %w", err)
+				}
+				if outputFile != "" {
+					if err := os.WriteFile(outputFile, []byte(out+"\n"), 0644); err != nil {
+						return fmt.Errorf("failed to write plan: %w", err)
+					}
+				} else {
+					fmt.Println(out)
+				}
+			}
+			if !jsonOutput {
+				plan.PrintSummary()
+			}
+
+			if len(plan.Errors) > 0 {
+				return fmt.Errorf("%d image(s) could not be inspected", len(plan.Errors))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Config, "config", "c", "", "ImageSetConfiguration to plan, typically the changed one")
+	cmd.Flags().StringVar(&opts.CacheDir, "cache-dir", opts.CacheDir, "oc-mirror v2 cache directory of the existing mirror")
+	cmd.Flags().StringVar(&opts.Workspace, "workspace", opts.Workspace, "Directory the dry run writes its image lists to (emptied first)")
+	cmd.Flags().StringVar(&opts.ImagesFile, "images", "", "Plan from this missing.txt or mapping.txt of an earlier dry run instead of running oc-mirror")
+	cmd.Flags().StringVar(&opts.AuthFile, "authfile", "", "Credentials file for the source registries (default: REGISTRY_AUTH_FILE, containers or docker auth files)")
+	cmd.Flags().BoolVar(&opts.SkipTLS, "skip-tls", false, "Skip TLS verification for the source registries")
+	cmd.Flags().IntVar(&opts.Concurrency, "concurrency", opts.Concurrency, "Number of parallel manifest requests")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the plan as JSON")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Also write the plan as JSON to this file")
+
+	return cmd
+}
@@ -0,0 +1,334 @@
+package plan
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+	"github.com/telco-core/ngc-495/pkg/registry"
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// maxListedRepositories limits the repositories printed in the summary; the
+// JSON report lists all of them
+const maxListedRepositories = 15
+
+// Options configures an update plan
+type Options struct {
+	Config      string // ImageSetConfiguration to plan
+	CacheDir    string // oc-mirror v2 --cache-dir of the existing mirror
+	Workspace   string // Where oc-mirror --dry-run writes its image lists
+	ImagesFile  string // Existing missing.txt or mapping.txt; skips the dry run
+	AuthFile    string
+	SkipTLS     bool
+	Concurrency int
+}
+
+// DefaultOptions returns the options of the plan subcommand
+func DefaultOptions() Options {
+	return Options{
+		CacheDir:    runner.DefaultCacheDir,
+		Workspace:   filepath.Join(runner.DefaultWorkspaceDir, "plan"),
+		Concurrency: 8,
+	}
+}
+
+// Validate checks the options
+func (o Options) Validate() error {
+	if o.Config == "" && o.ImagesFile == "" {
+		return fmt.Errorf("an ImageSetConfiguration (--config) or an image list (--images) is required")
+	}
+	if o.Concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+	return nil
+}
+
+// Plan is what mirroring an ImageSetConfiguration would add to an existing cache
+type Plan struct {
+	Config        string           `json:"config,omitempty"`
+	CacheDir      string           `json:"cache_dir"`
+	ImagesFile    string           `json:"images_file,omitempty"`
+	GeneratedAt   time.Time        `json:"generated_at"`
+	Duration      time.Duration    `json:"duration_seconds"`
+	Images        int              `json:"images"`         // In the mapping of the dry run, when known
+	MissingImages int              `json:"missing_images"` // Not in the cache
+	NewBlobs      int              `json:"new_blobs"`
+	NewBytes      int64            `json:"new_bytes"`
+	ReusedBlobs   int              `json:"reused_blobs"` // Blobs of missing images already in the cache
+	ReusedBytes   int64            `json:"reused_bytes"`
+	Repositories  []RepositoryPlan `json:"repositories"` // By new bytes, largest first
+	Errors        []ImageError     `json:"errors,omitempty"`
+}
+
+// RepositoryPlan is what a source repository adds. A blob shared between
+// repositories counts toward the first one listing it.
+type RepositoryPlan struct {
+	Repository string `json:"repository"` // host/path of the source
+	Images     int    `json:"images"`
+	NewBlobs   int    `json:"new_blobs"`
+	NewBytes   int64  `json:"new_bytes"`
+}
+
+// ImageError is a missing image whose manifests could not be read
+type ImageError struct {
+	Image string `json:"image"`
+	Error string `json:"error"`
+}
+
+// DryRun runs oc-mirror v2 --dry-run for the configuration against the cache
+// and returns the image list to plan from: missing.txt, or mapping.txt when
+// the cache already holds every image
+func DryRun(opts Options) (string, int, error) {
+	// Image lists of an earlier plan must not be mistaken for this one's
+	if err := os.RemoveAll(opts.Workspace); err != nil {
+		return "", 0, fmt.Errorf("failed to clean plan workspace: %w", err)
+	}
+	if err := os.MkdirAll(opts.Workspace, 0755); err != nil {
+		return "", 0, fmt.Errorf("failed to create plan workspace: %w", err)
+	}
+	workspace, err := filepath.Abs(opts.Workspace)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid plan workspace: %w", err)
+	}
+
+	cmd := command.NewOCMirrorCommand()
+	cmd.SetV2(true)
+	cmd.SetDryRun(true)
+	cmd.SetConfig(opts.Config)
+	cmd.SetCacheDir(opts.CacheDir)
+	cmd.SetOutput("file://" + workspace)
+	cmd.SetLogFile(filepath.Join(workspace, "dry-run.log"))
+	if _, err := cmd.Execute(); err != nil {
+		return "", 0, fmt.Errorf("dry run failed: %w", err)
+	}
+
+	dryRunDir := filepath.Join(workspace, "working-dir", "dry-run")
+	mapping, err := LoadImages(filepath.Join(dryRunDir, "mapping.txt"))
+	if err != nil {
+		return "", 0, err
+	}
+	missingFile := filepath.Join(dryRunDir, "missing.txt")
+	if _, err := os.Stat(missingFile); os.IsNotExist(err) {
+		return "", len(mapping), nil
+	}
+	return missingFile, len(mapping), nil
+}
+
+// LoadImages reads the source images of an oc-mirror image list: mapping.txt
+// and missing.txt (source=destination per line) or one reference per line
+func LoadImages(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image list: %w", err)
+	}
+	defer file.Close()
+
+	var images []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		source, _, _ := strings.Cut(line, "=")
+		source = strings.TrimPrefix(source, "docker://")
+		if !seen[source] {
+			seen[source] = true
+			images = append(images, source)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read image list %s: %w", path, err)
+	}
+	return images, nil
+}
+
+// imageBlobs is the result of reading the manifests of one image
+type imageBlobs struct {
+	host       string
+	repository string
+	blobs      []registry.Blob
+	err        error
+}
+
+// Build reads the manifests of the missing images from their source
+// registries and sums the blobs the cache does not hold yet. Nothing but
+// manifests is fetched.
+func Build(ctx context.Context, images []string, opts Options) *Plan {
+	startTime := time.Now()
+	plan := &Plan{CacheDir: opts.CacheDir, GeneratedAt: startTime, MissingImages: len(images)}
+
+	var mu sync.Mutex
+	clients := make(map[string]*registry.Client)
+	clientFor := func(host string) (*registry.Client, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if client, ok := clients[host]; ok {
+			return client, nil
+		}
+		client, err := registry.NewClient(host, opts.AuthFile, opts.SkipTLS)
+		if err != nil {
+			return nil, err
+		}
+		clients[host] = client
+		return client, nil
+	}
+
+	fetched := make([]imageBlobs, len(images))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fetched[i] = fetchImage(ctx, clientFor, images[i])
+			}
+		}()
+	}
+	for i := range images {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Aggregate in image list order, so shared blobs are attributed the same way every time
+	counted := make(map[string]bool)
+	byRepository := make(map[string]*RepositoryPlan)
+	for i, image := range fetched {
+		if image.err != nil {
+			plan.Errors = append(plan.Errors, ImageError{Image: images[i], Error: image.err.Error()})
+			continue
+		}
+		name := image.host + "/" + image.repository
+		repo, ok := byRepository[name]
+		if !ok {
+			repo = &RepositoryPlan{Repository: name}
+			byRepository[name] = repo
+		}
+		repo.Images++
+		for _, blob := range image.blobs {
+			if counted[blob.Digest] {
+				continue
+			}
+			counted[blob.Digest] = true
+			if CacheHasBlob(opts.CacheDir, blob.Digest) {
+				plan.ReusedBlobs++
+				plan.ReusedBytes += blob.Size
+				continue
+			}
+			plan.NewBlobs++
+			plan.NewBytes += blob.Size
+			repo.NewBlobs++
+			repo.NewBytes += blob.Size
+		}
+	}
+
+	plan.Repositories = make([]RepositoryPlan, 0, len(byRepository))
+	for _, repo := range byRepository {
+		plan.Repositories = append(plan.Repositories, *repo)
+	}
+	sort.Slice(plan.Repositories, func(i, j int) bool {
+		if plan.Repositories[i].NewBytes != plan.Repositories[j].NewBytes {
+			return plan.Repositories[i].NewBytes > plan.Repositories[j].NewBytes
+		}
+		return plan.Repositories[i].Repository < plan.Repositories[j].Repository
+	})
+	plan.Duration = time.Since(startTime)
+	return plan
+}
+
+// fetchImage lists the blobs of one image reference
+func fetchImage(ctx context.Context, clientFor func(string) (*registry.Client, error), ref string) imageBlobs {
+	host, img, err := registry.ParseImageReference(ref)
+	if err != nil {
+		return imageBlobs{err: err}
+	}
+	result := imageBlobs{host: host, repository: img.Repository}
+	client, err := clientFor(host)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	reference := img.Digest
+	if reference == "" {
+		reference = img.Tag
+	}
+	result.blobs, result.err = client.ImageBlobs(ctx, img.Repository, reference)
+	return result
+}
+
+// CacheHasBlob reports whether the oc-mirror v2 cache, a registry storage
+// layout below <cache-dir>/.oc-mirror/.cache, holds a blob
+func CacheHasBlob(cacheDir, digest string) bool {
+	algorithm, hex, ok := strings.Cut(digest, ":")
+	if !ok || len(hex) < 2 {
+		return false
+	}
+	path := filepath.Join(cacheDir, ".oc-mirror", ".cache", "docker", "registry", "v2",
+		"blobs", algorithm, hex[:2], hex, "data")
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// FormatJSON returns the plan as indented JSON
+func (p *Plan) FormatJSON() (string, error) {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// PrintSummary prints the size of the update and the repositories adding the most
+func (p *Plan) PrintSummary() {
+	fmt.Printf("╔═══════════════════════════════════════════════════════════════╗\n")
+	fmt.Printf("║                      Mirror Update Plan                       ║\n")
+	fmt.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+	if p.Config != "" {
+		fmt.Printf("Config:     %s\n", p.Config)
+	}
+	fmt.Printf("Cache:      %s\n", p.CacheDir)
+	if p.Images > 0 {
+		fmt.Printf("Images:     %d in the mapping, %d not in the cache\n", p.Images, p.MissingImages)
+	} else {
+		fmt.Printf("Images:     %d not in the cache\n", p.MissingImages)
+	}
+	fmt.Printf("New blobs:  %d (%s)\n", p.NewBlobs, monitor.FormatBytesHuman(p.NewBytes))
+	fmt.Printf("Reused:     %d blobs already cached (%s)\n", p.ReusedBlobs, monitor.FormatBytesHuman(p.ReusedBytes))
+	fmt.Printf("Duration:   %v\n", p.Duration.Round(time.Millisecond))
+
+	if p.MissingImages == 0 {
+		fmt.Printf("\n✅ The cache already holds every image; the update transfers no new content\n")
+		return
+	}
+
+	if len(p.Repositories) > 0 {
+		fmt.Printf("\nLargest Additions:\n")
+		for i, repo := range p.Repositories {
+			if i == maxListedRepositories {
+				fmt.Printf("  ... and %d more repositories\n", len(p.Repositories)-i)
+				break
+			}
+			fmt.Printf("  %10s  %3d image(s)  %s\n", monitor.FormatBytesHuman(repo.NewBytes), repo.Images, repo.Repository)
+		}
+	}
+
+	if len(p.Errors) > 0 {
+		fmt.Printf("\nNot Inspected (the size is a lower bound):\n")
+		for _, e := range p.Errors {
+			fmt.Printf("  ❌ %s: %s\n", e.Image, e.Error)
+		}
+	}
+}
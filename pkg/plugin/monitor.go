@@ -0,0 +1,255 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// maxLineBytes bounds one line of plugin output
+const maxLineBytes = 1 << 20
+
+// Sample is one line of values reported by a monitor plugin
+type Sample struct {
+	Timestamp time.Time          `json:"Timestamp"`
+	Values    map[string]float64 `json:"Values"`
+}
+
+// SeriesSummary summarizes one value reported by a monitor plugin over a phase
+type SeriesSummary struct {
+	Samples int     `json:"Samples"`
+	Min     float64 `json:"Min"`
+	Max     float64 `json:"Max"`
+	Mean    float64 `json:"Mean"`
+	Last    float64 `json:"Last"`
+}
+
+// Metrics is what a monitor plugin reported during a phase
+type Metrics struct {
+	Name         string                   `json:"Name"`
+	Command      string                   `json:"Command"`
+	Duration     time.Duration            `json:"Duration"`
+	Series       map[string]SeriesSummary `json:"Series,omitempty"`
+	Warnings     []string                 `json:"Warnings,omitempty"`
+	InvalidLines int                      `json:"InvalidLines,omitempty"`
+	Error        string                   `json:"Error,omitempty"` // Start failure or non-zero exit before it was stopped
+	Samples      []Sample                 `json:"Samples,omitempty"`
+}
+
+// line is one line of monitor plugin output
+type line struct {
+	Time    *time.Time         `json:"time"`
+	Values  map[string]float64 `json:"values"`
+	Warning string             `json:"warning"`
+}
+
+// Monitor runs a monitor plugin for the duration of a phase
+type Monitor struct {
+	spec      Spec
+	env       map[string]string
+	handler   monitor.SampleHandler
+	startTime time.Time
+
+	mu         sync.Mutex
+	metrics    Metrics
+	monitoring bool
+	stopping   bool
+	stop       func() // Terminates the process and waits until its output was read
+}
+
+// NewMonitor creates a monitor running spec with the run context in env
+func NewMonitor(spec Spec, env map[string]string) *Monitor {
+	merged := map[string]string{EnvInterval: fmt.Sprintf("%g", spec.SampleInterval().Seconds())}
+	for key, value := range env {
+		merged[key] = value
+	}
+	return &Monitor{spec: spec, env: merged, metrics: Metrics{Name: spec.Name, Command: spec.Command}}
+}
+
+// Name returns the plugin name
+func (m *Monitor) Name() string {
+	return m.spec.Name
+}
+
+// SetSampleHandler sets the handler receiving each sample; call it before Start
+func (m *Monitor) SetSampleHandler(handler monitor.SampleHandler) {
+	m.handler = handler
+}
+
+// Start starts the plugin process
+func (m *Monitor) Start() error {
+	cmd := command(m.spec, m.env)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	m.startTime = time.Now()
+	if err := cmd.Start(); err != nil {
+		m.metrics.Error = err.Error()
+		return fmt.Errorf("failed to start plugin %s: %w", m.spec.Name, err)
+	}
+
+	exited := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+		for scanner.Scan() {
+			m.handleLine(scanner.Bytes())
+		}
+		waitErr := cmd.Wait()
+		close(exited)
+		m.mu.Lock()
+		if waitErr != nil && !m.stopping {
+			m.metrics.Error = fmt.Sprintf("exited: %v", waitErr)
+		}
+		m.monitoring = false
+		m.mu.Unlock()
+		close(done)
+	}()
+
+	m.mu.Lock()
+	m.stop = func() {
+		terminate(cmd, exited)
+		<-done
+	}
+	m.monitoring = true
+	m.mu.Unlock()
+	return nil
+}
+
+// handleLine records one line of plugin output
+func (m *Monitor) handleLine(data []byte) {
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return
+	}
+	var l line
+	if err := json.Unmarshal(data, &l); err != nil || (len(l.Values) == 0 && l.Warning == "") {
+		m.mu.Lock()
+		m.metrics.InvalidLines++
+		m.mu.Unlock()
+		return
+	}
+	if l.Warning != "" {
+		logging.Printf("  │ Warning: Plugin %s: %s\n", m.spec.Name, l.Warning)
+		m.mu.Lock()
+		m.metrics.Warnings = append(m.metrics.Warnings, l.Warning)
+		m.mu.Unlock()
+	}
+	if len(l.Values) == 0 {
+		return
+	}
+
+	sample := Sample{Timestamp: time.Now(), Values: l.Values}
+	if l.Time != nil {
+		sample.Timestamp = *l.Time
+	}
+	m.mu.Lock()
+	m.metrics.Samples = append(m.metrics.Samples, sample)
+	m.mu.Unlock()
+	if m.handler != nil {
+		m.handler(sample)
+	}
+}
+
+// Stop terminates the plugin and returns what it reported
+func (m *Monitor) Stop() Metrics {
+	m.mu.Lock()
+	stop := m.stop
+	m.stopping = true
+	m.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitoring = false
+	m.metrics.Duration = m.GetDuration()
+	m.metrics.Series = summarize(m.metrics.Samples)
+	return m.metrics
+}
+
+// StopInterface implements monitor.Monitor
+func (m *Monitor) StopInterface() interface{} {
+	return m.Stop()
+}
+
+// IsMonitoring returns whether the plugin is running
+func (m *Monitor) IsMonitoring() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.monitoring
+}
+
+// GetDuration returns the time since the plugin started
+func (m *Monitor) GetDuration() time.Duration {
+	if m.startTime.IsZero() {
+		return 0
+	}
+	return time.Since(m.startTime)
+}
+
+// summarize computes the summary of every value of samples
+func summarize(samples []Sample) map[string]SeriesSummary {
+	if len(samples) == 0 {
+		return nil
+	}
+	series := make(map[string]SeriesSummary)
+	for _, sample := range samples {
+		for name, value := range sample.Values {
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+			s, ok := series[name]
+			if !ok {
+				s = SeriesSummary{Min: value, Max: value}
+			}
+			s.Samples++
+			s.Min = math.Min(s.Min, value)
+			s.Max = math.Max(s.Max, value)
+			s.Mean += value // Sum until all samples are seen
+			s.Last = value
+			series[name] = s
+		}
+	}
+	for name, s := range series {
+		s.Mean /= float64(s.Samples)
+		series[name] = s
+	}
+	return series
+}
+
+// PrintSummary prints the values a monitor plugin reported during a phase
+func (m Metrics) PrintSummary() {
+	if m.Error != "" {
+		logging.Printf("  │ Warning: Plugin %s: %s\n", m.Name, m.Error)
+	}
+	if m.InvalidLines > 0 {
+		logging.Printf("  │ Warning: Plugin %s wrote %d invalid lines\n", m.Name, m.InvalidLines)
+	}
+	if len(m.Series) == 0 {
+		logging.Printf("  │ Plugin %s: no samples\n", m.Name)
+		return
+	}
+	names := make([]string, 0, len(m.Series))
+	for name := range m.Series {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := m.Series[name]
+		logging.Printf("  │ Plugin %s: %s mean %.2f, min %.2f, max %.2f (%d samples)\n", m.Name, name, s.Mean, s.Min, s.Max, s.Samples)
+	}
+}
+
+var _ monitor.Monitor = (*Monitor)(nil)
+var _ monitor.SampleObserver = (*Monitor)(nil)
@@ -0,0 +1,121 @@
+// Package plugin runs site-specific collectors and consumers as external
+// programs, so they can feed the run without forking the tool.
+//
+// A monitor plugin is started with every download and upload phase and
+// stopped with SIGTERM when the phase ends. It writes one JSON object per line
+// to stdout:
+//
+//	{"time": "2026-01-02T15:04:05Z", "values": {"san_read_mbs": 412.5, "san_latency_ms": 1.8}}
+//	{"warning": "array controller B not reachable"}
+//
+// "time" is optional and defaults to when the line was read. Values become a
+// sample of the run timeline, published like the samples of the built-in
+// monitors, and are summarized per phase in the results. Warnings are printed
+// with the phase output. Other lines are counted as invalid.
+//
+// A sink plugin is started once per run and reads the run events from stdin,
+// one JSON object per line: the samples of every monitor, phase changes and a
+// final "end" event carrying the run status and the results file. Its stdin is
+// closed when the run ends.
+//
+// Both get the run context in OC_MIRROR_TEST_* environment variables, and
+// their stderr is passed through.
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// Environment variables passed to plugins
+const (
+	EnvName      = "OC_MIRROR_TEST_PLUGIN"    // Plugin name
+	EnvPhase     = "OC_MIRROR_TEST_PHASE"     // download or upload; monitor plugins only
+	EnvVersion   = "OC_MIRROR_TEST_VERSION"   // v1 or v2; monitor plugins only
+	EnvIteration = "OC_MIRROR_TEST_ITERATION" // Monitor plugins only
+	EnvInterval  = "OC_MIRROR_TEST_INTERVAL"  // Requested sampling interval in seconds; monitor plugins only
+	EnvRegistry  = "OC_MIRROR_TEST_REGISTRY"  // Target registry
+)
+
+// stopGrace is how long a plugin may take to exit after SIGTERM or the end
+// of its input before it is killed
+const stopGrace = 5 * time.Second
+
+// Spec is one plugin: a command run with sh -c
+type Spec struct {
+	Name     string        `yaml:"name"`
+	Command  string        `yaml:"command"`
+	Interval time.Duration `yaml:"interval,omitempty"` // Requested sampling interval of a monitor plugin (default 1s)
+}
+
+// SampleInterval returns the sampling interval requested from a monitor plugin
+func (s Spec) SampleInterval() time.Duration {
+	if s.Interval <= 0 {
+		return monitor.DefaultPollInterval
+	}
+	return s.Interval
+}
+
+// Config lists the plugins of a run
+type Config struct {
+	Monitors []Spec `yaml:"monitors,omitempty"` // Sample collectors run during each phase
+	Sinks    []Spec `yaml:"sinks,omitempty"`    // Event consumers run for the whole run
+}
+
+// Enabled returns true if any plugin is configured
+func (c Config) Enabled() bool {
+	return len(c.Monitors) > 0 || len(c.Sinks) > 0
+}
+
+// Validate checks that every plugin has a unique name and a command
+func (c Config) Validate() error {
+	for _, kind := range []struct {
+		name  string
+		specs []Spec
+	}{{"monitor", c.Monitors}, {"sink", c.Sinks}} {
+		seen := make(map[string]bool)
+		for _, spec := range kind.specs {
+			if spec.Name == "" || strings.ContainsAny(spec.Name, " /=") {
+				return fmt.Errorf("invalid %s plugin name %q", kind.name, spec.Name)
+			}
+			if strings.TrimSpace(spec.Command) == "" {
+				return fmt.Errorf("%s plugin %s has no command", kind.name, spec.Name)
+			}
+			if seen[spec.Name] {
+				return fmt.Errorf("duplicate %s plugin %s", kind.name, spec.Name)
+			}
+			if spec.Interval < 0 {
+				return fmt.Errorf("%s plugin %s has a negative interval", kind.name, spec.Name)
+			}
+			seen[spec.Name] = true
+		}
+	}
+	return nil
+}
+
+// ParseSpec parses a name=command plugin flag
+func ParseSpec(value string) (Spec, error) {
+	name, command, ok := strings.Cut(value, "=")
+	if !ok || name == "" || strings.TrimSpace(command) == "" {
+		return Spec{}, fmt.Errorf("invalid plugin %q: expected name=command", value)
+	}
+	return Spec{Name: name, Command: command}, nil
+}
+
+// command prepares a plugin process in its own process group, so stopping it
+// also stops what its shell started
+func command(spec Spec, env map[string]string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", spec.Command)
+	cmd.Env = append(os.Environ(), EnvName+"="+spec.Name)
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	cmd.Stderr = os.Stderr
+	setProcessGroup(cmd)
+	return cmd
+}
@@ -0,0 +1,13 @@
+package plugin
+
+import "os/exec"
+
+// setProcessGroup does nothing: Windows has no process groups to signal
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminate kills the plugin process; there is no SIGTERM to let it finish
+// first. done is closed once it exited.
+func terminate(cmd *exec.Cmd, done <-chan struct{}) {
+	cmd.Process.Kill()
+	<-done
+}
@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/events"
+)
+
+// sinkBuffer is how many events a sink may fall behind before it misses some
+const sinkBuffer = 4096
+
+// RunEnd is the data of the final event a sink receives
+type RunEnd struct {
+	Status      string `json:"status"` // completed or failed
+	Error       string `json:"error,omitempty"`
+	ResultsFile string `json:"results_file,omitempty"`
+}
+
+// TypeEnd is the type of the final event a sink receives; Data is a RunEnd
+const TypeEnd = "end"
+
+// Sink runs a sink plugin for the duration of a run, writing the events of
+// the run to its stdin
+type Sink struct {
+	spec   Spec
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	writer *bufio.Writer
+
+	events      <-chan events.Event
+	unsubscribe func()
+	forwarded   chan struct{} // Closed when the subscription was drained
+	exited      chan struct{}
+
+	mu     sync.Mutex
+	broken error // First write error; the plugin stopped reading
+}
+
+// StartSink starts a sink plugin subscribed to bus
+func StartSink(spec Spec, env map[string]string, bus *events.Bus) (*Sink, error) {
+	cmd := command(spec, env)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", spec.Name, err)
+	}
+
+	s := &Sink{
+		spec:      spec,
+		cmd:       cmd,
+		stdin:     stdin,
+		writer:    bufio.NewWriter(stdin),
+		forwarded: make(chan struct{}),
+		exited:    make(chan struct{}),
+	}
+	s.events, s.unsubscribe = bus.Subscribe(sinkBuffer)
+	go func() {
+		cmd.Wait()
+		close(s.exited)
+	}()
+	go s.forward()
+	return s, nil
+}
+
+// Name returns the plugin name
+func (s *Sink) Name() string {
+	return s.spec.Name
+}
+
+// forward writes the events to the plugin until the subscription ends
+func (s *Sink) forward() {
+	defer close(s.forwarded)
+	for event := range s.events {
+		s.write(event)
+		if len(s.events) == 0 {
+			s.flush()
+		}
+	}
+}
+
+// write sends one event as a JSON line, dropping it once the plugin stopped
+// reading
+func (s *Sink) write(event events.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.broken != nil {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if _, err := s.writer.Write(append(data, '\n')); err != nil {
+		s.broken = err
+	}
+}
+
+// flush sends the buffered events to the plugin
+func (s *Sink) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.broken == nil {
+		if err := s.writer.Flush(); err != nil {
+			s.broken = err
+		}
+	}
+}
+
+// Stop sends the final event, closes the input of the plugin and waits for it
+// to exit, killing it when it does not
+func (s *Sink) Stop(end RunEnd) error {
+	s.unsubscribe()
+	<-s.forwarded
+	s.write(events.Event{Type: TypeEnd, Time: time.Now(), Data: end})
+	s.flush()
+	s.stdin.Close()
+
+	terminateAfterGrace(s.cmd, s.exited)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.broken != nil {
+		return fmt.Errorf("plugin %s stopped reading events: %w", s.spec.Name, s.broken)
+	}
+	return nil
+}
+
+// terminateAfterGrace gives a plugin stopGrace to exit on its own before
+// terminating it
+func terminateAfterGrace(cmd *exec.Cmd, exited <-chan struct{}) {
+	select {
+	case <-exited:
+	case <-time.After(stopGrace):
+		terminate(cmd, exited)
+	}
+}
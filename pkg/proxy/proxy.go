@@ -0,0 +1,433 @@
+// Package proxy provides a local reverse proxy that counts the registry
+// traffic of a client pushed through it
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// maxListedRepositories limits the repositories printed in the summary
+const maxListedRepositories = 5
+
+// Options configures a counting proxy
+type Options struct {
+	Upstream  string                                // Registry the requests are forwarded to, https://host[:port]
+	SkipTLS   bool                                  // Do not verify the registry certificate
+	Proxy     func(*http.Request) (*url.URL, error) // Optional HTTP(S) proxy to reach the registry through
+	RateLimit RateLimit                             // Simulated registry rate limiting; disabled when zero
+}
+
+// Stats is the registry traffic seen by the proxy. Byte counts are request
+// and response bodies as the client sent and received them.
+type Stats struct {
+	Upstream         string            `json:"upstream"`
+	Duration         time.Duration     `json:"duration_seconds"`
+	Requests         int               `json:"requests"`
+	RequestBytes     int64             `json:"request_bytes"`  // Bodies sent by the client: the exact upload size
+	ResponseBytes    int64             `json:"response_bytes"` // Bodies returned to the client
+	BlobBytes        int64             `json:"blob_bytes"`     // Bodies of accepted blob upload PATCH and PUT requests
+	ManifestBytes    int64             `json:"manifest_bytes"` // Bodies of accepted manifest PUT requests
+	BlobUploads      int               `json:"blob_uploads"`   // Blob uploads completed with a PUT
+	BlobMounts       int               `json:"blob_mounts"`    // Blobs mounted from another repository instead of uploaded
+	BlobsExisting    int               `json:"blobs_existing"` // Blob HEAD requests finding the blob already in the registry
+	ManifestsPut     int               `json:"manifests_put"`
+	ProxyErrors      int               `json:"proxy_errors"`               // Requests the registry could not be reached for
+	RateLimit        *RateLimit        `json:"rate_limit,omitempty"`       // The simulated rate limiting, when enabled
+	Throttled        int               `json:"throttled"`                  // Requests answered with an injected 429
+	Retries          int               `json:"retries"`                    // Requests repeating one answered with 429 or 5xx
+	RetryBackoffMean time.Duration     `json:"retry_backoff_mean_seconds"` // Time between a 429 or 5xx and the retry of the request
+	RetryBackoffMax  time.Duration     `json:"retry_backoff_max_seconds"`
+	Methods          map[string]int    `json:"methods"`
+	StatusCodes      map[int]int       `json:"status_codes"`
+	Repositories     []RepositoryStats `json:"repositories"` // By request bytes, largest first
+	repositories     map[string]*RepositoryStats
+	retryBackoff     time.Duration
+}
+
+// RepositoryStats is the traffic of one repository
+type RepositoryStats struct {
+	Name          string         `json:"name"`
+	Requests      int            `json:"requests"`
+	RequestBytes  int64          `json:"request_bytes"`
+	ResponseBytes int64          `json:"response_bytes"`
+	Retries       int            `json:"retries"`
+	Methods       map[string]int `json:"methods"`
+	StatusCodes   map[int]int    `json:"status_codes"`
+}
+
+// CountingProxy forwards registry API requests to the upstream registry over
+// TLS with a self-signed certificate for localhost, counting every exchange.
+// With a rate limit, it answers part of the requests with 429 itself and caps
+// the bandwidth of each client connection.
+type CountingProxy struct {
+	upstream *url.URL
+	server   *http.Server
+	listener net.Listener
+	reverse  *httputil.ReverseProxy
+	limiter  *limiter // nil without a rate limit
+	started  time.Time
+
+	mu     sync.Mutex
+	stats  Stats
+	failed map[string]time.Time // When a request, by method and path, was last answered with 429 or 5xx
+}
+
+// Start starts a proxy on a free localhost port
+func Start(opts Options) (*CountingProxy, error) {
+	upstream, err := url.Parse(opts.Upstream)
+	if err != nil || upstream.Host == "" {
+		return nil, fmt.Errorf("invalid upstream registry %q", opts.Upstream)
+	}
+	certificate, err := selfSignedCertificate()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the accounting proxy: %w", err)
+	}
+
+	p := &CountingProxy{
+		upstream: upstream,
+		listener: listener,
+		started:  time.Now(),
+		stats: Stats{
+			Upstream:     upstream.Host,
+			Methods:      make(map[string]int),
+			StatusCodes:  make(map[int]int),
+			repositories: make(map[string]*RepositoryStats),
+		},
+		failed: make(map[string]time.Time),
+	}
+	if opts.RateLimit.Enabled() {
+		if err := opts.RateLimit.Validate(); err != nil {
+			listener.Close()
+			return nil, err
+		}
+		rateLimit := opts.RateLimit
+		p.limiter = newLimiter(rateLimit)
+		p.stats.RateLimit = &rateLimit
+	}
+	p.reverse = &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(upstream)
+		},
+		Transport: &http.Transport{
+			Proxy:               opts.Proxy,
+			TLSClientConfig:     &tls.Config{InsecureSkipVerify: opts.SkipTLS},
+			ForceAttemptHTTP2:   true,
+			MaxIdleConnsPerHost: 64,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		ModifyResponse: p.rewriteLocation,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			p.mu.Lock()
+			p.stats.ProxyErrors++
+			p.mu.Unlock()
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		},
+	}
+	p.server = &http.Server{
+		Handler:           p,
+		TLSConfig:         &tls.Config{Certificates: []tls.Certificate{certificate}},
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	if bytesPerSecond := opts.RateLimit.BandwidthBytes(); bytesPerSecond > 0 {
+		p.server.ConnContext = connContext(bytesPerSecond)
+	}
+	go p.server.ServeTLS(listener, "", "")
+	return p, nil
+}
+
+// Host returns the localhost:port clients push to instead of the registry
+func (p *CountingProxy) Host() string {
+	return fmt.Sprintf("localhost:%d", p.listener.Addr().(*net.TCPAddr).Port)
+}
+
+// UpstreamHost returns the host[:port] of the registry
+func (p *CountingProxy) UpstreamHost() string {
+	return p.upstream.Host
+}
+
+// Route returns a registry URL (docker://host[:port]/path) with the registry
+// host replaced by the proxy
+func (p *CountingProxy) Route(registryURL string) string {
+	return strings.Replace(registryURL, p.upstream.Host, p.Host(), 1)
+}
+
+// Stop shuts the proxy down and returns what it counted
+func (p *CountingProxy) Stop() Stats {
+	p.server.Close()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	stats.Duration = time.Since(p.started)
+	if stats.Retries > 0 {
+		stats.RetryBackoffMean = stats.retryBackoff / time.Duration(stats.Retries)
+	}
+	stats.Repositories = make([]RepositoryStats, 0, len(p.stats.repositories))
+	for _, repo := range p.stats.repositories {
+		stats.Repositories = append(stats.Repositories, *repo)
+	}
+	sort.Slice(stats.Repositories, func(i, j int) bool {
+		if stats.Repositories[i].RequestBytes != stats.Repositories[j].RequestBytes {
+			return stats.Repositories[i].RequestBytes > stats.Repositories[j].RequestBytes
+		}
+		return stats.Repositories[i].Name < stats.Repositories[j].Name
+	})
+	return stats
+}
+
+// ServeHTTP forwards a request, or rejects it under the rate limit, and
+// counts its body, response and status
+func (p *CountingProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	received := time.Now()
+	bandwidth := connBandwidth(r)
+	body := &countingReader{reader: r.Body, bandwidth: bandwidth}
+	r.Body = body
+	writer := &countingWriter{ResponseWriter: w, status: http.StatusOK, bandwidth: bandwidth}
+	if repository, _ := parsePath(r.URL.Path); p.limiter != nil && repository != "" && p.limiter.throttle() {
+		p.limiter.reject(writer)
+		p.record(r, received, body.n, writer.n, writer.status, true)
+		return
+	}
+	p.reverse.ServeHTTP(writer, r)
+	p.record(r, received, body.n, writer.n, writer.status, false)
+}
+
+// rewriteLocation points upload session and redirect locations on the
+// registry back at the proxy, so the client keeps pushing through it
+func (p *CountingProxy) rewriteLocation(resp *http.Response) error {
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return nil
+	}
+	u, err := url.Parse(location)
+	if err != nil || u.Host != p.upstream.Host {
+		return nil
+	}
+	u.Scheme, u.Host = "https", p.Host()
+	resp.Header.Set("Location", u.String())
+	return nil
+}
+
+// record adds an exchange to the totals and its repository. A request is a
+// retry when the same method and path were last answered with 429 or 5xx.
+func (p *CountingProxy) record(r *http.Request, received time.Time, requestBytes, responseBytes int64, status int, throttled bool) {
+	repository, kind := parsePath(r.URL.Path)
+	accepted := status >= 200 && status < 300
+	key := r.Method + " " + r.URL.Path
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := &p.stats
+	s.Requests++
+	s.RequestBytes += requestBytes
+	s.ResponseBytes += responseBytes
+	s.Methods[r.Method]++
+	s.StatusCodes[status]++
+	if throttled {
+		s.Throttled++
+	}
+	failedAt, retry := p.failed[key]
+	if retry {
+		backoff := max(0, received.Sub(failedAt))
+		s.Retries++
+		s.retryBackoff += backoff
+		s.RetryBackoffMax = max(s.RetryBackoffMax, backoff)
+		delete(p.failed, key)
+	}
+	if status == http.StatusTooManyRequests || status >= 500 {
+		p.failed[key] = time.Now()
+	}
+
+	switch {
+	case kind == "uploads" && (r.Method == http.MethodPatch || r.Method == http.MethodPut) && accepted:
+		s.BlobBytes += requestBytes
+		if r.Method == http.MethodPut {
+			s.BlobUploads++
+		}
+	case kind == "uploads" && r.Method == http.MethodPost && status == http.StatusCreated && r.URL.Query().Has("mount"):
+		s.BlobMounts++
+	case kind == "blobs" && r.Method == http.MethodHead && status == http.StatusOK:
+		s.BlobsExisting++
+	case kind == "manifests" && r.Method == http.MethodPut && accepted:
+		s.ManifestsPut++
+		s.ManifestBytes += requestBytes
+	}
+
+	if repository == "" {
+		return
+	}
+	repo := s.repositories[repository]
+	if repo == nil {
+		repo = &RepositoryStats{Name: repository, Methods: make(map[string]int), StatusCodes: make(map[int]int)}
+		s.repositories[repository] = repo
+	}
+	repo.Requests++
+	if retry {
+		repo.Retries++
+	}
+	repo.RequestBytes += requestBytes
+	repo.ResponseBytes += responseBytes
+	repo.Methods[r.Method]++
+	repo.StatusCodes[status]++
+}
+
+// parsePath returns the repository of a registry API path and what it
+// addresses: blobs, uploads, manifests or tags. Other paths (/v2/, _catalog)
+// have no repository.
+func parsePath(path string) (repository, kind string) {
+	rest, ok := strings.CutPrefix(path, "/v2/")
+	if !ok {
+		return "", ""
+	}
+	if i := strings.LastIndex(rest, "/blobs/uploads"); i > 0 {
+		return rest[:i], "uploads"
+	}
+	for _, k := range []string{"blobs", "manifests", "tags"} {
+		if i := strings.LastIndex(rest, "/"+k+"/"); i > 0 {
+			return rest[:i], k
+		}
+	}
+	return "", ""
+}
+
+// PrintSummary prints the exact upload size, the API calls and the busiest repositories
+func (s *Stats) PrintSummary() {
+	if s == nil {
+		return
+	}
+	logging.Printf("  │ ─── Upload Traffic (accounting proxy) ───────────────────────\n")
+	logging.Printf("  │   Requests: %d | Sent: %s | Received: %s\n", s.Requests, monitor.FormatBytesHuman(s.RequestBytes), monitor.FormatBytesHuman(s.ResponseBytes))
+	logging.Printf("  │   Blobs: %d uploaded (%s) | %d mounted | %d already present\n", s.BlobUploads, monitor.FormatBytesHuman(s.BlobBytes), s.BlobMounts, s.BlobsExisting)
+	logging.Printf("  │   Manifests: %d put (%s)\n", s.ManifestsPut, monitor.FormatBytesHuman(s.ManifestBytes))
+	logging.Printf("  │   Methods: %s | Status: %s\n", formatMethods(s.Methods), formatStatusCodes(s.StatusCodes))
+	for i, repo := range s.Repositories {
+		if i == maxListedRepositories {
+			logging.Printf("  │   ... and %d more repositories\n", len(s.Repositories)-i)
+			break
+		}
+		logging.Printf("  │   %s: %d requests, %s sent\n", repo.Name, repo.Requests, monitor.FormatBytesHuman(repo.RequestBytes))
+	}
+	if s.Throttled > 0 || s.Retries > 0 {
+		logging.Printf("  │   Rate limit: %d throttled (429) | %d retries, backoff mean %v, max %v\n",
+			s.Throttled, s.Retries, s.RetryBackoffMean.Round(time.Millisecond), s.RetryBackoffMax.Round(time.Millisecond))
+	}
+	if s.ProxyErrors > 0 {
+		logging.Printf("  │ Warning: %d request(s) could not be forwarded to %s\n", s.ProxyErrors, s.Upstream)
+	}
+}
+
+// formatMethods returns the method counts, e.g. "HEAD 120, PUT 40"
+func formatMethods(methods map[string]int) string {
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s %d", name, methods[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatStatusCodes returns the status counts, e.g. "201 40, 404 12"
+func formatStatusCodes(codes map[int]int) string {
+	keys := make([]int, 0, len(codes))
+	for code := range codes {
+		keys = append(keys, code)
+	}
+	sort.Ints(keys)
+	parts := make([]string, 0, len(keys))
+	for _, code := range keys {
+		parts = append(parts, fmt.Sprintf("%d %d", code, codes[code]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// countingReader counts the bytes read from a request body
+type countingReader struct {
+	reader    io.ReadCloser
+	bandwidth *bandwidth // Cap of the client connection, nil without one
+	n         int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.reader.Read(b)
+	c.n += int64(n)
+	c.bandwidth.wait(n)
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	return c.reader.Close()
+}
+
+// countingWriter counts the response bytes and keeps the status code
+type countingWriter struct {
+	http.ResponseWriter
+	status    int
+	bandwidth *bandwidth // Cap of the client connection, nil without one
+	n         int64
+}
+
+func (c *countingWriter) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	c.bandwidth.wait(len(b))
+	n, err := c.ResponseWriter.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// Unwrap lets the reverse proxy flush streamed responses
+func (c *countingWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// selfSignedCertificate returns a certificate for localhost, valid for a day
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate accounting proxy key: %w", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create accounting proxy certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
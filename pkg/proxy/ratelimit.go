@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// RateLimit simulates a rate-limiting registry in front of the upstream one
+type RateLimit struct {
+	ThrottleRate      float64       `json:"throttle_rate,omitempty" yaml:"throttleRate,omitempty"`            // Fraction of repository requests answered with 429 (0-1)
+	RequestsPerSecond float64       `json:"requests_per_second,omitempty" yaml:"requestsPerSecond,omitempty"` // Repository requests above this rate are answered with 429
+	RetryAfter        time.Duration `json:"retry_after,omitempty" yaml:"retryAfter,omitempty"`                // Retry-After sent with the injected 429s
+	Bandwidth         string        `json:"bandwidth,omitempty" yaml:"bandwidth,omitempty"`                   // Cap of each client connection per second, e.g. 10Mi
+}
+
+// Enabled returns true if requests are throttled or connections capped
+func (r RateLimit) Enabled() bool {
+	return r.ThrottleRate > 0 || r.RequestsPerSecond > 0 || r.Bandwidth != ""
+}
+
+// Validate checks the throttle rate and the bandwidth cap
+func (r RateLimit) Validate() error {
+	if r.ThrottleRate < 0 || r.ThrottleRate > 1 {
+		return fmt.Errorf("invalid throttle rate %v (expected 0 to 1)", r.ThrottleRate)
+	}
+	if r.RequestsPerSecond < 0 {
+		return fmt.Errorf("invalid request rate %v", r.RequestsPerSecond)
+	}
+	if r.RetryAfter < 0 {
+		return fmt.Errorf("invalid Retry-After %v", r.RetryAfter)
+	}
+	if r.Bandwidth != "" {
+		bytes, err := monitor.ParseByteSize(r.Bandwidth)
+		if err != nil {
+			return fmt.Errorf("invalid bandwidth cap: %w", err)
+		}
+		if bytes <= 0 {
+			return fmt.Errorf("invalid bandwidth cap %q", r.Bandwidth)
+		}
+	}
+	return nil
+}
+
+// BandwidthBytes returns the connection cap in bytes per second, 0 without one
+func (r RateLimit) BandwidthBytes() int64 {
+	if r.Bandwidth == "" {
+		return 0
+	}
+	bytes, _ := monitor.ParseByteSize(r.Bandwidth)
+	return bytes
+}
+
+// String returns a human-readable description of the simulation
+func (r RateLimit) String() string {
+	parts := make([]string, 0, 4)
+	if r.ThrottleRate > 0 {
+		parts = append(parts, fmt.Sprintf("%.1f%% of requests throttled", r.ThrottleRate*100))
+	}
+	if r.RequestsPerSecond > 0 {
+		parts = append(parts, fmt.Sprintf("%g requests/s", r.RequestsPerSecond))
+	}
+	if r.RetryAfter > 0 {
+		parts = append(parts, fmt.Sprintf("Retry-After %v", r.RetryAfter))
+	}
+	if r.Bandwidth != "" {
+		parts = append(parts, fmt.Sprintf("%s/s per connection", monitor.FormatBytesHuman(r.BandwidthBytes())))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// limiter decides which requests are answered with an injected 429
+type limiter struct {
+	config RateLimit
+
+	mu     sync.Mutex
+	tokens float64
+	filled time.Time
+}
+
+func newLimiter(config RateLimit) *limiter {
+	return &limiter{config: config, tokens: max(1, config.RequestsPerSecond), filled: time.Now()}
+}
+
+// throttle returns true if the request is to be rejected: drawn at the
+// throttle rate, or over the request rate with a burst of one second
+func (l *limiter) throttle() bool {
+	if l.config.ThrottleRate > 0 && rand.Float64() < l.config.ThrottleRate {
+		return true
+	}
+	if l.config.RequestsPerSecond <= 0 {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	burst := max(1, l.config.RequestsPerSecond)
+	l.tokens = min(burst, l.tokens+now.Sub(l.filled).Seconds()*l.config.RequestsPerSecond)
+	l.filled = now
+	if l.tokens < 1 {
+		return true
+	}
+	l.tokens--
+	return false
+}
+
+// reject answers a request with 429 and the registry's TOOMANYREQUESTS error
+func (l *limiter) reject(w http.ResponseWriter) {
+	if l.config.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(max(1, l.config.RetryAfter.Round(time.Second).Seconds()))))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	fmt.Fprint(w, `{"errors":[{"code":"TOOMANYREQUESTS","message":"rate limit simulated by oc-mirror-test"}]}`)
+}
+
+// bandwidthKey is the context key of the bandwidth cap of a connection
+type bandwidthKey struct{}
+
+// bandwidth paces the bytes of a client connection, both directions
+// included, to a number of bytes per second
+type bandwidth struct {
+	bytesPerSecond int64
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// connContext gives each client connection its own bandwidth cap
+func connContext(bytesPerSecond int64) func(context.Context, net.Conn) context.Context {
+	return func(ctx context.Context, _ net.Conn) context.Context {
+		return context.WithValue(ctx, bandwidthKey{}, &bandwidth{bytesPerSecond: bytesPerSecond})
+	}
+}
+
+// connBandwidth returns the cap of the connection a request came in on, nil without one
+func connBandwidth(r *http.Request) *bandwidth {
+	b, _ := r.Context().Value(bandwidthKey{}).(*bandwidth)
+	return b
+}
+
+// wait blocks until n more bytes fit in the cap
+func (b *bandwidth) wait(n int) {
+	if b == nil || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	now := time.Now()
+	if b.next.Before(now) {
+		b.next = now
+	}
+	delay := b.next.Sub(now)
+	b.next = b.next.Add(time.Duration(float64(n) / float64(b.bytesPerSecond) * float64(time.Second)))
+	b.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,257 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/compare"
+	"github.com/telco-core/ngc-495/pkg/results"
+)
+
+// Canned is a predefined query answering a common question about results files
+type Canned struct {
+	Name        string
+	Description string
+	run         func(runs []*results.Run) (*Table, error)
+}
+
+// Table is the result of a canned query: rows for the console, values for JSON
+type Table struct {
+	Columns []string
+	Rows    [][]string
+	Values  []interface{}
+}
+
+// IterationTiming is one iteration with its phase timings
+type IterationTiming struct {
+	Run             string  `json:"run"`
+	Iteration       int     `json:"iteration"`
+	Version         string  `json:"version"`
+	IsCleanRun      bool    `json:"is_clean_run"`
+	DownloadSeconds float64 `json:"download_seconds"`
+	UploadSeconds   float64 `json:"upload_seconds"`
+	TotalSeconds    float64 `json:"total_seconds"`
+}
+
+// RunRegression is a metric delta between two consecutive runs
+type RunRegression struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	compare.MetricDelta
+}
+
+// FailureEntry is a failed phase attempt or failed iteration
+type FailureEntry struct {
+	Run       string `json:"run"`
+	Iteration int    `json:"iteration"`
+	Version   string `json:"version"`
+	Phase     string `json:"phase"`
+	Attempt   int    `json:"attempt,omitempty"` // Zero for the final iteration failure
+	Error     string `json:"error"`
+}
+
+// CannedQueries lists the predefined queries in display order
+var CannedQueries = []Canned{
+	{Name: "slowest", Description: "Slowest completed iterations by total download and upload time", run: slowestIterations(true)},
+	{Name: "fastest", Description: "Fastest completed iterations by total download and upload time", run: slowestIterations(false)},
+	{Name: "biggest-regression", Description: "Largest metric increases between consecutive runs", run: biggestRegressions},
+	{Name: "failures", Description: "Failed iterations and retried phase attempts", run: failures},
+}
+
+// FindCanned returns the canned query with the given name
+func FindCanned(name string) (*Canned, error) {
+	names := make([]string, 0, len(CannedQueries))
+	for i := range CannedQueries {
+		if CannedQueries[i].Name == name {
+			return &CannedQueries[i], nil
+		}
+		names = append(names, CannedQueries[i].Name)
+	}
+	return nil, fmt.Errorf("unknown canned query %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// Run evaluates the canned query, returning at most limit rows (zero for all)
+func (c *Canned) Run(runs []*results.Run, limit int) (*Table, error) {
+	table, err := c.run(runs)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(table.Rows) > limit {
+		table.Rows = table.Rows[:limit]
+		table.Values = table.Values[:limit]
+	}
+	return table, nil
+}
+
+func slowestIterations(slowestFirst bool) func([]*results.Run) (*Table, error) {
+	return func(runs []*results.Run) (*Table, error) {
+		var timings []IterationTiming
+		for _, run := range runs {
+			for _, r := range run.Results {
+				if r.Failed {
+					continue
+				}
+				timings = append(timings, IterationTiming{
+					Run:             run.Name,
+					Iteration:       r.Iteration,
+					Version:         r.Version,
+					IsCleanRun:      r.IsCleanRun,
+					DownloadSeconds: r.DownloadPhase.WallTime.Seconds(),
+					UploadSeconds:   r.UploadPhase.WallTime.Seconds(),
+					TotalSeconds:    (r.DownloadPhase.WallTime + r.UploadPhase.WallTime).Seconds(),
+				})
+			}
+		}
+		sort.SliceStable(timings, func(i, j int) bool {
+			if slowestFirst {
+				return timings[i].TotalSeconds > timings[j].TotalSeconds
+			}
+			return timings[i].TotalSeconds < timings[j].TotalSeconds
+		})
+
+		table := &Table{Columns: []string{"RUN", "ITER", "VERSION", "STATE", "DOWNLOAD", "UPLOAD", "TOTAL"}}
+		for _, t := range timings {
+			table.Rows = append(table.Rows, []string{
+				t.Run, fmt.Sprint(t.Iteration), t.Version, cacheState(t.IsCleanRun),
+				formatSeconds(t.DownloadSeconds), formatSeconds(t.UploadSeconds), formatSeconds(t.TotalSeconds),
+			})
+			table.Values = append(table.Values, t)
+		}
+		return table, nil
+	}
+}
+
+func biggestRegressions(runs []*results.Run) (*Table, error) {
+	if len(runs) < 2 {
+		return nil, fmt.Errorf("biggest-regression needs at least two results files, got %d", len(runs))
+	}
+
+	var regressions []RunRegression
+	for i := 1; i < len(runs); i++ {
+		report, err := compare.Compare(runs[i-1:i+1], compare.DefaultThresholds())
+		if err != nil {
+			return nil, err
+		}
+		for _, delta := range report.Deltas {
+			if delta.DeltaPercent > 0 {
+				regressions = append(regressions, RunRegression{From: runs[i-1].Name, To: runs[i].Name, MetricDelta: delta})
+			}
+		}
+	}
+	sort.SliceStable(regressions, func(i, j int) bool {
+		return regressions[i].DeltaPercent > regressions[j].DeltaPercent
+	})
+
+	table := &Table{Columns: []string{"FROM", "TO", "GROUP", "METRIC", "BASELINE", "CANDIDATE", "DELTA"}}
+	for _, r := range regressions {
+		table.Rows = append(table.Rows, []string{
+			r.From, r.To, r.Group.String(), r.Metric,
+			fmt.Sprintf("%.2f", r.Baseline), fmt.Sprintf("%.2f", r.Candidate), fmt.Sprintf("+%.1f%%", r.DeltaPercent),
+		})
+		table.Values = append(table.Values, r)
+	}
+	return table, nil
+}
+
+func failures(runs []*results.Run) (*Table, error) {
+	var entries []FailureEntry
+	for _, run := range runs {
+		for _, r := range run.Results {
+			for _, attempt := range r.FailedAttempts {
+				entries = append(entries, FailureEntry{
+					Run: run.Name, Iteration: r.Iteration, Version: r.Version,
+					Phase: attempt.Phase, Attempt: attempt.Attempt, Error: attempt.Error,
+				})
+			}
+			if r.Failed {
+				entries = append(entries, FailureEntry{
+					Run: run.Name, Iteration: r.Iteration, Version: r.Version,
+					Phase: "iteration", Error: r.Error,
+				})
+			}
+		}
+	}
+
+	table := &Table{Columns: []string{"RUN", "ITER", "VERSION", "PHASE", "ATTEMPT", "ERROR"}}
+	for _, e := range entries {
+		attempt := "-"
+		if e.Attempt > 0 {
+			attempt = fmt.Sprint(e.Attempt)
+		}
+		table.Rows = append(table.Rows, []string{
+			e.Run, fmt.Sprint(e.Iteration), e.Version, e.Phase, attempt, firstLine(e.Error, 80),
+		})
+		table.Values = append(table.Values, e)
+	}
+	return table, nil
+}
+
+// Print writes the table with aligned columns
+func (t *Table) Print() {
+	if len(t.Rows) == 0 {
+		fmt.Printf("No matching results\n")
+		return
+	}
+	widths := make([]int, len(t.Columns))
+	for i, col := range t.Columns {
+		widths[i] = len(col)
+	}
+	for _, row := range t.Rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	printRow := func(cells []string) {
+		parts := make([]string, len(cells))
+		for i, cell := range cells {
+			parts[i] = fmt.Sprintf("%-*s", widths[i], cell)
+		}
+		fmt.Println(strings.TrimRight(strings.Join(parts, "  "), " "))
+	}
+	printRow(t.Columns)
+	for _, row := range t.Rows {
+		printRow(row)
+	}
+}
+
+// DocumentFor returns the JSON document queried for one results file, with
+// the run description under "header" and the iterations under "iterations"
+func DocumentFor(run *results.Run) (interface{}, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"name":       run.Name,
+		"path":       run.Path,
+		"header":     run.Header,
+		"iterations": run.Results,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", run.Path, err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", run.Path, err)
+	}
+	return doc, nil
+}
+
+func cacheState(isCleanRun bool) string {
+	if isCleanRun {
+		return "clean"
+	}
+	return "cached"
+}
+
+func formatSeconds(seconds float64) string {
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Second).String()
+}
+
+// firstLine returns the first line of s, shortened to maxLen
+func firstLine(s string, maxLen int) string {
+	s, _, _ = strings.Cut(s, "\n")
+	if len(s) > maxLen {
+		return s[:maxLen-3] + "..."
+	}
+	return s
+}
@@ -0,0 +1,140 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/telco-core/ngc-495/pkg/results"
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// NewResultsCommand creates the "results" command group for working with results files
+func NewResultsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "results",
+		Short: "Inspect and prune results files",
+	}
+	cmd.AddCommand(newQueryCommand(), newPruneCommand())
+	return cmd
+}
+
+func newQueryCommand() *cobra.Command {
+	var canned string
+	var limit int
+	var list, jsonOutput, raw, compact bool
+
+	cmd := &cobra.Command{
+		Use:   "query [<expression>] [<results.json|dir>...]",
+		Short: "Query results files with jq-like selectors or canned queries",
+		Long: "Evaluates a jq-like expression against each results file, or runs a canned query across all of them. " +
+			"Each file is queried as {\"name\", \"path\", \"iterations\": [...]}; durations are stored in nanoseconds. " +
+			"Directories expand to their results files, oldest first; without paths the default results directory is used.\n\n" +
+			"Example: results query '.iterations[] | select(.version == \"v2\") | .download_phase.wall_time_seconds / 1e9' results/",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if list {
+				for _, c := range CannedQueries {
+					fmt.Printf("  %-20s %s\n", c.Name, c.Description)
+				}
+				return nil
+			}
+
+			var q *Query
+			if canned == "" {
+				if len(args) == 0 {
+					return fmt.Errorf("an expression or --canned is required")
+				}
+				var err error
+				if q, err = Compile(args[0]); err != nil {
+					return fmt.Errorf("invalid expression: %w", err)
+				}
+				args = args[1:]
+			}
+
+			if len(args) == 0 {
+				args = []string{runner.DefaultResultsDir}
+			}
+			files, err := results.Resolve(args)
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				return fmt.Errorf("no results files found in %v", args)
+			}
+			runs, err := results.LoadAll(files, nil)
+			if err != nil {
+				return err
+			}
+			for _, run := range runs {
+				if run.Integrity.IsTampered() {
+					fmt.Fprintf(os.Stderr, "Warning: %s failed integrity check (%s): %s\n", run.Path, run.Integrity.Status, run.Integrity.Message)
+				}
+			}
+
+			if canned != "" {
+				c, err := FindCanned(canned)
+				if err != nil {
+					return err
+				}
+				table, err := c.Run(runs, limit)
+				if err != nil {
+					return err
+				}
+				if jsonOutput {
+					return printValue(table.Values, false, compact)
+				}
+				table.Print()
+				return nil
+			}
+
+			for _, run := range runs {
+				doc, err := DocumentFor(run)
+				if err != nil {
+					return err
+				}
+				values, err := q.Run(doc)
+				if err != nil {
+					return fmt.Errorf("%s: %w", run.Name, err)
+				}
+				for _, v := range values {
+					if err := printValue(v, raw, compact); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&canned, "canned", "", "Run a canned query instead of an expression (see --list)")
+	cmd.Flags().BoolVar(&list, "list", false, "List the canned queries")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 10, "Maximum rows of a canned query (0 for all)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print canned query rows as JSON")
+	cmd.Flags().BoolVarP(&raw, "raw-output", "r", false, "Print strings without quotes")
+	cmd.Flags().BoolVarP(&compact, "compact-output", "c", false, "Print each value on a single line")
+
+	return cmd
+}
+
+// printValue prints one query output as JSON, like jq
+func printValue(v interface{}, raw, compact bool) error {
+	if s, ok := v.(string); ok && raw {
+		fmt.Println(s)
+		return nil
+	}
+	var data []byte
+	var err error
+	if compact {
+		data, err = json.Marshal(v)
+	} else {
+		data, err = json.MarshalIndent(v, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
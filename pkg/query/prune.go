@@ -0,0 +1,94 @@
+package query
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+	"github.com/telco-core/ngc-495/pkg/results"
+	"github.com/telco-core/ngc-495/pkg/runner"
+	"github.com/telco-core/ngc-495/pkg/store"
+)
+
+func newPruneCommand() *cobra.Command {
+	var resultsDir, olderThan string
+	var remove, dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "prune --older-than <age>",
+		Short: "Archive or delete results files of old runs",
+		Long: "Moves the results files of runs started before the given age, with their sidecars and phase logs, into " +
+			"one tar.gz under <results-dir>/" + results.ArchiveDir + "/, or deletes them with --delete. " +
+			"The age is a number of days (30d), weeks (2w) or a duration (36h); the run start comes from the file name.\n\n" +
+			"Example: results prune --older-than 30d --results-dir results/",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			age, err := results.ParseAge(olderThan)
+			if err != nil {
+				return err
+			}
+			backend, err := store.Open(resultsDir, store.S3OptionsFromEnv())
+			if err != nil {
+				return err
+			}
+			runs, err := results.ListRuns(backend)
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			old := results.OlderThan(runs, now.Add(-age))
+			if len(old) == 0 {
+				fmt.Printf("No results older than %s in %s\n", olderThan, backend)
+				return nil
+			}
+
+			var size int64
+			for _, run := range old {
+				size += run.Size
+				if dryRun {
+					fmt.Printf("  %s (%d files, %s)\n", run.Name, len(run.Objects), monitor.FormatBytesHuman(run.Size))
+				}
+			}
+			switch {
+			case dryRun:
+				fmt.Printf("Would prune %d of %d results file(s): %s\n", len(old), len(runs), monitor.FormatBytesHuman(size))
+				return nil
+			case remove:
+				var failed int
+				for _, run := range old {
+					if err := results.Delete(backend, run); err != nil {
+						fmt.Printf("Warning: Failed to delete %s: %v\n", run.Name, err)
+						failed++
+					}
+				}
+				fmt.Printf("Deleted %d results file(s) with sidecars and logs: %s freed, %d kept\n",
+					len(old)-failed, monitor.FormatBytesHuman(size), len(runs)-len(old)+failed)
+				if failed > 0 {
+					return fmt.Errorf("failed to delete %d results file(s)", failed)
+				}
+				return nil
+			}
+
+			archives, err := results.Archive(backend, old, now)
+			for _, archive := range archives {
+				fmt.Printf("Archived to %s\n", archive)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Archived %d results file(s) with sidecars and logs (%s), %d kept\n",
+				len(old), monitor.FormatBytesHuman(size), len(runs)-len(old))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&resultsDir, "results-dir", runner.DefaultResultsDir, "Results directory, or s3://bucket/prefix")
+	cmd.Flags().StringVar(&olderThan, "older-than", "", "Prune runs started longer ago than this, e.g. 30d")
+	cmd.Flags().BoolVar(&remove, "delete", false, "Delete the old results instead of archiving them")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only list the results that would be pruned")
+	cmd.MarkFlagRequired("older-than")
+	return cmd
+}
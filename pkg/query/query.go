@@ -0,0 +1,904 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a compiled selector expression. The language is a small subset of
+// jq: paths (.a.b, .[], .[0]), pipes, select(), comparisons, and/or,
+// arithmetic, array and object construction, and a few builtins.
+type Query struct {
+	source string
+	root   node
+}
+
+// Compile parses a selector expression
+func Compile(source string) (*Query, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.peek().text, p.peek().pos)
+	}
+	return &Query{source: source, root: root}, nil
+}
+
+// String returns the expression the query was compiled from
+func (q *Query) String() string {
+	return q.source
+}
+
+// Run evaluates the query against a decoded JSON document and returns all outputs
+func (q *Query) Run(input interface{}) ([]interface{}, error) {
+	return q.root.eval(input)
+}
+
+// builtins lists the supported functions and whether they take an argument
+var builtins = map[string]bool{
+	"select": true, "map": true, "sort_by": true, "min_by": true, "max_by": true,
+	"length": false, "keys": false, "first": false, "last": false, "add": false,
+	"min": false, "max": false, "avg": false, "sort": false, "not": false,
+}
+
+// ---- tokens ----
+
+type tokenKind int
+
+const (
+	tokPunct tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var twoCharOps = []string{"==", "!=", "<=", ">="}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			text, err := strconv.Unquote(src[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %w", i, err)
+			}
+			tokens = append(tokens, token{kind: tokString, text: text, pos: i})
+			i = end + 1
+		case unicode.IsDigit(c):
+			end := i
+			for end < len(src) && (unicode.IsDigit(rune(src[end])) || strings.ContainsRune(".eE", rune(src[end])) ||
+				(strings.ContainsRune("+-", rune(src[end])) && strings.ContainsRune("eE", rune(src[end-1])))) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[i:end], pos: i})
+			i = end
+		case unicode.IsLetter(c) || c == '_':
+			end := i
+			for end < len(src) && (unicode.IsLetter(rune(src[end])) || unicode.IsDigit(rune(src[end])) || src[end] == '_') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[i:end], pos: i})
+			i = end
+		default:
+			text := string(c)
+			for _, op := range twoCharOps {
+				if strings.HasPrefix(src[i:], op) {
+					text = op
+				}
+			}
+			if !strings.Contains(".|[](){},:<>+-*/=!", string(c)) || text == "=" || text == "!" {
+				return nil, fmt.Errorf("unexpected %q at position %d", text, i)
+			}
+			tokens = append(tokens, token{kind: tokPunct, text: text, pos: i})
+			i += len(text)
+		}
+	}
+	return tokens, nil
+}
+
+// ---- parser ----
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{kind: tokPunct, text: "end of expression", pos: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+// accept consumes the next token if it is the given punctuation or keyword
+func (p *parser) accept(text string) bool {
+	if !p.done() && p.tokens[p.pos].kind != tokString && p.tokens[p.pos].text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return fmt.Errorf("expected %q, got %q", text, p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) parsePipe() (node, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("|") {
+		right, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		left = pipeNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return binaryNode{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().text
+		if op != "+" && op != "-" || !p.accept(op) {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek().text
+		if op != "*" && op != "/" || !p.accept(op) {
+			return left, nil
+		}
+		right, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+// parsePostfix parses a term followed by .field, [] and [n] suffixes
+func (p *parser) parsePostfix() (node, error) {
+	term, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.peek().text == "." && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].kind != tokPunct:
+			p.accept(".")
+			suffix, err := p.parseField()
+			if err != nil {
+				return nil, err
+			}
+			term = pipeNode{term, suffix}
+		case p.peek().text == "[" && p.peek().kind == tokPunct:
+			p.accept("[")
+			suffix, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			term = pipeNode{term, suffix}
+		default:
+			return term, nil
+		}
+	}
+}
+
+// parseField parses the name after "."
+func (p *parser) parseField() (node, error) {
+	tok := p.peek()
+	if tok.kind != tokIdent && tok.kind != tokString {
+		return nil, fmt.Errorf("expected field name after \".\", got %q", tok.text)
+	}
+	p.pos++
+	return fieldNode{tok.text}, nil
+}
+
+// parseBracket parses "]" or "n]" after "["
+func (p *parser) parseBracket() (node, error) {
+	if p.accept("]") {
+		return iterateNode{}, nil
+	}
+	negative := p.accept("-")
+	tok := p.peek()
+	if tok.kind == tokString {
+		p.pos++
+		return fieldNode{tok.text}, p.expect("]")
+	}
+	index, err := strconv.Atoi(tok.text)
+	if tok.kind != tokNumber || err != nil {
+		return nil, fmt.Errorf("expected array index, got %q", tok.text)
+	}
+	p.pos++
+	if negative {
+		index = -index
+	}
+	return indexNode{index}, p.expect("]")
+}
+
+func (p *parser) parseTerm() (node, error) {
+	tok := p.peek()
+	switch {
+	case tok.kind == tokString:
+		p.pos++
+		return literalNode{tok.text}, nil
+	case tok.kind == tokNumber:
+		p.pos++
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return literalNode{f}, nil
+	case tok.kind == tokIdent:
+		return p.parseIdent()
+	case p.accept("."):
+		// ".", ".field" and ".[...]"
+		next := p.peek()
+		if next.kind == tokIdent || next.kind == tokString {
+			return p.parseField()
+		}
+		if next.text == "[" && next.kind == tokPunct {
+			p.accept("[")
+			return p.parseBracket()
+		}
+		return identityNode{}, nil
+	case p.accept("("):
+		inner, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	case p.accept("["):
+		if p.accept("]") {
+			return collectNode{}, nil
+		}
+		inner, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return collectNode{inner}, p.expect("]")
+	case p.accept("{"):
+		return p.parseObject()
+	case p.accept("-"):
+		operand, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: "-", left: literalNode{0.0}, right: operand}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", tok.text)
+}
+
+func (p *parser) parseIdent() (node, error) {
+	name := p.peek().text
+	p.pos++
+	switch name {
+	case "true":
+		return literalNode{true}, nil
+	case "false":
+		return literalNode{false}, nil
+	case "null":
+		return literalNode{nil}, nil
+	}
+
+	takesArg, ok := builtins[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+	if !takesArg {
+		return callNode{name: name}, nil
+	}
+	if err := p.expect("("); err != nil {
+		return nil, fmt.Errorf("%s requires an argument: %w", name, err)
+	}
+	arg, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	return callNode{name: name, arg: arg}, p.expect(")")
+}
+
+// parseObject parses "{key: expr, key, ...}" after "{"
+func (p *parser) parseObject() (node, error) {
+	var obj objectNode
+	for !p.accept("}") {
+		if len(obj.keys) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		tok := p.peek()
+		if tok.kind != tokIdent && tok.kind != tokString {
+			return nil, fmt.Errorf("expected object key, got %q", tok.text)
+		}
+		p.pos++
+		var value node = fieldNode{tok.text} // {key} is short for {key: .key}
+		if p.accept(":") {
+			var err error
+			if value, err = p.parseOr(); err != nil {
+				return nil, err
+			}
+		}
+		obj.keys = append(obj.keys, tok.text)
+		obj.values = append(obj.values, value)
+	}
+	return obj, nil
+}
+
+// ---- evaluation ----
+
+type node interface {
+	eval(input interface{}) ([]interface{}, error)
+}
+
+type identityNode struct{}
+
+func (identityNode) eval(input interface{}) ([]interface{}, error) {
+	return []interface{}{input}, nil
+}
+
+type literalNode struct{ value interface{} }
+
+func (n literalNode) eval(interface{}) ([]interface{}, error) {
+	return []interface{}{n.value}, nil
+}
+
+type fieldNode struct{ name string }
+
+func (n fieldNode) eval(input interface{}) ([]interface{}, error) {
+	switch v := input.(type) {
+	case nil:
+		return []interface{}{nil}, nil
+	case map[string]interface{}:
+		return []interface{}{v[n.name]}, nil
+	}
+	return nil, fmt.Errorf("cannot index %s with %q", typeName(input), n.name)
+}
+
+type indexNode struct{ index int }
+
+func (n indexNode) eval(input interface{}) ([]interface{}, error) {
+	switch v := input.(type) {
+	case nil:
+		return []interface{}{nil}, nil
+	case []interface{}:
+		i := n.index
+		if i < 0 {
+			i += len(v)
+		}
+		if i < 0 || i >= len(v) {
+			return []interface{}{nil}, nil
+		}
+		return []interface{}{v[i]}, nil
+	}
+	return nil, fmt.Errorf("cannot index %s with a number", typeName(input))
+}
+
+type iterateNode struct{}
+
+func (iterateNode) eval(input interface{}) ([]interface{}, error) {
+	switch v := input.(type) {
+	case []interface{}:
+		return v, nil
+	case map[string]interface{}:
+		keys := sortedKeys(v)
+		out := make([]interface{}, len(keys))
+		for i, k := range keys {
+			out[i] = v[k]
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("cannot iterate over %s", typeName(input))
+}
+
+type pipeNode struct{ left, right node }
+
+func (n pipeNode) eval(input interface{}) ([]interface{}, error) {
+	lefts, err := n.left.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, l := range lefts {
+		rights, err := n.right.eval(l)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rights...)
+	}
+	return out, nil
+}
+
+type collectNode struct{ inner node }
+
+func (n collectNode) eval(input interface{}) ([]interface{}, error) {
+	if n.inner == nil {
+		return []interface{}{[]interface{}{}}, nil
+	}
+	values, err := n.inner.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = []interface{}{}
+	}
+	return []interface{}{values}, nil
+}
+
+type objectNode struct {
+	keys   []string
+	values []node
+}
+
+func (n objectNode) eval(input interface{}) ([]interface{}, error) {
+	obj := make(map[string]interface{}, len(n.keys))
+	for i, key := range n.keys {
+		values, err := n.values[i].eval(input)
+		if err != nil {
+			return nil, err
+		}
+		// Unlike jq, several outputs are collected instead of multiplying objects
+		switch len(values) {
+		case 0:
+			obj[key] = nil
+		case 1:
+			obj[key] = values[0]
+		default:
+			obj[key] = values
+		}
+	}
+	return []interface{}{obj}, nil
+}
+
+type logicNode struct {
+	op          string
+	left, right node
+}
+
+func (n logicNode) eval(input interface{}) ([]interface{}, error) {
+	lefts, err := n.left.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, l := range lefts {
+		if n.op == "and" && !truthy(l) {
+			out = append(out, false)
+			continue
+		}
+		if n.op == "or" && truthy(l) {
+			out = append(out, true)
+			continue
+		}
+		rights, err := n.right.eval(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rights {
+			out = append(out, truthy(r))
+		}
+	}
+	return out, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n binaryNode) eval(input interface{}) ([]interface{}, error) {
+	rights, err := n.right.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	lefts, err := n.left.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	var out []interface{}
+	for _, r := range rights {
+		for _, l := range lefts {
+			v, err := applyBinary(n.op, l, r)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+func applyBinary(op string, l, r interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return compareValues(l, r) == 0, nil
+	case "!=":
+		return compareValues(l, r) != 0, nil
+	case "<":
+		return compareValues(l, r) < 0, nil
+	case "<=":
+		return compareValues(l, r) <= 0, nil
+	case ">":
+		return compareValues(l, r) > 0, nil
+	case ">=":
+		return compareValues(l, r) >= 0, nil
+	}
+
+	if op == "+" {
+		switch {
+		case l == nil:
+			return r, nil
+		case r == nil:
+			return l, nil
+		}
+		if ls, ok := l.(string); ok {
+			if rs, ok := r.(string); ok {
+				return ls + rs, nil
+			}
+		}
+		if la, ok := l.([]interface{}); ok {
+			if ra, ok := r.([]interface{}); ok {
+				return append(append([]interface{}{}, la...), ra...), nil
+			}
+		}
+	}
+
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("cannot apply %q to %s and %s", op, typeName(l), typeName(r))
+	}
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	}
+	if rf == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	return lf / rf, nil
+}
+
+type callNode struct {
+	name string
+	arg  node
+}
+
+func (n callNode) eval(input interface{}) ([]interface{}, error) {
+	switch n.name {
+	case "select":
+		conditions, err := n.arg.eval(input)
+		if err != nil {
+			return nil, err
+		}
+		var out []interface{}
+		for _, c := range conditions {
+			if truthy(c) {
+				out = append(out, input)
+			}
+		}
+		return out, nil
+	case "map":
+		return collectNode{pipeNode{iterateNode{}, n.arg}}.eval(input)
+	case "not":
+		return []interface{}{!truthy(input)}, nil
+	case "length":
+		return lengthOf(input)
+	case "keys":
+		obj, ok := input.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s has no keys", typeName(input))
+		}
+		keys := sortedKeys(obj)
+		out := make([]interface{}, len(keys))
+		for i, k := range keys {
+			out[i] = k
+		}
+		return []interface{}{out}, nil
+	}
+
+	arr, ok := input.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s requires an array, got %s", n.name, typeName(input))
+	}
+	switch n.name {
+	case "first", "last":
+		if len(arr) == 0 {
+			return []interface{}{nil}, nil
+		}
+		if n.name == "first" {
+			return []interface{}{arr[0]}, nil
+		}
+		return []interface{}{arr[len(arr)-1]}, nil
+	case "add", "avg":
+		var sum interface{}
+		for _, v := range arr {
+			var err error
+			if sum, err = applyBinary("+", sum, v); err != nil {
+				return nil, err
+			}
+		}
+		if n.name == "avg" {
+			if len(arr) == 0 {
+				return []interface{}{nil}, nil
+			}
+			return wrap(applyBinary("/", sum, float64(len(arr))))
+		}
+		return []interface{}{sum}, nil
+	}
+
+	// Sorting builtins, keyed by the argument or by the value itself
+	sortKeys := make([]interface{}, len(arr))
+	for i, v := range arr {
+		sortKeys[i] = v
+		if n.arg != nil {
+			keys, err := n.arg.eval(v)
+			if err != nil {
+				return nil, err
+			}
+			sortKeys[i] = nil
+			if len(keys) > 0 {
+				sortKeys[i] = keys[0]
+			}
+		}
+	}
+	order := make([]int, len(arr))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return compareValues(sortKeys[order[a]], sortKeys[order[b]]) < 0
+	})
+
+	switch n.name {
+	case "sort", "sort_by":
+		sorted := make([]interface{}, len(arr))
+		for i, idx := range order {
+			sorted[i] = arr[idx]
+		}
+		return []interface{}{sorted}, nil
+	case "min", "min_by":
+		if len(arr) == 0 {
+			return []interface{}{nil}, nil
+		}
+		return []interface{}{arr[order[0]]}, nil
+	default: // max, max_by
+		if len(arr) == 0 {
+			return []interface{}{nil}, nil
+		}
+		return []interface{}{arr[order[len(order)-1]]}, nil
+	}
+}
+
+func wrap(v interface{}, err error) ([]interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{v}, nil
+}
+
+func lengthOf(input interface{}) ([]interface{}, error) {
+	switch v := input.(type) {
+	case nil:
+		return []interface{}{0.0}, nil
+	case string:
+		return []interface{}{float64(len([]rune(v)))}, nil
+	case []interface{}:
+		return []interface{}{float64(len(v))}, nil
+	case map[string]interface{}:
+		return []interface{}{float64(len(v))}, nil
+	case float64:
+		return []interface{}{math.Abs(v)}, nil
+	}
+	return nil, fmt.Errorf("%s has no length", typeName(input))
+}
+
+// truthy follows jq: only false and null are false
+func truthy(v interface{}) bool {
+	switch b := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return b
+	}
+	return true
+}
+
+// typeRank orders values of different types as jq does
+func typeRank(v interface{}) int {
+	switch b := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if b {
+			return 2
+		}
+		return 1
+	case float64:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	}
+	return 6
+}
+
+// compareValues returns -1, 0 or 1, ordering across types like jq
+func compareValues(a, b interface{}) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+	switch av := a.(type) {
+	case float64:
+		bv := b.(float64)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(av, b.(string))
+	case []interface{}:
+		bv := b.([]interface{})
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if c := compareValues(av[i], bv[i]); c != 0 {
+				return c
+			}
+		}
+		return compareInts(len(av), len(bv))
+	case map[string]interface{}:
+		if reflect.DeepEqual(a, b) {
+			return 0
+		}
+		return compareInts(len(av), len(b.(map[string]interface{})))
+	}
+	return 0
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
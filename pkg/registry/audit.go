@@ -0,0 +1,155 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Audit statuses of an inventory image
+const (
+	StatusOK          = "ok"
+	StatusMissing     = "missing"     // Manifest no longer exists (deleted tag or digest)
+	StatusOverwritten = "overwritten" // Tag now points at a different digest
+	StatusError       = "error"       // Registry could not be checked
+)
+
+// maxPrintedDrift limits the drifted images listed on the console
+const maxPrintedDrift = 50
+
+// AuditFinding is the audit result of one inventory image
+type AuditFinding struct {
+	Image         InventoryImage `json:"image"`
+	Status        string         `json:"status"`
+	CurrentDigest string         `json:"current_digest,omitempty"` // Digest the tag points at now, for overwritten tags
+	Error         string         `json:"error,omitempty"`
+}
+
+// AuditReport summarizes the drift of a registry from its inventory
+type AuditReport struct {
+	Registry    string         `json:"registry"`
+	Inventory   string         `json:"inventory"`
+	StartedAt   time.Time      `json:"started_at"`
+	Duration    time.Duration  `json:"duration_seconds"`
+	Checked     int            `json:"checked"`
+	OK          int            `json:"ok"`
+	Missing     int            `json:"missing"`
+	Overwritten int            `json:"overwritten"`
+	Errors      int            `json:"errors"`
+	Drift       []AuditFinding `json:"drift"` // Every finding other than ok, in inventory order
+}
+
+// HasDrift returns true if any image is missing or overwritten
+func (r *AuditReport) HasDrift() bool {
+	return r.Missing > 0 || r.Overwritten > 0
+}
+
+// FormatJSON returns the report as indented JSON
+func (r *AuditReport) FormatJSON() (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Audit checks every inventory image with a manifest HEAD request, running up
+// to concurrency requests at once
+func Audit(ctx context.Context, client *Client, inv *Inventory, concurrency int) *AuditReport {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	report := &AuditReport{Registry: client.Host(), StartedAt: time.Now(), Drift: make([]AuditFinding, 0)}
+	findings := make([]AuditFinding, len(inv.Images))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				findings[i] = checkImage(ctx, client, inv.Images[i])
+			}
+		}()
+	}
+	for i := range inv.Images {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, finding := range findings {
+		report.Checked++
+		switch finding.Status {
+		case StatusOK:
+			report.OK++
+			continue
+		case StatusMissing:
+			report.Missing++
+		case StatusOverwritten:
+			report.Overwritten++
+		default:
+			report.Errors++
+		}
+		report.Drift = append(report.Drift, finding)
+	}
+	report.Duration = time.Since(report.StartedAt)
+	return report
+}
+
+// checkImage resolves the tag when the inventory has one, otherwise the digest
+func checkImage(ctx context.Context, client *Client, img InventoryImage) AuditFinding {
+	finding := AuditFinding{Image: img, Status: StatusOK}
+	reference := img.Tag
+	if reference == "" {
+		reference = img.Digest
+	}
+
+	digest, status, err := client.HeadManifest(ctx, img.Repository, reference)
+	switch {
+	case err != nil:
+		finding.Status = StatusError
+		finding.Error = err.Error()
+	case status == http.StatusNotFound:
+		finding.Status = StatusMissing
+	case img.Tag != "" && img.Digest != "" && digest != "" && digest != img.Digest:
+		finding.Status = StatusOverwritten
+		finding.CurrentDigest = digest
+	}
+	return finding
+}
+
+// PrintSummary prints the audit totals and the drifted images
+func (r *AuditReport) PrintSummary() {
+	fmt.Printf("╔═══════════════════════════════════════════════════════════════╗\n")
+	fmt.Printf("║                   Mirror Integrity Audit                      ║\n")
+	fmt.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+	fmt.Printf("Registry:  %s\n", r.Registry)
+	fmt.Printf("Inventory: %s\n", r.Inventory)
+	fmt.Printf("Checked %d images in %v: %d ok | %d missing | %d overwritten | %d errors\n\n",
+		r.Checked, r.Duration.Round(time.Millisecond), r.OK, r.Missing, r.Overwritten, r.Errors)
+
+	if len(r.Drift) == 0 {
+		fmt.Printf("✅ No drift: every inventory image is present and unchanged\n")
+		return
+	}
+
+	for i, finding := range r.Drift {
+		if i == maxPrintedDrift {
+			fmt.Printf("  ... %d more (use --json for the full list)\n", len(r.Drift)-maxPrintedDrift)
+			break
+		}
+		switch finding.Status {
+		case StatusOverwritten:
+			fmt.Printf("  %-11s %s (now %s)\n", finding.Status, finding.Image.Reference(), finding.CurrentDigest)
+		case StatusError:
+			fmt.Printf("  %-11s %s: %s\n", finding.Status, finding.Image.Reference(), finding.Error)
+		default:
+			fmt.Printf("  %-11s %s\n", finding.Status, finding.Image.Reference())
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Blob is a content-addressed object an image is stored as: a manifest, a
+// config or a layer
+type Blob struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// imageManifest holds the fields of an image manifest or index that
+// reference other content
+type imageManifest struct {
+	Config    *Blob  `json:"config"`
+	Layers    []Blob `json:"layers"`
+	Manifests []Blob `json:"manifests"` // Index or manifest list entries
+}
+
+// ImageBlobs returns every blob of a tag or digest: its manifest, and for an
+// index or manifest list the manifests of all platforms, with their configs
+// and layers. Blobs shared by several platforms are listed once.
+func (c *Client) ImageBlobs(ctx context.Context, repository, reference string) ([]Blob, error) {
+	seen := make(map[string]bool)
+	var blobs []Blob
+	add := func(blob Blob) {
+		if blob.Digest != "" && !seen[blob.Digest] {
+			seen[blob.Digest] = true
+			blobs = append(blobs, blob)
+		}
+	}
+
+	pending := []string{reference}
+	for len(pending) > 0 {
+		ref := pending[0]
+		pending = pending[1:]
+
+		data, err := c.GetManifest(ctx, repository, ref)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		add(Blob{Digest: "sha256:" + hex.EncodeToString(sum[:]), Size: int64(len(data))})
+
+		var manifest imageManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest %s:%s: %w", repository, ref, err)
+		}
+		if manifest.Config != nil {
+			add(*manifest.Config)
+		}
+		for _, layer := range manifest.Layers {
+			add(layer)
+		}
+		for _, child := range manifest.Manifests {
+			if !seen[child.Digest] {
+				pending = append(pending, child.Digest)
+			}
+		}
+	}
+	return blobs, nil
+}
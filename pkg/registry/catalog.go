@@ -0,0 +1,254 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// Sources of a repository listing
+const (
+	CatalogSourceV2     = "catalog" // /v2/_catalog
+	CatalogSourceQuay   = "quay"    // /api/v1/repository of the namespace
+	CatalogSourceHarbor = "harbor"  // /api/v2.0/projects/<project>/repositories
+)
+
+// maxListedChanges limits the added, changed and removed repositories stored per diff
+const maxListedChanges = 200
+
+// CatalogSnapshot is the tag count of every repository under a prefix at one point in time
+type CatalogSnapshot struct {
+	Source   string
+	Prefix   string
+	TakenAt  time.Time
+	Duration time.Duration
+	Tags     map[string]int // Tag count by repository
+}
+
+// CatalogDiff is how the repositories under a prefix changed between two snapshots
+type CatalogDiff struct {
+	Registry           string             `json:"registry"`
+	Prefix             string             `json:"prefix,omitempty"`
+	Source             string             `json:"source"`           // catalog, quay or harbor
+	Duration           time.Duration      `json:"duration_seconds"` // Both listings
+	RepositoriesBefore int                `json:"repositories_before"`
+	RepositoriesAfter  int                `json:"repositories_after"`
+	TagsBefore         int                `json:"tags_before"`
+	TagsAfter          int                `json:"tags_after"`
+	Added              []RepositoryChange `json:"added,omitempty"`
+	Changed            []RepositoryChange `json:"changed,omitempty"` // Repositories whose tag count changed
+	Removed            []RepositoryChange `json:"removed,omitempty"`
+	AddedCount         int                `json:"added_count"` // Including repositories beyond the listed ones
+	ChangedCount       int                `json:"changed_count"`
+	RemovedCount       int                `json:"removed_count"`
+}
+
+// RepositoryChange is the tag count of a repository before and after
+type RepositoryChange struct {
+	Repository string `json:"repository"`
+	TagsBefore int    `json:"tags_before"`
+	TagsAfter  int    `json:"tags_after"`
+}
+
+// SnapshotCatalog lists the repositories under prefix with their tag counts.
+// Registries that restrict /v2/_catalog, such as Quay and Harbor for
+// non-admin users, are listed through their own API in the namespace or
+// project the prefix starts with.
+func SnapshotCatalog(ctx context.Context, client *Client, prefix string) (*CatalogSnapshot, error) {
+	startTime := time.Now()
+	prefix = strings.Trim(prefix, "/")
+	snapshot := &CatalogSnapshot{Source: CatalogSourceV2, Prefix: prefix, TakenAt: startTime, Tags: make(map[string]int)}
+
+	repositories, err := client.Repositories(ctx)
+	if err != nil || len(underPrefix(repositories, prefix)) == 0 {
+		namespace, _, _ := strings.Cut(prefix, "/")
+		if namespace == "" {
+			if err != nil {
+				return nil, fmt.Errorf("failed to list repositories: %w", err)
+			}
+			return snapshot, nil
+		}
+		if quay, quayErr := client.quayRepositories(ctx, namespace); quayErr == nil {
+			snapshot.Source, repositories, err = CatalogSourceQuay, quay, nil
+		} else if harbor, harborErr := client.harborRepositories(ctx, namespace); harborErr == nil {
+			snapshot.Source, repositories, err = CatalogSourceHarbor, harbor, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to list repositories: %w", err)
+		}
+	}
+
+	for _, repository := range underPrefix(repositories, prefix) {
+		tags, err := client.Tags(ctx, repository)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Tags[repository] = len(tags)
+	}
+	snapshot.Duration = time.Since(startTime)
+	return snapshot, nil
+}
+
+// underPrefix returns the repositories below prefix, all of them for an empty prefix
+func underPrefix(repositories []string, prefix string) []string {
+	if prefix == "" {
+		return repositories
+	}
+	var matched []string
+	for _, repository := range repositories {
+		if strings.HasPrefix(repository, prefix+"/") {
+			matched = append(matched, repository)
+		}
+	}
+	return matched
+}
+
+// quayRepositories lists the repositories of a Quay namespace
+func (c *Client) quayRepositories(ctx context.Context, namespace string) ([]string, error) {
+	var repositories []string
+	query := url.Values{"namespace": {namespace}}
+	for {
+		body, err := c.getAPI(ctx, c.url("/api/v1/repository?%s", query.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Repositories []struct {
+				Namespace string `json:"namespace"`
+				Name      string `json:"name"`
+			} `json:"repositories"`
+			NextPage string `json:"next_page"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse Quay repositories: %w", err)
+		}
+		for _, repo := range page.Repositories {
+			repositories = append(repositories, repo.Namespace+"/"+repo.Name)
+		}
+		if page.NextPage == "" {
+			return repositories, nil
+		}
+		query.Set("next_page", page.NextPage)
+	}
+}
+
+// harborRepositories lists the repositories of a Harbor project
+func (c *Client) harborRepositories(ctx context.Context, project string) ([]string, error) {
+	var repositories []string
+	for pageNumber := 1; ; pageNumber++ {
+		body, err := c.getAPI(ctx, c.url("/api/v2.0/projects/%s/repositories?page=%d&page_size=100", url.PathEscape(project), pageNumber))
+		if err != nil {
+			return nil, err
+		}
+		var page []struct {
+			Name string `json:"name"` // project/repository
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse Harbor repositories: %w", err)
+		}
+		for _, repo := range page {
+			repositories = append(repositories, repo.Name)
+		}
+		if len(page) < 100 {
+			return repositories, nil
+		}
+	}
+}
+
+// getAPI fetches a JSON document of a registry's own API with the registry credentials
+func (c *Client) getAPI(ctx context.Context, target string) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, target, "registry:catalog:*", map[string]string{"Accept": "application/json"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned %s", target, resp.Status)
+	}
+	var body json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", target, err)
+	}
+	return body, nil
+}
+
+// DiffCatalogs compares two snapshots of the same prefix
+func DiffCatalogs(registry string, before, after *CatalogSnapshot) *CatalogDiff {
+	diff := &CatalogDiff{
+		Registry:           registry,
+		Prefix:             after.Prefix,
+		Source:             after.Source,
+		Duration:           before.Duration + after.Duration,
+		RepositoriesBefore: len(before.Tags),
+		RepositoriesAfter:  len(after.Tags),
+	}
+	for repository, tags := range after.Tags {
+		diff.TagsAfter += tags
+		previous, existed := before.Tags[repository]
+		change := RepositoryChange{Repository: repository, TagsBefore: previous, TagsAfter: tags}
+		switch {
+		case !existed:
+			diff.AddedCount++
+			diff.Added = append(diff.Added, change)
+		case previous != tags:
+			diff.ChangedCount++
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+	for repository, tags := range before.Tags {
+		diff.TagsBefore += tags
+		if _, exists := after.Tags[repository]; !exists {
+			diff.RemovedCount++
+			diff.Removed = append(diff.Removed, RepositoryChange{Repository: repository, TagsBefore: tags})
+		}
+	}
+	diff.Added = sortedChanges(diff.Added)
+	diff.Changed = sortedChanges(diff.Changed)
+	diff.Removed = sortedChanges(diff.Removed)
+	return diff
+}
+
+func sortedChanges(changes []RepositoryChange) []RepositoryChange {
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Repository < changes[j].Repository })
+	if len(changes) > maxListedChanges {
+		changes = changes[:maxListedChanges]
+	}
+	return changes
+}
+
+// TagsAdded returns the net number of tags the upload published
+func (d *CatalogDiff) TagsAdded() int {
+	return d.TagsAfter - d.TagsBefore
+}
+
+// PrintSummary prints the repositories and tags the upload published
+func (d *CatalogDiff) PrintSummary() {
+	if d == nil {
+		return
+	}
+	where := d.Registry
+	if d.Prefix != "" {
+		where += "/" + d.Prefix
+	}
+	logging.Printf("  │ ─── Registry Catalog (%s, %s) ───────────────────────────\n", where, d.Source)
+	logging.Printf("  │   Repositories: %d -> %d | Tags: %d -> %d (%+d)\n",
+		d.RepositoriesBefore, d.RepositoriesAfter, d.TagsBefore, d.TagsAfter, d.TagsAdded())
+	logging.Printf("  │   Added: %d | Tag count changed: %d | Removed: %d (listed in %v)\n",
+		d.AddedCount, d.ChangedCount, d.RemovedCount, d.Duration.Round(time.Millisecond))
+	for i, change := range d.Added {
+		if i == 5 {
+			logging.Printf("  │   ... and %d more\n", d.AddedCount-i)
+			break
+		}
+		logging.Printf("  │   + %s (%d tags)\n", change.Repository, change.TagsAfter)
+	}
+	for _, change := range d.Removed {
+		logging.Printf("  │   Warning: %s disappeared during the upload (%d tags before)\n", change.Repository, change.TagsBefore)
+	}
+}
@@ -0,0 +1,414 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// manifestAcceptTypes are the manifest media types requested from the registry
+var manifestAcceptTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.v1+prettyjws",
+}
+
+// maxManifestBytes bounds a manifest read; registries reject larger manifests anyway
+const maxManifestBytes = 4 << 20
+
+var (
+	challengeParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+	nextLinkPattern       = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+)
+
+// Client talks to the Docker Registry HTTP API v2 of one registry
+type Client struct {
+	host       string // host[:port]
+	scheme     string
+	username   string
+	password   string
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]string // Bearer tokens by scope
+}
+
+// NewClient creates a client for a registry given as host[:port], optionally
+// prefixed with docker:// or https://. Credentials are read from authFile, or
+// from the default containers and docker auth files when authFile is empty.
+func NewClient(registryURL, authFile string, skipTLS bool) (*Client, error) {
+	host, scheme := ParseRegistryHost(registryURL)
+	if host == "" {
+		return nil, fmt.Errorf("invalid registry URL %q", registryURL)
+	}
+
+	c := &Client{
+		host:   host,
+		scheme: scheme,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig:     &tls.Config{InsecureSkipVerify: skipTLS},
+				MaxIdleConnsPerHost: 16,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		tokens: make(map[string]string),
+	}
+
+	username, password, err := lookupCredentials(host, authFile)
+	if err != nil {
+		return nil, err
+	}
+	c.username, c.password = username, password
+	return c, nil
+}
+
+// Host returns the registry host[:port]
+func (c *Client) Host() string {
+	return c.host
+}
+
+// ParseRegistryHost returns the host[:port] and URL scheme of a registry URL.
+// Anything after the host is ignored; http:// selects plain HTTP.
+func ParseRegistryHost(registryURL string) (host, scheme string) {
+	scheme = "https"
+	rest := registryURL
+	if idx := strings.Index(rest, "://"); idx >= 0 {
+		if rest[:idx] == "http" {
+			scheme = "http"
+		}
+		rest = rest[idx+3:]
+	}
+	host = strings.SplitN(rest, "/", 2)[0]
+	return host, scheme
+}
+
+// HeadManifest resolves a tag or digest with a manifest HEAD request. It returns
+// the manifest digest and HTTP status; a missing manifest is not an error.
+func (c *Client) HeadManifest(ctx context.Context, repository, reference string) (string, int, error) {
+	resp, err := c.do(ctx, http.MethodHead, c.url("/v2/%s/manifests/%s", repository, reference), repository,
+		map[string]string{"Accept": strings.Join(manifestAcceptTypes, ", ")})
+	if err != nil {
+		return "", 0, err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Header.Get("Docker-Content-Digest"), resp.StatusCode, nil
+	case http.StatusNotFound:
+		return "", resp.StatusCode, nil
+	default:
+		return "", resp.StatusCode, fmt.Errorf("manifest HEAD %s:%s returned %s", repository, reference, resp.Status)
+	}
+}
+
+// GetManifest fetches the manifest of a tag or digest
+func (c *Client) GetManifest(ctx context.Context, repository, reference string) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.url("/v2/%s/manifests/%s", repository, reference), repository,
+		map[string]string{"Accept": strings.Join(manifestAcceptTypes, ", ")})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("manifest GET %s:%s returned %s", repository, reference, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s:%s: %w", repository, reference, err)
+	}
+	return body, nil
+}
+
+// GetBlob fetches a blob of up to maxBytes and checks it against its digest
+func (c *Client) GetBlob(ctx context.Context, repository, digest string, maxBytes int64) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.url("/v2/%s/blobs/%s", repository, digest), repository, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("blob GET %s@%s returned %s", repository, digest, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s@%s: %w", repository, digest, err)
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, fmt.Errorf("blob %s@%s is larger than %d bytes", repository, digest, maxBytes)
+	}
+	if sum := sha256.Sum256(body); "sha256:"+hex.EncodeToString(sum[:]) != digest {
+		return nil, fmt.Errorf("blob %s@%s does not match its digest", repository, digest)
+	}
+	return body, nil
+}
+
+// Repositories lists the repositories in the registry catalog
+func (c *Client) Repositories(ctx context.Context) ([]string, error) {
+	var repositories []string
+	err := c.paginate(ctx, c.url("/v2/_catalog?n=1000"), "registry:catalog:*", func(body []byte) error {
+		var page struct {
+			Repositories []string `json:"repositories"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to parse catalog: %w", err)
+		}
+		repositories = append(repositories, page.Repositories...)
+		return nil
+	})
+	return repositories, err
+}
+
+// Tags lists the tags of a repository
+func (c *Client) Tags(ctx context.Context, repository string) ([]string, error) {
+	var tags []string
+	err := c.paginate(ctx, c.url("/v2/%s/tags/list?n=1000", repository), repository, func(body []byte) error {
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("failed to parse tags of %s: %w", repository, err)
+		}
+		tags = append(tags, page.Tags...)
+		return nil
+	})
+	return tags, err
+}
+
+// paginate follows the Link headers of a listing endpoint
+func (c *Client) paginate(ctx context.Context, next, scope string, page func(body []byte) error) error {
+	for next != "" {
+		resp, err := c.do(ctx, http.MethodGet, next, scope, nil)
+		if err != nil {
+			return err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", next, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("GET %s returned %s", next, resp.Status)
+		}
+		if err := page(body); err != nil {
+			return err
+		}
+
+		next = ""
+		if m := nextLinkPattern.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			if strings.HasPrefix(m[1], "/") {
+				next = c.scheme + "://" + c.host + m[1]
+			} else {
+				next = m[1]
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Client) url(format string, args ...interface{}) string {
+	return c.scheme + "://" + c.host + fmt.Sprintf(format, args...)
+}
+
+// do sends a request, answering a Bearer challenge once. scope is a repository
+// name, or a full token scope when it contains ":".
+func (c *Client) do(ctx context.Context, method, target, scope string, headers map[string]string) (*http.Response, error) {
+	if !strings.Contains(scope, ":") {
+		scope = "repository:" + scope + ":pull"
+	}
+
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, target, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		c.mu.Lock()
+		token := c.tokens[scope]
+		c.mu.Unlock()
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else if c.username != "" {
+			req.SetBasicAuth(c.username, c.password)
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s failed: %w", method, target, err)
+		}
+		return resp, nil
+	}
+
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return nil, fmt.Errorf("%s %s: unauthorized (check the registry credentials)", method, target)
+	}
+	if err := c.fetchToken(ctx, challenge, scope); err != nil {
+		return nil, err
+	}
+	return send()
+}
+
+// fetchToken obtains a Bearer token for scope from the realm in the challenge
+func (c *Client) fetchToken(ctx context.Context, challenge, scope string) error {
+	params := make(map[string]string)
+	for _, m := range challengeParamPattern.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(m[1])] = m[2]
+	}
+	realm := params["realm"]
+	if realm == "" {
+		return fmt.Errorf("registry auth challenge has no realm: %s", challenge)
+	}
+
+	query := url.Values{}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", scope)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create token request: %w", err)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry token request returned %s", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to parse registry token: %w", err)
+	}
+	token := body.Token
+	if token == "" {
+		token = body.AccessToken
+	}
+
+	c.mu.Lock()
+	c.tokens[scope] = token
+	c.mu.Unlock()
+	return nil
+}
+
+// lookupCredentials reads the credentials for host from a containers-auth.json
+// style file. Without authFile, the podman and docker default locations are tried.
+func lookupCredentials(host, authFile string) (string, string, error) {
+	candidates := []string{authFile}
+	if authFile == "" {
+		candidates = defaultAuthFiles()
+	}
+
+	for _, path := range candidates {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if authFile != "" {
+				return "", "", fmt.Errorf("failed to read auth file: %w", err)
+			}
+			continue
+		}
+		var file struct {
+			Auths map[string]struct {
+				Auth string `json:"auth"`
+			} `json:"auths"`
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return "", "", fmt.Errorf("failed to parse auth file %s: %w", path, err)
+		}
+		for key, entry := range file.Auths {
+			keyHost, _ := ParseRegistryHost(key)
+			if keyHost != host || entry.Auth == "" {
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return "", "", fmt.Errorf("invalid credentials for %s in %s: %w", host, path, err)
+			}
+			username, password, _ := strings.Cut(string(decoded), ":")
+			return username, password, nil
+		}
+	}
+	return "", "", nil
+}
+
+// defaultAuthFiles returns the auth files oc-mirror and podman read by default
+func defaultAuthFiles() []string {
+	var files []string
+	if path := os.Getenv("REGISTRY_AUTH_FILE"); path != "" {
+		files = append(files, path)
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		files = append(files, filepath.Join(dir, "containers", "auth.json"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files,
+			filepath.Join(home, ".config", "containers", "auth.json"),
+			filepath.Join(home, ".docker", "config.json"))
+	}
+	return files
+}
+
+// AliasAuthFile writes to path a copy of the first default auth file holding
+// credentials for host, with alias added under the same credentials, so a
+// client pushing to host through a proxy at alias authenticates as it would
+// with host. It returns false when no auth file has credentials for host.
+func AliasAuthFile(host, alias, path string) (bool, error) {
+	for _, candidate := range defaultAuthFiles() {
+		data, err := os.ReadFile(candidate)
+		if err != nil {
+			continue
+		}
+		var file map[string]interface{}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return false, fmt.Errorf("failed to parse auth file %s: %w", candidate, err)
+		}
+		auths, _ := file["auths"].(map[string]interface{})
+		for key, entry := range auths {
+			if keyHost, _ := ParseRegistryHost(key); keyHost != host {
+				continue
+			}
+			auths[alias] = entry
+			out, err := json.MarshalIndent(file, "", "  ")
+			if err != nil {
+				return false, err
+			}
+			if err := os.WriteFile(path, out, 0600); err != nil {
+				return false, fmt.Errorf("failed to write auth file: %w", err)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// NewAuditCommand creates a cobra command that checks a registry against an image inventory
+func NewAuditCommand() *cobra.Command {
+	var registryURL, inventoryFile, authFile, prefix string
+	var skipTLS, export, jsonOutput bool
+	var concurrency int
+
+	cmd := &cobra.Command{
+		Use:   "audit --registry <url> --inventory <file>",
+		Short: "Verify a mirror registry still holds every image of an inventory",
+		Long: "Sends a manifest HEAD request for every image in the inventory and reports drift: images that were deleted and tags that now point at a different digest. " +
+			"The inventory is the JSON written by --export, an oc-mirror mapping.txt, or one image reference per line. " +
+			"Exits non-zero when drift is found, so it can run as a periodic check on long-lived disconnected registries.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := NewClient(registryURL, authFile, skipTLS)
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+
+			if export {
+				fmt.Printf("Exporting inventory of %s...\n", client.Host())
+				inv, err := ExportInventory(ctx, client, prefix)
+				if err != nil {
+					return err
+				}
+				if err := inv.Save(inventoryFile); err != nil {
+					return err
+				}
+				fmt.Printf("✅ Wrote %d images to %s\n", len(inv.Images), inventoryFile)
+				return nil
+			}
+
+			inv, err := LoadInventory(inventoryFile)
+			if err != nil {
+				return err
+			}
+			if inv.Registry != "" && inv.Registry != client.Host() {
+				fmt.Printf("Note: inventory was taken from %s, auditing %s\n", inv.Registry, client.Host())
+			}
+
+			report := Audit(ctx, client, inv, concurrency)
+			report.Inventory = inventoryFile
+			if jsonOutput {
+				out, err := report.FormatJSON()
+				if err != nil {
+					return fmt.Errorf("failed to format report: %w", err)
+				}
+				fmt.Println(out)
+			} else {
+				report.PrintSummary()
+			}
+
+			if report.HasDrift() {
+				return fmt.Errorf("registry drift detected: %d missing, %d overwritten", report.Missing, report.Overwritten)
+			}
+			if report.Errors > 0 {
+				return fmt.Errorf("%d image(s) could not be checked", report.Errors)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&registryURL, "registry", "r", "", "Registry to audit (host:port, docker:// prefix allowed)")
+	cmd.Flags().StringVar(&inventoryFile, "inventory", "", "Inventory file to check against (written with --export)")
+	cmd.Flags().BoolVar(&export, "export", false, "Write the current registry content to --inventory instead of auditing")
+	cmd.Flags().StringVar(&prefix, "prefix", "", "With --export, only include repositories under this path")
+	cmd.Flags().StringVar(&authFile, "authfile", "", "Registry credentials file (default: REGISTRY_AUTH_FILE, containers or docker auth files)")
+	cmd.Flags().BoolVar(&skipTLS, "skip-tls", false, "Skip TLS verification for the registry")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 8, "Number of parallel manifest requests")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the report as JSON")
+	cmd.MarkFlagRequired("registry")
+	cmd.MarkFlagRequired("inventory")
+
+	return cmd
+}
@@ -0,0 +1,160 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentDigests is every blob the repositories under a prefix reference: the
+// manifests of their tags with the configs and layers of those manifests
+type ContentDigests struct {
+	Repositories int
+	Tags         int
+	Blobs        map[string]int64 // Size by digest
+	Errors       []string         // Tags whose manifests could not be read
+	Duration     time.Duration
+}
+
+// TagDigests is the manifest digest every tag under a prefix points to
+type TagDigests struct {
+	Repositories int
+	Digests      map[string]string // Digest by repository:tag
+	Errors       []string          // Tags that could not be resolved
+	Duration     time.Duration
+}
+
+// taggedImage is a tag of a repository
+type taggedImage struct {
+	repository string
+	tag        string
+}
+
+// String returns the image as repository:tag
+func (image taggedImage) String() string {
+	return image.repository + ":" + image.tag
+}
+
+// listTaggedImages lists the tags of every repository under prefix
+func listTaggedImages(ctx context.Context, client *Client, prefix string) ([]taggedImage, int, error) {
+	repositories, err := client.Repositories(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list repositories: %w", err)
+	}
+	sort.Strings(repositories)
+
+	var images []taggedImage
+	count := 0
+	for _, repository := range repositories {
+		if prefix != "" && !strings.HasPrefix(repository, strings.Trim(prefix, "/")+"/") {
+			continue
+		}
+		tags, err := client.Tags(ctx, repository)
+		if err != nil {
+			return nil, 0, err
+		}
+		count++
+		for _, tag := range tags {
+			images = append(images, taggedImage{repository: repository, tag: tag})
+		}
+	}
+	return images, count, nil
+}
+
+// forEachImage runs fn for every image, up to concurrency at once
+func forEachImage(images []taggedImage, concurrency int, fn func(image taggedImage)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	jobs := make(chan taggedImage)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for image := range jobs {
+				fn(image)
+			}
+		}()
+	}
+	for _, image := range images {
+		jobs <- image
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// ListContentDigests lists the tags of every repository under prefix and
+// collects the blobs of each tag, running up to concurrency manifest walks at
+// once. A tag that cannot be read is recorded and the listing continues.
+func ListContentDigests(ctx context.Context, client *Client, prefix string, concurrency int) (*ContentDigests, error) {
+	startTime := time.Now()
+	content := &ContentDigests{Blobs: make(map[string]int64)}
+
+	images, repositories, err := listTaggedImages(ctx, client, prefix)
+	if err != nil {
+		return nil, err
+	}
+	content.Repositories = repositories
+	content.Tags = len(images)
+
+	var mu sync.Mutex
+	forEachImage(images, concurrency, func(image taggedImage) {
+		blobs, err := client.ImageBlobs(ctx, image.repository, image.tag)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			content.Errors = append(content.Errors, fmt.Sprintf("%s: %v", image, err))
+		}
+		for _, blob := range blobs {
+			content.Blobs[blob.Digest] = blob.Size
+		}
+	})
+
+	sort.Strings(content.Errors)
+	content.Duration = time.Since(startTime)
+	return content, nil
+}
+
+// ListTagDigests resolves every tag of the repositories under prefix to the
+// digest of its manifest with HEAD requests, up to concurrency at once. A tag
+// that cannot be resolved is recorded and the listing continues.
+func ListTagDigests(ctx context.Context, client *Client, prefix string, concurrency int) (*TagDigests, error) {
+	startTime := time.Now()
+	digests := &TagDigests{Digests: make(map[string]string)}
+
+	images, repositories, err := listTaggedImages(ctx, client, prefix)
+	if err != nil {
+		return nil, err
+	}
+	digests.Repositories = repositories
+
+	var mu sync.Mutex
+	forEachImage(images, concurrency, func(image taggedImage) {
+		digest, _, err := client.HeadManifest(ctx, image.repository, image.tag)
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case err != nil:
+			digests.Errors = append(digests.Errors, fmt.Sprintf("%s: %v", image, err))
+		case digest != "":
+			digests.Digests[image.String()] = digest
+		}
+	})
+
+	sort.Strings(digests.Errors)
+	digests.Duration = time.Since(startTime)
+	return digests, nil
+}
+
+// Manifests returns the number of distinct manifests the tags point to
+func (d *TagDigests) Manifests() int {
+	distinct := make(map[string]bool)
+	for _, digest := range d.Digests {
+		distinct[digest] = true
+	}
+	return len(distinct)
+}
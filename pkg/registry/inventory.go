@@ -0,0 +1,164 @@
+package registry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Inventory lists the images expected in a mirror registry
+type Inventory struct {
+	Registry    string           `json:"registry"` // Registry the inventory was exported from
+	GeneratedAt time.Time        `json:"generated_at"`
+	Images      []InventoryImage `json:"images"`
+}
+
+// InventoryImage is one image of an inventory. Either Tag or Digest may be
+// empty; with both set, the tag is expected to still point at the digest.
+type InventoryImage struct {
+	Repository string `json:"repository"` // Path within the registry, without the host
+	Tag        string `json:"tag,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+}
+
+// Reference returns the image reference without the registry host
+func (img InventoryImage) Reference() string {
+	ref := img.Repository
+	if img.Tag != "" {
+		ref += ":" + img.Tag
+	}
+	if img.Digest != "" {
+		ref += "@" + img.Digest
+	}
+	return ref
+}
+
+// LoadInventory reads an inventory file. Besides the JSON written by
+// "audit --export", it accepts an oc-mirror mapping.txt (source=destination
+// per line) and plain text with one image reference per line.
+func LoadInventory(path string) (*Inventory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %w", err)
+	}
+
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "{") {
+		var inv Inventory
+		if err := json.Unmarshal(data, &inv); err != nil {
+			return nil, fmt.Errorf("failed to parse inventory %s: %w", path, err)
+		}
+		return &inv, nil
+	}
+
+	inv := &Inventory{}
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// mapping.txt: the destination carries the tag, the source the digest
+		var sourceDigest string
+		if source, destination, ok := strings.Cut(line, "="); ok {
+			if _, digest, hasDigest := strings.Cut(source, "@"); hasDigest {
+				sourceDigest = digest
+			}
+			line = destination
+		}
+
+		host, img, err := ParseImageReference(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+		if img.Digest == "" {
+			img.Digest = sourceDigest
+		}
+		if inv.Registry == "" {
+			inv.Registry = host
+		}
+		inv.Images = append(inv.Images, img)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %w", err)
+	}
+	return inv, nil
+}
+
+// Save writes the inventory as JSON
+func (inv *Inventory) Save(path string) error {
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal inventory: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create inventory directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write inventory: %w", err)
+	}
+	return nil
+}
+
+// ExportInventory snapshots every tag in the registry, optionally limited to
+// repositories under prefix, with the digest it currently points at
+func ExportInventory(ctx context.Context, client *Client, prefix string) (*Inventory, error) {
+	repositories, err := client.Repositories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories: %w", err)
+	}
+	sort.Strings(repositories)
+
+	inv := &Inventory{Registry: client.Host(), GeneratedAt: time.Now()}
+	for _, repository := range repositories {
+		if prefix != "" && !strings.HasPrefix(repository, strings.Trim(prefix, "/")+"/") {
+			continue
+		}
+		tags, err := client.Tags(ctx, repository)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			digest, _, err := client.HeadManifest(ctx, repository, tag)
+			if err != nil {
+				return nil, err
+			}
+			if digest == "" {
+				continue // Deleted while listing
+			}
+			inv.Images = append(inv.Images, InventoryImage{Repository: repository, Tag: tag, Digest: digest})
+		}
+	}
+	return inv, nil
+}
+
+// ParseImageReference splits host/repository[:tag][@digest] into the registry
+// host and the inventory image. The host may carry a docker:// prefix.
+func ParseImageReference(ref string) (string, InventoryImage, error) {
+	ref = strings.TrimPrefix(ref, "docker://")
+	host, rest, ok := strings.Cut(ref, "/")
+	if !ok || !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return "", InventoryImage{}, fmt.Errorf("image reference %q has no registry host", ref)
+	}
+
+	var img InventoryImage
+	rest, img.Digest, _ = strings.Cut(rest, "@")
+	// A ":" after the last "/" separates the tag
+	if idx := strings.LastIndex(rest, ":"); idx > strings.LastIndex(rest, "/") {
+		rest, img.Tag = rest[:idx], rest[idx+1:]
+	}
+	img.Repository = rest
+	if img.Repository == "" || img.Tag == "" && img.Digest == "" {
+		return "", InventoryImage{}, fmt.Errorf("image reference %q needs a repository and a tag or digest", ref)
+	}
+	return host, img, nil
+}
@@ -0,0 +1,321 @@
+package registry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// Storage backends of a local registry
+const (
+	LocalStorageFilesystem = "filesystem" // Host directory mounted as the registry storage
+	LocalStorageInMemory   = "inmemory"   // Registry process memory, gone with the container
+)
+
+const (
+	// DefaultLocalRegistryImage is the CNCF distribution registry
+	DefaultLocalRegistryImage = "docker.io/library/registry:2"
+	// localRegistryRepository is the path the content is mirrored under
+	localRegistryRepository = "oc-mirror-test"
+	// localRegistryReadyTimeout covers the image pull and the registry start
+	localRegistryReadyTimeout = 3 * time.Minute
+	// localRegistryConfigDir is where the generated config and certificate are mounted
+	localRegistryConfigDir = "/etc/docker/registry"
+	// localRegistryStorageDir is the root directory of the filesystem storage in the container
+	localRegistryStorageDir = "/var/lib/registry"
+)
+
+// LocalRegistryConfig describes a disposable registry container started
+// before the run as its target and removed when the run ends
+type LocalRegistryConfig struct {
+	Enabled     bool   `json:"enabled" yaml:"enabled,omitempty"`
+	Runtime     string `json:"runtime,omitempty" yaml:"runtime,omitempty"`          // podman or docker; default the first installed
+	Image       string `json:"image,omitempty" yaml:"image,omitempty"`              // Registry image, default DefaultLocalRegistryImage
+	Port        int    `json:"port,omitempty" yaml:"port,omitempty"`                // Host port; 0 picks a free one
+	Storage     string `json:"storage,omitempty" yaml:"storage,omitempty"`          // filesystem (default) or inmemory
+	StoragePath string `json:"storage_path,omitempty" yaml:"storagePath,omitempty"` // filesystem: host directory, kept; default a temporary one
+}
+
+// Validate checks the runtime, storage backend and port
+func (c LocalRegistryConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	switch c.Runtime {
+	case "", "podman", "docker":
+	default:
+		return fmt.Errorf("unknown container runtime %q (valid: podman, docker)", c.Runtime)
+	}
+	switch c.Storage {
+	case "", LocalStorageFilesystem:
+	case LocalStorageInMemory:
+		if c.StoragePath != "" {
+			return fmt.Errorf("a storage path requires the %s storage", LocalStorageFilesystem)
+		}
+	default:
+		return fmt.Errorf("unknown local registry storage %q (valid: %s, %s)", c.Storage, LocalStorageFilesystem, LocalStorageInMemory)
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("local registry port must be between 0 and 65535")
+	}
+	return nil
+}
+
+// GetImage returns the registry image, defaulting to DefaultLocalRegistryImage
+func (c LocalRegistryConfig) GetImage() string {
+	if c.Image == "" {
+		return DefaultLocalRegistryImage
+	}
+	return c.Image
+}
+
+// AssignPort picks a free port when none is configured, so the registry URL
+// is known before the registry starts
+func (c *LocalRegistryConfig) AssignPort() error {
+	if c.Port != 0 {
+		return nil
+	}
+	port, err := freePort()
+	if err != nil {
+		return err
+	}
+	c.Port = port
+	return nil
+}
+
+// URL returns the registry URL the content is mirrored to, on the configured port
+func (c LocalRegistryConfig) URL() string {
+	return fmt.Sprintf("docker://localhost:%d/%s/", c.Port, localRegistryRepository)
+}
+
+// GetStorage returns the storage backend, defaulting to filesystem
+func (c LocalRegistryConfig) GetStorage() string {
+	if c.Storage == "" {
+		return LocalStorageFilesystem
+	}
+	return c.Storage
+}
+
+// LocalRegistry is a running registry container serving TLS with a
+// self-signed certificate on localhost
+type LocalRegistry struct {
+	config      LocalRegistryConfig
+	runtime     string
+	name        string
+	root        string // Temporary directory holding the config, certificate and default storage
+	storagePath string // Host directory of the filesystem storage, "" in memory
+	ownsStorage bool   // The storage is below root and removed with it
+}
+
+// StartLocalRegistry starts the registry container and waits until it answers
+func StartLocalRegistry(cfg LocalRegistryConfig) (*LocalRegistry, error) {
+	runtime, err := containerRuntime(cfg.Runtime)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.AssignPort(); err != nil {
+		return nil, err
+	}
+	port := cfg.Port
+	root, err := os.MkdirTemp("", "oc-mirror-test-registry-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local registry directory: %w", err)
+	}
+	r := &LocalRegistry{config: cfg, runtime: runtime, name: fmt.Sprintf("oc-mirror-test-registry-%d", os.Getpid()), root: root}
+
+	configDir := filepath.Join(root, "config")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		os.RemoveAll(root)
+		return nil, fmt.Errorf("failed to create local registry directory: %w", err)
+	}
+	if err := writeCertificate(configDir); err != nil {
+		os.RemoveAll(root)
+		return nil, err
+	}
+	storage := cfg.GetStorage()
+	if err := os.WriteFile(filepath.Join(configDir, "config.yml"), []byte(localRegistryConfig(storage)), 0644); err != nil {
+		os.RemoveAll(root)
+		return nil, fmt.Errorf("failed to write local registry config: %w", err)
+	}
+
+	args := []string{"run", "-d", "--name", r.name,
+		"-p", fmt.Sprintf("%d:5000", port),
+		"-v", configDir + ":" + localRegistryConfigDir + ":ro,Z"}
+	if storage == LocalStorageFilesystem {
+		r.storagePath = cfg.StoragePath
+		if r.storagePath == "" {
+			r.storagePath, r.ownsStorage = filepath.Join(root, "storage"), true
+		}
+		if r.storagePath, err = filepath.Abs(r.storagePath); err != nil {
+			os.RemoveAll(root)
+			return nil, fmt.Errorf("invalid local registry storage path: %w", err)
+		}
+		if err := os.MkdirAll(r.storagePath, 0755); err != nil {
+			os.RemoveAll(root)
+			return nil, fmt.Errorf("failed to create local registry storage: %w", err)
+		}
+		args = append(args, "-v", r.storagePath+":"+localRegistryStorageDir+":Z")
+	}
+	args = append(args, cfg.GetImage())
+
+	logging.Printf("Starting local registry %s (%s, %s storage) on port %d...\n", cfg.GetImage(), runtime, storage, port)
+	if out, err := exec.Command(runtime, args...).CombinedOutput(); err != nil {
+		os.RemoveAll(root)
+		return nil, fmt.Errorf("failed to start local registry: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	if err := r.waitReady(localRegistryReadyTimeout); err != nil {
+		logs, _ := exec.Command(runtime, "logs", "--tail", "20", r.name).CombinedOutput()
+		r.Stop()
+		return nil, fmt.Errorf("%w; container logs:\n%s", err, strings.TrimSpace(string(logs)))
+	}
+	return r, nil
+}
+
+// URL returns the registry URL the content is mirrored to
+func (r *LocalRegistry) URL() string {
+	return r.config.URL()
+}
+
+// StoragePath returns the host directory of the filesystem storage, "" in memory
+func (r *LocalRegistry) StoragePath() string {
+	return r.storagePath
+}
+
+// GCCommand returns the command garbage-collecting the blobs of deleted content
+func (r *LocalRegistry) GCCommand() string {
+	return fmt.Sprintf("%s exec %s registry garbage-collect --delete-untagged %s/config.yml", r.runtime, r.name, localRegistryConfigDir)
+}
+
+// Stop removes the container and the temporary directory with the storage it
+// holds. A storage path given in the configuration is kept.
+func (r *LocalRegistry) Stop() error {
+	var errs []error
+	if r.ownsStorage {
+		// The container may write the storage as a user the host cannot remove
+		exec.Command(r.runtime, "exec", r.name, "sh", "-c", "rm -rf "+localRegistryStorageDir+"/*").Run()
+	}
+	if out, err := exec.Command(r.runtime, "rm", "-f", r.name).CombinedOutput(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to remove local registry container %s: %w: %s", r.name, err, strings.TrimSpace(string(out))))
+	}
+	if err := os.RemoveAll(r.root); err != nil {
+		errs = append(errs, fmt.Errorf("failed to remove local registry directory: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// waitReady polls /v2/ until the registry answers
+func (r *LocalRegistry) waitReady(timeout time.Duration) error {
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	target := fmt.Sprintf("https://localhost:%d/v2/", r.config.Port)
+	for {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("local registry did not answer on %s within %v", target, timeout)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// localRegistryConfig returns the distribution config: the storage backend
+// with deletes enabled, for the delete phase, and TLS with the generated certificate
+func localRegistryConfig(storage string) string {
+	backend := "  inmemory: {}\n"
+	if storage == LocalStorageFilesystem {
+		backend = "  filesystem:\n    rootdirectory: " + localRegistryStorageDir + "\n"
+	}
+	return "version: 0.1\n" +
+		"log:\n  level: info\n" +
+		"storage:\n" + backend +
+		"  delete:\n    enabled: true\n" +
+		"http:\n  addr: :5000\n  tls:\n" +
+		"    certificate: " + localRegistryConfigDir + "/tls.crt\n" +
+		"    key: " + localRegistryConfigDir + "/tls.key\n"
+}
+
+// writeCertificate writes a self-signed certificate for localhost to dir
+func writeCertificate(dir string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate local registry key: %w", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(0, 0, 30),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("failed to create local registry certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode local registry key: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tls.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return fmt.Errorf("failed to write local registry certificate: %w", err)
+	}
+	// Readable by the registry user of the container
+	if err := os.WriteFile(filepath.Join(dir, "tls.key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0644); err != nil {
+		return fmt.Errorf("failed to write local registry key: %w", err)
+	}
+	return nil
+}
+
+// containerRuntime returns the configured runtime, or podman or docker, whichever is installed first
+func containerRuntime(name string) (string, error) {
+	candidates := []string{"podman", "docker"}
+	if name != "" {
+		candidates = []string{name}
+	}
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no container runtime found for the local registry (tried %s)", strings.Join(candidates, ", "))
+}
+
+// freePort returns a TCP port nothing listens on
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port for the local registry: %w", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,388 @@
+package registry
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// Kinds of cosign artifacts, named after the suffix of their tag
+const (
+	KindSignature   = "sig"
+	KindAttestation = "att"
+	KindSBOM        = "sbom"
+)
+
+// Verification statuses of a cosign signature or attestation
+const (
+	SignatureVerified   = "verified"   // Signed payload matches the public key and references its image
+	SignatureUnverified = "unverified" // Well-formed and references its image; no public key to check against
+	SignatureInvalid    = "invalid"    // Payload or signature does not match
+	SignatureOrphaned   = "orphaned"   // The signed image is not in the repository
+)
+
+// Layer annotations and media types cosign writes
+const (
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	dsseEnvelopeMediaType     = "application/vnd.dsse.envelope.v1+json"
+)
+
+// maxPayloadBytes bounds the signature payloads and attestation envelopes fetched
+const maxPayloadBytes = 4 << 20
+
+// cosignTagPattern matches the tags cosign attaches to an image digest, e.g. sha256-<hex>.sig
+var cosignTagPattern = regexp.MustCompile(`^sha256-([0-9a-f]{64})\.(sig|att|sbom)$`)
+
+// SignatureMetrics counts the cosign signatures, attestations and SBOMs mirrored
+// alongside the images of a registry and how many of them verify
+type SignatureMetrics struct {
+	Registry       string             `json:"registry"`
+	Prefix         string             `json:"prefix,omitempty"`
+	KeyFile        string             `json:"key_file,omitempty"` // Public key the signatures were verified against
+	Duration       time.Duration      `json:"duration_seconds"`
+	Repositories   int                `json:"repositories"`
+	ImageTags      int                `json:"image_tags"` // Tags other than cosign artifacts
+	Signatures     int                `json:"signatures"`
+	Attestations   int                `json:"attestations"`
+	SBOMs          int                `json:"sboms"`
+	SignedImages   int                `json:"signed_images"`   // Distinct image digests with a signature
+	AttestedImages int                `json:"attested_images"` // Distinct image digests with an attestation
+	Verified       int                `json:"verified"`
+	Unverified     int                `json:"unverified"`
+	Invalid        int                `json:"invalid"`
+	Orphaned       int                `json:"orphaned"`
+	Errors         int                `json:"errors"`
+	Findings       []SignatureFinding `json:"findings"` // Invalid, orphaned and failed checks
+}
+
+// SignatureFinding is the verification result of one signature or attestation tag
+type SignatureFinding struct {
+	Repository    string `json:"repository"`
+	Tag           string `json:"tag"`
+	Kind          string `json:"kind"` // sig or att
+	SubjectDigest string `json:"subject_digest"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+}
+
+// cosignTag is a cosign artifact tag found in a repository
+type cosignTag struct {
+	repository string
+	tag        string
+	subject    string // Digest of the image the artifact belongs to
+	kind       string
+}
+
+// ScanSignatures finds the cosign artifact tags in the repositories under prefix
+// and checks every signature and attestation, running up to concurrency checks
+// at once. With a public key, signatures are verified cryptographically;
+// otherwise only their payloads are checked against the signed image.
+func ScanSignatures(ctx context.Context, client *Client, prefix string, key crypto.PublicKey, concurrency int) (*SignatureMetrics, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	startTime := time.Now()
+	metrics := &SignatureMetrics{Registry: client.Host(), Prefix: prefix, Findings: make([]SignatureFinding, 0)}
+
+	repositories, err := client.Repositories(ctx)
+	if err != nil {
+		return metrics, fmt.Errorf("failed to list repositories: %w", err)
+	}
+	sort.Strings(repositories)
+
+	var checks []cosignTag
+	signed := make(map[string]bool)
+	attested := make(map[string]bool)
+	for _, repository := range repositories {
+		if prefix != "" && !strings.HasPrefix(repository, strings.Trim(prefix, "/")+"/") {
+			continue
+		}
+		tags, err := client.Tags(ctx, repository)
+		if err != nil {
+			return metrics, err
+		}
+		metrics.Repositories++
+		for _, tag := range tags {
+			matches := cosignTagPattern.FindStringSubmatch(tag)
+			if matches == nil {
+				metrics.ImageTags++
+				continue
+			}
+			artifact := cosignTag{repository: repository, tag: tag, subject: "sha256:" + matches[1], kind: matches[2]}
+			switch artifact.kind {
+			case KindSignature:
+				metrics.Signatures++
+				signed[repository+"@"+artifact.subject] = true
+			case KindAttestation:
+				metrics.Attestations++
+				attested[repository+"@"+artifact.subject] = true
+			case KindSBOM:
+				metrics.SBOMs++
+				continue // SBOM attachments are not signed
+			}
+			checks = append(checks, artifact)
+		}
+	}
+	metrics.SignedImages = len(signed)
+	metrics.AttestedImages = len(attested)
+
+	findings := make([]SignatureFinding, len(checks))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				findings[i] = checkCosignTag(ctx, client, key, checks[i])
+			}
+		}()
+	}
+	for i := range checks {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, finding := range findings {
+		switch finding.Status {
+		case SignatureVerified:
+			metrics.Verified++
+			continue
+		case SignatureUnverified:
+			metrics.Unverified++
+			continue
+		case SignatureInvalid:
+			metrics.Invalid++
+		case SignatureOrphaned:
+			metrics.Orphaned++
+		default:
+			metrics.Errors++
+		}
+		metrics.Findings = append(metrics.Findings, finding)
+	}
+	metrics.Duration = time.Since(startTime)
+	return metrics, nil
+}
+
+// checkCosignTag checks that the signed image exists and that every layer of
+// the artifact is a valid signature of it
+func checkCosignTag(ctx context.Context, client *Client, key crypto.PublicKey, artifact cosignTag) SignatureFinding {
+	finding := SignatureFinding{Repository: artifact.repository, Tag: artifact.tag, Kind: artifact.kind, SubjectDigest: artifact.subject}
+	fail := func(status string, err error) SignatureFinding {
+		finding.Status = status
+		finding.Error = err.Error()
+		return finding
+	}
+
+	_, status, err := client.HeadManifest(ctx, artifact.repository, artifact.subject)
+	if err != nil {
+		return fail(StatusError, err)
+	}
+	if status == http.StatusNotFound {
+		return fail(SignatureOrphaned, fmt.Errorf("signed image %s is not in the repository", artifact.subject))
+	}
+
+	data, err := client.GetManifest(ctx, artifact.repository, artifact.tag)
+	if err != nil {
+		return fail(StatusError, err)
+	}
+	var manifest struct {
+		Layers []struct {
+			MediaType   string            `json:"mediaType"`
+			Digest      string            `json:"digest"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fail(SignatureInvalid, fmt.Errorf("failed to parse manifest: %w", err))
+	}
+	if len(manifest.Layers) == 0 {
+		return fail(SignatureInvalid, fmt.Errorf("manifest has no layers"))
+	}
+
+	for _, layer := range manifest.Layers {
+		blob, err := client.GetBlob(ctx, artifact.repository, layer.Digest, maxPayloadBytes)
+		if err != nil {
+			return fail(StatusError, err)
+		}
+		if artifact.kind == KindAttestation || layer.MediaType == dsseEnvelopeMediaType {
+			err = verifyAttestation(blob, artifact.subject, key)
+		} else {
+			err = verifySignature(blob, layer.Annotations[cosignSignatureAnnotation], artifact.subject, key)
+		}
+		if err != nil {
+			return fail(SignatureInvalid, err)
+		}
+	}
+
+	finding.Status = SignatureUnverified
+	if key != nil {
+		finding.Status = SignatureVerified
+	}
+	return finding
+}
+
+// verifySignature checks a cosign simple signing payload against the signed
+// image digest and, with a key, the base64 signature of the payload
+func verifySignature(payload []byte, signature, subject string, key crypto.PublicKey) error {
+	var simpleSigning struct {
+		Critical struct {
+			Image struct {
+				DockerManifestDigest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}
+	if err := json.Unmarshal(payload, &simpleSigning); err != nil {
+		return fmt.Errorf("failed to parse signature payload: %w", err)
+	}
+	if signed := simpleSigning.Critical.Image.DockerManifestDigest; signed != subject {
+		return fmt.Errorf("payload signs %s instead of %s", signed, subject)
+	}
+	if key == nil {
+		return nil
+	}
+	if signature == "" {
+		return fmt.Errorf("layer has no %s annotation", cosignSignatureAnnotation)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	return verifyWithKey(key, payload, sig)
+}
+
+// verifyAttestation checks that the in-toto statement of a DSSE envelope has
+// the image among its subjects and, with a key, one of the envelope signatures
+func verifyAttestation(envelopeData []byte, subject string, key crypto.PublicKey) error {
+	var envelope struct {
+		PayloadType string `json:"payloadType"`
+		Payload     string `json:"payload"`
+		Signatures  []struct {
+			Sig string `json:"sig"`
+		} `json:"signatures"`
+	}
+	if err := json.Unmarshal(envelopeData, &envelope); err != nil {
+		return fmt.Errorf("failed to parse attestation envelope: %w", err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return fmt.Errorf("invalid attestation payload encoding: %w", err)
+	}
+
+	var statement struct {
+		Subject []struct {
+			Digest map[string]string `json:"digest"`
+		} `json:"subject"`
+	}
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return fmt.Errorf("failed to parse in-toto statement: %w", err)
+	}
+	found := false
+	for _, s := range statement.Subject {
+		if "sha256:"+s.Digest["sha256"] == subject {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("attestation does not name %s as its subject", subject)
+	}
+	if key == nil {
+		return nil
+	}
+
+	// DSSE signs the pre-authentication encoding of type and payload
+	pae := fmt.Sprintf("DSSEv1 %d %s %d %s", len(envelope.PayloadType), envelope.PayloadType, len(payload), payload)
+	var lastErr error = fmt.Errorf("attestation envelope has no signatures")
+	for _, s := range envelope.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err != nil {
+			lastErr = fmt.Errorf("invalid attestation signature encoding: %w", err)
+			continue
+		}
+		if lastErr = verifyWithKey(key, []byte(pae), sig); lastErr == nil {
+			return nil
+		}
+	}
+	return lastErr
+}
+
+// verifyWithKey verifies a signature of data made the way cosign signs with a key pair
+func verifyWithKey(key crypto.PublicKey, data, sig []byte) error {
+	digest := sha256.Sum256(data)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest[:], sig) {
+			return fmt.Errorf("signature does not match the public key")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig); err != nil {
+			return fmt.Errorf("signature does not match the public key")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, data, sig) {
+			return fmt.Errorf("signature does not match the public key")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	return nil
+}
+
+// LoadPublicKey reads a PEM-encoded cosign public key (ECDSA, RSA or Ed25519)
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block in public key %s", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// PrintSummary prints the artifact counts and verification results
+func (m *SignatureMetrics) PrintSummary() {
+	logging.Printf("  │ ─── Signatures ───────────────────────────────────────────────\n")
+	logging.Printf("  │   Signatures: %d | Attestations: %d | SBOMs: %d (%d repositories, %d image tags)\n",
+		m.Signatures, m.Attestations, m.SBOMs, m.Repositories, m.ImageTags)
+	logging.Printf("  │   Signed images: %d | Attested images: %d\n", m.SignedImages, m.AttestedImages)
+	if m.Signatures+m.Attestations == 0 {
+		return
+	}
+	verified := fmt.Sprintf("Verified: %d", m.Verified)
+	if m.KeyFile == "" {
+		verified = fmt.Sprintf("Payload checked: %d (no public key)", m.Unverified)
+	}
+	logging.Printf("  │   %s | Invalid: %d | Orphaned: %d | Errors: %d\n", verified, m.Invalid, m.Orphaned, m.Errors)
+	for i, finding := range m.Findings {
+		if i == 5 {
+			logging.Printf("  │     ... %d more in the results file\n", len(m.Findings)-5)
+			break
+		}
+		logging.Printf("  │     %s %s:%s: %s\n", finding.Status, finding.Repository, finding.Tag, finding.Error)
+	}
+}
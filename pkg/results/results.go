@@ -0,0 +1,139 @@
+package results
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/integrity"
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// FilePattern matches result files written by the test runner
+const FilePattern = "results_*.json"
+
+// Run is a single results file loaded from disk
+type Run struct {
+	Name    string               `json:"name"`
+	Path    string               `json:"path"`
+	ModTime time.Time            `json:"mod_time"`
+	Header  runner.ResultsHeader `json:"header"` // Run description, migrated for older files
+	Results []runner.TestResult  `json:"results"`
+
+	Integrity integrity.Result `json:"integrity"` // Checksum/signature verification at load time
+}
+
+// LoadFile reads a results file written by the test runner and verifies its checksum
+func LoadFile(path string) (*Run, error) {
+	return LoadFileWithKey(path, nil)
+}
+
+// LoadFileWithKey reads a results file and verifies its checksum and, when a key
+// is given, its signature
+func LoadFileWithKey(path string, key []byte) (*Run, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat results file: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results file: %w", err)
+	}
+
+	file, err := runner.ParseResultsFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse results file %s: %w", path, err)
+	}
+	samplesErr := file.LoadSamples(func(name string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(filepath.Dir(path), name))
+	})
+	if samplesErr != nil && !errors.Is(samplesErr, runner.ErrSamplesModified) {
+		return nil, fmt.Errorf("failed to load samples of %s: %w", path, samplesErr)
+	}
+
+	run := &Run{
+		Name:    strings.TrimSuffix(filepath.Base(path), ".json"),
+		Path:    path,
+		ModTime: info.ModTime(),
+		Header:  file.ResultsHeader,
+		Results: file.Results,
+
+		Integrity: integrity.Verify(path, key),
+	}
+	if samplesErr != nil && !run.Integrity.IsTampered() {
+		// The results load without the modified samples
+		run.Integrity = integrity.Result{Status: integrity.StatusModified, Message: samplesErr.Error()}
+	}
+	return run, nil
+}
+
+// ListFiles returns the result files in a directory, oldest first
+func ListFiles(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, FilePattern))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list results files: %w", err)
+	}
+
+	type fileEntry struct {
+		path    string
+		modTime time.Time
+	}
+	entries := make([]fileEntry, 0, len(matches))
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		entries = append(entries, fileEntry{path: match, modTime: info.ModTime()})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+
+	files := make([]string, len(entries))
+	for i, entry := range entries {
+		files[i] = entry.path
+	}
+	return files, nil
+}
+
+// Resolve expands the given paths into result files. Files are used as-is;
+// directories contribute their result files, oldest first.
+func Resolve(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		dirFiles, err := ListFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, dirFiles...)
+	}
+	return files, nil
+}
+
+// LoadAll loads every result file in order
+func LoadAll(paths []string, key []byte) ([]*Run, error) {
+	runs := make([]*Run, 0, len(paths))
+	for _, path := range paths {
+		run, err := LoadFileWithKey(path, key)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
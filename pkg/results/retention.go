@@ -0,0 +1,247 @@
+package results
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/runner"
+	"github.com/telco-core/ngc-495/pkg/store"
+)
+
+// ArchiveDir is the directory below a results root that archives are written to
+const ArchiveDir = "archive"
+
+// StoredRun is a results file in a store with the objects written for it
+type StoredRun struct {
+	Name    string    `json:"name"`    // Results file, below the store root
+	Started time.Time `json:"started"` // Run start from the file name, or the modification time
+	Objects []string  `json:"objects"` // Results file first, then its sidecars and phase logs
+	Size    int64     `json:"size"`    // Bytes of all objects
+}
+
+// ParseAge parses a retention age: a number of days (30d) or weeks (2w), or
+// a Go duration such as 36h
+func ParseAge(value string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q (expected e.g. 30d, 2w or 36h)", value)
+	}
+	return age, nil
+}
+
+// ListRuns returns the results files of a store with their sidecars, oldest
+// first. Phase logs are included for local directories, whose logs/
+// directory can be listed.
+func ListRuns(backend store.Store) ([]StoredRun, error) {
+	objects, err := backend.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list results: %w", err)
+	}
+	listed := make(map[string]store.ObjectInfo, len(objects))
+	for _, object := range objects {
+		listed[object.Name] = object
+	}
+
+	var runs []StoredRun
+	for _, object := range objects {
+		if matched, _ := path.Match(FilePattern, path.Base(object.Name)); !matched {
+			continue
+		}
+		run := StoredRun{Name: object.Name, Started: object.ModTime, Objects: []string{object.Name}, Size: object.Size}
+		if meta, ok := runner.ParseResultsFileName(object.Name); ok {
+			run.Started = meta.Timestamp
+		}
+		for _, sidecar := range runner.ResultsSidecars(object.Name) {
+			if info, ok := listed[sidecar]; ok {
+				run.Objects = append(run.Objects, sidecar)
+				run.Size += info.Size
+			}
+		}
+		if local, ok := backend.(*store.Local); ok {
+			logs, _ := filepath.Glob(runner.LogsPattern(filepath.Join(local.Dir(), object.Name)))
+			for _, log := range logs {
+				if info, err := os.Stat(log); err == nil {
+					run.Objects = append(run.Objects, "logs/"+filepath.Base(log))
+					run.Size += info.Size()
+				}
+			}
+		}
+		runs = append(runs, run)
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Started.Before(runs[j].Started)
+	})
+	return runs, nil
+}
+
+// OlderThan returns the runs started before cutoff
+func OlderThan(runs []StoredRun, cutoff time.Time) []StoredRun {
+	var old []StoredRun
+	for _, run := range runs {
+		if run.Started.Before(cutoff) {
+			old = append(old, run)
+		}
+	}
+	return old
+}
+
+// FindRun returns the stored run of a results file
+func FindRun(backend store.Store, name string) (StoredRun, error) {
+	runs, err := ListRuns(backend)
+	if err != nil {
+		return StoredRun{}, err
+	}
+	for _, run := range runs {
+		if run.Name == name {
+			return run, nil
+		}
+	}
+	return StoredRun{}, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+}
+
+// Delete removes the objects of a run. The results file goes last and stays
+// when a sidecar could not be removed, so the run is still listed.
+func Delete(backend store.Store, run StoredRun) error {
+	var errs []error
+	for _, object := range run.Objects[1:] {
+		if err := backend.Delete(object); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		errs = append(errs, backend.Delete(run.Name))
+	}
+	return errors.Join(errs...)
+}
+
+// Archive writes the objects of the runs into one tar.gz per results root
+// under its archive directory, named after the time of archiving, and
+// removes them. It returns the archives written.
+func Archive(backend store.Store, runs []StoredRun, now time.Time) ([]string, error) {
+	// A federated store has one root per source; each keeps its own archive
+	byRoot := map[string][]StoredRun{}
+	var roots []string
+	for _, run := range runs {
+		root := path.Dir(run.Name)
+		if _, ok := byRoot[root]; !ok {
+			roots = append(roots, root)
+		}
+		byRoot[root] = append(byRoot[root], run)
+	}
+
+	var archives []string
+	for _, root := range roots {
+		name := path.Join(root, ArchiveDir, "results_"+now.Format("20060102_150405"))
+		for n := 2; archiveExists(backend, name+".tar.gz"); n++ {
+			name = path.Join(root, ArchiveDir, fmt.Sprintf("results_%s_%d", now.Format("20060102_150405"), n))
+		}
+		name += ".tar.gz"
+		if err := writeArchive(backend, name, byRoot[root]); err != nil {
+			return archives, err
+		}
+		archives = append(archives, name)
+
+		var errs []error
+		for _, run := range byRoot[root] {
+			if err := Delete(backend, run); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return archives, fmt.Errorf("archived to %s, but failed to remove: %w", name, err)
+		}
+	}
+	return archives, nil
+}
+
+// archiveExists reports whether an archive of the same name was written before
+func archiveExists(backend store.Store, name string) bool {
+	if local, ok := backend.(*store.Local); ok {
+		_, err := os.Stat(filepath.Join(local.Dir(), filepath.FromSlash(name)))
+		return err == nil
+	}
+	_, err := backend.Read(name)
+	return err == nil
+}
+
+// writeArchive writes the objects of runs into a tar.gz object, with their
+// names relative to the results root. Results with samples can be large, so
+// the objects are streamed into an archive staged in a temporary file.
+func writeArchive(backend store.Store, name string, runs []StoredRun) error {
+	tmp, err := os.CreateTemp("", "results-archive-*.tar.gz")
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	gz := gzip.NewWriter(tmp)
+	tw := tar.NewWriter(gz)
+	for _, run := range runs {
+		for _, object := range run.Objects {
+			if err := archiveObject(tw, backend, object, run); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := backend.Write(name, tmp, size); err != nil {
+		return fmt.Errorf("failed to write archive %s: %w", name, err)
+	}
+	return nil
+}
+
+// archiveObject copies one object of a run into the archive
+func archiveObject(tw *tar.Writer, backend store.Store, object string, run StoredRun) error {
+	reader, size, err := backend.Open(object)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", object, err)
+	}
+	defer reader.Close()
+
+	header := &tar.Header{
+		Name:    strings.TrimPrefix(object, path.Dir(run.Name)+"/"),
+		Mode:    0644,
+		Size:    size,
+		ModTime: run.Started,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if _, err := io.CopyN(tw, reader, size); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", object, err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package rollup
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/telco-core/ngc-495/pkg/integrity"
+	"github.com/telco-core/ngc-495/pkg/results"
+	"github.com/telco-core/ngc-495/pkg/store"
+)
+
+// NewFleetReportCommand creates a cobra command for the fleet roll-up report
+func NewFleetReportCommand() *cobra.Command {
+	opts := DefaultOptions()
+	var format, outputFile, signingKeyFile string
+
+	cmd := &cobra.Command{
+		Use:   "fleet-report [label=]<dir>...",
+		Short: "Roll up the results of many sites into one report",
+		Long: "Summarizes the results of many sites in one table: per-site medians over the last weeks, outlier sites against the fleet " +
+			"and week-over-week deltas. Each argument is the results directory of one site, named by its label or its directory name. " +
+			"A single directory holding one subdirectory per site, as collected from the sites, is expanded into its subdirectories.\n\n" +
+			"Example: fleet-report --weeks 6 --format markdown collected/",
+		Args:          cobra.MinimumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Validate(); err != nil {
+				return err
+			}
+			switch format {
+			case "table", "markdown", "csv", "json":
+			default:
+				return fmt.Errorf("invalid format %q: expected table, markdown, csv or json", format)
+			}
+			if format == "table" && outputFile != "" {
+				return fmt.Errorf("the table format is printed to the console; use markdown, csv or json with --output")
+			}
+
+			var key []byte
+			if signingKeyFile != "" {
+				var err error
+				if key, err = integrity.LoadKey(signingKeyFile); err != nil {
+					return err
+				}
+			}
+			sites, err := LoadSites(args, key)
+			if err != nil {
+				return err
+			}
+			report, err := Build(sites, opts)
+			if err != nil {
+				return err
+			}
+			return writeReport(report, format, outputFile)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Week, "week", "", "ISO week to report on, like 2026-W41 (default: latest week with results)")
+	cmd.Flags().IntVar(&opts.Weeks, "weeks", opts.Weeks, "Weeks of history up to the reported week used for the site medians")
+	cmd.Flags().StringVar(&opts.Version, "version", "", "Only roll up iterations of this oc-mirror version (v1 or v2)")
+	cmd.Flags().StringVar(&opts.CacheState, "cache-state", opts.CacheState, "Iterations to roll up: clean, cached or all")
+	cmd.Flags().Float64Var(&opts.OutlierScore, "outlier-score", opts.OutlierScore, "Robust z-score above which a site is reported as an outlier")
+	cmd.Flags().StringVar(&format, "format", "table", "Output format: table, markdown, csv or json")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write the report to a file instead of stdout")
+	cmd.Flags().StringVar(&signingKeyFile, "signing-key-file", "", "Key file used to verify results file signatures")
+
+	return cmd
+}
+
+// LoadSites loads the results of each site. Specs are [label=]<dir>; a single
+// directory without results files is expanded into its subdirectories.
+func LoadSites(specs []string, key []byte) ([]Site, error) {
+	if len(specs) == 1 {
+		expanded, err := expandCollected(specs[0])
+		if err != nil {
+			return nil, err
+		}
+		if expanded != nil {
+			specs = expanded
+		}
+	}
+
+	sites := make([]Site, 0, len(specs))
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		label, dir := store.ParseRoot(spec)
+		if seen[label] {
+			return nil, fmt.Errorf("duplicate site %s", label)
+		}
+		seen[label] = true
+
+		files, err := results.Resolve([]string{dir})
+		if err != nil {
+			return nil, err
+		}
+		runs, err := results.LoadAll(files, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, run := range runs {
+			if run.Integrity.IsTampered() {
+				fmt.Fprintf(os.Stderr, "Warning: %s failed integrity check (%s): %s\n", run.Path, run.Integrity.Status, run.Integrity.Message)
+			}
+		}
+		sites = append(sites, Site{Name: label, Runs: runs})
+	}
+	return sites, nil
+}
+
+// expandCollected returns one site spec per subdirectory holding results
+// files when dir has none itself, nil otherwise
+func expandCollected(spec string) ([]string, error) {
+	if _, location := store.ParseRoot(spec); location != spec {
+		return nil, nil // Labeled, so it is one site
+	}
+	info, err := os.Stat(spec)
+	if err != nil || !info.IsDir() {
+		return nil, nil
+	}
+	own, err := results.ListFiles(spec)
+	if err != nil || len(own) > 0 {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", spec, err)
+	}
+	var specs []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(spec, entry.Name())
+		files, err := results.ListFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 0 {
+			specs = append(specs, entry.Name()+"="+dir)
+		}
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no results files found in %s or its subdirectories", spec)
+	}
+	sort.Strings(specs)
+	return specs, nil
+}
+
+// writeReport writes the report in format to outputFile, or stdout
+func writeReport(report *Report, format, outputFile string) error {
+	out := os.Stdout
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to create report file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	switch format {
+	case "json":
+		data, err := report.FormatJSON()
+		if err != nil {
+			return fmt.Errorf("failed to format report: %w", err)
+		}
+		fmt.Fprintln(out, data)
+	case "markdown":
+		fmt.Fprint(out, report.FormatMarkdown())
+	case "csv":
+		w := csv.NewWriter(out)
+		if err := w.WriteAll(report.CSVRecords()); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	default:
+		report.PrintSummary()
+	}
+	if outputFile != "" {
+		fmt.Printf("Fleet report written to %s\n", outputFile)
+	}
+	return nil
+}
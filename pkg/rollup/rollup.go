@@ -0,0 +1,575 @@
+// Package rollup summarizes the results of many sites into one fleet report:
+// per-site medians, outlier sites and week-over-week deltas.
+package rollup
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/results"
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// Cache states selecting the iterations of a roll-up
+const (
+	CacheClean  = "clean"
+	CacheCached = "cached"
+	CacheAll    = "all"
+)
+
+// DefaultOutlierScore is the robust z-score above which a site is an outlier
+const DefaultOutlierScore = 3.5
+
+// Options select the iterations and weeks of a roll-up
+type Options struct {
+	Week         string  // ISO week reported on, like 2026-W41; the latest week with results when empty
+	Weeks        int     // Weeks of history up to the reported week used for the site medians
+	Version      string  // v1 or v2; all versions when empty
+	CacheState   string  // clean, cached or all
+	OutlierScore float64 // Robust z-score above which a site is an outlier
+}
+
+// DefaultOptions returns the options of a four-week clean-run roll-up
+func DefaultOptions() Options {
+	return Options{Weeks: 4, CacheState: CacheClean, OutlierScore: DefaultOutlierScore}
+}
+
+// Validate checks the options
+func (o Options) Validate() error {
+	if o.Week != "" {
+		if _, err := weekStart(o.Week); err != nil {
+			return err
+		}
+	}
+	if o.Weeks < 1 {
+		return fmt.Errorf("weeks must be at least 1")
+	}
+	if o.Version != "" && o.Version != "v1" && o.Version != "v2" {
+		return fmt.Errorf("invalid version %q: expected v1 or v2", o.Version)
+	}
+	switch o.CacheState {
+	case CacheClean, CacheCached, CacheAll:
+	default:
+		return fmt.Errorf("invalid cache state %q: expected clean, cached or all", o.CacheState)
+	}
+	if o.OutlierScore <= 0 {
+		return fmt.Errorf("outlier score must be positive")
+	}
+	return nil
+}
+
+// Site is the results of one site
+type Site struct {
+	Name string
+	Runs []*results.Run
+}
+
+// Medians are the median timings of a set of iterations. Timings are in
+// seconds; only iterations that completed are counted.
+type Medians struct {
+	Iterations  int     `json:"iterations"`
+	Passed      int     `json:"passed"`
+	DownloadSec float64 `json:"download_seconds"`
+	UploadSec   float64 `json:"upload_seconds"`
+	TotalSec    float64 `json:"total_seconds"`
+	UploadMBps  float64 `json:"upload_mbps,omitempty"`
+	completed   int
+}
+
+// PassRate returns the share of iterations that passed, in percent
+func (m Medians) PassRate() float64 {
+	if m.Iterations == 0 {
+		return 0
+	}
+	return float64(m.Passed) / float64(m.Iterations) * 100
+}
+
+// WeekMedians are the medians of a site in one week
+type WeekMedians struct {
+	Week string `json:"week"`
+	Runs int    `json:"runs"`
+	Medians
+}
+
+// SiteSummary is one row of the roll-up
+type SiteSummary struct {
+	Site    string        `json:"site"`
+	Runs    int           `json:"runs"`
+	Medians Medians       `json:"medians"`           // Over the reported window
+	Weeks   []WeekMedians `json:"weeks,omitempty"`   // Each week of the window with results, oldest first
+	Current *WeekMedians  `json:"current,omitempty"` // Reported week
+	Prior   *WeekMedians  `json:"prior,omitempty"`   // Week before the reported week
+
+	// Week-over-week change of the total time and upload throughput, in percent
+	TotalDeltaPct      *float64 `json:"total_delta_percent,omitempty"`
+	ThroughputDeltaPct *float64 `json:"throughput_delta_percent,omitempty"`
+
+	Outlier        bool     `json:"outlier"`
+	OutlierReasons []string `json:"outlier_reasons,omitempty"`
+}
+
+// Fleet is the median of the site medians
+type Fleet struct {
+	Sites       int     `json:"sites"`
+	PassRate    float64 `json:"pass_rate_percent"`
+	DownloadSec float64 `json:"download_seconds"`
+	UploadSec   float64 `json:"upload_seconds"`
+	TotalSec    float64 `json:"total_seconds"`
+	UploadMBps  float64 `json:"upload_mbps,omitempty"`
+}
+
+// Report is the fleet roll-up
+type Report struct {
+	GeneratedAt time.Time     `json:"generated_at"`
+	Week        string        `json:"week"`       // Reported ISO week
+	FirstWeek   string        `json:"first_week"` // First week of the window
+	Version     string        `json:"version,omitempty"`
+	CacheState  string        `json:"cache_state"`
+	Fleet       Fleet         `json:"fleet"`
+	Sites       []SiteSummary `json:"sites"`
+	Skipped     []string      `json:"skipped,omitempty"` // Sites without results in the window
+}
+
+// iteration is one selected iteration with the week of its run
+type iteration struct {
+	week   string
+	run    *results.Run
+	result *runner.TestResult
+}
+
+// Build computes the roll-up of sites
+func Build(sites []Site, opts Options) (*Report, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	bySite := make([][]iteration, len(sites))
+	latest := ""
+	for i, site := range sites {
+		for _, run := range site.Runs {
+			week := WeekOf(runTime(run))
+			for j := range run.Results {
+				result := &run.Results[j]
+				if !selected(result, opts) {
+					continue
+				}
+				bySite[i] = append(bySite[i], iteration{week: week, run: run, result: result})
+				if week > latest {
+					latest = week
+				}
+			}
+		}
+	}
+
+	week := opts.Week
+	if week == "" {
+		week = latest
+	}
+	if week == "" {
+		return nil, fmt.Errorf("no iterations match the roll-up selection")
+	}
+	start, err := weekStart(week)
+	if err != nil {
+		return nil, err
+	}
+	firstWeek := WeekOf(start.AddDate(0, 0, -7*(opts.Weeks-1)))
+	priorWeek := WeekOf(start.AddDate(0, 0, -7))
+
+	report := &Report{
+		GeneratedAt: time.Now(),
+		Week:        week,
+		FirstWeek:   firstWeek,
+		Version:     opts.Version,
+		CacheState:  opts.CacheState,
+	}
+	for i, site := range sites {
+		var window []iteration
+		for _, it := range bySite[i] {
+			if it.week >= firstWeek && it.week <= week {
+				window = append(window, it)
+			}
+		}
+		if len(window) == 0 {
+			report.Skipped = append(report.Skipped, site.Name)
+			continue
+		}
+
+		summary := SiteSummary{Site: site.Name, Runs: countRuns(window), Medians: mediansOf(window)}
+		for _, w := range weeksOf(window) {
+			w := w
+			summary.Weeks = append(summary.Weeks, w)
+			switch w.Week {
+			case week:
+				summary.Current = &w
+			case priorWeek:
+				summary.Prior = &w
+			}
+		}
+		if summary.Current != nil && summary.Prior != nil {
+			summary.TotalDeltaPct = deltaPct(summary.Prior.TotalSec, summary.Current.TotalSec)
+			summary.ThroughputDeltaPct = deltaPct(summary.Prior.UploadMBps, summary.Current.UploadMBps)
+		}
+		report.Sites = append(report.Sites, summary)
+	}
+	if len(report.Sites) == 0 {
+		return nil, fmt.Errorf("no site has results between %s and %s", firstWeek, week)
+	}
+
+	report.flagOutliers(opts.OutlierScore)
+	sort.SliceStable(report.Sites, func(i, j int) bool {
+		return report.Sites[i].Site < report.Sites[j].Site
+	})
+	return report, nil
+}
+
+// selected returns whether an iteration matches the version and cache state;
+// warm-up iterations never do
+func selected(result *runner.TestResult, opts Options) bool {
+	if result.Warmup {
+		return false
+	}
+	if opts.Version != "" && result.Version != opts.Version {
+		return false
+	}
+	switch opts.CacheState {
+	case CacheClean:
+		return result.IsCleanRun
+	case CacheCached:
+		return !result.IsCleanRun
+	}
+	return true
+}
+
+// runTime returns when a run was created, falling back to the file time for
+// files written before the header recorded it
+func runTime(run *results.Run) time.Time {
+	if !run.Header.CreatedAt.IsZero() {
+		return run.Header.CreatedAt
+	}
+	return run.ModTime
+}
+
+// WeekOf returns the ISO week of t in UTC, like 2026-W41
+func WeekOf(t time.Time) string {
+	year, week := t.UTC().ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// weekStart returns the Monday of an ISO week, in UTC
+func weekStart(week string) (time.Time, error) {
+	var year, number int
+	if _, err := fmt.Sscanf(week, "%d-W%d", &year, &number); err != nil || number < 1 || number > 53 {
+		return time.Time{}, fmt.Errorf("invalid week %q: expected YYYY-Www", week)
+	}
+	// January 4th is always in week 1
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday())+6)%7)+7*(number-1))
+	if WeekOf(monday) != fmt.Sprintf("%04d-W%02d", year, number) {
+		return time.Time{}, fmt.Errorf("invalid week %q: %d has no week %d", week, year, number)
+	}
+	return monday, nil
+}
+
+// countRuns returns the number of runs the iterations come from
+func countRuns(iterations []iteration) int {
+	runs := make(map[*results.Run]bool)
+	for _, it := range iterations {
+		runs[it.run] = true
+	}
+	return len(runs)
+}
+
+// weeksOf returns the medians of each week of the iterations, oldest first
+func weeksOf(iterations []iteration) []WeekMedians {
+	byWeek := make(map[string][]iteration)
+	for _, it := range iterations {
+		byWeek[it.week] = append(byWeek[it.week], it)
+	}
+	weeks := make([]WeekMedians, 0, len(byWeek))
+	for week, its := range byWeek {
+		weeks = append(weeks, WeekMedians{Week: week, Runs: countRuns(its), Medians: mediansOf(its)})
+	}
+	sort.Slice(weeks, func(i, j int) bool { return weeks[i].Week < weeks[j].Week })
+	return weeks
+}
+
+// mediansOf computes the medians of the completed iterations
+func mediansOf(iterations []iteration) Medians {
+	m := Medians{Iterations: len(iterations)}
+	var download, upload, total, throughput []float64
+	for _, it := range iterations {
+		r := it.result
+		if r.Passed {
+			m.Passed++
+		}
+		if r.Failed {
+			continue
+		}
+		d := r.DownloadPhase.WallTime.Seconds()
+		u := r.UploadPhase.WallTime.Seconds()
+		download = append(download, d)
+		upload = append(upload, u)
+		total = append(total, d+u)
+		if r.UploadPhase.BytesUploaded > 0 && u > 0 {
+			throughput = append(throughput, float64(r.UploadPhase.BytesUploaded)/1024/1024/u)
+		}
+	}
+	m.completed = len(total)
+	m.DownloadSec = median(download)
+	m.UploadSec = median(upload)
+	m.TotalSec = median(total)
+	m.UploadMBps = median(throughput)
+	return m
+}
+
+// flagOutliers marks the sites whose medians are far from the fleet, using the
+// robust z-score against the median absolute deviation of the site medians
+func (r *Report) flagOutliers(threshold float64) {
+	metrics := []struct {
+		name   string
+		value  func(Medians) float64
+		fleet  *float64
+		higher string // Meaning of a value above the fleet
+		lower  string
+	}{
+		{"total time", func(m Medians) float64 { return m.TotalSec }, &r.Fleet.TotalSec, "slower", "faster"},
+		{"download time", func(m Medians) float64 { return m.DownloadSec }, &r.Fleet.DownloadSec, "slower", "faster"},
+		{"upload time", func(m Medians) float64 { return m.UploadSec }, &r.Fleet.UploadSec, "slower", "faster"},
+		{"upload throughput", func(m Medians) float64 { return m.UploadMBps }, &r.Fleet.UploadMBps, "higher", "lower"},
+	}
+
+	passRates := make([]float64, len(r.Sites))
+	for i, site := range r.Sites {
+		passRates[i] = site.Medians.PassRate()
+	}
+	r.Fleet.Sites = len(r.Sites)
+	r.Fleet.PassRate = median(passRates)
+
+	for _, metric := range metrics {
+		var values []float64
+		var indices []int
+		for i, site := range r.Sites {
+			if v := metric.value(site.Medians); site.Medians.completed > 0 && v > 0 {
+				values = append(values, v)
+				indices = append(indices, i)
+			}
+		}
+		fleet := median(values)
+		*metric.fleet = fleet
+		// Outliers need enough sites for the fleet median to mean something
+		if len(values) < 3 {
+			continue
+		}
+		spread := mad(values, fleet)
+		if spread == 0 {
+			continue
+		}
+		for k, v := range values {
+			score := 0.6745 * (v - fleet) / spread
+			if math.Abs(score) <= threshold {
+				continue
+			}
+			direction := metric.higher
+			if score < 0 {
+				direction = metric.lower
+			}
+			site := &r.Sites[indices[k]]
+			site.Outlier = true
+			site.OutlierReasons = append(site.OutlierReasons,
+				fmt.Sprintf("%s %s than fleet (%.1fx median)", metric.name, direction, v/fleet))
+		}
+	}
+}
+
+// median returns the median of values, 0 when there are none
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// mad returns the median absolute deviation of values around center
+func mad(values []float64, center float64) float64 {
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - center)
+	}
+	return median(deviations)
+}
+
+// deltaPct returns the change from prior to current in percent, nil when
+// either is missing
+func deltaPct(prior, current float64) *float64 {
+	if prior <= 0 || current <= 0 {
+		return nil
+	}
+	delta := (current - prior) / prior * 100
+	return &delta
+}
+
+// FormatJSON returns the report as indented JSON
+func (r *Report) FormatJSON() (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// columns of the roll-up table
+var columns = []string{"Site", "Runs", "Pass", "Download", "Upload", "Total", "MB/s", "Week total", "WoW total", "WoW MB/s", "Outlier"}
+
+// rows returns the cells of the roll-up table, with the fleet last. Outliers
+// are flagged with their reasons, or just flagged when brief is set.
+func (r *Report) rows(brief bool) [][]string {
+	rows := make([][]string, 0, len(r.Sites)+1)
+	for _, site := range r.Sites {
+		m := site.Medians
+		current := "-"
+		if site.Current != nil && site.Current.completed > 0 {
+			current = formatSeconds(site.Current.TotalSec)
+		}
+		outlier := ""
+		if site.Outlier {
+			outlier = "yes"
+			if !brief {
+				outlier = strings.Join(site.OutlierReasons, "; ")
+			}
+		}
+		rows = append(rows, []string{
+			site.Site,
+			fmt.Sprintf("%d", site.Runs),
+			fmt.Sprintf("%.0f%%", m.PassRate()),
+			formatSeconds(m.DownloadSec),
+			formatSeconds(m.UploadSec),
+			formatSeconds(m.TotalSec),
+			formatMBps(m.UploadMBps),
+			current,
+			formatDelta(site.TotalDeltaPct),
+			formatDelta(site.ThroughputDeltaPct),
+			outlier,
+		})
+	}
+	rows = append(rows, []string{
+		"FLEET",
+		fmt.Sprintf("%d sites", r.Fleet.Sites),
+		fmt.Sprintf("%.0f%%", r.Fleet.PassRate),
+		formatSeconds(r.Fleet.DownloadSec),
+		formatSeconds(r.Fleet.UploadSec),
+		formatSeconds(r.Fleet.TotalSec),
+		formatMBps(r.Fleet.UploadMBps),
+		"", "", "", "",
+	})
+	return rows
+}
+
+// PrintSummary prints the roll-up as a console table
+func (r *Report) PrintSummary() {
+	fmt.Printf("\nFleet Roll-up: %s\n", r.title())
+	rows := append([][]string{columns}, r.rows(true)...)
+	widths := make([]int, len(columns))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+	for _, row := range rows {
+		parts := make([]string, len(row))
+		for i, cell := range row {
+			parts[i] = cell + strings.Repeat(" ", widths[i]-len([]rune(cell)))
+		}
+		fmt.Println("  " + strings.TrimRight(strings.Join(parts, "  "), " "))
+	}
+	r.printNotes()
+}
+
+// FormatMarkdown returns the roll-up as a markdown document
+func (r *Report) FormatMarkdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Fleet Roll-up: %s\n\n", r.title())
+	fmt.Fprintf(&b, "| %s |\n", strings.Join(columns, " | "))
+	fmt.Fprintf(&b, "|%s\n", strings.Repeat("---|", len(columns)))
+	for _, row := range r.rows(false) {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.ReplaceAll(cell, "|", "\\|")
+		}
+		fmt.Fprintf(&b, "| %s |\n", strings.Join(cells, " | "))
+	}
+	b.WriteString("\nMedians over the window; WoW compares the reported week with the week before.\n")
+	if len(r.Skipped) > 0 {
+		fmt.Fprintf(&b, "\nNo results in the window: %s\n", strings.Join(r.Skipped, ", "))
+	}
+	return b.String()
+}
+
+// CSVRecords returns the roll-up table as CSV records, header first
+func (r *Report) CSVRecords() [][]string {
+	return append([][]string{columns}, r.rows(false)...)
+}
+
+// title describes the window and selection of the report
+func (r *Report) title() string {
+	title := r.Week
+	if r.FirstWeek != r.Week {
+		title = r.FirstWeek + " to " + r.Week
+	}
+	selection := r.CacheState + " iterations"
+	if r.Version != "" {
+		selection = r.Version + " " + selection
+	}
+	return fmt.Sprintf("%s (%s)", title, selection)
+}
+
+// printNotes prints the outliers and skipped sites below the table
+func (r *Report) printNotes() {
+	fmt.Printf("\nOutlier Sites:\n")
+	outliers := 0
+	for _, site := range r.Sites {
+		for _, reason := range site.OutlierReasons {
+			fmt.Printf("  ❌ %s: %s\n", site.Site, reason)
+			outliers++
+		}
+	}
+	if outliers == 0 {
+		fmt.Printf("  ✅ No outlier sites\n")
+	}
+	if len(r.Skipped) > 0 {
+		fmt.Printf("  Warning: No results in the window for: %s\n", strings.Join(r.Skipped, ", "))
+	}
+}
+
+// formatSeconds formats a median duration, "-" when there is none
+func formatSeconds(seconds float64) string {
+	if seconds <= 0 {
+		return "-"
+	}
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Second).String()
+}
+
+// formatMBps formats a median throughput, "-" when there is none
+func formatMBps(mbps float64) string {
+	if mbps <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", mbps)
+}
+
+// formatDelta formats a week-over-week change, "-" when there is none
+func formatDelta(delta *float64) string {
+	if delta == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", *delta)
+}
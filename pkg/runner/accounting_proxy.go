@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/proxy"
+	"github.com/telco-core/ngc-495/pkg/registry"
+)
+
+// accountingAuthFile holds the registry credentials under the accounting proxy host
+const accountingAuthFile = "oc-mirror-clone/accounting-proxy-auth.json"
+
+// accountingProxyEnabled returns true if uploads go through the accounting
+// proxy, which the rate limit simulation implies
+func (c *Config) accountingProxyEnabled() bool {
+	return c.AccountingProxy || c.RateLimit.Enabled()
+}
+
+// ValidateAccountingProxy checks that the accounting proxy has a registry to
+// forward to and the simulated rate limit
+func (c *Config) ValidateAccountingProxy() error {
+	if !c.accountingProxyEnabled() {
+		return nil
+	}
+	if c.IsOCITarget() {
+		return fmt.Errorf("the accounting proxy needs a registry target")
+	}
+	if err := c.RateLimit.Validate(); err != nil {
+		return fmt.Errorf("invalid rate limit simulation: %w", err)
+	}
+	return nil
+}
+
+// accountingRoute is the accounting proxy an upload phase is pushed through
+type accountingRoute struct {
+	proxy *proxy.CountingProxy
+	env   []string // REGISTRY_AUTH_FILE with the registry credentials under the proxy host
+}
+
+// startAccountingProxy starts the proxy to the target registry; nil without
+// one, or when it cannot start and the upload goes to the registry directly
+func (tr *TestRunner) startAccountingProxy() *accountingRoute {
+	if !tr.config.accountingProxyEnabled() || tr.config.IsOCITarget() {
+		return nil
+	}
+	host, scheme := registry.ParseRegistryHost(tr.targetRegistry())
+	opts := proxy.Options{Upstream: scheme + "://" + host, SkipTLS: tr.config.SkipTLS, RateLimit: tr.config.RateLimit}
+	if tr.config.Proxy.Enabled() && tr.proxyMode != ProxyModeDirect && tr.config.Proxy.HTTPSProxy != "" {
+		if u, err := url.Parse(tr.config.Proxy.HTTPSProxy); err == nil {
+			opts.Proxy = http.ProxyURL(u)
+		}
+	}
+	countingProxy, err := proxy.Start(opts)
+	if err != nil {
+		logging.Printf("  │ Warning: Failed to start the accounting proxy, uploading directly: %v\n", err)
+		return nil
+	}
+
+	route := &accountingRoute{proxy: countingProxy}
+	if err := os.MkdirAll(filepath.Dir(accountingAuthFile), 0755); err != nil {
+		logging.Printf("  │ Warning: Failed to create %s: %v\n", filepath.Dir(accountingAuthFile), err)
+	}
+	found, err := registry.AliasAuthFile(host, countingProxy.Host(), accountingAuthFile)
+	if err != nil {
+		logging.Printf("  │ Warning: Failed to pass the registry credentials to the accounting proxy: %v\n", err)
+	}
+	if found {
+		path, _ := filepath.Abs(accountingAuthFile)
+		route.env = []string{"REGISTRY_AUTH_FILE=" + path}
+	}
+	logging.Printf("  │ Accounting proxy: %s -> %s\n", countingProxy.Host(), host)
+	return route
+}
+
+// routeURL returns the registry URL with the registry host replaced by the proxy
+func (r *accountingRoute) routeURL(registryURL string) string {
+	if r == nil {
+		return registryURL
+	}
+	return r.proxy.Route(registryURL)
+}
+
+// environ returns the variables oc-mirror needs to authenticate through the proxy
+func (r *accountingRoute) environ() []string {
+	if r == nil {
+		return nil
+	}
+	return r.env
+}
+
+// stop shuts the proxy down and returns what it counted. oc-mirror wrote the
+// proxy host into the cluster resources in clusterResourcesDir; the registry
+// host is restored so they point at the registry.
+func (r *accountingRoute) stop(clusterResourcesDir string) *proxy.Stats {
+	if r == nil {
+		return nil
+	}
+	stats := r.proxy.Stop()
+	if err := replaceInFiles(clusterResourcesDir, r.proxy.Host(), r.proxy.UpstreamHost()); err != nil {
+		logging.Printf("  │ Warning: Failed to restore the registry host in the cluster resources: %v\n", err)
+	}
+	if r.env != nil {
+		os.Remove(accountingAuthFile)
+	}
+	return &stats
+}
+
+// replaceInFiles replaces old with new in the regular files of dir; a missing dir is ignored
+func replaceInFiles(dir, old, new string) error {
+	if dir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Contains(data, []byte(old)) {
+			continue
+		}
+		if err := os.WriteFile(path, bytes.ReplaceAll(data, []byte(old), []byte(new)), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
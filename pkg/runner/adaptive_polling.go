@@ -0,0 +1,44 @@
+package runner
+
+import (
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// PollBackoff records how the poll interval of a monitor backed off during a phase
+type PollBackoff struct {
+	Phase   string             `json:"phase"`
+	Monitor string             `json:"monitor"`
+	Steps   []monitor.PollStep `json:"steps"`
+}
+
+// polledMonitor is a per-phase monitor whose poll interval backs off
+type polledMonitor struct {
+	phase   string
+	source  string
+	monitor monitor.AdaptiveMonitor
+}
+
+// adaptPolling applies the adaptive polling of the run to a monitor of the
+// current phase. The iteration-wide resource monitor spans several phases, so
+// it keeps its interval and the phase transitions stay sampled in full.
+func (tr *TestRunner) adaptPolling(observer monitor.SampleObserver, source string) {
+	m, ok := observer.(monitor.AdaptiveMonitor)
+	if !ok || !tr.config.AdaptivePolling.Enabled() || source == sampleSourceIteration {
+		return
+	}
+	m.SetAdaptivePolling(tr.config.AdaptivePolling)
+	tr.polled = append(tr.polled, polledMonitor{phase: tr.phase.Phase, source: source, monitor: m})
+}
+
+// takePollBackoff returns how the monitors of the iteration backed off and
+// forgets them
+func (tr *TestRunner) takePollBackoff() []PollBackoff {
+	var backoff []PollBackoff
+	for _, p := range tr.polled {
+		if steps := p.monitor.PollSteps(); len(steps) > 0 {
+			backoff = append(backoff, PollBackoff{Phase: p.phase, Monitor: p.source, Steps: steps})
+		}
+	}
+	tr.polled = nil
+	return backoff
+}
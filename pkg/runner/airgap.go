@@ -0,0 +1,204 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+const (
+	// airGapRoot holds the disconnected side: the transferred archives and its cache
+	airGapRoot = "airgap"
+	// airGapCacheDir is the oc-mirror v2 --cache-dir of the disconnected side
+	airGapCacheDir = "airgap/cache-v2"
+	// transferChunk is the copy buffer size, and the granularity of the throttle
+	transferChunk = 1024 * 1024
+)
+
+// AirGapConfig enables the fully disconnected workflow: the download phase
+// mirrors to a tar archive, the archive is transferred to a separate
+// directory, optionally throttled, and the upload phase mirrors from it
+type AirGapConfig struct {
+	Enabled         bool    `json:"enabled" yaml:"enabled,omitempty"`
+	TransferRateMBs float64 `json:"transfer_rate_mbs,omitempty" yaml:"transferRateMBs,omitempty"` // Simulated transfer bandwidth; 0 copies at disk speed
+}
+
+// String returns a human-readable description of the workflow
+func (c AirGapConfig) String() string {
+	if c.TransferRateMBs > 0 {
+		return fmt.Sprintf("archive, transfer at %.1f MB/s, disk-to-mirror", c.TransferRateMBs)
+	}
+	return "archive, transfer, disk-to-mirror"
+}
+
+// AirGapMetrics represents the archive and transfer of an air-gapped iteration.
+// The disk-to-mirror from the transferred archive is the upload phase.
+type AirGapMetrics struct {
+	Archive          monitor.ArchiveMetrics `json:"archive"`
+	TransferDir      string                 `json:"transfer_dir"`
+	TransferTime     time.Duration          `json:"transfer_time_seconds"`
+	TransferredBytes int64                  `json:"transferred_bytes"`
+	TransferRateMBs  float64                `json:"transfer_rate_mbs"`
+	ThrottleMBs      float64                `json:"throttle_mbs,omitempty"`
+}
+
+// ValidateAirGap checks that the air-gap workflow can run with the rest of the configuration
+func (c *Config) ValidateAirGap() error {
+	if !c.usesAirGap() {
+		if c.AirGap.TransferRateMBs != 0 {
+			return fmt.Errorf("a transfer rate requires the air-gap workflow to be enabled")
+		}
+		return nil
+	}
+	if c.AirGap.TransferRateMBs < 0 {
+		return fmt.Errorf("air-gap transfer rate must not be negative")
+	}
+	if c.IsOCITarget() {
+		return fmt.Errorf("the air-gap workflow needs a registry target")
+	}
+	if c.IsRegistryComparison() {
+		return fmt.Errorf("the air-gap workflow cannot be combined with registry comparison")
+	}
+	if c.Delete.Enabled {
+		return fmt.Errorf("the air-gap workflow cannot be combined with the delete phase")
+	}
+	return nil
+}
+
+// archivePattern returns the archive names oc-mirror writes for a version
+func archivePattern(version string) string {
+	if version == "v1" {
+		return "mirror_seq*.tar"
+	}
+	return "mirror_*.tar"
+}
+
+// airGapDir returns where the archives of a version are transferred to
+func airGapDir(version string) string {
+	return filepath.Join(airGapRoot, version)
+}
+
+// uploadCacheDir returns the oc-mirror --cache-dir of the upload phase: the
+// cache of the disconnected side in the air-gap workflow
+func (tr *TestRunner) uploadCacheDir(version string) string {
+	if tr.config.AirGap.Enabled && version == "v2" {
+		return airGapCacheDir
+	}
+	return tr.cacheDir(version)
+}
+
+// uploadFrom returns the archive directory the v1 upload mirrors from
+func (tr *TestRunner) uploadFrom(version string) string {
+	if tr.config.AirGap.Enabled {
+		return airGapDir(version) + "/"
+	}
+	return tr.mirrorDir(version) + "/"
+}
+
+// startArchiveMonitor removes the archives of the previous iteration, which
+// were transferred already, and watches for the archives of this download.
+// It returns nil unless the air-gap workflow is enabled.
+func (tr *TestRunner) startArchiveMonitor(version string) *monitor.ArchiveMonitor {
+	if !tr.config.AirGap.Enabled {
+		return nil
+	}
+	dir := tr.mirrorDir(version)
+	stale, _ := monitor.FindArchives(dir, archivePattern(version))
+	for _, file := range stale {
+		if err := os.Remove(filepath.Join(dir, file.Name)); err != nil {
+			logging.Printf("  │ Warning: Failed to remove archive of the previous iteration: %v\n", err)
+		}
+	}
+	archiveMonitor := monitor.NewArchiveMonitor(dir, archivePattern(version))
+	if err := archiveMonitor.Start(); err != nil {
+		// The archives are still found when the phase ends, without a creation time
+		logging.Printf("  │ Warning: Failed to start archive monitoring: %v\n", err)
+	}
+	return archiveMonitor
+}
+
+// runTransferPhase copies the archives of the download phase to the
+// disconnected side, replacing those of the previous iteration, at the
+// configured transfer rate
+func (tr *TestRunner) runTransferPhase(version string, archive monitor.ArchiveMetrics) (*AirGapMetrics, error) {
+	metrics := &AirGapMetrics{Archive: archive, TransferDir: airGapDir(version), ThrottleMBs: tr.config.AirGap.TransferRateMBs}
+	archive.PrintSummary()
+	if len(archive.Files) == 0 {
+		return metrics, fmt.Errorf("no archive matching %s in %s", archivePattern(version), archive.Dir)
+	}
+
+	stale, _ := monitor.FindArchives(metrics.TransferDir, archivePattern(version))
+	for _, file := range stale {
+		if err := os.Remove(filepath.Join(metrics.TransferDir, file.Name)); err != nil {
+			return metrics, fmt.Errorf("failed to remove transferred archive: %w", err)
+		}
+	}
+	if err := os.MkdirAll(metrics.TransferDir, 0755); err != nil {
+		return metrics, fmt.Errorf("failed to create transfer directory: %w", err)
+	}
+
+	startTime := time.Now()
+	for _, file := range archive.Files {
+		n, err := transferFile(filepath.Join(metrics.TransferDir, file.Name), filepath.Join(archive.Dir, file.Name), metrics.ThrottleMBs)
+		metrics.TransferredBytes += n
+		if err != nil {
+			metrics.TransferTime = time.Since(startTime)
+			return metrics, fmt.Errorf("failed to transfer %s: %w", file.Name, err)
+		}
+	}
+	metrics.TransferTime = time.Since(startTime)
+	if seconds := metrics.TransferTime.Seconds(); seconds > 0 {
+		metrics.TransferRateMBs = float64(metrics.TransferredBytes) / seconds / (1024 * 1024)
+	}
+
+	logging.Printf("  │ Transferred %s to %s in %v (%.2f MB/s)\n",
+		monitor.FormatBytesHuman(metrics.TransferredBytes), metrics.TransferDir, metrics.TransferTime.Round(time.Millisecond), metrics.TransferRateMBs)
+	return metrics, nil
+}
+
+// transferFile copies src to dst, sleeping between chunks so the copy does
+// not exceed rateMBs when it is set
+func transferFile(dst, src string, rateMBs float64) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+	buf := make([]byte, transferChunk)
+	start := time.Now()
+	for {
+		n, readErr := in.Read(buf)
+		if n > 0 {
+			if _, err := out.Write(buf[:n]); err != nil {
+				out.Close()
+				return written, err
+			}
+			written += int64(n)
+			if rateMBs > 0 {
+				due := time.Duration(float64(written) / (rateMBs * 1024 * 1024) * float64(time.Second))
+				if wait := due - time.Since(start); wait > 0 {
+					time.Sleep(wait)
+				}
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			out.Close()
+			return written, readErr
+		}
+	}
+	return written, out.Close()
+}
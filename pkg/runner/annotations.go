@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Note is a free-text remark about a run, e.g. "registry upgraded to Quay 3.12"
+type Note struct {
+	Text      string    `json:"text"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Annotations are the tags and notes attached to a run. The ones given when
+// the run starts are kept in the results header; the ones added later, from
+// the web UI, in the annotations sidecar so the results file and its
+// checksum stay untouched.
+type Annotations struct {
+	Tags  map[string]string `json:"tags,omitempty"` // A tag without a value is a label, e.g. new-nic
+	Notes []Note            `json:"notes,omitempty"`
+}
+
+// AnnotationsPath returns the annotations sidecar of a results file
+func AnnotationsPath(resultsPath string) string {
+	return resultsPath + ".annotations"
+}
+
+// ParseTags parses key=value tags; a tag without "=" has an empty value
+func ParseTags(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	tags := make(map[string]string, len(values))
+	for _, value := range values {
+		key, tagValue, _ := strings.Cut(value, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("invalid tag %q (expected key=value or key)", value)
+		}
+		tags[key] = strings.TrimSpace(tagValue)
+	}
+	return tags, nil
+}
+
+// ParseAnnotations decodes an annotations sidecar
+func ParseAnnotations(data []byte) (Annotations, error) {
+	var annotations Annotations
+	if err := json.Unmarshal(data, &annotations); err != nil {
+		return Annotations{}, fmt.Errorf("failed to parse annotations: %w", err)
+	}
+	return annotations, nil
+}
+
+// Annotations returns the tags and notes the run was started with
+func (h ResultsHeader) Annotations() Annotations {
+	return Annotations{Tags: h.Tags, Notes: h.Notes}
+}
+
+// Merge adds the tags and notes of other; its tags replace tags of the same key
+func (a Annotations) Merge(other Annotations) Annotations {
+	merged := Annotations{Notes: append(append([]Note(nil), a.Notes...), other.Notes...)}
+	if len(a.Tags)+len(other.Tags) > 0 {
+		merged.Tags = make(map[string]string, len(a.Tags)+len(other.Tags))
+		for _, tags := range []map[string]string{a.Tags, other.Tags} {
+			for key, value := range tags {
+				merged.Tags[key] = value
+			}
+		}
+	}
+	sort.SliceStable(merged.Notes, func(i, j int) bool {
+		return merged.Notes[i].CreatedAt.Before(merged.Notes[j].CreatedAt)
+	})
+	return merged
+}
+
+// HasTag reports whether the annotations carry the tag key, or key=value
+// when filter has a value
+func (a Annotations) HasTag(filter string) bool {
+	key, value, withValue := strings.Cut(filter, "=")
+	tagValue, ok := a.Tags[key]
+	return ok && (!withValue || tagValue == value)
+}
+
+// String lists the tags as key=value, sorted by key
+func (a Annotations) String() string {
+	keys := make([]string, 0, len(a.Tags))
+	for key := range a.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		if value := a.Tags[key]; value != "" {
+			keys[i] = key + "=" + value
+		}
+	}
+	return strings.Join(keys, ", ")
+}
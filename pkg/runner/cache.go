@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// cacheDir returns the oc-mirror --cache-dir of a version; v1 has no local cache
+func (tr *TestRunner) cacheDir(version string) string {
+	if version == "v2" {
+		return tr.config.GetCacheDir()
+	}
+	return ""
+}
+
+// startCacheMonitor measures the oc-mirror cache before a phase; nil without a cache (v1)
+func startCacheMonitor(dir string) *monitor.CacheMonitor {
+	if dir == "" {
+		return nil
+	}
+	cacheMonitor := monitor.NewCacheMonitor(dir)
+	if err := cacheMonitor.Start(); err != nil {
+		logging.Printf("  │ Warning: Failed to measure oc-mirror cache: %v\n", err)
+		return nil
+	}
+	return cacheMonitor
+}
+
+// stopCacheMonitor measures the oc-mirror cache after a phase and returns the change, if any
+func stopCacheMonitor(cacheMonitor *monitor.CacheMonitor) *monitor.CacheMetrics {
+	if cacheMonitor == nil {
+		return nil
+	}
+	metrics := cacheMonitor.Stop()
+	return &metrics
+}
+
+// cacheHits returns the blobs that were already cached when the phase started
+func cacheHits(metrics *monitor.CacheMetrics) int {
+	if metrics == nil {
+		return 0
+	}
+	return metrics.Before.Blobs
+}
+
+// inferCacheHitRatio compares the cache growth of a download phase with the
+// clean run of the same version and stage. The clean run is the reference; when it found
+// the cache already warm from an earlier run, no ratio can be inferred.
+func (tr *TestRunner) inferCacheHitRatio(metrics *monitor.CacheMetrics, isCleanRun bool, version string) {
+	if metrics == nil {
+		return
+	}
+	if isCleanRun {
+		if metrics.Before.Blobs > 0 {
+			logging.Printf("  │ Note: oc-mirror cache was already warm (%d blobs), cache hit ratio not inferred\n", metrics.Before.Blobs)
+			return
+		}
+		metrics.InferHitRatio(metrics.GrowthBytes)
+		return
+	}
+	for _, r := range tr.results {
+		if r.IsCleanRun && r.Version == version && sameStage(r.Stage, tr.stage) && r.DownloadPhase.CacheMetrics != nil && r.DownloadPhase.CacheMetrics.HitRatio != nil {
+			metrics.InferHitRatio(r.DownloadPhase.CacheMetrics.GrowthBytes)
+			return
+		}
+	}
+}
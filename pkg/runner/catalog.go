@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/registry"
+)
+
+// catalogPrefix returns the repository path an upload pushes under: v1
+// uploads to the registry root, v2 under the path of the registry URL
+func (tr *TestRunner) catalogPrefix(version string) string {
+	if version == "v2" {
+		return registryPath(tr.targetRegistry())
+	}
+	return ""
+}
+
+// snapshotCatalog lists the repositories and tag counts of the target
+// registry before an upload. It returns nil when disabled or for OCI layout
+// targets, whose content is counted by the output analysis.
+func (tr *TestRunner) snapshotCatalog(version string) *registry.CatalogSnapshot {
+	if !tr.config.CatalogDiff || tr.config.IsOCITarget() {
+		return nil
+	}
+	client, err := registry.NewClient(tr.targetRegistry(), "", tr.config.SkipTLS)
+	if err != nil {
+		logging.Printf("  │ Warning: Failed to list the registry catalog: %v\n", err)
+		return nil
+	}
+	snapshot, err := registry.SnapshotCatalog(context.Background(), client, tr.catalogPrefix(version))
+	if err != nil {
+		logging.Printf("  │ Warning: Failed to list the registry catalog: %v\n", err)
+		return nil
+	}
+	return snapshot
+}
+
+// diffCatalog lists the target registry again after an upload and compares
+// it with the listing taken before, as a server-side cross-check of what the
+// upload published
+func (tr *TestRunner) diffCatalog(version string, before *registry.CatalogSnapshot) *registry.CatalogDiff {
+	if before == nil {
+		return nil
+	}
+	after := tr.snapshotCatalog(version)
+	if after == nil {
+		return nil
+	}
+	diff := registry.DiffCatalogs(tr.targetRegistry(), before, after)
+	diff.PrintSummary()
+	return diff
+}
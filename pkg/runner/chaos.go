@@ -0,0 +1,236 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// chaosPollInterval is how often the bytes written are measured against the kill threshold
+const chaosPollInterval = 2 * time.Second
+
+// ChaosConfig kills oc-mirror during the download of each clean iteration
+// and runs the download again, to measure how much of the interrupted work
+// the re-run resumes from the cache instead of fetching it again
+type ChaosConfig struct {
+	KillAfter      time.Duration `json:"kill_after,omitempty" yaml:"killAfter,omitempty"`
+	KillAfterBytes string        `json:"kill_after_bytes,omitempty" yaml:"killAfterBytes,omitempty"` // Written to the workspace and cache, e.g. 2Gi
+}
+
+// ChaosMetrics represents an interrupted download and its resume. The resume
+// is the download phase of the iteration.
+type ChaosMetrics struct {
+	KillAfter      time.Duration `json:"kill_after_seconds,omitempty"`
+	KillAfterBytes int64         `json:"kill_after_bytes,omitempty"`
+	Killed         bool          `json:"killed"` // false when oc-mirror ended before the threshold
+	KilledAt       time.Duration `json:"killed_at_seconds,omitempty"`
+	KilledAtBytes  int64         `json:"killed_at_bytes,omitempty"` // Written to the workspace and cache when killed
+	Interrupted    PhaseMetrics  `json:"interrupted"`
+	Error          string        `json:"error,omitempty"` // oc-mirror failed on its own before the kill
+
+	InterruptedNetworkBytes int64         `json:"interrupted_network_bytes"` // Fetched before the kill
+	RetainedBytes           int64         `json:"retained_bytes"`            // Left in the workspace and cache after the kill
+	ResumeNetworkBytes      int64         `json:"resume_network_bytes"`      // Fetched by the resumed download
+	RedoneBytes             int64         `json:"redone_bytes"`              // Fetched before the kill but not retained, so fetched again
+	ResumedPercent          float64       `json:"resumed_percent"`           // Of the bytes fetched before the kill, those the resume kept
+	TotalWallTime           time.Duration `json:"total_wall_time_seconds"`   // Interrupted and resumed download
+}
+
+// Enabled returns true if a kill threshold is set
+func (c ChaosConfig) Enabled() bool {
+	return c.KillAfter > 0 || c.KillAfterBytes != ""
+}
+
+// Validate checks the kill thresholds
+func (c ChaosConfig) Validate() error {
+	if c.KillAfter < 0 {
+		return fmt.Errorf("kill time must not be negative")
+	}
+	if c.KillAfterBytes == "" {
+		return nil
+	}
+	size, err := monitor.ParseByteSize(c.KillAfterBytes)
+	if err != nil {
+		return fmt.Errorf("invalid kill threshold: %w", err)
+	}
+	if size <= 0 {
+		return fmt.Errorf("kill threshold must be positive")
+	}
+	return nil
+}
+
+// String returns a human-readable description of the kill thresholds
+func (c ChaosConfig) String() string {
+	var triggers []string
+	if c.KillAfter > 0 {
+		triggers = append(triggers, fmt.Sprintf("after %v", c.KillAfter))
+	}
+	if c.KillAfterBytes != "" {
+		triggers = append(triggers, fmt.Sprintf("after %s written", c.KillAfterBytes))
+	}
+	return "kill oc-mirror " + strings.Join(triggers, " or ") + " in clean downloads, then resume"
+}
+
+// chaosKiller kills the oc-mirror process of a download once it ran for the
+// kill time or wrote the kill bytes to the workspace and cache
+type chaosKiller struct {
+	after      time.Duration
+	afterBytes int64
+	paths      []string
+	baseline   int64
+
+	started bool
+	stopCh  chan struct{}
+	done    chan struct{}
+
+	// Set by the watch, read after stop
+	killed    bool
+	killedAt  time.Duration
+	killBytes int64
+}
+
+// newChaosKiller measures the workspace and cache the thresholds count from
+func (tr *TestRunner) newChaosKiller(version string) *chaosKiller {
+	afterBytes, _ := monitor.ParseByteSize(tr.config.Chaos.KillAfterBytes)
+	k := &chaosKiller{
+		after:      tr.config.Chaos.KillAfter,
+		afterBytes: afterBytes,
+		paths:      tr.diskIOPaths(version, false),
+		stopCh:     make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	k.baseline = k.written()
+	return k
+}
+
+// written returns the current size of the workspace and cache
+func (k *chaosKiller) written() int64 {
+	var total int64
+	for _, path := range k.paths {
+		size, _ := dirSize(path)
+		total += size
+	}
+	return total
+}
+
+// start watches the oc-mirror process; nil-safe so downloads without chaos pass nil
+func (k *chaosKiller) start(pid int) {
+	if k == nil {
+		return
+	}
+	k.started = true
+	go func() {
+		defer close(k.done)
+		startTime := time.Now()
+		ticker := time.NewTicker(chaosPollInterval)
+		defer ticker.Stop()
+		var deadline <-chan time.Time
+		if k.after > 0 {
+			timer := time.NewTimer(k.after)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+		for {
+			select {
+			case <-k.stopCh:
+				return
+			case <-deadline:
+			case <-ticker.C:
+				if k.afterBytes == 0 || k.written()-k.baseline < k.afterBytes {
+					continue
+				}
+			}
+			k.kill(pid, time.Since(startTime))
+			return
+		}
+	}()
+}
+
+// kill sends SIGKILL to oc-mirror, as a crash or power loss would end it
+func (k *chaosKiller) kill(pid int, elapsed time.Duration) {
+	written := k.written() - k.baseline
+	process, err := os.FindProcess(pid)
+	if err == nil {
+		err = process.Kill()
+	}
+	if err != nil {
+		logging.Printf("  │ Warning: Failed to kill oc-mirror (PID %d): %v\n", pid, err)
+		return
+	}
+	k.killed, k.killedAt, k.killBytes = true, elapsed, written
+	logging.Printf("  │ Chaos: killed oc-mirror (PID %d) after %v, %s written\n",
+		pid, elapsed.Round(time.Second), monitor.FormatBytesHuman(written))
+}
+
+// stop ends the watch once oc-mirror exited
+func (k *chaosKiller) stop() {
+	if k == nil || !k.started {
+		return
+	}
+	close(k.stopCh)
+	<-k.done
+}
+
+// runInterruptedDownload runs the download of a clean iteration until the
+// kill threshold and kills oc-mirror. The download phase of the iteration
+// then resumes from what the killed run left behind.
+func (tr *TestRunner) runInterruptedDownload(iterationNum int, version string) *ChaosMetrics {
+	logging.Printf("\n  ┌─ Interrupted Download (%s) ──────────────────────────────────┐\n", version)
+	defer logging.Printf("  └─────────────────────────────────────────────────────────────┘\n")
+
+	killer := tr.newChaosKiller(version)
+	chaos := &ChaosMetrics{KillAfter: killer.after, KillAfterBytes: killer.afterBytes}
+
+	networkMonitor := monitor.NewNetworkMonitor()
+	tr.observe(networkMonitor, sampleSourceNetwork)
+	if err := networkMonitor.Start(); err != nil {
+		logging.Printf("  │ Warning: Failed to start network monitoring: %v\n", err)
+	}
+	metrics, err := tr.runDownloadPhase(true, version, tr.phaseLogFile(iterationNum, version, "download-killed", 1), killer)
+	chaos.InterruptedNetworkBytes = networkMonitor.Stop().TotalBytesTransferred
+	chaos.Interrupted = metrics
+
+	chaos.Killed, chaos.KilledAt, chaos.KilledAtBytes = killer.killed, killer.killedAt, killer.killBytes
+	chaos.RetainedBytes = killer.written() - killer.baseline
+	switch {
+	case chaos.Killed:
+		logging.Printf("  │ Retained: %s in the workspace and cache after the kill\n", monitor.FormatBytesHuman(chaos.RetainedBytes))
+	case err != nil:
+		chaos.Error = truncateError(err, 1000)
+		logging.Printf("  │ Warning: oc-mirror failed before the kill threshold: %s\n", truncateError(err, 200))
+	default:
+		logging.Printf("  │ Warning: oc-mirror finished before the kill threshold; the resume runs on a complete mirror\n")
+	}
+	return chaos
+}
+
+// resumed completes the metrics with the download that resumed the interrupted one
+func (c *ChaosMetrics) resumed(download PhaseMetrics, networkBytes int64) {
+	c.ResumeNetworkBytes = networkBytes
+	c.TotalWallTime = c.Interrupted.WallTime + download.WallTime
+	retained := min(c.RetainedBytes, c.InterruptedNetworkBytes)
+	c.RedoneBytes = max(c.InterruptedNetworkBytes-retained, 0)
+	if c.InterruptedNetworkBytes > 0 {
+		c.ResumedPercent = float64(max(retained, 0)) / float64(c.InterruptedNetworkBytes) * 100
+	}
+	c.PrintSummary()
+}
+
+// PrintSummary prints how much of the interrupted download the resume kept
+func (c *ChaosMetrics) PrintSummary() {
+	logging.Printf("\nInterrupted Download:\n")
+	if !c.Killed {
+		logging.Printf("  ❌ oc-mirror was not killed, no resume measured\n")
+		return
+	}
+	logging.Printf("  Killed after:   %v (%s written)\n", c.KilledAt.Round(time.Second), monitor.FormatBytesHuman(c.KilledAtBytes))
+	logging.Printf("  Before kill:    %s fetched, %s retained\n",
+		monitor.FormatBytesHuman(c.InterruptedNetworkBytes), monitor.FormatBytesHuman(c.RetainedBytes))
+	logging.Printf("  Resume:         %s fetched\n", monitor.FormatBytesHuman(c.ResumeNetworkBytes))
+	logging.Printf("  Redone:         %s (%.1f%% of the interrupted work resumed)\n", monitor.FormatBytesHuman(c.RedoneBytes), c.ResumedPercent)
+	logging.Printf("  Total time:     %v\n", c.TotalWallTime.Round(time.Millisecond))
+}
@@ -0,0 +1,245 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/telco-core/ngc-495/pkg/integrity"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// CleanupPolicy prunes what earlier runs left on the host before a run
+// starts, so long-lived lab hosts do not run out of disk mid-test
+type CleanupPolicy struct {
+	KeepLast       int    `json:"keep_last,omitempty" yaml:"keepLast,omitempty"`              // Results files kept in the results directory; 0 keeps all
+	MaxResultsSize string `json:"max_results_size,omitempty" yaml:"maxResultsSize,omitempty"` // Total size of the kept results files, e.g. 20Gi
+	Workspaces     bool   `json:"workspaces,omitempty" yaml:"workspaces,omitempty"`           // Remove the oc-mirror workspaces of earlier runs
+	Cache          bool   `json:"cache,omitempty" yaml:"cache,omitempty"`                     // Remove the oc-mirror v2 cache, so the clean run starts cold
+}
+
+// Enabled returns true if anything is pruned before the run
+func (p CleanupPolicy) Enabled() bool {
+	return p.KeepLast > 0 || p.MaxResultsSize != "" || p.Workspaces || p.Cache
+}
+
+// Validate checks the policy
+func (p CleanupPolicy) Validate() error {
+	if p.KeepLast < 0 {
+		return fmt.Errorf("keep-last must not be negative")
+	}
+	if p.MaxResultsSize != "" {
+		size, err := monitor.ParseByteSize(p.MaxResultsSize)
+		if err != nil {
+			return fmt.Errorf("invalid max results size: %w", err)
+		}
+		if size <= 0 {
+			return fmt.Errorf("max results size must be positive")
+		}
+	}
+	return nil
+}
+
+// MaxResultsBytes returns the results size limit in bytes, zero when unset or invalid
+func (p CleanupPolicy) MaxResultsBytes() int64 {
+	size, _ := monitor.ParseByteSize(p.MaxResultsSize)
+	return size
+}
+
+// String returns a human-readable description of the policy
+func (p CleanupPolicy) String() string {
+	var parts []string
+	if p.KeepLast > 0 {
+		parts = append(parts, fmt.Sprintf("keep last %d results", p.KeepLast))
+	}
+	if p.MaxResultsSize != "" {
+		parts = append(parts, fmt.Sprintf("results up to %s", p.MaxResultsSize))
+	}
+	if p.Workspaces {
+		parts = append(parts, "workspaces")
+	}
+	if p.Cache {
+		parts = append(parts, "cache")
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// resultsRun is a results file of an earlier run with its sidecars and logs
+type resultsRun struct {
+	path  string
+	files []string // Results file first
+	size  int64
+}
+
+// cleanup applies the cleanup policy before the run. Failures to remove
+// single files are reported; the run goes on.
+func (tr *TestRunner) cleanup() {
+	policy := tr.config.Cleanup
+	if !policy.Enabled() {
+		return
+	}
+	logging.Printf("\nCleanup:\n")
+
+	if policy.Workspaces {
+		tr.pruneDirs("workspaces", tr.workspaceDirs())
+	}
+	if policy.Cache {
+		tr.pruneDirs("oc-mirror cache", []string{tr.config.GetCacheDir()})
+	}
+	if policy.KeepLast > 0 || policy.MaxResultsSize != "" {
+		tr.pruneResults(policy)
+	}
+}
+
+// workspaceDirs returns the oc-mirror workspaces any earlier run may have left.
+// The first iteration of every run is clean and starts from empty workspaces.
+func (tr *TestRunner) workspaceDirs() []string {
+	return []string{
+		tr.legacyMirrorDir(),
+		tr.mirrorDir("v1"),
+		tr.mirrorDir("v2"),
+		"platform/mirror",
+		airGapRoot,
+	}
+}
+
+// pruneDirs removes dirs and reports the space freed
+func (tr *TestRunner) pruneDirs(what string, dirs []string) {
+	var freed int64
+	for _, dir := range dirs {
+		size, err := dirSize(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			logging.Printf("  Warning: Failed to remove %s: %v\n", dir, err)
+			continue
+		}
+		freed += size
+	}
+	logging.Printf("  Removed %s: %s freed\n", what, monitor.FormatBytesHuman(freed))
+}
+
+// pruneResults removes the oldest results files beyond the newest KeepLast,
+// then the oldest of the rest until they fit in MaxResultsSize
+func (tr *TestRunner) pruneResults(policy CleanupPolicy) {
+	runs, err := listResultsRuns(tr.config.GetResultsDir(), tr.resultsPath)
+	if err != nil {
+		logging.Printf("  Warning: %v\n", err)
+		return
+	}
+
+	var total int64
+	for _, run := range runs {
+		total += run.size
+	}
+	limit := policy.MaxResultsBytes()
+	var pruned []resultsRun
+	for len(runs) > 0 {
+		overCount := policy.KeepLast > 0 && len(runs) > policy.KeepLast
+		overSize := limit > 0 && total > limit
+		if !overCount && !overSize {
+			break
+		}
+		pruned = append(pruned, runs[0])
+		total -= runs[0].size
+		runs = runs[1:]
+	}
+
+	var freed int64
+	removed := 0
+	for _, run := range pruned {
+		failed := false
+		for _, file := range run.files {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				logging.Printf("  Warning: Failed to remove %s: %v\n", file, err)
+				failed = true
+			}
+		}
+		if !failed {
+			removed++
+			freed += run.size
+		}
+	}
+	logging.Printf("  Removed %d results file(s) with sidecars and logs: %s freed, %d kept (%s)\n",
+		removed, monitor.FormatBytesHuman(freed), len(runs), monitor.FormatBytesHuman(total))
+}
+
+// listResultsRuns returns the results files in dir with their sidecars and
+// phase logs, oldest first by the run start embedded in the file name. The
+// results file of the current run is left out.
+func listResultsRuns(dir, current string) ([]resultsRun, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "results_*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list results files: %w", err)
+	}
+
+	runs := make([]resultsRun, 0, len(matches))
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || filepath.Clean(path) == filepath.Clean(current) {
+			continue
+		}
+		run := resultsRun{path: path}
+		for _, file := range resultsRunFiles(path) {
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				run.files = append(run.files, file)
+				run.size += info.Size()
+			}
+		}
+		runs = append(runs, run)
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return resultsRunTime(runs[i].path) < resultsRunTime(runs[j].path)
+	})
+	return runs, nil
+}
+
+// resultsRunFiles returns the files written for the results file at path
+func resultsRunFiles(path string) []string {
+	files := append([]string{path}, ResultsSidecars(path)...)
+	logs, _ := filepath.Glob(LogsPattern(path))
+	return append(files, logs...)
+}
+
+// ResultsSidecars returns the files that may be written next to the results
+// file at path: integrity, samples, run state and annotations sidecars, the
+// ticket report and the bundle. path may also be a slash-separated store name.
+func ResultsSidecars(path string) []string {
+	base := strings.TrimSuffix(path, ".json")
+	return []string{
+		integrity.ChecksumPath(path),
+		integrity.SignaturePath(path),
+		base + "_report.md",
+		base + ".tar.gz",
+		SamplesPath(path, SampleStorageDelta),
+		SamplesPath(path, SampleStorageDeltaGzip),
+		StatePath(path),
+		AnnotationsPath(path),
+	}
+}
+
+// LogsPattern returns the glob matching the phase logs of the results file at path
+func LogsPattern(path string) string {
+	return filepath.Join(filepath.Dir(path), "logs", strings.TrimSuffix(filepath.Base(path), ".json")+"_*_iter*.log")
+}
+
+// resultsRunTime returns a sortable start time of a results file, falling
+// back to its modification time for names without a timestamp
+func resultsRunTime(path string) string {
+	if meta, ok := ParseResultsFileName(path); ok {
+		return meta.Timestamp.Format(resultsTimestampLayout)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return info.ModTime().Format(resultsTimestampLayout)
+}
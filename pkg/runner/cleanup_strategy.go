@@ -0,0 +1,171 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// Workspace cleanup strategies, applied before an iteration
+const (
+	CleanupKeep     = "keep"     // Nothing is removed; the iteration reuses the workspace and cache
+	CleanupMirror   = "mirror"   // Empty the workspace of the version; the cache stays warm
+	CleanupCache    = "cache"    // Empty the workspace and the v2 cache, so the download starts cold
+	CleanupRegistry = "registry" // Also delete the content the run mirrored from the target, so the upload starts cold
+)
+
+// CleanupStrategy prepares the host for an iteration by removing what the
+// iteration must not reuse
+type CleanupStrategy interface {
+	// Name is the strategy name of --workspace-cleanup
+	Name() string
+	// Clean removes the state of earlier iterations; what it measured is recorded on result
+	Clean(tr *TestRunner, version string, result *TestResult) error
+}
+
+// cleanupStrategies lists the strategies by name
+var cleanupStrategies = map[string]CleanupStrategy{
+	CleanupKeep:     keepCleanup{},
+	CleanupMirror:   mirrorCleanup{},
+	CleanupCache:    cacheCleanup{},
+	CleanupRegistry: registryCleanup{},
+}
+
+// cleanupStrategyNames returns the strategy names from the least to the most removed
+func cleanupStrategyNames() []string {
+	return []string{CleanupKeep, CleanupMirror, CleanupCache, CleanupRegistry}
+}
+
+type keepCleanup struct{}
+
+func (keepCleanup) Name() string { return CleanupKeep }
+
+func (keepCleanup) Clean(*TestRunner, string, *TestResult) error { return nil }
+
+type mirrorCleanup struct{}
+
+func (mirrorCleanup) Name() string { return CleanupMirror }
+
+// Clean empties the workspace, the OCI layout target and the air-gap directories of the version
+func (mirrorCleanup) Clean(tr *TestRunner, version string, _ *TestResult) error {
+	return tr.cleanWorkspaceForVersion(version)
+}
+
+type cacheCleanup struct{}
+
+func (cacheCleanup) Name() string { return CleanupCache }
+
+// Clean empties the workspace and removes the cache of the version; v1 keeps no cache
+func (cacheCleanup) Clean(tr *TestRunner, version string, result *TestResult) error {
+	if err := (mirrorCleanup{}).Clean(tr, version, result); err != nil {
+		return err
+	}
+	if dir := tr.cacheDir(version); dir != "" {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to clear the cache: %w", err)
+		}
+	}
+	return nil
+}
+
+type registryCleanup struct{}
+
+func (registryCleanup) Name() string { return CleanupRegistry }
+
+// Clean deletes the content the run uploaded to the target registry with
+// oc-mirror delete, which needs the cache, then empties the workspace and
+// cache. An OCI layout target is emptied with the workspace.
+func (registryCleanup) Clean(tr *TestRunner, version string, result *TestResult) error {
+	if !tr.config.IsOCITarget() {
+		if !tr.uploadedTo(tr.targetRegistry()) {
+			logging.Printf("  Registry holds no content of this run, nothing to delete\n")
+		} else {
+			logging.Printf("  Deleting the mirrored content from %s...\n", tr.targetRegistry())
+			metrics, err := tr.deleteContent()
+			result.RegistryCleanup = metrics
+			if err != nil {
+				metrics.Error = truncateError(err, 500)
+				return fmt.Errorf("failed to delete the mirrored content from the registry: %w", err)
+			}
+			logging.Printf("  Deleted %d image(s) in %v\n", metrics.DeleteMetrics.ImagesDeleted, metrics.WallTime.Round(time.Millisecond))
+		}
+	}
+	return (cacheCleanup{}).Clean(tr, version, result)
+}
+
+// uploadedTo returns true if an iteration of the run uploaded to registry,
+// failed uploads included since they may have pushed part of the content
+func (tr *TestRunner) uploadedTo(registry string) bool {
+	for _, r := range tr.results {
+		if r.Registry == registry && r.UploadPhase.WallTime > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanupStrategy returns the strategy of an iteration (1-based): the
+// configured one, the last applying to later iterations, cache for every
+// iteration of repeated clean runs, otherwise mirror for a clean iteration
+// and keep for the others
+func (c *Config) cleanupStrategy(iteration int, isCleanRun bool) CleanupStrategy {
+	if c.RepeatClean {
+		return cacheCleanup{}
+	}
+	if n := len(c.WorkspaceCleanup); n > 0 {
+		return cleanupStrategies[c.WorkspaceCleanup[min(iteration, n)-1]]
+	}
+	return defaultCleanup(isCleanRun)
+}
+
+// defaultCleanup returns mirror for a clean iteration and keep for the others
+func defaultCleanup(isCleanRun bool) CleanupStrategy {
+	if isCleanRun {
+		return mirrorCleanup{}
+	}
+	return keepCleanup{}
+}
+
+// cleanIteration returns true if the iteration starts from an emptied
+// workspace. The configured strategies decide when set.
+func (c *Config) cleanIteration(iteration int, isCleanRun bool) bool {
+	if len(c.WorkspaceCleanup) == 0 && !c.RepeatClean {
+		return isCleanRun
+	}
+	return c.cleanupStrategy(iteration, isCleanRun).Name() != CleanupKeep
+}
+
+// ValidateWorkspaceCleanup checks the strategy names, that a registry
+// cleanup can delete what was mirrored and that repeated clean runs have
+// nothing else deciding the cache
+func (c *Config) ValidateWorkspaceCleanup() error {
+	if c.RepeatClean {
+		switch {
+		case len(c.WorkspaceCleanup) > 0:
+			return fmt.Errorf("repeated clean runs cannot be combined with a workspace cleanup")
+		case len(c.Matrix.CacheStates) > 0:
+			return fmt.Errorf("repeated clean runs cannot be combined with matrix cache states")
+		case c.UpdateContent != nil:
+			return fmt.Errorf("repeated clean runs cannot be combined with a day-2 update")
+		}
+	}
+	if len(c.WorkspaceCleanup) == 0 {
+		return nil
+	}
+	for _, name := range c.WorkspaceCleanup {
+		if _, ok := cleanupStrategies[name]; !ok {
+			return fmt.Errorf("unknown workspace cleanup %q (valid: %s)", name, strings.Join(cleanupStrategyNames(), ", "))
+		}
+	}
+	if len(c.Matrix.CacheStates) > 0 {
+		return fmt.Errorf("workspace cleanup cannot be combined with matrix cache states")
+	}
+	if slices.Contains(c.WorkspaceCleanup, CleanupRegistry) && !c.IsOCITarget() && c.runsV1() {
+		return fmt.Errorf("the registry workspace cleanup deletes with oc-mirror v2 and cannot be combined with v1")
+	}
+	return nil
+}
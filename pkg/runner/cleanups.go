@@ -0,0 +1,46 @@
+package runner
+
+import "sync"
+
+// Cleanups is a stack of functions undoing the changes a run makes to the
+// host, such as network shaping rules or a local registry container. The
+// command running the test owns signal handling: it runs the stack when the
+// run ends and when it is interrupted, so nothing is left behind either way.
+type Cleanups struct {
+	mu    sync.Mutex
+	funcs []func()
+}
+
+// Push adds fn to the stack and returns a function that runs it at most once,
+// for a caller that undoes its change itself before the stack runs
+func (c *Cleanups) Push(fn func()) func() {
+	var once sync.Once
+	run := func() { once.Do(fn) }
+	if c == nil {
+		return run
+	}
+	c.mu.Lock()
+	c.funcs = append(c.funcs, run)
+	c.mu.Unlock()
+	return run
+}
+
+// Run calls the functions on the stack, last pushed first, and empties it. A
+// concurrent Run waits until these have finished.
+func (c *Cleanups) Run() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := len(c.funcs) - 1; i >= 0; i-- {
+		c.funcs[i]()
+	}
+	c.funcs = nil
+}
+
+// SetCleanups sets the stack the run pushes its host changes on, so that the
+// command can undo them when it is interrupted
+func (tr *TestRunner) SetCleanups(cleanups *Cleanups) {
+	tr.cleanups = cleanups
+}
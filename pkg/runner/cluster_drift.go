@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// checkClusterDrift compares the cluster resources of the last successful
+// upload with the state applied on the cluster of the configured kubeconfig
+// and records the report in the results file. Drift is reported, not failed
+// on: the cluster may lag behind the mirror on purpose.
+func (tr *TestRunner) checkClusterDrift() {
+	if tr.config.Kubeconfig == "" {
+		return
+	}
+	resources := tr.lastClusterResources()
+	if resources == nil {
+		logging.Printf("\nWarning: No generated cluster resources to compare with the cluster\n")
+		return
+	}
+
+	report, err := command.CompareClusterResources(tr.config.Kubeconfig, resources)
+	if err != nil {
+		logging.Printf("\nWarning: Failed to check cluster drift: %v\n", err)
+		return
+	}
+	report.PrintSummary()
+
+	tr.header.ClusterDrift = report
+	if err := tr.saveResults(); err != nil {
+		logging.Printf("Warning: Failed to save cluster drift: %v\n", err)
+	}
+}
+
+// validateClusterArtifacts checks that the cluster resources of this upload
+// point at the registry it pushed to. oci:// targets have no registry to
+// point at, so their resources are not checked.
+func (tr *TestRunner) validateClusterArtifacts(result *TestResult) *command.ClusterArtifactValidation {
+	resources := result.UploadPhase.ClusterResources
+	if resources == nil || resources.TotalFiles == 0 || tr.config.IsOCITarget() {
+		return nil
+	}
+	validation := command.ValidateClusterArtifacts(resources, tr.targetRegistry())
+	validation.PrintSummary()
+	return validation
+}
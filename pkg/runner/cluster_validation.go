@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+const (
+	// validationNamespace holds the sample workload on the test cluster
+	validationNamespace = "oc-mirror-test-validation"
+	// defaultValidationTimeout covers the machine config rollout of the mirror sets and the pulls
+	defaultValidationTimeout = 30 * time.Minute
+)
+
+// ClusterValidationConfig enables the end-to-end validation phase: after the
+// iterations, the generated cluster resources are applied to the disposable
+// test cluster of the kubeconfig and a sample workload checks that images are
+// pulled from the mirror
+type ClusterValidationConfig struct {
+	Enabled  bool          `json:"enabled" yaml:"enabled,omitempty"`
+	Images   []string      `json:"images,omitempty" yaml:"images,omitempty"`     // Run as pods; default: the additional images of the content
+	Operator string        `json:"operator,omitempty" yaml:"operator,omitempty"` // Package to subscribe to; default: a package of the mirrored catalogs
+	Channel  string        `json:"channel,omitempty" yaml:"channel,omitempty"`
+	Timeout  time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// GetTimeout returns the validation timeout, defaulting to defaultValidationTimeout
+func (c ClusterValidationConfig) GetTimeout() time.Duration {
+	if c.Timeout <= 0 {
+		return defaultValidationTimeout
+	}
+	return c.Timeout
+}
+
+// ValidateClusterValidation checks that the validation phase has a cluster and a registry to pull from
+func (c *Config) ValidateClusterValidation() error {
+	v := c.ClusterValidation
+	if !v.Enabled {
+		if len(v.Images) > 0 || v.Operator != "" || v.Channel != "" || v.Timeout != 0 {
+			return fmt.Errorf("cluster validation options require cluster validation to be enabled")
+		}
+		return nil
+	}
+	if c.Kubeconfig == "" {
+		return fmt.Errorf("cluster validation requires a kubeconfig")
+	}
+	if c.IsOCITarget() {
+		return fmt.Errorf("cluster validation needs a registry target the cluster can pull from")
+	}
+	if v.Timeout < 0 {
+		return fmt.Errorf("cluster validation timeout must not be negative")
+	}
+	if v.Channel != "" && v.Operator == "" {
+		return fmt.Errorf("a cluster validation channel requires an operator")
+	}
+	return nil
+}
+
+// clusterValidationOptions returns the sample workload: the configured images
+// and operator, or those of the mirrored content
+func (tr *TestRunner) clusterValidationOptions() command.ClusterValidationOptions {
+	v := tr.config.ClusterValidation
+	opts := command.ClusterValidationOptions{
+		Kubeconfig: tr.config.Kubeconfig,
+		Namespace:  validationNamespace,
+		Images:     v.Images,
+		Timeout:    v.GetTimeout(),
+	}
+	content := tr.mirroredContent()
+	if content == nil {
+		return opts
+	}
+	if len(opts.Images) == 0 {
+		opts.Images = content.AdditionalImages
+	}
+
+	catalog, pkg, ok := content.SampleOperator()
+	switch {
+	case v.Operator != "":
+		// Without a mirrored catalog, the only generated CatalogSource is used
+		opts.Operator = &command.OperatorWorkload{Package: v.Operator, Channel: v.Channel, Catalog: catalog}
+	case ok:
+		opts.Operator = &command.OperatorWorkload{Package: pkg.Name, Channel: pkg.DefaultChannel, Catalog: catalog}
+		if opts.Operator.Channel == "" && len(pkg.Channels) > 0 {
+			opts.Operator.Channel = pkg.Channels[0].Name
+		}
+	}
+	return opts
+}
+
+// lastClusterResources returns the cluster resources generated by the last
+// successful upload, or nil if none was
+func (tr *TestRunner) lastClusterResources() *command.ClusterResourcesMetrics {
+	for i := len(tr.results) - 1; i >= 0; i-- {
+		if !tr.results[i].Failed && tr.results[i].UploadPhase.ClusterResources != nil {
+			return tr.results[i].UploadPhase.ClusterResources
+		}
+	}
+	return nil
+}
+
+// validateOnCluster applies the cluster resources of the last successful
+// upload to the test cluster and records whether the sample workload pulled
+// its images from the mirror. A failed validation fails the run.
+func (tr *TestRunner) validateOnCluster() error {
+	if !tr.config.ClusterValidation.Enabled {
+		return nil
+	}
+	resources := tr.lastClusterResources()
+	if resources == nil {
+		logging.Printf("\nWarning: No generated cluster resources to apply to the cluster\n")
+		return nil
+	}
+
+	opts := tr.clusterValidationOptions()
+	workload := append([]string(nil), opts.Images...)
+	if opts.Operator != nil {
+		workload = append(workload, opts.Operator.Package)
+	}
+	if len(workload) == 0 {
+		logging.Printf("\nWarning: No images or operators to deploy; validating the catalog source pods only\n")
+	}
+	logging.Printf("\nValidating the mirror on %s (up to %v): %s\n", opts.Kubeconfig, opts.Timeout, strings.Join(workload, ", "))
+
+	report, err := command.ValidateOnCluster(opts, resources)
+	if err != nil {
+		return fmt.Errorf("failed to validate the mirror on the cluster: %w", err)
+	}
+	report.PrintSummary()
+
+	tr.header.ClusterValidation = report
+	if err := tr.saveResults(); err != nil {
+		logging.Printf("Warning: Failed to save cluster validation: %v\n", err)
+	}
+	if !report.Passed {
+		return fmt.Errorf("cluster validation failed (see cluster_validation in the results file)")
+	}
+	return nil
+}
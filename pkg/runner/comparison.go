@@ -0,0 +1,240 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// Units of comparison metrics
+const (
+	UnitSeconds = "seconds"
+	UnitMBs     = "MB/s"
+	UnitMbps    = "Mbps"
+	UnitPercent = "%"
+	UnitMB      = "MB"
+	UnitBytes   = "bytes"
+	UnitCount   = "count"
+)
+
+// Which value of a comparison metric is the better one
+const (
+	BetterLower  = "lower"
+	BetterHigher = "higher"
+)
+
+// ComparisonMetric is one metric of the two sides of a comparison
+type ComparisonMetric struct {
+	Section      string  `json:"section"`
+	Name         string  `json:"name"`
+	Unit         string  `json:"unit"`
+	A            float64 `json:"a"`
+	B            float64 `json:"b"`
+	DeltaPercent float64 `json:"delta_percent"`    // Change from A to B, 0 when A is 0
+	Better       string  `json:"better,omitempty"` // lower or higher; empty when neither is better
+}
+
+// ComparisonSide is the iterations one side of a comparison is taken from
+type ComparisonSide struct {
+	Label           string `json:"label"`
+	File            string `json:"file,omitempty"`
+	Version         string `json:"version"`
+	Iteration       int    `json:"iteration"`
+	Clean           bool   `json:"clean"`                      // The compared iteration is a clean run
+	CachedIteration int    `json:"cached_iteration,omitempty"` // First cached iteration after it, compared for caching effectiveness
+}
+
+// RunComparison compares the clean iteration of two sides, v1 and v2 of a
+// run or two runs, and their first cached iterations when both have one
+type RunComparison struct {
+	A       ComparisonSide     `json:"a"`
+	B       ComparisonSide     `json:"b"`
+	Metrics []ComparisonMetric `json:"metrics"`
+	Notes   []string           `json:"notes,omitempty"`
+}
+
+// CompareRuns compares two sides of completed iterations. Each side is
+// represented by its first clean iteration, or its first iteration when none
+// is clean, and the first cached iteration after it.
+func CompareRuns(labelA string, a []TestResult, labelB string, b []TestResult) (*RunComparison, error) {
+	primaryA, cachedA := comparisonIterations(a)
+	primaryB, cachedB := comparisonIterations(b)
+	if primaryA == nil {
+		return nil, fmt.Errorf("%s has no completed iteration", labelA)
+	}
+	if primaryB == nil {
+		return nil, fmt.Errorf("%s has no completed iteration", labelB)
+	}
+
+	c := &RunComparison{
+		A: ComparisonSide{Label: labelA, Version: primaryA.Version, Iteration: primaryA.Iteration, Clean: primaryA.IsCleanRun},
+		B: ComparisonSide{Label: labelB, Version: primaryB.Version, Iteration: primaryB.Iteration, Clean: primaryB.IsCleanRun},
+	}
+	if primaryA.IsCleanRun != primaryB.IsCleanRun {
+		c.Notes = append(c.Notes, "a clean iteration is compared with a cached one")
+	}
+	c.addMetrics(*primaryA, *primaryB)
+	if cachedA != nil && cachedB != nil {
+		c.A.CachedIteration, c.B.CachedIteration = cachedA.Iteration, cachedB.Iteration
+		c.addCaching(*primaryA, *cachedA, *primaryB, *cachedB)
+	}
+	return c, nil
+}
+
+// comparisonIterations returns the iteration a side is represented by and
+// the first cached iteration after it; failed, warm-up and day-2 update
+// iterations are left out
+func comparisonIterations(results []TestResult) (primary, cached *TestResult) {
+	var measured []*TestResult
+	for i := range results {
+		if r := &results[i]; !r.Failed && !r.Warmup && !r.IsUpdateRun {
+			measured = append(measured, r)
+		}
+	}
+	for _, r := range measured {
+		if r.IsCleanRun {
+			primary = r
+			break
+		}
+	}
+	if primary == nil && len(measured) > 0 {
+		primary = measured[0]
+	}
+	for _, r := range measured {
+		if r != primary && !r.IsCleanRun && r.Iteration > primary.Iteration {
+			return primary, r
+		}
+	}
+	return primary, nil
+}
+
+// add appends a metric and its change from A to B
+func (c *RunComparison) add(section, name, unit, better string, a, b float64) {
+	m := ComparisonMetric{Section: section, Name: name, Unit: unit, A: a, B: b, Better: better}
+	if a != 0 {
+		m.DeltaPercent = (b - a) / a * 100
+	}
+	c.Metrics = append(c.Metrics, m)
+}
+
+// addMetrics compares the timing, speed, resource, network, content, error
+// and output metrics of the compared iterations
+func (c *RunComparison) addMetrics(a, b TestResult) {
+	c.add("Timing", "Download Time", UnitSeconds, BetterLower, a.DownloadPhase.WallTime.Seconds(), b.DownloadPhase.WallTime.Seconds())
+	c.add("Timing", "Upload Time", UnitSeconds, BetterLower, a.UploadPhase.WallTime.Seconds(), b.UploadPhase.WallTime.Seconds())
+	c.add("Timing", "Total Time", UnitSeconds, BetterLower,
+		(a.DownloadPhase.WallTime + a.UploadPhase.WallTime).Seconds(), (b.DownloadPhase.WallTime + b.UploadPhase.WallTime).Seconds())
+	if a.UploadPhase.ClusterResources != nil || b.UploadPhase.ClusterResources != nil {
+		c.add("Timing", "Cluster Resources Generation", UnitSeconds, BetterLower, clusterResourcesTime(a).Seconds(), clusterResourcesTime(b).Seconds())
+	}
+
+	c.add("Download Speed", "Average Download Speed", UnitMBs, BetterHigher,
+		a.DownloadPhase.DownloadMetrics.AverageSpeedMBs, b.DownloadPhase.DownloadMetrics.AverageSpeedMBs)
+	c.add("Download Speed", "Peak Download Speed", UnitMBs, BetterHigher,
+		a.DownloadPhase.DownloadMetrics.PeakSpeedMBs, b.DownloadPhase.DownloadMetrics.PeakSpeedMBs)
+
+	c.add("Resource Usage", "CPU Average", UnitPercent, BetterLower, a.ResourceMetrics.CPUAvgPercent, b.ResourceMetrics.CPUAvgPercent)
+	c.add("Resource Usage", "CPU Peak", UnitPercent, BetterLower, a.ResourceMetrics.CPUPeakPercent, b.ResourceMetrics.CPUPeakPercent)
+	c.add("Resource Usage", "Memory Average", UnitMB, BetterLower, a.ResourceMetrics.MemoryAvgMB, b.ResourceMetrics.MemoryAvgMB)
+	c.add("Resource Usage", "Memory Peak", UnitMB, BetterLower, a.ResourceMetrics.MemoryPeakMB, b.ResourceMetrics.MemoryPeakMB)
+
+	c.add("Network Bandwidth", "Average Bandwidth", UnitMbps, BetterHigher, a.NetworkMetrics.AverageBandwidthMbps, b.NetworkMetrics.AverageBandwidthMbps)
+	c.add("Network Bandwidth", "Peak Bandwidth", UnitMbps, BetterHigher, a.NetworkMetrics.PeakBandwidthMbps, b.NetworkMetrics.PeakBandwidthMbps)
+
+	if a.DescribeMetrics != nil && b.DescribeMetrics != nil {
+		da, db := a.DescribeMetrics, b.DescribeMetrics
+		c.add("Mirror Content", "Total Images", UnitCount, "", float64(da.TotalImages), float64(db.TotalImages))
+		c.add("Mirror Content", "Total Layers", UnitCount, "", float64(da.TotalLayers), float64(db.TotalLayers))
+		c.add("Mirror Content", "Total Manifests", UnitCount, "", float64(da.TotalManifests), float64(db.TotalManifests))
+		c.add("Mirror Content", "Operator Packages", UnitCount, "", float64(da.OperatorPackages), float64(db.OperatorPackages))
+		c.add("Mirror Content", "Total Associations", UnitCount, "", float64(da.TotalAssociations), float64(db.TotalAssociations))
+	} else {
+		c.Notes = append(c.Notes, "oc-mirror describe metrics not available for comparison")
+	}
+
+	ea := a.DownloadPhase.ExtendedMetrics
+	eb := b.DownloadPhase.ExtendedMetrics
+	ua := a.UploadPhase.ExtendedMetrics
+	ub := b.UploadPhase.ExtendedMetrics
+	c.add("Errors and Retries", "Errors", UnitCount, BetterLower, float64(ea.ErrorCount+ua.ErrorCount), float64(eb.ErrorCount+ub.ErrorCount))
+	c.add("Errors and Retries", "Retries", UnitCount, BetterLower, float64(ea.RetryCount+ua.RetryCount), float64(eb.RetryCount+ub.RetryCount))
+	c.add("Errors and Retries", "Warnings", UnitCount, BetterLower, float64(ea.WarningCount+ua.WarningCount), float64(eb.WarningCount+ub.WarningCount))
+	if ta, tb := a.UploadPhase.UploadTraffic, b.UploadPhase.UploadTraffic; ta != nil && tb != nil {
+		c.add("Errors and Retries", "Upload Retries (accounting proxy)", UnitCount, BetterLower, float64(ta.Retries), float64(tb.Retries))
+		c.add("Errors and Retries", "Throttled Uploads (accounting proxy)", UnitCount, BetterLower, float64(ta.Throttled), float64(tb.Throttled))
+		c.add("Errors and Retries", "Max Upload Retry Backoff", UnitSeconds, BetterLower, ta.RetryBackoffMax.Seconds(), tb.RetryBackoffMax.Seconds())
+	}
+
+	c.add("Output Size", "Total Downloaded", UnitBytes, "", float64(a.OutputMetrics.TotalSize), float64(b.OutputMetrics.TotalSize))
+	c.add("Output Size", "Total Files", UnitCount, "", float64(a.OutputMetrics.TotalFiles), float64(b.OutputMetrics.TotalFiles))
+}
+
+// addCaching compares how much faster the cached iteration of each side downloads than its clean one
+func (c *RunComparison) addCaching(cleanA, cachedA, cleanB, cachedB TestResult) {
+	improvement := func(clean, cached TestResult) float64 {
+		if clean.DownloadPhase.WallTime == 0 {
+			return 0
+		}
+		return float64(clean.DownloadPhase.WallTime-cached.DownloadPhase.WallTime) / float64(clean.DownloadPhase.WallTime) * 100
+	}
+	c.add("Caching Effectiveness", "Download Time Improvement (Clean vs Cached)", UnitPercent, BetterHigher,
+		improvement(cleanA, cachedA), improvement(cleanB, cachedB))
+	c.add("Caching Effectiveness", "Cache Hits (Cached Run)", UnitCount, BetterHigher,
+		float64(cachedA.DownloadPhase.CacheHits), float64(cachedB.DownloadPhase.CacheHits))
+}
+
+// FormatValue formats a metric value in its unit
+func (m ComparisonMetric) FormatValue(value float64) string {
+	switch m.Unit {
+	case UnitSeconds:
+		return time.Duration(value * float64(time.Second)).Round(time.Millisecond).String()
+	case UnitBytes:
+		return monitor.FormatBytesHuman(int64(value))
+	case UnitCount:
+		return fmt.Sprintf("%.0f", value)
+	case UnitPercent:
+		return fmt.Sprintf("%.2f%%", value)
+	}
+	return fmt.Sprintf("%.2f %s", value, m.Unit)
+}
+
+// Print prints the comparison in sections, with how much faster or slower
+// B is for the timings
+func (c *RunComparison) Print() {
+	section := ""
+	for _, m := range c.Metrics {
+		if m.Section != section {
+			section = m.Section
+			printComparisonLine("")
+			printComparisonLine("═══ " + strings.ToUpper(section) + " " + strings.Repeat("═", max(0, 68-utf8.RuneCountInString(section))))
+			printComparisonLine("")
+		}
+		printComparisonLine(m.Name + ":")
+		printComparisonLine("  " + c.A.Label + ": " + m.FormatValue(m.A))
+		printComparisonLine("  " + c.B.Label + ": " + m.FormatValue(m.B))
+		if m.Unit == UnitSeconds && m.A > 0 && m.Section == "Timing" {
+			status := "faster"
+			if m.B > m.A {
+				status = "slower"
+			}
+			diff := m.DeltaPercent
+			if diff < 0 {
+				diff = -diff
+			}
+			printComparisonLine(fmt.Sprintf("  %s is %.2f%% %s", c.B.Label, diff, status))
+		}
+	}
+	for _, note := range c.Notes {
+		printComparisonLine("")
+		printComparisonLine("(" + note + ")")
+	}
+}
+
+// printComparisonLine prints a line inside the comparison box
+func printComparisonLine(text string) {
+	logging.Printf("║  %s%s ║\n", text, strings.Repeat(" ", max(0, 76-utf8.RuneCountInString(text))))
+}
@@ -1,9 +1,238 @@
 package runner
 
+import (
+	"time"
+
+	"github.com/telco-core/ngc-495/internal/config"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+	"github.com/telco-core/ngc-495/pkg/netshape"
+	"github.com/telco-core/ngc-495/pkg/notify"
+	"github.com/telco-core/ngc-495/pkg/pacing"
+	"github.com/telco-core/ngc-495/pkg/plugin"
+	"github.com/telco-core/ngc-495/pkg/proxy"
+	"github.com/telco-core/ngc-495/pkg/ticket"
+	"github.com/telco-core/ngc-495/pkg/tracing"
+)
+
 // Config holds the test runner configuration
 type Config struct {
 	RegistryURL string
 	Iterations  int
 	CompareV1V2 bool
 	SkipTLS     bool
+	Shaping     netshape.Config // Optional tc/netem constraints applied for the whole run
+	ExtraArgs   []string        // Additional oc-mirror arguments for every invocation
+	Pacing      pacing.Config   // Optional upload pacing: concurrency cap and allowed windows
+
+	// Directory the client tools are downloaded into and run from, and the
+	// OpenShift version they are downloaded for when missing. With
+	// ToolsFromDir, missing tools are installed from the tarballs staged there.
+	BinDir       string
+	ToolsVersion string
+	ToolsFromDir string
+
+	// Dimensions of the iteration matrix; every combination of version,
+	// reference, workflow and concurrency runs the iterations in the listed
+	// cache states. ComparePinning runs the content by tag and pinned to digests.
+	Matrix         MatrixConfig
+	ComparePinning bool
+
+	// Additional registries receiving identical content for a registry
+	// comparison, visited in RegistryOrder (sequential or round-robin)
+	CompareRegistries []string
+	RegistryOrder     string
+
+	// Name of the scenario definition this run was created from and the
+	// sha256 of its file, recorded in the results file
+	ScenarioName string
+	ScenarioHash string
+
+	// Version of this tool, recorded in the results file
+	ToolVersion string
+
+	// run flags the run was started with, kept in the run state file next to
+	// the results file. Resume is the state of an interrupted run to continue:
+	// its completed iterations are skipped and its results file extended.
+	RunArgs []string
+	Resume  *RunState
+
+	// Mirrored content (nil uses the default operator catalog)
+	ContentScenario string              // Scenario name recorded with results (operators, additional-images, helm, mixed, custom)
+	Content         *config.ContentSpec // Content rendered into the imageset configuration
+
+	// Day-2 update: from the second iteration on, this content is mirrored
+	// instead, measuring the incremental cost of newer versions
+	UpdateContent *config.ContentSpec
+
+	// How network traffic is attributed to the test: interface (default) or process
+	NetworkAccounting string
+
+	// oc-mirror output lines each phase keeps in the results besides the log file
+	LogRetention LogRetention
+
+	// Which processes the oc-mirror CPU and memory cover: process (default),
+	// tree (the process and its children) or cgroup (a transient cgroup v2)
+	ResourceScope string
+
+	// Where monitor samples are stored: inline (default), delta or delta-gzip
+	// for a delta-encoded sidecar next to the results file
+	SampleStorage string
+
+	// Local path backing the destination registry storage, recorded in the environment snapshot
+	RegistryStoragePath string
+
+	// Where oc-mirror keeps its per-version workspaces (default "mirror") and
+	// the v2 blob cache (default "operators-v2")
+	WorkspaceDir string
+	CacheDir     string
+
+	// What earlier runs left on the host is pruned before the run starts, and
+	// the free space is checked against the estimated footprint of the run
+	Cleanup   CleanupPolicy
+	DiskSpace DiskSpaceCheck
+
+	// Optional registry or reverse proxy access log read for the response status
+	// codes of the upload phase, and whether oc-mirror uploads log at debug level
+	// so the status codes can be read from its output instead
+	RegistryAccessLog string
+	UploadDebugLog    bool
+
+	// Push uploads through a local reverse proxy counting the exact bytes,
+	// API calls and status codes per repository
+	AccountingProxy bool
+
+	// Simulated registry rate limiting in the accounting proxy: injected 429s
+	// and a bandwidth cap per connection; enables the proxy
+	RateLimit proxy.RateLimit
+
+	// List the target registry catalog before and after each upload and
+	// record which repositories and tags the upload added
+	CatalogDiff bool
+
+	// Render the operator catalogs with opm before mirroring and check that
+	// the images of the selected bundles are in the registry after each upload
+	ValidateContent bool
+
+	// Attribute the bytes in the registry after each upload to the operator
+	// packages of the content, from their catalogs rendered with opm
+	PackageSizes bool
+
+	// Compare the blob digests of the local mirror with the blobs the
+	// registry references after each upload
+	VerifyUpload bool
+
+	// Optional kill of oc-mirror during clean downloads, to measure how much
+	// of the interrupted work the download resumes from the cache
+	Chaos ChaosConfig
+
+	// Optional HTTP(S) proxy for oc-mirror, and whether to compare the
+	// iterations through the proxy with direct ones
+	Proxy ProxyConfig
+
+	// Optional Prometheus endpoint of the target registry scraped during uploads
+	RegistryMetrics monitor.RegistryServerConfig
+
+	// Optional back-off of the poll interval of the phase monitors on long phases
+	AdaptivePolling monitor.AdaptivePolling
+
+	// Failed download/upload phases are retried up to RetryFailed times, waiting
+	// RetryBackoff (doubling per retry) in between. With retries enabled, an
+	// iteration that still fails is recorded and the remaining iterations run.
+	RetryFailed  int
+	RetryBackoff time.Duration
+
+	// oc-mirror is killed when a download or upload phase runs longer, zero
+	// for no limit. The monitors of the phase are still stopped and recorded.
+	DownloadTimeout time.Duration
+	UploadTimeout   time.Duration
+
+	// Cleanup strategy of each iteration (keep, mirror, cache or registry),
+	// the last applying to later iterations. Empty: mirror for clean
+	// iterations, keep for cached ones.
+	WorkspaceCleanup []string
+
+	// Run every iteration clean, from an empty workspace and v2 cache, to
+	// measure the spread of clean runs
+	RepeatClean bool
+
+	// Iterations run before the measured ones of each version, left out of
+	// the comparisons and statistics
+	Warmup int
+
+	// Where results are written (default "results") and an optional run name
+	// embedded in the results file name
+	ResultsDir string
+	RunName    string
+
+	// Free-text notes and key/value tags describing the run, e.g. the
+	// registry version or a hardware change, kept in the results header
+	Tags  map[string]string
+	Notes []string
+
+	// Optional HMAC key used to sign results files (a sha256 checksum is always written)
+	SigningKey []byte
+
+	// Do not rewrite a live progress line during oc-mirror invocations, e.g.
+	// for CI logs; it is only shown on a terminal anyway
+	NoTUI bool
+
+	// Optional NDJSON file receiving the run events as they happen: phase
+	// starts and ends, monitor samples and iteration and run summaries
+	EventsFile string
+
+	// Liveness reporting for unattended runs
+	HeartbeatFile     string        // Status file rewritten on every beat
+	HeartbeatURL      string        // Optional URL receiving the status as a JSON POST
+	HeartbeatInterval time.Duration // Interval between beats
+
+	// Webhooks receiving a summary when the run completes or fails
+	Notify notify.Config
+
+	// Optional OTLP endpoint receiving a trace per iteration
+	Tracing tracing.Config
+
+	// External programs feeding samples into each phase or consuming the run events
+	Plugins plugin.Config
+
+	// Optional oc-mirror delete run after the iterations, measuring pruning cost
+	Delete DeleteConfig
+
+	// Optional host memory budget checked against oc-mirror RSS plus page cache pressure
+	MemoryCeiling monitor.MemoryCeilingConfig
+
+	// Optional scan for cosign signatures and attestations mirrored with the images
+	Signatures SignatureConfig
+
+	// Jira issue or ServiceNow ticket receiving the run report and results bundle
+	Ticket ticket.Config
+
+	// Optional results store receiving the results file and sidecars when the run ends
+	Store StoreConfig
+
+	// Optional cluster whose applied IDMS/ITMS/ICSP and catalog sources are
+	// compared with the generated ones after the run
+	Kubeconfig string
+
+	// Optional pass/fail gates checked as each iteration completes
+	Gates GateConfig
+
+	// Optional fully disconnected workflow: mirror to an archive, transfer it
+	// and mirror from the transferred archive
+	AirGap AirGapConfig
+
+	// Optional replication of the uploaded content to further registries
+	// after the upload, in a chain or hub-and-spoke topology
+	Replication ReplicationConfig
+
+	// Optional end-to-end validation applying the generated cluster resources
+	// to the cluster of Kubeconfig and pulling a sample workload from the mirror
+	ClusterValidation ClusterValidationConfig
+
+	// Optional check after each upload that the cluster of Kubeconfig serves
+	// the mirrored catalogs and resolves their packages
+	PullThrough PullThroughConfig
+
+	// Optional priority-ordered mirroring: the content is split into stages
+	// mirrored one after another by separate oc-mirror invocations
+	Stages []StageConfig
 }
@@ -1,6 +1,14 @@
 package runner
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
 
 // Config methods
 
@@ -12,29 +20,276 @@ func (c *Config) Validate() error {
 	if c.Iterations < 1 {
 		return fmt.Errorf("iterations must be at least 1")
 	}
-	if c.Iterations < 2 && !c.CompareV1V2 {
+	if c.Iterations < 2 && len(c.matrixGroups()) < 2 {
 		return fmt.Errorf("iterations must be at least 2 for clean vs cached comparison")
 	}
+	if c.IsOCITarget() {
+		if c.OCILayoutPath() == "" {
+			return fmt.Errorf("oci:// target requires a layout directory path")
+		}
+		if c.runsV1() {
+			return fmt.Errorf("oci:// target is only supported by oc-mirror v2")
+		}
+		if c.ValidateContent {
+			return fmt.Errorf("content validation needs a registry target")
+		}
+		if c.VerifyUpload {
+			return fmt.Errorf("upload verification needs a registry target")
+		}
+		if c.PackageSizes {
+			return fmt.Errorf("operator package sizes need a registry target")
+		}
+	}
+	if err := c.ValidateRegistryComparison(); err != nil {
+		return err
+	}
+	if err := c.ValidateMatrix(); err != nil {
+		return fmt.Errorf("invalid iteration matrix: %w", err)
+	}
+	if err := c.ValidateDelete(); err != nil {
+		return err
+	}
+	if err := c.ValidateStorage(); err != nil {
+		return err
+	}
+	if err := c.ValidateWorkspaceCleanup(); err != nil {
+		return err
+	}
+	if err := c.Cleanup.Validate(); err != nil {
+		return fmt.Errorf("invalid cleanup policy: %w", err)
+	}
+	if err := c.DiskSpace.Validate(); err != nil {
+		return err
+	}
+	if c.Content != nil {
+		if err := c.Content.Validate(); err != nil {
+			return fmt.Errorf("invalid content: %w", err)
+		}
+	}
+	if err := c.ValidateUpdate(); err != nil {
+		return err
+	}
+	if err := c.ValidateStages(); err != nil {
+		return err
+	}
+	if err := c.Shaping.Validate(); err != nil {
+		return fmt.Errorf("invalid network shaping: %w", err)
+	}
+	if err := c.Pacing.Validate(); err != nil {
+		return fmt.Errorf("invalid upload pacing: %w", err)
+	}
+	if err := c.Notify.Validate(); err != nil {
+		return fmt.Errorf("invalid notifications: %w", err)
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		return fmt.Errorf("invalid tracing: %w", err)
+	}
+	if err := c.MemoryCeiling.Validate(); err != nil {
+		return fmt.Errorf("invalid memory ceiling: %w", err)
+	}
+	if err := c.AdaptivePolling.Validate(); err != nil {
+		return fmt.Errorf("invalid adaptive polling: %w", err)
+	}
+	if err := c.Signatures.Validate(); err != nil {
+		return fmt.Errorf("invalid signature verification: %w", err)
+	}
+	if err := c.Chaos.Validate(); err != nil {
+		return fmt.Errorf("invalid chaos option: %w", err)
+	}
+	if err := c.ValidateProxyComparison(); err != nil {
+		return err
+	}
+	if err := c.Ticket.Validate(); err != nil {
+		return fmt.Errorf("invalid ticket: %w", err)
+	}
+	if err := c.Store.Validate(); err != nil {
+		return fmt.Errorf("invalid results store: %w", err)
+	}
+	if c.RetryFailed < 0 {
+		return fmt.Errorf("retry-failed must not be negative")
+	}
+	if c.DownloadTimeout < 0 || c.UploadTimeout < 0 {
+		return fmt.Errorf("phase timeouts must not be negative")
+	}
+	if err := c.ValidateKubeconfig(); err != nil {
+		return err
+	}
+	if err := c.Gates.Validate(); err != nil {
+		return fmt.Errorf("invalid gates: %w", err)
+	}
+	if err := c.ValidateClusterValidation(); err != nil {
+		return err
+	}
+	if err := c.ValidatePullThrough(); err != nil {
+		return err
+	}
+	if err := c.ValidateWarmup(); err != nil {
+		return err
+	}
+	if err := c.ValidateAccountingProxy(); err != nil {
+		return err
+	}
+	if err := c.ValidateAirGap(); err != nil {
+		return err
+	}
+	if err := c.ValidateReplication(); err != nil {
+		return err
+	}
+	switch c.NetworkAccounting {
+	case "", NetworkAccountingInterface, NetworkAccountingProcess:
+	default:
+		return fmt.Errorf("unknown network accounting mode %q (valid: %s, %s)", c.NetworkAccounting, NetworkAccountingInterface, NetworkAccountingProcess)
+	}
+	if err := c.LogRetention.Validate(); err != nil {
+		return err
+	}
+	switch c.ResourceScope {
+	case "", monitor.ResourceScopeProcess, monitor.ResourceScopeTree, monitor.ResourceScopeCgroup:
+	default:
+		return fmt.Errorf("unknown resource scope %q (valid: %s, %s, %s)", c.ResourceScope, monitor.ResourceScopeProcess, monitor.ResourceScopeTree, monitor.ResourceScopeCgroup)
+	}
+	switch c.SampleStorage {
+	case "", SampleStorageInline, SampleStorageDelta, SampleStorageDeltaGzip:
+	default:
+		return fmt.Errorf("unknown sample storage %q (valid: %s, %s, %s)", c.SampleStorage, SampleStorageInline, SampleStorageDelta, SampleStorageDeltaGzip)
+	}
+	return nil
+}
+
+// ValidateKubeconfig checks that the kubeconfig of the cluster drift check exists
+func (c *Config) ValidateKubeconfig() error {
+	if c.Kubeconfig == "" {
+		return nil
+	}
+	if _, err := os.Stat(c.Kubeconfig); err != nil {
+		return fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	return nil
+}
+
+// GetResultsDir returns the results directory, defaulting to DefaultResultsDir
+func (c *Config) GetResultsDir() string {
+	if c.ResultsDir == "" {
+		return DefaultResultsDir
+	}
+	return c.ResultsDir
+}
+
+// GetWorkspaceDir returns the oc-mirror workspace directory, defaulting to DefaultWorkspaceDir
+func (c *Config) GetWorkspaceDir() string {
+	if c.WorkspaceDir == "" {
+		return DefaultWorkspaceDir
+	}
+	return c.WorkspaceDir
+}
+
+// GetCacheDir returns the oc-mirror v2 cache directory, defaulting to DefaultCacheDir
+func (c *Config) GetCacheDir() string {
+	if c.CacheDir == "" {
+		return DefaultCacheDir
+	}
+	return c.CacheDir
+}
+
+// ValidateStorage checks that the cache is kept apart from the workspace,
+// which clean runs wipe
+func (c *Config) ValidateStorage() error {
+	workspace, err := filepath.Abs(c.GetWorkspaceDir())
+	if err != nil {
+		return fmt.Errorf("invalid workspace directory: %w", err)
+	}
+	cache, err := filepath.Abs(c.GetCacheDir())
+	if err != nil {
+		return fmt.Errorf("invalid cache directory: %w", err)
+	}
+	if cache == workspace || strings.HasPrefix(cache, workspace+string(filepath.Separator)) {
+		return fmt.Errorf("cache directory %s must not be inside the workspace directory %s, which clean runs wipe", c.GetCacheDir(), c.GetWorkspaceDir())
+	}
+	if strings.HasPrefix(workspace, cache+string(filepath.Separator)) {
+		return fmt.Errorf("workspace directory %s must not be inside the cache directory %s", c.GetWorkspaceDir(), c.GetCacheDir())
+	}
 	return nil
 }
 
+// GetRetryBackoff returns the wait before the first phase retry, defaulting to 30 seconds
+func (c *Config) GetRetryBackoff() time.Duration {
+	if c.RetryBackoff <= 0 {
+		return 30 * time.Second
+	}
+	return c.RetryBackoff
+}
+
+// formatPhaseTimeout prints a phase timeout for the run header
+func formatPhaseTimeout(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "none"
+	}
+	return timeout.String()
+}
+
+// GetNetworkAccounting returns the network accounting mode, defaulting to interface counters
+func (c *Config) GetNetworkAccounting() string {
+	if c.NetworkAccounting == "" {
+		return NetworkAccountingInterface
+	}
+	return c.NetworkAccounting
+}
+
+// GetResourceScope returns which processes the oc-mirror resource usage covers, defaulting to the process itself
+func (c *Config) GetResourceScope() string {
+	if c.ResourceScope == "" {
+		return monitor.ResourceScopeProcess
+	}
+	return c.ResourceScope
+}
+
+// GetSampleStorage returns where monitor samples are stored, defaulting to inline
+func (c *Config) GetSampleStorage() string {
+	if c.SampleStorage == "" {
+		return SampleStorageInline
+	}
+	return c.SampleStorage
+}
+
+// IsOCITarget returns true if the destination is a local OCI layout directory
+func (c *Config) IsOCITarget() bool {
+	return strings.HasPrefix(c.RegistryURL, "oci://")
+}
+
+// OCILayoutPath returns the filesystem path of an oci:// destination
+func (c *Config) OCILayoutPath() string {
+	return strings.TrimRight(strings.TrimPrefix(c.RegistryURL, "oci://"), "/")
+}
+
+// GetContentScenario returns the content scenario name, defaulting to operators
+func (c *Config) GetContentScenario() string {
+	if c.ContentScenario == "" {
+		return "operators"
+	}
+	return c.ContentScenario
+}
+
 // GetEffectiveIterations returns the effective number of iterations
 // For v1/v2 comparison, this accounts for both versions
 func (c *Config) GetEffectiveIterations() int {
-	if c.CompareV1V2 {
-		return c.Iterations * 2 // Both v1 and v2
+	if c.IsRegistryComparison() {
+		return c.Iterations * len(c.TargetRegistries())
 	}
-	return c.Iterations
+	return c.Iterations * len(c.matrixGroups()) // Both v1 and v2 in a v1/v2 comparison
 }
 
 // String returns a string representation of the configuration
 func (c *Config) String() string {
 	mode := "Standard"
-	if c.CompareV1V2 {
+	if c.matrixEnabled() {
+		mode = fmt.Sprintf("Matrix (%d combinations)", len(c.matrixGroups()))
+	} else if c.CompareV1V2 {
 		mode = "V1/V2 Comparison"
+	} else if c.IsRegistryComparison() {
+		mode = fmt.Sprintf("Registry Comparison (%d registries, %s)", len(c.TargetRegistries()), c.GetRegistryOrder())
 	}
-	return fmt.Sprintf("Config{Registry: %s, Iterations: %d, Mode: %s, SkipTLS: %v}",
-		c.RegistryURL, c.Iterations, mode, c.SkipTLS)
+	return fmt.Sprintf("Config{Registry: %s, Iterations: %d, Mode: %s, SkipTLS: %v, Shaping: %s}",
+		c.RegistryURL, c.Iterations, mode, c.SkipTLS, c.Shaping.String())
 }
 
 
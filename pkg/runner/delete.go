@@ -0,0 +1,265 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/internal/config"
+	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// deleteConfigPath is where the DeleteImageSetConfiguration derived from the run content is written
+const deleteConfigPath = "oc-mirror-clone/deleteimagesetconfiguration-v2.yaml"
+
+// DeleteConfig configures the delete phase run after the iterations
+type DeleteConfig struct {
+	Enabled          bool   `json:"enabled" yaml:"enabled,omitempty"`
+	ConfigFile       string `json:"config_file,omitempty" yaml:"config,omitempty"`                  // DeleteImageSetConfiguration; default deletes the mirrored content
+	GCCommand        string `json:"gc_command,omitempty" yaml:"gcCommand,omitempty"`                // Shell command run afterwards to garbage-collect registry blobs
+	ForceCacheDelete bool   `json:"force_cache_delete,omitempty" yaml:"forceCacheDelete,omitempty"` // Also delete the images from the local cache
+}
+
+// DeletePhaseMetrics represents the metrics of the delete phase: generating the
+// list of images to delete, deleting them and optionally garbage-collecting blobs
+type DeletePhaseMetrics struct {
+	Registry         string                  `json:"registry"`
+	ConfigFile       string                  `json:"config_file"`
+	DeleteImagesFile string                  `json:"delete_images_file,omitempty"`
+	GenerateTime     time.Duration           `json:"generate_time_seconds"`
+	DeleteTime       time.Duration           `json:"delete_time_seconds"`
+	GCTime           time.Duration           `json:"gc_time_seconds,omitempty"`
+	WallTime         time.Duration           `json:"wall_time_seconds"`
+	ImagesPlanned    int                     `json:"images_planned"`
+	ImagesByType     map[string]int          `json:"images_by_type,omitempty"`
+	DeleteMetrics    command.DeleteMetrics   `json:"delete_metrics"`
+	StorageBefore    int64                   `json:"registry_storage_before_bytes,omitempty"` // Size of --registry-storage-path
+	StorageAfter     int64                   `json:"registry_storage_after_bytes,omitempty"`
+	BytesReclaimed   int64                   `json:"bytes_reclaimed"`
+	ResourceMetrics  monitor.ResourceMetrics `json:"resource_metrics"`
+	ExtendedMetrics  command.ExtendedMetrics `json:"extended_metrics"`
+	GenerateLogFile  string                  `json:"generate_log_file,omitempty"`
+	LogFile          string                  `json:"log_file,omitempty"`
+	GCLogFile        string                  `json:"gc_log_file,omitempty"`
+	Error            string                  `json:"error,omitempty"`
+}
+
+// ValidateDelete checks that the delete phase can run with the rest of the configuration
+func (c *Config) ValidateDelete() error {
+	if !c.Delete.Enabled {
+		if c.Delete.ConfigFile != "" || c.Delete.GCCommand != "" || c.Delete.ForceCacheDelete {
+			return fmt.Errorf("delete options require the delete phase to be enabled")
+		}
+		return nil
+	}
+	if c.runsV1() {
+		return fmt.Errorf("the delete phase is only supported by oc-mirror v2 and cannot be combined with v1/v2 comparison")
+	}
+	if c.IsRegistryComparison() {
+		return fmt.Errorf("the delete phase cannot be combined with registry comparison")
+	}
+	if c.IsOCITarget() {
+		return fmt.Errorf("the delete phase needs a registry target")
+	}
+	if c.Delete.ConfigFile != "" {
+		if _, err := os.Stat(c.Delete.ConfigFile); err != nil {
+			return fmt.Errorf("delete config: %w", err)
+		}
+	}
+	return nil
+}
+
+// runDeletePhase deletes the mirrored content from the registry after the
+// iterations and attaches the metrics to the last iteration
+func (tr *TestRunner) runDeletePhase() error {
+	if !tr.config.Delete.Enabled || len(tr.results) == 0 {
+		return nil
+	}
+
+	tr.setPhase("delete", "v2", 0)
+	logging.Printf("\n  ┌─ Delete Phase (v2) ─────────────────────────────────────────┐\n")
+	metrics, err := tr.deleteContent()
+	if err != nil {
+		metrics.Error = truncateError(err, 500)
+		logging.Printf("  │ Delete failed: %v\n", truncateError(err, 200))
+	} else {
+		printDeleteSummary(metrics)
+	}
+	logging.Printf("  └─────────────────────────────────────────────────────────────┘\n")
+
+	tr.results[len(tr.results)-1].DeletePhase = metrics
+	if err != nil {
+		return fmt.Errorf("delete phase failed: %w", err)
+	}
+	return nil
+}
+
+// deleteContent generates the list of images to delete, deletes them and runs
+// the optional garbage collection, measuring registry storage around all three
+func (tr *TestRunner) deleteContent() (*DeletePhaseMetrics, error) {
+	registryURL := strings.TrimRight(tr.targetRegistry(), "/")
+	if !strings.Contains(registryURL, "://") {
+		registryURL = "docker://" + registryURL
+	}
+	metrics := &DeletePhaseMetrics{Registry: registryURL, ConfigFile: tr.config.Delete.ConfigFile}
+	iteration := len(tr.results)
+	startTime := time.Now()
+	defer func() { metrics.WallTime = time.Since(startTime) }()
+
+	if metrics.ConfigFile == "" {
+		metrics.ConfigFile = deleteConfigPath
+		if err := config.CreateDeleteImageSetConfigForContent(deleteConfigPath, tr.mirroredContent()); err != nil {
+			return metrics, fmt.Errorf("failed to create delete imageset-config: %w", err)
+		}
+	}
+	if storagePath := tr.config.RegistryStoragePath; storagePath != "" {
+		metrics.StorageBefore = directorySize(storagePath)
+	}
+
+	// Step 1: write the list of images to delete into the workspace
+	deleteID := time.Now().Format("20060102150405")
+	generate := tr.newDeleteCommand()
+	generate.SetConfig(metrics.ConfigFile)
+	generate.SetWorkspace(tr.workspaceURL("v2"))
+	generate.SetGenerate(true)
+	generate.SetDeleteID(deleteID)
+	metrics.GenerateLogFile = tr.phaseLogFile(iteration, "v2", "delete-generate", 1)
+	generate.SetLogFile(metrics.GenerateLogFile)
+	generate.SetOutput(registryURL)
+
+	generateStart := time.Now()
+	endTrace := tr.traceOCMirror("delete generate", generate)
+	output, err := generate.Execute()
+	metrics.GenerateTime = time.Since(generateStart)
+	endTrace(output, err)
+	if err != nil {
+		return metrics, fmt.Errorf("oc-mirror delete --generate failed: %w", err)
+	}
+	logging.Printf("  │ Generated delete list in %v\n", metrics.GenerateTime.Round(time.Millisecond))
+
+	metrics.DeleteImagesFile = command.DeleteImagesFile(tr.mirrorDir("v2"), deleteID)
+	list, err := command.LoadDeleteImageList(metrics.DeleteImagesFile)
+	if err != nil {
+		return metrics, err
+	}
+	metrics.ImagesPlanned = len(list.Items)
+	metrics.ImagesByType = list.CountByType()
+
+	// Step 2: delete the listed manifests; debug logging exposes every registry request
+	resourceMonitor := tr.newProcessResourceMonitor()
+	execute := tr.newDeleteCommand()
+	execute.SetDeleteYAMLFile(metrics.DeleteImagesFile)
+	execute.SetForceCacheDelete(tr.config.Delete.ForceCacheDelete)
+	execute.SetExtraArgs([]string{"--log-level", "debug"})
+	metrics.LogFile = tr.phaseLogFile(iteration, "v2", "delete", 1)
+	execute.SetLogFile(metrics.LogFile)
+	execute.SetOutput(registryURL)
+
+	deleteStart := time.Now()
+	endTrace = tr.traceOCMirror("delete", execute)
+	output, err = execute.ExecuteWithCallback(func(pid int) {
+		resourceMonitor.SetTargetPID(pid)
+		if startErr := resourceMonitor.Start(); startErr != nil {
+			logging.Printf("  │ Warning: Failed to start resource monitoring for oc-mirror (PID %d): %v\n", pid, startErr)
+		}
+	})
+	metrics.DeleteTime = time.Since(deleteStart)
+	endTrace(output, err)
+	metrics.ResourceMetrics = resourceMonitor.Stop()
+	metrics.ExtendedMetrics = output.ExtractExtendedMetrics()
+	metrics.DeleteMetrics = output.ExtractDeleteMetrics()
+	if err != nil {
+		return metrics, fmt.Errorf("oc-mirror delete failed: %w", err)
+	}
+	if metrics.DeleteMetrics.ImagesDeleted == 0 && metrics.DeleteMetrics.ImagesFailed == 0 {
+		// oc-mirror logged no per-image progress; a successful delete removed the whole list
+		metrics.DeleteMetrics.ImagesDeleted = metrics.ImagesPlanned
+	}
+
+	// Step 3: registries only free blob storage on garbage collection
+	if tr.config.Delete.GCCommand != "" {
+		metrics.GCLogFile = tr.phaseLogFile(iteration, "v2", "delete-gc", 1)
+		gcStart := time.Now()
+		err := runGCCommand(tr.config.Delete.GCCommand, metrics.GCLogFile)
+		metrics.GCTime = time.Since(gcStart)
+		if err != nil {
+			return metrics, fmt.Errorf("registry garbage collection failed: %w", err)
+		}
+	}
+
+	if storagePath := tr.config.RegistryStoragePath; storagePath != "" {
+		metrics.StorageAfter = directorySize(storagePath)
+		metrics.BytesReclaimed = max(metrics.StorageBefore-metrics.StorageAfter, 0)
+	}
+	return metrics, nil
+}
+
+// newDeleteCommand returns an oc-mirror v2 delete command against the cache of the iterations
+func (tr *TestRunner) newDeleteCommand() *command.OCMirrorCommand {
+	cmd := command.NewOCMirrorCommand()
+	cmd.SetDelete(true)
+	cmd.SetV2(true)
+	cmd.SetSkipTLS(tr.config.SkipTLS)
+	cmd.SetEnv(tr.proxyEnv())
+	cmd.SetCacheDir(tr.config.GetCacheDir())
+	return cmd
+}
+
+// runGCCommand runs the registry garbage collection command through the shell,
+// writing its output to logFile
+func runGCCommand(gcCommand, logFile string) error {
+	logging.Printf("  │ Running registry garbage collection: %s\n", gcCommand)
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	file, err := os.Create(logFile)
+	if err != nil {
+		return fmt.Errorf("failed to create log file: %w", err)
+	}
+	defer file.Close()
+	fmt.Fprintf(file, "$ %s\n", gcCommand)
+
+	cmd := exec.Command("sh", "-c", gcCommand)
+	cmd.Stdout = file
+	cmd.Stderr = file
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w (log: %s)", err, logFile)
+	}
+	return nil
+}
+
+// directorySize returns the bytes stored under path
+func directorySize(path string) int64 {
+	return monitor.NewDiskWriteMonitor(path).GetCurrentStats().TotalBytes
+}
+
+// printDeleteSummary prints the timing, deleted images and reclaimed space
+func printDeleteSummary(m *DeletePhaseMetrics) {
+	logging.Printf("  │ Delete completed in %v (generate: %v | delete: %v",
+		m.WallTime.Round(time.Millisecond), m.GenerateTime.Round(time.Millisecond), m.DeleteTime.Round(time.Millisecond))
+	if m.GCTime > 0 {
+		logging.Printf(" | gc: %v", m.GCTime.Round(time.Millisecond))
+	}
+	logging.Printf(")\n")
+	types := make([]string, 0, len(m.ImagesByType))
+	for imageType, count := range m.ImagesByType {
+		types = append(types, fmt.Sprintf("%s: %d", imageType, count))
+	}
+	sort.Strings(types)
+	logging.Printf("  │ Images planned: %d %v\n", m.ImagesPlanned, types)
+	if m.StorageBefore > 0 {
+		logging.Printf("  │ Registry storage: %s -> %s (reclaimed %s)\n",
+			monitor.FormatBytesHuman(m.StorageBefore), monitor.FormatBytesHuman(m.StorageAfter), monitor.FormatBytesHuman(m.BytesReclaimed))
+	} else {
+		logging.Printf("  │ Registry storage: not measured (set --registry-storage-path)\n")
+	}
+	logging.Printf("  │ Log: %s\n", m.LogFile)
+	m.ResourceMetrics.PrintSummary()
+	m.DeleteMetrics.PrintSummary()
+}
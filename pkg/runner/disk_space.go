@@ -0,0 +1,178 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+const (
+	// diskSpaceMargin is added to the estimated footprint, as mirrors grow
+	// with every catalog update
+	diskSpaceMargin = 1.2
+	// diskEstimateRuns is how many earlier matching results files the
+	// footprint is estimated from
+	diskEstimateRuns = 5
+)
+
+// DiskSpaceCheck is the pre-flight check that the workspace and cache
+// filesystems can hold the mirror before the first iteration starts
+type DiskSpaceCheck struct {
+	Estimate string `json:"estimate,omitempty" yaml:"estimate,omitempty"` // Peak footprint of workspace, cache and OCI layout, e.g. 120Gi; from earlier runs when empty
+	Skip     bool   `json:"skip,omitempty" yaml:"skip,omitempty"`
+}
+
+// Validate checks the estimate
+func (c DiskSpaceCheck) Validate() error {
+	if c.Estimate == "" {
+		return nil
+	}
+	size, err := monitor.ParseByteSize(c.Estimate)
+	if err != nil {
+		return fmt.Errorf("invalid disk estimate: %w", err)
+	}
+	if size <= 0 {
+		return fmt.Errorf("disk estimate must be positive")
+	}
+	return nil
+}
+
+// filesystemSpace is the free space of one filesystem and the footprint
+// the run needs on it
+type filesystemSpace struct {
+	paths     []string
+	free      int64
+	footprint int64 // Of the paths now, reused by the run
+}
+
+// checkDiskSpace fails the run up front when the workspace, cache and OCI
+// layout filesystems have less free space than the estimated footprint of
+// the run, instead of oc-mirror failing hours into an iteration. Space the
+// run reuses, like the current cache, counts as available.
+func (tr *TestRunner) checkDiskSpace() error {
+	if tr.config.DiskSpace.Skip {
+		return nil
+	}
+	estimate, source := tr.diskEstimate()
+	if estimate == 0 {
+		logging.Printf("Disk Space: no estimate (set --disk-estimate or run once), check skipped\n")
+		return nil
+	}
+	needed := int64(float64(estimate) * diskSpaceMargin)
+	logging.Printf("Disk Space: %s needed (%s from %s, plus %.0f%%)\n",
+		monitor.FormatBytesHuman(needed), monitor.FormatBytesHuman(estimate), source, (diskSpaceMargin-1)*100)
+
+	filesystems, err := tr.footprintFilesystems()
+	if err != nil {
+		logging.Printf("Warning: Failed to check free disk space: %v\n", err)
+		return nil
+	}
+	var short []string
+	for _, fs := range filesystems {
+		available := fs.free + fs.footprint
+		status := "✅"
+		if available < needed {
+			status = "❌"
+			short = append(short, fmt.Sprintf("%s has %s available, %s needed",
+				strings.Join(fs.paths, ", "), monitor.FormatBytesHuman(available), monitor.FormatBytesHuman(needed)))
+		}
+		logging.Printf("  %s %s: %s free, %s reused\n", status, strings.Join(fs.paths, ", "),
+			monitor.FormatBytesHuman(fs.free), monitor.FormatBytesHuman(fs.footprint))
+	}
+	if len(short) > 0 {
+		return fmt.Errorf("insufficient disk space: %s; free space (e.g. --keep-last, --prune-workspaces) or pass --skip-disk-check",
+			strings.Join(short, "; "))
+	}
+	return nil
+}
+
+// diskEstimate returns the expected peak footprint of the run and where it
+// comes from: the configured estimate, or the largest disk usage of each
+// version in the newest earlier results of the same scenario or content
+func (tr *TestRunner) diskEstimate() (int64, string) {
+	if tr.config.DiskSpace.Estimate != "" {
+		size, _ := monitor.ParseByteSize(tr.config.DiskSpace.Estimate)
+		return size, "estimate"
+	}
+
+	runs, err := listResultsRuns(tr.config.GetResultsDir(), tr.resultsPath)
+	if err != nil {
+		return 0, ""
+	}
+	peaks := make(map[string]int64)
+	matched := 0
+	for i := len(runs) - 1; i >= 0 && matched < diskEstimateRuns; i-- {
+		data, err := os.ReadFile(runs[i].path)
+		if err != nil {
+			continue
+		}
+		file, err := ParseResultsFile(data)
+		if err != nil || !tr.sameWorkload(file) {
+			continue
+		}
+		matched++
+		for _, result := range file.Results {
+			peaks[result.Version] = max(peaks[result.Version], result.DiskUsageBytes)
+		}
+	}
+	// Versions of a comparison keep their workspaces side by side
+	var estimate int64
+	for _, peak := range peaks {
+		estimate += peak
+	}
+	if estimate == 0 {
+		return 0, ""
+	}
+	return estimate, fmt.Sprintf("%d earlier run(s)", matched)
+}
+
+// sameWorkload returns whether an earlier results file mirrored what this
+// run mirrors: the same scenario file, or else the same content
+func (tr *TestRunner) sameWorkload(file *ResultsFile) bool {
+	if tr.config.ScenarioHash != "" {
+		return file.ScenarioHash == tr.config.ScenarioHash
+	}
+	for _, result := range file.Results {
+		if result.ContentScenario != "" && result.ContentScenario != tr.config.GetContentScenario() {
+			return false
+		}
+	}
+	return len(file.Results) > 0
+}
+
+// footprintFilesystems groups the workspace, cache and OCI layout paths by
+// filesystem, with its free space and what the paths occupy now
+func (tr *TestRunner) footprintFilesystems() ([]filesystemSpace, error) {
+	paths := []string{tr.config.GetWorkspaceDir(), tr.config.GetCacheDir()}
+	if tr.config.IsOCITarget() {
+		paths = append(paths, tr.config.OCILayoutPath())
+	}
+
+	byDevice := make(map[uint64]*filesystemSpace)
+	for _, path := range paths {
+		device, free, err := filesystemOf(path)
+		if err != nil {
+			return nil, err
+		}
+		fs, ok := byDevice[device]
+		if !ok {
+			fs = &filesystemSpace{free: free}
+			byDevice[device] = fs
+		}
+		fs.paths = append(fs.paths, path)
+		if size, err := dirSize(path); err == nil {
+			fs.footprint += size
+		}
+	}
+
+	filesystems := make([]filesystemSpace, 0, len(byDevice))
+	for _, fs := range byDevice {
+		filesystems = append(filesystems, *fs)
+	}
+	sort.Slice(filesystems, func(i, j int) bool { return filesystems[i].paths[0] < filesystems[j].paths[0] })
+	return filesystems, nil
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package runner
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+)
+
+// filesystemOf returns the device and free bytes of the filesystem holding
+// path. Paths that do not exist yet are resolved through their deepest
+// existing parent.
+func filesystemOf(path string) (uint64, int64, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var stat syscall.Stat_t
+	candidate := abs
+	for ; ; candidate = filepath.Dir(candidate) {
+		if err = syscall.Stat(candidate, &stat); err == nil {
+			break
+		}
+		if candidate == filepath.Dir(candidate) {
+			return 0, 0, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+	}
+
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(candidate, &fs); err != nil {
+		return 0, 0, fmt.Errorf("failed to read free space of %s: %w", path, err)
+	}
+	return uint64(stat.Dev), int64(fs.Bavail) * int64(fs.Bsize), nil
+}
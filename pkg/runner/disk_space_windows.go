@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// filesystemOf returns the volume and free bytes of the filesystem holding
+// path. Paths that do not exist yet are resolved through their deepest
+// existing parent; volumes are told apart by their name.
+func filesystemOf(path string) (uint64, int64, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	candidate := abs
+	for ; ; candidate = filepath.Dir(candidate) {
+		if _, err = os.Stat(candidate); err == nil {
+			break
+		}
+		if candidate == filepath.Dir(candidate) {
+			return 0, 0, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+	}
+
+	name, err := syscall.UTF16PtrFromString(candidate)
+	if err != nil {
+		return 0, 0, err
+	}
+	var free uint64
+	if ok, _, callErr := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&free)), 0, 0); ok == 0 {
+		return 0, 0, fmt.Errorf("failed to read free space of %s: %w", path, callErr)
+	}
+
+	volume := fnv.New64a()
+	volume.Write([]byte(strings.ToUpper(filepath.VolumeName(candidate))))
+	return volume.Sum64(), int64(free), nil
+}
@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// measureDiskUsage returns the bytes the workspace, the oc-mirror cache and the
+// OCI layout of version occupy after the iteration. The workspace only grows
+// during an iteration, so this is its peak footprint.
+func (tr *TestRunner) measureDiskUsage(version string) int64 {
+	var total int64
+	for _, path := range tr.diskIOPaths(version, true) {
+		size, err := dirSize(path)
+		if err != nil && !os.IsNotExist(err) {
+			logging.Printf("  │ Warning: Failed to measure disk usage of %s: %v\n", path, err)
+		}
+		total += size
+	}
+	logging.Printf("  │ Disk Usage: %s (workspace, cache and layout)\n", monitor.FormatBytesHuman(total))
+	return total
+}
+
+// dirSize returns the total size of the regular files below dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil // Removed while walking
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// diskIOPaths returns the directories whose backing devices are sampled during a phase
+func (tr *TestRunner) diskIOPaths(version string, upload bool) []string {
+	paths := []string{tr.mirrorDir(version)}
+	if version == "v2" {
+		paths = append(paths, tr.cacheDir(version))
+	}
+	if upload && tr.config.IsOCITarget() {
+		paths = append(paths, tr.config.OCILayoutPath())
+	}
+	if upload && tr.config.AirGap.Enabled {
+		// The disconnected side: transferred archive, its working-dir and cache
+		paths = append(paths, airGapDir(version))
+		if version == "v2" {
+			paths = append(paths, airGapCacheDir)
+		}
+	}
+	return paths
+}
+
+// startDiskIOMonitor starts device-level I/O sampling for a phase; nil if /proc/diskstats is unavailable
+func (tr *TestRunner) startDiskIOMonitor(version string, upload bool) *monitor.DiskIOMonitor {
+	diskIOMonitor := monitor.NewDiskIOMonitor(tr.diskIOPaths(version, upload)...)
+	tr.observe(diskIOMonitor, sampleSourceDiskIO)
+	if err := diskIOMonitor.Start(); err != nil {
+		logging.Printf("  │ Warning: Failed to start disk I/O monitoring: %v\n", err)
+		return nil
+	}
+	return diskIOMonitor
+}
+
+// stopDiskIOMonitor stops device-level I/O sampling and returns the metrics, if any
+func stopDiskIOMonitor(diskIOMonitor *monitor.DiskIOMonitor) *monitor.DiskIOMetrics {
+	if diskIOMonitor == nil {
+		return nil
+	}
+	metrics := diskIOMonitor.Stop()
+	return &metrics
+}
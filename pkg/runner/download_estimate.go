@@ -0,0 +1,155 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+	"github.com/telco-core/ngc-495/pkg/registry"
+)
+
+// downloadEstimate is the size the mirror directory of a clean download is
+// expected to reach, against which its remaining time is estimated
+type downloadEstimate struct {
+	bytes  int64
+	source string // How the size is known, for the console
+}
+
+// estimateDownload returns the expected size of a clean download: what the
+// first clean download of the same content measured, or else the size of the
+// operator images rendered from the catalogs of the content. It returns nil
+// for incremental runs, whose size depends on what changed.
+func (tr *TestRunner) estimateDownload(version string, isCleanRun bool, expected *expectedImages) *downloadEstimate {
+	if !isCleanRun {
+		return nil
+	}
+	if size, ok := tr.measuredSizes[tr.downloadKey(version)]; ok {
+		return &downloadEstimate{bytes: size, source: "measured by the first clean download"}
+	}
+	if expected == nil || len(expected.images) == 0 {
+		return nil
+	}
+	size, sized := sumImageSizes(expected.images)
+	if size == 0 {
+		return nil
+	}
+	source := fmt.Sprintf("%d operator image(s) sized from their catalogs", sized)
+	if unsized := len(expected.images) - sized; unsized > 0 {
+		source += fmt.Sprintf(", %d could not be sized", unsized)
+	}
+	return &downloadEstimate{bytes: size, source: source}
+}
+
+// recordDownloadSize keeps the size of the mirror directory after the first
+// clean download of a content, the estimate of the following ones
+func (tr *TestRunner) recordDownloadSize(version string, isCleanRun bool) {
+	key := tr.downloadKey(version)
+	if _, ok := tr.measuredSizes[key]; ok || !isCleanRun {
+		return
+	}
+	size, err := dirSize(tr.mirrorDir(version))
+	if err != nil || size == 0 {
+		return
+	}
+	if tr.measuredSizes == nil {
+		tr.measuredSizes = make(map[string]int64)
+	}
+	tr.measuredSizes[key] = size
+}
+
+// downloadKey identifies the content a download mirrors: the oc-mirror
+// version, the content revision of an update run and the stage
+func (tr *TestRunner) downloadKey(version string) string {
+	key := version + "|" + tr.contentRevision
+	if tr.stage != nil {
+		key += "|" + tr.stage.Name
+	}
+	return key
+}
+
+// print shows the expected size at the start of the download phase
+func (e *downloadEstimate) print() {
+	if e != nil {
+		logging.Printf("  │ Expected Download: %s (%s)\n", monitor.FormatBytesHuman(e.bytes), e.source)
+	}
+}
+
+// sumImageSizes reads the manifests of images from their source registries
+// and sums their blobs, counting blobs shared between images once. It also
+// returns the number of images whose manifests could be read.
+func sumImageSizes(images []string) (int64, int) {
+	var mu sync.Mutex
+	clients := make(map[string]*registry.Client)
+	clientFor := func(host string) (*registry.Client, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if client, ok := clients[host]; ok {
+			return client, nil
+		}
+		client, err := registry.NewClient(host, "", false)
+		if err != nil {
+			return nil, err
+		}
+		clients[host] = client
+		return client, nil
+	}
+
+	blobs := make([][]registry.Blob, len(images))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < expectedContentConcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				blobs[i] = imageBlobs(clientFor, images[i])
+			}
+		}()
+	}
+	for i := range images {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var size int64
+	sized := 0
+	counted := make(map[string]bool)
+	for _, image := range blobs {
+		if image == nil {
+			continue
+		}
+		sized++
+		for _, blob := range image {
+			if !counted[blob.Digest] {
+				counted[blob.Digest] = true
+				size += blob.Size
+			}
+		}
+	}
+	return size, sized
+}
+
+// imageBlobs lists the blobs of an image reference, nil when its manifests
+// cannot be read
+func imageBlobs(clientFor func(string) (*registry.Client, error), image string) []registry.Blob {
+	host, img, err := registry.ParseImageReference(image)
+	if err != nil {
+		return nil
+	}
+	client, err := clientFor(host)
+	if err != nil {
+		return nil
+	}
+	reference := img.Digest
+	if reference == "" {
+		reference = img.Tag
+	}
+	blobs, err := client.ImageBlobs(context.Background(), img.Repository, reference)
+	if err != nil {
+		return nil
+	}
+	return blobs
+}
@@ -0,0 +1,229 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/events"
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// Record types of the event stream
+const (
+	streamPhaseStart       = "phase_start"
+	streamSample           = "sample"
+	streamPhaseEnd         = "phase_end"
+	streamIterationSummary = "iteration_summary"
+	streamRunSummary       = "run_summary"
+)
+
+// eventStreamBuffer is how many events the stream may fall behind the bus
+// before it misses some
+const eventStreamBuffer = 4096
+
+// PhaseEnd is the data of a phase_end record
+type PhaseEnd struct {
+	events.PhaseChange
+	Duration time.Duration `json:"duration_seconds"`
+}
+
+// IterationSummary is the data of an iteration event: the outcome of one
+// iteration, without the samples and detailed metrics of the results file
+type IterationSummary struct {
+	Iteration       int           `json:"iteration"`
+	Version         string        `json:"version"`
+	Registry        string        `json:"registry,omitempty"`
+	Kind            string        `json:"kind"` // clean, cached or update
+	Failed          bool          `json:"failed,omitempty"`
+	Passed          bool          `json:"passed"`
+	Error           string        `json:"error,omitempty"`
+	FailureReasons  []string      `json:"failure_reasons,omitempty"`
+	DownloadTime    time.Duration `json:"download_time_seconds"`
+	UploadTime      time.Duration `json:"upload_time_seconds"`
+	BytesDownloaded int64         `json:"bytes_downloaded"`
+	BytesUploaded   int64         `json:"bytes_uploaded"`
+}
+
+// RunSummary is the data of the run_summary record, the last of the stream
+type RunSummary struct {
+	Status      string        `json:"status"` // completed or failed
+	Error       string        `json:"error,omitempty"`
+	ResultsFile string        `json:"results_file"`
+	Duration    time.Duration `json:"duration_seconds"`
+	Iterations  int           `json:"iterations"`
+	Failed      int           `json:"failed"`      // Iterations that failed after retries
+	GateFailed  int           `json:"gate_failed"` // Completed iterations that missed a gate
+}
+
+// eventStream writes the events of the run to a file as NDJSON, one record
+// per line, so other tools can tail the run. Phase changes become a
+// phase_end record for the phase left and a phase_start record.
+type eventStream struct {
+	file        *os.File
+	writer      *bufio.Writer
+	events      <-chan events.Event
+	unsubscribe func()
+	done        chan struct{}
+
+	phase      *events.PhaseChange // Open phase, nil between iterations
+	phaseStart time.Time
+	err        error // First write error
+}
+
+// startEventStream starts writing the run events to Config.EventsFile, if set
+func (tr *TestRunner) startEventStream() {
+	if tr.config.EventsFile == "" {
+		return
+	}
+	if dir := filepath.Dir(tr.config.EventsFile); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			logging.Printf("Warning: Failed to create event stream directory: %v\n", err)
+			return
+		}
+	}
+	file, err := os.Create(tr.config.EventsFile)
+	if err != nil {
+		logging.Printf("Warning: Failed to create event stream: %v\n", err)
+		return
+	}
+	s := &eventStream{
+		file:   file,
+		writer: bufio.NewWriter(file),
+		done:   make(chan struct{}),
+	}
+	s.events, s.unsubscribe = tr.events.Subscribe(eventStreamBuffer)
+	go s.forward()
+	tr.eventStream = s
+	logging.Printf("Event Stream: %s\n", tr.config.EventsFile)
+}
+
+// stopEventStream closes the open phase, writes the run summary and closes the file
+func (tr *TestRunner) stopEventStream(startedAt time.Time, runErr error) {
+	s := tr.eventStream
+	if s == nil {
+		return
+	}
+	tr.eventStream = nil
+	s.unsubscribe()
+	<-s.done
+
+	now := time.Now()
+	s.endPhase(now)
+	summary := RunSummary{
+		Status:      "completed",
+		ResultsFile: tr.resultsPath,
+		Duration:    now.Sub(startedAt),
+		Iterations:  len(tr.results),
+	}
+	if runErr != nil {
+		summary.Status = "failed"
+		summary.Error = runErr.Error()
+	}
+	for _, r := range tr.results {
+		if r.Failed {
+			summary.Failed++
+		} else if !r.Passed {
+			summary.GateFailed++
+		}
+	}
+	s.write(streamRunSummary, events.Event{Time: now, Data: summary})
+	s.flush()
+	if err := s.file.Close(); err != nil && s.err == nil {
+		s.err = err
+	}
+	if s.err != nil {
+		logging.Printf("Warning: Failed to write event stream %s: %v\n", tr.config.EventsFile, s.err)
+	}
+}
+
+// SetResultHandler sets a function called with each finished iteration, on
+// the goroutine of the run, e.g. to send it to metric sinks
+func (tr *TestRunner) SetResultHandler(handler func(TestResult)) {
+	tr.onResult = handler
+}
+
+// publishIteration publishes the outcome of a finished iteration and passes
+// it to the result handler
+func (tr *TestRunner) publishIteration(result TestResult) {
+	if tr.onResult != nil {
+		tr.onResult(result)
+	}
+	logging.Event("iteration completed", "iteration", result.Iteration, "version", result.Version,
+		"registry", result.Registry, "kind", strings.ToLower(runKind(result.IsCleanRun, result.IsUpdateRun)),
+		"failed", result.Failed, "passed", result.Passed, "error", result.Error,
+		"download_seconds", result.DownloadPhase.WallTime.Seconds(), "upload_seconds", result.UploadPhase.WallTime.Seconds(),
+		"total_seconds", result.GetTotalTime().Seconds(), "speed_mbs", result.GetAverageSpeedMBs())
+	tr.events.Publish(events.Event{Type: events.TypeIteration, Data: IterationSummary{
+		Iteration:       result.Iteration,
+		Version:         result.Version,
+		Registry:        result.Registry,
+		Kind:            strings.ToLower(runKind(result.IsCleanRun, result.IsUpdateRun)),
+		Failed:          result.Failed,
+		Passed:          result.Passed,
+		Error:           result.Error,
+		FailureReasons:  result.FailureReasons,
+		DownloadTime:    result.DownloadPhase.WallTime,
+		UploadTime:      result.UploadPhase.WallTime,
+		BytesDownloaded: result.DownloadPhase.DownloadMetrics.TotalBytesDownloaded,
+		BytesUploaded:   result.UploadPhase.BytesUploaded,
+	}})
+}
+
+// forward writes the events until the subscription ends, flushing whenever
+// it caught up so readers see each record promptly
+func (s *eventStream) forward() {
+	defer close(s.done)
+	for event := range s.events {
+		switch event.Type {
+		case events.TypePhase:
+			s.endPhase(event.Time)
+			if phase, ok := event.Data.(events.PhaseChange); ok {
+				s.phase, s.phaseStart = &phase, event.Time
+			}
+			s.write(streamPhaseStart, event)
+		case events.TypeSample:
+			s.write(streamSample, event)
+		case events.TypeIteration:
+			s.endPhase(event.Time)
+			s.write(streamIterationSummary, event)
+		}
+		if len(s.events) == 0 {
+			s.flush()
+		}
+	}
+}
+
+// endPhase writes the phase_end record of the open phase
+func (s *eventStream) endPhase(at time.Time) {
+	if s.phase == nil {
+		return
+	}
+	s.write(streamPhaseEnd, events.Event{Time: at, Data: PhaseEnd{PhaseChange: *s.phase, Duration: at.Sub(s.phaseStart)}})
+	s.phase = nil
+}
+
+// write appends one record, keeping the source and data of the event
+func (s *eventStream) write(recordType string, event events.Event) {
+	if s.err != nil {
+		return
+	}
+	event.Type = recordType
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if _, err := s.writer.Write(append(data, '\n')); err != nil {
+		s.err = err
+	}
+}
+
+// flush writes the buffered records to the file
+func (s *eventStream) flush() {
+	if s.err == nil {
+		s.err = s.writer.Flush()
+	}
+}
@@ -0,0 +1,42 @@
+package runner
+
+import (
+	"log/slog"
+
+	"github.com/telco-core/ngc-495/pkg/events"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// Sources of the samples published on the event bus
+const (
+	sampleSourceNetwork        = "network"         // Interface bandwidth of the current phase
+	sampleSourceIteration      = "iteration"       // CPU and memory of the test tool over the iteration
+	sampleSourceResource       = "resource"        // CPU and memory of the oc-mirror process
+	sampleSourceDownload       = "download"        // Mirror directory growth
+	sampleSourceLayoutWrite    = "layout_write"    // OCI layout writes of the upload phase
+	sampleSourceDiskIO         = "disk_io"         // Per-device I/O rates
+	sampleSourceProcessNetwork = "process_network" // TCP traffic of the oc-mirror process tree
+	sampleSourceRegistry       = "registry"        // Bytes sent to the registry
+	sampleSourceRegistryServer = "registry_server" // Request, error and storage rates scraped from the registry
+)
+
+// Events returns the bus the run publishes monitor samples and phase changes on
+func (tr *TestRunner) Events() *events.Bus {
+	return tr.events
+}
+
+// observe publishes the samples of a monitor on the event bus, traces the
+// monitor and applies adaptive polling; call it before the monitor starts
+func (tr *TestRunner) observe(observer monitor.SampleObserver, source string) {
+	tr.adaptPolling(observer, source)
+	span := tr.traceMonitor(source)
+	verbose := logging.Enabled(slog.LevelDebug)
+	observer.SetSampleHandler(func(sample interface{}) {
+		span.sample()
+		tr.events.Publish(events.Event{Type: events.TypeSample, Source: source, Data: sample})
+		if verbose {
+			logging.Debug("sample", "source", source, "data", sample)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/telco-core/ngc-495/internal/config"
+	"github.com/telco-core/ngc-495/pkg/catalog"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/registry"
+)
+
+// expectedContentConcurrency is the number of images checked in parallel
+const expectedContentConcurrency = 8
+
+// maxListedMissingImages limits the missing images printed per iteration; the
+// results file lists all of them
+const maxListedMissingImages = 10
+
+// ContentValidation compares the images the operator packages of the content
+// should mirror, rendered from their catalogs with opm, with the images the
+// registry holds after the upload
+type ContentValidation struct {
+	Catalogs []string `json:"catalogs"`
+	Bundles  int      `json:"bundles"`         // Bundles selected by the packages and channels
+	Expected int      `json:"expected_images"` // Bundle and related images
+	Found    int      `json:"found_images"`
+	Missing  []string `json:"missing_images,omitempty"`
+	Errors   []string `json:"errors,omitempty"` // Packages that did not resolve and images that could not be checked
+}
+
+// expectedImages is the image set of a content, computed before mirroring it
+type expectedImages struct {
+	catalogs []string
+	bundles  int
+	images   []string
+	errors   []string
+	packages []packageImages
+}
+
+// packageImages is the image set of one operator package of the content
+type packageImages struct {
+	catalog string
+	name    string
+	bundles int
+	images  []string
+}
+
+// expectedContent renders the catalogs of the content the iteration mirrors
+// with opm and selects the bundles of its packages, as oc-mirror does. It
+// returns nil for content without operators. Rendered catalogs are kept for
+// the following iterations.
+func (tr *TestRunner) expectedContent() *expectedImages {
+	content := tr.mirroredContent()
+	if content == nil {
+		content = config.DefaultContent()
+	}
+	catalogs, err := content.OperatorCatalogs()
+	if err != nil {
+		logging.Printf("  │ Warning: Failed to compute the expected content: %v\n", err)
+		return nil
+	}
+	if len(catalogs) == 0 {
+		logging.Printf("  │ No operator catalogs in the content, nothing to validate\n")
+		return nil
+	}
+
+	expected := &expectedImages{}
+	seen := make(map[string]bool)
+	for _, operatorCatalog := range catalogs {
+		expected.catalogs = append(expected.catalogs, operatorCatalog.Catalog)
+		idx, err := tr.renderCatalog(operatorCatalog.Catalog)
+		if err != nil {
+			expected.errors = append(expected.errors, err.Error())
+			continue
+		}
+		for _, pkg := range operatorCatalog.Packages {
+			channels := make([]catalog.ChannelSelection, len(pkg.Channels))
+			for i, channel := range pkg.Channels {
+				channels[i] = catalog.ChannelSelection{Name: channel.Name, MinVersion: channel.MinVersion, MaxVersion: channel.MaxVersion}
+			}
+			bundles, err := idx.SelectBundles(pkg.Name, pkg.DefaultChannel, channels)
+			if err != nil {
+				expected.errors = append(expected.errors, err.Error())
+				continue
+			}
+			expected.bundles += len(bundles)
+			pkgImages := packageImages{catalog: operatorCatalog.Catalog, name: pkg.Name, bundles: len(bundles)}
+			pkgSeen := make(map[string]bool)
+			for _, bundle := range bundles {
+				for _, image := range bundle.Images() {
+					if !pkgSeen[image] {
+						pkgSeen[image] = true
+						pkgImages.images = append(pkgImages.images, image)
+					}
+					if !seen[image] {
+						seen[image] = true
+						expected.images = append(expected.images, image)
+					}
+				}
+			}
+			expected.packages = append(expected.packages, pkgImages)
+		}
+	}
+	sort.Strings(expected.images)
+
+	logging.Printf("  │ Expected Content: %d bundle(s), %d image(s) from %d catalog(s)\n",
+		expected.bundles, len(expected.images), len(expected.catalogs))
+	for _, e := range expected.errors {
+		logging.Printf("  │ Warning: %s\n", e)
+	}
+	return expected
+}
+
+// renderCatalog renders a catalog image with opm once per run
+func (tr *TestRunner) renderCatalog(image string) (*catalog.Index, error) {
+	if idx, ok := tr.catalogIndexes[image]; ok {
+		return idx, nil
+	}
+	logging.Printf("  │ Rendering %s with opm...\n", image)
+	idx, err := catalog.Render(image)
+	if err != nil {
+		return nil, err
+	}
+	if tr.catalogIndexes == nil {
+		tr.catalogIndexes = make(map[string]*catalog.Index)
+	}
+	tr.catalogIndexes[image] = idx
+	return idx, nil
+}
+
+// validateContent checks that every expected image is in the registry after
+// the upload, under the repository path of its source
+func (tr *TestRunner) validateContent(version string, expected *expectedImages) *ContentValidation {
+	if expected == nil || !tr.config.ValidateContent {
+		return nil
+	}
+	validation := &ContentValidation{
+		Catalogs: expected.catalogs,
+		Bundles:  expected.bundles,
+		Expected: len(expected.images),
+		Errors:   expected.errors,
+	}
+	client, err := registry.NewClient(tr.targetRegistry(), "", tr.config.SkipTLS)
+	if err != nil {
+		logging.Printf("  │ Warning: Failed to validate the expected content: %v\n", err)
+		return nil
+	}
+
+	prefix := tr.catalogPrefix(version)
+	found := make([]bool, len(expected.images))
+	failures := make([]string, len(expected.images))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < expectedContentConcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				found[i], failures[i] = checkExpectedImage(client, prefix, expected.images[i])
+			}
+		}()
+	}
+	for i := range expected.images {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, image := range expected.images {
+		switch {
+		case found[i]:
+			validation.Found++
+		case failures[i] != "":
+			validation.Errors = append(validation.Errors, failures[i])
+		default:
+			validation.Missing = append(validation.Missing, image)
+		}
+	}
+	validation.PrintSummary()
+	return validation
+}
+
+// checkExpectedImage looks up the manifest of an image in the mirror
+func checkExpectedImage(client *registry.Client, prefix, image string) (bool, string) {
+	_, img, err := registry.ParseImageReference(image)
+	if err != nil {
+		return false, err.Error()
+	}
+	reference := img.Digest
+	if reference == "" {
+		reference = img.Tag
+	}
+	_, status, err := client.HeadManifest(context.Background(), path.Join(prefix, img.Repository), reference)
+	if err != nil {
+		return false, fmt.Sprintf("%s: %v", image, err)
+	}
+	return status == http.StatusOK, ""
+}
+
+// PrintSummary prints how much of the expected content was found and the missing images
+func (v *ContentValidation) PrintSummary() {
+	logging.Printf("  │ Expected Content: %d of %d image(s) found in the registry\n", v.Found, v.Expected)
+	if unchecked := v.Expected - v.Found - len(v.Missing); unchecked > 0 {
+		logging.Printf("  │ Warning: %d image(s) could not be checked\n", unchecked)
+	}
+	for i, image := range v.Missing {
+		if i == maxListedMissingImages {
+			logging.Printf("  │   ... and %d more\n", len(v.Missing)-i)
+			break
+		}
+		logging.Printf("  │   ❌ missing %s\n", image)
+	}
+}
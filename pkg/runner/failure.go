@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/netdiag"
+	"github.com/telco-core/ngc-495/pkg/registry"
+)
+
+// Failure classes of a failed phase attempt
+const (
+	FailureConnection = "connection" // The registry or a source could not be reached
+	FailureTLS        = "tls"        // Certificate or handshake errors
+	FailureAuth       = "auth"       // Missing or rejected credentials
+	FailureOOM        = "oom"        // oc-mirror was killed by the OOM killer
+	FailureDisk       = "disk"       // Local storage ran out
+	FailureTimeout    = "timeout"    // oc-mirror was killed when the phase timeout passed
+	FailureOther      = "other"
+)
+
+// failurePatterns classify an attempt by its error and the oc-mirror output tail
+// included in it, checked in order
+var failurePatterns = []struct {
+	class   string
+	pattern *regexp.Regexp
+}{
+	{FailureDisk, regexp.MustCompile(`(?i)no space left on device|disk quota exceeded`)},
+	{FailureTLS, regexp.MustCompile(`(?i)x509:|tls: |certificate (signed by unknown|has expired|is not valid)|first record does not look like a TLS handshake`)},
+	{FailureAuth, regexp.MustCompile(`(?i)unauthorized|authentication required|denied: requested access|invalid username/password`)},
+	{FailureConnection, regexp.MustCompile(`(?i)connection refused|connection reset|no route to host|network is unreachable|i/o timeout|no such host|dial tcp|TLS handshake timeout|server misbehaving|unexpected EOF`)},
+}
+
+// classifyFailure returns the failure class of an attempt
+func classifyFailure(err error, oomKilled bool) string {
+	if oomKilled {
+		return FailureOOM
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return FailureTimeout
+	}
+	for _, p := range failurePatterns {
+		if p.pattern.MatchString(err.Error()) {
+			return p.class
+		}
+	}
+	return FailureOther
+}
+
+// diagnoseFailure runs connectivity diagnostics against the target registry
+// when an upload attempt failed to reach it. It returns nil for other failures
+// and for OCI layout targets.
+func (tr *TestRunner) diagnoseFailure(phase, class string) *netdiag.Report {
+	if phase != "upload" || tr.config.IsOCITarget() || (class != FailureConnection && class != FailureTLS) {
+		return nil
+	}
+	hostPort, scheme := registry.ParseRegistryHost(tr.targetRegistry())
+	if _, _, err := net.SplitHostPort(hostPort); err != nil {
+		port := "443"
+		if scheme == "http" {
+			port = "80"
+		}
+		hostPort = net.JoinHostPort(hostPort, port)
+	}
+	logging.Printf("  │ Upload failed with a %s error, diagnosing connectivity to %s...\n", class, hostPort)
+	report := netdiag.Diagnose(context.Background(), hostPort, tr.config.SkipTLS)
+	report.PrintSummary()
+	return report
+}
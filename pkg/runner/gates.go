@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// GateConfig holds the pass/fail gates every iteration is checked against as
+// it completes. A run with a failed gate exits non-zero, so CI performance
+// pipelines can use it as a check.
+type GateConfig struct {
+	MaxDownloadTime time.Duration `json:"max_download_time,omitempty" yaml:"maxDownloadTime,omitempty"`
+	MinAvgSpeedMBs  float64       `json:"min_avg_speed_mbs,omitempty" yaml:"minAvgSpeedMBs,omitempty"` // Average download speed
+	MaxMemoryMB     float64       `json:"max_memory_mb,omitempty" yaml:"maxMemoryMB,omitempty"`        // Peak oc-mirror RSS of either phase
+	MaxErrors       *int          `json:"max_errors,omitempty" yaml:"maxErrors,omitempty"`             // oc-mirror errors of both phases; 0 allows none
+}
+
+// Enabled reports whether any gate is set
+func (c GateConfig) Enabled() bool {
+	return c.MaxDownloadTime > 0 || c.MinAvgSpeedMBs > 0 || c.MaxMemoryMB > 0 || c.MaxErrors != nil
+}
+
+// Validate checks that no gate is negative
+func (c GateConfig) Validate() error {
+	if c.MaxDownloadTime < 0 || c.MinAvgSpeedMBs < 0 || c.MaxMemoryMB < 0 {
+		return fmt.Errorf("gates must not be negative")
+	}
+	if c.MaxErrors != nil && *c.MaxErrors < 0 {
+		return fmt.Errorf("max errors must not be negative")
+	}
+	return nil
+}
+
+// String returns a human-readable description of the gates
+func (c GateConfig) String() string {
+	var gates []string
+	if c.MaxDownloadTime > 0 {
+		gates = append(gates, "download <= "+c.MaxDownloadTime.String())
+	}
+	if c.MinAvgSpeedMBs > 0 {
+		gates = append(gates, fmt.Sprintf("avg speed >= %.1f MB/s", c.MinAvgSpeedMBs))
+	}
+	if c.MaxMemoryMB > 0 {
+		gates = append(gates, fmt.Sprintf("memory <= %.0f MB", c.MaxMemoryMB))
+	}
+	if c.MaxErrors != nil {
+		gates = append(gates, fmt.Sprintf("errors <= %d", *c.MaxErrors))
+	}
+	return strings.Join(gates, ", ")
+}
+
+// Evaluate returns the gates a completed iteration did not meet
+func (c GateConfig) Evaluate(result *TestResult) []string {
+	var reasons []string
+	if c.MaxDownloadTime > 0 && result.DownloadPhase.WallTime > c.MaxDownloadTime {
+		reasons = append(reasons, fmt.Sprintf("download time %v exceeds %v", result.DownloadPhase.WallTime.Round(time.Second), c.MaxDownloadTime))
+	}
+	if speed := result.DownloadPhase.DownloadMetrics.AverageSpeedMBs; c.MinAvgSpeedMBs > 0 && speed < c.MinAvgSpeedMBs {
+		reasons = append(reasons, fmt.Sprintf("average download speed %.1f MB/s below %.1f MB/s", speed, c.MinAvgSpeedMBs))
+	}
+	if memory := max(result.DownloadPhase.ResourceMetrics.MemoryPeakMB, result.UploadPhase.ResourceMetrics.MemoryPeakMB); c.MaxMemoryMB > 0 && memory > c.MaxMemoryMB {
+		reasons = append(reasons, fmt.Sprintf("peak memory %.0f MB exceeds %.0f MB", memory, c.MaxMemoryMB))
+	}
+	if errs := result.DownloadPhase.ExtendedMetrics.ErrorCount + result.UploadPhase.ExtendedMetrics.ErrorCount; c.MaxErrors != nil && errs > *c.MaxErrors {
+		reasons = append(reasons, fmt.Sprintf("%d oc-mirror error(s) exceed %d", errs, *c.MaxErrors))
+	}
+	return reasons
+}
+
+// evaluateGates records whether an iteration passed. A failed iteration never
+// passes; its gates are not evaluated since its metrics are incomplete.
+func (tr *TestRunner) evaluateGates(result *TestResult) {
+	result.Passed = !result.Failed
+	if result.Failed || !tr.config.Gates.Enabled() {
+		return
+	}
+	result.FailureReasons = tr.config.Gates.Evaluate(result)
+	if len(result.FailureReasons) == 0 {
+		logging.Printf("\n  ✅ Gates passed (iteration %d, %s)\n", result.Iteration, result.Version)
+		return
+	}
+	result.Passed = false
+	logging.Printf("\n  ❌ Gates failed (iteration %d, %s):\n", result.Iteration, result.Version)
+	for _, reason := range result.FailureReasons {
+		logging.Printf("    %s\n", reason)
+	}
+}
+
+// gateFailuresError summarizes the completed iterations that failed a gate,
+// or returns nil if all passed
+func gateFailuresError(results []TestResult) error {
+	failed := 0
+	for _, r := range results {
+		if !r.Failed && !r.Passed {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d iteration(s) failed the gates (see failure_reasons in the results file)", failed, len(results))
+}
@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"github.com/telco-core/ngc-495/pkg/events"
+	"github.com/telco-core/ngc-495/pkg/heartbeat"
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// startHeartbeat starts liveness reporting if a heartbeat file or URL is configured
+func (tr *TestRunner) startHeartbeat() {
+	if tr.config.HeartbeatFile == "" && tr.config.HeartbeatURL == "" {
+		return
+	}
+
+	hb := heartbeat.NewHeartbeat(tr.config.HeartbeatFile, tr.config.HeartbeatInterval)
+	hb.SetPingURL(tr.config.HeartbeatURL)
+	hb.SetTotalIterations(tr.config.GetEffectiveIterations())
+	hb.SetProgressFunc(func() int64 {
+		if tr.registryMonitor != nil && tr.registryMonitor.IsMonitoring() {
+			return tr.registryMonitor.GetCurrentMetrics().TotalBytesUploaded
+		}
+		return 0
+	})
+
+	if err := hb.Start(); err != nil {
+		logging.Printf("Warning: Failed to start heartbeat: %v\n", err)
+		return
+	}
+	tr.heartbeat = hb
+
+	if tr.config.HeartbeatFile != "" {
+		logging.Printf("Heartbeat: writing %s every %v\n", tr.config.HeartbeatFile, hb.GetInterval())
+	}
+	if tr.config.HeartbeatURL != "" {
+		logging.Printf("Heartbeat: pinging %s every %v\n", tr.config.HeartbeatURL, hb.GetInterval())
+	}
+}
+
+// stopHeartbeat writes the final heartbeat with the run outcome
+func (tr *TestRunner) stopHeartbeat(runErr error) {
+	if tr.heartbeat == nil {
+		return
+	}
+	if runErr != nil {
+		tr.heartbeat.Stop("failed")
+	} else {
+		tr.heartbeat.Stop("completed")
+	}
+}
+
+// setPhase reports the current phase to the heartbeat, if enabled, on the
+// event bus, to plugins and as a span
+func (tr *TestRunner) setPhase(phase, version string, iteration int) {
+	tr.tracePhase(phase, version, iteration)
+	tr.phase = events.PhaseChange{Phase: phase, Version: version, Iteration: iteration}
+	tr.events.Publish(events.Event{Type: events.TypePhase, Data: tr.phase})
+	logging.Event("phase started", "phase", phase, "version", version, "iteration", iteration)
+	if tr.heartbeat != nil {
+		tr.heartbeat.SetPhase(phase, version, iteration)
+	}
+}
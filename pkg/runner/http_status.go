@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// startAccessLogMonitor starts reading the registry access log for the
+// upload phase, when one is configured
+func (tr *TestRunner) startAccessLogMonitor() *monitor.AccessLogMonitor {
+	if tr.config.RegistryAccessLog == "" || tr.config.IsOCITarget() {
+		return nil
+	}
+	accessLog := monitor.NewAccessLogMonitor(tr.config.RegistryAccessLog)
+	if err := accessLog.Start(); err != nil {
+		logging.Printf("  │ Warning: Failed to start access log monitoring: %v\n", err)
+		return nil
+	}
+	return accessLog
+}
+
+// uploadHTTPStatus returns the distribution of the registry response status
+// codes of the upload phase: from the access log when one is configured, which
+// sees every response, otherwise from the oc-mirror output
+func (tr *TestRunner) uploadHTTPStatus(output *command.CommandOutput, accessLog *monitor.AccessLogMonitor, metrics *PhaseMetrics) *monitor.HTTPStatusMetrics {
+	bucket := retryBucketFor(metrics.WallTime)
+	if accessLog != nil {
+		tally, err := accessLog.Stop()
+		if err != nil {
+			logging.Printf("  │ Warning: %v\n", err)
+		}
+		if status := tally.Metrics(monitor.HTTPStatusSourceAccessLog, bucket); status != nil {
+			return status
+		}
+	}
+	if output == nil || tr.config.IsOCITarget() {
+		return nil
+	}
+
+	responses, inferred := output.RegistryResponses()
+	tally := monitor.NewHTTPStatusTally(output.StartTime)
+	for _, response := range responses {
+		tally.Add(response.Time, response.Code)
+	}
+	status := tally.Metrics(monitor.HTTPStatusSourceDebugLog, bucket)
+	if status != nil {
+		status.SuccessInferred = inferred
+	}
+	return status
+}
+
+// reportRegistryResponses prints the 429 and 5xx responses of every upload,
+// since registry throttling is the usual cause of a slow mirror
+func (tr *TestRunner) reportRegistryResponses() {
+	var reported []TestResult
+	for _, r := range tr.results {
+		if r.UploadPhase.HTTPStatus != nil {
+			reported = append(reported, r)
+		}
+	}
+	if len(reported) == 0 {
+		return
+	}
+
+	logging.Printf("\nRegistry Responses During Upload (429 and 5xx):\n")
+	degraded := false
+	for _, r := range reported {
+		status := r.UploadPhase.HTTPStatus
+		mark := "✅"
+		if status.Degraded() {
+			mark = "❌"
+			degraded = true
+		}
+		label := fmt.Sprintf("Iteration %d (%s", r.Iteration, r.Version)
+		if r.Stage != nil {
+			label += ", stage " + r.Stage.Name
+		}
+		if tr.config.IsRegistryComparison() {
+			label += ", " + r.Registry
+		}
+		logging.Printf("  %s %s): %s\n", mark, label, status.String())
+		for _, s := range status.Storms {
+			logging.Printf("       Storm at +%ds for %ds: %d throttled, %d server errors of %d responses\n",
+				s.OffsetSeconds, s.DurationSeconds, s.Throttled, s.ServerErrors, s.Responses)
+		}
+	}
+	if degraded {
+		logging.Printf("  Warning: Upload times include registry throttling or errors; check the registry rate limits and storage backend\n")
+	}
+}
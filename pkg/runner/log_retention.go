@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/telco-core/ngc-495/pkg/command"
+)
+
+// LogRetention selects the oc-mirror output lines each phase keeps in the
+// results. The full output always goes to the phase log file, and the
+// distinct error and warning messages are summarized in every mode.
+type LogRetention struct {
+	Mode  string `json:"mode,omitempty" yaml:"mode,omitempty"`   // file (default), head-tail or errors
+	Lines int    `json:"lines,omitempty" yaml:"lines,omitempty"` // Lines kept at each end, or error and warning lines kept; 0 for the default
+}
+
+// Validate checks the retention mode
+func (c LogRetention) Validate() error {
+	switch c.Mode {
+	case "", command.LogRetentionFile, command.LogRetentionHeadTail, command.LogRetentionErrors:
+	default:
+		return fmt.Errorf("unknown log retention mode %q (valid: %s, %s, %s)", c.Mode, command.LogRetentionFile, command.LogRetentionHeadTail, command.LogRetentionErrors)
+	}
+	if c.Lines < 0 {
+		return fmt.Errorf("log retention lines must not be negative")
+	}
+	return nil
+}
+
+// String returns a human-readable description of the retention
+func (c LogRetention) String() string {
+	lines := c.Lines
+	if lines == 0 {
+		lines = command.DefaultLogRetentionLines
+	}
+	switch c.Mode {
+	case command.LogRetentionHeadTail:
+		return fmt.Sprintf("first and last %d lines", lines)
+	case command.LogRetentionErrors:
+		return fmt.Sprintf("first %d error and warning lines", lines)
+	}
+	return "log file only"
+}
+
+// applyLogRetention configures which output lines an oc-mirror command keeps
+func (tr *TestRunner) applyLogRetention(cmd *command.OCMirrorCommand) {
+	cmd.SetLogRetention(tr.config.LogRetention.Mode, tr.config.LogRetention.Lines)
+}
+
+// recordLogs stores the retained output lines and the message summary of a phase
+func recordLogs(metrics *PhaseMetrics, output *command.CommandOutput) {
+	metrics.LogExcerpt = output.LogExcerpt()
+	metrics.LogSummary = output.LogSummary()
+}
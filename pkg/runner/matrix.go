@@ -0,0 +1,525 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// Workflows of the iteration matrix
+const (
+	WorkflowMirror = "mirror" // Mirror to the workspace, then from the workspace to the registry
+	WorkflowAirGap = "airgap" // Mirror to archives, transfer them, then disk-to-mirror
+)
+
+// Cache states of the iteration matrix
+const (
+	CacheClean  = "clean"  // Empty workspace; the cache is kept, as in a clean run
+	CacheCached = "cached" // Workspace and cache of the previous iteration
+)
+
+// MatrixConfig lists the values of each dimension of the iteration matrix.
+// Every combination of version, reference, workflow and concurrency runs the configured
+// iterations; the cache states assign the first iteration the first state
+// and the others the last, so clean and cached run one clean iteration and
+// cached reruns. Dimensions left empty take their value from the rest of the
+// configuration.
+type MatrixConfig struct {
+	Versions    []string `json:"versions,omitempty" yaml:"versions,omitempty"`
+	Workflows   []string `json:"workflows,omitempty" yaml:"workflows,omitempty"`
+	CacheStates []string `json:"cache_states,omitempty" yaml:"cacheStates,omitempty"`
+	Concurrency []int    `json:"concurrency,omitempty" yaml:"concurrency,omitempty"` // Max concurrent pushes; 0 leaves the oc-mirror default
+	References  []string `json:"references,omitempty" yaml:"references,omitempty"`   // Content by tag, or pinned to digests
+}
+
+// MatrixCell tags a result with the dimension values it ran with
+type MatrixCell struct {
+	Version     string `json:"version"`
+	Workflow    string `json:"workflow"`
+	Cache       string `json:"cache"`
+	Concurrency int    `json:"concurrency"` // 0: oc-mirror default
+	Reference   string `json:"reference"`
+}
+
+// MatrixSummary aggregates the results of one combination of the matrix
+type MatrixSummary struct {
+	Label              string        `json:"label"`
+	Iterations         int           `json:"iterations"`
+	Failed             int           `json:"failed"`
+	CleanDownloadTime  time.Duration `json:"clean_download_time_seconds"`
+	CachedDownloadTime time.Duration `json:"cached_download_time_seconds"` // Average over cached iterations
+	CleanUploadTime    time.Duration `json:"clean_upload_time_seconds"`
+	CachedUploadTime   time.Duration `json:"cached_upload_time_seconds"` // Average over cached iterations
+	BytesUploaded      int64         `json:"bytes_uploaded"`             // Clean iteration
+	TagsAdded          int           `json:"tags_added,omitempty"`       // Clean iteration, when counted
+	ManifestsAdded     int           `json:"manifests_added,omitempty"`
+}
+
+// matrixGroup is one combination of version, reference, workflow and
+// concurrency. Its iterations run in sequence, sharing the workspace and cache.
+type matrixGroup struct {
+	version     string
+	reference   string
+	workflow    string
+	concurrency int
+	logPrefix   string // Distinguishes the log files of groups of the same version
+}
+
+// Enabled returns true if any dimension is set
+func (c MatrixConfig) Enabled() bool {
+	return len(c.Versions) > 0 || len(c.Workflows) > 0 || len(c.CacheStates) > 0 || len(c.Concurrency) > 0 || len(c.References) > 0
+}
+
+// Validate checks the dimension values
+func (c MatrixConfig) Validate() error {
+	if err := checkDimension("version", c.Versions, "v1", "v2"); err != nil {
+		return err
+	}
+	if err := checkDimension("workflow", c.Workflows, WorkflowMirror, WorkflowAirGap); err != nil {
+		return err
+	}
+	if err := checkDimension("cache state", c.CacheStates, CacheClean, CacheCached); err != nil {
+		return err
+	}
+	if err := checkDimension("reference", c.References, ReferenceTag, ReferenceDigest); err != nil {
+		return err
+	}
+	if len(c.CacheStates) == 2 && c.CacheStates[0] != CacheClean {
+		return fmt.Errorf("the clean cache state must come first")
+	}
+	seen := make(map[int]bool)
+	for _, n := range c.Concurrency {
+		if n < 0 {
+			return fmt.Errorf("matrix concurrency must not be negative")
+		}
+		if seen[n] {
+			return fmt.Errorf("matrix concurrency %d is listed twice", n)
+		}
+		seen[n] = true
+	}
+	return nil
+}
+
+// checkDimension checks that values are valid and listed once
+func checkDimension(name string, values []string, valid ...string) error {
+	seen := make(map[string]bool)
+	for _, value := range values {
+		if !slices.Contains(valid, value) {
+			return fmt.Errorf("unknown matrix %s %q (valid: %s)", name, value, strings.Join(valid, ", "))
+		}
+		if seen[value] {
+			return fmt.Errorf("matrix %s %q is listed twice", name, value)
+		}
+		seen[value] = true
+	}
+	return nil
+}
+
+// matrixString returns a human-readable description of the dimensions
+func (c *Config) matrixString() string {
+	concurrency := make([]string, 0, len(c.matrixConcurrency()))
+	for _, n := range c.matrixConcurrency() {
+		concurrency = append(concurrency, concurrencyLabel(n))
+	}
+	return fmt.Sprintf("version %s × reference %s × workflow %s × cache %s × concurrency %s (%d combination(s))",
+		strings.Join(c.MatrixVersions(), ","), strings.Join(c.matrixReferences(), ","), strings.Join(c.matrixWorkflows(), ","),
+		strings.Join(c.matrixCacheStates(), ","), strings.Join(concurrency, ","), len(c.matrixGroups()))
+}
+
+// MatrixVersions returns the oc-mirror versions the run exercises: the matrix
+// versions, or v1 and v2 for the v1/v2 comparison, otherwise v2
+func (c *Config) MatrixVersions() []string {
+	switch {
+	case len(c.Matrix.Versions) > 0:
+		return c.Matrix.Versions
+	case c.CompareV1V2:
+		return []string{"v1", "v2"}
+	}
+	return []string{"v2"}
+}
+
+// matrixEnabled returns true if the run goes through the matrix dimensions,
+// set explicitly or by the tag vs digest comparison
+func (c *Config) matrixEnabled() bool {
+	return c.Matrix.Enabled() || c.ComparePinning
+}
+
+// matrixReferences returns the image references, tag and digest for the
+// pinning comparison, otherwise tag
+func (c *Config) matrixReferences() []string {
+	switch {
+	case len(c.Matrix.References) > 0:
+		return c.Matrix.References
+	case c.ComparePinning:
+		return []string{ReferenceTag, ReferenceDigest}
+	}
+	return []string{ReferenceTag}
+}
+
+// runsV1 returns true if any iteration runs oc-mirror v1
+func (c *Config) runsV1() bool {
+	return slices.Contains(c.MatrixVersions(), "v1")
+}
+
+// matrixWorkflows returns the workflows, the air-gap workflow when enabled
+func (c *Config) matrixWorkflows() []string {
+	switch {
+	case len(c.Matrix.Workflows) > 0:
+		return c.Matrix.Workflows
+	case c.AirGap.Enabled:
+		return []string{WorkflowAirGap}
+	}
+	return []string{WorkflowMirror}
+}
+
+// matrixCacheStates returns the cache states, a clean iteration followed by cached ones by default
+func (c *Config) matrixCacheStates() []string {
+	if len(c.Matrix.CacheStates) > 0 {
+		return c.Matrix.CacheStates
+	}
+	return []string{CacheClean, CacheCached}
+}
+
+// matrixConcurrency returns the push concurrencies, the pacing cap by default
+func (c *Config) matrixConcurrency() []int {
+	if len(c.Matrix.Concurrency) > 0 {
+		return c.Matrix.Concurrency
+	}
+	return []int{c.Pacing.MaxConcurrentPushes}
+}
+
+// usesAirGap returns true if any iteration runs the air-gap workflow
+func (c *Config) usesAirGap() bool {
+	return slices.Contains(c.matrixWorkflows(), WorkflowAirGap)
+}
+
+// matrixGroups returns the cross-product of versions, references, workflows
+// and concurrencies, versions outermost so each version runs in one block and
+// references next so the imageset configurations change least often
+func (c *Config) matrixGroups() []matrixGroup {
+	references, workflows, concurrencies := c.matrixReferences(), c.matrixWorkflows(), c.matrixConcurrency()
+	var groups []matrixGroup
+	for _, version := range c.MatrixVersions() {
+		for _, reference := range references {
+			for _, workflow := range workflows {
+				for _, concurrency := range concurrencies {
+					group := matrixGroup{version: version, reference: reference, workflow: workflow, concurrency: concurrency}
+					var prefix []string
+					if len(workflows) > 1 || len(concurrencies) > 1 {
+						prefix = append(prefix, workflow, concurrencyLabel(concurrency))
+					}
+					if len(references) > 1 {
+						prefix = append(prefix, reference)
+					}
+					group.logPrefix = strings.Join(prefix, "_")
+					groups = append(groups, group)
+				}
+			}
+		}
+	}
+	return groups
+}
+
+// ValidateMatrix checks that the matrix can run with the rest of the configuration
+func (c *Config) ValidateMatrix() error {
+	if err := c.Matrix.Validate(); err != nil {
+		return err
+	}
+	if !c.matrixEnabled() {
+		return nil
+	}
+	switch {
+	case len(c.Matrix.References) > 0 && c.ComparePinning:
+		return fmt.Errorf("matrix references cannot be combined with the pinning comparison, list tag and digest as matrix references instead")
+	case c.pinsDigests() && c.UpdateContent != nil:
+		return fmt.Errorf("digest references cannot be combined with the day-2 update")
+	case c.pinsDigests() && c.Delete.Enabled:
+		return fmt.Errorf("digest references cannot be combined with the delete phase")
+	case len(c.Matrix.Versions) > 0 && c.CompareV1V2:
+		return fmt.Errorf("matrix versions cannot be combined with the v1/v2 comparison, list v1 and v2 as matrix versions instead")
+	case len(c.Matrix.Workflows) > 0 && c.AirGap.Enabled:
+		return fmt.Errorf("matrix workflows cannot be combined with the air-gap flag, list airgap as a matrix workflow instead")
+	case len(c.Matrix.Concurrency) > 0 && c.Pacing.MaxConcurrentPushes > 0:
+		return fmt.Errorf("matrix concurrency cannot be combined with max concurrent pushes")
+	case c.IsRegistryComparison():
+		return fmt.Errorf("the iteration matrix cannot be combined with registry comparison")
+	case c.Proxy.Compare:
+		return fmt.Errorf("the iteration matrix cannot be combined with the proxy comparison")
+	case len(c.Stages) > 0:
+		return fmt.Errorf("the iteration matrix cannot be combined with stages")
+	case c.UpdateContent != nil && len(c.matrixGroups()) > 1:
+		return fmt.Errorf("the day-2 update cannot be combined with a matrix of several combinations")
+	}
+	return nil
+}
+
+// concurrencyLabel names a push concurrency, e.g. c8 or default
+func concurrencyLabel(n int) string {
+	if n == 0 {
+		return "default"
+	}
+	return "c" + strconv.Itoa(n)
+}
+
+// label names the combination, e.g. v2/airgap/c8, with a /digest suffix for pinned content
+func (g matrixGroup) label() string {
+	label := g.version + "/" + g.workflow + "/" + concurrencyLabel(g.concurrency)
+	if g.reference == ReferenceDigest {
+		label += "/" + ReferenceDigest
+	}
+	return label
+}
+
+// useMatrixGroup applies the workflow and concurrency of a group to the configuration
+func (tr *TestRunner) useMatrixGroup(group *matrixGroup) {
+	tr.matrixGroup = group
+	tr.config.AirGap.Enabled = group.workflow == WorkflowAirGap
+	tr.config.Pacing.MaxConcurrentPushes = group.concurrency
+}
+
+// resetForMatrixGroup empties the workspace between combinations. A group
+// starting clean also starts from an empty v2 cache once an earlier group
+// filled it, so every clean iteration downloads the whole content.
+func (tr *TestRunner) resetForMatrixGroup(group matrixGroup, cacheUsed bool) error {
+	logging.Printf("Cleaning workspace for %s...\n", group.label())
+	if err := tr.cleanWorkspace(); err != nil {
+		return fmt.Errorf("failed to clean workspace for %s: %w", group.label(), err)
+	}
+	if group.version == "v2" && cacheUsed && tr.config.matrixCacheStates()[0] == CacheClean {
+		if err := os.RemoveAll(tr.cacheDir("v2")); err != nil {
+			return fmt.Errorf("failed to clear the cache for %s: %w", group.label(), err)
+		}
+	}
+	return nil
+}
+
+// runMatrix runs the iterations of every combination of the matrix and tags
+// each result with the values it ran with. The standard test is a matrix of
+// one combination; the v1/v2 comparison runs both versions.
+func (tr *TestRunner) runMatrix() error {
+	groups := tr.config.matrixGroups()
+	states := tr.config.matrixCacheStates()
+	airGap, concurrency := tr.config.AirGap.Enabled, tr.config.Pacing.MaxConcurrentPushes
+	reference := ReferenceTag
+	defer func() {
+		tr.matrixGroup = nil
+		tr.config.AirGap.Enabled, tr.config.Pacing.MaxConcurrentPushes = airGap, concurrency
+		if reference != ReferenceTag {
+			if err := tr.writeReferenceConfigs(ReferenceTag); err != nil {
+				logging.Printf("Warning: Failed to restore the imageset configurations: %v\n", err)
+			}
+		}
+	}()
+
+	if tr.config.pinsDigests() {
+		if err := tr.pinContent(); err != nil {
+			return err
+		}
+	}
+
+	if len(groups) > 1 {
+		logging.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
+		logging.Printf("║              Iteration Matrix                                 ║\n")
+		logging.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+	}
+
+	cacheUsed := false
+	for g := range groups {
+		group := groups[g]
+		if len(groups) > 1 {
+			logging.Printf("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+			logging.Printf("Running %s (%d/%d)\n", group.label(), g+1, len(groups))
+			logging.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+			if g > 0 {
+				if err := tr.resetForMatrixGroup(group, cacheUsed); err != nil {
+					return err
+				}
+			}
+		}
+		if group.reference != reference {
+			if err := tr.writeReferenceConfigs(group.reference); err != nil {
+				return err
+			}
+			reference = group.reference
+		}
+		tr.useMatrixGroup(&group)
+		cacheUsed = cacheUsed || group.version == "v2"
+		if err := tr.runWarmups(group.version); err != nil {
+			return err
+		}
+
+		for i := 0; i < tr.config.Iterations; i++ {
+			isCleanRun := tr.config.cleanIteration(i+1, states[min(i, len(states)-1)] == CacheClean)
+			cache := CacheCached
+			if isCleanRun {
+				cache = CacheClean
+			}
+			isUpdateRun := i == 1 && tr.config.UpdateContent != nil
+			if len(groups) > 1 {
+				logging.Printf("\n[%s] Iteration %d/%d (%s)\n", group.label(), i+1, tr.config.Iterations, runKind(isCleanRun, isUpdateRun))
+			} else {
+				logging.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
+				logging.Printf("║  Iteration %d/%d (%s)                                          ║\n", i+1, tr.config.Iterations, runKind(isCleanRun, isUpdateRun))
+				logging.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+			}
+			if isUpdateRun {
+				if err := tr.startUpdate(); err != nil {
+					return err
+				}
+			}
+			key := tr.iterationKey(i+1, group.version)
+			if tr.skipCompleted(key) {
+				continue
+			}
+
+			result, err := tr.runIteration(i+1, isCleanRun, group.version)
+			result.Matrix = &MatrixCell{Version: group.version, Workflow: group.workflow, Cache: cache, Concurrency: group.concurrency, Reference: group.reference}
+			if err != nil && !tr.recordFailedIteration(&result, err) {
+				if len(groups) > 1 {
+					return fmt.Errorf("%s iteration %d failed: %w", group.label(), i+1, err)
+				}
+				return fmt.Errorf("iteration %d failed: %w", i+1, err)
+			}
+			tr.evaluateGates(&result)
+			tr.publishIteration(result)
+
+			tr.results = append(tr.results, result)
+			if !result.Failed {
+				tr.printIterationSummary(result)
+			}
+
+			// Save results incrementally after each iteration
+			if err := tr.saveResults(); err != nil {
+				logging.Printf("Warning: Failed to save results incrementally: %v\n", err)
+			}
+			tr.markCompleted(key)
+		}
+	}
+
+	logging.Summary(func() { tr.compareMatrix(groups) })
+
+	// Measure pruning the mirrored content
+	deleteErr := tr.runDeletePhase()
+
+	// Final save (in case of any updates)
+	if err := tr.saveResults(); err != nil {
+		return fmt.Errorf("failed to save results: %w", err)
+	}
+	if deleteErr != nil {
+		return deleteErr
+	}
+
+	return errors.Join(failedIterationsError(tr.results), gateFailuresError(tr.results))
+}
+
+// compareMatrix prints the comparisons of the run: clean vs cached (or the
+// day-2 update) for one combination, the detailed v1 vs v2 comparison when
+// only the version varies, and a row per combination otherwise, followed by
+// the iteration statistics
+func (tr *TestRunner) compareMatrix(groups []matrixGroup) {
+	defer printStatistics(SummarizeStatistics(tr.results))
+	if len(groups) == 1 {
+		switch {
+		case tr.config.UpdateContent != nil:
+			tr.compareIncrementalUpdate()
+		case !tr.config.RepeatClean:
+			tr.compareCleanVsCached()
+		}
+		return
+	}
+
+	if len(groups) == 2 && groups[0].version == "v1" && groups[1].version == "v2" && groups[0].reference == groups[1].reference {
+		var v1Results, v2Results []TestResult
+		for _, r := range measuredResults(tr.results) {
+			if r.Version == "v1" {
+				v1Results = append(v1Results, r)
+			} else {
+				v2Results = append(v2Results, r)
+			}
+		}
+		tr.compareV1VsV2(completedResults(v1Results), completedResults(v2Results))
+		return
+	}
+	printMatrixComparison(SummarizeMatrix(tr.results))
+}
+
+// SummarizeMatrix groups results by combination, in first-seen order
+func SummarizeMatrix(results []TestResult) []MatrixSummary {
+	var summaries []MatrixSummary
+	index := make(map[string]int)
+	cachedRuns := make(map[string]int)
+
+	for _, r := range results {
+		if r.Matrix == nil || r.Warmup {
+			continue
+		}
+		label := matrixGroup{version: r.Matrix.Version, reference: r.Matrix.Reference, workflow: r.Matrix.Workflow, concurrency: r.Matrix.Concurrency}.label()
+		i, ok := index[label]
+		if !ok {
+			i = len(summaries)
+			index[label] = i
+			summaries = append(summaries, MatrixSummary{Label: label})
+		}
+		s := &summaries[i]
+		s.Iterations++
+		if r.Failed {
+			s.Failed++
+			continue
+		}
+
+		if r.IsCleanRun {
+			s.CleanDownloadTime = r.DownloadPhase.WallTime
+			s.CleanUploadTime = r.UploadPhase.WallTime
+			s.BytesUploaded = r.UploadPhase.BytesUploaded
+			if r.ReferenceCounts != nil {
+				s.TagsAdded, s.ManifestsAdded = r.ReferenceCounts.TagsAdded, r.ReferenceCounts.ManifestsAdded
+			}
+		} else {
+			s.CachedDownloadTime += r.DownloadPhase.WallTime
+			s.CachedUploadTime += r.UploadPhase.WallTime
+			cachedRuns[label]++
+		}
+	}
+
+	for i := range summaries {
+		if n := cachedRuns[summaries[i].Label]; n > 0 {
+			summaries[i].CachedDownloadTime /= time.Duration(n)
+			summaries[i].CachedUploadTime /= time.Duration(n)
+		}
+	}
+	return summaries
+}
+
+// printMatrixComparison prints one row per combination
+func printMatrixComparison(summaries []MatrixSummary) {
+	if len(summaries) < 2 {
+		return
+	}
+	logging.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
+	logging.Printf("║  Comparison: Iteration Matrix                                 ║\n")
+	logging.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+	logging.Printf("%-28s %-7s %-12s %-12s %-12s %-12s %-10s %-6s %s\n",
+		"COMBINATION", "OK", "CLEAN DL", "CACHED DL", "CLEAN UL", "CACHED UL", "UPLOADED", "TAGS", "MANIFESTS")
+	for _, s := range summaries {
+		tags, manifests := "-", "-"
+		if s.TagsAdded > 0 || s.ManifestsAdded > 0 {
+			tags, manifests = strconv.Itoa(s.TagsAdded), strconv.Itoa(s.ManifestsAdded)
+		}
+		logging.Printf("%-28s %-7s %-12s %-12s %-12s %-12s %-10s %-6s %s\n",
+			s.Label,
+			fmt.Sprintf("%d/%d", s.Iterations-s.Failed, s.Iterations),
+			formatDuration(s.CleanDownloadTime),
+			formatDuration(s.CachedDownloadTime),
+			formatDuration(s.CleanUploadTime),
+			formatDuration(s.CachedUploadTime),
+			monitor.FormatBytesHuman(s.BytesUploaded),
+			tags, manifests)
+	}
+}
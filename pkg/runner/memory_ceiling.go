@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// newMemoryCeilingMonitor returns a memory ceiling monitor when a memory budget is configured, nil otherwise
+func (tr *TestRunner) newMemoryCeilingMonitor() *monitor.MemoryCeilingMonitor {
+	if !tr.config.MemoryCeiling.Enabled() {
+		return nil
+	}
+	return monitor.NewMemoryCeilingMonitor(tr.config.MemoryCeiling)
+}
+
+// startMemoryCeilingMonitor attaches the memory budget check to the oc-mirror PID
+func startMemoryCeilingMonitor(memoryMonitor *monitor.MemoryCeilingMonitor, pid int) {
+	if memoryMonitor == nil {
+		return
+	}
+	memoryMonitor.SetTargetPID(pid)
+	if err := memoryMonitor.Start(); err != nil {
+		logging.Printf("  │ Warning: Failed to start memory ceiling monitoring for PID %d: %v\n", pid, err)
+	}
+}
+
+// stopMemoryCeilingMonitor stops the budget check, scans for OOM kills and returns the metrics, if any
+func stopMemoryCeilingMonitor(memoryMonitor *monitor.MemoryCeilingMonitor) *monitor.MemoryCeilingMetrics {
+	if memoryMonitor == nil || !memoryMonitor.IsMonitoring() {
+		return nil
+	}
+	metrics := memoryMonitor.Stop()
+	if len(metrics.OOMKills) > 0 || metrics.CgroupOOMKills > 0 {
+		logging.Printf("  │ Warning: The kernel OOM killer activated during this phase\n")
+	}
+	return &metrics
+}
+
+// memoryCeilingPeak returns the highest usage of the iteration as a percentage of the budget
+func memoryCeilingPeak(result TestResult) float64 {
+	var peak float64
+	for _, phase := range []PhaseMetrics{result.DownloadPhase, result.UploadPhase} {
+		if phase.MemoryCeiling != nil {
+			peak = max(peak, phase.MemoryCeiling.PeakPercent)
+		}
+	}
+	return peak
+}
+
+// oomKillerActivated returns true if the OOM killer activated during any phase of the iteration
+func oomKillerActivated(result TestResult) bool {
+	for _, phase := range []PhaseMetrics{result.DownloadPhase, result.UploadPhase} {
+		if phase.MemoryCeiling != nil && phase.MemoryCeiling.OOMKillerInvoked {
+			return true
+		}
+	}
+	return false
+}
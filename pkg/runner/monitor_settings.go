@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// processPollInterval is the resource sampling interval of the oc-mirror
+// process, shorter than the default so short phases still get samples
+const processPollInterval = 500 * time.Millisecond
+
+// Monitor names recorded in MonitorSettings
+const (
+	MonitorNetwork        = "network"
+	MonitorProcessNetwork = "process_network"
+	MonitorResource       = "resource"
+	MonitorDownload       = "download"
+	MonitorDiskIO         = "disk_io"
+	MonitorDiskWrite      = "disk_write"
+	MonitorRegistry       = "registry"
+	MonitorMemoryCeiling  = "memory_ceiling"
+	MonitorRegistryServer = "registry_server"
+	MonitorPlugin         = "plugin:" // Prefix of the monitor plugins, followed by their name
+)
+
+// MonitorSettings records how an iteration was sampled. Changing a poll
+// interval, the network accounting mode or the monitored interface changes
+// the metrics as well, so runs measured differently should not be compared.
+type MonitorSettings struct {
+	NetworkAccounting string                   `json:"network_accounting"`       // interface or process
+	Interface         string                   `json:"interface,omitempty"`      // Interface whose counters are sampled in interface accounting
+	ResourceScope     string                   `json:"resource_scope,omitempty"` // process, tree or cgroup
+	Monitors          []MonitorSetting         `json:"monitors"`
+	AdaptivePolling   *monitor.AdaptivePolling `json:"adaptive_polling,omitempty"` // Back-off of the phase monitors on long phases
+	Backoff           []PollBackoff            `json:"backoff,omitempty"`          // Poll interval changes of the iteration
+}
+
+// MonitorSetting is one monitor enabled during the iteration
+type MonitorSetting struct {
+	Name           string `json:"name"`
+	PollIntervalMs int64  `json:"poll_interval_ms"`
+	Target         string `json:"target,omitempty"` // What is sampled: the oc-mirror process, paths, the registry
+}
+
+// String returns a one-line summary such as "interface eth0: network 1s, resource 500ms"
+func (s *MonitorSettings) String() string {
+	var monitors []string
+	for _, m := range s.Monitors {
+		monitors = append(monitors, fmt.Sprintf("%s %v", m.Name, time.Duration(m.PollIntervalMs)*time.Millisecond))
+	}
+	mode := s.NetworkAccounting
+	if s.Interface != "" {
+		mode += " " + s.Interface
+	}
+	summary := mode + ": " + strings.Join(monitors, ", ")
+	if s.AdaptivePolling != nil {
+		summary += "; adaptive " + s.AdaptivePolling.String()
+	}
+	return summary
+}
+
+// Get returns the setting of a monitor, if it was enabled
+func (s *MonitorSettings) Get(name string) (MonitorSetting, bool) {
+	for _, m := range s.Monitors {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return MonitorSetting{}, false
+}
+
+// Diff lists the material differences to other: the network accounting mode,
+// the monitored interface, the resource scope, adaptive polling and the poll
+// intervals of monitors enabled in both.
+// Monitors enabled in only one run, such as the memory ceiling check, and
+// targets such as the registry host do not change how metrics are sampled.
+func (s *MonitorSettings) Diff(other *MonitorSettings) []string {
+	var diffs []string
+	if s.NetworkAccounting != other.NetworkAccounting {
+		diffs = append(diffs, fmt.Sprintf("network accounting %s vs %s", s.NetworkAccounting, other.NetworkAccounting))
+	} else if s.Interface != other.Interface {
+		diffs = append(diffs, fmt.Sprintf("monitored interface %s vs %s", s.Interface, other.Interface))
+	}
+	if s.resourceScope() != other.resourceScope() {
+		diffs = append(diffs, fmt.Sprintf("resource scope %s vs %s", s.resourceScope(), other.resourceScope()))
+	}
+	if s.adaptivePolling() != other.adaptivePolling() {
+		diffs = append(diffs, fmt.Sprintf("adaptive polling %s vs %s", s.adaptivePolling(), other.adaptivePolling()))
+	}
+	for _, m := range s.Monitors {
+		o, ok := other.Get(m.Name)
+		if ok && m.PollIntervalMs != o.PollIntervalMs {
+			diffs = append(diffs, fmt.Sprintf("%s poll interval %dms vs %dms", m.Name, m.PollIntervalMs, o.PollIntervalMs))
+		}
+	}
+	return diffs
+}
+
+// monitorSettings returns the monitors an iteration of version runs with
+func (tr *TestRunner) monitorSettings(version string) *MonitorSettings {
+	settings := &MonitorSettings{NetworkAccounting: tr.config.GetNetworkAccounting(), ResourceScope: tr.config.GetResourceScope()}
+	add := func(name string, interval time.Duration, target string) {
+		settings.Monitors = append(settings.Monitors, MonitorSetting{Name: name, PollIntervalMs: interval.Milliseconds(), Target: target})
+	}
+
+	// Interface counters are sampled in both modes; process accounting adds per-process counters
+	add(MonitorNetwork, monitor.DefaultPollInterval, tr.networkInterface)
+	if settings.NetworkAccounting == NetworkAccountingProcess {
+		add(MonitorProcessNetwork, monitor.DefaultPollInterval, "oc-mirror")
+	} else {
+		settings.Interface = tr.networkInterface
+	}
+	add(MonitorResource, processPollInterval, "oc-mirror")
+	add(MonitorDownload, monitor.DefaultPollInterval, tr.mirrorDir(version))
+	add(MonitorDiskIO, monitor.DefaultPollInterval, strings.Join(tr.diskIOPaths(version, true), ","))
+	if tr.config.IsOCITarget() {
+		add(MonitorDiskWrite, monitor.DefaultPollInterval, tr.config.OCILayoutPath())
+	} else if tr.registryMonitor.IsMonitoring() {
+		add(MonitorRegistry, monitor.DefaultPollInterval, tr.targetRegistry())
+	}
+	if tr.config.MemoryCeiling.Enabled() {
+		add(MonitorMemoryCeiling, monitor.DefaultPollInterval, tr.config.MemoryCeiling.Budget)
+	}
+	if tr.config.RegistryMetrics.Enabled() && !tr.config.IsOCITarget() {
+		add(MonitorRegistryServer, tr.config.RegistryMetrics.ScrapeInterval(), tr.config.RegistryMetrics.URL)
+	}
+	for _, spec := range tr.config.Plugins.Monitors {
+		add(MonitorPlugin+spec.Name, spec.SampleInterval(), spec.Command)
+	}
+
+	sort.Slice(settings.Monitors, func(i, j int) bool {
+		return settings.Monitors[i].Name < settings.Monitors[j].Name
+	})
+	if tr.config.AdaptivePolling.Enabled() {
+		adaptive := tr.config.AdaptivePolling
+		settings.AdaptivePolling = &adaptive
+	}
+	return settings
+}
+
+// resourceScope returns the resource scope, process for settings recorded before scopes existed
+func (s *MonitorSettings) resourceScope() string {
+	if s.ResourceScope == "" {
+		return monitor.ResourceScopeProcess
+	}
+	return s.ResourceScope
+}
+
+// newProcessResourceMonitor returns the resource monitor of an oc-mirror
+// process, covering the configured resource scope
+func (tr *TestRunner) newProcessResourceMonitor() *monitor.ResourceMonitor {
+	resourceMonitor := monitor.NewResourceMonitor()
+	resourceMonitor.SetPollInterval(processPollInterval) // More frequent sampling for child process
+	resourceMonitor.SetScope(tr.config.GetResourceScope())
+	return resourceMonitor
+}
+
+// adaptivePolling describes the back-off of the settings, "off" without one
+func (s *MonitorSettings) adaptivePolling() string {
+	if s.AdaptivePolling == nil {
+		return "off"
+	}
+	return s.AdaptivePolling.String()
+}
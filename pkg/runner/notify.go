@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/notify"
+)
+
+// sendNotification posts the run summary to the configured webhooks
+func (tr *TestRunner) sendNotification(startedAt time.Time, runErr error) {
+	if !tr.config.Notify.ShouldNotify(runErr != nil) {
+		return
+	}
+	summary := tr.buildSummary(startedAt, runErr)
+	if err := notify.Send(tr.config.Notify, summary); err != nil {
+		logging.Printf("Warning: Failed to send run notification: %v\n", err)
+		return
+	}
+	logging.Printf("Sent run notification to %d webhook(s)\n", len(tr.config.Notify.URLs))
+}
+
+// buildSummary describes the finished run for notifications
+func (tr *TestRunner) buildSummary(startedAt time.Time, runErr error) notify.Summary {
+	hostname, _ := os.Hostname()
+	summary := notify.Summary{
+		Status:          "completed",
+		RunName:         tr.config.RunName,
+		Scenario:        tr.config.ScenarioName,
+		Registry:        tr.config.RegistryURL,
+		Hostname:        hostname,
+		StartedAt:       startedAt,
+		DurationSeconds: time.Since(startedAt).Seconds(),
+		Iterations:      len(tr.results),
+		Improvement:     tr.runImprovement(),
+		ResultsFile:     tr.resultsPath,
+		DashboardURL:    tr.config.Notify.DashboardURL,
+	}
+	if runErr != nil {
+		summary.Status = "failed"
+		summary.Errors = append(summary.Errors, truncateError(runErr, 500))
+	}
+	for _, r := range tr.results {
+		if r.Failed {
+			summary.FailedIterations++
+			summary.Errors = append(summary.Errors, fmt.Sprintf("%s iteration %d: %s", r.Version, r.Iteration, truncateText(r.Error, 300)))
+		}
+	}
+	return summary
+}
+
+// runImprovement returns the total time improvement of v2 over v1 in comparison
+// runs, otherwise of the cached iterations over the clean one
+func (tr *TestRunner) runImprovement() *notify.Improvement {
+	results := completedResults(measuredResults(tr.results))
+	if tr.config.runsV1() {
+		var v1, v2 []TestResult
+		for _, r := range results {
+			if r.Version == "v1" {
+				v1 = append(v1, r)
+			} else {
+				v2 = append(v2, r)
+			}
+		}
+		return improvement("v2 vs v1", averageTotalTime(v1), averageTotalTime(v2))
+	}
+	if len(results) < 2 || !results[0].IsCleanRun {
+		return nil
+	}
+	return improvement("cached vs clean", averageTotalTime(results[:1]), averageTotalTime(results[1:]))
+}
+
+func improvement(comparison string, baseline, candidate time.Duration) *notify.Improvement {
+	if baseline <= 0 || candidate <= 0 {
+		return nil
+	}
+	return &notify.Improvement{
+		Comparison: comparison,
+		Percent:    float64(baseline-candidate) / float64(baseline) * 100,
+	}
+}
+
+// averageTotalTime returns the average download plus upload time
+func averageTotalTime(results []TestResult) time.Duration {
+	if len(results) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, r := range results {
+		total += r.DownloadPhase.WallTime + r.UploadPhase.WallTime
+	}
+	return total / time.Duration(len(results))
+}
@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/pacing"
+)
+
+// uploadArgs returns the extra oc-mirror arguments for the upload phase; pacing
+// arguments come last so they override the same flags from a scenario
+func (tr *TestRunner) uploadArgs(version string) []string {
+	args := append([]string{}, tr.config.ExtraArgs...)
+	if tr.config.UploadDebugLog {
+		// Debug output logs every registry request, the base of the status code distribution
+		args = append(args, "--log-level", "debug")
+	}
+	return append(args, tr.config.Pacing.Args(version)...)
+}
+
+// waitForUploadWindow blocks until the upload phase may start and returns the
+// pacing applied, plus when the current window closes (zero without windows)
+func (tr *TestRunner) waitForUploadWindow(version string) (*pacing.Applied, time.Time, error) {
+	if !tr.config.Pacing.Enabled() {
+		return nil, time.Time{}, nil
+	}
+
+	applied := &pacing.Applied{
+		Config: tr.config.Pacing,
+		Args:   tr.config.Pacing.Args(version),
+	}
+	if len(applied.Args) > 0 {
+		logging.Printf("  │ Pacing: max %d concurrent pushes\n", tr.config.Pacing.MaxConcurrentPushes)
+	}
+
+	now := time.Now()
+	next, window, err := tr.config.Pacing.NextAllowed(now)
+	if err != nil {
+		return applied, time.Time{}, err
+	}
+	if wait := next.Sub(now); wait > 0 {
+		if tr.config.Pacing.MaxWait > 0 && wait > tr.config.Pacing.MaxWait {
+			return applied, time.Time{}, fmt.Errorf("next upload window (%s) opens at %s, beyond the maximum wait of %v",
+				window, next.Format("2006-01-02 15:04"), tr.config.Pacing.MaxWait)
+		}
+		logging.Printf("  │ Outside upload windows, waiting %v until %s (%s)\n",
+			wait.Round(time.Second), next.Format("2006-01-02 15:04"), window)
+		time.Sleep(wait)
+		applied.Waited = wait
+	}
+
+	applied.StartedAt = time.Now()
+	var windowEnd time.Time
+	if window != nil {
+		applied.Window = window.String()
+		windowEnd = tr.config.Pacing.AllowedUntil(applied.StartedAt)
+		logging.Printf("  │ Upload window %s open until %s\n", window, windowEnd.Format("2006-01-02 15:04"))
+	}
+	return applied, windowEnd, nil
+}
+
+// finishUploadWindow records whether the upload ran past the end of its window
+func finishUploadWindow(applied *pacing.Applied, windowEnd time.Time) {
+	if applied == nil || windowEnd.IsZero() || !time.Now().After(windowEnd) {
+		return
+	}
+	applied.OverranWindow = true
+	logging.Printf("  │ Warning: upload ran past the end of its window (closed %s)\n", windowEnd.Format("2006-01-02 15:04"))
+}
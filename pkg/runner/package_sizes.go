@@ -0,0 +1,178 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+	"github.com/telco-core/ngc-495/pkg/registry"
+)
+
+// maxListedPackages limits the packages printed per iteration; the results
+// file lists all of them
+const maxListedPackages = 10
+
+// PackageSizes attributes the mirrored bytes to the operator packages of the
+// content. Each package counts the distinct blobs of its bundle and related
+// images as the registry holds them after the upload; blobs referenced by
+// several packages are counted in each of them and once in SharedBytes.
+type PackageSizes struct {
+	Packages    []PackageSize `json:"packages"`     // Largest first
+	TotalBytes  int64         `json:"total_bytes"`  // Distinct blobs of all packages
+	SharedBytes int64         `json:"shared_bytes"` // Blobs referenced by more than one package
+	Errors      []string      `json:"errors,omitempty"`
+}
+
+// PackageSize is the share of the mirrored content of one operator package
+type PackageSize struct {
+	Catalog         string `json:"catalog"`
+	Package         string `json:"package"`
+	Bundles         int    `json:"bundles"`
+	Images          int    `json:"images"`
+	DescribedImages int    `json:"described_images,omitempty"` // Images of the package in the oc-mirror describe associations (v1)
+	Bytes           int64  `json:"bytes"`                      // Distinct blobs of the package
+	UniqueBytes     int64  `json:"unique_bytes"`               // Blobs no other package references
+}
+
+// measurePackageSizes looks up the blobs of the images of every package in
+// the registry after the upload and sums their sizes per package
+func (tr *TestRunner) measurePackageSizes(version string, expected *expectedImages, describe *command.DescribeMetrics) *PackageSizes {
+	if !tr.config.PackageSizes || expected == nil || len(expected.packages) == 0 {
+		return nil
+	}
+	client, err := registry.NewClient(tr.targetRegistry(), "", tr.config.SkipTLS)
+	if err != nil {
+		logging.Printf("  │ Warning: Failed to measure the operator package sizes: %v\n", err)
+		return nil
+	}
+
+	prefix := tr.catalogPrefix(version)
+	blobs := make([][]registry.Blob, len(expected.images))
+	failures := make([]string, len(expected.images))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < expectedContentConcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				blobs[i], failures[i] = mirroredBlobs(client, prefix, expected.images[i])
+			}
+		}()
+	}
+	for i := range expected.images {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	sizes := &PackageSizes{}
+	imageBlobs := make(map[string][]registry.Blob, len(expected.images))
+	for i, image := range expected.images {
+		if failures[i] != "" {
+			sizes.Errors = append(sizes.Errors, failures[i])
+			continue
+		}
+		imageBlobs[image] = blobs[i]
+	}
+	described := make(map[string]bool)
+	if describe != nil {
+		for _, image := range describe.UniqueImages {
+			described[image] = true
+		}
+	}
+	sizes.attribute(expected.packages, imageBlobs, described)
+	sizes.PrintSummary()
+	return sizes
+}
+
+// attribute sums the blobs of each package and splits them into the bytes
+// only that package references and the bytes shared with others
+func (s *PackageSizes) attribute(packages []packageImages, imageBlobs map[string][]registry.Blob, described map[string]bool) {
+	packageBlobs := make([]map[string]int64, len(packages))
+	references := make(map[string]int)
+	for i, pkg := range packages {
+		packageBlobs[i] = make(map[string]int64)
+		for _, image := range pkg.images {
+			for _, blob := range imageBlobs[image] {
+				packageBlobs[i][blob.Digest] = blob.Size
+			}
+		}
+		for digest := range packageBlobs[i] {
+			references[digest]++
+		}
+	}
+
+	counted := make(map[string]bool)
+	for i, pkg := range packages {
+		size := PackageSize{Catalog: pkg.catalog, Package: pkg.name, Bundles: pkg.bundles, Images: len(pkg.images)}
+		for _, image := range pkg.images {
+			if described[image] {
+				size.DescribedImages++
+			}
+		}
+		for digest, bytes := range packageBlobs[i] {
+			size.Bytes += bytes
+			if references[digest] == 1 {
+				size.UniqueBytes += bytes
+			}
+			if !counted[digest] {
+				counted[digest] = true
+				s.TotalBytes += bytes
+				if references[digest] > 1 {
+					s.SharedBytes += bytes
+				}
+			}
+		}
+		s.Packages = append(s.Packages, size)
+	}
+	sort.SliceStable(s.Packages, func(i, j int) bool { return s.Packages[i].Bytes > s.Packages[j].Bytes })
+}
+
+// mirroredBlobs returns the blobs of an image in the mirror, under the
+// repository path of its source
+func mirroredBlobs(client *registry.Client, prefix, image string) ([]registry.Blob, string) {
+	_, img, err := registry.ParseImageReference(image)
+	if err != nil {
+		return nil, err.Error()
+	}
+	reference := img.Digest
+	if reference == "" {
+		reference = img.Tag
+	}
+	blobs, err := client.ImageBlobs(context.Background(), path.Join(prefix, img.Repository), reference)
+	if err != nil {
+		return nil, fmt.Sprintf("%s: %v", image, err)
+	}
+	return blobs, ""
+}
+
+// Share returns the part of the total bytes that only this package references
+func (s *PackageSizes) Share(p PackageSize) float64 {
+	if s.TotalBytes == 0 {
+		return 0
+	}
+	return float64(p.UniqueBytes) / float64(s.TotalBytes) * 100
+}
+
+// PrintSummary prints the largest packages with their share of the mirrored bytes
+func (s *PackageSizes) PrintSummary() {
+	logging.Printf("  │ Operator Packages: %s in %d package(s), %s shared\n",
+		monitor.FormatBytesHuman(s.TotalBytes), len(s.Packages), monitor.FormatBytesHuman(s.SharedBytes))
+	for i, p := range s.Packages {
+		if i == maxListedPackages {
+			logging.Printf("  │   ... and %d more\n", len(s.Packages)-i)
+			break
+		}
+		logging.Printf("  │   %-32s %4d image(s) %10s  %5.1f%% unique\n",
+			p.Package, p.Images, monitor.FormatBytesHuman(p.Bytes), s.Share(p))
+	}
+	if len(s.Errors) > 0 {
+		logging.Printf("  │ Warning: %d image(s) could not be measured\n", len(s.Errors))
+	}
+}
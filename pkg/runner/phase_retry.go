@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/netdiag"
+)
+
+// maxRetryBackoff caps the exponential backoff between phase retries
+const maxRetryBackoff = 5 * time.Minute
+
+// FailedAttempt records a failed download or upload phase attempt
+type FailedAttempt struct {
+	Phase     string        `json:"phase"`   // download or upload
+	Attempt   int           `json:"attempt"` // 1 for the first run of the phase
+	StartedAt time.Time     `json:"started_at"`
+	WallTime  time.Duration `json:"wall_time_seconds"`
+	Error     string        `json:"error"`
+	LogFile   string        `json:"log_file,omitempty"`        // oc-mirror output of the attempt
+	Backoff   time.Duration `json:"backoff_seconds,omitempty"` // Wait before the next attempt, zero when retries were exhausted
+	OOMKilled bool          `json:"oom_killed,omitempty"`      // The kernel OOM killer activated during the attempt
+
+	// Failure class (connection, tls, auth, oom, disk, timeout, other) and, for upload
+	// connection and TLS failures, the connectivity diagnostics run right after
+	Classification string          `json:"classification,omitempty"`
+	Diagnostics    *netdiag.Report `json:"diagnostics,omitempty"`
+}
+
+// retryBackoff returns the wait before the given retry (1-based), doubling each time
+func (tr *TestRunner) retryBackoff(retry int) time.Duration {
+	backoff := tr.config.GetRetryBackoff()
+	for i := 1; i < retry && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxRetryBackoff)
+}
+
+// runPhaseWithRetry runs a phase, retrying it with backoff up to the configured
+// number of times. run receives the attempt number, starting at 1. Every failed
+// attempt is appended to result.FailedAttempts; beforeRetry, if set, runs before
+// each new attempt.
+func (tr *TestRunner) runPhaseWithRetry(result *TestResult, phase string, run func(attempt int) (PhaseMetrics, error), beforeRetry func() error) (PhaseMetrics, error) {
+	for attempt := 1; ; attempt++ {
+		startedAt := time.Now()
+		metrics, err := run(attempt)
+		if err == nil {
+			if attempt > 1 {
+				logging.Printf("  │ %s phase succeeded on attempt %d\n", phase, attempt)
+			}
+			return metrics, nil
+		}
+
+		failure := FailedAttempt{
+			Phase:     phase,
+			Attempt:   attempt,
+			StartedAt: startedAt,
+			WallTime:  time.Since(startedAt),
+			Error:     err.Error(),
+			LogFile:   metrics.LogFile,
+			OOMKilled: metrics.MemoryCeiling != nil && metrics.MemoryCeiling.OOMKillerInvoked,
+		}
+		failure.Classification = classifyFailure(err, failure.OOMKilled)
+		failure.Diagnostics = tr.diagnoseFailure(phase, failure.Classification)
+		if attempt > tr.config.RetryFailed {
+			result.FailedAttempts = append(result.FailedAttempts, failure)
+			if tr.config.RetryFailed > 0 {
+				return metrics, fmt.Errorf("%w (gave up after %d attempts)", err, attempt)
+			}
+			return metrics, err
+		}
+
+		failure.Backoff = tr.retryBackoff(attempt)
+		result.FailedAttempts = append(result.FailedAttempts, failure)
+		logging.Printf("  │ Warning: %s phase failed (attempt %d/%d): %s\n", phase, attempt, tr.config.RetryFailed+1, truncateError(err, 200))
+		logging.Printf("  │ Retrying %s phase in %v...\n", phase, failure.Backoff)
+		time.Sleep(failure.Backoff)
+
+		if beforeRetry != nil {
+			if prepErr := beforeRetry(); prepErr != nil {
+				return metrics, fmt.Errorf("failed to prepare %s retry: %w", phase, prepErr)
+			}
+		}
+	}
+}
+
+// recordFailedIteration marks an iteration that failed after all retries. It
+// returns true when the run should continue with the remaining iterations.
+func (tr *TestRunner) recordFailedIteration(result *TestResult, err error) bool {
+	if tr.config.RetryFailed == 0 {
+		return false
+	}
+	result.Failed = true
+	result.Error = truncateError(err, 1000)
+	logging.Printf("\n  ✗ Iteration %d (%s) failed, continuing with the remaining iterations\n",
+		result.Iteration, result.Version)
+	logging.Printf("    %s\n", truncateError(err, 200))
+	return true
+}
+
+// completedResults returns the results of iterations that did not fail
+func completedResults(results []TestResult) []TestResult {
+	completed := make([]TestResult, 0, len(results))
+	for _, r := range results {
+		if !r.Failed {
+			completed = append(completed, r)
+		}
+	}
+	return completed
+}
+
+// failedIterationsError summarizes failed iterations, or returns nil if all completed
+func failedIterationsError(results []TestResult) error {
+	failed := len(results) - len(completedResults(results))
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d iteration(s) failed after retries (see failed_attempts in the results file)", failed, len(results))
+}
+
+// truncateError shortens an error message for console output; oc-mirror errors embed the last output lines
+func truncateError(err error, maxLen int) string {
+	return truncateText(err.Error(), maxLen)
+}
+
+// truncateText shortens s to maxLen bytes
+func truncateText(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
@@ -0,0 +1,160 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/internal/config"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/registry"
+)
+
+// Image references of the iteration matrix
+const (
+	ReferenceTag    = "tag"    // Catalogs and additional images requested by tag, as configured
+	ReferenceDigest = "digest" // Pinned to the digest each tag resolved to when the run started
+)
+
+// referenceCountConcurrency is the number of tags resolved in parallel when counting the registry content
+const referenceCountConcurrency = 8
+
+// ReferenceCounts is what a clean upload left in the registry: the tags it
+// created or moved and the manifests they point to
+type ReferenceCounts struct {
+	TagsAdded      int           `json:"tags_added"`
+	ManifestsAdded int           `json:"manifests_added"` // Distinct manifests of the added tags
+	TagsAfter      int           `json:"tags_after"`      // Under the upload prefix
+	ManifestsAfter int           `json:"manifests_after"`
+	Errors         []string      `json:"errors,omitempty"` // Tags that could not be resolved
+	Duration       time.Duration `json:"duration_seconds"` // Both listings
+}
+
+// pinsDigests returns true if any iteration mirrors digest-pinned content
+func (c *Config) pinsDigests() bool {
+	for _, reference := range c.matrixReferences() {
+		if reference == ReferenceDigest {
+			return true
+		}
+	}
+	return false
+}
+
+// pinContent resolves the catalogs and additional images of the content to
+// the digests their tags point to now, for the digest iterations
+func (tr *TestRunner) pinContent() error {
+	content := tr.config.Content
+	if content == nil {
+		content = config.DefaultContent()
+	}
+	logging.Printf("Resolving image tags to digests...\n")
+	pinned, images, err := content.PinDigests(digestResolver())
+	if err != nil {
+		return err
+	}
+	for _, image := range images {
+		logging.Printf("  %s -> %s\n", image.Image, image.Pinned)
+	}
+	tr.pinnedContent = pinned
+	return nil
+}
+
+// digestResolver returns a function resolving image tags to manifest
+// digests, with one registry client per host
+func digestResolver() func(image string) (string, error) {
+	clients := make(map[string]*registry.Client)
+	return func(image string) (string, error) {
+		host, repository := splitImageHost(image)
+		name, tag := config.SplitImageTag(repository)
+		client, ok := clients[host]
+		if !ok {
+			var err error
+			if client, err = registry.NewClient(host, "", false); err != nil {
+				return "", err
+			}
+			clients[host] = client
+		}
+		digest, status, err := client.HeadManifest(context.Background(), name, tag)
+		if err != nil {
+			return "", err
+		}
+		if digest == "" {
+			return "", fmt.Errorf("manifest not found (HTTP %d)", status)
+		}
+		return digest, nil
+	}
+}
+
+// splitImageHost splits an image reference into its registry host and
+// repository, Docker Hub for references without a host
+func splitImageHost(image string) (host, repository string) {
+	first, rest, found := strings.Cut(image, "/")
+	switch {
+	case found && (strings.ContainsAny(first, ".:") || first == "localhost"):
+		return first, rest
+	case found:
+		return "registry-1.docker.io", image
+	}
+	return "registry-1.docker.io", "library/" + image
+}
+
+// writeReferenceConfigs renders the imageset configurations of a reference mode
+func (tr *TestRunner) writeReferenceConfigs(reference string) error {
+	if reference == ReferenceDigest {
+		return writeImageSetConfigs(tr.pinnedContent)
+	}
+	return writeImageSetConfigs(tr.config.Content)
+}
+
+// snapshotTagDigests resolves the tags under the upload prefix before the
+// clean upload of a run that pins digests. It returns nil otherwise.
+func (tr *TestRunner) snapshotTagDigests(version string, isCleanRun bool) *registry.TagDigests {
+	if !isCleanRun || !tr.config.pinsDigests() || tr.config.IsOCITarget() {
+		return nil
+	}
+	return tr.listTagDigests(version)
+}
+
+// listTagDigests resolves the tags under the upload prefix, nil on failure
+func (tr *TestRunner) listTagDigests(version string) *registry.TagDigests {
+	client, err := registry.NewClient(tr.targetRegistry(), "", tr.config.SkipTLS)
+	if err == nil {
+		var digests *registry.TagDigests
+		if digests, err = registry.ListTagDigests(context.Background(), client, tr.catalogPrefix(version), referenceCountConcurrency); err == nil {
+			return digests
+		}
+	}
+	logging.Printf("  │ Warning: Failed to list the registry tags: %v\n", err)
+	return nil
+}
+
+// countReferences lists the tags again after the upload and counts those it
+// created or moved
+func (tr *TestRunner) countReferences(version string, before *registry.TagDigests) *ReferenceCounts {
+	if before == nil {
+		return nil
+	}
+	after := tr.listTagDigests(version)
+	if after == nil {
+		return nil
+	}
+
+	counts := &ReferenceCounts{
+		TagsAfter:      len(after.Digests),
+		ManifestsAfter: after.Manifests(),
+		Errors:         truncateList(append(before.Errors, after.Errors...), maxListedDigests),
+		Duration:       before.Duration + after.Duration,
+	}
+	added := make(map[string]bool)
+	for tag, digest := range after.Digests {
+		if before.Digests[tag] != digest {
+			counts.TagsAdded++
+			added[digest] = true
+		}
+	}
+	counts.ManifestsAdded = len(added)
+	logging.Printf("  │ Registry References: %d tag(s) added pointing to %d manifest(s) (%d tags, %d manifests in total)\n",
+		counts.TagsAdded, counts.ManifestsAdded, counts.TagsAfter, counts.ManifestsAfter)
+	return counts
+}
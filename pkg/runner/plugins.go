@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/plugin"
+)
+
+// pluginEnv returns the run context passed to plugins
+func (tr *TestRunner) pluginEnv() map[string]string {
+	env := map[string]string{plugin.EnvRegistry: tr.targetRegistry()}
+	if tr.phase.Phase != "" {
+		env[plugin.EnvPhase] = tr.phase.Phase
+		env[plugin.EnvVersion] = tr.phase.Version
+		env[plugin.EnvIteration] = strconv.Itoa(tr.phase.Iteration)
+	}
+	return env
+}
+
+// startMonitorPlugins starts the monitor plugins for the current phase. A
+// plugin that fails to start is kept, so its error is recorded with the phase.
+func (tr *TestRunner) startMonitorPlugins() []*plugin.Monitor {
+	var monitors []*plugin.Monitor
+	for _, spec := range tr.config.Plugins.Monitors {
+		m := plugin.NewMonitor(spec, tr.pluginEnv())
+		tr.observe(m, MonitorPlugin+spec.Name)
+		if err := m.Start(); err != nil {
+			logging.Printf("  │ Warning: %v\n", err)
+		}
+		monitors = append(monitors, m)
+	}
+	return monitors
+}
+
+// stopMonitorPlugins stops the monitor plugins of a phase together and
+// returns what they reported
+func stopMonitorPlugins(monitors []*plugin.Monitor) []plugin.Metrics {
+	if len(monitors) == 0 {
+		return nil
+	}
+	metrics := make([]plugin.Metrics, len(monitors))
+	var wg sync.WaitGroup
+	for i, m := range monitors {
+		wg.Add(1)
+		go func(i int, m *plugin.Monitor) {
+			defer wg.Done()
+			metrics[i] = m.Stop()
+		}(i, m)
+	}
+	wg.Wait()
+	for _, m := range metrics {
+		m.PrintSummary()
+	}
+	return metrics
+}
+
+// startSinkPlugins starts the sink plugins, which receive the events of the
+// whole run
+func (tr *TestRunner) startSinkPlugins() {
+	for _, spec := range tr.config.Plugins.Sinks {
+		sink, err := plugin.StartSink(spec, tr.pluginEnv(), tr.events)
+		if err != nil {
+			logging.Printf("Warning: %v\n", err)
+			continue
+		}
+		logging.Printf("Sink plugin: %s\n", spec.Name)
+		tr.sinks = append(tr.sinks, sink)
+	}
+}
+
+// stopSinkPlugins sends the run outcome to the sink plugins and waits for
+// them to exit
+func (tr *TestRunner) stopSinkPlugins(runErr error) {
+	end := plugin.RunEnd{Status: "completed", ResultsFile: tr.resultsPath}
+	if runErr != nil {
+		end.Status = "failed"
+		end.Error = runErr.Error()
+	}
+	for _, sink := range tr.sinks {
+		if err := sink.Stop(end); err != nil {
+			logging.Printf("Warning: %v\n", err)
+		}
+	}
+	tr.sinks = nil
+}
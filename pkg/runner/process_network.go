@@ -0,0 +1,102 @@
+package runner
+
+import (
+	"net"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// Network accounting modes
+const (
+	NetworkAccountingInterface = "interface" // Interface counters; includes all host traffic
+	NetworkAccountingProcess   = "process"   // TCP traffic of the oc-mirror process tree only
+)
+
+// newProcessNetworkMonitor returns a per-process network monitor in process accounting mode, nil otherwise
+func (tr *TestRunner) newProcessNetworkMonitor() *monitor.ProcessNetworkMonitor {
+	if tr.config.GetNetworkAccounting() != NetworkAccountingProcess {
+		return nil
+	}
+	processNetworkMonitor := monitor.NewProcessNetworkMonitor()
+	tr.observe(processNetworkMonitor, sampleSourceProcessNetwork)
+	return processNetworkMonitor
+}
+
+// startProcessNetworkMonitor attaches per-process network accounting to the oc-mirror PID
+func startProcessNetworkMonitor(processNetworkMonitor *monitor.ProcessNetworkMonitor, pid int) {
+	if processNetworkMonitor == nil {
+		return
+	}
+	processNetworkMonitor.SetTargetPID(pid)
+	if err := processNetworkMonitor.Start(); err != nil {
+		logging.Printf("  │ Warning: Failed to start per-process network accounting for PID %d: %v\n", pid, err)
+	}
+}
+
+// stopProcessNetworkMonitor stops per-process network accounting and returns the metrics, if any
+func stopProcessNetworkMonitor(processNetworkMonitor *monitor.ProcessNetworkMonitor) *monitor.ProcessNetworkMetrics {
+	if processNetworkMonitor == nil || !processNetworkMonitor.IsMonitoring() {
+		return nil
+	}
+	metrics := processNetworkMonitor.Stop()
+	return &metrics
+}
+
+// applyProcessNetworkAccounting replaces interface-based network and registry metrics with
+// the traffic attributed to the oc-mirror processes of both phases
+func (tr *TestRunner) applyProcessNetworkAccounting(result *TestResult) {
+	download := result.DownloadPhase.ProcessNetworkMetrics
+	upload := result.UploadPhase.ProcessNetworkMetrics
+	if download == nil && upload == nil {
+		logging.Printf("  │ Warning: No per-process network data, keeping interface metrics\n")
+		result.NetworkAccounting = NetworkAccountingInterface
+		return
+	}
+
+	var combined monitor.NetworkMetrics
+	phases := 0
+	for _, phase := range []*monitor.ProcessNetworkMetrics{download, upload} {
+		if phase == nil {
+			continue
+		}
+		metrics := phase.ToNetworkMetrics()
+		combined.TotalBytesTransferred += metrics.TotalBytesTransferred
+		combined.Duration += metrics.Duration
+		combined.PeakBandwidthMbps = max(combined.PeakBandwidthMbps, metrics.PeakBandwidthMbps)
+		combined.AverageBandwidthMbps += metrics.AverageBandwidthMbps
+		combined.AverageRxRateMbps += metrics.AverageRxRateMbps
+		combined.AverageTxRateMbps += metrics.AverageTxRateMbps
+		phases++
+	}
+	combined.AverageBandwidthMbps /= float64(phases)
+	combined.AverageRxRateMbps /= float64(phases)
+	combined.AverageTxRateMbps /= float64(phases)
+	result.NetworkMetrics = combined
+
+	if upload == nil || tr.config.IsOCITarget() {
+		return
+	}
+
+	// Registry upload is the traffic sent to the registry port during the upload phase
+	_, port, err := net.SplitHostPort(extractRegistryAddress(tr.targetRegistry()))
+	if err != nil {
+		return
+	}
+	registryMetrics := monitor.RegistryMetrics{
+		TotalBytesUploaded: upload.TxBytesToPort(port),
+		Duration:           upload.Duration,
+		EndTime:            time.Now(),
+	}
+	registryMetrics.StartTime = registryMetrics.EndTime.Add(-upload.Duration)
+	for _, remote := range upload.Remotes {
+		if _, remotePort, err := net.SplitHostPort(remote.Address); err == nil && remotePort == port {
+			registryMetrics.ConnectionCount += remote.Connections
+		}
+	}
+	if seconds := upload.Duration.Seconds(); seconds > 0 {
+		registryMetrics.AverageUploadRateMB = float64(registryMetrics.TotalBytesUploaded) / seconds / (1024 * 1024)
+	}
+	result.RegistryMetrics = &registryMetrics
+}
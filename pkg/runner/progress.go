@@ -0,0 +1,198 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/events"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// progressRedraw is how often the live progress line is redrawn between
+// samples, keeping the elapsed time current
+const progressRedraw = time.Second
+
+// spinnerFrames animate the live progress line
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+// liveProgress rewrites one console line with the progress of the running
+// oc-mirror invocation. The line is cleared when it stops, so the phase
+// summary printed afterwards reads the same as without it.
+type liveProgress struct {
+	label string
+	start time.Time
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// progressState is the progress shown on the line
+type progressState struct {
+	bytes int64
+	rate  float64 // MB/s
+	extra string
+}
+
+// liveProgressEnabled returns whether the live progress line is shown: on a
+// terminal with the interactive console output, unless disabled for CI logs
+func (tr *TestRunner) liveProgressEnabled() bool {
+	if tr.config.NoTUI || !logging.Interactive() || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// startDownloadProgress shows the mirror directory growth reported on the
+// progress channel of a download monitor; call the returned function before
+// the monitor stops
+func (tr *TestRunner) startDownloadProgress(version string, downloadMonitor *monitor.DownloadMonitor) func() {
+	if !tr.liveProgressEnabled() {
+		return func() {}
+	}
+	updates := downloadMonitor.GetProgressChannel()
+	p := newLiveProgress("download " + version)
+	go p.run(func() (progressState, bool) {
+		select {
+		case update, ok := <-updates:
+			return progressState{
+				bytes: update.TotalBytes,
+				rate:  update.CurrentRateMBs,
+				extra: fmt.Sprintf("avg %.1f MB/s  %d files", update.AverageRateMBs, update.FileCount) + downloadETA(update),
+			}, ok
+		case <-p.stop:
+			return progressState{}, false
+		}
+	})
+	return p.finish
+}
+
+// downloadETA formats the share of the expected size and the time remaining,
+// e.g. "  42% of 14.20 GB  ETA 6m10s"; empty without an estimate
+func downloadETA(update monitor.DownloadProgress) string {
+	if update.ExpectedBytes <= 0 {
+		return ""
+	}
+	eta := fmt.Sprintf("  %.0f%% of %s", update.PercentDone, monitor.FormatBytesHuman(update.ExpectedBytes))
+	if update.ETA > 0 {
+		eta += "  ETA " + update.ETA.String()
+	}
+	return eta
+}
+
+// startUploadProgress shows the bytes sent to the registry, or written to
+// the OCI layout, from the samples published on the event bus
+func (tr *TestRunner) startUploadProgress(version string) func() {
+	if !tr.liveProgressEnabled() {
+		return func() {}
+	}
+	samples, unsubscribe := tr.events.Subscribe(64)
+	p := newLiveProgress("upload " + version)
+	var state progressState
+	var layoutBaseline int64 = -1
+	go p.run(func() (progressState, bool) {
+		select {
+		case event, ok := <-samples:
+			if !ok || event.Type != events.TypeSample {
+				return state, ok
+			}
+			switch sample := event.Data.(type) {
+			case monitor.RegistrySample:
+				state.bytes += sample.BytesDelta
+				state.rate = sample.UploadRateMB
+				state.extra = fmt.Sprintf("%d connections", sample.Connections)
+			case monitor.DiskWriteSample:
+				if layoutBaseline < 0 {
+					layoutBaseline = sample.TotalBytes
+				}
+				state.bytes = sample.TotalBytes - layoutBaseline
+				state.rate = sample.WriteRate
+				state.extra = fmt.Sprintf("%d files", sample.FileCount)
+			}
+			return state, true
+		case <-p.stop:
+			return state, false
+		}
+	})
+	return func() {
+		p.finish()
+		unsubscribe()
+	}
+}
+
+func newLiveProgress(label string) *liveProgress {
+	return &liveProgress{
+		label: label,
+		start: time.Now(),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// run redraws the line until feed returns false. feed blocks for the next
+// update; redraws in between come from a ticker of its own.
+func (p *liveProgress) run(feed func() (progressState, bool)) {
+	defer close(p.done)
+	updates := make(chan progressState)
+	go func() {
+		defer close(updates)
+		for {
+			state, ok := feed()
+			if !ok {
+				return
+			}
+			select {
+			case updates <- state:
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(progressRedraw)
+	defer ticker.Stop()
+	var state progressState
+	frame := 0
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				logging.Print("\r\033[K")
+				return
+			}
+			state = update
+		case <-ticker.C:
+			frame++
+		case <-p.stop:
+			logging.Print("\r\033[K")
+			return
+		}
+		logging.Print("\r\033[K" + p.line(state, spinnerFrames[frame%len(spinnerFrames)]))
+	}
+}
+
+// line formats the progress, e.g. "  │ ⠋ download v2  12m3s  14.20 GB  87.3 MB/s  avg 64.1 MB/s  1234 files  42% of 33.80 GB  ETA 16m40s"
+func (p *liveProgress) line(state progressState, spinner rune) string {
+	parts := []string{
+		fmt.Sprintf("  │ %c %s", spinner, p.label),
+		time.Since(p.start).Round(time.Second).String(),
+		monitor.FormatBytesHuman(state.bytes),
+		fmt.Sprintf("%.1f MB/s", state.rate),
+	}
+	if state.extra != "" {
+		parts = append(parts, state.extra)
+	}
+	return strings.Join(parts, "  ")
+}
+
+// finish clears the line; the phase summary follows
+func (p *liveProgress) finish() {
+	select {
+	case <-p.stop:
+	default:
+		close(p.stop)
+	}
+	<-p.done
+}
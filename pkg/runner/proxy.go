@@ -0,0 +1,308 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// Proxy modes of an iteration
+const (
+	ProxyModeProxy  = "proxy"  // oc-mirror goes through the configured proxy
+	ProxyModeDirect = "direct" // Proxy variables cleared, including inherited ones
+)
+
+// proxyEnvKeys are the variables oc-mirror reads its proxy from, in both cases
+var proxyEnvKeys = []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"}
+
+// ProxyConfig routes oc-mirror through an HTTP(S) proxy, as at sites that
+// mirror through a corporate proxy. With Compare, the iterations run once
+// through the proxy and once direct.
+type ProxyConfig struct {
+	HTTPProxy  string `json:"http_proxy,omitempty" yaml:"httpProxy,omitempty"`
+	HTTPSProxy string `json:"https_proxy,omitempty" yaml:"httpsProxy,omitempty"`
+	NoProxy    string `json:"no_proxy,omitempty" yaml:"noProxy,omitempty"` // Hosts reached directly, e.g. the target registry
+	Compare    bool   `json:"compare,omitempty" yaml:"compare,omitempty"`
+}
+
+// ProxySettings records the proxy an iteration ran with. Proxy credentials are redacted.
+type ProxySettings struct {
+	Mode       string `json:"mode"` // proxy or direct
+	HTTPProxy  string `json:"http_proxy,omitempty"`
+	HTTPSProxy string `json:"https_proxy,omitempty"`
+	NoProxy    string `json:"no_proxy,omitempty"`
+}
+
+// ProxySummary aggregates the download metrics of one proxy mode in a comparison
+type ProxySummary struct {
+	Mode               string        `json:"mode"`
+	Iterations         int           `json:"iterations"`
+	Failed             int           `json:"failed"`
+	CleanDownloadTime  time.Duration `json:"clean_download_time_seconds"`
+	CachedDownloadTime time.Duration `json:"cached_download_time_seconds"` // Average over cached iterations
+	BytesDownloaded    int64         `json:"bytes_downloaded"`             // Clean iteration
+	AverageSpeedMBs    float64       `json:"average_speed_mbs"`            // Clean iteration
+	Errors             int           `json:"errors"`
+	Retries            int           `json:"retries"`
+}
+
+// Enabled returns true if a proxy is configured
+func (c ProxyConfig) Enabled() bool {
+	return c.HTTPProxy != "" || c.HTTPSProxy != ""
+}
+
+// Validate checks the proxy URLs
+func (c ProxyConfig) Validate() error {
+	for _, proxy := range []string{c.HTTPProxy, c.HTTPSProxy} {
+		if proxy == "" {
+			continue
+		}
+		u, err := url.Parse(proxy)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid proxy URL %q (expected e.g. http://proxy.example.com:3128)", redactProxy(proxy))
+		}
+	}
+	if c.NoProxy != "" && !c.Enabled() {
+		return fmt.Errorf("no-proxy requires an HTTP or HTTPS proxy")
+	}
+	if c.Compare && !c.Enabled() {
+		return fmt.Errorf("the proxy comparison requires an HTTP or HTTPS proxy")
+	}
+	return nil
+}
+
+// String returns a human-readable description of the proxy, without credentials
+func (c ProxyConfig) String() string {
+	var parts []string
+	if c.HTTPProxy != "" {
+		parts = append(parts, "http "+redactProxy(c.HTTPProxy))
+	}
+	if c.HTTPSProxy != "" {
+		parts = append(parts, "https "+redactProxy(c.HTTPSProxy))
+	}
+	if c.NoProxy != "" {
+		parts = append(parts, "except "+c.NoProxy)
+	}
+	s := strings.Join(parts, ", ")
+	if c.Compare {
+		s += " (compared with direct)"
+	}
+	return s
+}
+
+// ValidateProxyComparison checks that the proxy comparison can run with the rest of the configuration
+func (c *Config) ValidateProxyComparison() error {
+	if err := c.Proxy.Validate(); err != nil {
+		return err
+	}
+	if !c.Proxy.Compare {
+		return nil
+	}
+	switch {
+	case c.CompareV1V2:
+		return fmt.Errorf("the proxy comparison cannot be combined with the v1/v2 comparison")
+	case c.IsRegistryComparison():
+		return fmt.Errorf("the proxy comparison cannot be combined with registry comparison")
+	case len(c.Stages) > 0:
+		return fmt.Errorf("the proxy comparison cannot be combined with stages")
+	case c.UpdateContent != nil:
+		return fmt.Errorf("the proxy comparison cannot be combined with the day-2 update")
+	}
+	return nil
+}
+
+// redactProxy hides the password of a proxy URL
+func redactProxy(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return proxy
+	}
+	return u.Redacted()
+}
+
+// proxyEnv returns the proxy variables oc-mirror runs with: those set in the
+// configuration, overriding inherited values, or all of them cleared in the
+// direct leg of a comparison. Without a proxy the environment is inherited.
+func (tr *TestRunner) proxyEnv() []string {
+	if !tr.config.Proxy.Enabled() {
+		return nil
+	}
+	values := map[string]string{
+		"HTTP_PROXY":  tr.config.Proxy.HTTPProxy,
+		"HTTPS_PROXY": tr.config.Proxy.HTTPSProxy,
+		"NO_PROXY":    tr.config.Proxy.NoProxy,
+	}
+	var env []string
+	for _, key := range proxyEnvKeys {
+		if tr.proxyMode == ProxyModeDirect {
+			env = append(env, key+"=")
+		} else if value := values[strings.ToUpper(key)]; value != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
+// proxySettings returns the proxy the current iteration runs with, nil without a proxy
+func (tr *TestRunner) proxySettings() *ProxySettings {
+	if !tr.config.Proxy.Enabled() {
+		return nil
+	}
+	if tr.proxyMode == ProxyModeDirect {
+		return &ProxySettings{Mode: ProxyModeDirect}
+	}
+	return &ProxySettings{
+		Mode:       ProxyModeProxy,
+		HTTPProxy:  redactProxy(tr.config.Proxy.HTTPProxy),
+		HTTPSProxy: redactProxy(tr.config.Proxy.HTTPSProxy),
+		NoProxy:    tr.config.Proxy.NoProxy,
+	}
+}
+
+// runProxyComparison runs the iterations through the proxy, then again
+// direct. Each leg starts from an empty cache, so both clean downloads fetch
+// the whole content.
+func (tr *TestRunner) runProxyComparison() error {
+	logging.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
+	logging.Printf("║              Proxy Comparison Test                            ║\n")
+	logging.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+
+	for _, mode := range []string{ProxyModeProxy, ProxyModeDirect} {
+		tr.proxyMode = mode
+		logging.Printf("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+		logging.Printf("Running Tests %s\n", map[string]string{ProxyModeProxy: "Through the Proxy", ProxyModeDirect: "Without the Proxy"}[mode])
+		logging.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+		// A leg resumed after its clean iteration keeps the cache it filled
+		if !tr.completed(tr.iterationKey(1, "v2")) {
+			if err := os.RemoveAll(tr.cacheDir("v2")); err != nil {
+				return fmt.Errorf("failed to clear the cache before the %s leg: %w", mode, err)
+			}
+		}
+		if err := tr.runWarmups("v2"); err != nil {
+			return err
+		}
+
+		for i := 0; i < tr.config.Iterations; i++ {
+			isCleanRun := tr.config.cleanIteration(i+1, i == 0)
+			logging.Printf("\n[%s] Iteration %d/%d (%s)\n", mode, i+1, tr.config.Iterations, runKind(isCleanRun, false))
+			key := tr.iterationKey(i+1, "v2")
+			if tr.skipCompleted(key) {
+				continue
+			}
+
+			result, err := tr.runIteration(i+1, isCleanRun, "v2")
+			if err != nil && !tr.recordFailedIteration(&result, err) {
+				return fmt.Errorf("%s iteration %d failed: %w", mode, i+1, err)
+			}
+			tr.evaluateGates(&result)
+			tr.publishIteration(result)
+			tr.results = append(tr.results, result)
+			if !result.Failed {
+				tr.printIterationSummary(result)
+			}
+
+			// Save results incrementally after each iteration
+			if err := tr.saveResults(); err != nil {
+				logging.Printf("Warning: Failed to save results incrementally: %v\n", err)
+			}
+			tr.markCompleted(key)
+		}
+	}
+
+	logging.Summary(func() {
+		printProxyComparison(SummarizeProxy(tr.results))
+		printStatistics(SummarizeStatistics(tr.results))
+	})
+
+	// Final save (in case of any updates)
+	if err := tr.saveResults(); err != nil {
+		return fmt.Errorf("failed to save results: %w", err)
+	}
+
+	return errors.Join(failedIterationsError(tr.results), gateFailuresError(tr.results))
+}
+
+// SummarizeProxy groups results by proxy mode, in first-seen order
+func SummarizeProxy(results []TestResult) []ProxySummary {
+	var summaries []ProxySummary
+	index := make(map[string]int)
+	cachedRuns := make(map[string]int)
+
+	for _, r := range results {
+		if r.Proxy == nil || r.Warmup {
+			continue
+		}
+		i, ok := index[r.Proxy.Mode]
+		if !ok {
+			i = len(summaries)
+			index[r.Proxy.Mode] = i
+			summaries = append(summaries, ProxySummary{Mode: r.Proxy.Mode})
+		}
+		s := &summaries[i]
+		s.Iterations++
+		if r.Failed {
+			s.Failed++
+			continue
+		}
+
+		if r.IsCleanRun {
+			s.CleanDownloadTime = r.DownloadPhase.WallTime
+			s.BytesDownloaded = r.DownloadPhase.DownloadMetrics.TotalBytesDownloaded
+			s.AverageSpeedMBs = r.DownloadPhase.DownloadMetrics.AverageSpeedMBs
+		} else {
+			s.CachedDownloadTime += r.DownloadPhase.WallTime
+			cachedRuns[r.Proxy.Mode]++
+		}
+		s.Errors += r.DownloadPhase.ExtendedMetrics.ErrorCount
+		s.Retries += r.DownloadPhase.ExtendedMetrics.RetryCount
+	}
+
+	for i := range summaries {
+		if n := cachedRuns[summaries[i].Mode]; n > 0 {
+			summaries[i].CachedDownloadTime /= time.Duration(n)
+		}
+	}
+	return summaries
+}
+
+// printProxyComparison prints one row per proxy mode, with the clean
+// download time through the proxy relative to the direct one
+func printProxyComparison(summaries []ProxySummary) {
+	if len(summaries) < 2 {
+		return
+	}
+
+	var direct time.Duration
+	for _, s := range summaries {
+		if s.Mode == ProxyModeDirect {
+			direct = s.CleanDownloadTime
+		}
+	}
+
+	logging.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
+	logging.Printf("║  Comparison: Proxy vs Direct (download)                       ║\n")
+	logging.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+	logging.Printf("%-8s %-7s %-12s %-12s %-10s %-10s %-8s %s\n",
+		"MODE", "OK", "CLEAN", "CACHED AVG", "BYTES", "AVG MB/s", "RETRIES", "VS DIRECT")
+	for _, s := range summaries {
+		relative := "-"
+		if s.Mode != ProxyModeDirect && s.CleanDownloadTime > 0 && direct > 0 {
+			relative = fmt.Sprintf("%+.1f%%", float64(s.CleanDownloadTime-direct)/float64(direct)*100)
+		}
+		logging.Printf("%-8s %-7s %-12s %-12s %-10s %-10.2f %-8d %s\n",
+			s.Mode,
+			fmt.Sprintf("%d/%d", s.Iterations-s.Failed, s.Iterations),
+			formatDuration(s.CleanDownloadTime),
+			formatDuration(s.CachedDownloadTime),
+			monitor.FormatBytesHuman(s.BytesDownloaded),
+			s.AverageSpeedMBs,
+			s.Retries,
+			relative)
+	}
+}
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+const (
+	// pullThroughNamespace holds the CatalogSources of the pull-through check,
+	// where the package server of the cluster picks them up
+	pullThroughNamespace = "openshift-marketplace"
+	// defaultPullThroughTimeout covers the catalog pod pull and start
+	defaultPullThroughTimeout = 10 * time.Minute
+)
+
+// PullThroughConfig enables the pull-through check after each upload: a
+// CatalogSource for each mirrored catalog is created on the cluster of the
+// kubeconfig and the mirrored packages must resolve as package manifests
+type PullThroughConfig struct {
+	Enabled bool          `json:"enabled" yaml:"enabled,omitempty"`
+	Timeout time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// GetTimeout returns the check timeout, defaulting to defaultPullThroughTimeout
+func (c PullThroughConfig) GetTimeout() time.Duration {
+	if c.Timeout <= 0 {
+		return defaultPullThroughTimeout
+	}
+	return c.Timeout
+}
+
+// ValidatePullThrough checks that the pull-through check has a cluster and a registry to pull from
+func (c *Config) ValidatePullThrough() error {
+	v := c.PullThrough
+	if !v.Enabled {
+		if v.Timeout != 0 {
+			return fmt.Errorf("a pull-through timeout requires the pull-through check to be enabled")
+		}
+		return nil
+	}
+	if c.Kubeconfig == "" {
+		return fmt.Errorf("the pull-through check requires a kubeconfig")
+	}
+	if c.IsOCITarget() {
+		return fmt.Errorf("the pull-through check needs a registry target the cluster can pull from")
+	}
+	if v.Timeout < 0 {
+		return fmt.Errorf("pull-through timeout must not be negative")
+	}
+	return nil
+}
+
+// pullThroughCatalogs returns the operator catalogs of the mirrored content
+// with the names of their packages
+func (tr *TestRunner) pullThroughCatalogs() []command.PullThroughCatalog {
+	content := tr.mirroredContent()
+	if content == nil {
+		return nil
+	}
+	catalogs, err := content.OperatorCatalogs()
+	if err != nil {
+		return nil
+	}
+	var expected []command.PullThroughCatalog
+	for _, catalog := range catalogs {
+		entry := command.PullThroughCatalog{Catalog: catalog.Catalog}
+		for _, pkg := range catalog.Packages {
+			entry.Packages = append(entry.Packages, pkg.Name)
+		}
+		expected = append(expected, entry)
+	}
+	return expected
+}
+
+// verifyPullThrough checks that the cluster can consume the catalogs this
+// upload mirrored. A mirror that is not consumable is recorded but does not
+// fail the iteration.
+func (tr *TestRunner) verifyPullThrough(result *TestResult) *command.PullThroughReport {
+	if !tr.config.PullThrough.Enabled {
+		return nil
+	}
+	resources := result.UploadPhase.ClusterResources
+	if resources == nil || resources.Kinds["CatalogSource"] == 0 {
+		logging.Printf("  │ Warning: No mirrored catalog to check on the cluster\n")
+		return nil
+	}
+
+	report, err := command.VerifyPullThrough(command.PullThroughOptions{
+		Kubeconfig: tr.config.Kubeconfig,
+		Namespace:  pullThroughNamespace,
+		Catalogs:   tr.pullThroughCatalogs(),
+		Timeout:    tr.config.PullThrough.GetTimeout(),
+	}, resources)
+	if err != nil {
+		logging.Printf("  │ Warning: Pull-through check failed: %v\n", err)
+		return nil
+	}
+	report.PrintSummary()
+	return report
+}
@@ -0,0 +1,288 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// Registry comparison orders
+const (
+	RegistryOrderSequential = "sequential"  // All iterations against one registry, then the next
+	RegistryOrderRoundRobin = "round-robin" // Each iteration pushes to every registry in turn
+)
+
+// RegistrySummary aggregates the upload metrics of one registry in a comparison
+type RegistrySummary struct {
+	Registry         string        `json:"registry"`
+	Iterations       int           `json:"iterations"`
+	Failed           int           `json:"failed"`
+	CleanUploadTime  time.Duration `json:"clean_upload_time_seconds"`
+	CachedUploadTime time.Duration `json:"cached_upload_time_seconds"` // Average over cached iterations
+	BytesUploaded    int64         `json:"bytes_uploaded"`             // Clean iteration
+	AverageUploadMBs float64       `json:"average_upload_mbs"`         // Bytes uploaded over upload time, all completed iterations
+	PeakUploadMBs    float64       `json:"peak_upload_mbs"`            // From the registry monitor
+	Errors           int           `json:"errors"`
+	Retries          int           `json:"retries"`
+}
+
+// TargetRegistries returns every registry receiving the content: the primary
+// registry followed by the comparison registries
+func (c *Config) TargetRegistries() []string {
+	return append([]string{c.RegistryURL}, c.CompareRegistries...)
+}
+
+// IsRegistryComparison returns true if the content is pushed to more than one registry
+func (c *Config) IsRegistryComparison() bool {
+	return len(c.CompareRegistries) > 0
+}
+
+// GetRegistryOrder returns the registry comparison order, defaulting to sequential
+func (c *Config) GetRegistryOrder() string {
+	if c.RegistryOrder == "" {
+		return RegistryOrderSequential
+	}
+	return c.RegistryOrder
+}
+
+// ValidateRegistryComparison checks the comparison registries and order
+func (c *Config) ValidateRegistryComparison() error {
+	switch c.RegistryOrder {
+	case "", RegistryOrderSequential, RegistryOrderRoundRobin:
+	default:
+		return fmt.Errorf("unknown registry order %q (valid: %s, %s)", c.RegistryOrder, RegistryOrderSequential, RegistryOrderRoundRobin)
+	}
+	if !c.IsRegistryComparison() {
+		return nil
+	}
+	if c.CompareV1V2 {
+		return fmt.Errorf("registry comparison cannot be combined with v1/v2 comparison")
+	}
+	seen := make(map[string]bool)
+	for _, registry := range c.TargetRegistries() {
+		if strings.HasPrefix(registry, "oci://") {
+			return fmt.Errorf("registry comparison needs registry targets, got %s", registry)
+		}
+		key := strings.TrimRight(strings.TrimPrefix(registry, "docker://"), "/")
+		if seen[key] {
+			return fmt.Errorf("registry %s is listed more than once", registry)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// targetRegistry returns the registry the current iteration pushes to
+func (tr *TestRunner) targetRegistry() string {
+	if tr.registryURL == "" {
+		return tr.config.RegistryURL
+	}
+	return tr.registryURL
+}
+
+// useRegistry switches uploads to registryURL and restarts the registry monitor,
+// so registry metrics of each iteration only cover that registry
+func (tr *TestRunner) useRegistry(registryURL string) {
+	if tr.registryMonitor != nil && tr.registryMonitor.IsMonitoring() {
+		tr.registryMonitor.Stop()
+	}
+	tr.registryURL = registryURL
+	if err := tr.startRegistryMonitor(); err != nil {
+		logging.Printf("Warning: Failed to start registry monitor: %v\n", err)
+	}
+}
+
+func (tr *TestRunner) runRegistryComparison() error {
+	registries := tr.config.TargetRegistries()
+	order := tr.config.GetRegistryOrder()
+
+	logging.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
+	logging.Printf("║              Registry Comparison Test                          ║\n")
+	logging.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+	logging.Printf("Order: %s\n", order)
+	for i, registry := range registries {
+		logging.Printf("  %d. %s\n", i+1, registry)
+	}
+
+	runOne := func(registry string, i int) error {
+		tr.useRegistry(registry)
+		isCleanRun := tr.config.cleanIteration(i+1, i == 0)
+		host := extractRegistryAddress(registry)
+		logging.Printf("\n[%s] Iteration %d/%d (%s)\n", host, i+1, tr.config.Iterations, map[bool]string{true: "CLEAN", false: "CACHED"}[isCleanRun])
+		key := tr.iterationKey(i+1, "v2")
+		if tr.skipCompleted(key) {
+			return nil
+		}
+
+		result, err := tr.runIteration(i+1, isCleanRun, "v2")
+		if err != nil && !tr.recordFailedIteration(&result, err) {
+			return fmt.Errorf("%s iteration %d failed: %w", host, i+1, err)
+		}
+		tr.evaluateGates(&result)
+		tr.publishIteration(result)
+		tr.results = append(tr.results, result)
+		if !result.Failed {
+			tr.printIterationSummary(result)
+		}
+
+		// Save results incrementally after each iteration
+		if err := tr.saveResults(); err != nil {
+			logging.Printf("Warning: Failed to save results incrementally: %v\n", err)
+		}
+		tr.markCompleted(key)
+		return nil
+	}
+
+	warmup := func(registry string) error {
+		tr.useRegistry(registry)
+		return tr.runWarmups("v2")
+	}
+
+	if order == RegistryOrderRoundRobin {
+		for _, registry := range registries {
+			if err := warmup(registry); err != nil {
+				return err
+			}
+		}
+		// Interleaving spreads time-dependent effects (shared links, other tenants) across registries
+		for i := 0; i < tr.config.Iterations; i++ {
+			for _, registry := range registries {
+				if err := runOne(registry, i); err != nil {
+					return err
+				}
+			}
+		}
+	} else {
+		for _, registry := range registries {
+			logging.Printf("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+			logging.Printf("Running Tests Against %s\n", registry)
+			logging.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+			if err := warmup(registry); err != nil {
+				return err
+			}
+			for i := 0; i < tr.config.Iterations; i++ {
+				if err := runOne(registry, i); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	logging.Summary(func() {
+		printRegistryComparison(SummarizeRegistries(tr.results))
+		printStatistics(SummarizeStatistics(tr.results))
+	})
+
+	// Final save (in case of any updates)
+	if err := tr.saveResults(); err != nil {
+		return fmt.Errorf("failed to save results: %w", err)
+	}
+
+	return errors.Join(failedIterationsError(tr.results), gateFailuresError(tr.results))
+}
+
+// SummarizeRegistries groups results by target registry, in first-seen order
+func SummarizeRegistries(results []TestResult) []RegistrySummary {
+	var summaries []RegistrySummary
+	index := make(map[string]int)
+	cachedRuns := make(map[string]int)
+	uploadSeconds := make(map[string]float64)
+	uploadBytes := make(map[string]int64)
+
+	for _, r := range results {
+		if r.Warmup {
+			continue
+		}
+		i, ok := index[r.Registry]
+		if !ok {
+			i = len(summaries)
+			index[r.Registry] = i
+			summaries = append(summaries, RegistrySummary{Registry: r.Registry})
+		}
+		s := &summaries[i]
+		s.Iterations++
+		if r.Failed {
+			s.Failed++
+			continue
+		}
+
+		if r.IsCleanRun {
+			s.CleanUploadTime = r.UploadPhase.WallTime
+			s.BytesUploaded = r.UploadPhase.BytesUploaded
+		} else {
+			s.CachedUploadTime += r.UploadPhase.WallTime
+			cachedRuns[r.Registry]++
+		}
+		uploadSeconds[r.Registry] += r.UploadPhase.WallTime.Seconds()
+		uploadBytes[r.Registry] += r.UploadPhase.BytesUploaded
+		if r.RegistryMetrics != nil {
+			s.PeakUploadMBs = max(s.PeakUploadMBs, r.RegistryMetrics.PeakUploadRateMB)
+		}
+		s.Errors += r.UploadPhase.ExtendedMetrics.ErrorCount
+		s.Retries += r.UploadPhase.ExtendedMetrics.RetryCount
+	}
+
+	for i := range summaries {
+		s := &summaries[i]
+		if n := cachedRuns[s.Registry]; n > 0 {
+			s.CachedUploadTime /= time.Duration(n)
+		}
+		if seconds := uploadSeconds[s.Registry]; seconds > 0 {
+			s.AverageUploadMBs = float64(uploadBytes[s.Registry]) / seconds / (1024 * 1024)
+		}
+	}
+	return summaries
+}
+
+// printRegistryComparison prints one row per registry, with clean upload time
+// relative to the fastest registry
+func printRegistryComparison(summaries []RegistrySummary) {
+	if len(summaries) < 2 {
+		return
+	}
+
+	var fastest time.Duration
+	for _, s := range summaries {
+		if s.CleanUploadTime > 0 && (fastest == 0 || s.CleanUploadTime < fastest) {
+			fastest = s.CleanUploadTime
+		}
+	}
+
+	logging.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
+	logging.Printf("║  Comparison: Registries (upload)                              ║\n")
+	logging.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+	logging.Printf("%-32s %-7s %-12s %-12s %-10s %-10s %-10s %-8s %s\n",
+		"REGISTRY", "OK", "CLEAN", "CACHED AVG", "BYTES", "AVG MB/s", "PEAK MB/s", "RETRIES", "VS FASTEST")
+	for _, s := range summaries {
+		relative := "-"
+		if s.CleanUploadTime > 0 && fastest > 0 {
+			if s.CleanUploadTime == fastest {
+				relative = "fastest"
+			} else {
+				relative = fmt.Sprintf("+%.1f%%", float64(s.CleanUploadTime-fastest)/float64(fastest)*100)
+			}
+		}
+		logging.Printf("%-32s %-7s %-12s %-12s %-10s %-10.2f %-10.2f %-8d %s\n",
+			truncateText(extractRegistryAddress(s.Registry), 32),
+			fmt.Sprintf("%d/%d", s.Iterations-s.Failed, s.Iterations),
+			formatDuration(s.CleanUploadTime),
+			formatDuration(s.CachedUploadTime),
+			monitor.FormatBytesHuman(s.BytesUploaded),
+			s.AverageUploadMBs,
+			s.PeakUploadMBs,
+			s.Retries,
+			relative)
+	}
+}
+
+// formatDuration rounds d for table output, "-" when unmeasured
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
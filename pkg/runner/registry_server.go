@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// startRegistryServerMonitor starts scraping the metrics endpoint of the
+// target registry for the upload phase, when one is configured. Uploads to
+// comparison registries are not scraped; the endpoint belongs to --registry.
+func (tr *TestRunner) startRegistryServerMonitor() *monitor.RegistryServerMonitor {
+	if !tr.config.RegistryMetrics.Enabled() || tr.config.IsOCITarget() || tr.targetRegistry() != tr.config.RegistryURL {
+		return nil
+	}
+	config := tr.config.RegistryMetrics
+	config.SkipTLS = tr.config.SkipTLS
+	serverMonitor := monitor.NewRegistryServerMonitor(config)
+	tr.observe(serverMonitor, sampleSourceRegistryServer)
+	if err := serverMonitor.Start(); err != nil {
+		logging.Printf("  │ Warning: Failed to start registry metrics scraping: %v\n", err)
+		return nil
+	}
+	return serverMonitor
+}
+
+// stopRegistryServerMonitor stops scraping and lines the server activity up
+// with the upload rate the client saw
+func (tr *TestRunner) stopRegistryServerMonitor(serverMonitor *monitor.RegistryServerMonitor) *monitor.RegistryServerMetrics {
+	if serverMonitor == nil {
+		return nil
+	}
+	metrics := serverMonitor.Stop()
+	if tr.registryMonitor != nil && tr.registryMonitor.IsMonitoring() {
+		metrics.Correlate(tr.registryMonitor.GetCurrentMetrics().Samples)
+	}
+	metrics.PrintSummary()
+	return &metrics
+}
@@ -0,0 +1,230 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+	"github.com/telco-core/ngc-495/pkg/registry"
+)
+
+// Replication topologies
+const (
+	TopologyChain    = "chain"     // Each registry is fed from the one before it
+	TopologyHubSpoke = "hub-spoke" // Every registry is fed from the primary registry
+)
+
+// ReplicationConfig replicates the uploaded content from the primary
+// registry to further registries after the upload, as in a mirror of
+// mirrors. Each hop copies the repositories with skopeo sync and is timed
+// on its own.
+type ReplicationConfig struct {
+	Registries []string `json:"registries" yaml:"registries,omitempty"`
+	Topology   string   `json:"topology,omitempty" yaml:"topology,omitempty"` // chain or hub-spoke
+}
+
+// HopMetrics represents the replication of the content from one registry to another
+type HopMetrics struct {
+	Hop                int                    `json:"hop"`
+	Source             string                 `json:"source"`
+	Destination        string                 `json:"destination"`
+	WallTime           time.Duration          `json:"wall_time_seconds"`
+	Repositories       int                    `json:"repositories"` // Replicated without error
+	Tags               int                    `json:"tags"`
+	FailedRepositories int                    `json:"failed_repositories,omitempty"`
+	NetworkMetrics     monitor.NetworkMetrics `json:"network_metrics"`
+	LogFile            string                 `json:"log_file,omitempty"`
+	Error              string                 `json:"error,omitempty"`
+}
+
+// Enabled returns true if the content is replicated after the upload
+func (c ReplicationConfig) Enabled() bool {
+	return len(c.Registries) > 0
+}
+
+// GetTopology returns the topology, defaulting to chain
+func (c ReplicationConfig) GetTopology() string {
+	if c.Topology == "" {
+		return TopologyChain
+	}
+	return c.Topology
+}
+
+// String returns a human-readable description of the hops
+func (c ReplicationConfig) String() string {
+	if c.GetTopology() == TopologyHubSpoke {
+		return fmt.Sprintf("hub-spoke to %s", strings.Join(c.Registries, ", "))
+	}
+	return fmt.Sprintf("chain through %s", strings.Join(c.Registries, " → "))
+}
+
+// ValidateReplication checks the replication registries and topology
+func (c *Config) ValidateReplication() error {
+	switch c.Replication.Topology {
+	case "", TopologyChain, TopologyHubSpoke:
+	default:
+		return fmt.Errorf("unknown replication topology %q (valid: %s, %s)", c.Replication.Topology, TopologyChain, TopologyHubSpoke)
+	}
+	if !c.Replication.Enabled() {
+		return nil
+	}
+	if c.IsOCITarget() {
+		return fmt.Errorf("replication needs a registry target")
+	}
+	if c.IsRegistryComparison() {
+		return fmt.Errorf("replication cannot be combined with registry comparison")
+	}
+	seen := map[string]bool{registryKey(c.RegistryURL): true}
+	for _, registry := range c.Replication.Registries {
+		if strings.HasPrefix(registry, "oci://") {
+			return fmt.Errorf("replication needs registry targets, got %s", registry)
+		}
+		key := registryKey(registry)
+		if seen[key] {
+			return fmt.Errorf("registry %s is listed more than once", registry)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// registryKey returns a registry URL without scheme and trailing slash, for comparisons
+func registryKey(registryURL string) string {
+	return strings.TrimRight(strings.TrimPrefix(registryURL, "docker://"), "/")
+}
+
+// registryHost returns the host[:port] of a registry URL as given, for skopeo references
+func registryHost(registryURL string) string {
+	host, _, _ := strings.Cut(registryKey(registryURL), "/")
+	return host
+}
+
+// replicationSource returns the registry and repository prefix hop i copies
+// from: the primary registry for the first hop and in hub-spoke, else the
+// registry of the hop before
+func (tr *TestRunner) replicationSource(hop int, version string) (string, string) {
+	if hop == 0 || tr.config.Replication.GetTopology() == TopologyHubSpoke {
+		return tr.targetRegistry(), tr.catalogPrefix(version)
+	}
+	previous := tr.config.Replication.Registries[hop-1]
+	return previous, registryPath(previous)
+}
+
+// runReplication replicates the uploaded content to the replication
+// registries, one hop after another. A chain stops at the first hop that
+// did not replicate everything, as the hops after it would copy less.
+func (tr *TestRunner) runReplication(iterationNum int, version string) ([]HopMetrics, error) {
+	if err := command.SkopeoAvailable(); err != nil {
+		return nil, err
+	}
+	var hops []HopMetrics
+	var errs []error
+	for i, destination := range tr.config.Replication.Registries {
+		source, prefix := tr.replicationSource(i, version)
+		tr.setPhase("replicate", version, iterationNum)
+		logging.Printf("\n  ┌─ Replication Hop %d (%s → %s) ───────────────────────┐\n",
+			i+1, registryHost(source), registryHost(destination))
+		hop := tr.replicateHop(i+1, source, prefix, destination,
+			tr.phaseLogFile(iterationNum, version, fmt.Sprintf("replicate%d", i+1), 1))
+		logging.Printf("  └─────────────────────────────────────────────────────────────┘\n")
+		hops = append(hops, hop)
+
+		if hop.Error != "" {
+			errs = append(errs, fmt.Errorf("hop %d to %s: %s", hop.Hop, destination, hop.Error))
+			if tr.config.Replication.GetTopology() == TopologyChain {
+				break
+			}
+		}
+	}
+	return hops, errors.Join(errs...)
+}
+
+// replicateHop copies every repository under prefix of source to
+// destination, keeping the repository paths below the prefix
+func (tr *TestRunner) replicateHop(hopNum int, source, prefix, destination, logFile string) HopMetrics {
+	hop := HopMetrics{Hop: hopNum, Source: source, Destination: destination, LogFile: logFile}
+
+	client, err := registry.NewClient(source, "", tr.config.SkipTLS)
+	if err != nil {
+		hop.Error = fmt.Sprintf("failed to connect to source registry: %v", err)
+		logging.Printf("  │ Error: %s\n", hop.Error)
+		return hop
+	}
+	snapshot, err := registry.SnapshotCatalog(context.Background(), client, prefix)
+	if err != nil {
+		hop.Error = fmt.Sprintf("failed to list the source repositories: %v", err)
+		logging.Printf("  │ Error: %s\n", hop.Error)
+		return hop
+	}
+	if len(snapshot.Tags) == 0 {
+		hop.Error = "no repositories to replicate"
+		logging.Printf("  │ Error: %s under %q\n", hop.Error, prefix)
+		return hop
+	}
+
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		hop.Error = fmt.Sprintf("failed to create log directory: %v", err)
+		return hop
+	}
+	log, err := os.Create(logFile)
+	if err != nil {
+		hop.Error = fmt.Sprintf("failed to create log file: %v", err)
+		return hop
+	}
+	defer log.Close()
+
+	repositories := make([]string, 0, len(snapshot.Tags))
+	for repository := range snapshot.Tags {
+		repositories = append(repositories, repository)
+	}
+	sort.Strings(repositories)
+	logging.Printf("  │ Repositories: %d under %s/%s\n", len(repositories), registryHost(source), prefix)
+
+	networkMonitor := monitor.NewNetworkMonitor()
+	tr.observe(networkMonitor, sampleSourceNetwork)
+	if err := networkMonitor.Start(); err != nil {
+		logging.Printf("  │ Warning: Failed to start network monitoring: %v\n", err)
+	}
+
+	sourceHost, destinationHost := registryHost(source), registryHost(destination)
+	destinationPath := registryPath(destination)
+	var firstErr error
+	startTime := time.Now()
+	for _, repository := range repositories {
+		relative := strings.TrimPrefix(strings.TrimPrefix(repository, prefix), "/")
+		// skopeo creates the last path component of the source under the destination
+		target := path.Join(destinationHost, destinationPath, path.Dir(relative))
+		err := command.SkopeoSync(sourceHost+"/"+repository, target, tr.config.SkipTLS, log)
+		if err != nil {
+			hop.FailedRepositories++
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		hop.Repositories++
+		hop.Tags += snapshot.Tags[repository]
+	}
+	hop.WallTime = time.Since(startTime)
+	hop.NetworkMetrics = networkMonitor.Stop()
+
+	if firstErr != nil {
+		hop.Error = fmt.Sprintf("%d of %d repositories failed, first: %v (log: %s)",
+			hop.FailedRepositories, len(repositories), firstErr, logFile)
+		logging.Printf("  │ Warning: %s\n", hop.Error)
+	}
+	logging.Printf("  │ Replicated: %d repositories, %d tags in %v\n", hop.Repositories, hop.Tags, hop.WallTime.Round(time.Millisecond))
+	logging.Printf("  │ Network: Avg %.2f Mbps | Peak %.2f Mbps | %s\n",
+		hop.NetworkMetrics.AverageBandwidthMbps, hop.NetworkMetrics.PeakBandwidthMbps,
+		monitor.FormatBytesHuman(hop.NetworkMetrics.TotalBytesTransferred))
+	return hop
+}
@@ -28,8 +28,12 @@ func (tr *TestResult) GetAverageSpeedMBs() float64 {
 	return 0
 }
 
-// GetCacheEfficiency returns cache hit ratio
+// GetCacheEfficiency returns cache hit ratio, inferred from the cache growth
+// against the clean run when measured
 func (tr *TestResult) GetCacheEfficiency() float64 {
+	if m := tr.DownloadPhase.CacheMetrics; m != nil && m.HitRatio != nil {
+		return *m.HitRatio
+	}
 	totalOps := tr.DownloadPhase.CacheHits + tr.DownloadPhase.ImagesSkipped
 	if totalOps > 0 {
 		return float64(tr.DownloadPhase.CacheHits) / float64(totalOps)
@@ -55,7 +59,7 @@ func (tr *TestResult) GetSuccessRate() float64 {
 func (tr *TestResult) Format() string {
 	return fmt.Sprintf("Iteration %d (%s, %s): Total=%v, Downloaded=%s, Uploaded=%s, CacheHits=%d",
 		tr.Iteration,
-		map[bool]string{true: "CLEAN", false: "CACHED"}[tr.IsCleanRun],
+		runKind(tr.IsCleanRun, tr.IsUpdateRun),
 		tr.Version,
 		tr.GetTotalTime(),
 		monitor.FormatBytesHuman(tr.DownloadPhase.DownloadMetrics.TotalBytesDownloaded),
@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/environment"
+)
+
+// ResultsSchemaVersion is the layout of the results files this build writes.
+// Version 1 files are a bare JSON array of iterations; version 2 wraps the
+// iterations in an envelope describing the run; version 3 records whether
+// each iteration passed.
+const ResultsSchemaVersion = 3
+
+// ResultsHeader describes the run that produced a results file
+type ResultsHeader struct {
+	SchemaVersion   int       `json:"schema_version"`
+	ToolVersion     string    `json:"tool_version,omitempty"`
+	OCMirrorVersion string    `json:"oc_mirror_version,omitempty"`
+	ScenarioHash    string    `json:"scenario_hash,omitempty"` // sha256 of the scenario file the run was created from
+	Host            *HostInfo `json:"host,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	SamplesFile     string    `json:"samples_file,omitempty"`   // Delta-encoded sample sidecar, next to the results file
+	SamplesSHA256   string    `json:"samples_sha256,omitempty"` // sha256 of the samples sidecar, covered by the checksum of the results file
+
+	Tags  map[string]string `json:"tags,omitempty"`  // Given with --tag; later ones are in the annotations sidecar
+	Notes []Note            `json:"notes,omitempty"` // Given with --note
+
+	ToolHealth        *ToolHealth                      `json:"tool_health,omitempty"`        // Leak check after the run
+	ClusterDrift      *command.ClusterDriftReport      `json:"cluster_drift,omitempty"`      // Generated vs applied cluster resources, with --kubeconfig
+	ClusterValidation *command.ClusterValidationReport `json:"cluster_validation,omitempty"` // Image pulls from the mirror on a test cluster, with --validate-cluster
+	ContentChanges    []string                         `json:"content_changes,omitempty"`    // Update content against the initial content, in a day-2 update run
+	Statistics        []IterationStatistics            `json:"statistics,omitempty"`         // Spread of the clean and cached iterations of each group
+}
+
+// HostInfo identifies the machine the run was executed on
+type HostInfo struct {
+	Hostname      string `json:"hostname"`
+	OS            string `json:"os"`
+	Arch          string `json:"arch"`
+	KernelVersion string `json:"kernel_version,omitempty"`
+	CPUCount      int    `json:"cpu_count"`
+}
+
+// ResultsFile is the envelope written to a results file
+type ResultsFile struct {
+	ResultsHeader
+	Results []TestResult `json:"results"`
+}
+
+// resultsMigrations upgrade a results file from the schema version used as
+// key to the next one
+var resultsMigrations = map[int]func(*ResultsFile){
+	1: migrateResultsV1,
+	2: migrateResultsV2,
+}
+
+// ParseResultsFile decodes a results file of any supported schema version and
+// migrates it to ResultsSchemaVersion
+func ParseResultsFile(data []byte) (*ResultsFile, error) {
+	file := &ResultsFile{}
+	if trimmed := bytes.TrimLeft(data, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+		file.SchemaVersion = 1
+		if err := json.Unmarshal(data, &file.Results); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := json.Unmarshal(data, file); err != nil {
+			return nil, err
+		}
+		if file.SchemaVersion == 0 {
+			return nil, fmt.Errorf("missing schema_version")
+		}
+		if file.SchemaVersion > ResultsSchemaVersion {
+			return nil, fmt.Errorf("results schema version %d is newer than the supported version %d, upgrade oc-mirror-test", file.SchemaVersion, ResultsSchemaVersion)
+		}
+	}
+
+	for file.SchemaVersion < ResultsSchemaVersion {
+		migrate, ok := resultsMigrations[file.SchemaVersion]
+		if !ok {
+			return nil, fmt.Errorf("no migration from results schema version %d", file.SchemaVersion)
+		}
+		migrate(file)
+		file.SchemaVersion++
+	}
+	return file, nil
+}
+
+// migrateResultsV1 recovers the run description from the iterations, which
+// is all a version 1 file has: the host and start time of the first
+// environment snapshot
+func migrateResultsV1(file *ResultsFile) {
+	for _, r := range file.Results {
+		if r.Environment != nil {
+			file.Host = hostInfo(r.Environment)
+			file.CreatedAt = r.Environment.CapturedAt
+			return
+		}
+	}
+}
+
+// migrateResultsV2 marks the iterations of a version 2 file as passed unless
+// they failed; gates did not exist yet
+func migrateResultsV2(file *ResultsFile) {
+	for i := range file.Results {
+		file.Results[i].Passed = !file.Results[i].Failed
+	}
+}
+
+// hostInfo extracts the host identity from an environment snapshot
+func hostInfo(snapshot *environment.Snapshot) *HostInfo {
+	if snapshot == nil {
+		return nil
+	}
+	return &HostInfo{
+		Hostname:      snapshot.Hostname,
+		OS:            snapshot.OS,
+		Arch:          snapshot.Arch,
+		KernelVersion: snapshot.KernelVersion,
+		CPUCount:      snapshot.CPUCount,
+	}
+}
@@ -0,0 +1,130 @@
+package runner
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultResultsDir is where results are written unless overridden
+const DefaultResultsDir = "results"
+
+// Default oc-mirror workspace and v2 cache directories
+const (
+	DefaultWorkspaceDir = "mirror"
+	DefaultCacheDir     = "operators-v2"
+)
+
+// resultsTimestampLayout is the run start time embedded in results file names
+const resultsTimestampLayout = "20060102_150405"
+
+// unsafeNameChars are replaced in the components of a results file name; "_"
+// is reserved as the component separator
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// ResultsFileMeta is the metadata embedded in a results file name
+type ResultsFileMeta struct {
+	Timestamp    time.Time `json:"timestamp"`
+	RunName      string    `json:"run_name,omitempty"`
+	RegistryHost string    `json:"registry_host,omitempty"`
+	Version      string    `json:"version,omitempty"` // v1, v2 or v1-v2 for comparisons
+}
+
+// ResultsFileName returns the results file name for a run started at start:
+// results_<timestamp>[_<run-name>]_<registry-host>_<version>.json
+func ResultsFileName(cfg *Config, start time.Time) string {
+	parts := []string{"results", start.Format(resultsTimestampLayout)}
+	if name := sanitizeNameComponent(cfg.RunName); name != "" {
+		parts = append(parts, name)
+	}
+	parts = append(parts, sanitizeNameComponent(resultsRegistryHost(cfg)), resultsVersion(cfg))
+	return strings.Join(parts, "_") + ".json"
+}
+
+// ParseResultsFileName extracts the metadata from a results file name. Names
+// written before the naming scheme carried metadata only yield the timestamp.
+func ParseResultsFileName(name string) (ResultsFileMeta, bool) {
+	base := strings.TrimSuffix(filepath.Base(name), ".json")
+	parts := strings.Split(base, "_")
+	if len(parts) < 3 || parts[0] != "results" {
+		return ResultsFileMeta{}, false
+	}
+	timestamp, err := time.ParseInLocation(resultsTimestampLayout, parts[1]+"_"+parts[2], time.Local)
+	if err != nil {
+		return ResultsFileMeta{}, false
+	}
+
+	meta := ResultsFileMeta{Timestamp: timestamp}
+	switch extra := parts[3:]; len(extra) {
+	case 0:
+	case 2:
+		meta.RegistryHost, meta.Version = extra[0], extra[1]
+	case 3:
+		meta.RunName, meta.RegistryHost, meta.Version = extra[0], extra[1], extra[2]
+	default:
+		return ResultsFileMeta{}, false
+	}
+	return meta, true
+}
+
+// phaseLogFile returns the oc-mirror log file of one phase attempt, next to the results file:
+// logs/<results-base>_[<registry>_]<version>_iter<N>_<phase>[_attempt<K>].log, where
+// the registry host is only included in registry comparisons
+func (tr *TestRunner) phaseLogFile(iteration int, version, phase string, attempt int) string {
+	if tr.config.IsRegistryComparison() {
+		version = sanitizeNameComponent(extractRegistryAddress(tr.targetRegistry())) + "_" + version
+	}
+	if tr.config.Proxy.Compare {
+		version = tr.proxySettings().Mode + "_" + version
+	}
+	if g := tr.matrixGroup; g != nil && g.logPrefix != "" {
+		version = g.logPrefix + "_" + version
+	}
+	name := fmt.Sprintf("%s_%s_%s_%s", strings.TrimSuffix(filepath.Base(tr.resultsPath), ".json"), version, tr.iterationLabel(iteration), phase)
+	if attempt > 1 {
+		name += fmt.Sprintf("_attempt%d", attempt)
+	}
+	return filepath.Join(filepath.Dir(tr.resultsPath), "logs", name+".log")
+}
+
+// resultsRegistryHost returns the destination host for file names, "oci" for layout targets
+func resultsRegistryHost(cfg *Config) string {
+	if cfg.IsOCITarget() {
+		return "oci"
+	}
+	host := cfg.RegistryURL
+	if idx := strings.Index(host, "://"); idx >= 0 {
+		host = host[idx+3:]
+	}
+	host = strings.SplitN(host, "/", 2)[0]
+	if host == "" {
+		return "unknown"
+	}
+	return host
+}
+
+// resultsVersion returns the oc-mirror version(s) exercised by the run
+func resultsVersion(cfg *Config) string {
+	return strings.Join(cfg.MatrixVersions(), "-")
+}
+
+// sanitizeNameComponent makes s safe to use as one component of a file name
+func sanitizeNameComponent(s string) string {
+	return strings.Trim(unsafeNameChars.ReplaceAllString(s, "-"), "-")
+}
+
+// String returns a short label such as "nightly · registry.lab-8443 · v2"
+func (m ResultsFileMeta) String() string {
+	var parts []string
+	for _, part := range []string{m.RunName, m.RegistryHost, m.Version} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return m.Timestamp.Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(parts, " · "), m.Timestamp.Format("2006-01-02 15:04:05"))
+}
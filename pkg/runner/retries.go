@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// retryBucketFor returns the retry timeline slice length for a phase, keeping
+// long phases at roughly 60 slices
+func retryBucketFor(wallTime time.Duration) time.Duration {
+	bucket := 10 * time.Second
+	if perSlice := wallTime / 60; perSlice > bucket {
+		bucket = (perSlice + bucket - 1) / bucket * bucket
+	}
+	return bucket
+}
+
+// downloadRetryTimeline builds the download phase retry timeline; every host is
+// upstream since the destination registry is not involved
+func downloadRetryTimeline(output *command.CommandOutput, metrics *PhaseMetrics) *command.RetryTimeline {
+	if output == nil {
+		return nil
+	}
+	timeline := output.ExtractRetryTimeline("", retryBucketFor(metrics.WallTime))
+	if timeline == nil {
+		return nil
+	}
+
+	samples := make([]command.ThroughputSample, 0, len(metrics.DownloadMetrics.Samples))
+	for _, s := range metrics.DownloadMetrics.Samples {
+		samples = append(samples, command.ThroughputSample{Timestamp: s.Timestamp, RateMBs: s.DownloadRateMB})
+	}
+	timeline.AttachThroughput("mirror directory growth", samples)
+	return timeline
+}
+
+// uploadRetryTimeline builds the upload phase retry timeline, charted against the
+// best available upload throughput: per-process TX, registry TX or OCI layout writes
+func (tr *TestRunner) uploadRetryTimeline(output *command.CommandOutput, metrics *PhaseMetrics) *command.RetryTimeline {
+	if output == nil {
+		return nil
+	}
+	registryHost := ""
+	if !tr.config.IsOCITarget() {
+		registryHost = extractRegistryAddress(tr.targetRegistry())
+	}
+	timeline := output.ExtractRetryTimeline(registryHost, retryBucketFor(metrics.WallTime))
+	if timeline == nil {
+		return nil
+	}
+
+	var samples []command.ThroughputSample
+	switch {
+	case metrics.ProcessNetworkMetrics != nil && len(metrics.ProcessNetworkMetrics.Samples) > 0:
+		for _, s := range metrics.ProcessNetworkMetrics.Samples {
+			samples = append(samples, command.ThroughputSample{Timestamp: s.Timestamp, RateMBs: s.TxRate / 8})
+		}
+		timeline.AttachThroughput("oc-mirror TX", samples)
+	case metrics.DiskWriteMetrics != nil:
+		for _, s := range metrics.DiskWriteMetrics.Samples {
+			samples = append(samples, command.ThroughputSample{Timestamp: s.Timestamp, RateMBs: s.WriteRate})
+		}
+		timeline.AttachThroughput("OCI layout writes", samples)
+	case tr.registryMonitor != nil && tr.registryMonitor.IsMonitoring():
+		registryMetrics := tr.registryMonitor.GetCurrentMetrics()
+		samples = registryThroughputSamples(registryMetrics, timeline.StartTime)
+		timeline.AttachThroughput("registry TX", samples)
+	}
+	return timeline
+}
+
+// registryThroughputSamples returns the registry monitor samples taken since start
+func registryThroughputSamples(metrics monitor.RegistryMetrics, start time.Time) []command.ThroughputSample {
+	var samples []command.ThroughputSample
+	for _, s := range metrics.Samples {
+		if s.Timestamp.Before(start) {
+			continue
+		}
+		samples = append(samples, command.ThroughputSample{Timestamp: s.Timestamp, RateMBs: s.UploadRateMB})
+	}
+	return samples
+}
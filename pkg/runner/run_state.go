@@ -0,0 +1,228 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// RunState is written next to the results file as the run progresses, so a
+// run whose process died can be continued with oc-mirror-test resume
+type RunState struct {
+	Args         []string  `json:"args"`        // run flags the run was started with
+	WorkingDir   string    `json:"working_dir"` // Directory the relative paths of the run refer to
+	ScenarioHash string    `json:"scenario_hash,omitempty"`
+	ResultsFile  string    `json:"results_file"`
+	WorkspaceDir string    `json:"workspace_dir"`
+	CacheDir     string    `json:"cache_dir"`
+	StartedAt    time.Time `json:"started_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Resumed      int       `json:"resumed,omitempty"` // Times the run was resumed
+	Completed    []string  `json:"completed"`         // Iterations already in the results file
+	Finished     bool      `json:"finished"`          // The run ended without error; it cannot be resumed
+}
+
+// StatePath returns the run state file of the results file at resultsPath
+func StatePath(resultsPath string) string {
+	return strings.TrimSuffix(resultsPath, ".json") + ".state"
+}
+
+// LoadRunState reads a run state file
+func LoadRunState(path string) (*RunState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run state: %w", err)
+	}
+	state := &RunState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse run state %s: %w", path, err)
+	}
+	if state.ResultsFile == "" || len(state.Args) == 0 {
+		return nil, fmt.Errorf("run state %s does not describe a run", path)
+	}
+	return state, nil
+}
+
+// iterationKey identifies an iteration within the run: its number and
+// version, prefixed by the matrix combination, registry or proxy leg it
+// belongs to
+func (tr *TestRunner) iterationKey(iteration int, version string) string {
+	var parts []string
+	if g := tr.matrixGroup; g != nil {
+		// The label starts with the version
+		parts = append(parts, g.label())
+		version = ""
+	}
+	if tr.config.IsRegistryComparison() {
+		parts = append(parts, extractRegistryAddress(tr.targetRegistry()))
+	}
+	if tr.config.Proxy.Compare {
+		parts = append(parts, tr.proxySettings().Mode)
+	}
+	if version != "" {
+		parts = append(parts, version)
+	}
+	parts = append(parts, tr.iterationLabel(iteration))
+	return strings.Join(parts, "/")
+}
+
+// iterationLabel names an iteration in keys and log files: iter<N>, or
+// warmup<N> for a warm-up iteration
+func (tr *TestRunner) iterationLabel(iteration int) string {
+	if tr.warmup {
+		return fmt.Sprintf("warmup%d", iteration)
+	}
+	return fmt.Sprintf("iter%d", iteration)
+}
+
+// startRunState writes the state file of a new run, or continues the state
+// of the resumed run with the results it already recorded. Runs without a
+// recorded command line keep no state.
+func (tr *TestRunner) startRunState() error {
+	if len(tr.config.RunArgs) == 0 {
+		return nil
+	}
+	resume := tr.config.Resume
+	if resume == nil {
+		dir, _ := os.Getwd()
+		tr.runState = &RunState{
+			Args:         tr.config.RunArgs,
+			WorkingDir:   dir,
+			ScenarioHash: tr.config.ScenarioHash,
+			ResultsFile:  tr.resultsPath,
+			WorkspaceDir: tr.config.GetWorkspaceDir(),
+			CacheDir:     tr.config.GetCacheDir(),
+			StartedAt:    time.Now(),
+			Completed:    make([]string, 0),
+		}
+		return tr.writeRunState()
+	}
+
+	tr.runState = resume
+	tr.runState.Resumed++
+	data, err := os.ReadFile(resume.ResultsFile)
+	if errors.Is(err, os.ErrNotExist) {
+		// The run stopped before its first iteration was saved
+		return tr.writeRunState()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the results of the resumed run: %w", err)
+	}
+	file, err := ParseResultsFile(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse the results of the resumed run: %w", err)
+	}
+	err = file.LoadSamples(func(name string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(filepath.Dir(resume.ResultsFile), name))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load the samples of the resumed run: %w", err)
+	}
+
+	tr.header = file.ResultsHeader
+	tr.header.ToolVersion = tr.config.ToolVersion
+	for _, r := range file.Results {
+		// A staged iteration restarts from its first stage
+		if r.Stage != nil && !tr.completed(tr.iterationKey(r.Iteration, r.Version)) {
+			continue
+		}
+		tr.results = append(tr.results, r)
+	}
+	return tr.writeRunState()
+}
+
+// printRunState prints the state file in the run header and, for a resumed
+// run, what it continues from
+func (tr *TestRunner) printRunState() {
+	if tr.runState == nil {
+		return
+	}
+	logging.Printf("Run State: %s\n", StatePath(tr.resultsPath))
+	resume := tr.config.Resume
+	if resume == nil {
+		return
+	}
+	logging.Printf("Resuming: %d iteration(s) completed, %d result(s) kept (resume %d)\n",
+		len(resume.Completed), len(tr.results), resume.Resumed)
+	if resume.ScenarioHash != tr.config.ScenarioHash {
+		logging.Printf("Warning: the scenario file changed since the run started\n")
+	}
+	for _, dir := range []string{resume.WorkspaceDir, resume.CacheDir} {
+		if _, err := os.Stat(dir); err != nil {
+			logging.Printf("Warning: %s of the interrupted run is gone; the next cached iteration starts cold\n", dir)
+		}
+	}
+}
+
+// completed reports whether the iteration is in the results of the resumed run
+func (tr *TestRunner) completed(key string) bool {
+	if tr.runState == nil {
+		return false
+	}
+	for _, done := range tr.runState.Completed {
+		if done == key {
+			return true
+		}
+	}
+	return false
+}
+
+// skipCompleted reports, with a note, whether the iteration completed before the run was resumed
+func (tr *TestRunner) skipCompleted(key string) bool {
+	if tr.config.Resume == nil || !tr.completed(key) {
+		return false
+	}
+	logging.Printf("  Completed before the resume, skipping\n")
+	return true
+}
+
+// markCompleted records an iteration whose result was saved
+func (tr *TestRunner) markCompleted(key string) {
+	if tr.runState == nil || tr.completed(key) {
+		return
+	}
+	tr.runState.Completed = append(tr.runState.Completed, key)
+	if err := tr.writeRunState(); err != nil {
+		logging.Printf("Warning: Failed to save the run state: %v\n", err)
+	}
+}
+
+// finishRunState marks the run finished when it ended without error; after
+// an error or a crash the state stays resumable
+func (tr *TestRunner) finishRunState(err error) {
+	if tr.runState == nil || err != nil {
+		return
+	}
+	tr.runState.Finished = true
+	if err := tr.writeRunState(); err != nil {
+		logging.Printf("Warning: Failed to save the run state: %v\n", err)
+	}
+}
+
+// writeRunState replaces the state file atomically
+func (tr *TestRunner) writeRunState() error {
+	tr.runState.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(tr.runState, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := StatePath(tr.resultsPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create results directory: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
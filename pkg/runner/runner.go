@@ -1,26 +1,60 @@
 package runner
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/telco-core/ngc-495/internal/config"
-	"github.com/telco-core/ngc-495/pkg/client"
+	"github.com/telco-core/ngc-495/pkg/catalog"
 	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/environment"
+	"github.com/telco-core/ngc-495/pkg/events"
+	"github.com/telco-core/ngc-495/pkg/heartbeat"
+	"github.com/telco-core/ngc-495/pkg/integrity"
+	"github.com/telco-core/ngc-495/pkg/logging"
 	"github.com/telco-core/ngc-495/pkg/monitor"
+	"github.com/telco-core/ngc-495/pkg/plugin"
+	"github.com/telco-core/ngc-495/pkg/tracing"
 )
 
 // TestRunner orchestrates test execution
 type TestRunner struct {
-	config          *Config
-	results         []TestResult
-	resultsPath     string                   // Path to the results file for this test run
-	registryMonitor *monitor.RegistryMonitor // Daemon monitor for registry uploads
+	config           *Config
+	results          []TestResult
+	resultsPath      string                    // Path to the results file for this test run
+	registryURL      string                    // Registry the current iteration pushes to
+	registryMonitor  *monitor.RegistryMonitor  // Daemon monitor for registry uploads
+	monitorMu        sync.Mutex                // Guards registryMonitor swaps against web UI readers
+	heartbeat        *heartbeat.Heartbeat      // Liveness reporting (nil when disabled)
+	environment      *environment.Snapshot     // Host and storage layout captured at start
+	networkInterface string                    // Interface sampled in interface network accounting
+	header           ResultsHeader             // Run description written to the results file envelope
+	events           *events.Bus               // Monitor samples and phase changes for live views
+	contentRevision  string                    // Content mirrored by the current iteration, in a day-2 update run
+	stage            *StageMetrics             // Stage the current iteration mirrors, in a priority-ordered run
+	stageContent     *config.ContentSpec       // Content of the current stage
+	tracer           *tracing.Tracer           // Exports spans over OTLP (nil when disabled)
+	traces           traceState                // Open spans of the run
+	phase            events.PhaseChange        // Current phase, passed to plugins
+	sinks            []*plugin.Sink            // Sink plugins receiving the run events
+	polled           []polledMonitor           // Monitors of the iteration whose poll interval backs off
+	eventStream      *eventStream              // NDJSON event file (nil when disabled)
+	catalogIndexes   map[string]*catalog.Index // Catalogs rendered for the expected content, by image
+	proxyMode        string                    // Direct in the leg of a proxy comparison without the proxy
+	matrixGroup      *matrixGroup              // Combination of the iteration matrix the current iteration runs
+	warmup           bool                      // Set while the warm-up iterations of a version run
+	pinnedContent    *config.ContentSpec       // Content pinned to digests, for the digest references
+	expectedSize     *downloadEstimate         // Expected size of the current clean download, for its ETA
+	measuredSizes    map[string]int64          // Mirror directory size after the first clean download, by content
+	onResult         func(TestResult)          // Called with each finished iteration
+	runState         *RunState                 // Completed iterations, for resuming the run (nil without a command line)
+	cleanups         *Cleanups                 // Host changes the command undoes when interrupted
 }
 
 // RegistryMonitorInterface defines the interface for accessing registry monitor
@@ -29,28 +63,48 @@ type RegistryMonitorInterface interface {
 	GetCurrentMetrics() interface{}
 }
 
-// GetRegistryMonitor returns the registry monitor instance for external access
+// GetResults returns the results collected so far
+func (tr *TestRunner) GetResults() []TestResult {
+	return tr.results
+}
+
+// GetRegistryMonitor returns the registry monitor for external access. It
+// follows the monitor the run currently uploads with, which is replaced when
+// the run starts and when a registry comparison switches registries.
 func (tr *TestRunner) GetRegistryMonitor() RegistryMonitorInterface {
-	return &registryMonitorWrapper{rm: tr.registryMonitor}
+	return &registryMonitorWrapper{tr: tr}
+}
+
+// ResultsPath returns the path of the results file the run writes
+func (tr *TestRunner) ResultsPath() string {
+	return tr.resultsPath
 }
 
-// registryMonitorWrapper wraps RegistryMonitor to implement the interface
+// registryMonitorWrapper wraps the runner's current RegistryMonitor to implement the interface
 type registryMonitorWrapper struct {
-	rm *monitor.RegistryMonitor
+	tr *TestRunner
+}
+
+func (w *registryMonitorWrapper) current() *monitor.RegistryMonitor {
+	w.tr.monitorMu.Lock()
+	defer w.tr.monitorMu.Unlock()
+	return w.tr.registryMonitor
 }
 
 func (w *registryMonitorWrapper) IsMonitoring() bool {
-	if w.rm == nil {
+	rm := w.current()
+	if rm == nil {
 		return false
 	}
-	return w.rm.IsMonitoring()
+	return rm.IsMonitoring()
 }
 
 func (w *registryMonitorWrapper) GetCurrentMetrics() interface{} {
-	if w.rm == nil {
+	rm := w.current()
+	if rm == nil {
 		return nil
 	}
-	return w.rm.GetCurrentMetrics()
+	return rm.GetCurrentMetrics()
 }
 
 // NewTestRunner creates a new test runner
@@ -58,17 +112,38 @@ func NewTestRunner(cfg *Config) *TestRunner {
 	if cfg.Iterations < 2 {
 		cfg.Iterations = 2
 	}
-	// Initialize results file path with timestamp
-	resultsPath := filepath.Join("results", fmt.Sprintf("results_%s.json", time.Now().Format("20060102_150405")))
+	// Initialize results file path with timestamp, run name, registry and version
+	resultsPath := filepath.Join(cfg.GetResultsDir(), ResultsFileName(cfg, time.Now()))
+	if cfg.Resume != nil {
+		// A resumed run extends the results file it started
+		resultsPath = cfg.Resume.ResultsFile
+	}
 
 	// Extract registry host:port for monitoring
 	registryAddr := extractRegistryAddress(cfg.RegistryURL)
 
+	createdAt := time.Now()
+	notes := make([]Note, 0, len(cfg.Notes))
+	for _, text := range cfg.Notes {
+		notes = append(notes, Note{Text: text, CreatedAt: createdAt})
+	}
+
 	return &TestRunner{
-		config:          cfg,
-		results:         make([]TestResult, 0),
-		resultsPath:     resultsPath,
-		registryMonitor: monitor.NewRegistryMonitor(registryAddr),
+		config:           cfg,
+		results:          make([]TestResult, 0),
+		resultsPath:      resultsPath,
+		registryURL:      cfg.RegistryURL,
+		registryMonitor:  monitor.NewRegistryMonitor(registryAddr),
+		networkInterface: monitor.DefaultInterface(),
+		events:           events.NewBus(),
+		header: ResultsHeader{
+			SchemaVersion: ResultsSchemaVersion,
+			ToolVersion:   cfg.ToolVersion,
+			ScenarioHash:  cfg.ScenarioHash,
+			CreatedAt:     createdAt,
+			Tags:          cfg.Tags,
+			Notes:         notes,
+		},
 	}
 }
 
@@ -89,193 +164,291 @@ func extractRegistryAddress(registryURL string) string {
 	return addr
 }
 
+// startRegistryMonitor starts the registry upload monitor daemon
+func (tr *TestRunner) startRegistryMonitor() error {
+	registryAddr := extractRegistryAddress(tr.targetRegistry())
+	logging.Printf("Starting registry upload monitor daemon for %s...\n", registryAddr)
+	registryMonitor := monitor.NewRegistryMonitor(registryAddr)
+	registryMonitor.SetPollInterval(monitor.DefaultPollInterval)
+	tr.observe(registryMonitor, sampleSourceRegistry)
+	tr.monitorMu.Lock()
+	tr.registryMonitor = registryMonitor
+	tr.monitorMu.Unlock()
+	if err := registryMonitor.Start(); err != nil {
+		return err
+	}
+	logging.Printf("Registry monitor daemon started (monitoring uploads to %s)\n", registryAddr)
+	return nil
+}
+
 // Run executes all test iterations
-func (tr *TestRunner) Run() error {
-	fmt.Printf("╔═══════════════════════════════════════════════════════════════╗\n")
-	fmt.Printf("║        OC Mirror Test Automation - Metrics Collection        ║\n")
-	fmt.Printf("╚═══════════════════════════════════════════════════════════════╝\n\n")
-	fmt.Printf("Registry URL: %s\n", tr.config.RegistryURL)
-	fmt.Printf("Iterations: %d\n", tr.config.Iterations)
+func (tr *TestRunner) Run() (err error) {
+	startedAt := time.Now()
+	defer func() { tr.sendNotification(startedAt, err) }()
+	defer tr.uploadResults() // After the ticket attachment so its report and bundle are included
+	defer func() { tr.attachToTicket(startedAt, err) }()
+	// After every monitor was stopped and before the results leave the host
+	defer tr.checkToolHealth(captureHealthBaseline())
+	defer tr.checkClusterDrift()
+	// Before the drift check, which then confirms the apply
+	defer func() { err = errors.Join(err, tr.validateOnCluster()) }()
+	defer tr.reportRegistryResponses()
+	if err := tr.startRunState(); err != nil {
+		return err
+	}
+	defer func() { tr.finishRunState(err) }()
+
+	logging.Printf("╔═══════════════════════════════════════════════════════════════╗\n")
+	logging.Printf("║        OC Mirror Test Automation - Metrics Collection        ║\n")
+	logging.Printf("╚═══════════════════════════════════════════════════════════════╝\n\n")
+	logging.Printf("Registry URL: %s\n", tr.config.RegistryURL)
+	if tr.config.IsRegistryComparison() {
+		logging.Printf("Registry Comparison: %s (%s)\n", strings.Join(tr.config.CompareRegistries, ", "), tr.config.GetRegistryOrder())
+	}
+	logging.Printf("Iterations: %d\n", tr.config.Iterations)
 	if tr.config.CompareV1V2 {
-		fmt.Printf("V1/V2 Comparison: Enabled\n")
+		logging.Printf("V1/V2 Comparison: Enabled\n")
+	}
+	if tr.config.matrixEnabled() {
+		logging.Printf("Iteration Matrix: %s\n", tr.config.matrixString())
+	}
+	logging.Printf("Content: %s\n", tr.config.GetContentScenario())
+	if tags := tr.header.Annotations().String(); tags != "" {
+		logging.Printf("Tags: %s\n", tags)
+	}
+	if tr.config.AdaptivePolling.Enabled() {
+		logging.Printf("Adaptive Polling: %s\n", tr.config.AdaptivePolling)
+	}
+	if len(tr.config.Stages) > 0 {
+		logging.Printf("Stages: %s\n", tr.config.stageNames())
+	}
+	if tr.config.UpdateContent != nil {
+		tr.header.ContentChanges = tr.contentChanges()
+		logging.Printf("Day-2 Update: %d content change(s), mirrored from iteration 2\n", len(tr.header.ContentChanges))
+	}
+	logging.Printf("Results: %s\n", tr.resultsPath)
+	tr.printRunState()
+	if path := tr.samplesPath(); path != "" {
+		logging.Printf("Samples: %s (%s)\n", path, tr.config.GetSampleStorage())
+	}
+	if tr.config.Pacing.Enabled() {
+		logging.Printf("Upload Pacing: %s\n", tr.config.Pacing.String())
+	}
+	if tr.config.RetryFailed > 0 {
+		logging.Printf("Phase Retries: %d (backoff from %v)\n", tr.config.RetryFailed, tr.config.GetRetryBackoff())
+	}
+	if tr.config.DownloadTimeout > 0 || tr.config.UploadTimeout > 0 {
+		logging.Printf("Phase Timeouts: download %s | upload %s\n", formatPhaseTimeout(tr.config.DownloadTimeout), formatPhaseTimeout(tr.config.UploadTimeout))
+	}
+	if len(tr.config.WorkspaceCleanup) > 0 {
+		logging.Printf("Workspace Cleanup: %s (the last repeats)\n", strings.Join(tr.config.WorkspaceCleanup, ", "))
+	}
+	if tr.config.Warmup > 0 {
+		logging.Printf("Warm-up: %d iteration(s) per version, not measured\n", tr.config.Warmup)
+	}
+	if tr.config.RepeatClean {
+		logging.Printf("Repeated Clean Runs: every iteration from an empty workspace and cache\n")
+	}
+	if tr.config.Shaping.Enabled() {
+		logging.Printf("Network Shaping: %s\n", tr.config.Shaping.String())
+	}
+	if tr.config.RateLimit.Enabled() {
+		logging.Printf("Simulated Rate Limit: %s\n", tr.config.RateLimit.String())
+	}
+	if tr.config.Notify.Enabled() {
+		logging.Printf("Notifications: %s\n", tr.config.Notify.String())
+	}
+	if tr.config.Tracing.Enabled() {
+		logging.Printf("Tracing: %s\n", tr.config.Tracing.String())
+	}
+	if tr.config.Signatures.Enabled {
+		logging.Printf("Signature Checks: %s\n", tr.config.Signatures.String())
+	}
+	if tr.config.ValidateContent {
+		logging.Printf("Expected Content: operator catalogs rendered with opm, checked after each upload\n")
+	}
+	if tr.config.PackageSizes {
+		logging.Printf("Operator Package Sizes: mirrored bytes attributed to each package after each upload\n")
+	}
+	if tr.config.VerifyUpload {
+		logging.Printf("Upload Verification: local blob digests compared with the registry after each upload\n")
+	}
+	if tr.config.Ticket.Enabled() {
+		logging.Printf("Ticket: %s\n", tr.config.Ticket.String())
+	}
+	if tr.config.Store.Enabled() {
+		logging.Printf("Results Store: %s\n", tr.config.Store.String())
+	}
+	if tr.config.Cleanup.Enabled() {
+		logging.Printf("Cleanup: %s\n", tr.config.Cleanup.String())
+	}
+	if tr.config.Delete.Enabled {
+		logging.Printf("Delete Phase: after the last iteration\n")
+	}
+	if tr.config.Kubeconfig != "" {
+		logging.Printf("Cluster Drift Check: %s\n", tr.config.Kubeconfig)
+	}
+	if tr.config.ClusterValidation.Enabled {
+		logging.Printf("Cluster Validation: apply and pull from the mirror (timeout %v)\n", tr.config.ClusterValidation.GetTimeout())
+	}
+	if tr.config.PullThrough.Enabled {
+		logging.Printf("Pull-Through Check: serve the mirrored catalogs after each upload (timeout %v)\n", tr.config.PullThrough.GetTimeout())
+	}
+	if tr.config.Gates.Enabled() {
+		logging.Printf("Gates: %s\n", tr.config.Gates.String())
+	}
+	if tr.config.AirGap.Enabled {
+		logging.Printf("Air-Gap Workflow: %s\n", tr.config.AirGap.String())
+	}
+	if tr.config.Replication.Enabled() {
+		logging.Printf("Replication: %s\n", tr.config.Replication.String())
+	}
+	if tr.config.Chaos.Enabled() {
+		logging.Printf("Chaos: %s\n", tr.config.Chaos.String())
+	}
+	if tr.config.Proxy.Enabled() {
+		logging.Printf("Proxy: %s\n", tr.config.Proxy.String())
+	}
+	if tr.config.MemoryCeiling.Enabled() {
+		logging.Printf("Memory Budget: %s\n", tr.config.MemoryCeiling.String())
+	}
+	if mode := tr.config.LogRetention.Mode; mode != "" && mode != command.LogRetentionFile {
+		logging.Printf("Log Retention: %s\n", tr.config.LogRetention.String())
+	}
+	switch tr.config.GetResourceScope() {
+	case monitor.ResourceScopeTree:
+		logging.Printf("Resource Scope: process tree (oc-mirror and its children)\n")
+	case monitor.ResourceScopeCgroup:
+		logging.Printf("Resource Scope: transient cgroup (oc-mirror and its children)\n")
+		if err := monitor.CgroupAvailable(); err != nil {
+			logging.Printf("Warning: cgroup resource monitoring unavailable, falling back to the process tree: %v\n", err)
+		}
 	}
-	fmt.Printf("\n")
+	if tr.config.GetNetworkAccounting() == NetworkAccountingProcess {
+		logging.Printf("Network Accounting: per-process (oc-mirror TCP traffic only)\n")
+		if err := monitor.ProcessNetworkAvailable(); err != nil {
+			logging.Printf("Warning: per-process network accounting unavailable, falling back to interface counters: %v\n", err)
+		}
+	}
+	for _, limitation := range monitor.HostLimitations() {
+		logging.Printf("Warning: %s\n", limitation)
+	}
+	logging.Printf("\n")
+
+	// Start liveness reporting before anything that can hang
+	tr.startHeartbeat()
+	defer func() { tr.stopHeartbeat(err) }()
+	tr.startTracing()
+	defer func() { tr.stopTracing(err) }()
+	tr.startSinkPlugins()
+	defer func() { tr.stopSinkPlugins(err) }()
+	tr.startEventStream()
+	defer func() { tr.stopEventStream(startedAt, err) }()
+	tr.setPhase("setup", "", 0)
 
 	// Ensure required tools are available
-	fmt.Printf("Checking for required tools (oc-mirror)...\n")
-	ctx := context.Background()
-	binDir := "./bin"
-	if err := client.EnsureTools(ctx, binDir, []string{"oc-mirror"}); err != nil {
-		fmt.Printf("Warning: Failed to ensure tools are available: %v\n", err)
-		fmt.Printf("Please ensure oc-mirror is in PATH or run: oc-mirror-test download\n")
+	tools := []string{"oc-mirror"}
+	if tr.config.ValidateContent || tr.config.PackageSizes {
+		tools = append(tools, "opm") // Renders the catalogs for the expected content
 	}
-
-	// Update PATH to include bin directory for downloaded binaries
-	if err := tr.updatePathWithBinDir(binDir); err != nil {
-		fmt.Printf("Warning: Failed to update PATH: %v\n", err)
+	if tr.config.Kubeconfig != "" {
+		tools = append(tools, "oc") // Drift, cluster validation and pull-through checks
+	}
+	tr.ensureTools(tools)
+	if version, err := command.OCMirrorVersion(); err != nil {
+		logging.Printf("Warning: Failed to detect oc-mirror version: %v\n", err)
 	} else {
-		fmt.Printf("Updated PATH to include: %s\n", binDir)
+		tr.header.OCMirrorVersion = version
+		logging.Printf("oc-mirror version: %s\n", version)
 	}
 
-	// Start registry monitoring daemon
-	registryAddr := extractRegistryAddress(tr.config.RegistryURL)
-	fmt.Printf("Starting registry upload monitor daemon for %s...\n", registryAddr)
-	tr.registryMonitor = monitor.NewRegistryMonitor(registryAddr)
-	tr.registryMonitor.SetPollInterval(1 * time.Second)
-	if err := tr.registryMonitor.Start(); err != nil {
-		fmt.Printf("Warning: Failed to start registry monitor: %v\n", err)
+	if tr.config.IsOCITarget() {
+		if tr.config.runsV1() {
+			return fmt.Errorf("oci:// target is only supported by oc-mirror v2")
+		}
+		// No registry involved: upload metrics come from disk writes to the layout
+		logging.Printf("OCI layout target: %s (upload measured from disk writes)\n", tr.config.OCILayoutPath())
+	} else if err := tr.startRegistryMonitor(); err != nil {
+		logging.Printf("Warning: Failed to start registry monitor: %v\n", err)
 	} else {
-		fmt.Printf("Registry monitor daemon started (monitoring uploads to %s)\n", registryAddr)
 		// Ensure monitor is stopped when tests complete
 		defer func() {
 			if tr.registryMonitor != nil && tr.registryMonitor.IsMonitoring() {
 				metrics := tr.registryMonitor.Stop()
-				fmt.Printf("\nRegistry Monitor Summary:\n")
-				fmt.Printf("  Total Bytes Uploaded: %s\n", monitor.FormatBytesHuman(metrics.TotalBytesUploaded))
-				fmt.Printf("  Average Upload Rate: %.2f MB/s\n", metrics.AverageUploadRateMB)
-				fmt.Printf("  Peak Upload Rate: %.2f MB/s\n", metrics.PeakUploadRateMB)
+				logging.Printf("\nRegistry Monitor Summary:\n")
+				logging.Printf("  Total Bytes Uploaded: %s\n", monitor.FormatBytesHuman(metrics.TotalBytesUploaded))
+				logging.Printf("  Average Upload Rate: %.2f MB/s\n", metrics.AverageUploadRateMB)
+				logging.Printf("  Peak Upload Rate: %.2f MB/s\n", metrics.PeakUploadRateMB)
 			}
 		}()
 	}
 
+	// Prune earlier runs before the directories are recreated; a resumed run
+	// continues with the workspaces and cache it left
+	if tr.config.Resume == nil {
+		tr.cleanup()
+	}
+
 	// Create necessary directories
 	if err := tr.setupDirectories(); err != nil {
 		return fmt.Errorf("failed to setup directories: %w", err)
 	}
 
-	// Create imageset-config files for v1 and v2
-	// v1 uses v1alpha2 API version, v2 uses v2alpha1
-	if err := config.CreateImageSetConfigWithVersion("oc-mirror-clone/imagesetconfiguration_operators-v1.yaml", "v1alpha2"); err != nil {
-		return fmt.Errorf("failed to create v1 imageset-config: %w", err)
-	}
-	if err := config.CreateImageSetConfigWithVersion("oc-mirror-clone/imagesetconfiguration_operators-v2.yaml", "v2alpha1"); err != nil {
-		return fmt.Errorf("failed to create v2 imageset-config: %w", err)
-	}
-	// Also create default for backward compatibility
-	if err := config.CreateImageSetConfig("oc-mirror-clone/imagesetconfiguration_operators.yaml"); err != nil {
-		return fmt.Errorf("failed to create imageset-config: %w", err)
+	// Record the storage layout; filesystem and device differences explain much run-to-run variance
+	tr.environment = environment.Capture(tr.storagePaths())
+	tr.environment.PrintSummary()
+	tr.warnSharedStorage()
+	tr.header.Host = hostInfo(tr.environment)
+	if err := tr.checkDiskSpace(); err != nil {
+		return err
 	}
 
-	if tr.config.CompareV1V2 {
-		return tr.runV1V2Comparison()
+	// Create imageset-config files for v1 and v2
+	// v1 uses v1alpha2 API version, v2 uses v2alpha1
+	if err := writeImageSetConfigs(tr.config.Content); err != nil {
+		return err
 	}
-
-	return tr.runStandardTest()
-}
-
-func (tr *TestRunner) runStandardTest() error {
-	// Run iterations
-	for i := 0; i < tr.config.Iterations; i++ {
-		isCleanRun := i == 0
-		fmt.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
-		fmt.Printf("║  Iteration %d/%d (%s)                                          ║\n", i+1, tr.config.Iterations, map[bool]string{true: "CLEAN", false: "CACHED"}[isCleanRun])
-		fmt.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
-
-		result, err := tr.runIteration(i+1, isCleanRun, "v2")
-		if err != nil {
-			return fmt.Errorf("iteration %d failed: %w", i+1, err)
-		}
-
-		tr.results = append(tr.results, result)
-		tr.printIterationSummary(result)
-
-		// Save results incrementally after each iteration
-		if err := tr.saveResults(); err != nil {
-			fmt.Printf("Warning: Failed to save results incrementally: %v\n", err)
-		}
+	if tr.config.UpdateContent != nil {
+		tr.contentRevision = ContentRevisionInitial
 	}
 
-	// Compare results
-	tr.compareCleanVsCached()
-
-	// Final save (in case of any updates)
-	if err := tr.saveResults(); err != nil {
-		return fmt.Errorf("failed to save results: %w", err)
+	// Apply network constraints for the duration of the iterations
+	removeShaping, err := tr.applyNetworkShaping()
+	if err != nil {
+		return fmt.Errorf("failed to apply network shaping: %w", err)
 	}
+	defer removeShaping()
+	defer logging.Summary(tr.printOutcome)
 
-	return nil
-}
-
-func (tr *TestRunner) runV1V2Comparison() error {
-	fmt.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
-	fmt.Printf("║              V1 vs V2 Comparison Test                          ║\n")
-	fmt.Printf("╚═══════════════════════════════════════════════════════════════╝\n\n")
-
-	// Run v1 tests
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	fmt.Printf("Running V1 Tests\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	var v1Results []TestResult
-	for i := 0; i < tr.config.Iterations; i++ {
-		isCleanRun := i == 0
-		fmt.Printf("\n[V1] Iteration %d/%d (%s)\n", i+1, tr.config.Iterations, map[bool]string{true: "CLEAN", false: "CACHED"}[isCleanRun])
-
-		result, err := tr.runIteration(i+1, isCleanRun, "v1")
-		if err != nil {
-			return fmt.Errorf("v1 iteration %d failed: %w", i+1, err)
-		}
-		v1Results = append(v1Results, result)
-
-		// Save results incrementally after each v1 iteration
-		tr.results = v1Results
-		if err := tr.saveResults(); err != nil {
-			fmt.Printf("Warning: Failed to save results incrementally: %v\n", err)
-		}
+	if tr.config.IsRegistryComparison() {
+		return tr.runRegistryComparison()
 	}
-
-	// Clean workspace for v2
-	fmt.Printf("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	fmt.Printf("Cleaning workspace for V2 tests...\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	if err := tr.cleanWorkspace(); err != nil {
-		return fmt.Errorf("failed to clean workspace for v2: %w", err)
+	if tr.config.Proxy.Compare {
+		return tr.runProxyComparison()
 	}
-
-	// Run v2 tests
-	fmt.Printf("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	fmt.Printf("Running V2 Tests\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	var v2Results []TestResult
-	for i := 0; i < tr.config.Iterations; i++ {
-		isCleanRun := i == 0
-		fmt.Printf("\n[V2] Iteration %d/%d (%s)\n", i+1, tr.config.Iterations, map[bool]string{true: "CLEAN", false: "CACHED"}[isCleanRun])
-
-		result, err := tr.runIteration(i+1, isCleanRun, "v2")
-		if err != nil {
-			return fmt.Errorf("v2 iteration %d failed: %w", i+1, err)
-		}
-		v2Results = append(v2Results, result)
-
-		// Save results incrementally after each v2 iteration (include both v1 and v2)
-		tr.results = append(v1Results, v2Results...)
-		if err := tr.saveResults(); err != nil {
-			fmt.Printf("Warning: Failed to save results incrementally: %v\n", err)
-		}
+	if len(tr.config.Stages) > 0 {
+		return tr.runStagedTest()
 	}
 
-	// Store all results
-	tr.results = append(v1Results, v2Results...)
-
-	// Compare v1 vs v2
-	tr.compareV1VsV2(v1Results, v2Results)
-
-	// Final save (in case of any updates)
-	if err := tr.saveResults(); err != nil {
-		return fmt.Errorf("failed to save results: %w", err)
-	}
+	return tr.runMatrix()
+}
 
-	return nil
+// printOutcome prints the last line of a run: the iterations, the failed
+// ones and the results file
+func (tr *TestRunner) printOutcome() {
+	failed := len(tr.results) - len(completedResults(tr.results))
+	logging.Printf("\nRun finished: %d iteration(s), %d failed. Results: %s\n", len(tr.results), failed, tr.resultsPath)
 }
 
 func (tr *TestRunner) setupDirectories() error {
 	dirs := []string{
 		"oc-mirror-clone",
-		"mirror/operators",
-		"mirror/operators-v1",
-		"mirror/operators-v2",
+		tr.legacyMirrorDir(),
+		tr.mirrorDir("v1"),
+		tr.mirrorDir("v2"),
 		"platform",
 		"platform/mirror",
-		"results",
+		tr.config.GetResultsDir(),
 		// Note: Cache directories (operators, operators-v1, operators-v2) are created
 		// automatically by oc-mirror when needed, so we don't pre-create them
 	}
@@ -289,59 +462,147 @@ func (tr *TestRunner) setupDirectories() error {
 	return nil
 }
 
-func (tr *TestRunner) runIteration(iterationNum int, isCleanRun bool, version string) (TestResult, error) {
-	result := TestResult{
-		Iteration:  iterationNum,
-		IsCleanRun: isCleanRun,
-		Version:    version,
-	}
-
-	// Clean workspace if this is a clean run
-	if isCleanRun {
-		if err := tr.cleanWorkspaceForVersion(version); err != nil {
+func (tr *TestRunner) runIteration(iterationNum int, isCleanRun bool, version string) (result TestResult, err error) {
+	result = TestResult{
+		Iteration:   iterationNum,
+		IsCleanRun:  isCleanRun,
+		IsUpdateRun: tr.contentRevision == ContentRevisionUpdate && iterationNum == 2, // The update starts at the second iteration
+		Warmup:      tr.warmup,
+		Version:     version,
+		Registry:    tr.targetRegistry(),
+
+		Scenario:        tr.config.ScenarioName,
+		ContentScenario: tr.config.GetContentScenario(),
+		ContentRevision: tr.contentRevision,
+		Environment:     tr.environment,
+		MonitorSettings: tr.monitorSettings(version),
+
+		NetworkAccounting: tr.config.GetNetworkAccounting(),
+		Proxy:             tr.proxySettings(),
+	}
+	if tr.config.Shaping.Enabled() {
+		shaping := tr.config.Shaping
+		result.NetworkShaping = &shaping
+	}
+	if tr.stage != nil {
+		stage := *tr.stage
+		result.Stage = &stage
+	}
+	endTrace := tr.startIterationTrace(&result)
+	defer func() { endTrace(err) }()
+	defer func() { result.MonitorSettings.Backoff = tr.takePollBackoff() }()
+
+	// Apply the cleanup strategy of the iteration; later stages build on the first
+	if tr.stage.first() {
+		strategy := tr.config.cleanupStrategy(iterationNum, isCleanRun)
+		if tr.warmup {
+			strategy = defaultCleanup(isCleanRun)
+		}
+		result.Cleanup = strategy.Name()
+		if err := strategy.Clean(tr, version, &result); err != nil {
 			return result, fmt.Errorf("failed to clean workspace: %w", err)
 		}
 	}
 
+	// Render the operator catalogs before mirroring to know what the upload must contain
+	var expected *expectedImages
+	if tr.config.ValidateContent || tr.config.PackageSizes {
+		logging.Printf("\n  ┌─ Expected Content (%s) ─────────────────────────────────────┐\n", version)
+		expected = tr.expectedContent()
+		logging.Printf("  └─────────────────────────────────────────────────────────────┘\n")
+	}
+	tr.expectedSize = tr.estimateDownload(version, isCleanRun, expected)
+
 	// Start network monitoring
+	tr.setPhase("download", version, iterationNum)
+
+	// Kill a clean download part way; the download phase below resumes it
+	if tr.config.Chaos.Enabled() && isCleanRun && tr.stage.first() && !tr.warmup {
+		result.Chaos = tr.runInterruptedDownload(iterationNum, version)
+	}
+
 	networkMonitor := monitor.NewNetworkMonitor()
+	tr.observe(networkMonitor, sampleSourceNetwork)
 	if err := networkMonitor.Start(); err != nil {
-		fmt.Printf("Warning: Failed to start network monitoring: %v\n", err)
+		logging.Printf("Warning: Failed to start network monitoring: %v\n", err)
 	}
 
 	// Start overall resource monitoring for the entire iteration
 	overallResourceMonitor := monitor.NewResourceMonitor()
+	tr.observe(overallResourceMonitor, sampleSourceIteration)
 	if err := overallResourceMonitor.Start(); err != nil {
-		fmt.Printf("Warning: Failed to start overall resource monitoring: %v\n", err)
+		logging.Printf("Warning: Failed to start overall resource monitoring: %v\n", err)
 	}
 
+	// In the air-gap workflow, time the archive the download phase writes
+	archiveMonitor := tr.startArchiveMonitor(version)
+
 	// Run download phase
-	fmt.Printf("\n  ┌─ Download Phase (%s) ───────────────────────────────────────┐\n", version)
-	downloadMetrics, err := tr.runDownloadPhase(isCleanRun, version)
+	logging.Printf("\n  ┌─ Download Phase (%s) ───────────────────────────────────────┐\n", version)
+	downloadMetrics, err := tr.runPhaseWithRetry(&result, "download", func(attempt int) (PhaseMetrics, error) {
+		return tr.runDownloadPhase(isCleanRun, version, tr.phaseLogFile(iterationNum, version, "download", attempt), nil)
+	}, func() error {
+		// A clean run must not reuse what the failed attempt already mirrored
+		if isCleanRun && tr.stage.first() {
+			return tr.cleanWorkspaceForVersion(version)
+		}
+		return nil
+	})
 	if err != nil {
+		if archiveMonitor != nil {
+			archiveMonitor.Stop()
+		}
 		networkMonitor.Stop()
 		overallResourceMonitor.Stop()
+		result.DownloadPhase = downloadMetrics
 		return result, fmt.Errorf("download phase failed: %w", err)
 	}
 	result.DownloadPhase = downloadMetrics
-	fmt.Printf("  └─────────────────────────────────────────────────────────────┘\n")
+	tr.recordDownloadSize(version, isCleanRun)
+	logging.Printf("  └─────────────────────────────────────────────────────────────┘\n")
+
+	// Carry the archive to the disconnected side
+	if archiveMonitor != nil {
+		tr.setPhase("transfer", version, iterationNum)
+		logging.Printf("\n  ┌─ Transfer Phase (%s) ───────────────────────────────────────┐\n", version)
+		airGap, err := tr.runTransferPhase(version, archiveMonitor.Stop())
+		result.AirGap = airGap
+		if err != nil {
+			networkMonitor.Stop()
+			overallResourceMonitor.Stop()
+			return result, fmt.Errorf("transfer phase failed: %w", err)
+		}
+		logging.Printf("  └─────────────────────────────────────────────────────────────┘\n")
+	}
 
 	// Start network monitoring for upload phase
+	tr.setPhase("upload", version, iterationNum)
 	uploadNetworkMonitor := monitor.NewNetworkMonitor()
+	tr.observe(uploadNetworkMonitor, sampleSourceNetwork)
 	if err := uploadNetworkMonitor.Start(); err != nil {
-		fmt.Printf("Warning: Failed to start network monitoring for upload: %v\n", err)
+		logging.Printf("Warning: Failed to start network monitoring for upload: %v\n", err)
 	}
 
 	// Stop download network monitoring and get metrics
 	downloadNetworkMetrics := networkMonitor.Stop()
 	result.NetworkMetrics = downloadNetworkMetrics
+	if result.Chaos != nil {
+		result.Chaos.resumed(result.DownloadPhase, downloadNetworkMetrics.TotalBytesTransferred)
+	}
+
+	// List the registry catalog before the upload for the catalog diff
+	catalogBefore := tr.snapshotCatalog(version)
+	tagsBefore := tr.snapshotTagDigests(version, isCleanRun)
 
 	// Run upload phase
-	fmt.Printf("\n  ┌─ Upload Phase (%s) ─────────────────────────────────────────┐\n", version)
-	uploadMetrics, err := tr.runUploadPhase(version)
+	logging.Printf("\n  ┌─ Upload Phase (%s) ─────────────────────────────────────────┐\n", version)
+	uploadMetrics, err := tr.runPhaseWithRetry(&result, "upload", func(attempt int) (PhaseMetrics, error) {
+		return tr.runUploadPhase(version, tr.phaseLogFile(iterationNum, version, "upload", attempt))
+	}, nil)
 	if err != nil {
 		uploadNetworkMonitor.Stop()
 		overallResourceMonitor.Stop()
+		result.UploadPhase = uploadMetrics
 		return result, fmt.Errorf("upload phase failed: %w", err)
 	}
 	result.UploadPhase = uploadMetrics
@@ -350,13 +611,15 @@ func (tr *TestRunner) runIteration(iterationNum int, isCleanRun bool, version st
 	if tr.registryMonitor != nil && tr.registryMonitor.IsMonitoring() {
 		registryMetrics := tr.registryMonitor.GetCurrentMetrics()
 		result.RegistryMetrics = &registryMetrics
-		fmt.Printf("  │ Registry Upload: %s | Avg: %.2f MB/s | Peak: %.2f MB/s\n",
+		logging.Printf("  │ Registry Upload: %s | Avg: %.2f MB/s | Peak: %.2f MB/s\n",
 			monitor.FormatBytesHuman(registryMetrics.TotalBytesUploaded),
 			registryMetrics.AverageUploadRateMB,
 			registryMetrics.PeakUploadRateMB)
 	}
+	result.CatalogDiff = tr.diffCatalog(version, catalogBefore)
+	result.ReferenceCounts = tr.countReferences(version, tagsBefore)
 
-	fmt.Printf("  └─────────────────────────────────────────────────────────────┘\n")
+	logging.Printf("  └─────────────────────────────────────────────────────────────┘\n")
 
 	// Stop upload network monitoring
 	uploadNetworkMetrics := uploadNetworkMonitor.Stop()
@@ -373,21 +636,22 @@ func (tr *TestRunner) runIteration(iterationNum int, isCleanRun bool, version st
 		result.RegistryMetrics = &registryMetrics
 	}
 
+	// Attribute only oc-mirror traffic to the test in process accounting mode
+	if tr.config.GetNetworkAccounting() == NetworkAccountingProcess {
+		tr.applyProcessNetworkAccounting(&result)
+	}
+
 	// Stop overall resource monitoring
 	result.ResourceMetrics = overallResourceMonitor.Stop()
 
 	// Analyze output directory
-	var mirrorPath string
-	if version == "v1" {
-		mirrorPath = "mirror/operators-v1"
-	} else {
-		mirrorPath = "mirror/operators-v2"
-	}
-	fmt.Printf("\n  ┌─ Output Analysis (%s) ───────────────────────────────────────┐\n", version)
+	mirrorPath := tr.mirrorDir(version)
+	tr.setPhase("verify", version, iterationNum)
+	logging.Printf("\n  ┌─ Output Analysis (%s) ───────────────────────────────────────┐\n", version)
 	outputVerifier := monitor.NewOutputVerifier(mirrorPath)
 	outputMetrics, err := outputVerifier.Analyze()
 	if err != nil {
-		fmt.Printf("  │ Warning: Failed to analyze output: %v\n", err)
+		logging.Printf("  │ Warning: Failed to analyze output: %v\n", err)
 	} else {
 		result.OutputMetrics = outputMetrics
 		outputMetrics.PrintSummary()
@@ -396,12 +660,28 @@ func (tr *TestRunner) runIteration(iterationNum int, isCleanRun bool, version st
 	// Get accurate image/layer counts from oc-mirror describe
 	describeMetrics, err := command.DescribeMirror(mirrorPath + "/")
 	if err != nil {
-		fmt.Printf("  │ Warning: Failed to run oc-mirror describe: %v\n", err)
+		logging.Printf("  │ Warning: Failed to run oc-mirror describe: %v\n", err)
 	} else {
 		result.DescribeMetrics = describeMetrics
 		describeMetrics.PrintSummary()
 	}
-	fmt.Printf("  └─────────────────────────────────────────────────────────────┘\n")
+	result.SignatureMetrics = tr.verifySignatures(version)
+	result.ExpectedContent = tr.validateContent(version, expected)
+	result.UploadVerification = tr.verifyUpload(version, &result)
+	result.PackageSizes = tr.measurePackageSizes(version, expected, result.DescribeMetrics)
+	result.ClusterArtifacts = tr.validateClusterArtifacts(&result)
+	result.PullThrough = tr.verifyPullThrough(&result)
+	result.DiskUsageBytes = tr.measureDiskUsage(version)
+	logging.Printf("  └─────────────────────────────────────────────────────────────┘\n")
+
+	// Replicate the upload to the next registries of the topology
+	if tr.config.Replication.Enabled() {
+		hops, err := tr.runReplication(iterationNum, version)
+		result.Replication = hops
+		if err != nil {
+			return result, fmt.Errorf("replication failed: %w", err)
+		}
+	}
 
 	// Generate summary
 	result.Summary = tr.generateSummary(result)
@@ -411,9 +691,9 @@ func (tr *TestRunner) runIteration(iterationNum int, isCleanRun bool, version st
 
 func (tr *TestRunner) cleanWorkspace() error {
 	dirsToClean := []string{
-		"mirror/operators",
-		"mirror/operators-v1",
-		"mirror/operators-v2",
+		tr.legacyMirrorDir(),
+		tr.mirrorDir("v1"),
+		tr.mirrorDir("v2"),
 		"platform/mirror",
 	}
 
@@ -429,18 +709,40 @@ func (tr *TestRunner) cleanWorkspace() error {
 	return nil
 }
 
-func (tr *TestRunner) cleanWorkspaceForVersion(version string) error {
-	var mirrorDir string
-	if version == "v1" {
-		mirrorDir = "mirror/operators-v1"
-	} else {
-		mirrorDir = "mirror/operators-v2"
+// storagePaths returns the paths whose backing storage is recorded in the environment snapshot
+func (tr *TestRunner) storagePaths() map[string]string {
+	paths := map[string]string{
+		"workspace": tr.config.GetWorkspaceDir(),
+		"cache":     tr.config.GetCacheDir(),
+		"results":   tr.config.GetResultsDir(),
+	}
+	if tr.config.IsOCITarget() {
+		paths["oci-layout"] = tr.config.OCILayoutPath()
+	}
+	if tr.config.RegistryStoragePath != "" {
+		paths["registry"] = tr.config.RegistryStoragePath
+	}
+	if tr.config.usesAirGap() {
+		paths["air-gap"] = airGapRoot
 	}
+	return paths
+}
 
+func (tr *TestRunner) cleanWorkspaceForVersion(version string) error {
 	dirsToClean := []string{
-		mirrorDir,
+		tr.mirrorDir(version),
 		"platform/mirror",
 	}
+	if tr.config.IsOCITarget() {
+		dirsToClean = append(dirsToClean, tr.config.OCILayoutPath())
+	}
+	if tr.config.AirGap.Enabled {
+		// The disconnected side starts clean too
+		dirsToClean = append(dirsToClean, airGapDir(version))
+		if version == "v2" {
+			dirsToClean = append(dirsToClean, airGapCacheDir)
+		}
+	}
 
 	for _, dir := range dirsToClean {
 		if err := os.RemoveAll(dir); err != nil {
@@ -455,18 +757,11 @@ func (tr *TestRunner) cleanWorkspaceForVersion(version string) error {
 	return nil
 }
 
-func (tr *TestRunner) runDownloadPhase(isCleanRun bool, version string) (PhaseMetrics, error) {
-	metrics := PhaseMetrics{}
+func (tr *TestRunner) runDownloadPhase(isCleanRun bool, version, logFile string, killer *chaosKiller) (PhaseMetrics, error) {
+	metrics := PhaseMetrics{LogFile: logFile}
 
-	var mirrorDir string
-	var mirrorPath string // Path for download monitoring (without file:// prefix)
-	if version == "v1" {
-		mirrorDir = "file://mirror/operators-v1"
-		mirrorPath = "mirror/operators-v1"
-	} else {
-		mirrorDir = "file://mirror/operators-v2"
-		mirrorPath = "mirror/operators-v2"
-	}
+	mirrorPath := tr.mirrorDir(version) // Path for download monitoring (without file:// prefix)
+	mirrorDir := "file://" + mirrorPath
 
 	// Ensure the mirror directory exists
 	if err := os.MkdirAll(mirrorPath, 0755); err != nil {
@@ -475,18 +770,30 @@ func (tr *TestRunner) runDownloadPhase(isCleanRun bool, version string) (PhaseMe
 
 	// Start download monitoring for the mirror directory
 	downloadMonitor := monitor.NewDownloadMonitor(mirrorPath)
-	downloadMonitor.SetPollInterval(1 * time.Second)
+	downloadMonitor.SetPollInterval(monitor.DefaultPollInterval)
+	tr.observe(downloadMonitor, sampleSourceDownload)
+	if isCleanRun && tr.expectedSize != nil {
+		tr.expectedSize.print()
+		downloadMonitor.SetExpectedBytes(tr.expectedSize.bytes)
+	}
 	if err := downloadMonitor.Start(); err != nil {
-		fmt.Printf("  │ Warning: Failed to start download monitoring: %v\n", err)
+		logging.Printf("  │ Warning: Failed to start download monitoring: %v\n", err)
 	}
 
 	// Prepare resource monitor for oc-mirror process (will be started when we get the PID)
-	resourceMonitor := monitor.NewResourceMonitor()
-	resourceMonitor.SetPollInterval(500 * time.Millisecond) // More frequent sampling for child process
+	resourceMonitor := tr.newProcessResourceMonitor()
+	tr.observe(resourceMonitor, sampleSourceResource)
+	processNetworkMonitor := tr.newProcessNetworkMonitor()
+	memoryCeilingMonitor := tr.newMemoryCeilingMonitor()
 
 	cmd := command.NewOCMirrorCommand()
 	cmd.SetV2(version == "v2")
 	cmd.SetSkipTLS(tr.config.SkipTLS)
+	cmd.SetEnv(tr.proxyEnv())
+	cmd.SetExtraArgs(tr.config.ExtraArgs)
+	cmd.SetLogFile(logFile)
+	cmd.SetTimeout(tr.config.DownloadTimeout)
+	tr.applyLogRetention(cmd)
 
 	// Use version-specific config file
 	var configFile string
@@ -501,22 +808,36 @@ func (tr *TestRunner) runDownloadPhase(isCleanRun bool, version string) (PhaseMe
 	cmd.SetConfig(configFile)
 	cmd.SetOutput(mirrorDir)
 	if version == "v2" {
-		cmd.SetCacheDir("operators-v2")
+		cmd.SetCacheDir(tr.cacheDir(version))
 	}
 
+	diskIOMonitor := tr.startDiskIOMonitor(version, false)
+	cacheMonitor := startCacheMonitor(tr.cacheDir(version))
+	pluginMonitors := tr.startMonitorPlugins()
+
 	startTime := time.Now()
 
 	// Execute with callback to get oc-mirror process PID for monitoring
+	endTrace := tr.traceOCMirror("download", cmd)
+	stopProgress := func() {}
 	output, err := cmd.ExecuteWithCallback(func(pid int) {
 		// Set target PID to monitor the oc-mirror process, not the test runner
 		resourceMonitor.SetTargetPID(pid)
 		if startErr := resourceMonitor.Start(); startErr != nil {
-			fmt.Printf("  │ Warning: Failed to start resource monitoring for oc-mirror (PID %d): %v\n", pid, startErr)
+			logging.Printf("  │ Warning: Failed to start resource monitoring for oc-mirror (PID %d): %v\n", pid, startErr)
 		} else {
-			fmt.Printf("  │ Monitoring oc-mirror process (PID: %d)\n", pid)
+			logging.Printf("  │ Monitoring oc-mirror process (PID: %d)\n", pid)
 		}
+		startProcessNetworkMonitor(processNetworkMonitor, pid)
+		startMemoryCeilingMonitor(memoryCeilingMonitor, pid)
+		stopProgress = tr.startDownloadProgress(version, downloadMonitor)
+		killer.start(pid)
 	})
+	killer.stop()
+	stopProgress()
 	metrics.WallTime = time.Since(startTime)
+	metrics.Timeout = output.TimedOut
+	endTrace(output, err)
 
 	// Stop all monitors and collect metrics
 	downloadMetrics := downloadMonitor.Stop()
@@ -524,37 +845,69 @@ func (tr *TestRunner) runDownloadPhase(isCleanRun bool, version string) (PhaseMe
 
 	resourceMetrics := resourceMonitor.Stop()
 	metrics.ResourceMetrics = resourceMetrics
+	metrics.DiskIOMetrics = stopDiskIOMonitor(diskIOMonitor)
+	metrics.ProcessNetworkMetrics = stopProcessNetworkMonitor(processNetworkMonitor)
+	metrics.MemoryCeiling = stopMemoryCeilingMonitor(memoryCeilingMonitor)
+	metrics.CacheMetrics = stopCacheMonitor(cacheMonitor)
+	metrics.Plugins = stopMonitorPlugins(pluginMonitors)
 
 	// Extract extended metrics from logs
 	extendedMetrics := output.ExtractExtendedMetrics()
 	metrics.ExtendedMetrics = extendedMetrics
+	recordLogs(&metrics, output)
+	metrics.RetryTimeline = downloadRetryTimeline(output, &metrics)
 
 	if err != nil {
 		// Still collect metrics even on error
-		fmt.Printf("  │ Download failed but collected metrics\n")
+		if metrics.Timeout {
+			logging.Printf("  │ Download killed after the %v timeout\n", tr.config.DownloadTimeout)
+		}
+		logging.Printf("  │ Download failed but collected metrics\n")
 		return metrics, fmt.Errorf("oc-mirror download failed: %w", err)
 	}
 
-	// Parse logs for cache hits and skipped images
-	metrics.Logs = output.Logs
+	// Skipped images from the logs; cache hits from the cache directory
 	metrics.ImagesSkipped = output.CountSkippedImages()
-	metrics.CacheHits = output.CountCacheHits()
+	metrics.CacheHits = cacheHits(metrics.CacheMetrics)
+	tr.inferCacheHitRatio(metrics.CacheMetrics, isCleanRun, version)
 
 	// Print comprehensive download summary
-	fmt.Printf("  │ Download completed in %v\n", metrics.WallTime)
-	fmt.Printf("  │ Images skipped: %d | Cache hits: %d\n", metrics.ImagesSkipped, metrics.CacheHits)
+	logging.Printf("  │ Download completed in %v\n", metrics.WallTime)
+	logging.Printf("  │ Images skipped: %d | Cache hits: %d\n", metrics.ImagesSkipped, metrics.CacheHits)
+	logging.Printf("  │ Log: %s\n", metrics.LogFile)
 	downloadMetrics.PrintSummary()
 	resourceMetrics.PrintSummary()
+	if metrics.CacheMetrics != nil {
+		metrics.CacheMetrics.PrintSummary()
+	}
+	if metrics.DiskIOMetrics != nil {
+		metrics.DiskIOMetrics.PrintSummary()
+	}
+	if metrics.ProcessNetworkMetrics != nil {
+		metrics.ProcessNetworkMetrics.PrintSummary()
+	}
+	if metrics.MemoryCeiling != nil {
+		metrics.MemoryCeiling.PrintSummary()
+	}
 	extendedMetrics.PrintSummary()
+	metrics.LogSummary.PrintSummary()
+	metrics.RetryTimeline.PrintSummary()
 
 	return metrics, nil
 }
 
-func (tr *TestRunner) runUploadPhase(version string) (PhaseMetrics, error) {
-	metrics := PhaseMetrics{}
+func (tr *TestRunner) runUploadPhase(version, logFile string) (PhaseMetrics, error) {
+	metrics := PhaseMetrics{LogFile: logFile}
+
+	// Hold the upload until an allowed window on shared registries
+	pacingApplied, windowEnd, err := tr.waitForUploadWindow(version)
+	metrics.Pacing = pacingApplied
+	if err != nil {
+		return metrics, fmt.Errorf("upload pacing: %w", err)
+	}
 
 	// Normalize registry URL: remove trailing slashes and ensure proper format
-	registryURL := strings.TrimRight(tr.config.RegistryURL, "/")
+	registryURL := strings.TrimRight(tr.targetRegistry(), "/")
 
 	// For v1, oc-mirror requires docker:// prefix with scheme delimiter
 	// For v2, keep docker:// prefix if present
@@ -576,6 +929,9 @@ func (tr *TestRunner) runUploadPhase(version string) (PhaseMetrics, error) {
 				normalizedURL = parts[0] + "://" + hostPort
 			}
 		}
+	} else if tr.config.IsOCITarget() {
+		// v2: local OCI layout destination, passed through as-is
+		normalizedURL = registryURL
 	} else {
 		// v2: ensure docker:// prefix is present
 		if !strings.Contains(registryURL, "://") {
@@ -585,47 +941,91 @@ func (tr *TestRunner) runUploadPhase(version string) (PhaseMetrics, error) {
 		}
 	}
 
+	// Push through the accounting proxy to count the exact upload traffic; its certificate is self-signed
+	route := tr.startAccountingProxy()
+	normalizedURL = route.routeURL(normalizedURL)
+
 	// Prepare resource monitor for oc-mirror process (will be started when we get the PID)
-	resourceMonitor := monitor.NewResourceMonitor()
-	resourceMonitor.SetPollInterval(500 * time.Millisecond) // More frequent sampling for child process
+	resourceMonitor := tr.newProcessResourceMonitor()
+	tr.observe(resourceMonitor, sampleSourceResource)
+	processNetworkMonitor := tr.newProcessNetworkMonitor()
+	memoryCeilingMonitor := tr.newMemoryCeilingMonitor()
 
 	cmd := command.NewOCMirrorCommand()
 	cmd.SetV2(version == "v2")
-	cmd.SetSkipTLS(tr.config.SkipTLS)
+	cmd.SetSkipTLS(tr.config.SkipTLS || route != nil)
+	cmd.SetEnv(append(tr.proxyEnv(), route.environ()...))
+	cmd.SetExtraArgs(tr.uploadArgs(version))
+	cmd.SetLogFile(logFile)
+	cmd.SetTimeout(tr.config.UploadTimeout)
+	tr.applyLogRetention(cmd)
 
 	var platformConfigPath string
 	if version == "v1" {
 		// v1: Use platform config with --from flag to upload from local mirror
 		platformConfigPath = "platform/platform_config-v1.yaml"
-		if err := config.CreatePlatformConfigWithVersion(platformConfigPath, "v1alpha2"); err != nil {
+		if err := config.CreatePlatformConfigForContent(platformConfigPath, "v1alpha2", tr.mirroredContent()); err != nil {
 			return metrics, fmt.Errorf("failed to create platform config: %w", err)
 		}
 		cmd.SetConfig(platformConfigPath)
-		cmd.SetFrom("mirror/operators-v1/")
+		cmd.SetFrom(tr.uploadFrom(version))
+		cmd.SetOutput(normalizedURL)
+	} else if tr.config.AirGap.Enabled {
+		// v2 disk-to-mirror: from the transferred archive, with the cache of the disconnected side
+		// Command: oc-mirror --v2 --cache-dir airgap/cache-v2 -c <config> --from file://airgap/v2 docker://registry
+		cmd.SetConfig("oc-mirror-clone/imagesetconfiguration_operators-v2.yaml")
+		cmd.SetCacheDir(airGapCacheDir)
+		cmd.SetFrom("file://" + airGapDir(version))
 		cmd.SetOutput(normalizedURL)
 	} else {
 		// v2: Use original imageset config with --cache-dir, output directly to registry
 		// Command: oc-mirror --v2 --cache-dir operators-v2 -c <config> --workspace file://./mirror/operators-v2/ --dest-tls-verify=false docker://registry
 		cmd.SetConfig("oc-mirror-clone/imagesetconfiguration_operators-v2.yaml")
-		cmd.SetCacheDir("operators-v2")
-		cmd.SetWorkspace("file://./mirror/operators-v2/")
+		cmd.SetCacheDir(tr.cacheDir(version))
+		cmd.SetWorkspace(tr.workspaceURL(version))
 		cmd.SetOutput(normalizedURL)
 		// Note: v2 does NOT use --from flag
 	}
 
+	// For OCI layout targets, measure delivery by the data written to the layout
+	var layoutMonitor *monitor.DiskWriteMonitor
+	var layoutBaseline int64
+	if tr.config.IsOCITarget() {
+		layoutMonitor = monitor.NewDiskWriteMonitor(tr.config.OCILayoutPath())
+		layoutBaseline = layoutMonitor.GetCurrentStats().TotalBytes
+		tr.observe(layoutMonitor, sampleSourceLayoutWrite)
+		if err := layoutMonitor.Start(); err != nil {
+			logging.Printf("  │ Warning: Failed to start OCI layout write monitoring: %v\n", err)
+		}
+	}
+
+	diskIOMonitor := tr.startDiskIOMonitor(version, true)
+	cacheMonitor := startCacheMonitor(tr.uploadCacheDir(version))
+	pluginMonitors := tr.startMonitorPlugins()
+	accessLogMonitor := tr.startAccessLogMonitor()
+	registryServerMonitor := tr.startRegistryServerMonitor()
+
 	startTime := time.Now()
 
 	// Execute with callback to get oc-mirror process PID for monitoring
+	endTrace := tr.traceOCMirror("upload", cmd)
+	stopProgress := func() {}
 	output, err := cmd.ExecuteWithCallback(func(pid int) {
 		// Set target PID to monitor the oc-mirror process, not the test runner
 		resourceMonitor.SetTargetPID(pid)
 		if startErr := resourceMonitor.Start(); startErr != nil {
-			fmt.Printf("  │ Warning: Failed to start resource monitoring for oc-mirror (PID %d): %v\n", pid, startErr)
+			logging.Printf("  │ Warning: Failed to start resource monitoring for oc-mirror (PID %d): %v\n", pid, startErr)
 		} else {
-			fmt.Printf("  │ Monitoring oc-mirror process (PID: %d)\n", pid)
+			logging.Printf("  │ Monitoring oc-mirror process (PID: %d)\n", pid)
 		}
+		startProcessNetworkMonitor(processNetworkMonitor, pid)
+		startMemoryCeilingMonitor(memoryCeilingMonitor, pid)
+		stopProgress = tr.startUploadProgress(version)
 	})
+	stopProgress()
 	metrics.WallTime = time.Since(startTime)
+	metrics.Timeout = output.TimedOut
+	endTrace(output, err)
 
 	// Stop resource monitoring
 	resourceMetrics := resourceMonitor.Stop()
@@ -634,6 +1034,7 @@ func (tr *TestRunner) runUploadPhase(version string) (PhaseMetrics, error) {
 	// Extract extended metrics from logs
 	extendedMetrics := output.ExtractExtendedMetrics()
 	metrics.ExtendedMetrics = extendedMetrics
+	recordLogs(&metrics, output)
 
 	// If upload failed with invalid reference format or scheme delimiter, try fallback
 	if err != nil && (strings.Contains(err.Error(), "invalid reference format") ||
@@ -646,126 +1047,260 @@ func (tr *TestRunner) runUploadPhase(version string) (PhaseMetrics, error) {
 			if len(parts) == 2 {
 				hostPort := strings.Split(parts[1], "/")[0]
 				fallbackURL = parts[0] + "://" + hostPort
-				fmt.Printf("  │ Retrying with fallback registry URL: %s\n", fallbackURL)
+				logging.Printf("  │ Retrying with fallback registry URL: %s\n", fallbackURL)
 
 				// Create new command with fallback URL
 				cmdFallback := command.NewOCMirrorCommand()
 				cmdFallback.SetV2(false)
-				cmdFallback.SetSkipTLS(tr.config.SkipTLS)
+				cmdFallback.SetSkipTLS(tr.config.SkipTLS || route != nil)
+				cmdFallback.SetEnv(append(tr.proxyEnv(), route.environ()...))
+				cmdFallback.SetExtraArgs(tr.uploadArgs(version))
 				cmdFallback.SetConfig(platformConfigPath)
-				cmdFallback.SetFrom("mirror/operators-v1/")
+				cmdFallback.SetFrom(tr.uploadFrom(version))
 				cmdFallback.SetOutput(fallbackURL)
+				metrics.LogFile = strings.TrimSuffix(logFile, ".log") + "_fallback.log"
+				cmdFallback.SetLogFile(metrics.LogFile)
+				cmdFallback.SetTimeout(tr.config.UploadTimeout)
+				tr.applyLogRetention(cmdFallback)
 
 				// Retry with fallback URL
 				startTime = time.Now()
+				endTrace = tr.traceOCMirror("upload fallback", cmdFallback)
 				output, err = cmdFallback.ExecuteWithCallback(func(pid int) {
 					resourceMonitor.SetTargetPID(pid)
 					if startErr := resourceMonitor.Start(); startErr != nil {
-						fmt.Printf("  │ Warning: Failed to start resource monitoring for oc-mirror (PID %d): %v\n", pid, startErr)
+						logging.Printf("  │ Warning: Failed to start resource monitoring for oc-mirror (PID %d): %v\n", pid, startErr)
 					} else {
-						fmt.Printf("  │ Monitoring oc-mirror process (PID: %d)\n", pid)
+						logging.Printf("  │ Monitoring oc-mirror process (PID: %d)\n", pid)
 					}
+					startProcessNetworkMonitor(processNetworkMonitor, pid)
+					startMemoryCeilingMonitor(memoryCeilingMonitor, pid)
 				})
 				metrics.WallTime = time.Since(startTime)
+				metrics.Timeout = output.TimedOut
+				endTrace(output, err)
 
 				// Update metrics after retry
 				resourceMetrics = resourceMonitor.Stop()
 				metrics.ResourceMetrics = resourceMetrics
 				extendedMetrics = output.ExtractExtendedMetrics()
 				metrics.ExtendedMetrics = extendedMetrics
+				recordLogs(&metrics, output)
 			}
 		}
 	}
 
+	if layoutMonitor != nil {
+		diskMetrics := layoutMonitor.Stop()
+		metrics.DiskWriteMetrics = &diskMetrics
+	}
+	metrics.DiskIOMetrics = stopDiskIOMonitor(diskIOMonitor)
+	metrics.ProcessNetworkMetrics = stopProcessNetworkMonitor(processNetworkMonitor)
+	metrics.MemoryCeiling = stopMemoryCeilingMonitor(memoryCeilingMonitor)
+	metrics.CacheMetrics = stopCacheMonitor(cacheMonitor)
+	metrics.Plugins = stopMonitorPlugins(pluginMonitors)
+	metrics.RetryTimeline = tr.uploadRetryTimeline(output, &metrics)
+	metrics.HTTPStatus = tr.uploadHTTPStatus(output, accessLogMonitor, &metrics)
+	metrics.RegistryServer = tr.stopRegistryServerMonitor(registryServerMonitor)
+	metrics.UploadTraffic = route.stop(tr.clusterResourcesDir(version))
+	finishUploadWindow(pacingApplied, windowEnd)
+
 	if err != nil {
-		// Still show metrics on error
-		fmt.Printf("  │ Upload failed but collected metrics\n")
+		// Still show metrics on error; throttling often explains the failure
+		if metrics.Timeout {
+			logging.Printf("  │ Upload killed after the %v timeout\n", tr.config.UploadTimeout)
+		}
+		logging.Printf("  │ Upload failed but collected metrics\n")
+		metrics.HTTPStatus.PrintSummary()
+		metrics.UploadTraffic.PrintSummary()
 		return metrics, fmt.Errorf("oc-mirror upload failed: %w", err)
 	}
 
 	// Parse logs for bytes uploaded
-	metrics.Logs = output.Logs
 	metrics.BytesUploaded = output.ExtractBytesUploaded()
+	if metrics.UploadTraffic != nil {
+		// The request bodies the proxy forwarded, instead of the estimate from the logs
+		metrics.BytesUploaded = metrics.UploadTraffic.RequestBytes
+	}
+	if metrics.DiskWriteMetrics != nil {
+		// Layout growth is the authoritative delivery size for oci:// targets
+		metrics.BytesUploaded = max(metrics.DiskWriteMetrics.TotalBytesWritten-layoutBaseline, 0)
+		logging.Printf("  │ OCI layout: %d files | Avg write: %.2f MB/s | Peak write: %.2f MB/s\n",
+			metrics.DiskWriteMetrics.TotalFiles,
+			metrics.DiskWriteMetrics.AverageWriteRateMBs,
+			metrics.DiskWriteMetrics.PeakWriteRateMBs)
+	}
 	metrics.ImagesSkipped = output.CountSkippedImages()
-	metrics.CacheHits = output.CountCacheHits()
+	metrics.CacheHits = cacheHits(metrics.CacheMetrics)
+	metrics.ClusterResources = output.ExtractClusterResources(tr.clusterResourcesDir(version))
 
 	// Print comprehensive upload summary
-	fmt.Printf("  │ Upload completed in %v\n", metrics.WallTime)
-	fmt.Printf("  │ Bytes uploaded: %s\n", monitor.FormatBytesHuman(metrics.BytesUploaded))
-	fmt.Printf("  │ Images skipped: %d | Cache hits: %d\n", metrics.ImagesSkipped, metrics.CacheHits)
+	logging.Printf("  │ Upload completed in %v\n", metrics.WallTime)
+	logging.Printf("  │ Bytes uploaded: %s\n", monitor.FormatBytesHuman(metrics.BytesUploaded))
+	logging.Printf("  │ Images skipped: %d | Cache hits: %d\n", metrics.ImagesSkipped, metrics.CacheHits)
+	logging.Printf("  │ Log: %s\n", metrics.LogFile)
 	resourceMetrics.PrintSummary()
+	if metrics.CacheMetrics != nil {
+		metrics.CacheMetrics.PrintSummary()
+	}
+	if metrics.DiskIOMetrics != nil {
+		metrics.DiskIOMetrics.PrintSummary()
+	}
+	if metrics.ProcessNetworkMetrics != nil {
+		metrics.ProcessNetworkMetrics.PrintSummary()
+	}
+	if metrics.MemoryCeiling != nil {
+		metrics.MemoryCeiling.PrintSummary()
+	}
 	extendedMetrics.PrintSummary()
+	metrics.LogSummary.PrintSummary()
+	metrics.RetryTimeline.PrintSummary()
+	metrics.HTTPStatus.PrintSummary()
+	metrics.UploadTraffic.PrintSummary()
+	metrics.ClusterResources.PrintSummary()
 
 	return metrics, nil
 }
 
+// clusterResourcesDir returns where oc-mirror writes the cluster resources of an upload
+func (tr *TestRunner) clusterResourcesDir(version string) string {
+	if version == "v1" {
+		return command.LatestResultsDir("oc-mirror-workspace")
+	}
+	if tr.config.AirGap.Enabled {
+		return airGapDir(version) + "/working-dir/cluster-resources"
+	}
+	return filepath.Join(tr.mirrorDir(version), "working-dir", "cluster-resources")
+}
+
 func (tr *TestRunner) printIterationSummary(result TestResult) {
-	fmt.Printf("\n╔═══════════════════════════════════════════════════════════════════════════════╗\n")
-	fmt.Printf("║  Iteration %d Summary (%s) - %s                                               ║\n",
-		result.Iteration, result.Version, map[bool]string{true: "CLEAN RUN", false: "CACHED RUN"}[result.IsCleanRun])
-	fmt.Printf("╠═══════════════════════════════════════════════════════════════════════════════╣\n")
+	logging.Printf("\n╔═══════════════════════════════════════════════════════════════════════════════╗\n")
+	logging.Printf("║  Iteration %d Summary (%s) - %s                                               ║\n",
+		result.Iteration, result.Version, runKind(result.IsCleanRun, result.IsUpdateRun)+" RUN")
+	logging.Printf("╠═══════════════════════════════════════════════════════════════════════════════╣\n")
 
 	// Timing
-	fmt.Printf("║  TIMING                                                                       ║\n")
-	fmt.Printf("║    Download: %-65v ║\n", result.DownloadPhase.WallTime)
-	fmt.Printf("║    Upload:   %-65v ║\n", result.UploadPhase.WallTime)
-	fmt.Printf("║    Total:    %-65v ║\n", result.DownloadPhase.WallTime+result.UploadPhase.WallTime)
+	logging.Printf("║  TIMING                                                                       ║\n")
+	logging.Printf("║    Download: %-65v ║\n", result.DownloadPhase.WallTime)
+	logging.Printf("║    Upload:   %-65v ║\n", result.UploadPhase.WallTime)
+	logging.Printf("║    Total:    %-65v ║\n", result.DownloadPhase.WallTime+result.UploadPhase.WallTime)
+	if s := result.Stage; s != nil {
+		logging.Printf("║    Stage:    %-65s ║\n", fmt.Sprintf("%d/%d %s, available after %v", s.Index, s.Count, s.Name, s.TimeToContent.Round(time.Second)))
+	}
+	if result.TraceID != "" {
+		logging.Printf("║    Trace:    %-65s ║\n", result.TraceID)
+	}
+	if c := result.Chaos; c != nil && c.Killed {
+		logging.Printf("║    Killed:   %-65s ║\n", fmt.Sprintf("after %v, %.1f%% resumed, %s redone (%v in total)",
+			c.KilledAt.Round(time.Second), c.ResumedPercent, monitor.FormatBytesHuman(c.RedoneBytes), c.TotalWallTime.Round(time.Second)))
+	}
+	if ag := result.AirGap; ag != nil {
+		logging.Printf("║    Archive:  %-65s ║\n", fmt.Sprintf("%v (%s in %d file(s))",
+			ag.Archive.CreationTime.Round(time.Millisecond), monitor.FormatBytesHuman(ag.Archive.TotalBytes), len(ag.Archive.Files)))
+		logging.Printf("║    Transfer: %-65s ║\n", fmt.Sprintf("%v (%.2f MB/s)", ag.TransferTime.Round(time.Millisecond), ag.TransferRateMBs))
+	}
+	for _, hop := range result.Replication {
+		logging.Printf("║    Hop %d:    %-65s ║\n", hop.Hop, fmt.Sprintf("%v to %s (%d repositories, %d tags)",
+			hop.WallTime.Round(time.Millisecond), registryHost(hop.Destination), hop.Repositories, hop.Tags))
+	}
+	if cr := result.UploadPhase.ClusterResources; cr != nil {
+		logging.Printf("║    Cluster resources: %-56s ║\n", fmt.Sprintf("%v (%d files, %.1f KB)",
+			cr.GenerationTime.Round(time.Millisecond), cr.TotalFiles, float64(cr.TotalBytes)/1024))
+	}
 
 	// Data Transfer
-	fmt.Printf("║  DATA TRANSFER                                                                ║\n")
-	fmt.Printf("║    Downloaded: %-63s ║\n", monitor.FormatBytesHuman(result.DownloadPhase.DownloadMetrics.TotalBytesDownloaded))
-	fmt.Printf("║    Avg Speed:  %.2f MB/s | Peak: %.2f MB/s                                    ║\n",
+	logging.Printf("║  DATA TRANSFER                                                                ║\n")
+	logging.Printf("║    Downloaded: %-63s ║\n", monitor.FormatBytesHuman(result.DownloadPhase.DownloadMetrics.TotalBytesDownloaded))
+	logging.Printf("║    Avg Speed:  %.2f MB/s | Peak: %.2f MB/s                                    ║\n",
 		result.DownloadPhase.DownloadMetrics.AverageSpeedMBs, result.DownloadPhase.DownloadMetrics.PeakSpeedMBs)
 
 	// Resource Usage
-	fmt.Printf("║  RESOURCE USAGE                                                               ║\n")
-	fmt.Printf("║    CPU:    Avg %.2f%% | Peak %.2f%%                                            ║\n",
+	logging.Printf("║  RESOURCE USAGE                                                               ║\n")
+	logging.Printf("║    CPU:    Avg %.2f%% | Peak %.2f%%                                            ║\n",
 		result.ResourceMetrics.CPUAvgPercent, result.ResourceMetrics.CPUPeakPercent)
-	fmt.Printf("║    Memory: Avg %.2f MB | Peak %.2f MB                                         ║\n",
+	logging.Printf("║    Memory: Avg %.2f MB | Peak %.2f MB                                         ║\n",
 		result.ResourceMetrics.MemoryAvgMB, result.ResourceMetrics.MemoryPeakMB)
+	if tr.config.MemoryCeiling.Enabled() {
+		logging.Printf("║    Budget: %-66s ║\n", fmt.Sprintf("peak %.0f%% of %s | OOM killer: %s",
+			memoryCeilingPeak(result), monitor.FormatBytesHuman(tr.config.MemoryCeiling.BudgetBytes()),
+			map[bool]string{true: "ACTIVATED", false: "not activated"}[oomKillerActivated(result)]))
+	}
 
 	// Network
-	fmt.Printf("║  NETWORK                                                                      ║\n")
-	fmt.Printf("║    Bandwidth: Avg %.2f Mbps | Peak %.2f Mbps                                  ║\n",
+	logging.Printf("║  NETWORK                                                                      ║\n")
+	logging.Printf("║    Bandwidth: Avg %.2f Mbps | Peak %.2f Mbps                                  ║\n",
 		result.NetworkMetrics.AverageBandwidthMbps, result.NetworkMetrics.PeakBandwidthMbps)
 
 	// Image/Layer Processing (from oc-mirror describe)
-	fmt.Printf("║  MIRROR CONTENT                                                               ║\n")
+	logging.Printf("║  MIRROR CONTENT                                                               ║\n")
 	if result.DescribeMetrics != nil {
-		fmt.Printf("║    Images: %d | Layers: %d | Manifests: %d                                    ║\n",
+		logging.Printf("║    Images: %d | Layers: %d | Manifests: %d                                    ║\n",
 			result.DescribeMetrics.TotalImages, result.DescribeMetrics.TotalLayers, result.DescribeMetrics.TotalManifests)
-		fmt.Printf("║    Operator Packages: %d | Associations: %d                                   ║\n",
+		logging.Printf("║    Operator Packages: %d | Associations: %d                                   ║\n",
 			result.DescribeMetrics.OperatorPackages, result.DescribeMetrics.TotalAssociations)
 	} else {
-		fmt.Printf("║    (oc-mirror describe not available)                                        ║\n")
+		logging.Printf("║    (oc-mirror describe not available)                                        ║\n")
+	}
+	if ec := result.ExpectedContent; ec != nil {
+		logging.Printf("║    Expected: %-65s ║\n", fmt.Sprintf("%d of %d images found, %d missing (%d bundles)",
+			ec.Found, ec.Expected, len(ec.Missing), ec.Bundles))
+	}
+	if ps := result.PackageSizes; ps != nil && len(ps.Packages) > 0 {
+		top := ps.Packages[0]
+		logging.Printf("║    Packages: %-65s ║\n", fmt.Sprintf("%d, largest %s with %s of %s",
+			len(ps.Packages), top.Package, monitor.FormatBytesHuman(top.Bytes), monitor.FormatBytesHuman(ps.TotalBytes)))
 	}
-	fmt.Printf("║    Cache Hits: %d | Errors: %d | Retries: %d                                  ║\n",
+	if uv := result.UploadVerification; uv != nil {
+		logging.Printf("║    Upload:   %-65s ║\n", fmt.Sprintf("%s, %d of %d local blobs in the registry",
+			uv.Verdict, uv.Matched, uv.LocalDigests))
+	}
+	if ca := result.ClusterArtifacts; ca != nil {
+		logging.Printf("║    Artifacts: %-64s ║\n", fmt.Sprintf("%d mirror sets, %d catalog sources, %d of %d references outside the registry",
+			ca.MirrorSets, ca.CatalogSources, ca.MismatchCount, ca.Checked))
+	}
+	if pt := result.PullThrough; pt != nil {
+		verdict := "consumable"
+		if !pt.Consumable {
+			verdict = "not consumable"
+		}
+		logging.Printf("║    Cluster:  %-65s ║\n", fmt.Sprintf("%s, %d catalog(s), %d mirrored package(s) missing",
+			verdict, len(pt.Catalogs), pt.MissingCount()))
+	}
+	logging.Printf("║    Cache Hits: %d | Errors: %d | Retries: %d                                  ║\n",
 		result.DownloadPhase.CacheHits,
 		result.DownloadPhase.ExtendedMetrics.ErrorCount+result.UploadPhase.ExtendedMetrics.ErrorCount,
 		result.DownloadPhase.ExtendedMetrics.RetryCount+result.UploadPhase.ExtendedMetrics.RetryCount)
+	if breakdown := command.FormatErrorCategories(command.MergeErrorCategories(result.DownloadPhase.ExtendedMetrics, result.UploadPhase.ExtendedMetrics)); breakdown != "" {
+		logging.Printf("║    Error Types: %-62s ║\n", breakdown)
+	}
 
 	// Output
-	fmt.Printf("║  OUTPUT                                                                       ║\n")
-	fmt.Printf("║    Total Size: %-63s ║\n", monitor.FormatBytesHuman(result.OutputMetrics.TotalSize))
-	fmt.Printf("║    Files: %d | Directories: %d                                                ║\n",
+	logging.Printf("║  OUTPUT                                                                       ║\n")
+	logging.Printf("║    Total Size: %-63s ║\n", monitor.FormatBytesHuman(result.OutputMetrics.TotalSize))
+	logging.Printf("║    Files: %d | Directories: %d                                                ║\n",
 		result.OutputMetrics.TotalFiles, result.OutputMetrics.TotalDirs)
 
-	fmt.Printf("╚═══════════════════════════════════════════════════════════════════════════════╝\n")
+	logging.Printf("╚═══════════════════════════════════════════════════════════════════════════════╝\n")
 }
 
 func (tr *TestRunner) compareCleanVsCached() {
-	if len(tr.results) < 2 {
+	measured := measuredResults(tr.results)
+	results := completedResults(measured)
+	if len(results) < 2 || !results[0].IsCleanRun {
+		if len(results) < len(measured) {
+			logging.Printf("\nSkipping clean vs cached comparison: not enough completed iterations\n")
+		}
 		return
 	}
 
-	fmt.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
-	fmt.Printf("║  Comparison: Clean vs Cached                                  ║\n")
-	fmt.Printf("╠═══════════════════════════════════════════════════════════════╣\n")
+	logging.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
+	logging.Printf("║  Comparison: Clean vs Cached                                  ║\n")
+	logging.Printf("╠═══════════════════════════════════════════════════════════════╣\n")
 
-	cleanResult := tr.results[0]
+	cleanResult := results[0]
 	var cachedResults []TestResult
-	for i := 1; i < len(tr.results); i++ {
-		cachedResults = append(cachedResults, tr.results[i])
+	for i := 1; i < len(results); i++ {
+		cachedResults = append(cachedResults, results[i])
 	}
 
 	// Calculate averages for cached runs
@@ -788,225 +1323,108 @@ func (tr *TestRunner) compareCleanVsCached() {
 		avgCachedCacheHits /= len(cachedResults)
 	}
 
-	fmt.Printf("║  Download Time:                                                 ║\n")
-	fmt.Printf("║    Clean:  %-52v ║\n", cleanResult.DownloadPhase.WallTime)
-	fmt.Printf("║    Cached: %-52v ║\n", avgCachedDownloadTime)
+	logging.Printf("║  Download Time:                                                 ║\n")
+	logging.Printf("║    Clean:  %-52v ║\n", cleanResult.DownloadPhase.WallTime)
+	logging.Printf("║    Cached: %-52v ║\n", avgCachedDownloadTime)
 	if avgCachedDownloadTime > 0 {
 		improvement := float64(cleanResult.DownloadPhase.WallTime-avgCachedDownloadTime) / float64(cleanResult.DownloadPhase.WallTime) * 100
-		fmt.Printf("║    Improvement: %-46.2f%% ║\n", improvement)
+		logging.Printf("║    Improvement: %-46.2f%% ║\n", improvement)
 	}
 
-	fmt.Printf("║                                                                ║\n")
-	fmt.Printf("║  Upload Time:                                                   ║\n")
-	fmt.Printf("║    Clean:  %-52v ║\n", cleanResult.UploadPhase.WallTime)
-	fmt.Printf("║    Cached: %-52v ║\n", avgCachedUploadTime)
+	logging.Printf("║                                                                ║\n")
+	logging.Printf("║  Upload Time:                                                   ║\n")
+	logging.Printf("║    Clean:  %-52v ║\n", cleanResult.UploadPhase.WallTime)
+	logging.Printf("║    Cached: %-52v ║\n", avgCachedUploadTime)
 	if avgCachedUploadTime > 0 {
 		improvement := float64(cleanResult.UploadPhase.WallTime-avgCachedUploadTime) / float64(cleanResult.UploadPhase.WallTime) * 100
-		fmt.Printf("║    Improvement: %-46.2f%% ║\n", improvement)
+		logging.Printf("║    Improvement: %-46.2f%% ║\n", improvement)
 	}
 
-	fmt.Printf("║                                                                ║\n")
-	fmt.Printf("║  Cache Hits:                                                    ║\n")
-	fmt.Printf("║    Clean:  %-52d ║\n", cleanResult.DownloadPhase.CacheHits)
-	fmt.Printf("║    Cached: %-52d ║\n", avgCachedCacheHits)
+	logging.Printf("║                                                                ║\n")
+	logging.Printf("║  Cache Hits:                                                    ║\n")
+	logging.Printf("║    Clean:  %-52d ║\n", cleanResult.DownloadPhase.CacheHits)
+	logging.Printf("║    Cached: %-52d ║\n", avgCachedCacheHits)
 
-	fmt.Printf("║                                                                ║\n")
-	fmt.Printf("║  Bytes Uploaded:                                                ║\n")
-	fmt.Printf("║    Clean:  %-52d (%.2f MB) ║\n", cleanResult.UploadPhase.BytesUploaded, float64(cleanResult.UploadPhase.BytesUploaded)/(1024*1024))
-	fmt.Printf("║    Cached: %-52d (%.2f MB) ║\n", avgCachedBytes, float64(avgCachedBytes)/(1024*1024))
-	fmt.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+	logging.Printf("║                                                                ║\n")
+	logging.Printf("║  Bytes Uploaded:                                                ║\n")
+	logging.Printf("║    Clean:  %-52d (%.2f MB) ║\n", cleanResult.UploadPhase.BytesUploaded, float64(cleanResult.UploadPhase.BytesUploaded)/(1024*1024))
+	logging.Printf("║    Cached: %-52d (%.2f MB) ║\n", avgCachedBytes, float64(avgCachedBytes)/(1024*1024))
+	logging.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
 }
 
 func (tr *TestRunner) compareV1VsV2(v1Results, v2Results []TestResult) {
 	if len(v1Results) == 0 || len(v2Results) == 0 {
 		return
 	}
+	if !v1Results[0].IsCleanRun || !v2Results[0].IsCleanRun {
+		logging.Printf("\nSkipping v1 vs v2 comparison: a clean run did not complete\n")
+		return
+	}
 
-	fmt.Printf("\n╔═══════════════════════════════════════════════════════════════════════════════╗\n")
-	fmt.Printf("║                    COMPREHENSIVE V1 vs V2 COMPARISON                          ║\n")
-	fmt.Printf("╠═══════════════════════════════════════════════════════════════════════════════╣\n")
-
-	// Compare clean runs (first iteration)
-	v1Clean := v1Results[0]
-	v2Clean := v2Results[0]
-
-	// === TIMING COMPARISON ===
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  ═══ TIMING METRICS ═══════════════════════════════════════════════════════   ║\n")
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  Download Time:                                                               ║\n")
-	fmt.Printf("║    V1: %-71v ║\n", v1Clean.DownloadPhase.WallTime)
-	fmt.Printf("║    V2: %-71v ║\n", v2Clean.DownloadPhase.WallTime)
-	if v1Clean.DownloadPhase.WallTime > 0 {
-		diff := float64(v1Clean.DownloadPhase.WallTime-v2Clean.DownloadPhase.WallTime) / float64(v1Clean.DownloadPhase.WallTime) * 100
-		status := "faster"
-		if diff < 0 {
-			status = "slower"
-			diff = -diff
-		}
-		fmt.Printf("║    V2 is %.2f%% %s                                                          ║\n", diff, status)
-	}
-
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  Upload Time:                                                                 ║\n")
-	fmt.Printf("║    V1: %-71v ║\n", v1Clean.UploadPhase.WallTime)
-	fmt.Printf("║    V2: %-71v ║\n", v2Clean.UploadPhase.WallTime)
-	if v1Clean.UploadPhase.WallTime > 0 {
-		diff := float64(v1Clean.UploadPhase.WallTime-v2Clean.UploadPhase.WallTime) / float64(v1Clean.UploadPhase.WallTime) * 100
-		status := "faster"
-		if diff < 0 {
-			status = "slower"
-			diff = -diff
-		}
-		fmt.Printf("║    V2 is %.2f%% %s                                                          ║\n", diff, status)
-	}
-
-	totalV1 := v1Clean.DownloadPhase.WallTime + v1Clean.UploadPhase.WallTime
-	totalV2 := v2Clean.DownloadPhase.WallTime + v2Clean.UploadPhase.WallTime
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  Total Time:                                                                  ║\n")
-	fmt.Printf("║    V1: %-71v ║\n", totalV1)
-	fmt.Printf("║    V2: %-71v ║\n", totalV2)
-
-	// === DOWNLOAD SPEED COMPARISON ===
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  ═══ DOWNLOAD SPEED ═══════════════════════════════════════════════════════   ║\n")
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  Average Download Speed:                                                      ║\n")
-	fmt.Printf("║    V1: %.2f MB/s                                                              ║\n", v1Clean.DownloadPhase.DownloadMetrics.AverageSpeedMBs)
-	fmt.Printf("║    V2: %.2f MB/s                                                              ║\n", v2Clean.DownloadPhase.DownloadMetrics.AverageSpeedMBs)
-	fmt.Printf("║  Peak Download Speed:                                                         ║\n")
-	fmt.Printf("║    V1: %.2f MB/s                                                              ║\n", v1Clean.DownloadPhase.DownloadMetrics.PeakSpeedMBs)
-	fmt.Printf("║    V2: %.2f MB/s                                                              ║\n", v2Clean.DownloadPhase.DownloadMetrics.PeakSpeedMBs)
-
-	// === RESOURCE USAGE COMPARISON ===
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  ═══ RESOURCE USAGE ═══════════════════════════════════════════════════════   ║\n")
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  CPU Usage (Average / Peak):                                                  ║\n")
-	fmt.Printf("║    V1: %.2f%% / %.2f%%                                                         ║\n",
-		v1Clean.ResourceMetrics.CPUAvgPercent, v1Clean.ResourceMetrics.CPUPeakPercent)
-	fmt.Printf("║    V2: %.2f%% / %.2f%%                                                         ║\n",
-		v2Clean.ResourceMetrics.CPUAvgPercent, v2Clean.ResourceMetrics.CPUPeakPercent)
-	fmt.Printf("║  Memory Usage (Average / Peak):                                               ║\n")
-	fmt.Printf("║    V1: %.2f MB / %.2f MB                                                      ║\n",
-		v1Clean.ResourceMetrics.MemoryAvgMB, v1Clean.ResourceMetrics.MemoryPeakMB)
-	fmt.Printf("║    V2: %.2f MB / %.2f MB                                                      ║\n",
-		v2Clean.ResourceMetrics.MemoryAvgMB, v2Clean.ResourceMetrics.MemoryPeakMB)
-
-	// === NETWORK COMPARISON ===
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  ═══ NETWORK BANDWIDTH ════════════════════════════════════════════════════   ║\n")
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  Average Bandwidth:                                                           ║\n")
-	fmt.Printf("║    V1: %.2f Mbps                                                              ║\n", v1Clean.NetworkMetrics.AverageBandwidthMbps)
-	fmt.Printf("║    V2: %.2f Mbps                                                              ║\n", v2Clean.NetworkMetrics.AverageBandwidthMbps)
-	fmt.Printf("║  Peak Bandwidth:                                                              ║\n")
-	fmt.Printf("║    V1: %.2f Mbps                                                              ║\n", v1Clean.NetworkMetrics.PeakBandwidthMbps)
-	fmt.Printf("║    V2: %.2f Mbps                                                              ║\n", v2Clean.NetworkMetrics.PeakBandwidthMbps)
-
-	// === MIRROR CONTENT (from oc-mirror describe) ===
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  ═══ MIRROR CONTENT (oc-mirror describe) ══════════════════════════════════   ║\n")
-	fmt.Printf("║                                                                               ║\n")
-	if v1Clean.DescribeMetrics != nil && v2Clean.DescribeMetrics != nil {
-		fmt.Printf("║  Total Images:                                                                ║\n")
-		fmt.Printf("║    V1: %d                                                                     ║\n", v1Clean.DescribeMetrics.TotalImages)
-		fmt.Printf("║    V2: %d                                                                     ║\n", v2Clean.DescribeMetrics.TotalImages)
-		fmt.Printf("║  Total Layers:                                                                ║\n")
-		fmt.Printf("║    V1: %d                                                                     ║\n", v1Clean.DescribeMetrics.TotalLayers)
-		fmt.Printf("║    V2: %d                                                                     ║\n", v2Clean.DescribeMetrics.TotalLayers)
-		fmt.Printf("║  Total Manifests:                                                             ║\n")
-		fmt.Printf("║    V1: %d                                                                     ║\n", v1Clean.DescribeMetrics.TotalManifests)
-		fmt.Printf("║    V2: %d                                                                     ║\n", v2Clean.DescribeMetrics.TotalManifests)
-		fmt.Printf("║  Operator Packages:                                                           ║\n")
-		fmt.Printf("║    V1: %d                                                                     ║\n", v1Clean.DescribeMetrics.OperatorPackages)
-		fmt.Printf("║    V2: %d                                                                     ║\n", v2Clean.DescribeMetrics.OperatorPackages)
-		fmt.Printf("║  Total Associations:                                                          ║\n")
-		fmt.Printf("║    V1: %d                                                                     ║\n", v1Clean.DescribeMetrics.TotalAssociations)
-		fmt.Printf("║    V2: %d                                                                     ║\n", v2Clean.DescribeMetrics.TotalAssociations)
-	} else {
-		fmt.Printf("║  (oc-mirror describe metrics not available for comparison)                   ║\n")
-	}
-
-	// === ERROR/RETRY METRICS ===
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  ═══ ERROR/RETRY METRICS ══════════════════════════════════════════════════   ║\n")
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  Errors:                                                                      ║\n")
-	fmt.Printf("║    V1: %d                                                                     ║\n", v1Clean.DownloadPhase.ExtendedMetrics.ErrorCount+v1Clean.UploadPhase.ExtendedMetrics.ErrorCount)
-	fmt.Printf("║    V2: %d                                                                     ║\n", v2Clean.DownloadPhase.ExtendedMetrics.ErrorCount+v2Clean.UploadPhase.ExtendedMetrics.ErrorCount)
-	fmt.Printf("║  Retries:                                                                     ║\n")
-	fmt.Printf("║    V1: %d                                                                     ║\n", v1Clean.DownloadPhase.ExtendedMetrics.RetryCount+v1Clean.UploadPhase.ExtendedMetrics.RetryCount)
-	fmt.Printf("║    V2: %d                                                                     ║\n", v2Clean.DownloadPhase.ExtendedMetrics.RetryCount+v2Clean.UploadPhase.ExtendedMetrics.RetryCount)
-	fmt.Printf("║  Warnings:                                                                    ║\n")
-	fmt.Printf("║    V1: %d                                                                     ║\n", v1Clean.DownloadPhase.ExtendedMetrics.WarningCount+v1Clean.UploadPhase.ExtendedMetrics.WarningCount)
-	fmt.Printf("║    V2: %d                                                                     ║\n", v2Clean.DownloadPhase.ExtendedMetrics.WarningCount+v2Clean.UploadPhase.ExtendedMetrics.WarningCount)
-
-	// === OUTPUT SIZE COMPARISON ===
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  ═══ OUTPUT SIZE ══════════════════════════════════════════════════════════   ║\n")
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  Total Downloaded:                                                            ║\n")
-	fmt.Printf("║    V1: %s                                                                     ║\n", monitor.FormatBytesHuman(v1Clean.OutputMetrics.TotalSize))
-	fmt.Printf("║    V2: %s                                                                     ║\n", monitor.FormatBytesHuman(v2Clean.OutputMetrics.TotalSize))
-	fmt.Printf("║  Total Files:                                                                 ║\n")
-	fmt.Printf("║    V1: %d                                                                     ║\n", v1Clean.OutputMetrics.TotalFiles)
-	fmt.Printf("║    V2: %d                                                                     ║\n", v2Clean.OutputMetrics.TotalFiles)
+	logging.Printf("\n╔═══════════════════════════════════════════════════════════════════════════════╗\n")
+	logging.Printf("║                    COMPREHENSIVE V1 vs V2 COMPARISON                          ║\n")
+	logging.Printf("╠═══════════════════════════════════════════════════════════════════════════════╣\n")
+
+	metrics, err := CompareRuns("V1", v1Results, "V2", v2Results)
+	if err != nil {
+		logging.Printf("║  Could not compare: %v\n", err)
+		return
+	}
+	metrics.Print()
 
 	// === OUTPUT VERIFICATION ===
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("║  ═══ OUTPUT VERIFICATION ══════════════════════════════════════════════════   ║\n")
-	fmt.Printf("║                                                                               ║\n")
-	comparison, err := monitor.CompareOutputs("mirror/operators-v1", "mirror/operators-v2")
+	logging.Printf("║                                                                               ║\n")
+	logging.Printf("║  ═══ OUTPUT VERIFICATION ══════════════════════════════════════════════════   ║\n")
+	logging.Printf("║                                                                               ║\n")
+	comparison, err := monitor.CompareOutputs(tr.mirrorDir("v1"), tr.mirrorDir("v2"))
 	if err != nil {
-		fmt.Printf("║  Could not compare outputs: %v                                               ║\n", err)
+		logging.Printf("║  Could not compare outputs: %v                                               ║\n", err)
 	} else {
 		if comparison.Match {
-			fmt.Printf("║  ✓ V1 and V2 outputs are IDENTICAL                                           ║\n")
+			logging.Printf("║  ✓ V1 and V2 outputs are IDENTICAL                                           ║\n")
 		} else {
-			fmt.Printf("║  ✗ V1 and V2 outputs DIFFER                                                  ║\n")
-			fmt.Printf("║    Size difference: %s                                                       ║\n", monitor.FormatBytesHuman(comparison.SizeDifference))
-			fmt.Printf("║    File count difference: %d                                                 ║\n", comparison.FileCountDiff)
+			logging.Printf("║  ✗ V1 and V2 outputs DIFFER                                                  ║\n")
+			logging.Printf("║    Size difference: %s                                                       ║\n", monitor.FormatBytesHuman(comparison.SizeDifference))
+			logging.Printf("║    File count difference: %d                                                 ║\n", comparison.FileCountDiff)
 			if len(comparison.MissingInFirst) > 0 {
-				fmt.Printf("║    Missing in V1: %d files                                                   ║\n", len(comparison.MissingInFirst))
+				logging.Printf("║    Missing in V1: %d files                                                   ║\n", len(comparison.MissingInFirst))
 			}
 			if len(comparison.MissingInSecond) > 0 {
-				fmt.Printf("║    Missing in V2: %d files                                                   ║\n", len(comparison.MissingInSecond))
+				logging.Printf("║    Missing in V2: %d files                                                   ║\n", len(comparison.MissingInSecond))
 			}
 			if len(comparison.DifferentContent) > 0 {
-				fmt.Printf("║    Different content: %d files                                               ║\n", len(comparison.DifferentContent))
+				logging.Printf("║    Different content: %d files                                               ║\n", len(comparison.DifferentContent))
 			}
 		}
+		blobs := comparison.Blobs
+		logging.Printf("║  Blob Deduplication:                                                          ║\n")
+		logging.Printf("║    V1: %d unique blobs, %s wasted on %d duplicates                            ║\n",
+			blobs.First.UniqueBlobs, monitor.FormatBytesHuman(blobs.First.DuplicateBytes), blobs.First.DuplicateFiles)
+		logging.Printf("║    V2: %d unique blobs, %s wasted on %d duplicates                            ║\n",
+			blobs.Second.UniqueBlobs, monitor.FormatBytesHuman(blobs.Second.DuplicateBytes), blobs.Second.DuplicateFiles)
+		logging.Printf("║    Shared: %d (%.1f%%) | Only in V1: %d | Only in V2: %d                       ║\n",
+			blobs.SharedBlobs, blobs.SharedRatio*100, blobs.OnlyInFirst, blobs.OnlyInSecond)
+		if blobs.SameBlobs && !comparison.Match {
+			logging.Printf("║    ✓ Same blobs; the differences above are layout only                        ║\n")
+		}
 	}
 
-	// === CACHE EFFECTIVENESS (if we have cached runs) ===
-	if len(v1Results) > 1 && len(v2Results) > 1 {
-		fmt.Printf("║                                                                               ║\n")
-		fmt.Printf("║  ═══ CACHING EFFECTIVENESS ════════════════════════════════════════════════   ║\n")
-		fmt.Printf("║                                                                               ║\n")
-		v1Cached := v1Results[1]
-		v2Cached := v2Results[1]
-
-		v1CacheImprovement := float64(v1Clean.DownloadPhase.WallTime-v1Cached.DownloadPhase.WallTime) / float64(v1Clean.DownloadPhase.WallTime) * 100
-		v2CacheImprovement := float64(v2Clean.DownloadPhase.WallTime-v2Cached.DownloadPhase.WallTime) / float64(v2Clean.DownloadPhase.WallTime) * 100
+	logging.Printf("║                                                                               ║\n")
+	logging.Printf("╚═══════════════════════════════════════════════════════════════════════════════╝\n")
+}
 
-		fmt.Printf("║  Download Time Improvement (Clean vs Cached):                                 ║\n")
-		fmt.Printf("║    V1: %.2f%%                                                                 ║\n", v1CacheImprovement)
-		fmt.Printf("║    V2: %.2f%%                                                                 ║\n", v2CacheImprovement)
-		fmt.Printf("║  Cache Hits (Cached Run):                                                     ║\n")
-		fmt.Printf("║    V1: %d                                                                     ║\n", v1Cached.DownloadPhase.CacheHits)
-		fmt.Printf("║    V2: %d                                                                     ║\n", v2Cached.DownloadPhase.CacheHits)
+// clusterResourcesTime returns the cluster resources generation time of an iteration
+func clusterResourcesTime(result TestResult) time.Duration {
+	if result.UploadPhase.ClusterResources == nil {
+		return 0
 	}
-
-	fmt.Printf("║                                                                               ║\n")
-	fmt.Printf("╚═══════════════════════════════════════════════════════════════════════════════╝\n")
+	return result.UploadPhase.ClusterResources.GenerationTime.Round(time.Millisecond)
 }
 
 func (tr *TestRunner) generateSummary(result TestResult) string {
 	return fmt.Sprintf("Iteration %d (%s, %s): Download=%v, Upload=%v, Bytes=%d, CacheHits=%d",
 		result.Iteration,
-		map[bool]string{true: "CLEAN", false: "CACHED"}[result.IsCleanRun],
+		runKind(result.IsCleanRun, result.IsUpdateRun),
 		result.Version,
 		result.DownloadPhase.WallTime,
 		result.UploadPhase.WallTime,
@@ -1018,15 +1436,19 @@ func (tr *TestRunner) generateSummary(result TestResult) string {
 func (tr *TestRunner) saveResults() error {
 	// Use the same results file path throughout the test run
 	if tr.resultsPath == "" {
-		tr.resultsPath = filepath.Join("results", fmt.Sprintf("results_%s.json", time.Now().Format("20060102_150405")))
+		tr.resultsPath = filepath.Join(tr.config.GetResultsDir(), ResultsFileName(tr.config, time.Now()))
 	}
 
 	// Ensure results directory exists
-	if err := os.MkdirAll("results", 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(tr.resultsPath), 0755); err != nil {
 		return fmt.Errorf("failed to create results directory: %w", err)
 	}
 
-	data, err := json.MarshalIndent(tr.results, "", "  ")
+	file, err := tr.resultsFile()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -1043,32 +1465,10 @@ func (tr *TestRunner) saveResults() error {
 		return err
 	}
 
-	return nil
-}
-
-// updatePathWithBinDir updates the PATH environment variable to include the bin directory
-func (tr *TestRunner) updatePathWithBinDir(binDir string) error {
-	absBinPath, err := filepath.Abs(binDir)
-	if err != nil {
-		return fmt.Errorf("failed to get absolute path for bin directory: %w", err)
+	// Checksum (and optional signature) so later modification can be detected
+	if err := integrity.WriteSidecars(tr.resultsPath, tr.config.SigningKey); err != nil {
+		return fmt.Errorf("failed to write results checksum: %w", err)
 	}
 
-	currentPath := os.Getenv("PATH")
-	if currentPath == "" {
-		// If PATH is empty, just set it to the bin directory
-		return os.Setenv("PATH", absBinPath)
-	}
-
-	// Check if bin directory is already in PATH
-	pathDirs := strings.Split(currentPath, string(os.PathListSeparator))
-	for _, dir := range pathDirs {
-		if dir == absBinPath {
-			// Already in PATH, no need to update
-			return nil
-		}
-	}
-
-	// Prepend bin directory to PATH
-	newPath := absBinPath + string(os.PathListSeparator) + currentPath
-	return os.Setenv("PATH", newPath)
+	return nil
 }
@@ -0,0 +1,428 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Sample storage modes
+const (
+	SampleStorageInline    = "inline"     // Samples inside the results file (default)
+	SampleStorageDelta     = "delta"      // Delta-encoded JSON lines sidecar
+	SampleStorageDeltaGzip = "delta-gzip" // Gzipped delta-encoded JSON lines sidecar
+)
+
+// ErrSamplesModified is returned for a samples sidecar that changed after the
+// run wrote it
+var ErrSamplesModified = errors.New("samples file does not match the sha256 recorded in the results file")
+
+// sampleValueScale keeps three decimals of fractional sample values, such as
+// rates and percentages, when they are stored as integer deltas
+const sampleValueScale = 1000
+
+// sampleSeries is one sample slice of a result, addressed by its JSON path
+type sampleSeries struct {
+	path    string
+	samples interface{} // Pointer to the samples slice
+}
+
+// sampleSeriesOf returns every sample series a result carries
+func sampleSeriesOf(r *TestResult) []sampleSeries {
+	var series []sampleSeries
+	add := func(path string, samples interface{}) {
+		series = append(series, sampleSeries{path: path, samples: samples})
+	}
+
+	add("resource_metrics", &r.ResourceMetrics.Samples)
+	if r.RegistryMetrics != nil {
+		add("registry_metrics", &r.RegistryMetrics.Samples)
+	}
+	for _, phase := range []struct {
+		path    string
+		metrics *PhaseMetrics
+	}{{"download_phase", &r.DownloadPhase}, {"upload_phase", &r.UploadPhase}} {
+		m := phase.metrics
+		add(phase.path+".download_metrics", &m.DownloadMetrics.Samples)
+		add(phase.path+".resource_metrics", &m.ResourceMetrics.Samples)
+		if m.DiskWriteMetrics != nil {
+			add(phase.path+".disk_write_metrics", &m.DiskWriteMetrics.Samples)
+		}
+		if m.DiskIOMetrics != nil {
+			add(phase.path+".disk_io_metrics", &m.DiskIOMetrics.Samples)
+		}
+		if m.ProcessNetworkMetrics != nil {
+			add(phase.path+".process_network_metrics", &m.ProcessNetworkMetrics.Samples)
+		}
+		if m.RegistryServer != nil {
+			add(phase.path+".registry_server", &m.RegistryServer.Samples)
+		}
+		for i := range m.Plugins {
+			add(phase.path+".plugins."+m.Plugins[i].Name, &m.Plugins[i].Samples)
+		}
+	}
+	return series
+}
+
+// encodedSeries is one line of a samples file: the samples of one series,
+// stored column by column
+type encodedSeries struct {
+	Result  int                       `json:"result"` // Index of the iteration in the results array
+	Series  string                    `json:"series"` // JSON path of the metrics holding the samples
+	Count   int                       `json:"count"`
+	Columns map[string]*encodedColumn `json:"columns"`
+}
+
+// encodedColumn is one sample field. Timestamps are stored as millisecond
+// offsets from the previous sample, numbers as differences to the previous
+// value after scaling to integers; anything else is kept verbatim.
+type encodedColumn struct {
+	Start  *time.Time    `json:"start,omitempty"` // First timestamp of a timestamp column
+	Scale  int64         `json:"scale,omitempty"` // Numbers are stored as round(value * scale)
+	Deltas []int64       `json:"deltas,omitempty"`
+	Values []interface{} `json:"values,omitempty"`
+}
+
+// SamplesPath returns the samples sidecar written next to a results file
+func SamplesPath(resultsPath, storage string) string {
+	path := strings.TrimSuffix(resultsPath, ".json") + ".samples.jsonl"
+	if storage == SampleStorageDeltaGzip {
+		path += ".gz"
+	}
+	return path
+}
+
+// encodeSamples moves the samples of results into delta-encoded series. The
+// samples are cleared in results, which must be a copy owned by the caller.
+func encodeSamples(results []TestResult) ([]encodedSeries, error) {
+	var lines []encodedSeries
+	for i := range results {
+		for _, series := range sampleSeriesOf(&results[i]) {
+			data, err := json.Marshal(series.samples)
+			if err != nil {
+				return nil, err
+			}
+			var rows []map[string]interface{}
+			if err := json.Unmarshal(data, &rows); err != nil {
+				return nil, err
+			}
+			if len(rows) == 0 {
+				continue
+			}
+			lines = append(lines, encodedSeries{Result: i, Series: series.path, Count: len(rows), Columns: encodeColumns(rows)})
+			if err := json.Unmarshal([]byte("null"), series.samples); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return lines, nil
+}
+
+// encodeColumns splits sample rows into columns. Fields left out of some rows,
+// such as omitempty ones, still get a column, with null in the rows without
+// them.
+func encodeColumns(rows []map[string]interface{}) map[string]*encodedColumn {
+	names := make(map[string]bool)
+	for _, row := range rows {
+		for name := range row {
+			names[name] = true
+		}
+	}
+	columns := make(map[string]*encodedColumn)
+	for name := range names {
+		values := make([]interface{}, len(rows))
+		for i, row := range rows {
+			values[i] = row[name]
+		}
+		columns[name] = encodeColumn(values)
+	}
+	return columns
+}
+
+// encodeColumn picks the most compact encoding that reproduces values
+func encodeColumn(values []interface{}) *encodedColumn {
+	if times, ok := asTimes(values); ok {
+		start := times[0]
+		column := &encodedColumn{Start: &start, Deltas: make([]int64, len(times))}
+		// Deltas of the rounded offsets from the start, so rounding errors do not add up
+		var previous int64
+		for i, t := range times {
+			offset := t.Sub(start).Round(time.Millisecond).Milliseconds()
+			column.Deltas[i] = offset - previous
+			previous = offset
+		}
+		return column
+	}
+	if numbers, ok := asNumbers(values); ok {
+		column := &encodedColumn{Scale: 1, Deltas: make([]int64, len(numbers))}
+		for _, n := range numbers {
+			if n != math.Trunc(n) {
+				column.Scale = sampleValueScale
+				break
+			}
+		}
+		var previous int64
+		for i, n := range numbers {
+			scaled := int64(math.Round(n * float64(column.Scale)))
+			column.Deltas[i] = scaled - previous
+			previous = scaled
+		}
+		return column
+	}
+	return &encodedColumn{Values: values}
+}
+
+// asTimes returns the values as timestamps if all of them are RFC 3339 strings
+func asTimes(values []interface{}) ([]time.Time, bool) {
+	times := make([]time.Time, len(values))
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, false
+		}
+		times[i] = t
+	}
+	return times, true
+}
+
+// asNumbers returns the values as numbers if all of them are finite numbers
+func asNumbers(values []interface{}) ([]float64, bool) {
+	numbers := make([]float64, len(values))
+	for i, v := range values {
+		n, ok := v.(float64)
+		if !ok || math.IsInf(n, 0) || math.IsNaN(n) {
+			return nil, false
+		}
+		numbers[i] = n
+	}
+	return numbers, true
+}
+
+// decodeColumn reverses encodeColumn
+func decodeColumn(column *encodedColumn, count int) ([]interface{}, error) {
+	if column.Values != nil {
+		if len(column.Values) != count {
+			return nil, fmt.Errorf("column has %d values, expected %d", len(column.Values), count)
+		}
+		return column.Values, nil
+	}
+	if len(column.Deltas) != count {
+		return nil, fmt.Errorf("column has %d deltas, expected %d", len(column.Deltas), count)
+	}
+
+	values := make([]interface{}, count)
+	if column.Start != nil {
+		t := *column.Start
+		for i, delta := range column.Deltas {
+			t = t.Add(time.Duration(delta) * time.Millisecond)
+			values[i] = t.Format(time.RFC3339Nano)
+		}
+		return values, nil
+	}
+
+	var scaled int64
+	for i, delta := range column.Deltas {
+		scaled += delta
+		if column.Scale > 1 {
+			values[i] = float64(scaled) / float64(column.Scale)
+		} else {
+			values[i] = scaled
+		}
+	}
+	return values, nil
+}
+
+// writeSamplesFile moves the samples of results into the samples sidecar at
+// path, written atomically like the results file. It returns the sha256 of
+// the sidecar.
+func writeSamplesFile(path string, results []TestResult, compress bool) (string, error) {
+	lines, err := encodeSamples(results)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode samples: %w", err)
+	}
+
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		w = gz
+	}
+	encoder := json.NewEncoder(w)
+	for _, line := range lines {
+		if err := encoder.Encode(line); err != nil {
+			return "", fmt.Errorf("failed to encode samples: %w", err)
+		}
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return "", fmt.Errorf("failed to compress samples: %w", err)
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// VerifySamples checks the contents of the samples sidecar against the
+// sha256 recorded in the header. Files written before the sha256 was
+// recorded pass unchecked.
+func (h ResultsHeader) VerifySamples(data []byte) error {
+	if h.SamplesSHA256 == "" {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	if !strings.EqualFold(h.SamplesSHA256, hex.EncodeToString(sum[:])) {
+		return fmt.Errorf("%s: %w", h.SamplesFile, ErrSamplesModified)
+	}
+	return nil
+}
+
+// DecodeSamples restores the samples of a results file from the contents of
+// its samples sidecar, gzipped or not
+func DecodeSamples(file *ResultsFile, data []byte) error {
+	var r io.Reader = bytes.NewReader(data)
+	if len(data) > 1 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("failed to decompress samples: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 256<<20)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var line encodedSeries
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return fmt.Errorf("failed to parse samples: %w", err)
+		}
+		if err := decodeSeries(file.Results, line); err != nil {
+			return fmt.Errorf("failed to decode samples of iteration %d %s: %w", line.Result, line.Series, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read samples: %w", err)
+	}
+	return nil
+}
+
+// decodeSeries stores one decoded series in its result
+func decodeSeries(results []TestResult, line encodedSeries) error {
+	if line.Result < 0 || line.Result >= len(results) {
+		return fmt.Errorf("no such result")
+	}
+	rows := make([]map[string]interface{}, line.Count)
+	for i := range rows {
+		rows[i] = make(map[string]interface{}, len(line.Columns))
+	}
+	for name, column := range line.Columns {
+		values, err := decodeColumn(column, line.Count)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for i, v := range values {
+			rows[i][name] = v
+		}
+	}
+
+	for _, series := range sampleSeriesOf(&results[line.Result]) {
+		if series.path != line.Series {
+			continue
+		}
+		data, err := json.Marshal(rows)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, series.samples)
+	}
+	return fmt.Errorf("unknown series")
+}
+
+// samplesPath returns the samples sidecar of this run, "" when samples are
+// stored inline
+func (tr *TestRunner) samplesPath() string {
+	storage := tr.config.GetSampleStorage()
+	if storage == SampleStorageInline {
+		return ""
+	}
+	return SamplesPath(tr.resultsPath, storage)
+}
+
+// resultsFile returns the envelope written to the results file. With delta
+// sample storage the samples go to the sidecar instead and are cleared in a
+// copy of the results; the live results keep them for the web UI.
+func (tr *TestRunner) resultsFile() (*ResultsFile, error) {
+	file := &ResultsFile{ResultsHeader: tr.header, Results: tr.results}
+	file.Statistics = SummarizeStatistics(tr.results)
+	path := tr.samplesPath()
+	if path == "" {
+		return file, nil
+	}
+
+	data, err := json.Marshal(tr.results)
+	if err != nil {
+		return nil, err
+	}
+	var results []TestResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+	sum, err := writeSamplesFile(path, results, tr.config.GetSampleStorage() == SampleStorageDeltaGzip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write samples file: %w", err)
+	}
+	file.Results = results
+	file.SamplesFile = filepath.Base(path)
+	file.SamplesSHA256 = sum
+	return file, nil
+}
+
+// LoadSamples restores the samples from the sidecar named in the header,
+// reading it with read. Without the sidecar, for example when only the
+// results file was copied, the results load without samples. A sidecar that
+// does not match the sha256 in the header is not loaded; the error wraps
+// ErrSamplesModified.
+func (f *ResultsFile) LoadSamples(read func(name string) ([]byte, error)) error {
+	if f.SamplesFile == "" {
+		return nil
+	}
+	data, err := read(f.SamplesFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read samples file: %w", err)
+	}
+	if err := f.VerifySamples(data); err != nil {
+		return err
+	}
+	return DecodeSamples(f, data)
+}
@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// TestSamplesFileRoundTrip writes samples whose first row leaves out the
+// omitempty fields and checks that they all come back from the sidecar
+func TestSamplesFileRoundTrip(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	samples := []monitor.DownloadSample{
+		{Timestamp: start, TotalBytes: 0, FileCount: 0},
+		{Timestamp: start.Add(time.Second), TotalBytes: 1000, BytesDelta: 1000, DownloadRateMB: 0.5, FileCount: 2, ExpectedBytes: 4000, ETASeconds: 3.5},
+		{Timestamp: start.Add(2 * time.Second), TotalBytes: 3000, BytesDelta: 2000, DownloadRateMB: 1.25, FileCount: 5, ExpectedBytes: 4000, ETASeconds: 1},
+	}
+
+	for _, compress := range []bool{false, true} {
+		results := []TestResult{{}}
+		results[0].DownloadPhase.DownloadMetrics.Samples = append([]monitor.DownloadSample(nil), samples...)
+
+		path := filepath.Join(t.TempDir(), "results.samples.jsonl")
+		sum, err := writeSamplesFile(path, results, compress)
+		if err != nil {
+			t.Fatalf("compress=%v: writeSamplesFile: %v", compress, err)
+		}
+		if results[0].DownloadPhase.DownloadMetrics.Samples != nil {
+			t.Fatalf("compress=%v: samples were not moved to the sidecar", compress)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := &ResultsFile{ResultsHeader: ResultsHeader{SamplesSHA256: sum}, Results: results}
+		if err := file.VerifySamples(data); err != nil {
+			t.Fatalf("compress=%v: VerifySamples: %v", compress, err)
+		}
+		if err := DecodeSamples(file, data); err != nil {
+			t.Fatalf("compress=%v: DecodeSamples: %v", compress, err)
+		}
+
+		want, _ := json.Marshal(samples)
+		got, _ := json.Marshal(file.Results[0].DownloadPhase.DownloadMetrics.Samples)
+		if string(got) != string(want) {
+			t.Errorf("compress=%v: samples changed in the round trip\n got: %s\nwant: %s", compress, got, want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+	"github.com/telco-core/ngc-495/pkg/netshape"
+)
+
+// applyNetworkShaping installs the configured tc/netem rules and returns a
+// function that removes them. The function is also pushed on the cleanups of
+// the command, which runs it if the process is interrupted, so a cancelled run
+// never leaves the host throttled.
+func (tr *TestRunner) applyNetworkShaping() (func(), error) {
+	if !tr.config.Shaping.Enabled() {
+		return func() {}, nil
+	}
+
+	if tr.config.Shaping.Interface == "" {
+		tr.config.Shaping.Interface = monitor.DefaultInterface()
+	}
+
+	shaper := netshape.NewShaper(tr.config.Shaping)
+	logging.Printf("Applying network shaping: %s\n", tr.config.Shaping.String())
+	if err := shaper.Apply(); err != nil {
+		return nil, err
+	}
+
+	return tr.cleanups.Push(func() {
+		logging.Printf("Removing network shaping rules from %s...\n", tr.config.Shaping.Interface)
+		if err := shaper.Remove(); err != nil {
+			logging.Printf("Warning: %v\n", err)
+		}
+	}), nil
+}
@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"strings"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/registry"
+)
+
+// signatureScanConcurrency is the number of signatures checked in parallel
+const signatureScanConcurrency = 8
+
+// SignatureConfig enables scanning the registry for the cosign signatures and
+// attestations mirrored with the images after every upload
+type SignatureConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled,omitempty"`
+	KeyFile string `json:"key_file,omitempty" yaml:"keyFile,omitempty"` // cosign public key; without it only the payloads are checked
+}
+
+// Validate checks that the public key can be loaded
+func (c SignatureConfig) Validate() error {
+	if c.KeyFile == "" {
+		return nil
+	}
+	if !c.Enabled {
+		return fmt.Errorf("a signature key requires signature verification to be enabled")
+	}
+	_, err := registry.LoadPublicKey(c.KeyFile)
+	return err
+}
+
+// String returns a human-readable description of the signature checks
+func (c SignatureConfig) String() string {
+	if c.KeyFile == "" {
+		return "payloads only (no public key)"
+	}
+	return "verified against " + c.KeyFile
+}
+
+// verifySignatures scans the repositories the iteration pushed to for cosign
+// artifacts. It returns nil when disabled or for OCI layout targets, whose
+// cosign tags are counted by the output analysis.
+func (tr *TestRunner) verifySignatures(version string) *registry.SignatureMetrics {
+	if !tr.config.Signatures.Enabled || tr.config.IsOCITarget() {
+		return nil
+	}
+	client, err := registry.NewClient(tr.targetRegistry(), "", tr.config.SkipTLS)
+	if err != nil {
+		logging.Printf("  │ Warning: Failed to scan signatures: %v\n", err)
+		return nil
+	}
+	var key crypto.PublicKey
+	if tr.config.Signatures.KeyFile != "" {
+		if key, err = registry.LoadPublicKey(tr.config.Signatures.KeyFile); err != nil {
+			logging.Printf("  │ Warning: Failed to scan signatures: %v\n", err)
+			return nil
+		}
+	}
+
+	// v1 uploads to the registry root, v2 under the path of the registry URL
+	prefix := ""
+	if version == "v2" {
+		prefix = registryPath(tr.targetRegistry())
+	}
+	metrics, err := registry.ScanSignatures(context.Background(), client, prefix, key, signatureScanConcurrency)
+	if err != nil {
+		logging.Printf("  │ Warning: Failed to scan signatures: %v\n", err)
+		return nil
+	}
+	metrics.KeyFile = tr.config.Signatures.KeyFile
+	metrics.PrintSummary()
+	return metrics
+}
+
+// registryPath returns the repository path of a registry URL such as
+// docker://host:port/path, empty when it names the registry root
+func registryPath(registryURL string) string {
+	if idx := strings.Index(registryURL, "://"); idx >= 0 {
+		registryURL = registryURL[idx+3:]
+	}
+	_, path, _ := strings.Cut(registryURL, "/")
+	return strings.Trim(path, "/")
+}
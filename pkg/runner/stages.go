@@ -0,0 +1,224 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/internal/config"
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// remainingStage names the stage mirroring the content no stage selected
+const remainingStage = "remaining"
+
+// StageConfig is one chunk of a priority-ordered mirror. Stages are mirrored
+// in order, each by its own download and upload; content no stage selects is
+// mirrored last, as the remaining stage.
+type StageConfig struct {
+	Name                    string `json:"name" yaml:"name"`
+	config.ContentSelection `yaml:",inline"`
+}
+
+// StageMetrics places a result within the priority-ordered stages of its iteration
+type StageMetrics struct {
+	Name          string        `json:"name"`
+	Index         int           `json:"index"` // 1 is mirrored first
+	Count         int           `json:"count"`
+	TimeToContent time.Duration `json:"time_to_content_seconds"` // From the start of the iteration to the end of this stage
+}
+
+// first reports whether s is the first stage of an iteration; a run without
+// stages mirrors everything in its first stage
+func (s *StageMetrics) first() bool {
+	return s == nil || s.Index == 1
+}
+
+// sameStage reports whether two results mirrored the same stage
+func sameStage(a, b *StageMetrics) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Name == b.Name
+}
+
+// stageContent is a stage and the part of the content it mirrors
+type stageContent struct {
+	Name    string
+	Content *config.ContentSpec
+}
+
+// ValidateStages checks that the stages split the content and can run with
+// the rest of the configuration
+func (c *Config) ValidateStages() error {
+	if len(c.Stages) == 0 {
+		return nil
+	}
+	if c.CompareV1V2 {
+		return fmt.Errorf("stages cannot be combined with the v1/v2 comparison")
+	}
+	if c.IsRegistryComparison() {
+		return fmt.Errorf("stages cannot be combined with registry comparison")
+	}
+	if c.UpdateContent != nil {
+		return fmt.Errorf("stages cannot be combined with the day-2 update")
+	}
+	if c.Delete.Enabled {
+		return fmt.Errorf("stages cannot be combined with the delete phase")
+	}
+	if c.ClusterValidation.Enabled {
+		return fmt.Errorf("stages cannot be combined with cluster validation")
+	}
+	_, err := c.stagePlan()
+	return err
+}
+
+// stagePlan splits the content into the configured stages, followed by the
+// remaining stage when content is left over
+func (c *Config) stagePlan() ([]stageContent, error) {
+	rest := c.Content
+	if rest == nil {
+		rest = config.DefaultContent()
+	}
+	names := make(map[string]bool)
+	var plan []stageContent
+	for i, stage := range c.Stages {
+		if stage.Name == "" {
+			return nil, fmt.Errorf("stage %d requires a name", i+1)
+		}
+		if stage.Name == remainingStage || names[stage.Name] {
+			return nil, fmt.Errorf("stage name %q is reserved or used twice", stage.Name)
+		}
+		names[stage.Name] = true
+		if stage.IsEmpty() {
+			return nil, fmt.Errorf("stage %s selects no packages, images or charts", stage.Name)
+		}
+		selected, remaining, err := rest.Select(stage.ContentSelection)
+		if err != nil {
+			return nil, fmt.Errorf("stage %s: %w (each item is mirrored by one stage only)", stage.Name, err)
+		}
+		plan = append(plan, stageContent{Name: stage.Name, Content: selected})
+		rest = remaining
+	}
+	if !rest.IsEmpty() {
+		plan = append(plan, stageContent{Name: remainingStage, Content: rest})
+	}
+	if len(plan) < 2 {
+		return nil, fmt.Errorf("stages must split the content into at least 2 parts")
+	}
+	return plan, nil
+}
+
+// runStagedTest runs the iterations of a priority-ordered mirror: every
+// iteration mirrors the stages in order and records, per stage, when its
+// content became available in the registry
+func (tr *TestRunner) runStagedTest() error {
+	plan, err := tr.config.stagePlan()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tr.stage = nil
+		tr.stageContent = nil
+	}()
+
+	for i := 0; i < tr.config.Iterations; i++ {
+		isCleanRun := tr.config.cleanIteration(i+1, i == 0)
+		logging.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
+		logging.Printf("║  Iteration %d/%d (%s)                                          ║\n", i+1, tr.config.Iterations, runKind(isCleanRun, false))
+		logging.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+		key := tr.iterationKey(i+1, "v2")
+		if tr.skipCompleted(key) {
+			continue
+		}
+
+		startTime := time.Now()
+		for k, stage := range plan {
+			logging.Printf("\n━━━ Stage %d/%d: %s (%s) ━━━\n", k+1, len(plan), stage.Name, strings.Join(stage.Content.Kinds(), ", "))
+			tr.stage = &StageMetrics{Name: stage.Name, Index: k + 1, Count: len(plan)}
+			tr.stageContent = stage.Content
+			if err := writeImageSetConfigs(stage.Content); err != nil {
+				return err
+			}
+
+			result, err := tr.runIteration(i+1, isCleanRun, "v2")
+			result.Stage.TimeToContent = time.Since(startTime)
+			if err != nil && !tr.recordFailedIteration(&result, err) {
+				return fmt.Errorf("iteration %d stage %s failed: %w", i+1, stage.Name, err)
+			}
+			tr.evaluateGates(&result)
+			tr.publishIteration(result)
+
+			tr.results = append(tr.results, result)
+			if !result.Failed {
+				tr.printIterationSummary(result)
+			}
+			if err := tr.saveResults(); err != nil {
+				logging.Printf("Warning: Failed to save results incrementally: %v\n", err)
+			}
+			if result.Failed {
+				// Later stages of a bring-up wait for the earlier ones
+				logging.Printf("  Skipping the remaining stages of iteration %d\n", i+1)
+				break
+			}
+			logging.Printf("\n  Stage %s available after %v\n", stage.Name, result.Stage.TimeToContent.Round(time.Second))
+		}
+		tr.markCompleted(key)
+	}
+
+	logging.Summary(func() {
+		tr.compareStages(len(plan))
+		printStatistics(SummarizeStatistics(tr.results))
+	})
+
+	if err := tr.saveResults(); err != nil {
+		return fmt.Errorf("failed to save results: %w", err)
+	}
+	return errors.Join(failedIterationsError(tr.results), gateFailuresError(tr.results))
+}
+
+// compareStages prints, per iteration, when the content of each stage became
+// available, against the time to mirror everything
+func (tr *TestRunner) compareStages(count int) {
+	logging.Printf("\nPriority-Ordered Mirroring (time to content from the start of the iteration):\n")
+	for i := 0; i < len(tr.results); {
+		iteration := tr.results[i].Iteration
+		var stages []TestResult
+		for ; i < len(tr.results) && tr.results[i].Iteration == iteration; i++ {
+			stages = append(stages, tr.results[i])
+		}
+
+		last := stages[len(stages)-1]
+		complete := !last.Failed && len(stages) == count
+		logging.Printf("  Iteration %d (%s):\n", iteration, runKind(last.IsCleanRun, false))
+		for _, r := range stages {
+			if r.Failed {
+				logging.Printf("    ❌ %d. %-20s failed after %v\n", r.Stage.Index, r.Stage.Name, r.Stage.TimeToContent.Round(time.Second))
+				continue
+			}
+			share := ""
+			if complete && last.Stage.TimeToContent > 0 {
+				share = fmt.Sprintf(" (%.1f%% of total)", float64(r.Stage.TimeToContent)/float64(last.Stage.TimeToContent)*100)
+			}
+			logging.Printf("    ✅ %d. %-20s %v%s\n", r.Stage.Index, r.Stage.Name, r.Stage.TimeToContent.Round(time.Second), share)
+		}
+		if complete {
+			logging.Printf("    Time to %s content: %v of %v total\n", stages[0].Stage.Name,
+				stages[0].Stage.TimeToContent.Round(time.Second), last.Stage.TimeToContent.Round(time.Second))
+		}
+	}
+}
+
+// stageNames lists the stages in mirroring order, for the run header
+func (c *Config) stageNames() string {
+	plan, err := c.stagePlan()
+	if err != nil {
+		return err.Error()
+	}
+	names := make([]string, len(plan))
+	for i, stage := range plan {
+		names[i] = stage.Name
+	}
+	return strings.Join(names, " -> ")
+}
@@ -0,0 +1,189 @@
+package runner
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/registry"
+)
+
+// minStatisticsIterations is the number of completed iterations of a kind a
+// group needs before its spread is summarized
+const minStatisticsIterations = 2
+
+// Statistic summarizes the values of a metric across iterations
+type Statistic struct {
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	P95    float64 `json:"p95"`
+	StdDev float64 `json:"stddev"` // Sample standard deviation
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+// IterationStatistics summarizes the clean or the cached iterations of a
+// group: a version or matrix combination, split by registry, proxy mode and
+// stage when those vary
+type IterationStatistics struct {
+	Group        string    `json:"group"`
+	Kind         string    `json:"kind"` // clean or cached
+	Iterations   int       `json:"iterations"`
+	DownloadTime Statistic `json:"download_time_seconds"`
+	UploadTime   Statistic `json:"upload_time_seconds"`
+	TotalTime    Statistic `json:"total_time_seconds"`
+	SpeedMBs     Statistic `json:"speed_mbs"`       // Bytes of both phases over their wall time
+	CPUPercent   Statistic `json:"cpu_avg_percent"` // Average CPU of each iteration
+	MemoryPeakMB Statistic `json:"memory_peak_mb"`  // Peak memory of each iteration
+}
+
+// SummarizeStatistics computes the statistics of every group and kind with at
+// least minStatisticsIterations completed iterations, in first-seen order.
+// Failed, warm-up and day-2 update iterations are left out.
+func SummarizeStatistics(results []TestResult) []IterationStatistics {
+	registries := make(map[string]bool)
+	for _, r := range results {
+		registries[r.Registry] = true
+	}
+
+	var keys []string
+	groups := make(map[string][]TestResult)
+	for _, r := range results {
+		if r.Failed || r.IsUpdateRun || r.Warmup {
+			continue
+		}
+		key := statisticsGroup(r, len(registries) > 1) + "\x00" + strings.ToLower(runKind(r.IsCleanRun, false))
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], r)
+	}
+
+	var stats []IterationStatistics
+	for _, key := range keys {
+		if len(groups[key]) < minStatisticsIterations {
+			continue
+		}
+		group, kind, _ := strings.Cut(key, "\x00")
+		stats = append(stats, iterationStatistics(group, kind, groups[key]))
+	}
+	return stats
+}
+
+// statisticsGroup returns the label iterations are grouped under
+func statisticsGroup(r TestResult, byRegistry bool) string {
+	label := r.Version
+	if r.Matrix != nil {
+		label = matrixGroup{version: r.Matrix.Version, reference: r.Matrix.Reference, workflow: r.Matrix.Workflow, concurrency: r.Matrix.Concurrency}.label()
+	}
+	if byRegistry && r.Registry != "" {
+		host, _ := registry.ParseRegistryHost(r.Registry)
+		label += "@" + host
+	}
+	if r.Proxy != nil {
+		label += "/" + r.Proxy.Mode
+	}
+	if r.Stage != nil {
+		label += "/" + r.Stage.Name
+	}
+	return label
+}
+
+// iterationStatistics computes the statistics of the iterations of one group and kind
+func iterationStatistics(group, kind string, results []TestResult) IterationStatistics {
+	var download, upload, total, speed, cpu, memory []float64
+	for _, r := range results {
+		download = append(download, r.DownloadPhase.WallTime.Seconds())
+		upload = append(upload, r.UploadPhase.WallTime.Seconds())
+		total = append(total, r.GetTotalTime().Seconds())
+		speed = append(speed, r.GetAverageSpeedMBs())
+		cpu = append(cpu, r.ResourceMetrics.CPUAvgPercent)
+		memory = append(memory, r.ResourceMetrics.MemoryPeakMB)
+	}
+	return IterationStatistics{
+		Group:        group,
+		Kind:         kind,
+		Iterations:   len(results),
+		DownloadTime: computeStatistic(download),
+		UploadTime:   computeStatistic(upload),
+		TotalTime:    computeStatistic(total),
+		SpeedMBs:     computeStatistic(speed),
+		CPUPercent:   computeStatistic(cpu),
+		MemoryPeakMB: computeStatistic(memory),
+	}
+}
+
+// computeStatistic returns the statistic of values, zero when there are none
+func computeStatistic(values []float64) Statistic {
+	if len(values) == 0 {
+		return Statistic{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(len(sorted))
+	var squares float64
+	for _, v := range sorted {
+		squares += (v - mean) * (v - mean)
+	}
+	var stddev float64
+	if len(sorted) > 1 {
+		stddev = math.Sqrt(squares / float64(len(sorted)-1))
+	}
+	return Statistic{
+		Mean:   mean,
+		Median: percentile(sorted, 50),
+		P95:    percentile(sorted, 95),
+		StdDev: stddev,
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the p-th percentile of sorted values, interpolating
+// linearly between the closest ranks
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// seconds returns a statistic value in seconds as a rounded duration
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second)).Round(time.Millisecond)
+}
+
+// printStatistics prints the median, p95 and standard deviation of each group
+// and kind; mean and min/max are in the results
+func printStatistics(stats []IterationStatistics) {
+	if len(stats) == 0 {
+		return
+	}
+	logging.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
+	logging.Printf("║  Iteration Statistics (median / p95 / stddev)                 ║\n")
+	logging.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+	for _, s := range stats {
+		logging.Printf("  %s, %s (%d iterations):\n", s.Group, s.Kind, s.Iterations)
+		logging.Printf("    Download:  %v / %v / %v\n", seconds(s.DownloadTime.Median), seconds(s.DownloadTime.P95), seconds(s.DownloadTime.StdDev))
+		logging.Printf("    Upload:    %v / %v / %v\n", seconds(s.UploadTime.Median), seconds(s.UploadTime.P95), seconds(s.UploadTime.StdDev))
+		logging.Printf("    Total:     %v / %v / %v\n", seconds(s.TotalTime.Median), seconds(s.TotalTime.P95), seconds(s.TotalTime.StdDev))
+		logging.Printf("    Speed:     %.2f / %.2f / %.2f MB/s\n", s.SpeedMBs.Median, s.SpeedMBs.P95, s.SpeedMBs.StdDev)
+		logging.Printf("    CPU avg:   %.1f%% / %.1f%% / %.1f%%\n", s.CPUPercent.Median, s.CPUPercent.P95, s.CPUPercent.StdDev)
+		logging.Printf("    Mem peak:  %.1f / %.1f / %.1f MB\n", s.MemoryPeakMB.Median, s.MemoryPeakMB.P95, s.MemoryPeakMB.StdDev)
+		if s.TotalTime.Mean > 0 {
+			logging.Printf("    Variation: %.1f%% of the mean total time\n", s.TotalTime.StdDev/s.TotalTime.Mean*100)
+		}
+	}
+}
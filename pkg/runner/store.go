@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/telco-core/ngc-495/pkg/integrity"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/store"
+)
+
+// StoreConfig copies the results of a run to a results store, such as the
+// S3 bucket a central dashboard reads from, when the run ends
+type StoreConfig struct {
+	Location string          `json:"location,omitempty" yaml:"location,omitempty"` // s3://bucket/prefix or a directory
+	Logs     bool            `json:"logs,omitempty" yaml:"logs,omitempty"`         // Also upload the oc-mirror logs under logs/
+	S3       store.S3Options `json:"-" yaml:"-"`                                   // Endpoint and credentials for s3:// locations
+}
+
+// Enabled reports whether results are uploaded
+func (c StoreConfig) Enabled() bool {
+	return c.Location != ""
+}
+
+// Validate checks that the location can be opened
+func (c StoreConfig) Validate() error {
+	if !c.Enabled() {
+		if c.Logs {
+			return fmt.Errorf("uploading logs requires a results store location")
+		}
+		return nil
+	}
+	_, err := store.Open(c.Location, c.S3)
+	return err
+}
+
+// String returns a human-readable description of the upload
+func (c StoreConfig) String() string {
+	if c.Logs {
+		return c.Location + " (with logs)"
+	}
+	return c.Location
+}
+
+// uploadResults copies the results file, its integrity and samples sidecars,
+// the ticket report and bundle and optionally the phase logs to the results store.
+// Failures are reported but do not fail the run.
+func (tr *TestRunner) uploadResults() {
+	if !tr.config.Store.Enabled() {
+		return
+	}
+	target, err := store.Open(tr.config.Store.Location, tr.config.Store.S3)
+	if err != nil {
+		logging.Printf("Warning: Failed to open results store: %v\n", err)
+		return
+	}
+
+	base := strings.TrimSuffix(tr.resultsPath, ".json")
+	files := []string{
+		tr.resultsPath,
+		integrity.ChecksumPath(tr.resultsPath),
+		integrity.SignaturePath(tr.resultsPath),
+		base + "_report.md",
+		base + ".tar.gz",
+		tr.samplesPath(),
+	}
+	if tr.config.Store.Logs {
+		files = append(files, tr.logFiles()...)
+	}
+
+	logging.Printf("Uploading results to %s...\n", target)
+	resultsDir := filepath.Dir(tr.resultsPath)
+	uploaded := 0
+	for _, file := range files {
+		name, err := filepath.Rel(resultsDir, file)
+		if err != nil || strings.HasPrefix(name, "..") {
+			name = filepath.Base(file)
+		}
+		ok, err := uploadFile(target, filepath.ToSlash(name), file)
+		if err != nil {
+			logging.Printf("Warning: Failed to upload %s: %v\n", file, err)
+			continue
+		}
+		if ok {
+			uploaded++
+		}
+	}
+	logging.Printf("Uploaded %d files to %s\n", uploaded, target)
+}
+
+// logFiles returns every oc-mirror log the results reference
+func (tr *TestRunner) logFiles() []string {
+	var files []string
+	for _, r := range tr.results {
+		files = append(files, r.DownloadPhase.LogFile, r.UploadPhase.LogFile)
+		for _, attempt := range r.FailedAttempts {
+			files = append(files, attempt.LogFile)
+		}
+		for _, hop := range r.Replication {
+			files = append(files, hop.LogFile)
+		}
+		if r.DeletePhase != nil {
+			files = append(files, r.DeletePhase.GenerateLogFile, r.DeletePhase.LogFile, r.DeletePhase.GCLogFile)
+		}
+	}
+	return files
+}
+
+// uploadFile writes a local file to the store as name. Missing files are
+// skipped and reported as not uploaded.
+func uploadFile(target store.Store, name, path string) (bool, error) {
+	if path == "" {
+		return false, nil
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if err := target.Write(name, file, info.Size()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
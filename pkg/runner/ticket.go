@@ -0,0 +1,217 @@
+package runner
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/integrity"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+	"github.com/telco-core/ngc-495/pkg/ticket"
+)
+
+// attachToTicket renders the run report, bundles the results with the phase
+// logs and attaches both to the configured ticket
+func (tr *TestRunner) attachToTicket(startedAt time.Time, runErr error) {
+	if !tr.config.Ticket.Enabled() {
+		return
+	}
+	base := strings.TrimSuffix(tr.resultsPath, ".json")
+	reportPath := base + "_report.md"
+	bundlePath := base + ".tar.gz"
+
+	report := renderReport(tr.buildSummary(startedAt, runErr).Text(), tr.results)
+	if err := os.WriteFile(reportPath, []byte(report), 0644); err != nil {
+		logging.Printf("Warning: Failed to write run report: %v\n", err)
+		return
+	}
+	if err := writeBundle(bundlePath, filepath.Dir(tr.resultsPath), tr.bundleFiles(reportPath)); err != nil {
+		logging.Printf("Warning: Failed to create run bundle: %v\n", err)
+		return
+	}
+
+	logging.Printf("Attaching report and bundle to %s...\n", tr.config.Ticket.String())
+	if err := ticket.Attach(tr.config.Ticket, []string{reportPath, bundlePath}); err != nil {
+		logging.Printf("Warning: Failed to attach run artifacts to %s: %v\n", tr.config.Ticket.ID, err)
+		return
+	}
+	logging.Printf("Attached %s and %s to %s\n", filepath.Base(reportPath), filepath.Base(bundlePath), tr.config.Ticket.ID)
+}
+
+// bundleFiles returns the results file with its sidecars, the report and every
+// oc-mirror log the results reference
+func (tr *TestRunner) bundleFiles(reportPath string) []string {
+	files := []string{
+		tr.resultsPath,
+		integrity.ChecksumPath(tr.resultsPath),
+		integrity.SignaturePath(tr.resultsPath),
+		tr.samplesPath(),
+		reportPath,
+	}
+	files = append(files, tr.logFiles()...)
+	if tr.config.HeartbeatFile != "" {
+		files = append(files, tr.config.HeartbeatFile)
+	}
+	return files
+}
+
+// writeBundle writes the existing files into a gzipped tarball. Files under
+// baseDir keep their relative path; others are stored by name.
+func writeBundle(path, baseDir string, files []string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer out.Close()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	seen := make(map[string]bool)
+	for _, file := range files {
+		if file == "" || seen[file] {
+			continue
+		}
+		seen[file] = true
+		name, err := filepath.Rel(baseDir, file)
+		if err != nil || strings.HasPrefix(name, "..") {
+			name = filepath.Base(file)
+		}
+		if err := addToBundle(tw, file, filepath.ToSlash(name)); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return out.Close()
+}
+
+// addToBundle copies one file into the tarball, skipping files that were never written
+func addToBundle(tw *tar.Writer, path, name string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return fmt.Errorf("failed to add %s to bundle: %w", path, err)
+	}
+	header.Name = name
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to add %s to bundle: %w", path, err)
+	}
+	if _, err := io.Copy(tw, file); err != nil {
+		return fmt.Errorf("failed to add %s to bundle: %w", path, err)
+	}
+	return nil
+}
+
+// renderReport returns the run summary and a table of the iterations as Markdown
+func renderReport(summary string, results []TestResult) string {
+	var b strings.Builder
+	b.WriteString("# oc-mirror-test run report\n\n")
+	for _, line := range strings.Split(summary, "\n") {
+		fmt.Fprintf(&b, "%s  \n", strings.ReplaceAll(line, "*", ""))
+	}
+
+	// Throttling first: it is the usual reason a mirror is slow
+	var throttled []string
+	for _, r := range results {
+		if status := r.UploadPhase.HTTPStatus; status.Degraded() {
+			throttled = append(throttled, fmt.Sprintf("- Iteration %d %s upload: %s", r.Iteration, r.Version, status.String()))
+			for _, s := range status.Storms {
+				throttled = append(throttled, fmt.Sprintf("  - Storm at +%ds for %ds: %d throttled, %d server errors of %d responses",
+					s.OffsetSeconds, s.DurationSeconds, s.Throttled, s.ServerErrors, s.Responses))
+			}
+		}
+	}
+	if len(throttled) > 0 {
+		b.WriteString("\n## Registry Throttling and Errors\n\n")
+		b.WriteString(strings.Join(throttled, "\n") + "\n")
+	}
+
+	b.WriteString("\n## Iterations\n\n")
+	b.WriteString("| Iteration | Version | Registry | Run | Download | Upload | Downloaded | Uploaded | Cache Hits | Status |\n")
+	b.WriteString("|---|---|---|---|---|---|---|---|---|---|\n")
+	for _, r := range results {
+		run := "cached"
+		if r.IsCleanRun {
+			run = "clean"
+		}
+		if r.Warmup {
+			run += " (warm-up)"
+		}
+		status := "ok"
+		if r.Failed {
+			status = "failed: " + strings.ReplaceAll(truncateText(r.Error, 120), "|", "\\|")
+		}
+		fmt.Fprintf(&b, "| %d | %s | %s | %s | %s | %s | %s | %s | %d | %s |\n",
+			r.Iteration, r.Version, r.Registry, run,
+			formatDuration(r.DownloadPhase.WallTime), formatDuration(r.UploadPhase.WallTime),
+			monitor.FormatBytesHuman(r.DownloadPhase.DownloadMetrics.TotalBytesDownloaded),
+			monitor.FormatBytesHuman(r.UploadPhase.BytesUploaded),
+			r.DownloadPhase.CacheHits, status)
+	}
+
+	// Package sizes of the last iteration that measured them
+	for i := len(results) - 1; i >= 0; i-- {
+		ps := results[i].PackageSizes
+		if ps == nil || results[i].Warmup {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## Operator Packages\n\nIteration %d %s: %s in %d packages, %s shared by several packages\n\n",
+			results[i].Iteration, results[i].Version, monitor.FormatBytesHuman(ps.TotalBytes),
+			len(ps.Packages), monitor.FormatBytesHuman(ps.SharedBytes))
+		b.WriteString("| Package | Catalog | Bundles | Images | Size | Unique | Share |\n")
+		b.WriteString("|---|---|---|---|---|---|---|\n")
+		for _, p := range ps.Packages {
+			fmt.Fprintf(&b, "| %s | %s | %d | %d | %s | %s | %.1f%% |\n",
+				p.Package, p.Catalog, p.Bundles, p.Images, monitor.FormatBytesHuman(p.Bytes),
+				monitor.FormatBytesHuman(p.UniqueBytes), ps.Share(p))
+		}
+		break
+	}
+
+	var attempts []string
+	for _, r := range results {
+		for _, a := range r.FailedAttempts {
+			attempts = append(attempts, fmt.Sprintf("- Iteration %d %s attempt %d: %s", r.Iteration, a.Phase, a.Attempt, truncateText(a.Error, 200)))
+		}
+	}
+	if len(attempts) > 0 {
+		b.WriteString("\n## Failed Attempts\n\n")
+		b.WriteString(strings.Join(attempts, "\n") + "\n")
+	}
+
+	if last := len(results) - 1; last >= 0 && results[last].DeletePhase != nil {
+		d := results[last].DeletePhase
+		b.WriteString("\n## Delete Phase\n\n")
+		if d.Error != "" {
+			fmt.Fprintf(&b, "Failed: %s\n", d.Error)
+		} else {
+			fmt.Fprintf(&b, "%d/%d images deleted in %s with %d API calls, %s reclaimed\n",
+				d.DeleteMetrics.ImagesDeleted, d.ImagesPlanned, formatDuration(d.WallTime),
+				d.DeleteMetrics.APICalls, monitor.FormatBytesHuman(d.BytesReclaimed))
+		}
+	}
+	return b.String()
+}
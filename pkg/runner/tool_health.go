@@ -0,0 +1,190 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// Packages whose goroutines must all have exited when a run ends: the
+// pollers of the monitors and the heartbeat, and the oc-mirror output readers
+var pollerPackages = []string{
+	"github.com/telco-core/ngc-495/pkg/monitor.",
+	"github.com/telco-core/ngc-495/pkg/heartbeat.",
+	"github.com/telco-core/ngc-495/pkg/command.",
+}
+
+const (
+	// pollerExitGrace is how long stopped pollers get to notice their stop
+	// signal before they are reported as leaked
+	pollerExitGrace = 3 * time.Second
+
+	// heapGrowthTolerance is the heap growth over the run start that is not
+	// reported; the results of the run itself stay in memory
+	heapGrowthTolerance = 64 << 20
+)
+
+// ToolHealth is the self-check of the test tool at the end of a run. Leaked
+// pollers keep sampling after their phase and skew the metrics of later runs
+// in the same process, such as runs started from the web UI.
+type ToolHealth struct {
+	Goroutines         int      `json:"goroutines"`
+	BaselineGoroutines int      `json:"baseline_goroutines"`
+	LeakedGoroutines   []string `json:"leaked_goroutines,omitempty"` // Still running poller functions, with their count
+	OpenFiles          []string `json:"open_files,omitempty"`        // Files opened during the run and never closed
+	HeapBytes          int64    `json:"heap_bytes"`                  // Live heap after a GC
+	BaselineHeapBytes  int64    `json:"baseline_heap_bytes"`
+	Warnings           []string `json:"warnings,omitempty"`
+}
+
+// Healthy reports whether no leak was found
+func (h *ToolHealth) Healthy() bool {
+	return len(h.Warnings) == 0
+}
+
+// healthBaseline is the process state at the start of a run
+type healthBaseline struct {
+	goroutines int
+	heapBytes  int64
+	openFiles  map[string]bool
+}
+
+// captureHealthBaseline records the process state before any monitor starts
+func captureHealthBaseline() *healthBaseline {
+	files, _ := openFiles()
+	return &healthBaseline{
+		goroutines: runtime.NumGoroutine(),
+		heapBytes:  liveHeapBytes(),
+		openFiles:  files,
+	}
+}
+
+// checkToolHealth compares the process state after all monitors were stopped
+// with the baseline, prints the findings and records them in the results file
+func (tr *TestRunner) checkToolHealth(baseline *healthBaseline) {
+	health := &ToolHealth{BaselineGoroutines: baseline.goroutines, BaselineHeapBytes: baseline.heapBytes}
+
+	deadline := time.Now().Add(pollerExitGrace)
+	leaked := runningPollers()
+	for len(leaked) > 0 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		leaked = runningPollers()
+	}
+	health.LeakedGoroutines = leaked
+	health.Goroutines = runtime.NumGoroutine()
+	if len(leaked) > 0 {
+		health.Warnings = append(health.Warnings, fmt.Sprintf("monitor goroutines still running: %s", strings.Join(leaked, ", ")))
+	}
+
+	if files, err := openFiles(); err == nil {
+		for file := range files {
+			if !baseline.openFiles[file] {
+				health.OpenFiles = append(health.OpenFiles, file)
+			}
+		}
+		sort.Strings(health.OpenFiles)
+		if len(health.OpenFiles) > 0 {
+			health.Warnings = append(health.Warnings, fmt.Sprintf("files left open: %s", strings.Join(health.OpenFiles, ", ")))
+		}
+	}
+
+	health.HeapBytes = liveHeapBytes()
+	if growth := health.HeapBytes - baseline.heapBytes; growth > heapGrowthTolerance {
+		health.Warnings = append(health.Warnings, fmt.Sprintf("heap %s above the run start", monitor.FormatBytesHuman(growth)))
+	}
+
+	logging.Printf("\nTool Health:\n")
+	logging.Printf("  Goroutines: %d (%d at start)\n", health.Goroutines, health.BaselineGoroutines)
+	logging.Printf("  Heap: %s (%s at start)\n", monitor.FormatBytesHuman(health.HeapBytes), monitor.FormatBytesHuman(health.BaselineHeapBytes))
+	for _, warning := range health.Warnings {
+		logging.Printf("  Warning: %s\n", warning)
+	}
+
+	tr.header.ToolHealth = health
+	if len(tr.results) > 0 {
+		if err := tr.saveResults(); err != nil {
+			logging.Printf("Warning: Failed to save tool health: %v\n", err)
+		}
+	}
+}
+
+// runningPollers returns the poller functions that still have goroutines, as
+// "monitor.(*NetworkMonitor).monitorLoop (2)"
+func runningPollers() []string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	counts := make(map[string]int)
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		if fn := pollerFunction(stack); fn != "" {
+			counts[fn]++
+		}
+	}
+
+	pollers := make([]string, 0, len(counts))
+	for fn, count := range counts {
+		pollers = append(pollers, fmt.Sprintf("%s (%d)", fn, count))
+	}
+	sort.Strings(pollers)
+	return pollers
+}
+
+// pollerFunction returns the innermost poller package function of a goroutine
+// stack, or "" when the goroutine does not run poller code
+func pollerFunction(stack string) string {
+	for _, line := range strings.Split(stack, "\n") {
+		if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "created by ") {
+			continue
+		}
+		for _, pkg := range pollerPackages {
+			if strings.HasPrefix(line, pkg) {
+				fn := strings.TrimPrefix(line, "github.com/telco-core/ngc-495/pkg/")
+				if idx := strings.LastIndex(fn, "("); idx > 0 {
+					fn = fn[:idx]
+				}
+				return fn
+			}
+		}
+	}
+	return ""
+}
+
+// openFiles returns the regular files the process has open. Sockets, pipes
+// and devices are left out; the web UI opens and closes connections at will.
+func openFiles() (map[string]bool, error) {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]bool)
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", entry.Name()))
+		if err != nil || !filepath.IsAbs(target) || strings.HasPrefix(target, "/dev/") || strings.HasPrefix(target, "/proc/") {
+			continue
+		}
+		files[target] = true
+	}
+	return files, nil
+}
+
+// liveHeapBytes returns the heap in use after a garbage collection
+func liveHeapBytes() int64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return int64(stats.HeapAlloc)
+}
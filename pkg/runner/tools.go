@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"context"
+	"strings"
+
+	"github.com/telco-core/ngc-495/pkg/client"
+	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// defaultBinDir is where the client tools are downloaded without --bin-dir
+const defaultBinDir = "./bin"
+
+// ensureTools checks that the client tools the run needs are in the bin
+// directory or PATH and downloads the missing ones into the bin directory.
+// The commands run with the bin directory first in their PATH.
+func (tr *TestRunner) ensureTools(tools []string) {
+	binDir := tr.config.BinDir
+	if binDir == "" {
+		binDir = defaultBinDir
+	}
+	version := tr.config.ToolsVersion
+	if version == "" {
+		version = client.DefaultOCPVersion
+	}
+	command.SetBinDir(binDir)
+
+	logging.Printf("Checking for required tools (%s)...\n", strings.Join(tools, ", "))
+	downloaded, err := client.EnsureTools(context.Background(), version, binDir, tr.config.ToolsFromDir, tools)
+	for _, result := range downloaded {
+		if !result.Success || result.Checksum == "" {
+			continue
+		}
+		if len(result.Attempts) > 1 {
+			logging.Printf("%s needed %d download attempts, %d resumed\n", result.Tool, len(result.Attempts), result.Resumed())
+		}
+		if tr.config.ToolsFromDir != "" {
+			logging.Printf("Installed %s from %s to %s (sha256 verified)\n", result.Tool, tr.config.ToolsFromDir, result.Path)
+		} else {
+			logging.Printf("Downloaded %s for OpenShift %s to %s (sha256 verified)\n", result.Tool, version, result.Path)
+		}
+	}
+	if err != nil {
+		logging.Printf("Warning: Failed to ensure tools are available: %v\n", err)
+		if tr.config.ToolsFromDir != "" {
+			logging.Printf("Please stage the tarballs of %s with their sha256sum.txt in %s\n", strings.Join(tools, ", "), tr.config.ToolsFromDir)
+		} else {
+			logging.Printf("Please ensure %s is in PATH or run: oc-mirror-test download --version %s --bin-dir %s\n",
+				strings.Join(tools, ", "), version, binDir)
+		}
+		return
+	}
+	logging.Printf("Client tools run from: %s (then PATH)\n", command.BinDir())
+}
@@ -0,0 +1,222 @@
+package runner
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/tracing"
+)
+
+// Span names, each the root of its own trace or a child of the iteration
+const (
+	spanIteration = "iteration"
+	spanPhase     = "phase "     // + phase name, e.g. "phase download"
+	spanMonitor   = "monitor "   // + sample source
+	spanOCMirror  = "oc-mirror " // + what the invocation does
+)
+
+// traceState holds the open spans of the run
+type traceState struct {
+	mu        sync.Mutex
+	iteration *tracing.Span  // Root of the current iteration trace
+	phase     *tracing.Span  // Current phase, a child of the iteration or a root outside iterations
+	monitors  []*monitorSpan // Monitors observed since the run or the iteration started
+}
+
+// monitorSpan covers a monitor from its start to its last sample
+type monitorSpan struct {
+	span      *tracing.Span
+	iteration bool // Ends with the iteration rather than the run
+
+	mu      sync.Mutex
+	samples int64
+	last    time.Time
+}
+
+// startTracing creates the tracer when an OTLP endpoint is configured
+func (tr *TestRunner) startTracing() {
+	if !tr.config.Tracing.Enabled() {
+		return
+	}
+	host, _ := os.Hostname()
+	tr.tracer = tracing.NewTracer(tr.config.Tracing,
+		tracing.String("host.name", host),
+		tracing.String("test.scenario", tr.config.ScenarioName),
+		tracing.String("test.run_name", tr.config.RunName))
+}
+
+// stopTracing ends the spans still open and exports them
+func (tr *TestRunner) stopTracing(runErr error) {
+	if tr.tracer == nil {
+		return
+	}
+	tr.traces.mu.Lock()
+	tr.traces.phase.RecordError(runErr)
+	tr.traces.phase.End()
+	tr.traces.phase = nil
+	monitors := tr.traces.monitors
+	tr.traces.monitors = nil
+	tr.traces.mu.Unlock()
+	for _, ms := range monitors {
+		ms.end()
+	}
+	tr.flushTraces()
+}
+
+// flushTraces exports the ended spans
+func (tr *TestRunner) flushTraces() {
+	if err := tr.tracer.Flush(); err != nil {
+		logging.Printf("  │ Warning: Failed to export traces: %v\n", err)
+	}
+}
+
+// startIterationTrace starts the trace of an iteration and returns the function
+// ending it with the iteration outcome
+func (tr *TestRunner) startIterationTrace(result *TestResult) func(err error) {
+	if tr.tracer == nil {
+		return func(error) {}
+	}
+	attrs := []tracing.Attribute{
+		tracing.Int("iteration", int64(result.Iteration)),
+		tracing.String("oc_mirror.api", result.Version),
+		tracing.String("run.kind", runKind(result.IsCleanRun, result.IsUpdateRun)),
+		tracing.String("registry", result.Registry),
+	}
+	if tr.header.OCMirrorVersion != "" {
+		attrs = append(attrs, tracing.String("oc_mirror.version", tr.header.OCMirrorVersion))
+	}
+	if result.Scenario != "" {
+		attrs = append(attrs, tracing.String("test.scenario", result.Scenario))
+	}
+	if result.Stage != nil {
+		attrs = append(attrs, tracing.String("stage", result.Stage.Name), tracing.Int("stage.index", int64(result.Stage.Index)))
+	}
+
+	tr.traces.mu.Lock()
+	// The setup phase ends when the first iteration starts
+	tr.traces.phase.End()
+	tr.traces.phase = nil
+	span := tr.tracer.Start(nil, spanIteration, attrs...)
+	tr.traces.iteration = span
+	tr.traces.mu.Unlock()
+	result.TraceID = span.TraceID()
+
+	return func(err error) {
+		tr.traces.mu.Lock()
+		tr.traces.phase.RecordError(err)
+		tr.traces.phase.End()
+		tr.traces.phase = nil
+		tr.traces.iteration = nil
+		var monitors []*monitorSpan
+		kept := tr.traces.monitors[:0]
+		for _, ms := range tr.traces.monitors {
+			if ms.iteration {
+				monitors = append(monitors, ms)
+			} else {
+				kept = append(kept, ms)
+			}
+		}
+		tr.traces.monitors = kept
+		tr.traces.mu.Unlock()
+
+		for _, ms := range monitors {
+			ms.end()
+		}
+		span.RecordError(err)
+		span.End()
+		tr.flushTraces()
+	}
+}
+
+// tracePhase ends the span of the previous phase and starts one for phase
+func (tr *TestRunner) tracePhase(phase, version string, iteration int) {
+	if tr.tracer == nil {
+		return
+	}
+	attrs := []tracing.Attribute{tracing.String("phase", phase)}
+	if version != "" {
+		attrs = append(attrs, tracing.String("oc_mirror.api", version))
+	}
+	if iteration > 0 {
+		attrs = append(attrs, tracing.Int("iteration", int64(iteration)))
+	}
+
+	tr.traces.mu.Lock()
+	defer tr.traces.mu.Unlock()
+	tr.traces.phase.End()
+	tr.traces.phase = tr.tracer.Start(tr.traces.iteration, spanPhase+phase, attrs...)
+}
+
+// traceMonitor starts the span of a monitor under the current phase; the
+// returned span counts the samples the monitor takes
+func (tr *TestRunner) traceMonitor(source string) *monitorSpan {
+	if tr.tracer == nil {
+		return nil
+	}
+	tr.traces.mu.Lock()
+	defer tr.traces.mu.Unlock()
+	parent := tr.traces.phase
+	if parent == nil {
+		parent = tr.traces.iteration
+	}
+	ms := &monitorSpan{
+		span:      tr.tracer.Start(parent, spanMonitor+source, tracing.String("monitor.source", source)),
+		iteration: tr.traces.iteration != nil,
+	}
+	tr.traces.monitors = append(tr.traces.monitors, ms)
+	return ms
+}
+
+// sample records a sample of the monitor; nil-safe for runs without tracing
+func (ms *monitorSpan) sample() {
+	if ms == nil {
+		return
+	}
+	ms.mu.Lock()
+	ms.samples++
+	ms.last = time.Now()
+	ms.mu.Unlock()
+}
+
+// end ends the span at the last sample, or now if the monitor took none
+func (ms *monitorSpan) end() {
+	ms.mu.Lock()
+	samples, last := ms.samples, ms.last
+	ms.mu.Unlock()
+	ms.span.SetAttributes(tracing.Int("monitor.samples", samples))
+	if last.IsZero() {
+		ms.span.End()
+		return
+	}
+	ms.span.EndAt(last)
+}
+
+// traceOCMirror starts the span of an oc-mirror invocation under the current
+// phase and returns the function ending it with the command outcome
+func (tr *TestRunner) traceOCMirror(name string, cmd *command.OCMirrorCommand) func(*command.CommandOutput, error) {
+	if tr.tracer == nil {
+		return func(*command.CommandOutput, error) {}
+	}
+	tr.traces.mu.Lock()
+	parent := tr.traces.phase
+	if parent == nil {
+		parent = tr.traces.iteration
+	}
+	span := tr.tracer.Start(parent, spanOCMirror+name, tracing.String("oc_mirror.args", strings.Join(cmd.Args(), " ")))
+	tr.traces.mu.Unlock()
+
+	return func(output *command.CommandOutput, err error) {
+		if output != nil {
+			span.SetAttributes(tracing.Int("oc_mirror.exit_code", int64(output.ExitCode)))
+			if output.LogFile != "" {
+				span.SetAttributes(tracing.String("oc_mirror.log_file", output.LogFile))
+			}
+		}
+		span.RecordError(err)
+		span.End()
+	}
+}
@@ -4,34 +4,89 @@ import (
 	"time"
 
 	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/environment"
 	"github.com/telco-core/ngc-495/pkg/monitor"
+	"github.com/telco-core/ngc-495/pkg/netshape"
+	"github.com/telco-core/ngc-495/pkg/pacing"
+	"github.com/telco-core/ngc-495/pkg/plugin"
+	"github.com/telco-core/ngc-495/pkg/proxy"
+	"github.com/telco-core/ngc-495/pkg/registry"
 )
 
 // TestResult represents the results of a single test iteration
 type TestResult struct {
-	Iteration       int                      `json:"iteration"`
-	IsCleanRun      bool                     `json:"is_clean_run"`
-	Version         string                   `json:"version"` // "v1" or "v2"
-	DownloadPhase   PhaseMetrics             `json:"download_phase"`
-	UploadPhase     PhaseMetrics             `json:"upload_phase"`
-	NetworkMetrics  monitor.NetworkMetrics   `json:"network_metrics"`
-	ResourceMetrics monitor.ResourceMetrics  `json:"resource_metrics"`
-	OutputMetrics   monitor.OutputMetrics    `json:"output_metrics"`
-	DescribeMetrics *command.DescribeMetrics `json:"describe_metrics,omitempty"`
-	RegistryMetrics *monitor.RegistryMetrics `json:"registry_metrics,omitempty"` // Registry upload metrics
-	Summary         string                   `json:"summary"`
+	Iteration         int                      `json:"iteration"`
+	IsCleanRun        bool                     `json:"is_clean_run"`
+	Cleanup           string                   `json:"cleanup,omitempty"`          // Workspace cleanup strategy applied before the iteration
+	IsUpdateRun       bool                     `json:"is_update_run,omitempty"`    // First iteration mirroring the day-2 update content
+	Warmup            bool                     `json:"warmup,omitempty"`           // Warm-up iteration, left out of comparisons and statistics
+	Version           string                   `json:"version"`                    // "v1" or "v2"
+	Matrix            *MatrixCell              `json:"matrix,omitempty"`           // Dimension values of the iteration matrix the iteration ran with
+	Registry          string                   `json:"registry,omitempty"`         // Registry the iteration pushed to
+	Scenario          string                   `json:"scenario,omitempty"`         // Scenario file name, when run from --scenario
+	ContentScenario   string                   `json:"content_scenario,omitempty"` // Mirrored content type (operators, additional-images, helm, mixed, custom)
+	ContentRevision   string                   `json:"content_revision,omitempty"` // initial or update, in a day-2 update run
+	DownloadPhase     PhaseMetrics             `json:"download_phase"`
+	UploadPhase       PhaseMetrics             `json:"upload_phase"`
+	NetworkMetrics    monitor.NetworkMetrics   `json:"network_metrics"`
+	NetworkAccounting string                   `json:"network_accounting,omitempty"` // interface or process
+	Proxy             *ProxySettings             `json:"proxy,omitempty"`              // Proxy oc-mirror ran through, when one is configured
+	ResourceMetrics   monitor.ResourceMetrics  `json:"resource_metrics"`
+	OutputMetrics     monitor.OutputMetrics    `json:"output_metrics"`
+	DiskUsageBytes    int64                    `json:"disk_usage_bytes,omitempty"` // Workspace, cache and OCI layout size after the iteration
+	DescribeMetrics   *command.DescribeMetrics `json:"describe_metrics,omitempty"`
+	SignatureMetrics  *registry.SignatureMetrics `json:"signature_metrics,omitempty"` // cosign signatures and attestations in the registry after upload
+	CatalogDiff       *registry.CatalogDiff      `json:"catalog_diff,omitempty"`      // Repositories and tags the upload added to the registry catalog
+	ExpectedContent   *ContentValidation         `json:"expected_content,omitempty"`  // Images of the operator packages rendered with opm, checked after upload
+	UploadVerification *UploadVerification       `json:"upload_verification,omitempty"` // Blob digests of the local mirror compared with the registry after upload
+	PackageSizes      *PackageSizes              `json:"package_sizes,omitempty"`     // Mirrored bytes attributed to each operator package after upload
+	PullThrough       *command.PullThroughReport `json:"pull_through,omitempty"`      // Mirrored catalogs served and resolved on the cluster after upload
+	ClusterArtifacts  *command.ClusterArtifactValidation `json:"cluster_artifacts,omitempty"` // Generated IDMS/ITMS/ICSP and CatalogSource references checked against the registry
+	ReferenceCounts   *ReferenceCounts           `json:"reference_counts,omitempty"`  // Tags and manifests a clean upload added, when digest references are compared
+	Chaos             *ChaosMetrics              `json:"chaos,omitempty"`             // Download killed part way before the download phase resumed it
+	RegistryMetrics   *monitor.RegistryMetrics `json:"registry_metrics,omitempty"` // Registry upload metrics
+	Environment       *environment.Snapshot    `json:"environment,omitempty"`      // Host and storage layout for the run
+	MonitorSettings   *MonitorSettings         `json:"monitor_settings,omitempty"` // Monitors, poll intervals and sampled interface
+	NetworkShaping    *netshape.Config         `json:"network_shaping,omitempty"`  // Network constraints applied during the iteration
+	FailedAttempts    []FailedAttempt          `json:"failed_attempts,omitempty"`  // Failed phase attempts, including retried ones
+	DeletePhase       *DeletePhaseMetrics      `json:"delete_phase,omitempty"`     // Set on the last iteration when the run ends with a delete phase
+	RegistryCleanup   *DeletePhaseMetrics      `json:"registry_cleanup,omitempty"` // Content of the run deleted from the registry by the registry cleanup strategy
+	AirGap            *AirGapMetrics           `json:"air_gap,omitempty"`          // Archive and transfer, in the air-gap workflow
+	Replication       []HopMetrics             `json:"replication,omitempty"`      // Hops replicating the upload to further registries
+	Stage             *StageMetrics            `json:"stage,omitempty"`            // Stage of a priority-ordered iteration
+	TraceID           string                   `json:"trace_id,omitempty"`         // OpenTelemetry trace of the iteration, when tracing is enabled
+	Failed            bool                     `json:"failed,omitempty"`           // Set when a phase still failed after all retries
+	Error             string                   `json:"error,omitempty"`            // Error of a failed iteration
+	Passed            bool                     `json:"passed"`                     // Completed and met every gate
+	FailureReasons    []string                 `json:"failure_reasons,omitempty"`  // Gates the iteration did not meet
+	Summary           string                   `json:"summary"`
 }
 
 // PhaseMetrics represents metrics for a single phase (download or upload)
 type PhaseMetrics struct {
-	WallTime        time.Duration            `json:"wall_time_seconds"`
-	BytesUploaded   int64                    `json:"bytes_uploaded"`
-	Logs            []string                 `json:"logs,omitempty"`
-	ImagesSkipped   int                      `json:"images_skipped"`
-	CacheHits       int                      `json:"cache_hits"`
-	DownloadMetrics monitor.DownloadMetrics  `json:"download_metrics,omitempty"`
-	ResourceMetrics monitor.ResourceMetrics  `json:"resource_metrics,omitempty"`
-	ExtendedMetrics command.ExtendedMetrics  `json:"extended_metrics,omitempty"`
+	WallTime              time.Duration                  `json:"wall_time_seconds"`
+	Timeout               bool                           `json:"timeout,omitempty"` // oc-mirror was killed when the phase timeout passed
+	BytesUploaded         int64                          `json:"bytes_uploaded"`
+	LogFile               string                         `json:"log_file,omitempty"` // oc-mirror stdout and stderr of the phase
+	LogExcerpt            *command.LogExcerpt            `json:"log_excerpt,omitempty"` // Output lines kept by the log retention mode
+	LogSummary            *command.LogSummary            `json:"log_summary,omitempty"` // Distinct error and warning messages with their counts
+	ImagesSkipped         int                            `json:"images_skipped"`
+	CacheHits             int                            `json:"cache_hits"` // Blobs already in the oc-mirror v2 cache when the phase started
+	DownloadMetrics       monitor.DownloadMetrics        `json:"download_metrics,omitempty"`
+	ResourceMetrics       monitor.ResourceMetrics        `json:"resource_metrics,omitempty"`
+	ExtendedMetrics       command.ExtendedMetrics        `json:"extended_metrics,omitempty"`
+	DiskWriteMetrics      *monitor.DiskWriteMetrics      `json:"disk_write_metrics,omitempty"`      // Set for oci:// targets
+	DiskIOMetrics         *monitor.DiskIOMetrics         `json:"disk_io_metrics,omitempty"`         // Block device I/O for workspace and cache
+	ProcessNetworkMetrics *monitor.ProcessNetworkMetrics `json:"process_network_metrics,omitempty"` // Set in process network accounting mode
+	RetryTimeline         *command.RetryTimeline         `json:"retry_timeline,omitempty"`          // Retries over time against phase throughput
+	HTTPStatus            *monitor.HTTPStatusMetrics     `json:"http_status,omitempty"`             // Registry response status codes of the upload phase
+	Pacing                *pacing.Applied                `json:"pacing,omitempty"`                  // Upload pacing applied, when configured
+	ClusterResources      *command.ClusterResourcesMetrics `json:"cluster_resources,omitempty"`    // IDMS/ITMS/CatalogSource generation time and sizes
+	MemoryCeiling         *monitor.MemoryCeilingMetrics    `json:"memory_ceiling,omitempty"`       // Usage against the memory budget and OOM kills, when a budget is set
+	CacheMetrics          *monitor.CacheMetrics            `json:"cache_metrics,omitempty"`        // oc-mirror v2 cache size and growth over the phase
+	Plugins               []plugin.Metrics                 `json:"plugins,omitempty"`              // Values reported by monitor plugins
+	RegistryServer        *monitor.RegistryServerMetrics   `json:"registry_server,omitempty"`      // Registry-side request, error and storage rates of the upload phase
+	UploadTraffic         *proxy.Stats                     `json:"upload_traffic,omitempty"`       // Requests, bytes and status codes counted by the accounting proxy
 }
 
 // ComparisonResult represents comparison between v1 and v2 or clean vs cached
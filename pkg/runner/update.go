@@ -0,0 +1,170 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/telco-core/ngc-495/internal/config"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+)
+
+// Content revisions mirrored by the iterations of a day-2 update run
+const (
+	ContentRevisionInitial = "initial" // The content of the first iteration
+	ContentRevisionUpdate  = "update"  // The update content, from the second iteration on
+)
+
+// ValidateUpdate checks that the day-2 update can run with the rest of the configuration
+func (c *Config) ValidateUpdate() error {
+	if c.UpdateContent == nil {
+		return nil
+	}
+	if err := c.UpdateContent.Validate(); err != nil {
+		return fmt.Errorf("invalid update content: %w", err)
+	}
+	if c.Iterations < 2 {
+		return fmt.Errorf("the day-2 update requires at least 2 iterations")
+	}
+	if c.CompareV1V2 {
+		return fmt.Errorf("the day-2 update cannot be combined with the v1/v2 comparison")
+	}
+	if c.IsRegistryComparison() {
+		return fmt.Errorf("the day-2 update cannot be combined with registry comparison")
+	}
+	return nil
+}
+
+// runKind labels an iteration: the clean run, the first run of the update
+// content, or a cached rerun of the same content
+func runKind(isCleanRun, isUpdateRun bool) string {
+	switch {
+	case isCleanRun:
+		return "CLEAN"
+	case isUpdateRun:
+		return "UPDATE"
+	}
+	return "CACHED"
+}
+
+// writeImageSetConfigs renders content into the v1 (v1alpha2) and v2 (v2alpha1)
+// imageset configurations
+func writeImageSetConfigs(content *config.ContentSpec) error {
+	if err := config.CreateImageSetConfigForContent("oc-mirror-clone/imagesetconfiguration_operators-v1.yaml", "v1alpha2", content); err != nil {
+		return fmt.Errorf("failed to create v1 imageset-config: %w", err)
+	}
+	if err := config.CreateImageSetConfigForContent("oc-mirror-clone/imagesetconfiguration_operators-v2.yaml", "v2alpha1", content); err != nil {
+		return fmt.Errorf("failed to create v2 imageset-config: %w", err)
+	}
+	// Also create default for backward compatibility
+	if err := config.CreateImageSetConfigForContent("oc-mirror-clone/imagesetconfiguration_operators.yaml", "v2alpha1", content); err != nil {
+		return fmt.Errorf("failed to create imageset-config: %w", err)
+	}
+	return nil
+}
+
+// mirroredContent returns the content the current iteration mirrors
+func (tr *TestRunner) mirroredContent() *config.ContentSpec {
+	if tr.stageContent != nil {
+		return tr.stageContent
+	}
+	if tr.contentRevision == ContentRevisionUpdate {
+		return tr.config.UpdateContent
+	}
+	return tr.config.Content
+}
+
+// contentChanges returns how the update content differs from the initial content
+func (tr *TestRunner) contentChanges() []string {
+	initial := tr.config.Content
+	if initial == nil {
+		initial = config.DefaultContent()
+	}
+	return config.ContentChanges(initial, tr.config.UpdateContent)
+}
+
+// startUpdate switches the imageset configurations to the update content for
+// this and the following iterations
+func (tr *TestRunner) startUpdate() error {
+	tr.contentRevision = ContentRevisionUpdate
+	if err := writeImageSetConfigs(tr.config.UpdateContent); err != nil {
+		return err
+	}
+
+	logging.Printf("Mirroring the update content:\n")
+	if len(tr.header.ContentChanges) == 0 {
+		logging.Printf("  Warning: The update content does not differ from the initial content\n")
+	}
+	for _, change := range tr.header.ContentChanges {
+		logging.Printf("  - %s\n", change)
+	}
+	return nil
+}
+
+// compareIncrementalUpdate prints the cost of mirroring the update content
+// against the initial mirror
+func (tr *TestRunner) compareIncrementalUpdate() {
+	var initial, update *TestResult
+	for i := range tr.results {
+		result := &tr.results[i]
+		if result.Failed || result.Warmup {
+			continue
+		}
+		if result.IsCleanRun {
+			initial = result
+		} else if result.IsUpdateRun {
+			update = result
+		}
+	}
+	if initial == nil || update == nil {
+		logging.Printf("\nSkipping day-2 update comparison: the initial or the update iteration did not complete\n")
+		return
+	}
+
+	logging.Printf("\n╔═══════════════════════════════════════════════════════════════╗\n")
+	logging.Printf("║  Comparison: Initial Mirror vs Day-2 Update                   ║\n")
+	logging.Printf("╠═══════════════════════════════════════════════════════════════╣\n")
+
+	printDurations := func(title string, initialTime, updateTime time.Duration) {
+		logging.Printf("║  %-61s ║\n", title+":")
+		logging.Printf("║    Initial: %-50v ║\n", initialTime.Round(time.Millisecond))
+		logging.Printf("║    Update:  %-50v ║\n", updateTime.Round(time.Millisecond))
+		logging.Printf("║    Incremental: %-46s ║\n", percentOfInitial(float64(updateTime), float64(initialTime)))
+	}
+	printBytes := func(title string, initialBytes, updateBytes int64) {
+		logging.Printf("║  %-61s ║\n", title+":")
+		logging.Printf("║    Initial: %-50s ║\n", monitor.FormatBytesHuman(initialBytes))
+		logging.Printf("║    Update:  %-50s ║\n", monitor.FormatBytesHuman(updateBytes))
+		logging.Printf("║    Incremental: %-46s ║\n", percentOfInitial(float64(updateBytes), float64(initialBytes)))
+	}
+
+	printDurations("Download Time", initial.DownloadPhase.WallTime, update.DownloadPhase.WallTime)
+	logging.Printf("║                                                                ║\n")
+	printDurations("Upload Time", initial.UploadPhase.WallTime, update.UploadPhase.WallTime)
+	logging.Printf("║                                                                ║\n")
+	printBytes("Bytes Downloaded", initial.DownloadPhase.DownloadMetrics.TotalBytesDownloaded, update.DownloadPhase.DownloadMetrics.TotalBytesDownloaded)
+	logging.Printf("║                                                                ║\n")
+	printBytes("Bytes Uploaded", initial.UploadPhase.BytesUploaded, update.UploadPhase.BytesUploaded)
+	if initialCache, updateCache := initial.DownloadPhase.CacheMetrics, update.DownloadPhase.CacheMetrics; initialCache != nil && updateCache != nil {
+		// Blobs the update added to the oc-mirror v2 cache: the new layers of the newer versions
+		logging.Printf("║                                                                ║\n")
+		printBytes("Cache Growth", initialCache.GrowthBytes, updateCache.GrowthBytes)
+		logging.Printf("║    New Blobs: %-48s ║\n", fmt.Sprintf("%d initial, %d update", initialCache.NewBlobs, updateCache.NewBlobs))
+	}
+	if initial.DescribeMetrics != nil && update.DescribeMetrics != nil {
+		logging.Printf("║                                                                ║\n")
+		logging.Printf("║  %-61s ║\n", fmt.Sprintf("Images: %d initial, %d update (%+d)", initial.DescribeMetrics.TotalImages,
+			update.DescribeMetrics.TotalImages, update.DescribeMetrics.TotalImages-initial.DescribeMetrics.TotalImages))
+	}
+	logging.Printf("║                                                                ║\n")
+	logging.Printf("║  %-61s ║\n", fmt.Sprintf("Content Changes: %d", len(tr.header.ContentChanges)))
+	logging.Printf("╚═══════════════════════════════════════════════════════════════╝\n")
+}
+
+// percentOfInitial formats an update value as a percentage of the initial value
+func percentOfInitial(update, initial float64) string {
+	if initial <= 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%% of the initial mirror", update/initial*100)
+}
@@ -0,0 +1,210 @@
+package runner
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/command"
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+	"github.com/telco-core/ngc-495/pkg/registry"
+)
+
+// Verdicts of the upload verification
+const (
+	UploadVerified   = "verified"   // Every local blob is in the registry with the same size
+	UploadIncomplete = "incomplete" // Local blobs missing from the registry
+	UploadCorrupt    = "corrupt"    // Local blobs that do not match their digest, or stored with another size
+	UploadUnverified = "unverified" // No local digests to compare, or the registry could not be listed
+)
+
+// uploadVerificationConcurrency is the number of tags whose manifests are read in parallel
+const uploadVerificationConcurrency = 8
+
+// maxListedDigests limits the digests stored per finding; the counts cover all of them
+const maxListedDigests = 50
+
+// UploadVerification compares the blob digests of the local mirror with the
+// blobs the registry references after the upload
+type UploadVerification struct {
+	Verdict         string        `json:"verdict"`
+	LocalDigests    int           `json:"local_digests"`    // From the archives, blob files and oc-mirror describe
+	RegistryDigests int           `json:"registry_digests"` // Referenced by the tags under the upload prefix
+	RegistryTags    int           `json:"registry_tags"`
+	Matched         int           `json:"matched"`
+	MissingCount    int           `json:"missing_count"`
+	Missing         []string      `json:"missing,omitempty"` // First missing digests
+	SizeMismatches  []string      `json:"size_mismatches,omitempty"`
+	CorruptLocal    []string      `json:"corrupt_local,omitempty"` // Local blob files whose content does not match their digest
+	Errors          []string      `json:"errors,omitempty"`
+	Duration        time.Duration `json:"duration_seconds"`
+}
+
+// localDigests collects the blob digests of the local mirror with their size,
+// -1 when unknown: the entries of the archives oc-mirror wrote, the blob files
+// of the output analysis and the layers oc-mirror describe reports. Blob files
+// whose hash does not match their digest are returned as corrupt.
+func localDigests(mirrorPath, version string, output monitor.OutputMetrics, describe *command.DescribeMetrics) (map[string]int64, []string, []string) {
+	digests := make(map[string]int64)
+	add := func(digest string, size int64) {
+		if known, ok := digests[digest]; !ok || known < 0 {
+			digests[digest] = size
+		}
+	}
+	var corrupt, errs []string
+
+	archives, _ := filepath.Glob(filepath.Join(mirrorPath, archivePattern(version)))
+	for _, archive := range archives {
+		if err := archiveDigests(archive, add); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	for path, hash := range output.FileHashes {
+		digest := monitor.BlobDigest(path)
+		if digest == "" {
+			continue
+		}
+		add(digest, -1)
+		if !strings.HasPrefix(hash, "size:") && "sha256:"+hash != digest {
+			corrupt = append(corrupt, path)
+		}
+	}
+	if describe != nil {
+		for _, digest := range describe.LayerDigests {
+			add(digest, -1)
+		}
+	}
+	sort.Strings(corrupt)
+	return digests, corrupt, errs
+}
+
+// archiveDigests reads the blob entries of a tar archive without its content
+func archiveDigests(archive string, add func(digest string, size int64)) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	reader := tar.NewReader(f)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive %s: %w", filepath.Base(archive), err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if digest := monitor.BlobDigest(header.Name); digest != "" {
+			add(digest, header.Size)
+		}
+	}
+}
+
+// verifyUpload checks that the registry holds every blob of the local mirror
+// after the upload. It returns nil when disabled or for OCI layout targets.
+func (tr *TestRunner) verifyUpload(version string, result *TestResult) *UploadVerification {
+	if !tr.config.VerifyUpload || tr.config.IsOCITarget() {
+		return nil
+	}
+	startTime := time.Now()
+	verification := &UploadVerification{Verdict: UploadUnverified}
+
+	local, corrupt, errs := localDigests(tr.mirrorDir(version), version, result.OutputMetrics, result.DescribeMetrics)
+	verification.LocalDigests = len(local)
+	verification.CorruptLocal = truncateList(corrupt, maxListedDigests)
+	verification.Errors = errs
+
+	client, err := registry.NewClient(tr.targetRegistry(), "", tr.config.SkipTLS)
+	if err == nil {
+		var content *registry.ContentDigests
+		content, err = registry.ListContentDigests(context.Background(), client, tr.catalogPrefix(version), uploadVerificationConcurrency)
+		if err == nil {
+			verification.compare(local, content)
+		}
+	}
+	if err != nil {
+		verification.Errors = append(verification.Errors, err.Error())
+	}
+
+	switch {
+	case err != nil || verification.LocalDigests == 0:
+		verification.Verdict = UploadUnverified
+	case len(corrupt) > 0 || len(verification.SizeMismatches) > 0:
+		verification.Verdict = UploadCorrupt
+	case verification.MissingCount > 0:
+		verification.Verdict = UploadIncomplete
+	default:
+		verification.Verdict = UploadVerified
+	}
+	verification.Duration = time.Since(startTime)
+	verification.PrintSummary()
+	return verification
+}
+
+// compare matches the local digests against the registry blobs
+func (v *UploadVerification) compare(local map[string]int64, content *registry.ContentDigests) {
+	v.RegistryDigests = len(content.Blobs)
+	v.RegistryTags = content.Tags
+	v.Errors = append(v.Errors, truncateList(content.Errors, maxListedDigests)...)
+
+	digests := make([]string, 0, len(local))
+	for digest := range local {
+		digests = append(digests, digest)
+	}
+	sort.Strings(digests)
+
+	for _, digest := range digests {
+		size, found := content.Blobs[digest]
+		switch {
+		case !found:
+			v.MissingCount++
+			if len(v.Missing) < maxListedDigests {
+				v.Missing = append(v.Missing, digest)
+			}
+		case local[digest] >= 0 && size > 0 && size != local[digest]:
+			if len(v.SizeMismatches) < maxListedDigests {
+				v.SizeMismatches = append(v.SizeMismatches, fmt.Sprintf("%s: %d local, %d in the registry", digest, local[digest], size))
+			}
+		default:
+			v.Matched++
+		}
+	}
+}
+
+// truncateList returns at most maxLen entries of list
+func truncateList(list []string, maxLen int) []string {
+	if len(list) > maxLen {
+		return list[:maxLen]
+	}
+	return list
+}
+
+// PrintSummary prints the verdict and the first findings
+func (v *UploadVerification) PrintSummary() {
+	logging.Printf("  │ ─── Upload Verification ──────────────────────────────────────\n")
+	logging.Printf("  │   Verdict: %s (%d of %d local blobs in the registry, %d registry blobs in %d tags)\n",
+		v.Verdict, v.Matched, v.LocalDigests, v.RegistryDigests, v.RegistryTags)
+	if v.MissingCount > 0 {
+		logging.Printf("  │   ❌ %d blob(s) missing from the registry, e.g. %s\n", v.MissingCount, v.Missing[0])
+	}
+	for _, mismatch := range v.SizeMismatches {
+		logging.Printf("  │   ❌ size mismatch %s\n", mismatch)
+	}
+	for _, path := range v.CorruptLocal {
+		logging.Printf("  │   ❌ local blob does not match its digest: %s\n", path)
+	}
+	if len(v.Errors) > 0 {
+		logging.Printf("  │   Warning: %d error(s), e.g. %s\n", len(v.Errors), truncateText(v.Errors[0], 200))
+	}
+}
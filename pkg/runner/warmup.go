@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// ValidateWarmup checks the number of warm-up iterations
+func (c *Config) ValidateWarmup() error {
+	if c.Warmup < 0 {
+		return fmt.Errorf("warm-up iterations must not be negative")
+	}
+	if c.Warmup > 0 && len(c.Stages) > 0 {
+		return fmt.Errorf("warm-up iterations cannot be combined with stages")
+	}
+	return nil
+}
+
+// runWarmups runs the warm-up iterations of a version before its measured
+// ones. They execute fully, are saved with warmup set and left out of the
+// comparisons, statistics and gates. The first one starts from an empty
+// workspace; a v2 cache that was empty before is emptied again, so the clean
+// iteration measures what it would have without them.
+func (tr *TestRunner) runWarmups(version string) error {
+	if tr.config.Warmup == 0 {
+		return nil
+	}
+	tr.warmup = true
+	defer func() { tr.warmup = false }()
+
+	cacheDir := tr.cacheDir(version)
+	coldCache := cacheDir != "" && isEmptyDir(cacheDir)
+	for i := 0; i < tr.config.Warmup; i++ {
+		logging.Printf("\n── Warm-up %d/%d (%s, not measured) ──\n", i+1, tr.config.Warmup, version)
+		key := tr.iterationKey(i+1, version)
+		if tr.skipCompleted(key) {
+			continue
+		}
+
+		result, err := tr.runIteration(i+1, i == 0, version)
+		if err != nil && !tr.recordFailedIteration(&result, err) {
+			return fmt.Errorf("warm-up %d failed: %w", i+1, err)
+		}
+		result.Passed = !result.Failed
+		tr.publishIteration(result)
+		tr.results = append(tr.results, result)
+		if !result.Failed {
+			tr.printIterationSummary(result)
+		}
+		if err := tr.saveResults(); err != nil {
+			logging.Printf("Warning: Failed to save results incrementally: %v\n", err)
+		}
+		tr.markCompleted(key)
+	}
+
+	if coldCache {
+		if err := os.RemoveAll(cacheDir); err != nil {
+			return fmt.Errorf("failed to clear the cache filled by the warm-up: %w", err)
+		}
+	}
+	return nil
+}
+
+// measuredResults returns the results without the warm-up iterations
+func measuredResults(results []TestResult) []TestResult {
+	measured := make([]TestResult, 0, len(results))
+	for _, r := range results {
+		if !r.Warmup {
+			measured = append(measured, r)
+		}
+	}
+	return measured
+}
+
+// isEmptyDir returns true if dir is missing or has no entries
+func isEmptyDir(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	return (err != nil && os.IsNotExist(err)) || (err == nil && len(entries) == 0)
+}
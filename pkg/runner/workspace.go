@@ -0,0 +1,36 @@
+package runner
+
+import (
+	"path/filepath"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// mirrorDir returns the oc-mirror workspace of a version, under the workspace directory
+func (tr *TestRunner) mirrorDir(version string) string {
+	return filepath.Join(tr.config.GetWorkspaceDir(), "operators-"+version)
+}
+
+// legacyMirrorDir returns the unversioned workspace kept for older layouts
+func (tr *TestRunner) legacyMirrorDir() string {
+	return filepath.Join(tr.config.GetWorkspaceDir(), "operators")
+}
+
+// workspaceURL returns the oc-mirror v2 --workspace of a version
+func (tr *TestRunner) workspaceURL(version string) string {
+	dir := tr.mirrorDir(version)
+	if filepath.IsAbs(dir) {
+		return "file://" + dir + "/"
+	}
+	return "file://./" + dir + "/"
+}
+
+// warnSharedStorage warns when the workspace or cache is on network or
+// memory-backed storage, which skews the phase timings against local disks.
+// The warnings are kept with the environment snapshot of each iteration.
+func (tr *TestRunner) warnSharedStorage() {
+	tr.environment.Warnings = tr.environment.StorageWarnings("workspace", "cache")
+	for _, warning := range tr.environment.Warnings {
+		logging.Printf("Warning: %s\n", warning)
+	}
+}
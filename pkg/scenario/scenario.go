@@ -0,0 +1,488 @@
+package scenario
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/telco-core/ngc-495/internal/config"
+	"github.com/telco-core/ngc-495/pkg/monitor"
+	"github.com/telco-core/ngc-495/pkg/netshape"
+	"github.com/telco-core/ngc-495/pkg/pacing"
+	"github.com/telco-core/ngc-495/pkg/plugin"
+	"github.com/telco-core/ngc-495/pkg/proxy"
+	"github.com/telco-core/ngc-495/pkg/registry"
+	"github.com/telco-core/ngc-495/pkg/runner"
+	"github.com/telco-core/ngc-495/pkg/sink"
+)
+
+// Workflow names
+const (
+	WorkflowStandard    = "standard"
+	WorkflowCompareV1V2 = "compare-v1-v2"
+)
+
+// Scenario is a reproducible test case definition loaded from YAML
+type Scenario struct {
+	Name              string                         `yaml:"name"`
+	Description       string                         `yaml:"description,omitempty"`
+	Tags              map[string]string              `yaml:"tags,omitempty"`  // Tags of the runs, see --tag
+	Notes             []string                       `yaml:"notes,omitempty"` // Notes of the runs, see --note
+	Registry          string                         `yaml:"registry"`
+	CompareRegistries []string                       `yaml:"compareRegistries,omitempty"` // Registries receiving the same content for comparison
+	RegistryOrder     string                         `yaml:"registryOrder,omitempty"`     // sequential or round-robin
+	Iterations        int                            `yaml:"iterations,omitempty"`
+	Warmup            int                            `yaml:"warmup,omitempty"` // Warm-up iterations before the measured ones, see --warmup
+	Workflow          string                         `yaml:"workflow,omitempty"`
+	LocalRegistry     registry.LocalRegistryConfig   `yaml:"localRegistry,omitempty"` // Disposable registry container used as the target, see --local-registry
+	SkipTLS           bool                           `yaml:"skipTLS,omitempty"`
+	ContentScenario   string                         `yaml:"contentScenario,omitempty"`  // Built-in content (operators, additional-images, helm, mixed)
+	Content           *config.ContentSpec            `yaml:"content,omitempty"`          // Explicit content, overrides contentScenario
+	UpdateContent     *config.ContentSpec            `yaml:"updateContent,omitempty"`    // Day-2 update content, mirrored from the second iteration on
+	Stages            []runner.StageConfig           `yaml:"stages,omitempty"`           // Priority-ordered chunks of the content, mirrored one after another
+	Flags             []string                       `yaml:"flags,omitempty"`            // Additional oc-mirror arguments
+	BinDir            string                         `yaml:"binDir,omitempty"`           // Directory the client tools are run from and downloaded into
+	ToolsVersion      string                         `yaml:"toolsVersion,omitempty"`     // OpenShift version the missing client tools are downloaded for
+	ToolsFromDir      string                         `yaml:"toolsFromDir,omitempty"`     // Staged tarballs the missing client tools are installed from, see --tools-from-dir
+	RetryFailed       int                            `yaml:"retryFailed,omitempty"`      // Retries per failed phase, see --retry-failed
+	DownloadTimeout   time.Duration                  `yaml:"downloadTimeout,omitempty"`  // oc-mirror is killed after it, see --download-timeout
+	UploadTimeout     time.Duration                  `yaml:"uploadTimeout,omitempty"`    // oc-mirror is killed after it, see --upload-timeout
+	WorkspaceCleanup  []string                       `yaml:"workspaceCleanup,omitempty"` // Cleanup strategy of each iteration, see --workspace-cleanup
+	RepeatClean       bool                           `yaml:"repeatClean,omitempty"`      // Run every iteration clean, from an empty workspace and cache
+	SampleStorage     string                         `yaml:"sampleStorage,omitempty"`    // inline, delta or delta-gzip, see --sample-storage
+	Network           netshape.Config                `yaml:"network,omitempty"`
+	Pacing            pacing.Config                  `yaml:"pacing,omitempty"`            // Upload pacing for shared registries
+	Delete            runner.DeleteConfig            `yaml:"delete,omitempty"`            // Delete phase after the iterations
+	AirGap            runner.AirGapConfig            `yaml:"airGap,omitempty"`            // Mirror to an archive, transfer it and mirror from it
+	Replication       runner.ReplicationConfig       `yaml:"replication,omitempty"`       // Registries the upload is replicated to, chain or hub-spoke
+	MemoryCeiling     monitor.MemoryCeilingConfig    `yaml:"memoryCeiling,omitempty"`     // Host memory budget and OOM-risk warnings
+	RegistryMetrics   monitor.RegistryServerConfig   `yaml:"registryMetrics,omitempty"`   // Prometheus endpoint of the registry scraped during uploads
+	AdaptivePolling   monitor.AdaptivePolling        `yaml:"adaptivePolling,omitempty"`   // Poll interval back-off on long phases
+	Cleanup           runner.CleanupPolicy           `yaml:"cleanup,omitempty"`           // Pruning of earlier runs before the run
+	DiskSpace         runner.DiskSpaceCheck          `yaml:"diskSpace,omitempty"`         // Free space check before the run; budget.maxDiskGB is the default estimate
+	Signatures        runner.SignatureConfig         `yaml:"signatures,omitempty"`        // cosign signature scan after each upload
+	ValidateContent   bool                           `yaml:"validateContent,omitempty"`   // Check the registry for the images of the operator packages rendered with opm
+	VerifyUpload      bool                           `yaml:"verifyUpload,omitempty"`      // Compare the local blob digests with the registry after each upload
+	PackageSizes      bool                           `yaml:"packageSizes,omitempty"`      // Attribute the mirrored bytes to the operator packages after each upload
+	AccountingProxy   bool                           `yaml:"accountingProxy,omitempty"`   // Push uploads through a local proxy counting the exact bytes and API calls
+	RateLimit         proxy.RateLimit                `yaml:"rateLimit,omitempty"`         // Simulated registry rate limiting in the accounting proxy
+	Chaos             runner.ChaosConfig             `yaml:"chaos,omitempty"`             // Kill oc-mirror during clean downloads and measure the resume
+	Proxy             runner.ProxyConfig             `yaml:"proxy,omitempty"`             // HTTP(S) proxy for oc-mirror, optionally compared with direct
+	Matrix            runner.MatrixConfig            `yaml:"matrix,omitempty"`            // Versions, references, workflows, cache states and concurrencies whose combinations each run the iterations
+	ComparePinning    bool                           `yaml:"comparePinning,omitempty"`    // Mirror the content by tag and pinned to digests
+	LogRetention      runner.LogRetention            `yaml:"logRetention,omitempty"`      // oc-mirror output lines kept in the results besides the log file
+	ResultsStore      runner.StoreConfig             `yaml:"resultsStore,omitempty"`      // Upload of the results when the run ends
+	Gates             runner.GateConfig              `yaml:"gates,omitempty"`             // Pass/fail gates checked as each iteration completes
+	ClusterValidation runner.ClusterValidationConfig `yaml:"clusterValidation,omitempty"` // Apply and pull from the mirror on a test cluster, with --kubeconfig
+	PullThrough       runner.PullThroughConfig       `yaml:"pullThrough,omitempty"`       // Serve the mirrored catalogs on the cluster after each upload, with --kubeconfig
+	Thresholds        []Threshold                    `yaml:"thresholds,omitempty"`
+	Budget            Budget                         `yaml:"budget,omitempty"`  // Resource envelope, e.g. of a far-edge host
+	Plugins           plugin.Config                  `yaml:"plugins,omitempty"` // Site-specific collectors and event consumers
+	Sinks             []string                       `yaml:"sinks,omitempty"`   // Metric sinks the results and samples are sent to, see --sink
+
+	hash string // sha256 of the scenario file
+}
+
+// Budget actions on a violation
+const (
+	BudgetFail = "fail" // Fail the run (default)
+	BudgetFlag = "flag" // Report the violations without failing the run
+)
+
+// Budget is the resource envelope oc-mirror must fit in, checked against the
+// peaks of every iteration, failed ones included
+type Budget struct {
+	MaxCPUPercent float64 `yaml:"maxCPUPercent,omitempty"` // Peak oc-mirror CPU, 100 per core
+	MaxMemoryMB   float64 `yaml:"maxMemoryMB,omitempty"`   // Peak oc-mirror RSS
+	MaxDiskGB     float64 `yaml:"maxDiskGB,omitempty"`     // Workspace, cache and OCI layout size, in GiB
+	Action        string  `yaml:"action,omitempty"`        // fail or flag
+}
+
+// Threshold is an expected limit checked against matching iterations after the run
+type Threshold struct {
+	Version          string        `yaml:"version,omitempty"` // v1, v2 or empty for any
+	Run              string        `yaml:"run,omitempty"`     // clean, cached, update or empty for any
+	Stage            string        `yaml:"stage,omitempty"`   // Stage name, in a priority-ordered run
+	MaxWallTime      time.Duration `yaml:"maxWallTime,omitempty"`
+	MaxDownloadTime  time.Duration `yaml:"maxDownloadTime,omitempty"`
+	MaxUploadTime    time.Duration `yaml:"maxUploadTime,omitempty"`
+	MaxCPUPercent    float64       `yaml:"maxCPUPercent,omitempty"`
+	MaxMemoryMB      float64       `yaml:"maxMemoryMB,omitempty"`
+	MinCacheHits     int           `yaml:"minCacheHits,omitempty"`
+	MinCacheHitRatio float64       `yaml:"minCacheHitRatio,omitempty"` // 0-1, inferred from the v2 cache growth against the clean run
+	MaxTimeToContent time.Duration `yaml:"maxTimeToContent,omitempty"` // From the start of the iteration to the end of the stage
+}
+
+// Violation is a threshold that an iteration did not meet
+type Violation struct {
+	Iteration int
+	Version   string
+	Stage     string // Set in a priority-ordered run
+	Metric    string
+	Limit     string
+	Actual    string
+}
+
+// String returns a human-readable description of the violation
+func (v Violation) String() string {
+	if v.Stage != "" {
+		return fmt.Sprintf("iteration %d (%s, stage %s): %s is %s (limit %s)", v.Iteration, v.Version, v.Stage, v.Metric, v.Actual, v.Limit)
+	}
+	return fmt.Sprintf("iteration %d (%s): %s is %s (limit %s)", v.Iteration, v.Version, v.Metric, v.Actual, v.Limit)
+}
+
+// Load reads and validates a scenario file
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	sc := &Scenario{hash: hex.EncodeToString(sum[:])}
+	if err := yaml.Unmarshal(data, sc); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario file %s: %w", path, err)
+	}
+	if sc.Name == "" {
+		sc.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", sc.Name, err)
+	}
+	return sc, nil
+}
+
+// Hash returns the sha256 of the scenario file, so results can be traced to
+// the exact definition they were created from
+func (s *Scenario) Hash() string {
+	return s.hash
+}
+
+// Validate checks the scenario for unsupported values
+func (s *Scenario) Validate() error {
+	switch s.Workflow {
+	case "", WorkflowStandard, WorkflowCompareV1V2:
+	default:
+		return fmt.Errorf("unknown workflow %q (valid: %s, %s)", s.Workflow, WorkflowStandard, WorkflowCompareV1V2)
+	}
+	if s.Iterations < 0 {
+		return fmt.Errorf("iterations must not be negative")
+	}
+	if s.Warmup < 0 {
+		return fmt.Errorf("warmup must not be negative")
+	}
+	if s.RetryFailed < 0 {
+		return fmt.Errorf("retryFailed must not be negative")
+	}
+	if s.DownloadTimeout < 0 || s.UploadTimeout < 0 {
+		return fmt.Errorf("downloadTimeout and uploadTimeout must not be negative")
+	}
+	for _, name := range s.WorkspaceCleanup {
+		switch name {
+		case runner.CleanupKeep, runner.CleanupMirror, runner.CleanupCache, runner.CleanupRegistry:
+		default:
+			return fmt.Errorf("workspaceCleanup: unknown strategy %q (valid: %s, %s, %s, %s)", name, runner.CleanupKeep, runner.CleanupMirror, runner.CleanupCache, runner.CleanupRegistry)
+		}
+	}
+	if len(s.WorkspaceCleanup) > 0 && len(s.Matrix.CacheStates) > 0 {
+		return fmt.Errorf("workspaceCleanup cannot be combined with matrix cacheStates")
+	}
+	if s.RepeatClean && (len(s.WorkspaceCleanup) > 0 || len(s.Matrix.CacheStates) > 0) {
+		return fmt.Errorf("repeatClean cannot be combined with workspaceCleanup or matrix cacheStates")
+	}
+	switch s.SampleStorage {
+	case "", runner.SampleStorageInline, runner.SampleStorageDelta, runner.SampleStorageDeltaGzip:
+	default:
+		return fmt.Errorf("unknown sampleStorage %q (valid: %s, %s, %s)", s.SampleStorage, runner.SampleStorageInline, runner.SampleStorageDelta, runner.SampleStorageDeltaGzip)
+	}
+	if s.Content != nil {
+		if err := s.Content.Validate(); err != nil {
+			return fmt.Errorf("content: %w", err)
+		}
+	} else if _, err := config.ContentForScenario(s.ContentScenario); err != nil {
+		return err
+	}
+	if s.UpdateContent != nil {
+		if err := s.UpdateContent.Validate(); err != nil {
+			return fmt.Errorf("updateContent: %w", err)
+		}
+		if s.Iterations == 1 {
+			return fmt.Errorf("updateContent requires at least 2 iterations")
+		}
+		if len(s.Stages) > 0 {
+			return fmt.Errorf("updateContent cannot be combined with stages")
+		}
+	}
+	for i, stage := range s.Stages {
+		if stage.Name == "" {
+			return fmt.Errorf("stage %d requires a name", i+1)
+		}
+		if stage.IsEmpty() {
+			return fmt.Errorf("stage %s selects no packages, images or charts", stage.Name)
+		}
+	}
+	if err := s.Network.Validate(); err != nil {
+		return fmt.Errorf("network: %w", err)
+	}
+	if err := s.Pacing.Validate(); err != nil {
+		return fmt.Errorf("pacing: %w", err)
+	}
+	if err := s.MemoryCeiling.Validate(); err != nil {
+		return fmt.Errorf("memoryCeiling: %w", err)
+	}
+	if err := s.AdaptivePolling.Validate(); err != nil {
+		return fmt.Errorf("adaptivePolling: %w", err)
+	}
+	if s.LocalRegistry.Enabled && s.Registry != "" {
+		return fmt.Errorf("localRegistry replaces registry; set only one")
+	}
+	if err := s.LocalRegistry.Validate(); err != nil {
+		return fmt.Errorf("localRegistry: %w", err)
+	}
+	if err := s.RateLimit.Validate(); err != nil {
+		return fmt.Errorf("rateLimit: %w", err)
+	}
+	if err := s.Cleanup.Validate(); err != nil {
+		return fmt.Errorf("cleanup: %w", err)
+	}
+	if err := s.DiskSpace.Validate(); err != nil {
+		return fmt.Errorf("diskSpace: %w", err)
+	}
+	if err := s.ResultsStore.Validate(); err != nil {
+		return fmt.Errorf("resultsStore: %w", err)
+	}
+	if err := s.Budget.Validate(); err != nil {
+		return fmt.Errorf("budget: %w", err)
+	}
+	if err := s.Gates.Validate(); err != nil {
+		return fmt.Errorf("gates: %w", err)
+	}
+	if err := s.Chaos.Validate(); err != nil {
+		return fmt.Errorf("chaos: %w", err)
+	}
+	if err := s.Proxy.Validate(); err != nil {
+		return fmt.Errorf("proxy: %w", err)
+	}
+	if err := s.Matrix.Validate(); err != nil {
+		return fmt.Errorf("matrix: %w", err)
+	}
+	if s.ComparePinning && len(s.Matrix.References) > 0 {
+		return fmt.Errorf("comparePinning cannot be combined with matrix references")
+	}
+	if err := s.LogRetention.Validate(); err != nil {
+		return fmt.Errorf("logRetention: %w", err)
+	}
+	if s.AirGap.TransferRateMBs < 0 {
+		return fmt.Errorf("airGap: transfer rate must not be negative")
+	}
+	switch s.Replication.Topology {
+	case "", runner.TopologyChain, runner.TopologyHubSpoke:
+	default:
+		return fmt.Errorf("replication: topology must be %s or %s, got %q", runner.TopologyChain, runner.TopologyHubSpoke, s.Replication.Topology)
+	}
+	if s.ClusterValidation.Timeout < 0 {
+		return fmt.Errorf("clusterValidation: timeout must not be negative")
+	}
+	if s.PullThrough.Timeout < 0 {
+		return fmt.Errorf("pullThrough: timeout must not be negative")
+	}
+	for _, value := range s.Sinks {
+		if _, err := sink.ParseSpec(value); err != nil {
+			return fmt.Errorf("sinks: %w", err)
+		}
+	}
+	for i, t := range s.Thresholds {
+		switch t.Run {
+		case "", "clean", "cached", "update":
+		default:
+			return fmt.Errorf("threshold %d: run must be clean, cached or update, got %q", i+1, t.Run)
+		}
+	}
+	return nil
+}
+
+// ResolveContent returns the content the scenario mirrors first and its label
+func (s *Scenario) ResolveContent() (*config.ContentSpec, string, error) {
+	if s.Content != nil {
+		return s.Content, "custom", nil
+	}
+	content, err := config.ContentForScenario(s.ContentScenario)
+	if err != nil {
+		return nil, "", err
+	}
+	name := s.ContentScenario
+	if name == "" {
+		name = config.ContentOperators
+	}
+	return content, name, nil
+}
+
+// Evaluate checks the thresholds against the run results
+func (s *Scenario) Evaluate(results []runner.TestResult) []Violation {
+	var violations []Violation
+	for _, t := range s.Thresholds {
+		for i := range results {
+			result := &results[i]
+			if !t.matches(result) {
+				continue
+			}
+			violations = append(violations, t.check(result)...)
+		}
+	}
+	return violations
+}
+
+func (t Threshold) matches(result *runner.TestResult) bool {
+	if t.Version != "" && t.Version != result.Version {
+		return false
+	}
+	if t.Stage != "" && (result.Stage == nil || t.Stage != result.Stage.Name) {
+		return false
+	}
+	switch t.Run {
+	case "clean":
+		return result.IsCleanRun
+	case "cached":
+		return !result.IsCleanRun && !result.IsUpdateRun
+	case "update":
+		return result.IsUpdateRun
+	}
+	return true
+}
+
+func (t Threshold) check(result *runner.TestResult) []Violation {
+	var violations []Violation
+	add := func(metric, limit, actual string) {
+		v := Violation{
+			Iteration: result.Iteration,
+			Version:   result.Version,
+			Metric:    metric,
+			Limit:     limit,
+			Actual:    actual,
+		}
+		if result.Stage != nil {
+			v.Stage = result.Stage.Name
+		}
+		violations = append(violations, v)
+	}
+
+	if t.MaxWallTime > 0 && result.GetTotalTime() > t.MaxWallTime {
+		add("wall time", t.MaxWallTime.String(), result.GetTotalTime().Round(time.Second).String())
+	}
+	if t.MaxDownloadTime > 0 && result.DownloadPhase.WallTime > t.MaxDownloadTime {
+		add("download time", t.MaxDownloadTime.String(), result.DownloadPhase.WallTime.Round(time.Second).String())
+	}
+	if t.MaxUploadTime > 0 && result.UploadPhase.WallTime > t.MaxUploadTime {
+		add("upload time", t.MaxUploadTime.String(), result.UploadPhase.WallTime.Round(time.Second).String())
+	}
+
+	cpu := max(result.DownloadPhase.ResourceMetrics.CPUAvgPercent, result.UploadPhase.ResourceMetrics.CPUAvgPercent)
+	if t.MaxCPUPercent > 0 && cpu > t.MaxCPUPercent {
+		add("average CPU", fmt.Sprintf("%.1f%%", t.MaxCPUPercent), fmt.Sprintf("%.1f%%", cpu))
+	}
+
+	memory := max(result.DownloadPhase.ResourceMetrics.MemoryPeakMB, result.UploadPhase.ResourceMetrics.MemoryPeakMB)
+	if t.MaxMemoryMB > 0 && memory > t.MaxMemoryMB {
+		add("peak memory", fmt.Sprintf("%.0f MB", t.MaxMemoryMB), fmt.Sprintf("%.0f MB", memory))
+	}
+
+	if t.MaxTimeToContent > 0 {
+		if result.Stage == nil {
+			add("time to content", t.MaxTimeToContent.String(), "not staged")
+		} else if result.Stage.TimeToContent > t.MaxTimeToContent {
+			add("time to content", t.MaxTimeToContent.String(), result.Stage.TimeToContent.Round(time.Second).String())
+		}
+	}
+
+	if t.MinCacheHits > 0 && result.DownloadPhase.CacheHits < t.MinCacheHits {
+		add("cache hits", fmt.Sprintf(">= %d", t.MinCacheHits), fmt.Sprintf("%d", result.DownloadPhase.CacheHits))
+	}
+
+	if t.MinCacheHitRatio > 0 {
+		if m := result.DownloadPhase.CacheMetrics; m == nil || m.HitRatio == nil {
+			add("cache hit ratio", fmt.Sprintf(">= %.0f%%", t.MinCacheHitRatio*100), "not measured")
+		} else if *m.HitRatio < t.MinCacheHitRatio {
+			add("cache hit ratio", fmt.Sprintf(">= %.0f%%", t.MinCacheHitRatio*100), fmt.Sprintf("%.1f%%", *m.HitRatio*100))
+		}
+	}
+
+	return violations
+}
+
+// Enabled reports whether any budget limit is set
+func (b Budget) Enabled() bool {
+	return b.MaxCPUPercent > 0 || b.MaxMemoryMB > 0 || b.MaxDiskGB > 0
+}
+
+// Validate checks the limits and the action
+func (b Budget) Validate() error {
+	if b.MaxCPUPercent < 0 || b.MaxMemoryMB < 0 || b.MaxDiskGB < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	switch b.Action {
+	case "", BudgetFail, BudgetFlag:
+	default:
+		return fmt.Errorf("action must be %s or %s, got %q", BudgetFail, BudgetFlag, b.Action)
+	}
+	return nil
+}
+
+// GetAction returns the action on a violation, defaulting to fail
+func (b Budget) GetAction() string {
+	if b.Action == "" {
+		return BudgetFail
+	}
+	return b.Action
+}
+
+// String returns a human-readable description of the limits
+func (b Budget) String() string {
+	var limits []string
+	if b.MaxCPUPercent > 0 {
+		limits = append(limits, fmt.Sprintf("CPU %.0f%%", b.MaxCPUPercent))
+	}
+	if b.MaxMemoryMB > 0 {
+		limits = append(limits, fmt.Sprintf("memory %.0f MB", b.MaxMemoryMB))
+	}
+	if b.MaxDiskGB > 0 {
+		limits = append(limits, fmt.Sprintf("disk %.1f GiB", b.MaxDiskGB))
+	}
+	return strings.Join(limits, ", ") + " (" + b.GetAction() + " on violation)"
+}
+
+// Evaluate checks the measured peaks of every iteration against the budget
+func (b Budget) Evaluate(results []runner.TestResult) []Violation {
+	var violations []Violation
+	for i := range results {
+		result := &results[i]
+		add := func(metric, limit, actual string) {
+			violations = append(violations, Violation{
+				Iteration: result.Iteration,
+				Version:   result.Version,
+				Metric:    metric,
+				Limit:     limit,
+				Actual:    actual,
+			})
+		}
+
+		cpu := max(result.DownloadPhase.ResourceMetrics.CPUPeakPercent, result.UploadPhase.ResourceMetrics.CPUPeakPercent)
+		if b.MaxCPUPercent > 0 && cpu > b.MaxCPUPercent {
+			add("peak CPU", fmt.Sprintf("%.0f%%", b.MaxCPUPercent), fmt.Sprintf("%.1f%%", cpu))
+		}
+		memory := max(result.DownloadPhase.ResourceMetrics.MemoryPeakMB, result.UploadPhase.ResourceMetrics.MemoryPeakMB)
+		if b.MaxMemoryMB > 0 && memory > b.MaxMemoryMB {
+			add("peak memory", fmt.Sprintf("%.0f MB", b.MaxMemoryMB), fmt.Sprintf("%.0f MB", memory))
+		}
+		disk := float64(result.DiskUsageBytes) / (1 << 30)
+		if b.MaxDiskGB > 0 && disk > b.MaxDiskGB {
+			add("disk usage", fmt.Sprintf("%.1f GiB", b.MaxDiskGB), fmt.Sprintf("%.1f GiB", disk))
+		}
+	}
+	return violations
+}
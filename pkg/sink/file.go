@@ -0,0 +1,130 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// jsonSink keeps the results file format: the iterations finished so far as
+// one JSON array, rewritten after each iteration. Samples are already part
+// of the results, so they are not written separately.
+type jsonSink struct {
+	spec    Spec
+	results []runner.TestResult
+}
+
+func newJSONSink(spec Spec) *jsonSink {
+	return &jsonSink{spec: spec, results: []runner.TestResult{}}
+}
+
+func (s *jsonSink) Name() string {
+	return s.spec.String()
+}
+
+func (s *jsonSink) Write(result runner.TestResult) error {
+	s.results = append(s.results, result)
+	data, err := json.MarshalIndent(s.results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.spec.Target, data)
+}
+
+func (s *jsonSink) WriteSample(source string, at time.Time, sample interface{}) error {
+	return nil
+}
+
+func (s *jsonSink) Close() error {
+	return nil
+}
+
+// writeFileAtomic replaces path with data, so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create sink directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Record types of the NDJSON sink
+const (
+	recordSample = "sample"
+	recordResult = "result"
+)
+
+// ndjsonRecord is one line of the NDJSON sink
+type ndjsonRecord struct {
+	Type   string      `json:"type"`
+	Source string      `json:"source,omitempty"` // Monitor of a sample
+	Time   time.Time   `json:"time"`
+	Data   interface{} `json:"data"`
+}
+
+// ndjsonSink writes each sample and iteration as one JSON line, to stdout or
+// a file. Lines are flushed as they are written, so readers can follow them.
+type ndjsonSink struct {
+	spec   Spec
+	file   *os.File // nil for stdout
+	writer *bufio.Writer
+}
+
+func newNDJSONSink(spec Spec) (*ndjsonSink, error) {
+	s := &ndjsonSink{spec: spec}
+	var out io.Writer = os.Stdout
+	if spec.Target != "" {
+		if err := os.MkdirAll(filepath.Dir(spec.Target), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create sink directory: %w", err)
+		}
+		file, err := os.Create(spec.Target)
+		if err != nil {
+			return nil, err
+		}
+		s.file, out = file, file
+	}
+	s.writer = bufio.NewWriter(out)
+	return s, nil
+}
+
+func (s *ndjsonSink) Name() string {
+	return s.spec.String()
+}
+
+func (s *ndjsonSink) Write(result runner.TestResult) error {
+	return s.write(ndjsonRecord{Type: recordResult, Time: time.Now(), Data: result})
+}
+
+func (s *ndjsonSink) WriteSample(source string, at time.Time, sample interface{}) error {
+	return s.write(ndjsonRecord{Type: recordSample, Source: source, Time: at, Data: sample})
+}
+
+func (s *ndjsonSink) write(record ndjsonRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := s.writer.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return s.writer.Flush()
+}
+
+func (s *ndjsonSink) Close() error {
+	err := s.writer.Flush()
+	if s.file != nil {
+		if closeErr := s.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,162 @@
+package sink
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// Measurements of the InfluxDB sink
+const (
+	influxIterations = "oc_mirror_test_iteration" // Tagged run, version, kind, registry and stage
+	influxSamples    = "oc_mirror_test_sample"    // Tagged run and source
+)
+
+// Batching of the InfluxDB sink: lines are sent when this many are buffered,
+// when influxFlushInterval passed since the last write, after each iteration and
+// when the run ends
+const (
+	influxBatchLines    = 1000
+	influxFlushInterval = 10 * time.Second
+)
+
+// influxSink writes InfluxDB line protocol, POSTed to a write endpoint such
+// as http://influx:8086/api/v2/write?org=lab&bucket=oc-mirror, or appended
+// to a file for a later import
+type influxSink struct {
+	spec      Spec
+	run       string
+	url       string   // Write endpoint; empty when writing a file
+	file      *os.File // Line protocol file; nil when posting
+	token     string
+	client    *http.Client
+	lines     []string
+	lastFlush time.Time
+}
+
+func newInfluxSink(spec Spec, opts Options) (*influxSink, error) {
+	s := &influxSink{spec: spec, run: opts.Run, lastFlush: time.Now()}
+	if strings.HasPrefix(spec.Target, "http://") || strings.HasPrefix(spec.Target, "https://") {
+		s.url = spec.Target
+		s.token = os.Getenv(EnvInfluxToken)
+		s.client = &http.Client{Timeout: 15 * time.Second}
+		return s, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(spec.Target), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create sink directory: %w", err)
+	}
+	file, err := os.OpenFile(spec.Target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+	return s, nil
+}
+
+func (s *influxSink) Name() string {
+	return s.spec.String()
+}
+
+func (s *influxSink) Write(result runner.TestResult) error {
+	tags := resultLabels(result)
+	tags["run"] = s.run
+	s.lines = append(s.lines, lineProtocol(influxIterations, tags, resultFields(result), time.Now()))
+	return s.flush()
+}
+
+func (s *influxSink) WriteSample(source string, at time.Time, sample interface{}) error {
+	fields := sampleFields(sample)
+	if len(fields) == 0 {
+		return nil
+	}
+	s.lines = append(s.lines, lineProtocol(influxSamples, map[string]string{"run": s.run, "source": source}, fields, at))
+	if len(s.lines) < influxBatchLines && time.Since(s.lastFlush) < influxFlushInterval {
+		return nil
+	}
+	return s.flush()
+}
+
+func (s *influxSink) Close() error {
+	err := s.flush()
+	if s.file != nil {
+		if closeErr := s.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// flush sends the buffered lines. They are dropped when the write fails, so
+// an unreachable database does not grow the buffer for the rest of the run.
+func (s *influxSink) flush() error {
+	s.lastFlush = time.Now()
+	if len(s.lines) == 0 {
+		return nil
+	}
+	body := []byte(strings.Join(s.lines, "\n") + "\n")
+	s.lines = s.lines[:0]
+	if s.file != nil {
+		_, err := s.file.Write(body)
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Token "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to write to InfluxDB: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to write to InfluxDB: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// lineProtocol formats one point, e.g.
+// oc_mirror_test_sample,run=r1,source=download download_rate_mb=87.3 1700000000000000000
+func lineProtocol(measurement string, tags map[string]string, fields map[string]float64, at time.Time) string {
+	var b strings.Builder
+	b.WriteString(influxEscape(measurement, ", "))
+	for _, key := range sortedKeys(tags) {
+		if tags[key] == "" {
+			continue // Empty tag values are not allowed
+		}
+		b.WriteString("," + influxEscape(key, ",= ") + "=" + influxEscape(tags[key], ",= "))
+	}
+	for i, key := range sortedKeys(fields) {
+		separator := ","
+		if i == 0 {
+			separator = " "
+		}
+		b.WriteString(separator + influxEscape(key, ",= ") + "=" + formatValue(fields[key]))
+	}
+	fmt.Fprintf(&b, " %d", at.UnixNano())
+	return b.String()
+}
+
+// influxEscape escapes the characters special in a line protocol element
+func influxEscape(value, special string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
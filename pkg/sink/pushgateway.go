@@ -0,0 +1,180 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// Metric names and grouping of the Pushgateway sink
+const (
+	pushJob          = "oc_mirror_test"
+	iterationMetrics = "oc_mirror_test_iteration_" // Followed by the result field
+	sampleMetrics    = "oc_mirror_test_sample_"    // Followed by the sample field, labelled by source
+)
+
+// pushInterval bounds how often samples are pushed; a push replaces the
+// previous values of its group, so only the latest sample of each source
+// is kept between pushes
+const pushInterval = 10 * time.Second
+
+// pushgatewaySink pushes gauges to a Prometheus Pushgateway: one group per
+// iteration, job/oc_mirror_test/run/<run>/iteration/<n>, and one for the
+// latest samples, job/oc_mirror_test/run/<run>
+type pushgatewaySink struct {
+	spec     Spec
+	url      string
+	run      string
+	client   *http.Client
+	latest   map[string]map[string]float64 // Latest sample fields, by source
+	pending  bool                          // Samples arrived since the last push
+	lastPush time.Time
+}
+
+func newPushgatewaySink(spec Spec, opts Options) (*pushgatewaySink, error) {
+	if !strings.HasPrefix(spec.Target, "http://") && !strings.HasPrefix(spec.Target, "https://") {
+		return nil, fmt.Errorf("pushgateway needs an http(s) URL, e.g. pushgateway:http://pushgateway:9091")
+	}
+	run := opts.Run
+	if run == "" {
+		run = "unnamed"
+	}
+	return &pushgatewaySink{
+		spec:   spec,
+		url:    strings.TrimSuffix(spec.Target, "/"),
+		run:    run,
+		client: &http.Client{Timeout: 15 * time.Second},
+		latest: make(map[string]map[string]float64),
+	}, nil
+}
+
+func (s *pushgatewaySink) Name() string {
+	return s.spec.String()
+}
+
+func (s *pushgatewaySink) Write(result runner.TestResult) error {
+	fields := resultFields(result)
+	labels := formatLabels(resultLabels(result))
+	var body bytes.Buffer
+	for _, field := range resultFieldNames {
+		name := iterationMetrics + field
+		fmt.Fprintf(&body, "# TYPE %s gauge\n%s%s %s\n", name, name, labels, formatValue(fields[field]))
+	}
+	return s.push(strconv.Itoa(result.Iteration), body.Bytes())
+}
+
+func (s *pushgatewaySink) WriteSample(source string, at time.Time, sample interface{}) error {
+	fields := sampleFields(sample)
+	if len(fields) == 0 {
+		return nil
+	}
+	s.latest[source] = fields
+	s.pending = true
+	if time.Since(s.lastPush) < pushInterval {
+		return nil
+	}
+	return s.pushSamples()
+}
+
+func (s *pushgatewaySink) Close() error {
+	if !s.pending {
+		return nil
+	}
+	return s.pushSamples()
+}
+
+// pushSamples replaces the sample group with the latest sample of each
+// source, one metric family per field
+func (s *pushgatewaySink) pushSamples() error {
+	s.lastPush, s.pending = time.Now(), false
+	families := make(map[string][]string)
+	for source, fields := range s.latest {
+		for field, value := range fields {
+			name := sampleMetrics + metricName(field)
+			families[name] = append(families[name],
+				fmt.Sprintf("%s%s %s", name, formatLabels(map[string]string{"source": source}), formatValue(value)))
+		}
+	}
+	var body bytes.Buffer
+	for _, name := range sortedKeys(families) {
+		lines := families[name]
+		sort.Strings(lines)
+		fmt.Fprintf(&body, "# TYPE %s gauge\n%s\n", name, strings.Join(lines, "\n"))
+	}
+	return s.push("", body.Bytes())
+}
+
+// push replaces the metrics of the run group, or of one of its iterations
+func (s *pushgatewaySink) push(iteration string, body []byte) error {
+	target := s.url + "/metrics/job/" + pushJob + groupLabel("run", s.run)
+	if iteration != "" {
+		target += groupLabel("iteration", iteration)
+	}
+	req, err := http.NewRequest(http.MethodPut, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to push metrics: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// groupLabel returns a grouping key path element, base64-encoded when the
+// value cannot be a path segment
+func groupLabel(name, value string) string {
+	if value == "" || strings.Contains(value, "/") {
+		return "/" + name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return "/" + name + "/" + value
+}
+
+// formatLabels returns {name="value",...} in name order
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var parts []string
+	for _, name := range sortedKeys(labels) {
+		parts = append(parts, name+"="+strconv.Quote(labels[name]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// metricName keeps the characters Prometheus allows in metric names
+func metricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,281 @@
+// Package sink sends the results and monitor samples of a run to metric
+// stores while it executes: a JSON file, NDJSON on stdout or in a file, a
+// Prometheus Pushgateway or InfluxDB line protocol.
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/events"
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// Sink kinds, the part of a spec before the first ":"
+const (
+	KindJSON        = "json"        // json:<path>
+	KindNDJSON      = "ndjson"      // ndjson or ndjson:<path>
+	KindPushgateway = "pushgateway" // pushgateway:<url>
+	KindInflux      = "influx"      // influx:<write-url> or influx:<path>
+)
+
+// EnvInfluxToken is the token sent to InfluxDB as "Authorization: Token <token>"
+const EnvInfluxToken = "OC_MIRROR_TEST_INFLUX_TOKEN"
+
+// sampleBuffer is how many samples the sinks may fall behind the run before
+// they miss some
+const sampleBuffer = 4096
+
+// MetricSink receives the results and monitor samples of a run. Calls are
+// serialized by the Dispatcher.
+type MetricSink interface {
+	Name() string                                                      // Spec the sink was opened from
+	Write(result runner.TestResult) error                              // A finished iteration
+	WriteSample(source string, at time.Time, sample interface{}) error // A monitor sample, e.g. of "download"
+	Close() error                                                      // Sends what is buffered
+}
+
+// Options are the settings shared by the sinks of a run
+type Options struct {
+	Run string // Run label of the records, e.g. the results file name without extension
+}
+
+// Spec is a parsed --sink value
+type Spec struct {
+	Kind   string
+	Target string // Path or URL; empty for ndjson on stdout
+}
+
+// ParseSpec parses kind[:target]
+func ParseSpec(value string) (Spec, error) {
+	kind, target, _ := strings.Cut(value, ":")
+	spec := Spec{Kind: kind, Target: target}
+	switch kind {
+	case KindNDJSON:
+	case KindJSON, KindPushgateway, KindInflux:
+		if target == "" {
+			return Spec{}, fmt.Errorf("invalid sink %q: %s needs a target, e.g. %s:<path>", value, kind, kind)
+		}
+	default:
+		return Spec{}, fmt.Errorf("invalid sink %q: unknown kind %q (valid: %s, %s, %s, %s)",
+			value, kind, KindJSON, KindNDJSON, KindPushgateway, KindInflux)
+	}
+	return spec, nil
+}
+
+// String returns the spec as given on the command line
+func (s Spec) String() string {
+	if s.Target == "" {
+		return s.Kind
+	}
+	return s.Kind + ":" + s.Target
+}
+
+// Open creates the sink of a spec
+func Open(spec Spec, opts Options) (MetricSink, error) {
+	switch spec.Kind {
+	case KindJSON:
+		return newJSONSink(spec), nil
+	case KindNDJSON:
+		return newNDJSONSink(spec)
+	case KindPushgateway:
+		return newPushgatewaySink(spec, opts)
+	case KindInflux:
+		return newInfluxSink(spec, opts)
+	}
+	return nil, fmt.Errorf("unknown sink kind %q", spec.Kind)
+}
+
+// Dispatcher feeds the sinks of a run: the samples published on its event
+// bus and each finished iteration. A failing sink is reported once and keeps
+// receiving, so a store that comes back gets the rest of the run.
+type Dispatcher struct {
+	sinks       []MetricSink
+	events      <-chan events.Event
+	unsubscribe func()
+	done        chan struct{}
+
+	mu     sync.Mutex
+	failed map[string]bool
+}
+
+// Start opens the sinks of specs and subscribes them to bus
+func Start(specs []Spec, opts Options, bus *events.Bus) (*Dispatcher, error) {
+	d := &Dispatcher{done: make(chan struct{}), failed: make(map[string]bool)}
+	for _, spec := range specs {
+		s, err := Open(spec, opts)
+		if err != nil {
+			d.closeSinks()
+			return nil, fmt.Errorf("failed to open sink %s: %w", spec, err)
+		}
+		d.sinks = append(d.sinks, s)
+		fmt.Printf("Metric Sink: %s\n", spec)
+	}
+	d.events, d.unsubscribe = bus.Subscribe(sampleBuffer)
+	go d.forward()
+	return d, nil
+}
+
+// forward writes the samples until the subscription ends
+func (d *Dispatcher) forward() {
+	defer close(d.done)
+	for event := range d.events {
+		if event.Type != events.TypeSample {
+			continue
+		}
+		d.each(func(s MetricSink) error { return s.WriteSample(event.Source, event.Time, event.Data) })
+	}
+}
+
+// Write sends a finished iteration to every sink
+func (d *Dispatcher) Write(result runner.TestResult) {
+	d.each(func(s MetricSink) error { return s.Write(result) })
+}
+
+// Close ends the subscription and closes every sink
+func (d *Dispatcher) Close() {
+	d.unsubscribe()
+	<-d.done
+	d.closeSinks()
+}
+
+func (d *Dispatcher) closeSinks() {
+	d.each(func(s MetricSink) error { return s.Close() })
+}
+
+// each calls fn for every sink, warning about the first error of each
+func (d *Dispatcher) each(fn func(s MetricSink) error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, s := range d.sinks {
+		if err := fn(s); err != nil && !d.failed[s.Name()] {
+			d.failed[s.Name()] = true
+			fmt.Fprintf(os.Stderr, "Warning: metric sink %s: %v\n", s.Name(), err)
+		}
+	}
+}
+
+// Field names of an iteration, in output order
+var resultFieldNames = []string{
+	"iteration", "passed", "failed",
+	"download_time_seconds", "upload_time_seconds",
+	"bytes_downloaded", "bytes_uploaded",
+	"download_avg_mbs", "download_peak_mbs",
+	"images_skipped", "cache_hits",
+	"cpu_avg_percent", "cpu_peak_percent", "memory_peak_mb",
+	"disk_usage_bytes",
+}
+
+// resultFields returns the numeric outcome of an iteration, without the
+// samples and detailed metrics of the results file
+func resultFields(result runner.TestResult) map[string]float64 {
+	download := result.DownloadPhase.DownloadMetrics
+	return map[string]float64{
+		"iteration":             float64(result.Iteration),
+		"passed":                boolValue(result.Passed),
+		"failed":                boolValue(result.Failed),
+		"download_time_seconds": result.DownloadPhase.WallTime.Seconds(),
+		"upload_time_seconds":   result.UploadPhase.WallTime.Seconds(),
+		"bytes_downloaded":      float64(download.TotalBytesDownloaded),
+		"bytes_uploaded":        float64(result.UploadPhase.BytesUploaded),
+		"download_avg_mbs":      download.AverageSpeedMBs,
+		"download_peak_mbs":     download.PeakSpeedMBs,
+		"images_skipped":        float64(result.DownloadPhase.ImagesSkipped),
+		"cache_hits":            float64(result.DownloadPhase.CacheHits),
+		"cpu_avg_percent":       result.ResourceMetrics.CPUAvgPercent,
+		"cpu_peak_percent":      result.ResourceMetrics.CPUPeakPercent,
+		"memory_peak_mb":        result.ResourceMetrics.MemoryPeakMB,
+		"disk_usage_bytes":      float64(result.DiskUsageBytes),
+	}
+}
+
+// resultLabels returns what identifies an iteration besides the run
+func resultLabels(result runner.TestResult) map[string]string {
+	labels := map[string]string{"version": result.Version, "kind": runKind(result)}
+	if result.Registry != "" {
+		labels["registry"] = result.Registry
+	}
+	if result.Stage != nil {
+		labels["stage"] = result.Stage.Name
+	}
+	return labels
+}
+
+// runKind returns clean, cached or update
+func runKind(result runner.TestResult) string {
+	switch {
+	case result.IsUpdateRun:
+		return "update"
+	case result.IsCleanRun:
+		return "clean"
+	}
+	return "cached"
+}
+
+// sampleFields returns the numeric fields of a monitor sample, with nested
+// objects flattened into parent_child names. Lists and text are left out.
+func sampleFields(sample interface{}) map[string]float64 {
+	data, err := json.Marshal(sample)
+	if err != nil {
+		return nil
+	}
+	var decoded map[string]interface{}
+	if json.Unmarshal(data, &decoded) != nil {
+		return nil
+	}
+	fields := make(map[string]float64)
+	flatten(fields, "", decoded)
+	return fields
+}
+
+func flatten(fields map[string]float64, prefix string, values map[string]interface{}) {
+	for key, value := range values {
+		name := snakeCase(key)
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+		switch v := value.(type) {
+		case float64:
+			fields[name] = v
+		case bool:
+			fields[name] = boolValue(v)
+		case map[string]interface{}:
+			flatten(fields, name, v)
+		}
+	}
+}
+
+// snakeCase turns the PascalCase field names of monitor samples into
+// metric names, e.g. DownloadRateMB into download_rate_mb
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		upper := r >= 'A' && r <= 'Z'
+		if upper && i > 0 {
+			prevLower := runes[i-1] >= 'a' && runes[i-1] <= 'z' || runes[i-1] >= '0' && runes[i-1] <= '9'
+			nextLower := i+1 < len(runes) && runes[i+1] >= 'a' && runes[i+1] <= 'z'
+			if prevLower || nextLower && runes[i-1] != '_' {
+				b.WriteByte('_')
+			}
+		}
+		if upper {
+			r += 'a' - 'A'
+		} else if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9') {
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,248 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRootTimeout bounds each access to a root of a federated store, so a
+// hung NFS mount does not block the others
+const DefaultRootTimeout = 10 * time.Second
+
+// Root is one labelled store of a federated store
+type Root struct {
+	Label string
+	Store Store
+}
+
+// RootStatus is the outcome of the last listing of a root
+type RootStatus struct {
+	Label     string    `json:"label"`
+	Location  string    `json:"location"`
+	Available bool      `json:"available"`
+	Error     string    `json:"error,omitempty"`
+	Objects   int       `json:"objects"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Federated merges several stores, e.g. the results directories of different
+// runners, into one. Objects are named <label>/<name> after their root; a root
+// that fails or times out is left out of the listing instead of failing it.
+type Federated struct {
+	roots   []Root
+	timeout time.Duration
+
+	mu     sync.Mutex
+	status map[string]RootStatus
+}
+
+// NewFederated creates a store over roots; labels must be unique and must not
+// contain "/"
+func NewFederated(roots []Root) (*Federated, error) {
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no results roots")
+	}
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		if root.Label == "" || strings.ContainsAny(root.Label, "/\\") || root.Label == "." || root.Label == ".." {
+			return nil, fmt.Errorf("invalid label %q for results root %s", root.Label, root.Store)
+		}
+		if seen[root.Label] {
+			return nil, fmt.Errorf("duplicate results root label %q; name the roots with label=location", root.Label)
+		}
+		seen[root.Label] = true
+	}
+	return &Federated{roots: roots, timeout: DefaultRootTimeout, status: make(map[string]RootStatus)}, nil
+}
+
+// ParseRoot splits a label=location root specification. Without a label, the
+// last element of the location is used.
+func ParseRoot(spec string) (label, location string) {
+	if label, location, ok := strings.Cut(spec, "="); ok && !strings.ContainsAny(label, "/\\:") {
+		return label, location
+	}
+	location = spec
+	if IsRemote(spec) {
+		return path.Base(strings.TrimPrefix(spec, "s3://")), location
+	}
+	return filepath.Base(filepath.Clean(spec)), location
+}
+
+// SetTimeout bounds each access to a root
+func (f *Federated) SetTimeout(timeout time.Duration) {
+	f.timeout = timeout
+}
+
+// List returns the objects of all available roots, named <label>/<name>. It
+// fails only when no root is available.
+func (f *Federated) List() ([]ObjectInfo, error) {
+	type listing struct {
+		objects []ObjectInfo
+		err     error
+	}
+	listings := make([]listing, len(f.roots))
+	var wg sync.WaitGroup
+	for i, root := range f.roots {
+		wg.Add(1)
+		go func(i int, root Root) {
+			defer wg.Done()
+			listings[i].objects, listings[i].err = withTimeout(f.timeout, root.Store.List)
+		}(i, root)
+	}
+	wg.Wait()
+
+	var objects []ObjectInfo
+	var errs []error
+	for i, root := range f.roots {
+		l := listings[i]
+		f.record(root, l.err, len(l.objects))
+		if l.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", root.Label, l.err))
+			continue
+		}
+		for _, object := range l.objects {
+			object.Name = root.Label + "/" + object.Name
+			objects = append(objects, object)
+		}
+	}
+	if len(errs) == len(f.roots) {
+		return nil, fmt.Errorf("no results root available: %w", errors.Join(errs...))
+	}
+	return objects, nil
+}
+
+// Read returns the contents of <label>/<name>
+func (f *Federated) Read(name string) ([]byte, error) {
+	root, name, err := f.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return withTimeout(f.timeout, func() ([]byte, error) {
+		return root.Store.Read(name)
+	})
+}
+
+// Open returns a reader of <label>/<name>; the timeout covers opening it
+func (f *Federated) Open(name string) (io.ReadCloser, int64, error) {
+	root, name, err := f.resolve(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	type opened struct {
+		reader io.ReadCloser
+		size   int64
+	}
+	o, err := withTimeout(f.timeout, func() (opened, error) {
+		reader, size, err := root.Store.Open(name)
+		return opened{reader, size}, err
+	})
+	return o.reader, o.size, err
+}
+
+// Write stores the object <label>/<name> in the root of that label
+func (f *Federated) Write(name string, r io.Reader, size int64) error {
+	root, name, err := f.resolve(name)
+	if err != nil {
+		return err
+	}
+	return root.Store.Write(name, r, size)
+}
+
+// Delete removes the object <label>/<name> from the root of that label
+func (f *Federated) Delete(name string) error {
+	root, name, err := f.resolve(name)
+	if err != nil {
+		return err
+	}
+	return root.Store.Delete(name)
+}
+
+// String lists the roots with their labels
+func (f *Federated) String() string {
+	parts := make([]string, len(f.roots))
+	for i, root := range f.roots {
+		parts[i] = fmt.Sprintf("%s=%s", root.Label, root.Store)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Status returns the outcome of the last listing of every root, in the order
+// the roots were given; roots not listed yet are reported unavailable
+func (f *Federated) Status() []RootStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status := make([]RootStatus, len(f.roots))
+	for i, root := range f.roots {
+		s, ok := f.status[root.Label]
+		if !ok {
+			s = RootStatus{Label: root.Label, Location: root.Store.String(), Error: "not listed yet"}
+		}
+		status[i] = s
+	}
+	return status
+}
+
+// resolve returns the root and the name within it of a federated object name
+func (f *Federated) resolve(name string) (Root, string, error) {
+	label, rest, ok := strings.Cut(name, "/")
+	if ok {
+		for _, root := range f.roots {
+			if root.Label == label {
+				return root, rest, nil
+			}
+		}
+	}
+	return Root{}, "", fmt.Errorf("invalid object name %q: no results root %q: %w", name, label, os.ErrNotExist)
+}
+
+// record keeps the outcome of listing root, logging when it becomes
+// unavailable or available again
+func (f *Federated) record(root Root, err error, objects int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	previous, seen := f.status[root.Label]
+	status := RootStatus{Label: root.Label, Location: root.Store.String(), Available: err == nil, Objects: objects, CheckedAt: time.Now()}
+	if err != nil {
+		status.Error = err.Error()
+		if !seen || previous.Available {
+			log.Printf("Warning: results root %s (%s) is unavailable: %v", root.Label, root.Store, err)
+		}
+	} else if seen && !previous.Available {
+		log.Printf("Results root %s (%s) is available again", root.Label, root.Store)
+	}
+	f.status[root.Label] = status
+}
+
+// withTimeout runs fn, giving up after timeout. A timed out fn keeps running
+// in the background, as a hung file system call cannot be interrupted.
+func withTimeout[T any](timeout time.Duration, fn func() (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn()
+	}
+	type outcome struct {
+		value T
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := fn()
+		done <- outcome{value, err}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case o := <-done:
+		return o.value, o.err
+	case <-timer.C:
+		var zero T
+		return zero, fmt.Errorf("no response after %v", timeout)
+	}
+}
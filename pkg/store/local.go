@@ -0,0 +1,118 @@
+package store
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Local keeps results in a directory
+type Local struct {
+	dir string
+}
+
+// NewLocal creates a store for dir
+func NewLocal(dir string) *Local {
+	return &Local{dir: dir}
+}
+
+// Dir returns the results directory
+func (l *Local) Dir() string {
+	return l.dir
+}
+
+// List returns the files in the results directory
+func (l *Local) List() ([]ObjectInfo, error) {
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []ObjectInfo
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		objects = append(objects, ObjectInfo{Name: entry.Name(), ModTime: info.ModTime(), Size: info.Size()})
+	}
+	return objects, nil
+}
+
+// Read returns the contents of a file in the results directory
+func (l *Local) Read(name string) ([]byte, error) {
+	if err := checkObjectName(name); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filepath.Join(l.dir, filepath.FromSlash(name)))
+}
+
+// Open opens a file in the results directory
+func (l *Local) Open(name string) (io.ReadCloser, int64, error) {
+	if err := checkObjectName(name); err != nil {
+		return nil, 0, err
+	}
+	file, err := os.Open(filepath.Join(l.dir, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	return file, info.Size(), nil
+}
+
+// Write copies r into a file in the results directory, replacing it atomically
+func (l *Local) Write(name string, r io.Reader, size int64) error {
+	if err := checkObjectName(name); err != nil {
+		return err
+	}
+	target := filepath.Join(l.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", name, err)
+	}
+
+	tmpPath := target + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	written, err := io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written != size {
+		err = fmt.Errorf("wrote %d of %d bytes", written, size)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return nil
+}
+
+// Delete removes a file from the results directory
+func (l *Local) Delete(name string) error {
+	if err := checkObjectName(name); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(l.dir, filepath.FromSlash(name))); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete %s: %w", name, err)
+	}
+	return nil
+}
+
+// String returns the results directory
+func (l *Local) String() string {
+	return l.dir
+}
@@ -0,0 +1,396 @@
+package store
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3 settings read from the environment, as used by the AWS CLI and MinIO client
+const (
+	EnvS3AccessKey    = "AWS_ACCESS_KEY_ID"
+	EnvS3SecretKey    = "AWS_SECRET_ACCESS_KEY"
+	EnvS3SessionToken = "AWS_SESSION_TOKEN"
+	EnvS3Region       = "AWS_REGION"
+	EnvS3Endpoint     = "AWS_ENDPOINT_URL"
+)
+
+// emptyPayloadHash is the sha256 of an empty request body; uploads are streamed
+// with an unsigned payload instead of hashing them up front
+const (
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+)
+
+// maxPutSize is the largest object a single S3 PUT accepts
+const maxPutSize = 5 << 30
+
+// S3Options configures access to an S3-compatible object store
+type S3Options struct {
+	Endpoint     string // e.g. https://minio.lab:9000; empty for AWS S3
+	Region       string // Default us-east-1
+	AccessKey    string // Anonymous access when empty
+	SecretKey    string
+	SessionToken string
+	SkipTLS      bool
+}
+
+// S3OptionsFromEnv reads the S3 settings from the standard AWS environment variables
+func S3OptionsFromEnv() S3Options {
+	region := os.Getenv(EnvS3Region)
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	return S3Options{
+		Endpoint:     os.Getenv(EnvS3Endpoint),
+		Region:       region,
+		AccessKey:    os.Getenv(EnvS3AccessKey),
+		SecretKey:    os.Getenv(EnvS3SecretKey),
+		SessionToken: os.Getenv(EnvS3SessionToken),
+	}
+}
+
+// S3 keeps results under a bucket prefix of an S3-compatible object store
+// (AWS S3, MinIO, Ceph RGW, ...), signing requests with AWS Signature V4
+type S3 struct {
+	bucket  string
+	prefix  string // Ends with "/" unless empty
+	base    *url.URL
+	options S3Options
+	client  *http.Client
+}
+
+// NewS3 creates a store for an s3://bucket/prefix location
+func NewS3(location string, options S3Options) (*S3, error) {
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(location, "s3://"), "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid S3 location %q (expected s3://bucket/prefix)", location)
+	}
+	if prefix = strings.Trim(prefix, "/"); prefix != "" {
+		prefix += "/"
+	}
+	if options.Region == "" {
+		options.Region = "us-east-1"
+	}
+
+	// Custom endpoints (MinIO, RGW) use path-style addressing, AWS virtual-hosted style
+	endpoint := options.Endpoint
+	pathStyle := endpoint != ""
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, options.Region)
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	base, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", options.Endpoint)
+	}
+	if pathStyle {
+		base.Path += "/" + bucket
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if options.SkipTLS {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return &S3{
+		bucket:  bucket,
+		prefix:  prefix,
+		base:    base,
+		options: options,
+		client:  &http.Client{Transport: transport}, // Requests set their own deadlines; log uploads can take long
+	}, nil
+}
+
+// listBucketResult is the ListObjectsV2 response
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+}
+
+// s3Error is the error document returned by S3
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// List returns the objects directly under the prefix
+func (b *S3) List() ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {b.prefix}, "delimiter": {"/"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		data, err := b.do(http.MethodGet, "", query, nil, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list s3://%s/%s: %w", b.bucket, b.prefix, err)
+		}
+
+		var page listBucketResult
+		if err := xml.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse bucket listing: %w", err)
+		}
+		for _, obj := range page.Contents {
+			objects = append(objects, ObjectInfo{
+				Name:    strings.TrimPrefix(obj.Key, b.prefix),
+				ModTime: obj.LastModified,
+				Size:    obj.Size,
+			})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// Read returns the contents of an object under the prefix
+func (b *S3) Read(name string) ([]byte, error) {
+	if err := checkObjectName(name); err != nil {
+		return nil, err
+	}
+	data, err := b.do(http.MethodGet, b.prefix+name, nil, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read s3://%s/%s%s: %w", b.bucket, b.prefix, name, err)
+	}
+	return data, nil
+}
+
+// Open starts the download of an object under the prefix
+func (b *S3) Open(name string) (io.ReadCloser, int64, error) {
+	if err := checkObjectName(name); err != nil {
+		return nil, 0, err
+	}
+	resp, err := b.send(http.MethodGet, b.prefix+name, nil, nil, 0)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read s3://%s/%s%s: %w", b.bucket, b.prefix, name, err)
+	}
+	if resp.ContentLength < 0 {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("failed to read s3://%s/%s%s: no Content-Length", b.bucket, b.prefix, name)
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// Write uploads size bytes from r as an object under the prefix
+func (b *S3) Write(name string, r io.Reader, size int64) error {
+	if err := checkObjectName(name); err != nil {
+		return err
+	}
+	if size > maxPutSize {
+		return fmt.Errorf("%s is larger than the 5 GiB limit of a single S3 upload", name)
+	}
+	if _, err := b.do(http.MethodPut, b.prefix+name, nil, r, size); err != nil {
+		return fmt.Errorf("failed to write s3://%s/%s%s: %w", b.bucket, b.prefix, name, err)
+	}
+	return nil
+}
+
+// Delete removes an object under the prefix
+func (b *S3) Delete(name string) error {
+	if err := checkObjectName(name); err != nil {
+		return err
+	}
+	if _, err := b.do(http.MethodDelete, b.prefix+name, nil, nil, 0); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete s3://%s/%s%s: %w", b.bucket, b.prefix, name, err)
+	}
+	return nil
+}
+
+// String returns the s3:// location
+func (b *S3) String() string {
+	return fmt.Sprintf("s3://%s/%s (%s)", b.bucket, b.prefix, b.base.Host)
+}
+
+// do sends a signed request for an object key, or for the bucket when key is
+// empty, and returns the response body. body is only sent with PUT requests.
+func (b *S3) do(method, key string, query url.Values, body io.Reader, size int64) ([]byte, error) {
+	resp, err := b.send(method, key, query, body, size)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// send sends a signed request like do and returns the response of a
+// successful request, whose body the caller reads and closes. The request
+// deadline covers reading the body.
+func (b *S3) send(method, key string, query url.Values, body io.Reader, size int64) (*http.Response, error) {
+	u := *b.base
+	if key != "" {
+		u.Path += "/" + key
+	} else if u.Path == "" {
+		u.Path = "/"
+	}
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+
+	// Allow roughly 1 MB/s for uploads on top of the base timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second+time.Duration(size>>20)*time.Second)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		req.ContentLength = size
+		payloadHash = unsignedPayload
+	}
+	b.sign(req, time.Now().UTC(), payloadHash)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		resp.Body = cancelOnClose{resp.Body, cancel}
+		return resp, nil
+	}
+
+	defer cancel()
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, os.ErrNotExist
+	}
+	data, _ := io.ReadAll(resp.Body)
+	var s3err s3Error
+	if xml.Unmarshal(data, &s3err) == nil && s3err.Code != "" {
+		return nil, fmt.Errorf("%s: %s", s3err.Code, s3err.Message)
+	}
+	return nil, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// cancelOnClose releases the request context of a response body when the
+// body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// sign adds AWS Signature V4 headers to a request. Requests are sent unsigned
+// when no access key is configured.
+func (b *S3) sign(req *http.Request, now time.Time, payloadHash string) {
+	if b.options.AccessKey == "" {
+		return
+	}
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if b.options.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", b.options.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + b.options.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+b.options.SecretKey), date)
+	for _, part := range []string{b.options.Region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.options.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by key, as both the request
+// and the signature require
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, escapeRFC3986(key)+"="+escapeRFC3986(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath URI-encodes each segment of an object path
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = escapeRFC3986(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// escapeRFC3986 percent-encodes everything except unreserved characters
+func escapeRFC3986(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
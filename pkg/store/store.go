@@ -0,0 +1,57 @@
+package store
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// Store is where results files, their integrity sidecars and optionally the
+// oc-mirror logs are kept. The runner writes to it after a run and the web UI
+// reads from it, whether it is a local directory or an object store bucket.
+type Store interface {
+	// List returns the objects directly under the store root
+	List() ([]ObjectInfo, error)
+	// Read returns the contents of an object; missing objects return an error wrapping os.ErrNotExist
+	Read(name string) ([]byte, error)
+	// Open returns a reader of an object and its size, for objects too large to read at once; the caller closes it
+	Open(name string) (io.ReadCloser, int64, error)
+	// Write stores size bytes read from r as the object name, replacing any existing object
+	Write(name string, r io.Reader, size int64) error
+	// Delete removes an object; removing a missing object is not an error
+	Delete(name string) error
+	// String describes the store location for logs
+	String() string
+}
+
+// ObjectInfo describes a stored results file or sidecar
+type ObjectInfo struct {
+	Name    string
+	ModTime time.Time
+	Size    int64
+}
+
+// IsRemote reports whether location names an object store rather than a directory
+func IsRemote(location string) bool {
+	return strings.HasPrefix(location, "s3://")
+}
+
+// Open returns the store for location: s3://bucket/prefix for an S3-compatible
+// object store, anything else is a local directory
+func Open(location string, options S3Options) (Store, error) {
+	if IsRemote(location) {
+		return NewS3(location, options)
+	}
+	return NewLocal(location), nil
+}
+
+// checkObjectName rejects names that would escape the store root. Names may
+// contain "/" to address objects below the root, such as logs/<file>.
+func checkObjectName(name string) error {
+	if name == "" || strings.ContainsRune(name, '\\') || path.IsAbs(name) || path.Clean(name) != name || name == ".." || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("invalid object name %q", name)
+	}
+	return nil
+}
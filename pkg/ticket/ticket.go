@@ -0,0 +1,288 @@
+package ticket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Environment variables holding the tracker connection; the token is only read from the environment
+const (
+	EnvURL   = "OC_MIRROR_TEST_TICKET_URL"
+	EnvUser  = "OC_MIRROR_TEST_TICKET_USER"
+	EnvToken = "OC_MIRROR_TEST_TICKET_TOKEN"
+)
+
+// Supported ticket systems
+const (
+	SystemJira       = "jira"
+	SystemServiceNow = "servicenow"
+)
+
+var (
+	jiraKeyPattern          = regexp.MustCompile(`^[A-Z][A-Z0-9_]+-\d+$`)
+	serviceNowNumberPattern = regexp.MustCompile(`^([A-Z]+)\d+$`)
+)
+
+// serviceNowTables maps ServiceNow record number prefixes to their tables
+var serviceNowTables = map[string]string{
+	"INC":    "incident",
+	"CHG":    "change_request",
+	"CTASK":  "change_task",
+	"PRB":    "problem",
+	"REQ":    "sc_request",
+	"RITM":   "sc_req_item",
+	"SCTASK": "sc_task",
+	"TASK":   "task",
+}
+
+// uploadTimeout bounds a single attachment upload; bundles with oc-mirror logs can be large
+const uploadTimeout = 30 * time.Minute
+
+// Config describes the ticket run artifacts are attached to
+type Config struct {
+	ID     string // Jira issue key (e.g. MIRROR-123) or ServiceNow number (e.g. INC0012345)
+	System string // jira or servicenow; detected from ID when empty
+	URL    string // Base URL of the Jira or ServiceNow instance
+	User   string // Jira account email or ServiceNow user; a token without user is sent as a bearer token
+	Token  string // API token, personal access token or password
+	Table  string // ServiceNow table; derived from the number prefix when empty
+}
+
+// ConfigFromEnv reads the tracker connection from the environment
+func ConfigFromEnv() Config {
+	return Config{
+		URL:   os.Getenv(EnvURL),
+		User:  os.Getenv(EnvUser),
+		Token: os.Getenv(EnvToken),
+	}
+}
+
+// Enabled returns true if a ticket is configured
+func (c Config) Enabled() bool {
+	return c.ID != ""
+}
+
+// GetSystem returns the configured ticket system or the one matching the ID
+func (c Config) GetSystem() string {
+	if c.System != "" {
+		return c.System
+	}
+	if jiraKeyPattern.MatchString(c.ID) {
+		return SystemJira
+	}
+	if serviceNowNumberPattern.MatchString(c.ID) {
+		return SystemServiceNow
+	}
+	return ""
+}
+
+// GetTable returns the ServiceNow table of the ticket
+func (c Config) GetTable() string {
+	if c.Table != "" {
+		return c.Table
+	}
+	if matches := serviceNowNumberPattern.FindStringSubmatch(c.ID); len(matches) > 1 {
+		return serviceNowTables[matches[1]]
+	}
+	return ""
+}
+
+// Validate checks that the ticket can be reached
+func (c Config) Validate() error {
+	if !c.Enabled() {
+		if c.System != "" || c.Table != "" {
+			return fmt.Errorf("ticket options require a ticket ID")
+		}
+		return nil
+	}
+	switch c.GetSystem() {
+	case SystemJira:
+	case SystemServiceNow:
+		if c.GetTable() == "" {
+			return fmt.Errorf("cannot derive the ServiceNow table of %s, set the ticket table", c.ID)
+		}
+	case "":
+		return fmt.Errorf("cannot detect the ticket system of %q, set it to %s or %s", c.ID, SystemJira, SystemServiceNow)
+	default:
+		return fmt.Errorf("unknown ticket system %q (valid: %s, %s)", c.System, SystemJira, SystemServiceNow)
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid ticket URL %q (expected http:// or https://, env %s)", c.URL, EnvURL)
+	}
+	if c.Token == "" {
+		return fmt.Errorf("ticket upload requires an API token in %s", EnvToken)
+	}
+	return nil
+}
+
+// String returns a human-readable description that does not leak credentials
+func (c Config) String() string {
+	host := c.URL
+	if u, err := url.Parse(c.URL); err == nil {
+		host = u.Host
+	}
+	return fmt.Sprintf("%s %s on %s", c.GetSystem(), c.ID, host)
+}
+
+// Attach uploads every file to the ticket
+func Attach(cfg Config, files []string) error {
+	client := &http.Client{Timeout: uploadTimeout}
+	var upload func(string) error
+	switch cfg.GetSystem() {
+	case SystemJira:
+		upload = func(path string) error { return attachJira(client, cfg, path) }
+	case SystemServiceNow:
+		sysID, err := serviceNowSysID(client, cfg)
+		if err != nil {
+			return err
+		}
+		upload = func(path string) error { return attachServiceNow(client, cfg, sysID, path) }
+	default:
+		return fmt.Errorf("unknown ticket system %q", cfg.GetSystem())
+	}
+
+	var errs []error
+	for _, path := range files {
+		if err := upload(path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to attach %s: %w", filepath.Base(path), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// attachJira posts the file as multipart form data to the issue attachments
+// endpoint, streaming it so large bundles are not held in memory
+func attachJira(client *http.Client, cfg Config, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		part, err := form.CreateFormFile("file", filepath.Base(path))
+		if err == nil {
+			_, err = io.Copy(part, file)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	endpoint := strings.TrimRight(cfg.URL, "/") + "/rest/api/2/issue/" + url.PathEscape(cfg.ID) + "/attachments"
+	req, err := http.NewRequest(http.MethodPost, endpoint, body)
+	if err != nil {
+		body.Close()
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("X-Atlassian-Token", "no-check") // Required by Jira for attachment uploads
+	return do(client, cfg, req, nil)
+}
+
+// serviceNowSysID looks up the sys_id of the ticket number
+func serviceNowSysID(client *http.Client, cfg Config) (string, error) {
+	query := url.Values{}
+	query.Set("sysparm_query", "number="+cfg.ID)
+	query.Set("sysparm_fields", "sys_id")
+	query.Set("sysparm_limit", "1")
+	endpoint := strings.TrimRight(cfg.URL, "/") + "/api/now/table/" + url.PathEscape(cfg.GetTable()) + "?" + query.Encode()
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		Result []struct {
+			SysID string `json:"sys_id"`
+		} `json:"result"`
+	}
+	if err := do(client, cfg, req, &response); err != nil {
+		return "", fmt.Errorf("failed to look up %s: %w", cfg.ID, err)
+	}
+	if len(response.Result) == 0 || response.Result[0].SysID == "" {
+		return "", fmt.Errorf("%s not found in ServiceNow table %s", cfg.ID, cfg.GetTable())
+	}
+	return response.Result[0].SysID, nil
+}
+
+// attachServiceNow posts the raw file to the attachment API
+func attachServiceNow(client *http.Client, cfg Config, sysID, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("table_name", cfg.GetTable())
+	query.Set("table_sys_id", sysID)
+	query.Set("file_name", filepath.Base(path))
+	endpoint := strings.TrimRight(cfg.URL, "/") + "/api/now/attachment/file?" + query.Encode()
+	req, err := http.NewRequest(http.MethodPost, endpoint, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", contentType(path))
+	return do(client, cfg, req, nil)
+}
+
+// do sends an authenticated request and decodes the JSON response into out, if set
+func do(client *http.Client, cfg Config, req *http.Request, out interface{}) error {
+	if cfg.User != "" {
+		req.SetBasicAuth(cfg.User, cfg.Token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", req.URL.Host, errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 300))
+		return fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response from %s: %w", req.URL.Host, err)
+	}
+	return nil
+}
+
+// contentType returns the MIME type of an artifact by extension
+func contentType(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".tar.gz"):
+		return "application/gzip"
+	case strings.HasSuffix(path, ".md"):
+		return "text/markdown"
+	case strings.HasSuffix(path, ".json"):
+		return "application/json"
+	default:
+		return "application/octet-stream"
+	}
+}
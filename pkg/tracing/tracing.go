@@ -0,0 +1,275 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables of the OpenTelemetry SDKs, used when the flags are not set
+const (
+	EnvEndpoint    = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	EnvHeaders     = "OTEL_EXPORTER_OTLP_HEADERS" // Comma-separated key=value pairs
+	EnvServiceName = "OTEL_SERVICE_NAME"
+)
+
+// DefaultServiceName is the service.name of the exported spans
+const DefaultServiceName = "oc-mirror-test"
+
+// Config describes where spans are exported
+type Config struct {
+	Endpoint    string            // OTLP/HTTP endpoint, e.g. http://tempo:4318; spans are posted to <endpoint>/v1/traces
+	Headers     map[string]string // Sent with every export, e.g. an authorization token
+	ServiceName string            // service.name resource attribute, defaulting to DefaultServiceName
+}
+
+// ConfigFromEnv reads the exporter settings from the standard OpenTelemetry environment variables
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Endpoint:    os.Getenv(EnvEndpoint),
+		ServiceName: os.Getenv(EnvServiceName),
+	}
+	headers, err := ParseHeaders(strings.Split(os.Getenv(EnvHeaders), ","))
+	if err == nil && len(headers) > 0 {
+		cfg.Headers = headers
+	}
+	return cfg
+}
+
+// ParseHeaders parses key=value pairs; empty entries are skipped
+func ParseHeaders(pairs []string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range pairs {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid OTLP header %q (expected key=value)", pair)
+		}
+		// The OpenTelemetry environment variable allows URL-encoded values
+		if decoded, err := url.QueryUnescape(value); err == nil {
+			value = decoded
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// Enabled returns true if an endpoint is configured
+func (c Config) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// Validate checks the endpoint
+func (c Config) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid OTLP endpoint %q (expected http:// or https://)", c.Endpoint)
+	}
+	return nil
+}
+
+// String returns a human-readable description that does not leak header values
+func (c Config) String() string {
+	s := c.tracesURL() + " (service " + c.serviceName()
+	if len(c.Headers) > 0 {
+		keys := make([]string, 0, len(c.Headers))
+		for key := range c.Headers {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		s += ", headers " + strings.Join(keys, ", ")
+	}
+	return s + ")"
+}
+
+func (c Config) serviceName() string {
+	if c.ServiceName == "" {
+		return DefaultServiceName
+	}
+	return c.ServiceName
+}
+
+// tracesURL returns the OTLP/HTTP traces URL of the endpoint
+func (c Config) tracesURL() string {
+	endpoint := strings.TrimRight(c.Endpoint, "/")
+	if strings.HasSuffix(endpoint, "/v1/traces") {
+		return endpoint
+	}
+	return endpoint + "/v1/traces"
+}
+
+// Attribute is a span or resource attribute
+type Attribute struct {
+	Key   string
+	Value interface{} // string, int64, float64 or bool
+}
+
+// String returns a string attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute
+func Int(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Float returns a floating point attribute
+func Float(key string, value float64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean attribute
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Tracer records spans and exports the ended ones to an OTLP endpoint. A nil
+// Tracer records nothing, so callers need no checks when tracing is disabled.
+type Tracer struct {
+	resource []Attribute
+	exporter *exporter
+	mu       sync.Mutex
+	ended    []*Span // Waiting for the next Flush
+}
+
+// NewTracer creates a tracer exporting to cfg.Endpoint; resource attributes
+// describe the process, in addition to service.name
+func NewTracer(cfg Config, resource ...Attribute) *Tracer {
+	return &Tracer{
+		resource: append([]Attribute{String("service.name", cfg.serviceName())}, resource...),
+		exporter: newExporter(cfg),
+	}
+}
+
+// Span is a timed operation within a trace
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	start    time.Time
+
+	mu      sync.Mutex
+	end     time.Time
+	attrs   []Attribute
+	failed  bool
+	message string
+}
+
+// Start begins a span. Without a parent, the span is the root of a new trace.
+func (t *Tracer) Start(parent *Span, name string, attrs ...Attribute) *Span {
+	if t == nil {
+		return nil
+	}
+	span := &Span{tracer: t, name: name, start: time.Now(), attrs: attrs}
+	if parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		randomID(span.traceID[:])
+	}
+	randomID(span.spanID[:])
+	return span
+}
+
+// TraceID returns the hex trace ID, or "" for a nil span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// RecordError marks the span as failed with the error message; nil errors are ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = true
+	s.message = err.Error()
+}
+
+// End ends the span now
+func (s *Span) End() {
+	s.EndAt(time.Now())
+}
+
+// EndAt ends the span at a point in time, no earlier than its start. Only the
+// first call has an effect.
+func (s *Span) EndAt(end time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	if end.Before(s.start) {
+		end = s.start
+	}
+	s.end = end
+	s.mu.Unlock()
+
+	s.tracer.mu.Lock()
+	s.tracer.ended = append(s.tracer.ended, s)
+	s.tracer.mu.Unlock()
+}
+
+// Flush exports the spans ended since the last flush
+func (t *Tracer) Flush() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	spans := t.ended
+	t.ended = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+	return t.exporter.export(t.resource, spans)
+}
+
+// randomID fills id with random bytes; OpenTelemetry IDs must not be all zero
+func randomID(id []byte) {
+	for {
+		if _, err := rand.Read(id); err != nil {
+			// crypto/rand does not fail on supported platforms; fall back to the clock
+			now := uint64(time.Now().UnixNano())
+			for i := range id {
+				id[i] = byte(now >> (8 * (i % 8)))
+			}
+		}
+		for _, b := range id {
+			if b != 0 {
+				return
+			}
+		}
+	}
+}
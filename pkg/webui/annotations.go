@@ -0,0 +1,169 @@
+package webui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// maxAnnotationUpdate caps the body of an annotation update
+const maxAnnotationUpdate = 64 * 1024
+
+// AnnotationUpdate is the body of POST /api/annotations/<file>: tags to set
+// or remove and an optional note. Only tags added from the dashboard can be
+// removed; the ones of the results header are part of the checksummed file.
+type AnnotationUpdate struct {
+	Tags       map[string]string `json:"tags,omitempty"`
+	RemoveTags []string          `json:"remove_tags,omitempty"`
+	Note       string            `json:"note,omitempty"`
+	Author     string            `json:"author,omitempty"` // Defaults to the basic auth user
+}
+
+// AnnotationsResponse holds the annotations of a results file: the ones of
+// the results header, the ones of the sidecar and both merged
+type AnnotationsResponse struct {
+	File   string             `json:"file"`
+	Header runner.Annotations `json:"header"`
+	Added  runner.Annotations `json:"added"`
+	runner.Annotations
+}
+
+// SetAnnotations lets the dashboard and API add tags and notes to results files
+func (s *Server) SetAnnotations(enabled bool) {
+	s.annotations = enabled
+}
+
+// handleAnnotations returns (GET) or, when annotations are enabled, updates
+// (POST) the tags and notes of a results file. Updates are written to the
+// annotations sidecar next to the results file, through the backend.
+func (s *Server) handleAnnotations(w http.ResponseWriter, r *http.Request) {
+	filename := strings.TrimPrefix(r.URL.Path, "/api/annotations/")
+	if !s.validResultName(filename) {
+		http.Error(w, "invalid filename", http.StatusBadRequest)
+		return
+	}
+	data, err := s.backend.Read(filename)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	file, err := runner.ParseResultsFile(data)
+	if err != nil {
+		http.Error(w, "failed to parse "+filename+": "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if !s.annotations {
+			http.Error(w, "editing annotations is disabled; start serve with --enable-annotations", http.StatusForbidden)
+			return
+		}
+		if !requireJSON(w, r) {
+			return
+		}
+		var update AnnotationUpdate
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAnnotationUpdate))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&update); err != nil {
+			http.Error(w, "invalid annotation update: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if update.Author == "" {
+			update.Author, _, _ = r.BasicAuth()
+		}
+		if err := s.updateAnnotations(filename, update); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	added := s.readAnnotations(filename)
+	if s.annotations {
+		w.Header().Set("X-Results-Annotations", "enabled")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AnnotationsResponse{
+		File:        filename,
+		Header:      file.Annotations(),
+		Added:       added,
+		Annotations: file.Annotations().Merge(added),
+	})
+}
+
+// updateAnnotations applies an update to the annotations sidecar of a results file
+func (s *Server) updateAnnotations(filename string, update AnnotationUpdate) error {
+	note := strings.TrimSpace(update.Note)
+	if note == "" && len(update.Tags) == 0 && len(update.RemoveTags) == 0 {
+		return errors.New("nothing to update: give tags, remove_tags or a note")
+	}
+	for key := range update.Tags {
+		if strings.TrimSpace(key) == "" || strings.Contains(key, "=") {
+			return errors.New("tag keys must be non-empty and must not contain '='")
+		}
+	}
+
+	s.annotationsMu.Lock()
+	defer s.annotationsMu.Unlock()
+	current := s.readAnnotations(filename)
+	for _, key := range update.RemoveTags {
+		delete(current.Tags, key)
+	}
+	updated := current.Merge(runner.Annotations{Tags: update.Tags})
+	if note != "" {
+		updated.Notes = append(updated.Notes, runner.Note{Text: note, Author: strings.TrimSpace(update.Author), CreatedAt: time.Now()})
+	}
+
+	data, err := json.MarshalIndent(updated, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := s.backend.Write(runner.AnnotationsPath(filename), bytes.NewReader(data), int64(len(data))); err != nil {
+		return fmt.Errorf("failed to write the annotations: %w", err)
+	}
+	logging.Event("annotations updated", "file", filename, "tags", len(updated.Tags), "notes", len(updated.Notes))
+	return nil
+}
+
+// readAnnotations returns the annotations sidecar of a results file, empty
+// when there is none or it cannot be read
+func (s *Server) readAnnotations(filename string) runner.Annotations {
+	data, err := s.backend.Read(runner.AnnotationsPath(filename))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logging.Printf("Warning: failed to read the annotations of %s: %v\n", filename, err)
+		}
+		return runner.Annotations{}
+	}
+	annotations, err := runner.ParseAnnotations(data)
+	if err != nil {
+		logging.Printf("Warning: %s: %v\n", runner.AnnotationsPath(filename), err)
+	}
+	return annotations
+}
+
+// requireJSON rejects a request whose body is not JSON with 415. Browsers
+// only send JSON cross-site after a CORS preflight, which the server does
+// not answer, so this keeps other sites from posting forms to the API.
+func requireJSON(w http.ResponseWriter, r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+		return false
+	}
+	return true
+}
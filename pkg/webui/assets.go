@@ -0,0 +1,92 @@
+package webui
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+)
+
+// embeddedAssets are the dashboard files built into the binary: index.html,
+// served at /, and the files served below /static/
+//
+//go:embed assets
+var embeddedAssets embed.FS
+
+// assetTypes are the content types of the dashboard files whose extension
+// the system MIME table may not know
+var assetTypes = map[string]string{
+	".html": "text/html; charset=utf-8",
+	".js":   "application/javascript",
+	".css":  "text/css",
+}
+
+// SetAssetsDir serves the dashboard files from dir instead of the embedded
+// ones, re-read on every request so the frontend can be changed without
+// rebuilding. Files missing from dir are still served from the binary.
+func (s *Server) SetAssetsDir(dir string) error {
+	if dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("failed to open assets directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("assets directory %s is not a directory", dir)
+		}
+	}
+	s.assetsDir = dir
+	return nil
+}
+
+// handleStatic serves the files below /static/ (CSS, JS)
+func (s *Server) handleStatic(w http.ResponseWriter, r *http.Request) {
+	s.serveAsset(w, r, strings.TrimPrefix(r.URL.Path, "/static/"))
+}
+
+// serveAsset writes a dashboard file, from the assets directory when it has it
+func (s *Server) serveAsset(w http.ResponseWriter, r *http.Request, name string) {
+	data, err := s.readAsset(name)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		logging.Printf("Warning: failed to read dashboard file %s: %v\n", name, err)
+		http.Error(w, "failed to read "+name, http.StatusInternalServerError)
+		return
+	}
+
+	contentType, ok := assetTypes[path.Ext(name)]
+	if !ok {
+		contentType = mime.TypeByExtension(path.Ext(name))
+	}
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	if s.assetsDir != "" {
+		// Edited files show up on reload
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Write(data)
+}
+
+// readAsset returns a dashboard file; name is relative to the assets root
+// and may not leave it
+func (s *Server) readAsset(name string) ([]byte, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, fs.ErrInvalid
+	}
+	if s.assetsDir != "" {
+		data, err := fs.ReadFile(os.DirFS(s.assetsDir), name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return data, err
+		}
+	}
+	return embeddedAssets.ReadFile(path.Join("assets", name))
+}
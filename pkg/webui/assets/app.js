@@ -0,0 +1,1527 @@
+let autoRefreshInterval = null;
+let speedChart = null;
+let resourceChart = null;
+let networkChart = null;
+let retryChart = null;
+let packageChart = null;
+let speedTimeChart = null;
+let memoryTimeChart = null;
+let compareTimingChart = null;
+let compareDeltaChart = null;
+let resultFiles = []; // /api/results, filtered in the select by the tag filter
+
+// The zoom plugin registers itself with the UMD build of Chart.js; register
+// it explicitly in case the load order differs
+if (window.ChartZoom) {
+    Chart.register(window.ChartZoom);
+}
+
+// Format duration
+function formatDuration(seconds) {
+    if (!seconds) return '-';
+    const s = Math.floor(seconds);
+    const hours = Math.floor(s / 3600);
+    const minutes = Math.floor((s % 3600) / 60);
+    const secs = s % 60;
+    if (hours > 0) {
+        return hours + 'h ' + minutes + 'm ' + secs + 's';
+    } else if (minutes > 0) {
+        return minutes + 'm ' + secs + 's';
+    }
+    return secs + 's';
+}
+
+// Format bytes
+function formatBytes(bytes) {
+    if (!bytes) return '-';
+    const sizes = ['B', 'KB', 'MB', 'GB', 'TB'];
+    if (bytes === 0) return '0 B';
+    const i = Math.floor(Math.log(bytes) / Math.log(1024));
+    return Math.round(bytes / Math.pow(1024, i) * 100) / 100 + ' ' + sizes[i];
+}
+
+// Load results list
+async function loadResultsList() {
+    try {
+        const response = await fetch('/api/results');
+        resultFiles = (await response.json()) || [];
+        document.getElementById('deleteResultBtn').style.display =
+            response.headers.get('X-Results-Retention') === 'enabled' ? '' : 'none';
+        const select = document.getElementById('resultSelect');
+        
+        if (resultFiles.length === 0) {
+            select.innerHTML = '<option value="">No results found</option>';
+            return;
+        }
+        
+        renderResultOptions();
+        
+        // Select latest by default
+        select.value = 'latest';
+        loadResultData('latest', true); // Use live endpoint for initial load
+    } catch (error) {
+        showError('Failed to load results list: ' + error.message);
+    }
+    loadSources();
+}
+
+// Delete the selected results file after confirmation
+async function deleteResult() {
+    const file = document.getElementById('resultSelect').value;
+    if (!file || file === 'latest') {
+        showError('Select a results file to delete');
+        return;
+    }
+    if (!confirm('Delete ' + file + ' with its sidecars and logs?')) {
+        return;
+    }
+    try {
+        const response = await fetch('/api/results/' + file, {method: 'DELETE'});
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        loadResultsList();
+    } catch (error) {
+        showError('Failed to delete ' + file + ': ' + error.message);
+    }
+}
+
+// Fill the results select with the files carrying the tags of the tag filter,
+// keeping the selection when it is still listed
+function renderResultOptions() {
+    const select = document.getElementById('resultSelect');
+    const selected = select.value;
+    const filters = document.getElementById('tagFilter').value.split(',').map(f => f.trim()).filter(f => f !== '');
+    select.innerHTML = '';
+    
+    // Add latest option
+    const latestOption = document.createElement('option');
+    latestOption.value = 'latest';
+    latestOption.textContent = 'Latest Results';
+    select.appendChild(latestOption);
+    
+    // Add individual files
+    resultFiles.filter(file => filters.every(filter => hasTag(file.tags, filter))).forEach(file => {
+        const option = document.createElement('option');
+        option.value = file.filename;
+        option.textContent = (file.source ? '[' + file.source + '] ' : '') + (file.label || file.mod_time_str) + ' - ' + file.result_count + ' results';
+        if (file.integrity && (file.integrity.status === 'modified' || file.integrity.status === 'invalid_signature')) {
+            option.textContent += ' ⚠ modified after run';
+        }
+        const tags = formatTags(file.tags);
+        if (tags) {
+            option.textContent += ' 🏷 ' + tags;
+        }
+        const title = [];
+        if (file.header && file.header.oc_mirror_version) {
+            title.push('oc-mirror ' + file.header.oc_mirror_version + (file.header.tool_version ? ', oc-mirror-test ' + file.header.tool_version : ''));
+        }
+        (file.notes || []).forEach(note => title.push('📝 ' + note.text));
+        option.title = title.join('\n');
+        select.appendChild(option);
+    });
+    if (Array.from(select.options).some(option => option.value === selected)) {
+        select.value = selected;
+    }
+}
+
+// Report whether tags carry the filter: key, or key=value
+function hasTag(tags, filter) {
+    const i = filter.indexOf('=');
+    const key = i < 0 ? filter : filter.slice(0, i);
+    return !!tags && key in tags && (i < 0 || tags[key] === filter.slice(i + 1));
+}
+
+// List tags as key=value, sorted by key
+function formatTags(tags) {
+    return Object.keys(tags || {}).sort().map(key => tags[key] ? key + '=' + tags[key] : key).join(', ');
+}
+
+// Show the tags and notes of the displayed results file; the panel is only
+// reloaded when another file is displayed, so a note being typed is kept
+async function updateAnnotations(file) {
+    const panel = document.getElementById('annotationsPanel');
+    if (!file) {
+        panel.style.display = 'none';
+        panel.dataset.file = '';
+        return;
+    }
+    if (panel.dataset.file === file) {
+        return;
+    }
+    panel.dataset.file = file;
+    try {
+        // The annotations are attached to the file, not to "latest"
+        const latest = resultFiles[resultFiles.length - 1];
+        const name = file === 'latest' ? (latest ? latest.filename : '') : file;
+        if (!name) {
+            throw new Error('no results file');
+        }
+        const response = await fetch('/api/annotations/' + name);
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        // The form is only offered when the server accepts updates
+        panel.dataset.editable = response.headers.get('X-Results-Annotations') === 'enabled' ? 'true' : '';
+        document.getElementById('annotationForm').style.display = panel.dataset.editable ? '' : 'none';
+        renderAnnotations(await response.json());
+        panel.style.display = 'block';
+    } catch (error) {
+        panel.style.display = 'none';
+        panel.dataset.file = '';
+    }
+}
+
+// Render the annotations of a file; tags added from the dashboard can be removed
+function renderAnnotations(data) {
+    const panel = document.getElementById('annotationsPanel');
+    panel.dataset.resolved = data.file;
+    const added = (data.added && data.added.tags) || {};
+
+    const tags = document.getElementById('annotationTags');
+    tags.innerHTML = '';
+    Object.keys(data.tags || {}).sort().forEach(key => {
+        const chip = document.createElement('span');
+        chip.className = 'tag-chip';
+        chip.textContent = data.tags[key] ? key + '=' + data.tags[key] : key;
+        if (key in added && panel.dataset.editable) {
+            const remove = document.createElement('button');
+            remove.type = 'button';
+            remove.textContent = '×';
+            remove.title = 'Remove the tag';
+            remove.addEventListener('click', () => saveAnnotations({remove_tags: [key]}));
+            chip.appendChild(remove);
+        } else {
+            chip.title = 'Given when the run started';
+        }
+        tags.appendChild(chip);
+    });
+    if (tags.children.length === 0) {
+        tags.textContent = 'No tags';
+    }
+
+    const notes = document.getElementById('annotationNotes');
+    notes.innerHTML = '';
+    (data.notes || []).forEach(note => {
+        const item = document.createElement('li');
+        item.textContent = note.text;
+        const meta = document.createElement('span');
+        meta.className = 'note-meta';
+        meta.textContent = (note.author ? note.author + ', ' : '') + new Date(note.created_at).toLocaleString();
+        item.appendChild(meta);
+        notes.appendChild(item);
+    });
+
+    // Keep the results list in step with the annotations
+    const file = resultFiles.find(f => f.filename === data.file);
+    if (file && (formatTags(file.tags) !== formatTags(data.tags) || (file.notes || []).length !== (data.notes || []).length)) {
+        file.tags = data.tags;
+        file.notes = data.notes;
+        renderResultOptions();
+    }
+}
+
+// Send an annotation update for the displayed file and show the result
+async function saveAnnotations(update) {
+    const panel = document.getElementById('annotationsPanel');
+    const message = document.getElementById('annotationMessage');
+    try {
+        const response = await fetch('/api/annotations/' + panel.dataset.resolved, {
+            method: 'POST',
+            headers: {'Content-Type': 'application/json'},
+            body: JSON.stringify(update),
+        });
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        renderAnnotations(await response.json());
+        message.textContent = 'Saved';
+        return true;
+    } catch (error) {
+        message.textContent = 'Failed to save: ' + error.message;
+        return false;
+    }
+}
+
+// Add the tags and note of the form
+async function submitAnnotations(event) {
+    event.preventDefault();
+    const tagInput = document.getElementById('annotationTagInput');
+    const noteInput = document.getElementById('annotationNoteInput');
+    const update = {tags: {}, note: noteInput.value.trim(), author: document.getElementById('annotationAuthor').value.trim()};
+    tagInput.value.split(',').map(tag => tag.trim()).filter(tag => tag !== '').forEach(tag => {
+        const i = tag.indexOf('=');
+        update.tags[(i < 0 ? tag : tag.slice(0, i)).trim()] = i < 0 ? '' : tag.slice(i + 1).trim();
+    });
+    if (update.note === '' && Object.keys(update.tags).length === 0) {
+        document.getElementById('annotationMessage').textContent = 'Enter tags or a note';
+        return;
+    }
+    if (await saveAnnotations(update)) {
+        tagInput.value = '';
+        noteInput.value = '';
+    }
+}
+
+// Report results roots that could not be listed
+async function loadSources() {
+    const div = document.getElementById('sourcesWarning');
+    try {
+        const response = await fetch('/api/sources');
+        const sources = await response.json();
+        const unavailable = sources.filter(source => !source.available);
+        if (unavailable.length === 0) {
+            div.style.display = 'none';
+            return;
+        }
+        div.textContent = '⚠ Results roots unavailable, their runs are not listed: ' +
+            unavailable.map(source => source.label + ' (' + source.location + ': ' + source.error + ')').join(', ');
+        div.style.display = 'block';
+    } catch (error) {
+        div.style.display = 'none';
+    }
+}
+
+// Load registry metrics
+async function loadRegistryMetrics() {
+    try {
+        const runId = document.getElementById('runSelect').value;
+        const response = await fetch('/api/registry' + (runId ? '?run=' + encodeURIComponent(runId) : ''));
+        if (!response.ok) {
+            // Registry monitor not available or not monitoring
+            document.getElementById('registryTotal').textContent = '-';
+            document.getElementById('registryAvg').textContent = '-';
+            document.getElementById('registryPeak').textContent = '-';
+            document.getElementById('registryConnections').textContent = '-';
+            return;
+        }
+        const data = await response.json();
+        if (data.monitoring && data.metrics) {
+            const metrics = data.metrics;
+            document.getElementById('registryTotal').textContent = formatBytes(metrics.TotalBytesUploaded || 0);
+            document.getElementById('registryAvg').textContent = (metrics.AverageUploadRateMB || 0).toFixed(2) + ' MB/s';
+            document.getElementById('registryPeak').textContent = (metrics.PeakUploadRateMB || 0).toFixed(2) + ' MB/s';
+            document.getElementById('registryConnections').textContent = metrics.ConnectionCount || 0;
+        } else {
+            document.getElementById('registryTotal').textContent = '-';
+            document.getElementById('registryAvg').textContent = '-';
+            document.getElementById('registryPeak').textContent = '-';
+            document.getElementById('registryConnections').textContent = '-';
+        }
+    } catch (error) {
+        // Silently fail - registry monitor may not be available
+        console.log('Registry metrics not available:', error);
+    }
+}
+
+// Load result data
+async function loadResultData(filename, useLive = false) {
+    const loading = document.getElementById('loading');
+    const content = document.getElementById('content');
+    const errorDiv = document.getElementById('error');
+    const statusDiv = document.getElementById('status');
+    const statusText = document.getElementById('statusText');
+    
+    // Use live endpoint for latest when auto-refresh is on or explicitly requested
+    const useLiveEndpoint = useLive || (filename === 'latest' && autoRefreshInterval !== null);
+    
+    if (useLiveEndpoint && filename === 'latest') {
+        statusDiv.style.display = 'block';
+        statusText.textContent = '🔄 Live monitoring active - Refreshing every 2 seconds...';
+        // Also load registry metrics when in live mode
+        loadRegistryMetrics();
+    } else {
+        statusDiv.style.display = 'none';
+    }
+    
+    loading.style.display = 'block';
+    content.style.display = 'none';
+    errorDiv.style.display = 'none';
+    document.getElementById('compareView').style.display = 'none';
+    
+    try {
+        // A run picked in the run selector is followed by ID, so concurrent runs do not mix
+        const runId = document.getElementById('runSelect').value;
+        const liveURL = runId ? '/api/runs/' + encodeURIComponent(runId) + '/live' : '/api/live';
+        const url = useLiveEndpoint && filename === 'latest' ? liveURL :
+                   (filename === 'latest' ? '/api/latest' : '/api/results/' + filename);
+        const response = await fetch(url);
+        if (!response.ok) {
+            if (response.status === 404 && filename === 'latest') {
+                // No results yet, show waiting message
+                loading.textContent = '⏳ Waiting for test results to be generated...';
+                statusText.textContent = '⏳ Waiting for test execution to start...';
+                return;
+            }
+            throw new Error('Failed to load result data');
+        }
+        showIntegrityWarning(response.headers.get('X-Result-Integrity'), response.headers.get('X-Result-Integrity-Message'));
+        const data = await response.json();
+        // While following a run, /api/live also carries the recent monitor samples
+        const results = Array.isArray(data) ? data : data.results;
+        displayLiveSamples(Array.isArray(data) ? null : data.live);
+        if (results && results.length > 0) {
+            displayResults(results);
+            // A followed run may not have written the latest results file
+            updateTimeSeries(runId && useLiveEndpoint ? null : filename, results);
+            updateAnnotations(runId && useLiveEndpoint ? null : filename);
+            loading.style.display = 'none';
+            content.style.display = 'block';
+            if (useLiveEndpoint) {
+                statusText.textContent = '✅ Live monitoring active - Latest results displayed';
+            } else {
+                statusDiv.style.display = 'none';
+            }
+        } else {
+            // No results yet, keep loading state
+            loading.textContent = '⏳ Waiting for test results...';
+            statusText.textContent = '⏳ Waiting for test execution to complete...';
+        }
+    } catch (error) {
+        loading.style.display = 'none';
+        if (error.message.includes('Failed to load') || error.message.includes('404')) {
+            // No results file yet, show waiting message
+            showError('⏳ Waiting for test results to be generated...');
+            statusText.textContent = '⏳ Waiting for test execution to start...';
+        } else {
+            showError('Failed to load result data: ' + error.message);
+            statusDiv.style.display = 'none';
+        }
+    }
+}
+
+// Show the latest sample of each monitor from the live buffer of the followed run
+function displayLiveSamples(live) {
+    const panel = document.getElementById('liveSamples');
+    panel.innerHTML = '';
+    if (!live || !live.samples || Object.keys(live.samples).length === 0) {
+        panel.style.display = 'none';
+        return;
+    }
+    if (live.phase && live.phase.data) {
+        const phase = live.phase.data;
+        const line = document.createElement('div');
+        line.textContent = 'Phase: ' + phase.phase + (phase.version ? ' (' + phase.version + ', iteration ' + phase.iteration + ')' : '');
+        panel.appendChild(line);
+    }
+    Object.keys(live.samples).sort().forEach(source => {
+        const samples = live.samples[source];
+        const latest = samples[samples.length - 1].data || {};
+        const values = Object.keys(latest).filter(key => typeof latest[key] === 'number').map(key => {
+            const value = latest[key];
+            if (key === 'ETASeconds') {
+                return 'ETA ' + formatDuration(value);
+            }
+            if (/Bytes|RSS|VMS/.test(key)) {
+                return key + ' ' + formatBytes(value);
+            }
+            return key + ' ' + (Number.isInteger(value) ? value : value.toFixed(2));
+        });
+        const line = document.createElement('div');
+        line.textContent = source + ' (' + samples.length + '/' + live.size + ' samples): ' + values.join(', ');
+        panel.appendChild(line);
+    });
+    panel.style.display = 'block';
+}
+
+// Show a warning when the results file failed checksum or signature verification
+function showIntegrityWarning(status, message) {
+    const warning = document.getElementById('integrityWarning');
+    if (status === 'modified' || status === 'invalid_signature') {
+        warning.textContent = '⚠ This results file failed integrity verification (' + status + ')' +
+            (message ? ': ' + message : '') + '. It may have been modified after the run.';
+        warning.style.display = 'block';
+    } else {
+        warning.style.display = 'none';
+    }
+}
+
+// Display results
+function displayResults(results) {
+    if (!results || results.length === 0) {
+        showError('No results found');
+        return;
+    }
+    
+    // Aggregate metrics from all iterations
+    let totalDownloadTime = 0;
+    let totalUploadTime = 0;
+    let totalDownloaded = 0;
+    let totalUploaded = 0;
+    let totalCacheHits = 0;
+    let totalImagesSkipped = 0;
+    let totalErrors = 0;
+    let errorCategories = {};
+    let totalRetries = 0;
+    
+    let cpuAvgSum = 0;
+    let cpuPeakMax = 0;
+    let memAvgSum = 0;
+    let memPeakMax = 0;
+    let netAvgSum = 0;
+    let netPeakMax = 0;
+    let netTotalSum = 0;
+    
+    let avgSpeedSum = 0;
+    let peakSpeedMax = 0;
+    
+    let totalImages = 0;
+    let totalLayers = 0;
+    let totalManifests = 0;
+    let totalFiles = 0;
+    
+    let speedData = [];
+    let resourceData = [];
+    let networkData = [];
+    
+    results.forEach((result, index) => {
+        // Timing
+        const downloadTime = result.download_phase.wall_time_seconds || 0;
+        const uploadTime = result.upload_phase.wall_time_seconds || 0;
+        totalDownloadTime += downloadTime;
+        totalUploadTime += uploadTime;
+        
+        // Data transfer
+        const downloaded = result.download_phase.download_metrics?.TotalBytesDownloaded || 0;
+        const uploaded = result.upload_phase.bytes_uploaded || 0;
+        totalDownloaded += downloaded;
+        totalUploaded += uploaded;
+        
+        // Speed
+        const avgSpeed = result.download_phase.download_metrics?.AverageSpeedMBs || 0;
+        const peakSpeed = result.download_phase.download_metrics?.PeakSpeedMBs || 0;
+        avgSpeedSum += avgSpeed;
+        if (peakSpeed > peakSpeedMax) peakSpeedMax = peakSpeed;
+        
+        // Resources
+        const cpuAvg = result.resource_metrics?.CPUAvgPercent || 0;
+        const cpuPeak = result.resource_metrics?.CPUPeakPercent || 0;
+        const memAvg = result.resource_metrics?.MemoryAvgMB || 0;
+        const memPeak = result.resource_metrics?.MemoryPeakMB || 0;
+        cpuAvgSum += cpuAvg;
+        if (cpuPeak > cpuPeakMax) cpuPeakMax = cpuPeak;
+        memAvgSum += memAvg;
+        if (memPeak > memPeakMax) memPeakMax = memPeak;
+        
+        // Network
+        const netAvg = result.network_metrics?.AverageBandwidthMbps || 0;
+        const netPeak = result.network_metrics?.PeakBandwidthMbps || 0;
+        const netTotal = result.network_metrics?.TotalBytesTransferred || 0;
+        netAvgSum += netAvg;
+        if (netPeak > netPeakMax) netPeakMax = netPeak;
+        netTotalSum += netTotal;
+        
+        // Cache & performance
+        totalCacheHits += result.download_phase.cache_hits || 0;
+        totalImagesSkipped += result.download_phase.images_skipped || 0;
+        totalErrors += (result.download_phase.extended_metrics?.ErrorCount || 0) + 
+                      (result.upload_phase.extended_metrics?.ErrorCount || 0);
+        [result.download_phase.extended_metrics, result.upload_phase.extended_metrics].forEach(m => {
+            Object.entries(m?.ErrorCategories || {}).forEach(([category, n]) => {
+                errorCategories[category] = (errorCategories[category] || 0) + n;
+            });
+        });
+        totalRetries += (result.download_phase.extended_metrics?.RetryCount || 0) + 
+                       (result.upload_phase.extended_metrics?.RetryCount || 0);
+        
+        // Mirror content (use first result with describe metrics)
+        if (result.describe_metrics && totalImages === 0) {
+            totalImages = result.describe_metrics.TotalImages || 0;
+            totalLayers = result.describe_metrics.TotalLayers || 0;
+            totalManifests = result.describe_metrics.TotalManifests || 0;
+        }
+        
+        if (result.output_metrics && totalFiles === 0) {
+            totalFiles = result.output_metrics.TotalFiles || 0;
+        }
+        
+        // Chart data
+        speedData.push({
+            x: 'Iteration ' + result.iteration,
+            avg: avgSpeed,
+            peak: peakSpeed
+        });
+        
+        resourceData.push({
+            x: 'Iteration ' + result.iteration,
+            cpu: cpuAvg,
+            mem: memAvg
+        });
+        
+        networkData.push({
+            x: 'Iteration ' + result.iteration,
+            avg: netAvg,
+            peak: netPeak
+        });
+    });
+    
+    const count = results.length;
+    
+    // Update metrics display
+    document.getElementById('downloadTime').textContent = formatDuration(totalDownloadTime / count);
+    document.getElementById('uploadTime').textContent = formatDuration(totalUploadTime / count);
+    document.getElementById('totalTime').textContent = formatDuration((totalDownloadTime + totalUploadTime) / count);
+    
+    document.getElementById('downloaded').textContent = formatBytes(totalDownloaded);
+    document.getElementById('uploaded').textContent = formatBytes(totalUploaded);
+    document.getElementById('avgSpeed').textContent = (avgSpeedSum / count).toFixed(2) + ' MB/s';
+    document.getElementById('peakSpeed').textContent = peakSpeedMax.toFixed(2) + ' MB/s';
+    
+    document.getElementById('cpuAvg').textContent = (cpuAvgSum / count).toFixed(2) + '%';
+    document.getElementById('cpuPeak').textContent = cpuPeakMax.toFixed(2) + '%';
+    document.getElementById('memAvg').textContent = (memAvgSum / count).toFixed(2) + ' MB';
+    document.getElementById('memPeak').textContent = memPeakMax.toFixed(2) + ' MB';
+    
+    document.getElementById('netAvg').textContent = (netAvgSum / count).toFixed(2) + ' Mbps';
+    document.getElementById('netPeak').textContent = netPeakMax.toFixed(2) + ' Mbps';
+    document.getElementById('netTotal').textContent = formatBytes(netTotalSum);
+    
+    document.getElementById('images').textContent = totalImages;
+    document.getElementById('layers').textContent = totalLayers;
+    document.getElementById('manifests').textContent = totalManifests;
+    document.getElementById('files').textContent = totalFiles;
+    
+    document.getElementById('cacheHits').textContent = totalCacheHits;
+    document.getElementById('imagesSkipped').textContent = totalImagesSkipped;
+    document.getElementById('errors').textContent = totalErrors;
+    document.getElementById('errorTypes').textContent = formatErrorCategories(errorCategories);
+    document.getElementById('retries').textContent = totalRetries;
+    
+    // Update charts
+    updateCharts(speedData, resourceData, networkData);
+    updateRetryChart(results);
+    updatePackageChart(results);
+    
+    // Display iterations
+    displayIterations(results);
+}
+
+// Format error counts per category, most frequent first
+function formatErrorCategories(categories) {
+    const entries = Object.entries(categories).filter(([, n]) => n > 0);
+    if (entries.length === 0) return '-';
+    entries.sort((a, b) => b[1] - a[1]);
+    return entries.map(([category, n]) => category.replace('_', ' ') + ' ' + n).join(', ');
+}
+
+// Update charts
+function updateCharts(speedData, resourceData, networkData) {
+    // Speed chart
+    const speedCtx = document.getElementById('speedChart').getContext('2d');
+    if (speedChart) speedChart.destroy();
+    speedChart = new Chart(speedCtx, {
+        type: 'bar',
+        data: {
+            labels: speedData.map(d => d.x),
+            datasets: [{
+                label: 'Avg Speed (MB/s)',
+                data: speedData.map(d => d.avg),
+                backgroundColor: 'rgba(102, 126, 234, 0.6)'
+            }, {
+                label: 'Peak Speed (MB/s)',
+                data: speedData.map(d => d.peak),
+                backgroundColor: 'rgba(118, 75, 162, 0.6)'
+            }]
+        },
+        options: {
+            responsive: true,
+            maintainAspectRatio: false,
+            scales: {
+                y: { beginAtZero: true }
+            }
+        }
+    });
+    
+    // Resource chart
+    const resourceCtx = document.getElementById('resourceChart').getContext('2d');
+    if (resourceChart) resourceChart.destroy();
+    resourceChart = new Chart(resourceCtx, {
+        type: 'line',
+        data: {
+            labels: resourceData.map(d => d.x),
+            datasets: [{
+                label: 'CPU Avg (%)',
+                data: resourceData.map(d => d.cpu),
+                borderColor: 'rgb(102, 126, 234)',
+                backgroundColor: 'rgba(102, 126, 234, 0.1)',
+                tension: 0.4
+            }, {
+                label: 'Memory Avg (MB)',
+                data: resourceData.map(d => d.mem),
+                borderColor: 'rgb(118, 75, 162)',
+                backgroundColor: 'rgba(118, 75, 162, 0.1)',
+                tension: 0.4,
+                yAxisID: 'y1'
+            }]
+        },
+        options: {
+            responsive: true,
+            maintainAspectRatio: false,
+            scales: {
+                y: { beginAtZero: true },
+                y1: { beginAtZero: true, position: 'right' }
+            }
+        }
+    });
+    
+    // Network chart
+    const networkCtx = document.getElementById('networkChart').getContext('2d');
+    if (networkChart) networkChart.destroy();
+    networkChart = new Chart(networkCtx, {
+        type: 'bar',
+        data: {
+            labels: networkData.map(d => d.x),
+            datasets: [{
+                label: 'Avg Bandwidth (Mbps)',
+                data: networkData.map(d => d.avg),
+                backgroundColor: 'rgba(72, 187, 120, 0.6)'
+            }, {
+                label: 'Peak Bandwidth (Mbps)',
+                data: networkData.map(d => d.peak),
+                backgroundColor: 'rgba(245, 101, 101, 0.6)'
+            }]
+        },
+        options: {
+            responsive: true,
+            maintainAspectRatio: false,
+            scales: {
+                y: { beginAtZero: true }
+            }
+        }
+    });
+}
+
+// Retry timeline chart: retries by source against throughput for the latest iteration with retries
+function updateRetryChart(results) {
+    const container = document.getElementById('retryChartContainer');
+    if (retryChart) {
+        retryChart.destroy();
+        retryChart = null;
+    }
+
+    const result = results.slice().reverse().find(r =>
+        r.download_phase.retry_timeline || r.upload_phase.retry_timeline);
+    if (!result) {
+        container.style.display = 'none';
+        return;
+    }
+    container.style.display = '';
+
+    const buckets = [];
+    [['Download', result.download_phase.retry_timeline], ['Upload', result.upload_phase.retry_timeline]].forEach(([phase, timeline]) => {
+        if (!timeline) return;
+        (timeline.Buckets || []).forEach(b => buckets.push({
+            label: phase[0] + ' +' + b.OffsetSeconds + 's',
+            phase: phase,
+            bucket: b
+        }));
+    });
+
+    const ctx = document.getElementById('retryChart').getContext('2d');
+    retryChart = new Chart(ctx, {
+        type: 'bar',
+        data: {
+            labels: buckets.map(d => d.label),
+            datasets: [{
+                label: 'Registry retries',
+                data: buckets.map(d => d.bucket.Registry),
+                backgroundColor: 'rgba(245, 101, 101, 0.7)',
+                stack: 'retries'
+            }, {
+                label: 'Upstream retries',
+                data: buckets.map(d => d.bucket.Upstream),
+                backgroundColor: 'rgba(237, 137, 54, 0.7)',
+                stack: 'retries'
+            }, {
+                label: 'Unattributed retries',
+                data: buckets.map(d => d.bucket.Unknown),
+                backgroundColor: 'rgba(160, 174, 192, 0.7)',
+                stack: 'retries'
+            }, {
+                type: 'line',
+                label: 'Throughput (MB/s)',
+                data: buckets.map(d => d.bucket.ThroughputMBs),
+                borderColor: 'rgb(102, 126, 234)',
+                backgroundColor: 'rgba(102, 126, 234, 0.1)',
+                tension: 0.3,
+                yAxisID: 'y1'
+            }]
+        },
+        options: {
+            responsive: true,
+            maintainAspectRatio: false,
+            plugins: {
+                title: {
+                    display: true,
+                    text: 'Retries vs Throughput - Iteration ' + result.iteration + ' (' + result.version + ')'
+                },
+                tooltip: {
+                    callbacks: {
+                        afterBody: items => {
+                            const d = buckets[items[0].dataIndex];
+                            const lines = [];
+                            if (d.bucket.Throttled > 0) lines.push('Throttled (429): ' + d.bucket.Throttled);
+                            (d.bucket.Images || []).forEach(img => lines.push(img));
+                            return lines;
+                        }
+                    }
+                }
+            },
+            scales: {
+                x: { stacked: true },
+                y: { beginAtZero: true, stacked: true, title: { display: true, text: 'Retries' } },
+                y1: { beginAtZero: true, position: 'right', grid: { drawOnChartArea: false }, title: { display: true, text: 'MB/s' } }
+            }
+        }
+    });
+}
+
+// Operator package chart: bytes only each package references, stacked with
+// the bytes shared by several packages, per iteration
+function updatePackageChart(results) {
+    const container = document.getElementById('packageChartContainer');
+    if (packageChart) {
+        packageChart.destroy();
+        packageChart = null;
+    }
+
+    const measured = results.filter(r => r.package_sizes && !r.warmup);
+    if (measured.length === 0) {
+        container.style.display = 'none';
+        return;
+    }
+    container.style.display = '';
+
+    // The largest packages get their own segment, the rest are summed
+    const largest = {};
+    measured.forEach(r => (r.package_sizes.packages || []).forEach(p => {
+        largest[p.package] = Math.max(largest[p.package] || 0, p.unique_bytes);
+    }));
+    const names = Object.keys(largest).sort((a, b) => largest[b] - largest[a]);
+    const shown = names.slice(0, 8);
+    const gb = bytes => bytes / (1024 * 1024 * 1024);
+    const colors = ['102, 126, 234', '72, 187, 120', '237, 137, 54', '245, 101, 101',
+        '159, 122, 234', '56, 178, 172', '236, 201, 75', '237, 100, 166'];
+
+    const datasets = shown.map((name, i) => ({
+        label: name,
+        data: measured.map(r => {
+            const p = (r.package_sizes.packages || []).find(p => p.package === name);
+            return p ? gb(p.unique_bytes) : 0;
+        }),
+        backgroundColor: 'rgba(' + colors[i % colors.length] + ', 0.7)',
+        stack: 'packages'
+    }));
+    if (names.length > shown.length) {
+        datasets.push({
+            label: 'Other packages',
+            data: measured.map(r => gb((r.package_sizes.packages || [])
+                .filter(p => !shown.includes(p.package))
+                .reduce((sum, p) => sum + p.unique_bytes, 0))),
+            backgroundColor: 'rgba(113, 128, 150, 0.7)',
+            stack: 'packages'
+        });
+    }
+    datasets.push({
+        label: 'Shared by several packages',
+        data: measured.map(r => gb(r.package_sizes.shared_bytes)),
+        backgroundColor: 'rgba(160, 174, 192, 0.7)',
+        stack: 'packages'
+    });
+
+    const ctx = document.getElementById('packageChart').getContext('2d');
+    packageChart = new Chart(ctx, {
+        type: 'bar',
+        data: {
+            labels: measured.map(r => 'Iter ' + r.iteration + ' (' + r.version + ')'),
+            datasets: datasets
+        },
+        options: {
+            responsive: true,
+            maintainAspectRatio: false,
+            plugins: {
+                title: {
+                    display: true,
+                    text: 'Mirrored Size by Operator Package'
+                },
+                tooltip: {
+                    callbacks: {
+                        label: item => item.dataset.label + ': ' + item.parsed.y.toFixed(2) + ' GB'
+                    }
+                }
+            },
+            scales: {
+                x: { stacked: true },
+                y: { beginAtZero: true, stacked: true, title: { display: true, text: 'GB' } }
+            }
+        }
+    });
+}
+
+// Fill the iteration picker of the sample charts; the series are fetched
+// again when the file or its number of iterations changes
+function updateTimeSeries(file, results) {
+    const section = document.getElementById('timeseriesSection');
+    if (!file) {
+        section.style.display = 'none';
+        return;
+    }
+    section.style.display = '';
+
+    const select = document.getElementById('timeseriesIteration');
+    const key = file + ':' + results.length;
+    if (select.dataset.key === key) {
+        return;
+    }
+    const previous = select.dataset.file === file ? select.value : '';
+    select.innerHTML = '';
+    results.forEach((r, i) => {
+        const option = document.createElement('option');
+        option.value = i;
+        option.textContent = 'Iteration ' + r.iteration + ' (' + r.version + ', ' +
+            (r.is_clean_run ? 'clean' : (r.is_update_run ? 'update' : 'cached')) + ')';
+        select.appendChild(option);
+    });
+    select.value = previous !== '' ? previous : String(results.length - 1);
+    select.dataset.key = key;
+    select.dataset.file = file;
+    loadTimeSeries();
+}
+
+// Fetch the sample series of the picked iteration
+async function loadTimeSeries() {
+    const select = document.getElementById('timeseriesIteration');
+    const note = document.getElementById('timeseriesNote');
+    try {
+        const response = await fetch('/api/samples/' + select.dataset.file + '?index=' + select.value);
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        renderTimeSeries(await response.json());
+        note.textContent = 'Scroll or drag to zoom, shift+drag to pan';
+    } catch (error) {
+        note.textContent = 'Failed to load the samples: ' + error.message;
+    }
+}
+
+// Chart the transfer rates, and the memory and CPU of oc-mirror, over the
+// seconds since the first sample of the iteration
+function renderTimeSeries(data) {
+    const colors = {
+        download_rate: '102, 126, 234', upload_rate: '72, 187, 120', layout_write_rate: '56, 178, 172',
+        download_total: '160, 174, 192', download_memory: '102, 126, 234', upload_memory: '72, 187, 120',
+        download_cpu: '237, 137, 54', upload_cpu: '245, 101, 101'
+    };
+    const dataset = (s, axis) => ({
+        label: s.label + (s.name.endsWith('_memory') || s.name.endsWith('_cpu') ? ' (' + s.phase + ')' : '') + ' [' + s.unit + ']',
+        data: s.points.map(p => ({ x: p.t, y: p.v })),
+        borderColor: 'rgb(' + (colors[s.name] || '113, 128, 150') + ')',
+        backgroundColor: 'rgba(' + (colors[s.name] || '113, 128, 150') + ', 0.1)',
+        borderDash: axis === 'y1' ? [6, 3] : [],
+        pointRadius: 0,
+        borderWidth: 1.5,
+        yAxisID: axis
+    });
+    const speed = data.series.filter(s => s.unit === 'MB/s').map(s => dataset(s, 'y'))
+        .concat(data.series.filter(s => s.name === 'download_total').map(s => dataset(s, 'y1')));
+    const memory = data.series.filter(s => s.name.endsWith('_memory')).map(s => dataset(s, 'y'))
+        .concat(data.series.filter(s => s.name.endsWith('_cpu')).map(s => dataset(s, 'y1')));
+
+    if (speedTimeChart) {
+        speedTimeChart.destroy();
+    }
+    if (memoryTimeChart) {
+        memoryTimeChart.destroy();
+    }
+    const title = 'Iteration ' + data.iteration + ' (' + data.version + ')';
+    speedTimeChart = new Chart(document.getElementById('speedTimeChart').getContext('2d'), {
+        type: 'line',
+        data: { datasets: speed },
+        options: timeSeriesOptions('Speed over Time - ' + title, 'MB/s', 'MB downloaded')
+    });
+    memoryTimeChart = new Chart(document.getElementById('memoryTimeChart').getContext('2d'), {
+        type: 'line',
+        data: { datasets: memory },
+        options: timeSeriesOptions('oc-mirror Memory over Time - ' + title, 'MB', 'CPU %')
+    });
+}
+
+// Options of a sample chart: seconds on x, a value axis on each side, and
+// zooming and panning on x, kept in step between the two charts
+function timeSeriesOptions(title, leftAxis, rightAxis) {
+    const sync = ({ chart }) => {
+        const other = chart === speedTimeChart ? memoryTimeChart : speedTimeChart;
+        if (other && other.zoomScale) {
+            other.zoomScale('x', { min: chart.scales.x.min, max: chart.scales.x.max }, 'none');
+        }
+    };
+    return {
+        responsive: true,
+        maintainAspectRatio: false,
+        animation: false,
+        parsing: false,
+        interaction: { mode: 'nearest', axis: 'x', intersect: false },
+        plugins: {
+            title: { display: true, text: title },
+            tooltip: {
+                callbacks: {
+                    title: items => items.length ? formatDuration(items[0].parsed.x) + ' (' + items[0].parsed.x.toFixed(1) + 's)' : ''
+                }
+            },
+            zoom: {
+                zoom: {
+                    wheel: { enabled: true },
+                    pinch: { enabled: true },
+                    drag: { enabled: true },
+                    mode: 'x',
+                    onZoomComplete: sync
+                },
+                pan: { enabled: true, mode: 'x', modifierKey: 'shift', onPanComplete: sync }
+            }
+        },
+        scales: {
+            x: { type: 'linear', title: { display: true, text: 'Seconds since the first sample' } },
+            y: { beginAtZero: true, position: 'left', title: { display: true, text: leftAxis } },
+            y1: { beginAtZero: true, position: 'right', grid: { drawOnChartArea: false }, title: { display: true, text: rightAxis } }
+        }
+    };
+}
+
+// Open the comparison view with the listed results files, the previous run
+// against the latest by default; auto-refresh is stopped so it does not
+// replace the view
+function openCompare() {
+    if (autoRefreshInterval) {
+        toggleAutoRefresh();
+    }
+    const files = Array.from(document.getElementById('resultSelect').options)
+        .filter(o => o.value && o.value !== 'latest');
+    ['compareA', 'compareB'].forEach((id, i) => {
+        const select = document.getElementById(id);
+        select.innerHTML = '';
+        files.forEach(o => {
+            const option = document.createElement('option');
+            option.value = o.value;
+            option.textContent = o.textContent;
+            select.appendChild(option);
+        });
+        if (files.length > 0) {
+            select.value = files[Math.max(0, files.length - 2 + i)].value;
+        }
+    });
+    document.getElementById('loading').style.display = 'none';
+    document.getElementById('error').style.display = 'none';
+    document.getElementById('content').style.display = 'none';
+    document.getElementById('compareView').style.display = 'block';
+}
+
+// Return to the results picked in the results list
+function closeCompare() {
+    document.getElementById('compareView').style.display = 'none';
+    loadResultData(document.getElementById('resultSelect').value || 'latest');
+}
+
+// Fetch the comparison of the picked files
+async function runCompare() {
+    const summary = document.getElementById('compareSummary');
+    const params = new URLSearchParams({
+        a: document.getElementById('compareA').value,
+        b: document.getElementById('compareB').value
+    });
+    ['A', 'B'].forEach(side => {
+        const version = document.getElementById('compareVersion' + side).value;
+        if (version) {
+            params.set('version_' + side.toLowerCase(), version);
+        }
+    });
+    try {
+        const response = await fetch('/api/compare?' + params.toString());
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        renderComparison(await response.json());
+    } catch (error) {
+        summary.textContent = 'Comparison failed: ' + error.message;
+    }
+}
+
+// Format a comparison value in its unit
+function formatComparisonValue(metric, value) {
+    switch (metric.unit) {
+    case 'seconds':
+        return value >= 60 ? formatDuration(value) : value.toFixed(2) + 's';
+    case 'bytes':
+        return value ? formatBytes(value) : '0 B';
+    case 'count':
+        return String(Math.round(value));
+    case '%':
+        return value.toFixed(2) + '%';
+    }
+    return value.toFixed(2) + ' ' + metric.unit;
+}
+
+// Whether B is better or worse than A for a metric, empty when neither
+function comparisonOutcome(metric) {
+    if (!metric.better || metric.a === metric.b) {
+        return '';
+    }
+    return (metric.b < metric.a) === (metric.better === 'lower') ? 'better' : 'worse';
+}
+
+// Render the cards of each section side by side, the timings of both sides
+// and the change of every metric where one value is better
+function renderComparison(c) {
+    const describe = side => side.label + ': iteration ' + side.iteration + (side.clean ? ' (clean)' : ' (cached)') +
+        (side.cached_iteration ? ', cached iteration ' + side.cached_iteration : '');
+    document.getElementById('compareSummary').textContent =
+        'A = ' + describe(c.a) + ' | B = ' + describe(c.b) + (c.notes ? ' | ' + c.notes.join('; ') : '');
+
+    const cards = document.getElementById('compareCards');
+    cards.innerHTML = '';
+    const sections = [];
+    c.metrics.forEach(m => {
+        if (!sections.includes(m.section)) {
+            sections.push(m.section);
+        }
+    });
+    sections.forEach(section => {
+        const card = document.createElement('div');
+        card.className = 'compare-card';
+        const title = document.createElement('h3');
+        title.textContent = section;
+        card.appendChild(title);
+        const table = document.createElement('table');
+        const header = table.insertRow();
+        ['Metric', 'A', 'B', 'Change'].forEach(text => {
+            const th = document.createElement('th');
+            th.textContent = text;
+            header.appendChild(th);
+        });
+        c.metrics.filter(m => m.section === section).forEach(m => {
+            const row = table.insertRow();
+            row.insertCell().textContent = m.name;
+            row.insertCell().textContent = formatComparisonValue(m, m.a);
+            row.insertCell().textContent = formatComparisonValue(m, m.b);
+            const change = row.insertCell();
+            change.textContent = m.a ? (m.delta_percent >= 0 ? '+' : '') + m.delta_percent.toFixed(1) + '%' : '-';
+            change.className = comparisonOutcome(m);
+        });
+        card.appendChild(table);
+        cards.appendChild(card);
+    });
+
+    if (compareTimingChart) {
+        compareTimingChart.destroy();
+    }
+    const timing = c.metrics.filter(m => m.section === 'Timing');
+    compareTimingChart = new Chart(document.getElementById('compareTimingChart').getContext('2d'), {
+        type: 'bar',
+        data: {
+            labels: timing.map(m => m.name),
+            datasets: [
+                { label: 'A: ' + c.a.label, data: timing.map(m => m.a), backgroundColor: 'rgba(102, 126, 234, 0.7)' },
+                { label: 'B: ' + c.b.label, data: timing.map(m => m.b), backgroundColor: 'rgba(72, 187, 120, 0.7)' }
+            ]
+        },
+        options: {
+            responsive: true,
+            maintainAspectRatio: false,
+            plugins: { title: { display: true, text: 'Timing' } },
+            scales: { y: { beginAtZero: true, title: { display: true, text: 'Seconds' } } }
+        }
+    });
+
+    if (compareDeltaChart) {
+        compareDeltaChart.destroy();
+    }
+    const deltas = c.metrics.filter(m => m.better && m.a);
+    compareDeltaChart = new Chart(document.getElementById('compareDeltaChart').getContext('2d'), {
+        type: 'bar',
+        data: {
+            labels: deltas.map(m => m.name),
+            datasets: [{
+                label: 'Change from A to B (%)',
+                data: deltas.map(m => m.delta_percent),
+                backgroundColor: deltas.map(m => comparisonOutcome(m) === 'worse' ? 'rgba(245, 101, 101, 0.7)' : 'rgba(72, 187, 120, 0.7)')
+            }]
+        },
+        options: {
+            indexAxis: 'y',
+            responsive: true,
+            maintainAspectRatio: false,
+            plugins: {
+                title: { display: true, text: 'Change from A to B (green is better)' },
+                legend: { display: false }
+            },
+            scales: { x: { title: { display: true, text: '%' } } }
+        }
+    });
+}
+
+// Display iterations
+function displayIterations(results) {
+    const container = document.getElementById('iterations');
+    container.innerHTML = '<h2>Iterations</h2>';
+    
+    results.forEach(result => {
+        const card = document.createElement('div');
+        card.className = 'iteration-card';
+        
+        const badges = [];
+        if (result.is_clean_run) {
+            badges.push('<span class="badge clean">CLEAN</span>');
+        } else if (result.is_update_run) {
+            badges.push('<span class="badge update">UPDATE</span>');
+        } else {
+            badges.push('<span class="badge cached">CACHED</span>');
+        }
+        badges.push('<span class="badge ' + result.version + '">' + result.version.toUpperCase() + '</span>');
+        if (result.warmup) {
+            badges.push('<span class="badge warmup">WARM-UP</span>');
+        }
+        if (result.stage) {
+            badges.push('<span class="badge stage">STAGE ' + result.stage.index + '/' + result.stage.count + '</span>');
+        }
+        if (result.failed) {
+            badges.push('<span class="badge failed">FAILED</span>');
+        } else if (result.failed_attempts && result.failed_attempts.length > 0) {
+            badges.push('<span class="badge retried">RETRIED ' + result.failed_attempts.length + '×</span>');
+        }
+        if (!result.failed && result.failure_reasons && result.failure_reasons.length > 0) {
+            badges.push('<span class="badge failed">GATES FAILED</span>');
+        }
+        const httpStatus = result.upload_phase.http_status;
+        if (httpStatus && (httpStatus.Throttled > 0 || httpStatus.ServerErrors > 0)) {
+            badges.push('<span class="badge throttled">429/5xx ' + (httpStatus.Throttled + httpStatus.ServerErrors) + '×</span>');
+        }
+        
+        card.innerHTML = 
+            '<h4>Iteration ' + result.iteration + ' ' + badges.join(' ') + '</h4>' +
+            '<div class="metric-item"><span class="label">Download:</span><span class="value">' + formatDuration(result.download_phase.wall_time_seconds) + '</span></div>' +
+            '<div class="metric-item"><span class="label">Upload:</span><span class="value">' + formatDuration(result.upload_phase.wall_time_seconds) + '</span></div>' +
+            '<div class="metric-item"><span class="label">Downloaded:</span><span class="value">' + formatBytes(result.download_phase.download_metrics?.TotalBytesDownloaded) + '</span></div>' +
+            '<div class="metric-item"><span class="label">Cache Hits:</span><span class="value">' + (result.download_phase.cache_hits || 0) + '</span></div>';
+        const cache = result.download_phase.cache_metrics;
+        if (cache) {
+            card.innerHTML += '<div class="metric-item"><span class="label">Cache:</span><span class="value">' +
+                formatBytes(cache.After.SizeBytes) + ' (+' + formatBytes(Math.max(cache.GrowthBytes, 0)) + ')' +
+                (cache.HitRatio !== undefined && cache.HitRatio !== null ? ', ' + (cache.HitRatio * 100).toFixed(1) + '% hit ratio' : '') + '</span></div>';
+        }
+        if (result.disk_usage_bytes) {
+            card.innerHTML += '<div class="metric-item"><span class="label">Disk Usage:</span><span class="value">' + formatBytes(result.disk_usage_bytes) + '</span></div>';
+        }
+        const clusterResources = result.upload_phase.cluster_resources;
+        if (clusterResources) {
+            // GenerationTime is a Go duration in nanoseconds
+            card.innerHTML += '<div class="metric-item"><span class="label">Cluster Resources:</span><span class="value">' +
+                (clusterResources.GenerationTime / 1e9).toFixed(1) + 's, ' + clusterResources.TotalFiles + ' files, ' +
+                formatBytes(clusterResources.TotalBytes) + '</span></div>';
+        }
+        const airGap = result.air_gap;
+        if (airGap) {
+            // Durations are Go durations in nanoseconds
+            card.innerHTML += '<div class="metric-item"><span class="label">Air-Gap:</span><span class="value">' +
+                formatBytes(airGap.archive.TotalBytes) + ' archive in ' + formatDuration(airGap.archive.CreationTime / 1e9) + ', transfer ' +
+                formatDuration(airGap.transfer_time_seconds / 1e9) + ' (' + (airGap.transfer_rate_mbs || 0).toFixed(1) + ' MB/s)</span></div>';
+        }
+        const stage = result.stage;
+        if (stage) {
+            // Stage names come from the scenario, so they are set as text
+            const item = document.createElement('div');
+            item.className = 'metric-item';
+            const label = document.createElement('span');
+            label.className = 'label';
+            label.textContent = 'Stage:';
+            const value = document.createElement('span');
+            value.className = 'value';
+            value.textContent = stage.name + ', available after ' + formatDuration(stage.time_to_content_seconds / 1e9);
+            item.appendChild(label);
+            item.appendChild(value);
+            card.appendChild(item);
+        }
+        if (httpStatus) {
+            const classes = ['2xx', '3xx', '4xx', '5xx'].map(c => c + ' ' + (httpStatus.ByClass[c] || 0)).join(', ');
+            const storms = (httpStatus.Storms || []).length;
+            card.innerHTML += '<div class="metric-item"><span class="label">Registry Responses:</span><span class="value">' +
+                httpStatus.Total + ' (' + classes + ', 429 ' + httpStatus.Throttled + ')' +
+                (storms > 0 ? ', ' + storms + ' storm(s)' : '') + (httpStatus.SuccessInferred ? ', 2xx inferred' : '') + '</span></div>';
+        }
+        const signatures = result.signature_metrics;
+        if (signatures) {
+            const checked = signatures.key_file ? signatures.verified + ' verified' : signatures.unverified + ' payloads ok';
+            card.innerHTML += '<div class="metric-item"><span class="label">Signatures:</span><span class="value">' +
+                signatures.signatures + ' sig, ' + signatures.attestations + ' att, ' + checked +
+                (signatures.invalid + signatures.orphaned > 0 ? ', ' + signatures.invalid + ' invalid, ' + signatures.orphaned + ' orphaned' : '') +
+                '</span></div>';
+        }
+        const catalogDiff = result.catalog_diff;
+        if (catalogDiff) {
+            const tagsAdded = catalogDiff.tags_after - catalogDiff.tags_before;
+            card.innerHTML += '<div class="metric-item"><span class="label">Registry Catalog:</span><span class="value">+' +
+                catalogDiff.added_count + ' repos, ' + (tagsAdded >= 0 ? '+' : '') + tagsAdded + ' tags (' +
+                catalogDiff.repositories_after + ' repos, ' + catalogDiff.tags_after + ' tags)' +
+                (catalogDiff.removed_count > 0 ? ', ' + catalogDiff.removed_count + ' removed' : '') + '</span></div>';
+        }
+        const memoryCeilings = [result.download_phase.memory_ceiling, result.upload_phase.memory_ceiling].filter(m => m);
+        if (memoryCeilings.length > 0) {
+            const peak = Math.max(...memoryCeilings.map(m => m.PeakPercent || 0));
+            const warnings = memoryCeilings.reduce((sum, m) => sum + (m.Warnings || []).length, 0);
+            const oom = memoryCeilings.some(m => m.OOMKillerInvoked) ? ', OOM killer invoked' : '';
+            card.innerHTML += '<div class="metric-item"><span class="label">Memory Budget:</span><span class="value">' +
+                peak.toFixed(1) + '% peak, ' + warnings + ' warnings' + oom + '</span></div>';
+        }
+        const monitorSettings = result.monitor_settings;
+        if (monitorSettings) {
+            const item = document.createElement('div');
+            item.className = 'metric-item';
+            item.innerHTML = '<span class="label">Monitoring:</span><span class="value"></span>';
+            item.children[1].textContent = monitorSettings.network_accounting + (monitorSettings.interface ? ' ' + monitorSettings.interface : '') + ', ' +
+                monitorSettings.monitors.map(m => m.name + ' ' + (m.poll_interval_ms / 1000) + 's').join(', ');
+            item.title = monitorSettings.monitors.map(m => m.name + ': ' + (m.target || '-')).join('\n');
+            card.appendChild(item);
+        }
+        const deletePhase = result.delete_phase;
+        if (deletePhase) {
+            const deleted = deletePhase.error ? 'failed' :
+                formatDuration(deletePhase.wall_time_seconds) + ', ' + deletePhase.delete_metrics.ImagesDeleted + '/' + deletePhase.images_planned +
+                ' images, ' + deletePhase.delete_metrics.APICalls + ' API calls, ' + formatBytes(deletePhase.bytes_reclaimed) + ' reclaimed';
+            card.innerHTML += '<div class="metric-item"><span class="label">Delete:</span><span class="value">' + deleted + '</span></div>';
+        }
+        (result.failure_reasons || []).forEach(reason => {
+            const item = document.createElement('div');
+            item.className = 'metric-item';
+            item.innerHTML = '<span class="label">Gate:</span><span class="value"></span>';
+            item.children[1].textContent = reason;
+            card.appendChild(item);
+        });
+        (result.failed_attempts || []).forEach(attempt => {
+            const item = document.createElement('div');
+            item.className = 'metric-item';
+            item.innerHTML = '<span class="label"></span><span class="value"></span>';
+            item.children[0].textContent = attempt.phase + ' attempt ' + attempt.attempt + (attempt.classification ? ' (' + attempt.classification + ')' : '') + ':';
+            item.children[1].textContent = attempt.error.length > 120 ? attempt.error.slice(0, 117) + '...' : attempt.error;
+            item.title = attempt.error;
+            card.appendChild(item);
+            if (attempt.diagnostics) {
+                const diagnosis = document.createElement('div');
+                diagnosis.className = 'metric-item';
+                diagnosis.innerHTML = '<span class="label">Diagnosis:</span><span class="value"></span>';
+                diagnosis.children[1].textContent = attempt.diagnostics.findings.join('; ');
+                diagnosis.title = attempt.diagnostics.checks.map(c => c.name + ': ' + (c.skipped ? 'skipped' : c.ok ? 'ok' : c.error)).join('\n');
+                card.appendChild(diagnosis);
+            }
+        });
+        
+        container.appendChild(card);
+    });
+}
+
+// Show the Start Run button when the server can start runs
+async function initRunControl() {
+    await loadRuns();
+    setInterval(loadRuns, 5000);
+}
+
+// Refresh the runs the server follows and those started from the dashboard
+async function loadRuns() {
+    try {
+        const response = await fetch('/api/runs');
+        if (response.ok) {
+            if (response.headers.get('X-Run-Controller') === 'enabled') {
+                document.getElementById('startRunBtn').style.display = '';
+            }
+            renderRuns(await response.json());
+        }
+    } catch (error) {
+        // Keep the last list until the server answers again
+    }
+}
+
+// Fill the run selector: the latest results, or one run followed live
+function renderRunSelect(runs) {
+    const select = document.getElementById('runSelect');
+    const selected = select.value;
+    select.innerHTML = '<option value="">Latest results</option>';
+    runs.forEach(run => {
+        const option = document.createElement('option');
+        option.value = run.id;
+        option.textContent = (run.name || run.id) + ' (' + run.state + ')';
+        select.appendChild(option);
+    });
+    select.value = runs.some(run => run.id === selected) ? selected : '';
+    select.style.display = runs.length > 0 ? '' : 'none';
+}
+
+// List the latest runs with their state and progress; a run that just
+// finished reloads the results list so its file can be selected
+let activeRunId = null;
+function renderRuns(runs) {
+    renderRunSelect(runs);
+    const running = runs.find(run => run.state === 'running');
+    if (activeRunId && (!running || running.id !== activeRunId)) {
+        loadResultsList();
+    }
+    activeRunId = running ? running.id : null;
+    document.getElementById('runSubmit').disabled = runs.some(run => run.state === 'running' && !run.live);
+
+    const list = document.getElementById('runsList');
+    list.innerHTML = '';
+    runs.slice(0, 5).forEach(run => {
+        const item = document.createElement('div');
+        item.className = 'metric-item';
+        item.innerHTML = '<span class="label"></span><span class="value"></span>';
+        item.children[0].textContent = (run.name || run.id) + ' (' + run.state + ')';
+        let detail = 'started ' + new Date(run.started_at).toLocaleString();
+        if (run.progress) {
+            detail += ' | iteration ' + run.progress.iteration + '/' + run.progress.total_iterations +
+                (run.progress.phase ? ', ' + run.progress.phase : '') + ', ' + formatBytes(run.progress.bytes_processed);
+        }
+        if (run.results_file) {
+            detail += ' | ' + run.results_file;
+        }
+        if (run.error) {
+            detail += ' | ' + run.error;
+        }
+        item.children[1].textContent = detail;
+        item.title = run.args ? 'oc-mirror-test ' + run.args.join(' ') : 'Run of the server process';
+        if (run.state === 'running' && run.args) {
+            const cancel = document.createElement('button');
+            cancel.textContent = 'Cancel';
+            cancel.addEventListener('click', () => cancelRun(run.id));
+            item.children[1].appendChild(document.createTextNode(' '));
+            item.children[1].appendChild(cancel);
+        }
+        list.appendChild(item);
+    });
+}
+
+// Start a run from the form fields
+async function startRun(event) {
+    event.preventDefault();
+    const message = document.getElementById('runMessage');
+    const args = [];
+    const registry = document.getElementById('runRegistry').value.trim();
+    if (registry) {
+        args.push('--registry', registry);
+    }
+    const iterations = document.getElementById('runIterations').value;
+    if (iterations) {
+        args.push('--iterations', iterations);
+    }
+    if (document.getElementById('runCompareV1V2').checked) {
+        args.push('--compare-v1-v2');
+    }
+    args.push(...document.getElementById('runArgs').value.split(/\s+/).filter(arg => arg));
+
+    const request = {
+        name: document.getElementById('runName').value.trim(),
+        scenario: document.getElementById('runScenario').value,
+        args: args
+    };
+    message.textContent = 'Starting...';
+    try {
+        const response = await fetch('/api/runs', {
+            method: 'POST',
+            headers: {'Content-Type': 'application/json'},
+            body: JSON.stringify(request)
+        });
+        const body = await response.json();
+        message.textContent = response.ok ? 'Started run ' + body.id : 'Failed: ' + body.error;
+    } catch (error) {
+        message.textContent = 'Failed: ' + error.message;
+    }
+    loadRuns();
+}
+
+// Cancel a running run
+async function cancelRun(id) {
+    if (!confirm('Cancel run ' + id + '?')) return;
+    try {
+        const response = await fetch('/api/runs/' + encodeURIComponent(id) + '/cancel', {method: 'POST', headers: {'Content-Type': 'application/json'}});
+        if (!response.ok) {
+            const body = await response.json();
+            document.getElementById('runMessage').textContent = 'Failed: ' + body.error;
+        }
+    } catch (error) {
+        document.getElementById('runMessage').textContent = 'Failed: ' + error.message;
+    }
+    loadRuns();
+}
+
+// Show error
+function showError(message) {
+    const errorDiv = document.getElementById('error');
+    errorDiv.textContent = message;
+    errorDiv.style.display = 'block';
+}
+
+// Toggle auto-refresh
+function toggleAutoRefresh() {
+    const btn = document.getElementById('autoRefreshBtn');
+    if (autoRefreshInterval) {
+        clearInterval(autoRefreshInterval);
+        autoRefreshInterval = null;
+        btn.textContent = 'Auto-refresh: OFF';
+        btn.classList.remove('active');
+    } else {
+        // Use shorter interval for live updates (2 seconds)
+        autoRefreshInterval = setInterval(() => {
+            const select = document.getElementById('resultSelect');
+            const filename = select.value || 'latest';
+            loadResultData(filename, true); // Use live endpoint
+            loadRegistryMetrics(); // Also refresh registry metrics
+        }, 2000);
+        btn.textContent = 'Auto-refresh: ON';
+        btn.classList.add('active');
+    }
+}
+
+// Initialize
+document.addEventListener('DOMContentLoaded', () => {
+    loadResultsList();
+    initRunControl();
+    
+    // Auto-enable auto-refresh on page load for live monitoring
+    setTimeout(() => {
+        if (autoRefreshInterval === null) {
+            toggleAutoRefresh();
+        }
+    }, 1000);
+    
+    document.getElementById('refreshBtn').addEventListener('click', () => {
+        const select = document.getElementById('resultSelect');
+        loadResultData(select.value || 'latest', true);
+    });
+    
+    document.getElementById('autoRefreshBtn').addEventListener('click', toggleAutoRefresh);
+
+    document.getElementById('startRunBtn').addEventListener('click', () => {
+        const panel = document.getElementById('runPanel');
+        panel.style.display = panel.style.display === 'none' ? 'block' : 'none';
+    });
+    document.getElementById('runForm').addEventListener('submit', startRun);
+    
+    document.getElementById('resultSelect').addEventListener('change', (e) => {
+        loadResultData(e.target.value || 'latest');
+    });
+
+    document.getElementById('runSelect').addEventListener('change', () => {
+        document.getElementById('resultSelect').value = 'latest';
+        loadResultData('latest', true);
+    });
+
+    document.getElementById('timeseriesIteration').addEventListener('change', loadTimeSeries);
+    document.getElementById('compareBtn').addEventListener('click', openCompare);
+    document.getElementById('tagFilter').addEventListener('input', renderResultOptions);
+    document.getElementById('deleteResultBtn').addEventListener('click', deleteResult);
+    document.getElementById('annotationForm').addEventListener('submit', submitAnnotations);
+    document.getElementById('compareRunBtn').addEventListener('click', runCompare);
+    document.getElementById('compareCloseBtn').addEventListener('click', closeCompare);
+    document.getElementById('resetZoomBtn').addEventListener('click', () => {
+        [speedTimeChart, memoryTimeChart].forEach(chart => {
+            if (chart && chart.resetZoom) {
+                chart.resetZoom();
+            }
+        });
+    });
+});
@@ -0,0 +1,152 @@
+package webui
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Web UI credentials are read from the environment so they never show up in
+// process listings or shell history
+const (
+	EnvAuthUser     = "OC_MIRROR_TEST_WEBUI_USER"
+	EnvAuthPassword = "OC_MIRROR_TEST_WEBUI_PASSWORD"
+	EnvAuthToken    = "OC_MIRROR_TEST_WEBUI_TOKEN"
+)
+
+// tokenCookie keeps the token of a browser session that opened /?token=...,
+// so the dashboard's API requests are authenticated too
+const tokenCookie = "oc_mirror_test_token"
+
+// AuthConfig protects the web UI with HTTP basic auth, a bearer token, or both
+type AuthConfig struct {
+	User     string
+	Password string
+	Token    string
+}
+
+// AuthConfigFromEnv reads the web UI credentials from the environment
+func AuthConfigFromEnv() AuthConfig {
+	return AuthConfig{
+		User:     os.Getenv(EnvAuthUser),
+		Password: os.Getenv(EnvAuthPassword),
+		Token:    os.Getenv(EnvAuthToken),
+	}
+}
+
+// Enabled reports whether requests must be authenticated
+func (c AuthConfig) Enabled() bool {
+	return c.User != "" || c.Token != ""
+}
+
+// Validate checks that basic auth has both a user and a password
+func (c AuthConfig) Validate() error {
+	if c.User != "" && c.Password == "" {
+		return fmt.Errorf("basic auth user %q has no password (set %s)", c.User, EnvAuthPassword)
+	}
+	if c.User == "" && c.Password != "" {
+		return fmt.Errorf("basic auth password set without a user (set %s or --auth-user)", EnvAuthUser)
+	}
+	return nil
+}
+
+// String returns a human-readable description of the accepted credentials
+func (c AuthConfig) String() string {
+	var methods []string
+	if c.User != "" {
+		methods = append(methods, "basic auth ("+c.User+")")
+	}
+	if c.Token != "" {
+		methods = append(methods, "bearer token")
+	}
+	if len(methods) == 0 {
+		return "none"
+	}
+	return strings.Join(methods, ", ")
+}
+
+// TLSConfig is the certificate the web UI serves HTTPS with
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+}
+
+// Enabled reports whether the web UI serves HTTPS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// Validate checks that the certificate and key load as a pair
+func (c TLSConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return fmt.Errorf("TLS requires both a certificate and a key file")
+	}
+	if _, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile); err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	return nil
+}
+
+// requireAuth rejects requests that carry neither valid basic auth
+// credentials nor the token
+func (s *Server) requireAuth(next http.Handler) http.Handler {
+	return RequireAuth(s.auth, s.tls.Enabled(), next)
+}
+
+// RequireAuth wraps a handler so requests must carry valid basic auth
+// credentials or the token of auth; secure marks the session cookie as
+// HTTPS-only
+func RequireAuth(auth AuthConfig, secure bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.authenticated(w, r, secure) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if auth.User != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oc-mirror-test", charset="UTF-8"`)
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// authenticated checks the request credentials. A valid ?token= query
+// parameter also sets the session cookie for the browser.
+func (c AuthConfig) authenticated(w http.ResponseWriter, r *http.Request, secure bool) bool {
+	if c.User != "" {
+		if user, password, ok := r.BasicAuth(); ok && secureEqual(user, c.User) && secureEqual(password, c.Password) {
+			return true
+		}
+	}
+	if c.Token == "" {
+		return false
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && secureEqual(token, c.Token) {
+		return true
+	}
+	if cookie, err := r.Cookie(tokenCookie); err == nil && secureEqual(cookie.Value, c.Token) {
+		return true
+	}
+	if token := r.URL.Query().Get("token"); token != "" && secureEqual(token, c.Token) {
+		http.SetCookie(w, &http.Cookie{
+			Name:     tokenCookie,
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   secure,
+			SameSite: http.SameSiteStrictMode,
+		})
+		return true
+	}
+	return false
+}
+
+// secureEqual compares credentials in constant time
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,82 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// handleCompare compares two results files, or v1 and v2 of one file:
+// /api/compare?a=<file>&b=<file>[&version_a=v1&version_b=v2]. Each side is
+// the completed iterations of its version, by default the version of the
+// first iteration of the file, or v1 and v2 when a and b are the same file.
+func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	fileA, fileB := query.Get("a"), query.Get("b")
+	for _, file := range []string{fileA, fileB} {
+		if file != "latest" && !s.validResultName(file) {
+			http.Error(w, "a and b must name results files", http.StatusBadRequest)
+			return
+		}
+	}
+	versionA, versionB := query.Get("version_a"), query.Get("version_b")
+	if fileA == fileB && versionA == "" && versionB == "" {
+		versionA, versionB = "v1", "v2"
+	}
+
+	sideA, resultsA, err := s.comparisonSide(fileA, versionA)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	sideB, resultsB, err := s.comparisonSide(fileB, versionB)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if sideA.File == sideB.File {
+		// One file: the versions tell the sides apart
+		sideA.Label, sideB.Label = strings.ToUpper(sideA.Version), strings.ToUpper(sideB.Version)
+	}
+
+	comparison, err := runner.CompareRuns(sideA.Label, resultsA, sideB.Label, resultsB)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	comparison.A.File, comparison.B.File = sideA.File, sideB.File
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparison)
+}
+
+// comparisonSide returns the iterations of a version of a results file and
+// the label of the side
+func (s *Server) comparisonSide(file, version string) (runner.ComparisonSide, []runner.TestResult, error) {
+	file, results, err := s.fileResults(file)
+	if err != nil {
+		return runner.ComparisonSide{}, nil, err
+	}
+	if version == "" && len(results) > 0 {
+		version = results[0].Version
+	}
+	var selected []runner.TestResult
+	for _, result := range results {
+		if result.Version == version {
+			selected = append(selected, result)
+		}
+	}
+	if len(selected) == 0 {
+		return runner.ComparisonSide{}, nil, fmt.Errorf("%s has no %s iterations", file, version)
+	}
+
+	label := path.Base(file)
+	if meta, ok := runner.ParseResultsFileName(label); ok {
+		label = meta.String()
+	}
+	return runner.ComparisonSide{Label: label + " (" + version + ")", File: file, Version: version}, selected, nil
+}
@@ -0,0 +1,194 @@
+package webui
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// Result statuses of the status filter
+const (
+	statusPassed = "passed" // Every iteration passed
+	statusFailed = "failed" // At least one iteration failed or violated a gate
+)
+
+// resultsFilter selects and pages the results list. The zero value keeps
+// every file, oldest first.
+type resultsFilter struct {
+	from, to      time.Time // Run start range, either end open when zero
+	version       string    // v1, v2 or v1-v2
+	registry      string    // Substring of the registry host
+	tags          []string  // key or key=value, all required
+	query         string    // Substring of the name, run name, registry, tags or notes
+	minIterations int
+	status        string // passed or failed
+	newestFirst   bool
+	offset, limit int // limit 0 returns every match
+}
+
+// parseResultsFilter reads the filter from the /api/results query: from, to
+// (dates or RFC 3339 times), version, registry, tag (repeatable), q,
+// min_iterations, status, order (asc or desc), offset and limit
+func parseResultsFilter(query url.Values) (resultsFilter, error) {
+	filter := resultsFilter{
+		version:  query.Get("version"),
+		registry: strings.ToLower(query.Get("registry")),
+		tags:     query["tag"],
+		query:    strings.ToLower(query.Get("q")),
+		status:   query.Get("status"),
+	}
+
+	var err error
+	if filter.from, err = parseFilterTime(query.Get("from"), false); err != nil {
+		return resultsFilter{}, fmt.Errorf("invalid from: %w", err)
+	}
+	if filter.to, err = parseFilterTime(query.Get("to"), true); err != nil {
+		return resultsFilter{}, fmt.Errorf("invalid to: %w", err)
+	}
+	if !filter.from.IsZero() && !filter.to.IsZero() && filter.to.Before(filter.from) {
+		return resultsFilter{}, errors.New("to is before from")
+	}
+	if filter.status != "" && filter.status != statusPassed && filter.status != statusFailed {
+		return resultsFilter{}, fmt.Errorf("invalid status %q (expected %s or %s)", filter.status, statusPassed, statusFailed)
+	}
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		filter.newestFirst = true
+	default:
+		return resultsFilter{}, fmt.Errorf("invalid order %q (expected asc or desc)", order)
+	}
+
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{
+		{"min_iterations", &filter.minIterations},
+		{"offset", &filter.offset},
+		{"limit", &filter.limit},
+	} {
+		if value := query.Get(param.name); value != "" {
+			if *param.value, err = strconv.Atoi(value); err != nil || *param.value < 0 {
+				return resultsFilter{}, fmt.Errorf("%s must be a non-negative number", param.name)
+			}
+		}
+	}
+	return filter, nil
+}
+
+// parseFilterTime parses a date (2006-01-02, local time) or an RFC 3339
+// time. A date that ends a range covers the whole day.
+func parseFilterTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// apply returns the page of the matching files and the number of matches
+func (f resultsFilter) apply(files []ResultFileInfo) ([]ResultFileInfo, int) {
+	matched := []ResultFileInfo{}
+	for _, file := range files {
+		if f.matches(file) {
+			matched = append(matched, file)
+		}
+	}
+	if f.newestFirst {
+		slices.Reverse(matched) // The list is sorted oldest first
+	}
+
+	total := len(matched)
+	start := min(f.offset, total)
+	end := total
+	if f.limit > 0 {
+		end = min(start+f.limit, total)
+	}
+	return matched[start:end], total
+}
+
+// matches reports whether a file passes every filter
+func (f resultsFilter) matches(file ResultFileInfo) bool {
+	started := runStart(file)
+	if (!f.from.IsZero() && started.Before(f.from)) || (!f.to.IsZero() && started.After(f.to)) {
+		return false
+	}
+	if f.version != "" && file.Version != f.version {
+		return false
+	}
+	if f.registry != "" && !strings.Contains(strings.ToLower(file.RegistryHost), f.registry) {
+		return false
+	}
+	if file.ResultCount < f.minIterations {
+		return false
+	}
+	switch f.status {
+	case statusPassed:
+		if file.Failed > 0 || file.Passed == 0 {
+			return false
+		}
+	case statusFailed:
+		if file.Failed == 0 {
+			return false
+		}
+	}
+
+	annotations := runner.Annotations{Tags: file.Tags, Notes: file.Notes}
+	for _, tag := range f.tags {
+		if !annotations.HasTag(tag) {
+			return false
+		}
+	}
+	if f.query != "" {
+		searched := []string{file.Filename, file.RunName, file.RegistryHost, annotations.String()}
+		for _, note := range file.Notes {
+			searched = append(searched, note.Text)
+		}
+		if !strings.Contains(strings.ToLower(strings.Join(searched, "\n")), f.query) {
+			return false
+		}
+	}
+	return true
+}
+
+// runStart returns when the run of a file started: from the file name, the
+// results header, or the file modification time for files with neither
+func runStart(file ResultFileInfo) time.Time {
+	switch {
+	case !file.RunTime.IsZero():
+		return file.RunTime
+	case !file.Header.CreatedAt.IsZero():
+		return file.Header.CreatedAt
+	}
+	return file.ModTime
+}
+
+// resultsVersion returns the version of the iterations of a file whose name
+// does not carry it: v1, v2, or v1-v2 when it has both
+func resultsVersion(results []runner.TestResult) string {
+	var v1, v2 bool
+	for _, result := range results {
+		v1 = v1 || result.Version == "v1"
+		v2 = v2 || result.Version == "v2"
+	}
+	switch {
+	case v1 && v2:
+		return "v1-v2"
+	case v1:
+		return "v1"
+	case v2:
+		return "v2"
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/logging"
+	"github.com/telco-core/ngc-495/pkg/results"
+)
+
+// maxArchiveRequest caps the body of an archive request
+const maxArchiveRequest = 4096
+
+// ArchiveRequest is the body of POST /api/archive
+type ArchiveRequest struct {
+	OlderThan string `json:"older_than"` // Age of the runs to archive, e.g. 30d
+	DryRun    bool   `json:"dry_run,omitempty"`
+}
+
+// ArchiveResponse is the POST /api/archive response
+type ArchiveResponse struct {
+	DryRun   bool                `json:"dry_run,omitempty"`
+	Runs     []results.StoredRun `json:"runs"`     // Runs archived, or that would be with dry_run
+	Archives []string            `json:"archives"` // Archives written, below the results root
+	Bytes    int64               `json:"bytes"`    // Size of the archived runs before compression
+}
+
+// SetRetention lets the dashboard and API delete and archive results files
+func (s *Server) SetRetention(enabled bool) {
+	s.retention = enabled
+}
+
+// handleDeleteResult removes a results file with its sidecars and phase logs
+func (s *Server) handleDeleteResult(w http.ResponseWriter, r *http.Request, filename string) {
+	if !s.retention {
+		http.Error(w, "deleting results is disabled; start serve with --enable-retention", http.StatusForbidden)
+		return
+	}
+	if s.registry.writing(filename) {
+		http.Error(w, "a run is still writing "+filename, http.StatusConflict)
+		return
+	}
+	run, err := results.FindRun(s.backend, filename)
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = results.Delete(s.backend, run)
+	s.cache.invalidate(filename)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logging.Event("results deleted", "file", filename, "objects", len(run.Objects))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// handleArchive moves the runs started before the older_than age of the JSON
+// body (e.g. 30d) into a tar.gz under the archive directory of their results
+// root. With dry_run it only lists them.
+func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.retention {
+		http.Error(w, "archiving results is disabled; start serve with --enable-retention", http.StatusForbidden)
+		return
+	}
+	if !requireJSON(w, r) {
+		return
+	}
+	var request ArchiveRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxArchiveRequest))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		http.Error(w, "invalid archive request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	age, err := results.ParseAge(request.OlderThan)
+	if err != nil {
+		http.Error(w, "older_than: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	dryRun := request.DryRun
+
+	runs, err := results.ListRuns(s.backend)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response := ArchiveResponse{DryRun: dryRun, Runs: []results.StoredRun{}, Archives: []string{}}
+	for _, run := range results.OlderThan(runs, time.Now().Add(-age)) {
+		if s.registry.writing(run.Name) {
+			continue
+		}
+		response.Runs = append(response.Runs, run)
+		response.Bytes += run.Size
+	}
+
+	if !dryRun && len(response.Runs) > 0 {
+		archives, err := results.Archive(s.backend, response.Runs, time.Now())
+		for _, run := range response.Runs {
+			s.cache.invalidate(run.Name)
+		}
+		if archives != nil {
+			response.Archives = archives
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logging.Event("results archived", "runs", len(response.Runs), "archives", len(archives))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// writing reports whether a run followed by the server is still writing the
+// results file filename
+func (r *runRegistry) writing(filename string) bool {
+	for _, run := range r.list() {
+		if run.State == RunRunning && run.ResultsFile != "" && filepath.Base(run.ResultsFile) == path.Base(filename) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/events"
+	"github.com/telco-core/ngc-495/pkg/heartbeat"
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// RunRunning is the state of a run that has not ended; runs of the run
+// controller also end as succeeded, failed or cancelled
+const RunRunning = "running"
+
+// RunInfo describes a run the server follows: one executing in the server
+// process, or one started through the run controller
+type RunInfo struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name,omitempty"`
+	State       string            `json:"state"`
+	StartedAt   time.Time         `json:"started_at"`
+	ResultsFile string            `json:"results_file,omitempty"` // Path the run writes its results to, once known
+	Live        bool              `json:"live"`                   // Recent monitor samples are kept in memory
+	Args        []string          `json:"args,omitempty"`         // Arguments of a controlled run
+	Error       string            `json:"error,omitempty"`
+	Progress    *heartbeat.Status `json:"progress,omitempty"` // Latest heartbeat of a running controlled run
+}
+
+// RunLiveResponse is the /api/runs/<id>/live response
+type RunLiveResponse struct {
+	Run     RunInfo              `json:"run"`
+	Results []runner.TestResult  `json:"results"`
+	Live    *events.LiveSnapshot `json:"live,omitempty"` // Runs executing in the server process
+}
+
+// followedRun is a run executing in the server process, with the buffer of
+// its recent monitor samples and its registry monitor
+type followedRun struct {
+	RunInfo
+	live            *events.LiveBuffer
+	registryMonitor runner.RegistryMonitorInterface
+}
+
+// runRegistry keeps the runs the server follows by ID, so concurrent runs
+// writing to the same results directory are served apart
+type runRegistry struct {
+	mu         sync.RWMutex
+	followed   map[string]*followedRun
+	controller http.Handler     // Starts and cancels runs; nil when the dashboard is read-only
+	controlled func() []RunInfo // Runs of the controller
+}
+
+// RegisterRun follows a run executing in the server process: its results are
+// served from /api/runs/<id>/live with the recent samples of live, and its
+// upload rates from /api/registry?run=<id>. The ID defaults to the results
+// file name without extension.
+func (s *Server) RegisterRun(info RunInfo, live *events.LiveBuffer, registryMonitor runner.RegistryMonitorInterface) {
+	if info.ID == "" {
+		info.ID = strings.TrimSuffix(filepath.Base(info.ResultsFile), ".json")
+	}
+	if info.State == "" {
+		info.State = RunRunning
+	}
+	if info.StartedAt.IsZero() {
+		info.StartedAt = time.Now()
+	}
+	info.Live = live != nil
+
+	s.registry.mu.Lock()
+	defer s.registry.mu.Unlock()
+	if s.registry.followed == nil {
+		s.registry.followed = make(map[string]*followedRun)
+	}
+	s.registry.followed[info.ID] = &followedRun{RunInfo: info, live: live, registryMonitor: registryMonitor}
+}
+
+// SetRunController serves POST /api/runs and /api/runs/<id> with handler,
+// which starts runs, reports their status and cancels them, and shows the
+// Start Run form in the dashboard. list returns the runs it started, which
+// are listed and served live with the runs of the server process.
+func (s *Server) SetRunController(handler http.Handler, list func() []RunInfo) {
+	s.registry.mu.Lock()
+	defer s.registry.mu.Unlock()
+	s.registry.controller, s.registry.controlled = handler, list
+}
+
+// list returns every run, newest first
+func (r *runRegistry) list() []RunInfo {
+	r.mu.RLock()
+	runs := make([]RunInfo, 0, len(r.followed))
+	for _, run := range r.followed {
+		runs = append(runs, run.RunInfo)
+	}
+	controlled := r.controlled
+	r.mu.RUnlock()
+
+	if controlled != nil {
+		runs = append(runs, controlled()...)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	return runs
+}
+
+// find returns a run by ID, with its live sources when it executes in the server process
+func (r *runRegistry) find(id string) (RunInfo, *followedRun, bool) {
+	r.mu.RLock()
+	run, ok := r.followed[id]
+	r.mu.RUnlock()
+	if ok {
+		return run.RunInfo, run, true
+	}
+	for _, info := range r.list() {
+		if info.ID == id {
+			return info, nil, true
+		}
+	}
+	return RunInfo{}, nil, false
+}
+
+// active returns the newest running run of the server process, nil when there is none
+func (r *runRegistry) active() *followedRun {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var active *followedRun
+	for _, run := range r.followed {
+		if run.State == RunRunning && (active == nil || run.StartedAt.After(active.StartedAt)) {
+			active = run
+		}
+	}
+	return active
+}
+
+// hasController reports whether runs can be started from the dashboard
+func (r *runRegistry) hasController() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.controller != nil
+}
+
+// handleRuns lists the runs (GET) or passes a start request to the run controller
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		if s.registry.hasController() {
+			w.Header().Set("X-Run-Controller", "enabled")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		json.NewEncoder(w).Encode(s.registry.list())
+		return
+	}
+	s.passToController(w, r)
+}
+
+// handleRun serves /api/runs/<id>/live and the status of runs of the server
+// process, and passes the other run requests to the run controller
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/runs/"), "/")
+	switch {
+	case action == "live" && r.Method == http.MethodGet:
+		s.handleRunLive(w, r, id)
+		return
+	case action == "" && r.Method == http.MethodGet:
+		s.registry.mu.RLock()
+		run, ok := s.registry.followed[id]
+		s.registry.mu.RUnlock()
+		if ok {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(run.RunInfo)
+			return
+		}
+	}
+	s.passToController(w, r)
+}
+
+// passToController serves a request with the run controller, 404 without one.
+// Requests that start or cancel runs must be JSON, so that a cross-site form
+// cannot post them.
+func (s *Server) passToController(w http.ResponseWriter, r *http.Request) {
+	s.registry.mu.RLock()
+	controller := s.registry.controller
+	s.registry.mu.RUnlock()
+	if controller == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && !requireJSON(w, r) {
+		return
+	}
+	controller.ServeHTTP(w, r)
+}
+
+// handleRunLive returns the results a run wrote so far, with its recent
+// monitor samples when it executes in the server process
+func (s *Server) handleRunLive(w http.ResponseWriter, r *http.Request, id string) {
+	info, followed, ok := s.registry.find(id)
+	if !ok {
+		http.Error(w, "run not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	response := RunLiveResponse{Run: info, Results: []runner.TestResult{}}
+	if info.ResultsFile != "" {
+		results, err := s.runResults(info.ResultsFile)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if results != nil {
+			response.Results = results
+		}
+	}
+	if followed != nil && followed.live != nil {
+		snapshot := followed.live.Snapshot()
+		response.Live = &snapshot
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// runResults reads the results file of a run, nil until the run wrote it
+func (s *Server) runResults(resultsFile string) ([]runner.TestResult, error) {
+	files, err := s.getResultFiles()
+	if err != nil {
+		return nil, err
+	}
+	name := path.Base(filepath.ToSlash(resultsFile))
+	for _, file := range files {
+		if file.Filename == name {
+			results, _, err := s.currentResults(file)
+			return results, err
+		}
+	}
+	return nil, nil
+}
+
+// currentResults reads a listed results file through the cache, which is
+// only used while the file is unchanged, so a file rewritten by a running
+// test is read again. It also reports whether the cache was hit.
+func (s *Server) currentResults(file ResultFileInfo) ([]runner.TestResult, bool, error) {
+	if results, ok := s.cache.getUnchanged(file.Filename, file.ModTime); ok {
+		return results, true, nil
+	}
+	results, err := s.readResults(file.Filename)
+	if err != nil {
+		return nil, false, err
+	}
+	s.cache.setModified(file.Filename, file.ModTime, results)
+	return results, false, nil
+}
@@ -0,0 +1,198 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/telco-core/ngc-495/pkg/runner"
+)
+
+// defaultSeriesPoints is the number of points a series is averaged down to
+// unless the request asks for another
+const defaultSeriesPoints = 1000
+
+// SeriesPoint is one point of a time series: the seconds since the first
+// sample of the iteration and the value
+type SeriesPoint struct {
+	T float64 `json:"t"`
+	V float64 `json:"v"`
+}
+
+// TimeSeries is one monitored value of an iteration over time
+type TimeSeries struct {
+	Name    string        `json:"name"` // e.g. download_rate
+	Label   string        `json:"label"`
+	Phase   string        `json:"phase"` // download or upload
+	Unit    string        `json:"unit"`  // MB/s, MB or %
+	Samples int           `json:"samples"`
+	Points  []SeriesPoint `json:"points"` // Averaged down when there are more samples than requested points
+}
+
+// SamplesResponse is the /api/samples/<file>?index=<n> response: the sample
+// series of one iteration of a results file
+type SamplesResponse struct {
+	File      string       `json:"file"`
+	Index     int          `json:"index"` // Position of the iteration in the results file
+	Iteration int          `json:"iteration"`
+	Version   string       `json:"version"`
+	Start     time.Time    `json:"start"` // Time of the first sample, the origin of the series
+	Series    []TimeSeries `json:"series"`
+}
+
+// timedValue is a sample value with its time, before it is made relative
+type timedValue struct {
+	at    time.Time
+	value float64
+}
+
+// handleSamples serves the stored monitor samples of one iteration as time
+// series. The file is a results file name or "latest"; index selects the
+// iteration and points caps the points of each series, 0 for all samples.
+func (s *Server) handleSamples(w http.ResponseWriter, r *http.Request) {
+	filename := strings.TrimPrefix(r.URL.Path, "/api/samples/")
+	if filename != "latest" && !s.validResultName(filename) {
+		http.Error(w, "invalid filename", http.StatusBadRequest)
+		return
+	}
+	index, err := strconv.Atoi(r.URL.Query().Get("index"))
+	if err != nil || index < 0 {
+		http.Error(w, "index must be the position of the iteration in the results file", http.StatusBadRequest)
+		return
+	}
+	points := defaultSeriesPoints
+	if value := r.URL.Query().Get("points"); value != "" {
+		if points, err = strconv.Atoi(value); err != nil || points < 0 {
+			http.Error(w, "points must be a non-negative number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	filename, results, err := s.fileResults(filename)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if index >= len(results) {
+		http.Error(w, "no iteration at index "+strconv.Itoa(index), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(iterationSeries(filename, index, results[index], points))
+}
+
+// fileResults returns the results of a file through the cache, resolving
+// "latest" to the newest results file
+func (s *Server) fileResults(filename string) (string, []runner.TestResult, error) {
+	if filename == "latest" {
+		files, err := s.getResultFiles()
+		if err != nil {
+			return "", nil, err
+		}
+		if len(files) == 0 {
+			return "", nil, errors.New("no results found")
+		}
+		latest := files[len(files)-1]
+		results, _, err := s.currentResults(latest)
+		return latest.Filename, results, err
+	}
+
+	if results, ok := s.cache.get(filename); ok {
+		return filename, results, nil
+	}
+	results, err := s.readResults(filename)
+	if err != nil {
+		return "", nil, err
+	}
+	s.cache.set(filename, results)
+	return filename, results, nil
+}
+
+// iterationSeries builds the series of the download and upload samples of an
+// iteration: transfer rates, the oc-mirror memory and CPU, and the download
+// size, which flattens during stalls
+func iterationSeries(filename string, index int, result runner.TestResult, points int) SamplesResponse {
+	type series struct {
+		TimeSeries
+		values []timedValue
+	}
+	var all []series
+	add := func(name, label, phase, unit string, values []timedValue) {
+		if len(values) > 0 {
+			all = append(all, series{TimeSeries{Name: name, Label: label, Phase: phase, Unit: unit, Samples: len(values)}, values})
+		}
+	}
+
+	var downloaded, downloadRate []timedValue
+	for _, sample := range result.DownloadPhase.DownloadMetrics.Samples {
+		downloaded = append(downloaded, timedValue{sample.Timestamp, float64(sample.TotalBytes) / (1024 * 1024)})
+		downloadRate = append(downloadRate, timedValue{sample.Timestamp, sample.DownloadRateMB})
+	}
+	add("download_rate", "Download rate", "download", "MB/s", downloadRate)
+	add("download_total", "Downloaded", "download", "MB", downloaded)
+
+	if result.RegistryMetrics != nil {
+		var uploadRate []timedValue
+		for _, sample := range result.RegistryMetrics.Samples {
+			uploadRate = append(uploadRate, timedValue{sample.Timestamp, sample.UploadRateMB})
+		}
+		add("upload_rate", "Upload rate", "upload", "MB/s", uploadRate)
+	}
+	if m := result.UploadPhase.DiskWriteMetrics; m != nil {
+		var writeRate []timedValue
+		for _, sample := range m.Samples {
+			writeRate = append(writeRate, timedValue{sample.Timestamp, sample.WriteRate})
+		}
+		add("layout_write_rate", "OCI layout write rate", "upload", "MB/s", writeRate)
+	}
+
+	for _, phase := range []struct {
+		name    string
+		metrics runner.PhaseMetrics
+	}{{"download", result.DownloadPhase}, {"upload", result.UploadPhase}} {
+		var memory, cpu []timedValue
+		for _, sample := range phase.metrics.ResourceMetrics.Samples {
+			memory = append(memory, timedValue{sample.Timestamp, float64(sample.MemoryRSS) / (1024 * 1024)})
+			cpu = append(cpu, timedValue{sample.Timestamp, sample.CPUPercent})
+		}
+		add(phase.name+"_memory", "Memory (RSS)", phase.name, "MB", memory)
+		add(phase.name+"_cpu", "CPU", phase.name, "%", cpu)
+	}
+
+	response := SamplesResponse{File: filename, Index: index, Iteration: result.Iteration, Version: result.Version, Series: []TimeSeries{}}
+	for _, s := range all {
+		if response.Start.IsZero() || s.values[0].at.Before(response.Start) {
+			response.Start = s.values[0].at
+		}
+	}
+	for _, s := range all {
+		s.Points = downsample(s.values, response.Start, points)
+		response.Series = append(response.Series, s.TimeSeries)
+	}
+	return response
+}
+
+// downsample makes the values relative to start and averages consecutive
+// values into at most limit points; limit 0 keeps every value
+func downsample(values []timedValue, start time.Time, limit int) []SeriesPoint {
+	bucket := 1
+	if limit > 0 && len(values) > limit {
+		bucket = (len(values) + limit - 1) / limit
+	}
+	points := make([]SeriesPoint, 0, (len(values)+bucket-1)/bucket)
+	for i := 0; i < len(values); i += bucket {
+		end := min(i+bucket, len(values))
+		var t, v float64
+		for _, value := range values[i:end] {
+			t += value.at.Sub(start).Seconds()
+			v += value.value
+		}
+		n := float64(end - i)
+		points = append(points, SeriesPoint{T: t / n, V: v / n})
+	}
+	return points
+}
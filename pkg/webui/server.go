@@ -1,26 +1,44 @@
 package webui
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
 	"os"
-	"path/filepath"
+	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/telco-core/ngc-495/pkg/events"
+	"github.com/telco-core/ngc-495/pkg/integrity"
+	"github.com/telco-core/ngc-495/pkg/logging"
 	"github.com/telco-core/ngc-495/pkg/runner"
+	"github.com/telco-core/ngc-495/pkg/store"
 )
 
 // Server represents the web UI server
 type Server struct {
 	port           int
-	resultsDir     string
+	backend        store.Store // Where results files are read from
 	cache          *resultCache
 	registryMonitor *runner.RegistryMonitorInterface // Registry monitor for live metrics
+	signingKey     []byte                            // Optional key for results signature verification
+	bindAddress    string                            // Interface to listen on; empty for all
+	auth           AuthConfig                        // Optional basic auth and token credentials
+	tls            TLSConfig                         // Optional certificate for HTTPS
+	httpServer     *http.Server                      // Set by Listen
+	listener       net.Listener
+	registry       runRegistry                       // Runs followed live and started from the dashboard, by ID
+	annotationsMu  sync.Mutex                        // Serializes updates of the annotations sidecars
+	retention      bool                              // Results may be deleted and archived
+	annotations    bool                              // Tags and notes may be added to results files
+	assetsDir      string                            // Dashboard files served instead of the embedded ones
 }
 
 // resultCache caches parsed results to avoid repeated file I/O
@@ -33,6 +51,7 @@ type resultCache struct {
 type cacheEntry struct {
 	data      []runner.TestResult
 	timestamp time.Time
+	modTime   time.Time // Modification time of the file the data was read from, when known
 }
 
 func newResultCache(maxAge time.Duration) *resultCache {
@@ -68,41 +87,176 @@ func (c *resultCache) set(key string, data []runner.TestResult) {
 	}
 }
 
+// getUnchanged returns the cached data of a file that was not modified since it was read
+func (c *resultCache) getUnchanged(key string, modTime time.Time) ([]runner.TestResult, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[key]
+	if !ok || entry.modTime.IsZero() || !entry.modTime.Equal(modTime) {
+		return nil, false
+	}
+	return entry.data, true
+}
+
+// setModified caches the data of a file with its modification time
+func (c *resultCache) setModified(key string, modTime time.Time, data []runner.TestResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &cacheEntry{data: data, timestamp: time.Now(), modTime: modTime}
+}
+
+// invalidate drops the cached data of files that were deleted or archived
+func (c *resultCache) invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+}
+
 func (c *resultCache) clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.entries = make(map[string]*cacheEntry)
 }
 
-// NewServer creates a new web UI server
+// NewServer creates a new web UI server reading results from a local directory
 func NewServer(port int, resultsDir string) *Server {
 	return &Server{
-		port:       port,
-		resultsDir: resultsDir,
-		cache:      newResultCache(30 * time.Second), // Cache for 30 seconds
+		port:    port,
+		backend: store.NewLocal(resultsDir),
+		cache:   newResultCache(30 * time.Second), // Cache for 30 seconds
 	}
 }
 
-// Start starts the web server
+// SetBindAddress sets the interface address the server listens on
+func (s *Server) SetBindAddress(address string) {
+	s.bindAddress = address
+}
+
+// SetAuth requires requests to authenticate with the given credentials
+func (s *Server) SetAuth(auth AuthConfig) {
+	s.auth = auth
+}
+
+// SetTLS serves HTTPS with the given certificate
+func (s *Server) SetTLS(config TLSConfig) {
+	s.tls = config
+}
+
+// SetBackend sets where results files are read from, e.g. an S3 bucket
+func (s *Server) SetBackend(backend store.Store) {
+	s.backend = backend
+}
+
+// Start starts the web server and blocks until it fails or is shut down
 func (s *Server) Start() error {
-	// Ensure results directory exists
-	if err := os.MkdirAll(s.resultsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create results directory: %w", err)
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	return s.Serve()
+}
+
+// Listen binds the listen address so address errors surface before anything
+// else starts; Serve then handles requests on it
+func (s *Server) Listen() error {
+	// Ensure a local results directory exists
+	if local, ok := s.backend.(*store.Local); ok {
+		if err := os.MkdirAll(local.Dir(), 0755); err != nil {
+			return fmt.Errorf("failed to create results directory: %w", err)
+		}
 	}
 
 	// Register handlers
-	http.HandleFunc("/", s.handleIndex)
-	http.HandleFunc("/api/results", s.handleResultsList)
-	http.HandleFunc("/api/results/", s.handleResultDetail)
-	http.HandleFunc("/api/latest", s.handleLatestResult)
-	http.HandleFunc("/api/live", s.handleLiveMetrics)
-	http.HandleFunc("/api/registry", s.handleRegistryMetrics) // New endpoint for registry metrics
-	http.HandleFunc("/static/", s.handleStatic)
-
-	addr := fmt.Sprintf(":%d", s.port)
-	log.Printf("Starting web UI server on http://localhost%s", addr)
-	log.Printf("Results directory: %s", s.resultsDir)
-	return http.ListenAndServe(addr, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/api/results", s.handleResultsList)
+	mux.HandleFunc("/api/results/", s.handleResultDetail)
+	mux.HandleFunc("/api/latest", s.handleLatestResult)
+	mux.HandleFunc("/api/samples/", s.handleSamples)
+	mux.HandleFunc("/api/compare", s.handleCompare)
+	mux.HandleFunc("/api/annotations/", s.handleAnnotations)
+	mux.HandleFunc("/api/archive", s.handleArchive)
+	mux.HandleFunc("/api/sources", s.handleSources)
+	mux.HandleFunc("/api/live", s.handleLiveMetrics)
+	mux.HandleFunc("/api/registry", s.handleRegistryMetrics) // New endpoint for registry metrics
+	mux.HandleFunc("/static/", s.handleStatic)
+	mux.HandleFunc("/api/runs", s.handleRuns)
+	mux.HandleFunc("/api/runs/", s.handleRun)
+
+	handler := http.Handler(mux)
+	if s.auth.Enabled() {
+		handler = s.requireAuth(handler)
+	}
+	s.httpServer = &http.Server{
+		Addr:              net.JoinHostPort(s.bindAddress, strconv.Itoa(s.port)),
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.httpServer.Addr, err)
+	}
+	s.listener = listener
+
+	logging.Printf("Starting web UI server on %s\n", s.URL())
+	logging.Printf("Results: %s\n", s.backend)
+	if s.auth.Enabled() {
+		logging.Printf("Authentication: %s\n", s.auth)
+		if !s.tls.Enabled() {
+			logging.Printf("Warning: credentials are sent in clear text; use --tls-cert and --tls-key\n")
+		}
+	} else if s.registry.hasController() {
+		logging.Printf("Warning: the dashboard starts runs on this host without authentication; use --auth-user or %s\n", EnvAuthToken)
+	}
+	if s.retention && !s.auth.Enabled() {
+		logging.Printf("Warning: results can be deleted and archived without authentication; use --auth-user or %s\n", EnvAuthToken)
+	}
+	if s.annotations && !s.auth.Enabled() {
+		logging.Printf("Warning: tags and notes can be added to results without authentication; use --auth-user or %s\n", EnvAuthToken)
+	}
+	logging.Event("web UI started", "url", s.URL(), "results", fmt.Sprint(s.backend), "auth", s.auth.Enabled(), "tls", s.tls.Enabled())
+	return nil
+}
+
+// Serve handles requests until Shutdown is called; a shutdown is not an error
+func (s *Server) Serve() error {
+	if s.listener == nil {
+		return fmt.Errorf("web UI server is not listening")
+	}
+	var err error
+	if s.tls.Enabled() {
+		err = s.httpServer.ServeTLS(s.listener, s.tls.CertFile, s.tls.KeyFile)
+	} else {
+		err = s.httpServer.Serve(s.listener)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops the server, waiting for open requests until ctx expires
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Shutdown(ctx)
+}
+
+// URL returns the address the dashboard is reachable at, using localhost when
+// listening on all interfaces
+func (s *Server) URL() string {
+	scheme := "http"
+	if s.tls.Enabled() {
+		scheme = "https"
+	}
+	host := s.bindAddress
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(s.port)))
 }
 
 // handleIndex serves the main HTML page
@@ -111,29 +265,50 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	fmt.Fprint(w, indexHTML)
+	s.serveAsset(w, r, "index.html")
 }
 
-// handleResultsList returns a list of all result files
+// handleResultsList returns the result files matching the query filters,
+// one page of them with limit and offset
 func (s *Server) handleResultsList(w http.ResponseWriter, r *http.Request) {
 	files, err := s.getResultFiles()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	filter, err := parseResultsFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	files, total := filter.apply(files)
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if s.retention {
+		w.Header().Set("X-Results-Retention", "enabled")
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(files)
 }
 
-// handleResultDetail returns detailed metrics for a specific result file
+// handleResultDetail returns detailed metrics for a specific result file, or
+// deletes it (DELETE) when retention is enabled
 func (s *Server) handleResultDetail(w http.ResponseWriter, r *http.Request) {
 	filename := strings.TrimPrefix(r.URL.Path, "/api/results/")
 	if filename == "" {
 		http.Error(w, "filename required", http.StatusBadRequest)
 		return
 	}
+	if !s.validResultName(filename) {
+		http.Error(w, "invalid filename", http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		s.handleDeleteResult(w, r, filename)
+		return
+	}
+
+	s.setIntegrityHeaders(w, filename)
 
 	// Check cache first
 	if results, ok := s.cache.get(filename); ok {
@@ -143,19 +318,12 @@ func (s *Server) handleResultDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	filepath := filepath.Join(s.resultsDir, filename)
-	data, err := os.ReadFile(filepath)
+	results, err := s.readResults(filename)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	var results []runner.TestResult
-	if err := json.Unmarshal(data, &results); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
 	// Cache the results
 	s.cache.set(filename, results)
 
@@ -164,13 +332,59 @@ func (s *Server) handleResultDetail(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(results)
 }
 
-// handleLiveMetrics returns the most recent result with live updates
+// validResultName reports whether filename names a results file at the
+// backend root, or at the root of one source of a federated backend
+func (s *Server) validResultName(filename string) bool {
+	if _, ok := s.backend.(*store.Federated); ok {
+		_, filename, _ = strings.Cut(filename, "/")
+	}
+	return filename != "" && !strings.Contains(filename, "/")
+}
+
+// handleSources reports the availability of each results root of a federated
+// backend; the list is empty for a single root
+func (s *Server) handleSources(w http.ResponseWriter, r *http.Request) {
+	sources := []store.RootStatus{}
+	if federated, ok := s.backend.(*store.Federated); ok {
+		sources = federated.Status()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sources)
+}
+
+// LiveResponse is the /api/live response while the server follows a run
+type LiveResponse struct {
+	Results []runner.TestResult `json:"results"`
+	Live    events.LiveSnapshot `json:"live"`
+}
+
+// handleLiveMetrics returns the results of the newest run executing in the
+// server process with its recent monitor samples, or the latest results file
+// when the server follows no run. With several runs, /api/runs/<id>/live
+// selects one.
 func (s *Server) handleLiveMetrics(w http.ResponseWriter, r *http.Request) {
 	// Enable CORS for live updates
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
 	w.Header().Set("Pragma", "no-cache")
 	w.Header().Set("Expires", "0")
+
+	if run := s.registry.active(); run != nil {
+		results, err := s.runResults(run.ResultsFile)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response := LiveResponse{Results: results}
+		if results == nil {
+			response.Results = []runner.TestResult{}
+		}
+		if run.live != nil {
+			response.Live = run.live.Snapshot()
+		}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
 	
 	// Get latest result
 	files, err := s.getResultFiles()
@@ -185,38 +399,21 @@ func (s *Server) handleLiveMetrics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the latest file
-	latestFile := files[len(files)-1].Filename
-	
-	// Check cache first
-	if results, ok := s.cache.get("latest"); ok {
-		// Verify it's still the latest
-		if len(files) > 0 && files[len(files)-1].Filename == latestFile {
-			w.Header().Set("X-Cache", "HIT")
-			json.NewEncoder(w).Encode(results)
-			return
-		}
-	}
-
-	filepath := filepath.Join(s.resultsDir, latestFile)
-	data, err := os.ReadFile(filepath)
+	results, hit, err := s.currentResults(files[len(files)-1])
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	w.Header().Set("X-Cache", cacheStatus(hit))
+	json.NewEncoder(w).Encode(results)
+}
 
-	var results []runner.TestResult
-	if err := json.Unmarshal(data, &results); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// cacheStatus returns the X-Cache header value
+func cacheStatus(hit bool) string {
+	if hit {
+		return "HIT"
 	}
-
-	// Cache the results
-	s.cache.set("latest", results)
-	s.cache.set(latestFile, results)
-
-	w.Header().Set("X-Cache", "MISS")
-	json.NewEncoder(w).Encode(results)
+	return "MISS"
 }
 
 // handleRegistryMetrics returns current registry upload metrics from the daemon
@@ -226,7 +423,20 @@ func (s *Server) handleRegistryMetrics(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Pragma", "no-cache")
 	w.Header().Set("Expires", "0")
 	
-	if s.registryMonitor == nil || *s.registryMonitor == nil {
+	var monitor runner.RegistryMonitorInterface
+	if s.registryMonitor != nil {
+		monitor = *s.registryMonitor
+	}
+	// The monitor of the selected run, else of the newest run of the server process
+	if id := r.URL.Query().Get("run"); id != "" {
+		monitor = nil
+		if _, run, ok := s.registry.find(id); ok && run != nil {
+			monitor = run.registryMonitor
+		}
+	} else if run := s.registry.active(); run != nil && run.registryMonitor != nil {
+		monitor = run.registryMonitor
+	}
+	if monitor == nil {
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"monitoring": false,
 			"message": "Registry monitor not available",
@@ -234,7 +444,6 @@ func (s *Server) handleRegistryMetrics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	monitor := *s.registryMonitor
 	if !monitor.IsMonitoring() {
 		// Return empty metrics if not monitoring
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -251,6 +460,20 @@ func (s *Server) handleRegistryMetrics(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// SetSigningKey sets the key used to verify results file signatures
+func (s *Server) SetSigningKey(key []byte) {
+	s.signingKey = key
+}
+
+// setIntegrityHeaders reports the verification status of a results file so the UI can warn about modified files
+func (s *Server) setIntegrityHeaders(w http.ResponseWriter, filename string) {
+	result := s.verify(filename, nil)
+	w.Header().Set("X-Result-Integrity", result.Status)
+	if result.Message != "" {
+		w.Header().Set("X-Result-Integrity-Message", result.Message)
+	}
+}
+
 // SetRegistryMonitor sets the registry monitor for live metrics
 func (s *Server) SetRegistryMonitor(monitor runner.RegistryMonitorInterface) {
 	s.registryMonitor = &monitor
@@ -270,92 +493,109 @@ func (s *Server) handleLatestResult(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the latest file
-	latestFile := files[len(files)-1].Filename
-	
-	// Check cache first
-	if results, ok := s.cache.get("latest"); ok {
-		// Verify it's still the latest
-		if len(files) > 0 && files[len(files)-1].Filename == latestFile {
-			w.Header().Set("Content-Type", "application/json")
-			w.Header().Set("X-Cache", "HIT")
-			json.NewEncoder(w).Encode(results)
-			return
-		}
-	}
+	latest := files[len(files)-1]
+	s.setIntegrityHeaders(w, latest.Filename)
 
-	filepath := filepath.Join(s.resultsDir, latestFile)
-	data, err := os.ReadFile(filepath)
+	results, hit, err := s.currentResults(latest)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	var results []runner.TestResult
-	if err := json.Unmarshal(data, &results); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Cache the results
-	s.cache.set("latest", results)
-	s.cache.set(latestFile, results)
-
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("X-Cache", "MISS")
+	w.Header().Set("X-Cache", cacheStatus(hit))
 	json.NewEncoder(w).Encode(results)
 }
 
 // ResultFileInfo represents information about a result file
 type ResultFileInfo struct {
-	Filename    string    `json:"filename"`
-	ModTime     time.Time `json:"mod_time"`
-	ModTimeStr  string    `json:"mod_time_str"`
-	ResultCount int       `json:"result_count"`
+	Filename     string           `json:"filename"`
+	ModTime      time.Time        `json:"mod_time"`
+	ModTimeStr   string           `json:"mod_time_str"`
+	ResultCount  int              `json:"result_count"`
+	Integrity    integrity.Result `json:"integrity"`
+	RunTime      time.Time        `json:"run_time,omitempty"` // Run start, parsed from the file name
+	RunName      string           `json:"run_name,omitempty"`
+	RegistryHost string           `json:"registry_host,omitempty"`
+	Version      string           `json:"version,omitempty"` // v1, v2 or v1-v2
+	Label        string           `json:"label"`
+	Source       string           `json:"source,omitempty"` // Results root of a federated backend
+
+	Header runner.ResultsHeader `json:"header"` // Run description from the results file envelope
+
+	Tags  map[string]string `json:"tags,omitempty"`  // Tags of the header and the annotations sidecar
+	Notes []runner.Note     `json:"notes,omitempty"` // Notes of the header and the annotations sidecar
+
+	Passed int `json:"passed"` // Iterations that completed and met every gate
+	Failed int `json:"failed"` // Iterations that failed or violated a gate
 }
 
 // getResultFiles returns a list of all result JSON files
 func (s *Server) getResultFiles() ([]ResultFileInfo, error) {
 	var files []ResultFileInfo
 
-	entries, err := os.ReadDir(s.resultsDir)
+	objects, err := s.backend.List()
 	if err != nil {
 		return nil, err
 	}
+	names := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		names[object.Name] = true
+	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		if !strings.HasSuffix(entry.Name(), ".json") {
+	for _, object := range objects {
+		source, base := path.Split(object.Name)
+		if !strings.HasSuffix(base, ".json") {
 			continue
 		}
-		if !strings.HasPrefix(entry.Name(), "results_") {
+		if !strings.HasPrefix(base, "results_") {
 			continue
 		}
 
-		info, err := entry.Info()
+		// Count results in file
+		data, err := s.backend.Read(object.Name)
 		if err != nil {
 			continue
 		}
 
-		// Count results in file
-		filepath := filepath.Join(s.resultsDir, entry.Name())
-		data, err := os.ReadFile(filepath)
+		file, err := runner.ParseResultsFile(data)
 		if err != nil {
 			continue
 		}
 
-		var results []runner.TestResult
-		if err := json.Unmarshal(data, &results); err != nil {
-			continue
+		fileInfo := ResultFileInfo{
+			Filename:    object.Name,
+			ModTime:     object.ModTime,
+			ModTimeStr:  object.ModTime.Format("2006-01-02 15:04:05"),
+			ResultCount: len(file.Results),
+			Integrity:   s.verify(object.Name, data),
+			Label:       object.ModTime.Format("2006-01-02 15:04:05"),
+			Header:      file.ResultsHeader,
+			Source:      strings.TrimSuffix(source, "/"),
 		}
-
-		files = append(files, ResultFileInfo{
-			Filename:    entry.Name(),
-			ModTime:     info.ModTime(),
-			ModTimeStr:  info.ModTime().Format("2006-01-02 15:04:05"),
-			ResultCount: len(results),
-		})
+		if meta, ok := runner.ParseResultsFileName(base); ok {
+			fileInfo.RunTime = meta.Timestamp
+			fileInfo.RunName = meta.RunName
+			fileInfo.RegistryHost = meta.RegistryHost
+			fileInfo.Version = meta.Version
+			fileInfo.Label = meta.String()
+		}
+		if fileInfo.Version == "" {
+			fileInfo.Version = resultsVersion(file.Results)
+		}
+		for _, result := range file.Results {
+			if result.Passed {
+				fileInfo.Passed++
+			} else {
+				fileInfo.Failed++
+			}
+		}
+		annotations := file.Annotations()
+		if names[runner.AnnotationsPath(object.Name)] {
+			annotations = annotations.Merge(s.readAnnotations(object.Name))
+		}
+		fileInfo.Tags, fileInfo.Notes = annotations.Tags, annotations.Notes
+		files = append(files, fileInfo)
 	}
 
 	// Sort by modification time (oldest first)
@@ -366,893 +606,56 @@ func (s *Server) getResultFiles() ([]ResultFileInfo, error) {
 	return files, nil
 }
 
-// handleStatic serves static files (CSS, JS)
-func (s *Server) handleStatic(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/static/")
-	
-	switch path {
-	case "app.js":
-		w.Header().Set("Content-Type", "application/javascript")
-		fmt.Fprint(w, appJS)
-	case "styles.css":
-		w.Header().Set("Content-Type", "text/css")
-		fmt.Fprint(w, stylesCSS)
-	default:
-		http.NotFound(w, r)
+// readResults reads and parses a results file from the backend
+func (s *Server) readResults(filename string) ([]runner.TestResult, error) {
+	data, err := s.backend.Read(filename)
+	if err != nil {
+		return nil, err
 	}
+	file, err := runner.ParseResultsFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	err = file.LoadSamples(func(name string) ([]byte, error) {
+		return s.backend.Read(path.Join(path.Dir(filename), name))
+	})
+	if errors.Is(err, runner.ErrSamplesModified) {
+		// Served without samples; verify reports the file as modified
+		logging.Printf("Warning: not loading the samples of %s: %v\n", filename, err)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load samples of %s: %w", filename, err)
+	}
+	return file.Results, nil
 }
 
-const indexHTML = `<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>OC Mirror Test Metrics Dashboard</title>
-    <link rel="stylesheet" href="/static/styles.css">
-    <script src="https://cdn.jsdelivr.net/npm/chart.js@4.4.0/dist/chart.umd.min.js"></script>
-</head>
-<body>
-    <div class="container">
-        <header>
-            <h1>OC Mirror Test Metrics Dashboard</h1>
-            <div class="controls">
-                <select id="resultSelect">
-                    <option value="">Loading results...</option>
-                </select>
-                <button id="refreshBtn">Refresh</button>
-                <button id="autoRefreshBtn">Auto-refresh: OFF</button>
-            </div>
-        </header>
-
-        <div id="status" class="status-info" style="display: none;">
-            <span id="statusText">Monitoring test execution...</span>
-        </div>
-
-        <div id="loading" class="loading">Loading metrics...</div>
-        <div id="error" class="error" style="display: none;"></div>
-        <div id="content" style="display: none;">
-            <div class="metrics-grid">
-                <div class="metric-card">
-                    <h3>Timing Metrics</h3>
-                    <div class="metric-item">
-                        <span class="label">Download Time:</span>
-                        <span class="value" id="downloadTime">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Upload Time:</span>
-                        <span class="value" id="uploadTime">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Total Time:</span>
-                        <span class="value" id="totalTime">-</span>
-                    </div>
-                </div>
-
-                <div class="metric-card">
-                    <h3>Data Transfer</h3>
-                    <div class="metric-item">
-                        <span class="label">Downloaded:</span>
-                        <span class="value" id="downloaded">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Uploaded:</span>
-                        <span class="value" id="uploaded">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Avg Speed:</span>
-                        <span class="value" id="avgSpeed">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Peak Speed:</span>
-                        <span class="value" id="peakSpeed">-</span>
-                    </div>
-                </div>
-
-                <div class="metric-card">
-                    <h3>Resource Usage</h3>
-                    <div class="metric-item">
-                        <span class="label">CPU Avg:</span>
-                        <span class="value" id="cpuAvg">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">CPU Peak:</span>
-                        <span class="value" id="cpuPeak">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Memory Avg:</span>
-                        <span class="value" id="memAvg">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Memory Peak:</span>
-                        <span class="value" id="memPeak">-</span>
-                    </div>
-                </div>
-
-                <div class="metric-card">
-                    <h3>Network</h3>
-                    <div class="metric-item">
-                        <span class="label">Avg Bandwidth:</span>
-                        <span class="value" id="netAvg">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Peak Bandwidth:</span>
-                        <span class="value" id="netPeak">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Total Transferred:</span>
-                        <span class="value" id="netTotal">-</span>
-                    </div>
-                </div>
-
-                <div class="metric-card">
-                    <h3>Registry Upload (Live)</h3>
-                    <div class="metric-item">
-                        <span class="label">Total Uploaded:</span>
-                        <span class="value" id="registryTotal">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Avg Upload Rate:</span>
-                        <span class="value" id="registryAvg">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Peak Upload Rate:</span>
-                        <span class="value" id="registryPeak">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Active Connections:</span>
-                        <span class="value" id="registryConnections">-</span>
-                    </div>
-                </div>
-
-                <div class="metric-card">
-                    <h3>Mirror Content</h3>
-                    <div class="metric-item">
-                        <span class="label">Images:</span>
-                        <span class="value" id="images">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Layers:</span>
-                        <span class="value" id="layers">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Manifests:</span>
-                        <span class="value" id="manifests">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Files:</span>
-                        <span class="value" id="files">-</span>
-                    </div>
-                </div>
-
-                <div class="metric-card">
-                    <h3>Cache & Performance</h3>
-                    <div class="metric-item">
-                        <span class="label">Cache Hits:</span>
-                        <span class="value" id="cacheHits">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Images Skipped:</span>
-                        <span class="value" id="imagesSkipped">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Errors:</span>
-                        <span class="value" id="errors">-</span>
-                    </div>
-                    <div class="metric-item">
-                        <span class="label">Retries:</span>
-                        <span class="value" id="retries">-</span>
-                    </div>
-                </div>
-            </div>
-
-            <div class="charts-section">
-                <div class="chart-container">
-                    <canvas id="speedChart"></canvas>
-                </div>
-                <div class="chart-container">
-                    <canvas id="resourceChart"></canvas>
-                </div>
-                <div class="chart-container">
-                    <canvas id="networkChart"></canvas>
-                </div>
-            </div>
-
-            <div id="iterations" class="iterations-section"></div>
-        </div>
-    </div>
-    <script src="/static/app.js"></script>
-</body>
-</html>`
-
-const stylesCSS = `* {
-    margin: 0;
-    padding: 0;
-    box-sizing: border-box;
-}
-
-body {
-    font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Oxygen, Ubuntu, Cantarell, sans-serif;
-    background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-    min-height: 100vh;
-    padding: 20px;
-    color: #333;
-}
-
-.container {
-    max-width: 1400px;
-    margin: 0 auto;
-}
-
-header {
-    background: white;
-    padding: 20px 30px;
-    border-radius: 10px;
-    box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);
-    margin-bottom: 20px;
-    display: flex;
-    justify-content: space-between;
-    align-items: center;
-    flex-wrap: wrap;
-    gap: 15px;
-}
-
-header h1 {
-    color: #667eea;
-    font-size: 28px;
-}
-
-.controls {
-    display: flex;
-    gap: 10px;
-    align-items: center;
-}
-
-.controls select {
-    padding: 8px 12px;
-    border: 2px solid #ddd;
-    border-radius: 5px;
-    font-size: 14px;
-    min-width: 200px;
-}
-
-.controls button {
-    padding: 8px 16px;
-    background: #667eea;
-    color: white;
-    border: none;
-    border-radius: 5px;
-    cursor: pointer;
-    font-size: 14px;
-    transition: background 0.3s;
-}
-
-.controls button:hover {
-    background: #5568d3;
-}
-
-.controls button.active {
-    background: #48bb78;
-}
-
-.loading, .error {
-    background: white;
-    padding: 30px;
-    border-radius: 10px;
-    text-align: center;
-    box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);
-    margin-bottom: 20px;
-}
-
-.error {
-    background: #fed7d7;
-    color: #c53030;
-}
-
-.status-info {
-    background: #e6f3ff;
-    border-left: 4px solid #667eea;
-    padding: 12px 20px;
-    margin-bottom: 20px;
-    border-radius: 5px;
-    color: #2c5282;
-    font-weight: 500;
-}
-
-.metrics-grid {
-    display: grid;
-    grid-template-columns: repeat(auto-fit, minmax(300px, 1fr));
-    gap: 20px;
-    margin-bottom: 30px;
-}
-
-.metric-card {
-    background: white;
-    padding: 20px;
-    border-radius: 10px;
-    box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);
-}
-
-.metric-card h3 {
-    color: #667eea;
-    margin-bottom: 15px;
-    font-size: 18px;
-    border-bottom: 2px solid #e2e8f0;
-    padding-bottom: 10px;
-}
-
-.metric-item {
-    display: flex;
-    justify-content: space-between;
-    padding: 8px 0;
-    border-bottom: 1px solid #f0f0f0;
-}
-
-.metric-item:last-child {
-    border-bottom: none;
-}
-
-.metric-item .label {
-    color: #666;
-    font-weight: 500;
-}
-
-.metric-item .value {
-    color: #333;
-    font-weight: 600;
-}
-
-.charts-section {
-    display: grid;
-    grid-template-columns: repeat(auto-fit, minmax(400px, 1fr));
-    gap: 20px;
-    margin-bottom: 30px;
-}
-
-.chart-container {
-    background: white;
-    padding: 20px;
-    border-radius: 10px;
-    box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);
-    height: 300px;
-}
-
-.iterations-section {
-    background: white;
-    padding: 20px;
-    border-radius: 10px;
-    box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);
-}
-
-.iteration-card {
-    border: 2px solid #e2e8f0;
-    border-radius: 8px;
-    padding: 15px;
-    margin-bottom: 15px;
-}
-
-.iteration-card h4 {
-    color: #667eea;
-    margin-bottom: 10px;
-    display: flex;
-    align-items: center;
-    gap: 10px;
-}
-
-.badge {
-    display: inline-block;
-    padding: 4px 8px;
-    border-radius: 4px;
-    font-size: 12px;
-    font-weight: 600;
-}
-
-.badge.clean {
-    background: #c6f6d5;
-    color: #22543d;
-}
-
-.badge.cached {
-    background: #fed7aa;
-    color: #7c2d12;
-}
-
-.badge.v1 {
-    background: #bee3f8;
-    color: #2c5282;
-}
-
-.badge.v2 {
-    background: #fbb6ce;
-    color: #702459;
-}
-
-@media (max-width: 768px) {
-    header {
-        flex-direction: column;
-        align-items: flex-start;
-    }
-    
-    .metrics-grid {
-        grid-template-columns: 1fr;
-    }
-    
-    .charts-section {
-        grid-template-columns: 1fr;
-    }
-}`
-
-const appJS = `
-let autoRefreshInterval = null;
-let speedChart = null;
-let resourceChart = null;
-let networkChart = null;
-
-// Format duration
-function formatDuration(seconds) {
-    if (!seconds) return '-';
-    const s = Math.floor(seconds);
-    const hours = Math.floor(s / 3600);
-    const minutes = Math.floor((s % 3600) / 60);
-    const secs = s % 60;
-    if (hours > 0) {
-        return hours + 'h ' + minutes + 'm ' + secs + 's';
-    } else if (minutes > 0) {
-        return minutes + 'm ' + secs + 's';
-    }
-    return secs + 's';
-}
-
-// Format bytes
-function formatBytes(bytes) {
-    if (!bytes) return '-';
-    const sizes = ['B', 'KB', 'MB', 'GB', 'TB'];
-    if (bytes === 0) return '0 B';
-    const i = Math.floor(Math.log(bytes) / Math.log(1024));
-    return Math.round(bytes / Math.pow(1024, i) * 100) / 100 + ' ' + sizes[i];
-}
-
-// Load results list
-async function loadResultsList() {
-    try {
-        const response = await fetch('/api/results');
-        const files = await response.json();
-        const select = document.getElementById('resultSelect');
-        select.innerHTML = '';
-        
-        if (files.length === 0) {
-            select.innerHTML = '<option value="">No results found</option>';
-            return;
-        }
-        
-        // Add latest option
-        const latestOption = document.createElement('option');
-        latestOption.value = 'latest';
-        latestOption.textContent = 'Latest Results';
-        select.appendChild(latestOption);
-        
-        // Add individual files
-        files.forEach(file => {
-            const option = document.createElement('option');
-            option.value = file.filename;
-            option.textContent = file.mod_time_str + ' (' + file.result_count + ' results)';
-            select.appendChild(option);
-        });
-        
-        // Select latest by default
-        select.value = 'latest';
-        loadResultData('latest', true); // Use live endpoint for initial load
-    } catch (error) {
-        showError('Failed to load results list: ' + error.message);
-    }
-}
-
-// Load registry metrics
-async function loadRegistryMetrics() {
-    try {
-        const response = await fetch('/api/registry');
-        if (!response.ok) {
-            // Registry monitor not available or not monitoring
-            document.getElementById('registryTotal').textContent = '-';
-            document.getElementById('registryAvg').textContent = '-';
-            document.getElementById('registryPeak').textContent = '-';
-            document.getElementById('registryConnections').textContent = '-';
-            return;
-        }
-        const data = await response.json();
-        if (data.monitoring && data.metrics) {
-            const metrics = data.metrics;
-            document.getElementById('registryTotal').textContent = formatBytes(metrics.TotalBytesUploaded || 0);
-            document.getElementById('registryAvg').textContent = (metrics.AverageUploadRateMB || 0).toFixed(2) + ' MB/s';
-            document.getElementById('registryPeak').textContent = (metrics.PeakUploadRateMB || 0).toFixed(2) + ' MB/s';
-            document.getElementById('registryConnections').textContent = metrics.ConnectionCount || 0;
-        } else {
-            document.getElementById('registryTotal').textContent = '-';
-            document.getElementById('registryAvg').textContent = '-';
-            document.getElementById('registryPeak').textContent = '-';
-            document.getElementById('registryConnections').textContent = '-';
-        }
-    } catch (error) {
-        // Silently fail - registry monitor may not be available
-        console.log('Registry metrics not available:', error);
-    }
-}
-
-// Load result data
-async function loadResultData(filename, useLive = false) {
-    const loading = document.getElementById('loading');
-    const content = document.getElementById('content');
-    const errorDiv = document.getElementById('error');
-    const statusDiv = document.getElementById('status');
-    const statusText = document.getElementById('statusText');
-    
-    // Use live endpoint for latest when auto-refresh is on or explicitly requested
-    const useLiveEndpoint = useLive || (filename === 'latest' && autoRefreshInterval !== null);
-    
-    if (useLiveEndpoint && filename === 'latest') {
-        statusDiv.style.display = 'block';
-        statusText.textContent = '🔄 Live monitoring active - Refreshing every 2 seconds...';
-        // Also load registry metrics when in live mode
-        loadRegistryMetrics();
-    } else {
-        statusDiv.style.display = 'none';
-    }
-    
-    loading.style.display = 'block';
-    content.style.display = 'none';
-    errorDiv.style.display = 'none';
-    
-    try {
-        const url = useLiveEndpoint && filename === 'latest' ? '/api/live' : 
-                   (filename === 'latest' ? '/api/latest' : '/api/results/' + filename);
-        const response = await fetch(url);
-        if (!response.ok) {
-            if (response.status === 404 && filename === 'latest') {
-                // No results yet, show waiting message
-                loading.textContent = '⏳ Waiting for test results to be generated...';
-                statusText.textContent = '⏳ Waiting for test execution to start...';
-                return;
-            }
-            throw new Error('Failed to load result data');
-        }
-        const results = await response.json();
-        if (results && results.length > 0) {
-            displayResults(results);
-            loading.style.display = 'none';
-            content.style.display = 'block';
-            if (useLiveEndpoint) {
-                statusText.textContent = '✅ Live monitoring active - Latest results displayed';
-            } else {
-                statusDiv.style.display = 'none';
-            }
-        } else {
-            // No results yet, keep loading state
-            loading.textContent = '⏳ Waiting for test results...';
-            statusText.textContent = '⏳ Waiting for test execution to complete...';
-        }
-    } catch (error) {
-        loading.style.display = 'none';
-        if (error.message.includes('Failed to load') || error.message.includes('404')) {
-            // No results file yet, show waiting message
-            showError('⏳ Waiting for test results to be generated...');
-            statusText.textContent = '⏳ Waiting for test execution to start...';
-        } else {
-            showError('Failed to load result data: ' + error.message);
-            statusDiv.style.display = 'none';
-        }
-    }
-}
-
-// Display results
-function displayResults(results) {
-    if (!results || results.length === 0) {
-        showError('No results found');
-        return;
-    }
-    
-    // Aggregate metrics from all iterations
-    let totalDownloadTime = 0;
-    let totalUploadTime = 0;
-    let totalDownloaded = 0;
-    let totalUploaded = 0;
-    let totalCacheHits = 0;
-    let totalImagesSkipped = 0;
-    let totalErrors = 0;
-    let totalRetries = 0;
-    
-    let cpuAvgSum = 0;
-    let cpuPeakMax = 0;
-    let memAvgSum = 0;
-    let memPeakMax = 0;
-    let netAvgSum = 0;
-    let netPeakMax = 0;
-    let netTotalSum = 0;
-    
-    let avgSpeedSum = 0;
-    let peakSpeedMax = 0;
-    
-    let totalImages = 0;
-    let totalLayers = 0;
-    let totalManifests = 0;
-    let totalFiles = 0;
-    
-    let speedData = [];
-    let resourceData = [];
-    let networkData = [];
-    
-    results.forEach((result, index) => {
-        // Timing
-        const downloadTime = result.download_phase.wall_time_seconds || 0;
-        const uploadTime = result.upload_phase.wall_time_seconds || 0;
-        totalDownloadTime += downloadTime;
-        totalUploadTime += uploadTime;
-        
-        // Data transfer
-        const downloaded = result.download_phase.download_metrics?.TotalBytesDownloaded || 0;
-        const uploaded = result.upload_phase.bytes_uploaded || 0;
-        totalDownloaded += downloaded;
-        totalUploaded += uploaded;
-        
-        // Speed
-        const avgSpeed = result.download_phase.download_metrics?.AverageSpeedMBs || 0;
-        const peakSpeed = result.download_phase.download_metrics?.PeakSpeedMBs || 0;
-        avgSpeedSum += avgSpeed;
-        if (peakSpeed > peakSpeedMax) peakSpeedMax = peakSpeed;
-        
-        // Resources
-        const cpuAvg = result.resource_metrics?.CPUAvgPercent || 0;
-        const cpuPeak = result.resource_metrics?.CPUPeakPercent || 0;
-        const memAvg = result.resource_metrics?.MemoryAvgMB || 0;
-        const memPeak = result.resource_metrics?.MemoryPeakMB || 0;
-        cpuAvgSum += cpuAvg;
-        if (cpuPeak > cpuPeakMax) cpuPeakMax = cpuPeak;
-        memAvgSum += memAvg;
-        if (memPeak > memPeakMax) memPeakMax = memPeak;
-        
-        // Network
-        const netAvg = result.network_metrics?.AverageBandwidthMbps || 0;
-        const netPeak = result.network_metrics?.PeakBandwidthMbps || 0;
-        const netTotal = result.network_metrics?.TotalBytesTransferred || 0;
-        netAvgSum += netAvg;
-        if (netPeak > netPeakMax) netPeakMax = netPeak;
-        netTotalSum += netTotal;
-        
-        // Cache & performance
-        totalCacheHits += result.download_phase.cache_hits || 0;
-        totalImagesSkipped += result.download_phase.images_skipped || 0;
-        totalErrors += (result.download_phase.extended_metrics?.ErrorCount || 0) + 
-                      (result.upload_phase.extended_metrics?.ErrorCount || 0);
-        totalRetries += (result.download_phase.extended_metrics?.RetryCount || 0) + 
-                       (result.upload_phase.extended_metrics?.RetryCount || 0);
-        
-        // Mirror content (use first result with describe metrics)
-        if (result.describe_metrics && totalImages === 0) {
-            totalImages = result.describe_metrics.TotalImages || 0;
-            totalLayers = result.describe_metrics.TotalLayers || 0;
-            totalManifests = result.describe_metrics.TotalManifests || 0;
-        }
-        
-        if (result.output_metrics && totalFiles === 0) {
-            totalFiles = result.output_metrics.TotalFiles || 0;
-        }
-        
-        // Chart data
-        speedData.push({
-            x: 'Iteration ' + result.iteration,
-            avg: avgSpeed,
-            peak: peakSpeed
-        });
-        
-        resourceData.push({
-            x: 'Iteration ' + result.iteration,
-            cpu: cpuAvg,
-            mem: memAvg
-        });
-        
-        networkData.push({
-            x: 'Iteration ' + result.iteration,
-            avg: netAvg,
-            peak: netPeak
-        });
-    });
-    
-    const count = results.length;
-    
-    // Update metrics display
-    document.getElementById('downloadTime').textContent = formatDuration(totalDownloadTime / count);
-    document.getElementById('uploadTime').textContent = formatDuration(totalUploadTime / count);
-    document.getElementById('totalTime').textContent = formatDuration((totalDownloadTime + totalUploadTime) / count);
-    
-    document.getElementById('downloaded').textContent = formatBytes(totalDownloaded);
-    document.getElementById('uploaded').textContent = formatBytes(totalUploaded);
-    document.getElementById('avgSpeed').textContent = (avgSpeedSum / count).toFixed(2) + ' MB/s';
-    document.getElementById('peakSpeed').textContent = peakSpeedMax.toFixed(2) + ' MB/s';
-    
-    document.getElementById('cpuAvg').textContent = (cpuAvgSum / count).toFixed(2) + '%';
-    document.getElementById('cpuPeak').textContent = cpuPeakMax.toFixed(2) + '%';
-    document.getElementById('memAvg').textContent = (memAvgSum / count).toFixed(2) + ' MB';
-    document.getElementById('memPeak').textContent = memPeakMax.toFixed(2) + ' MB';
-    
-    document.getElementById('netAvg').textContent = (netAvgSum / count).toFixed(2) + ' Mbps';
-    document.getElementById('netPeak').textContent = netPeakMax.toFixed(2) + ' Mbps';
-    document.getElementById('netTotal').textContent = formatBytes(netTotalSum);
-    
-    document.getElementById('images').textContent = totalImages;
-    document.getElementById('layers').textContent = totalLayers;
-    document.getElementById('manifests').textContent = totalManifests;
-    document.getElementById('files').textContent = totalFiles;
-    
-    document.getElementById('cacheHits').textContent = totalCacheHits;
-    document.getElementById('imagesSkipped').textContent = totalImagesSkipped;
-    document.getElementById('errors').textContent = totalErrors;
-    document.getElementById('retries').textContent = totalRetries;
-    
-    // Update charts
-    updateCharts(speedData, resourceData, networkData);
-    
-    // Display iterations
-    displayIterations(results);
-}
-
-// Update charts
-function updateCharts(speedData, resourceData, networkData) {
-    // Speed chart
-    const speedCtx = document.getElementById('speedChart').getContext('2d');
-    if (speedChart) speedChart.destroy();
-    speedChart = new Chart(speedCtx, {
-        type: 'bar',
-        data: {
-            labels: speedData.map(d => d.x),
-            datasets: [{
-                label: 'Avg Speed (MB/s)',
-                data: speedData.map(d => d.avg),
-                backgroundColor: 'rgba(102, 126, 234, 0.6)'
-            }, {
-                label: 'Peak Speed (MB/s)',
-                data: speedData.map(d => d.peak),
-                backgroundColor: 'rgba(118, 75, 162, 0.6)'
-            }]
-        },
-        options: {
-            responsive: true,
-            maintainAspectRatio: false,
-            scales: {
-                y: { beginAtZero: true }
-            }
-        }
-    });
-    
-    // Resource chart
-    const resourceCtx = document.getElementById('resourceChart').getContext('2d');
-    if (resourceChart) resourceChart.destroy();
-    resourceChart = new Chart(resourceCtx, {
-        type: 'line',
-        data: {
-            labels: resourceData.map(d => d.x),
-            datasets: [{
-                label: 'CPU Avg (%)',
-                data: resourceData.map(d => d.cpu),
-                borderColor: 'rgb(102, 126, 234)',
-                backgroundColor: 'rgba(102, 126, 234, 0.1)',
-                tension: 0.4
-            }, {
-                label: 'Memory Avg (MB)',
-                data: resourceData.map(d => d.mem),
-                borderColor: 'rgb(118, 75, 162)',
-                backgroundColor: 'rgba(118, 75, 162, 0.1)',
-                tension: 0.4,
-                yAxisID: 'y1'
-            }]
-        },
-        options: {
-            responsive: true,
-            maintainAspectRatio: false,
-            scales: {
-                y: { beginAtZero: true },
-                y1: { beginAtZero: true, position: 'right' }
-            }
-        }
-    });
-    
-    // Network chart
-    const networkCtx = document.getElementById('networkChart').getContext('2d');
-    if (networkChart) networkChart.destroy();
-    networkChart = new Chart(networkCtx, {
-        type: 'bar',
-        data: {
-            labels: networkData.map(d => d.x),
-            datasets: [{
-                label: 'Avg Bandwidth (Mbps)',
-                data: networkData.map(d => d.avg),
-                backgroundColor: 'rgba(72, 187, 120, 0.6)'
-            }, {
-                label: 'Peak Bandwidth (Mbps)',
-                data: networkData.map(d => d.peak),
-                backgroundColor: 'rgba(245, 101, 101, 0.6)'
-            }]
-        },
-        options: {
-            responsive: true,
-            maintainAspectRatio: false,
-            scales: {
-                y: { beginAtZero: true }
-            }
-        }
-    });
-}
-
-// Display iterations
-function displayIterations(results) {
-    const container = document.getElementById('iterations');
-    container.innerHTML = '<h2>Iterations</h2>';
-    
-    results.forEach(result => {
-        const card = document.createElement('div');
-        card.className = 'iteration-card';
-        
-        const badges = [];
-        badges.push(result.is_clean_run ? '<span class="badge clean">CLEAN</span>' : '<span class="badge cached">CACHED</span>');
-        badges.push('<span class="badge ' + result.version + '">' + result.version.toUpperCase() + '</span>');
-        
-        card.innerHTML = 
-            '<h4>Iteration ' + result.iteration + ' ' + badges.join(' ') + '</h4>' +
-            '<div class="metric-item"><span class="label">Download:</span><span class="value">' + formatDuration(result.download_phase.wall_time_seconds) + '</span></div>' +
-            '<div class="metric-item"><span class="label">Upload:</span><span class="value">' + formatDuration(result.upload_phase.wall_time_seconds) + '</span></div>' +
-            '<div class="metric-item"><span class="label">Downloaded:</span><span class="value">' + formatBytes(result.download_phase.download_metrics?.TotalBytesDownloaded) + '</span></div>' +
-            '<div class="metric-item"><span class="label">Cache Hits:</span><span class="value">' + (result.download_phase.cache_hits || 0) + '</span></div>';
-        
-        container.appendChild(card);
-    });
-}
-
-// Show error
-function showError(message) {
-    const errorDiv = document.getElementById('error');
-    errorDiv.textContent = message;
-    errorDiv.style.display = 'block';
-}
+// verify checks a results file against its sidecars in the backend; data is
+// read from the backend when nil
+func (s *Server) verify(filename string, data []byte) integrity.Result {
+	if data == nil {
+		var err error
+		if data, err = s.backend.Read(filename); err != nil {
+			return integrity.Result{Status: integrity.StatusUnverified, Message: err.Error()}
+		}
+	}
+	checksum, _ := s.backend.Read(path.Join(path.Dir(filename), path.Base(integrity.ChecksumPath(filename))))
+	var signature []byte
+	if len(s.signingKey) > 0 {
+		signature, _ = s.backend.Read(path.Join(path.Dir(filename), path.Base(integrity.SignaturePath(filename))))
+	}
+	result := integrity.VerifyData(data, checksum, signature, s.signingKey)
+	if result.IsTampered() {
+		return result
+	}
 
-// Toggle auto-refresh
-function toggleAutoRefresh() {
-    const btn = document.getElementById('autoRefreshBtn');
-    if (autoRefreshInterval) {
-        clearInterval(autoRefreshInterval);
-        autoRefreshInterval = null;
-        btn.textContent = 'Auto-refresh: OFF';
-        btn.classList.remove('active');
-    } else {
-        // Use shorter interval for live updates (2 seconds)
-        autoRefreshInterval = setInterval(() => {
-            const select = document.getElementById('resultSelect');
-            const filename = select.value || 'latest';
-            loadResultData(filename, true); // Use live endpoint
-            loadRegistryMetrics(); // Also refresh registry metrics
-        }, 2000);
-        btn.textContent = 'Auto-refresh: ON';
-        btn.classList.add('active');
-    }
+	// The samples sidecar is covered through the sha256 in the results header
+	var header runner.ResultsHeader
+	if json.Unmarshal(data, &header) == nil && header.SamplesSHA256 != "" {
+		samples, err := s.backend.Read(path.Join(path.Dir(filename), header.SamplesFile))
+		if err == nil {
+			if err := header.VerifySamples(samples); err != nil {
+				return integrity.Result{Status: integrity.StatusModified, Message: err.Error()}
+			}
+		}
+	}
+	return result
 }
-
-// Initialize
-document.addEventListener('DOMContentLoaded', () => {
-    loadResultsList();
-    
-    // Auto-enable auto-refresh on page load for live monitoring
-    setTimeout(() => {
-        if (autoRefreshInterval === null) {
-            toggleAutoRefresh();
-        }
-    }, 1000);
-    
-    document.getElementById('refreshBtn').addEventListener('click', () => {
-        const select = document.getElementById('resultSelect');
-        loadResultData(select.value || 'latest', true);
-    });
-    
-    document.getElementById('autoRefreshBtn').addEventListener('click', toggleAutoRefresh);
-    
-    document.getElementById('resultSelect').addEventListener('change', (e) => {
-        loadResultData(e.target.value || 'latest');
-    });
-});
-`
-
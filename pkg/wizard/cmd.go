@@ -0,0 +1,54 @@
+package wizard
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// NewInitCommand creates a cobra command that interactively writes a scenario file
+func NewInitCommand() *cobra.Command {
+	var outputDir string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:           "init",
+		Short:         "Interactively create a scenario file",
+		Long:          "Asks for registry, OpenShift version, operators (searching the catalog with opm when available), iterations and workflows, then writes ready-to-run scenario files and the matching imageset configuration.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Printf("╔════════════════════════════════════════════════════════════════╗\n")
+			fmt.Printf("║       oc-mirror-test Scenario Wizard                          ║\n")
+			fmt.Printf("╚════════════════════════════════════════════════════════════════╝\n")
+			fmt.Printf("Press Enter to accept the [default] value.\n")
+
+			answers, err := NewWizard(NewPrompter(os.Stdin, os.Stdout)).Run()
+			if err != nil {
+				return err
+			}
+
+			files, err := Write(answers, outputDir, force)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("\n✅ Wrote:\n")
+			for _, file := range files {
+				fmt.Printf("  %s\n", file)
+			}
+			fmt.Printf("\nRun with:\n")
+			for _, file := range files[:len(files)-1] {
+				fmt.Printf("  ./bin/oc-mirror-test run --scenario %s\n", file)
+			}
+			fmt.Printf("\nAdd thresholds to the scenario files to gate runs on expected timings.\n")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputDir, "output-dir", "o", "scenarios", "Directory to write the scenario and imageset files to")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite existing files")
+
+	return cmd
+}
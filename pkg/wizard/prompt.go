@@ -0,0 +1,145 @@
+package wizard
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Prompter reads answers line by line, so the wizard can also be driven from a pipe
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewPrompter creates a prompter reading from in and writing questions to out
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// Printf writes to the prompter output
+func (p *Prompter) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(p.out, format, args...)
+}
+
+// Ask asks a question and returns the answer, or def when the answer is empty
+func (p *Prompter) Ask(question, def string) (string, error) {
+	if def != "" {
+		p.Printf("%s [%s]: ", question, def)
+	} else {
+		p.Printf("%s: ", question)
+	}
+
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			return "", fmt.Errorf("input closed while waiting for %q", question)
+		}
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	answer := strings.TrimSpace(line)
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// AskRequired asks until a non-empty answer is given
+func (p *Prompter) AskRequired(question, def string) (string, error) {
+	for {
+		answer, err := p.Ask(question, def)
+		if err != nil {
+			return "", err
+		}
+		if answer != "" {
+			return answer, nil
+		}
+		p.Printf("  A value is required.\n")
+	}
+}
+
+// AskInt asks for an integer of at least min
+func (p *Prompter) AskInt(question string, def, min int) (int, error) {
+	for {
+		answer, err := p.Ask(question, strconv.Itoa(def))
+		if err != nil {
+			return 0, err
+		}
+		value, err := strconv.Atoi(answer)
+		if err == nil && value >= min {
+			return value, nil
+		}
+		p.Printf("  Enter a number of at least %d.\n", min)
+	}
+}
+
+// AskBool asks a yes/no question
+func (p *Prompter) AskBool(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		answer, err := p.Ask(question+" ("+hint+")", "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		p.Printf("  Answer y or n.\n")
+	}
+}
+
+// AskChoices asks for a comma-separated subset of the options
+func (p *Prompter) AskChoices(question string, options []string, def []string) ([]string, error) {
+	for {
+		answer, err := p.Ask(fmt.Sprintf("%s (%s)", question, strings.Join(options, ", ")), strings.Join(def, ","))
+		if err != nil {
+			return nil, err
+		}
+		choices, invalid := splitChoices(answer, options)
+		if invalid == "" && len(choices) > 0 {
+			return choices, nil
+		}
+		if invalid != "" {
+			p.Printf("  Unknown choice %q.\n", invalid)
+		} else {
+			p.Printf("  Select at least one option.\n")
+		}
+	}
+}
+
+// splitChoices parses a comma-separated answer, returning the first value not in options
+func splitChoices(answer string, options []string) ([]string, string) {
+	var choices []string
+	for _, choice := range strings.Split(answer, ",") {
+		choice = strings.TrimSpace(choice)
+		if choice == "" {
+			continue
+		}
+		valid := false
+		for _, option := range options {
+			if choice == option {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, choice
+		}
+		choices = append(choices, choice)
+	}
+	return choices, ""
+}
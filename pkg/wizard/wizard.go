@@ -0,0 +1,320 @@
+package wizard
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/telco-core/ngc-495/internal/config"
+	"github.com/telco-core/ngc-495/pkg/catalog"
+	"github.com/telco-core/ngc-495/pkg/scenario"
+)
+
+// maxSearchResults limits how many catalog matches are listed per search
+const maxSearchResults = 20
+
+// Answers holds everything collected by the wizard
+type Answers struct {
+	Name       string
+	Registry   string
+	SkipTLS    bool
+	OCPVersion string
+	Iterations int
+	Workflows  []string
+	Content    *config.ContentSpec
+}
+
+// Wizard asks for the settings of a new scenario
+type Wizard struct {
+	prompt *Prompter
+	render func(image string) (*catalog.Index, error)
+}
+
+// NewWizard creates a wizard using the given prompter; catalogs are searched with opm
+func NewWizard(prompt *Prompter) *Wizard {
+	return &Wizard{
+		prompt: prompt,
+		render: catalog.Render,
+	}
+}
+
+// Run asks all questions and returns the answers
+func (w *Wizard) Run() (*Answers, error) {
+	p := w.prompt
+	answers := &Answers{}
+	var err error
+
+	p.Printf("\nRegistry\n")
+	if answers.Registry, err = p.AskRequired("  Destination registry (docker://host:port/path or oci:///path)", ""); err != nil {
+		return nil, err
+	}
+	if !strings.Contains(answers.Registry, "://") {
+		answers.Registry = "docker://" + answers.Registry
+	}
+	if strings.HasPrefix(answers.Registry, "docker://") {
+		if answers.SkipTLS, err = p.AskBool("  Skip TLS verification", false); err != nil {
+			return nil, err
+		}
+	}
+
+	p.Printf("\nOpenShift\n")
+	if answers.OCPVersion, err = p.AskRequired("  OpenShift version", "4.19"); err != nil {
+		return nil, err
+	}
+
+	p.Printf("\nOperators\n")
+	if answers.Content, err = w.askContent(answers.OCPVersion); err != nil {
+		return nil, err
+	}
+
+	p.Printf("\nRun\n")
+	if answers.Iterations, err = p.AskInt("  Iterations (first is clean, the rest cached)", 2, 1); err != nil {
+		return nil, err
+	}
+	workflows := []string{scenario.WorkflowStandard, scenario.WorkflowCompareV1V2}
+	if strings.HasPrefix(answers.Registry, "oci://") {
+		workflows = workflows[:1] // v1 cannot write OCI layouts
+	}
+	if answers.Workflows, err = p.AskChoices("  Workflows", workflows, []string{scenario.WorkflowStandard}); err != nil {
+		return nil, err
+	}
+
+	defaultName := "ocp-" + answers.OCPVersion + "-" + strings.Join(answers.Content.Kinds(), "-")
+	if answers.Name, err = p.AskRequired("  Scenario name", defaultName); err != nil {
+		return nil, err
+	}
+
+	return answers, nil
+}
+
+// askContent selects operator packages, searching the catalog with opm when available
+func (w *Wizard) askContent(ocpVersion string) (*config.ContentSpec, error) {
+	p := w.prompt
+
+	image, err := p.AskRequired("  Operator catalog", config.RedHatOperatorCatalog(ocpVersion))
+	if err != nil {
+		return nil, err
+	}
+
+	p.Printf("  Rendering %s with opm (pulls the catalog image, this can take a minute)...\n", image)
+	idx, err := w.render(image)
+	if err != nil {
+		p.Printf("  Warning: catalog search unavailable: %v\n", err)
+		p.Printf("  Enter package names manually.\n")
+		idx = nil
+	} else {
+		p.Printf("  Found %d packages.\n", len(idx.Packages))
+	}
+
+	var packages []config.OperatorPackage
+	for {
+		var pkg *config.OperatorPackage
+		if idx != nil {
+			pkg, err = w.searchPackage(idx)
+		} else {
+			pkg, err = w.manualPackage()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if pkg == nil {
+			break
+		}
+		packages = append(packages, *pkg)
+		p.Printf("  Added %s.\n", pkg.Name)
+	}
+
+	if len(packages) > 0 {
+		return &config.ContentSpec{
+			Catalogs: []config.OperatorCatalog{{Catalog: image, Packages: packages}},
+		}, nil
+	}
+
+	useDefault, err := p.AskBool("  No operators selected. Use the built-in ODF/logging operator set (OpenShift 4.19)", true)
+	if err != nil {
+		return nil, err
+	}
+	if !useDefault {
+		return nil, fmt.Errorf("no content selected")
+	}
+	return config.DefaultContent(), nil
+}
+
+// searchPackage lets the user search the catalog and pick one package; nil when done
+func (w *Wizard) searchPackage(idx *catalog.Index) (*config.OperatorPackage, error) {
+	p := w.prompt
+	for {
+		term, err := p.Ask("  Search operators (empty to finish)", "")
+		if err != nil || term == "" {
+			return nil, err
+		}
+
+		matches := idx.Search(term)
+		if len(matches) == 0 {
+			p.Printf("  No packages match %q.\n", term)
+			continue
+		}
+		if exact := idx.Package(term); exact != nil && len(matches) > 1 {
+			matches = []catalog.Package{*exact}
+		}
+		if len(matches) > maxSearchResults {
+			p.Printf("  %d matches, showing the first %d. Refine the search to narrow down.\n", len(matches), maxSearchResults)
+			matches = matches[:maxSearchResults]
+		}
+		for i, pkg := range matches {
+			p.Printf("    %2d) %s [%s]\n", i+1, pkg.Name, strings.Join(pkg.ChannelNames(), ", "))
+		}
+
+		choice, err := p.Ask("  Select package number (empty to search again)", "")
+		if err != nil {
+			return nil, err
+		}
+		if choice == "" {
+			continue
+		}
+		n, convErr := strconv.Atoi(choice)
+		if convErr != nil || n < 1 || n > len(matches) {
+			p.Printf("  Invalid selection %q.\n", choice)
+			continue
+		}
+		return w.configurePackage(&matches[n-1])
+	}
+}
+
+// configurePackage asks for the channel and optional version pin of a catalog package
+func (w *Wizard) configurePackage(pkg *catalog.Package) (*config.OperatorPackage, error) {
+	p := w.prompt
+
+	channelName := pkg.DefaultChannel
+	for {
+		answer, err := p.Ask("    Channel for "+pkg.Name, pkg.DefaultChannel)
+		if err != nil {
+			return nil, err
+		}
+		if pkg.Channel(answer) != nil {
+			channelName = answer
+			break
+		}
+		p.Printf("    Unknown channel %q (available: %s).\n", answer, strings.Join(pkg.ChannelNames(), ", "))
+	}
+
+	example := ""
+	if channel := pkg.Channel(channelName); channel != nil && len(channel.Bundles) > 0 {
+		example = catalog.BundleVersion(channel.Bundles[len(channel.Bundles)-1])
+	}
+	question := "    Pin version (empty for channel head)"
+	if example != "" {
+		question += ", e.g. " + example
+	}
+	version, err := p.Ask(question, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return newPackage(pkg.Name, channelName, pkg.DefaultChannel, version), nil
+}
+
+// manualPackage asks for a package without catalog data; nil when done
+func (w *Wizard) manualPackage() (*config.OperatorPackage, error) {
+	p := w.prompt
+
+	name, err := p.Ask("  Operator package (empty to finish)", "")
+	if err != nil || name == "" {
+		return nil, err
+	}
+	channel, err := p.Ask("    Channel (empty for the package default)", "")
+	if err != nil {
+		return nil, err
+	}
+	version := ""
+	if channel != "" {
+		if version, err = p.Ask("    Pin version (empty for channel head)", ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return newPackage(name, channel, "", version), nil
+}
+
+// newPackage builds a package entry; a non-default channel is also set as defaultChannel,
+// which oc-mirror requires when the catalog default channel is not mirrored
+func newPackage(name, channel, catalogDefault, version string) *config.OperatorPackage {
+	pkg := &config.OperatorPackage{Name: name}
+	if channel == "" {
+		return pkg
+	}
+	if catalogDefault != "" && channel != catalogDefault {
+		pkg.DefaultChannel = channel
+	}
+	pkg.Channels = []config.OperatorChannel{{Name: channel, MinVersion: version, MaxVersion: version}}
+	return pkg
+}
+
+// Write writes one scenario file per selected workflow and the rendered imageset
+// configuration for review, returning the paths written
+func Write(answers *Answers, dir string, force bool) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	files := make(map[string][]byte)
+	var order []string
+	for _, workflow := range answers.Workflows {
+		name := answers.Name
+		if len(answers.Workflows) > 1 {
+			name += "-" + workflow
+		}
+		sc := &scenario.Scenario{
+			Name:        name,
+			Description: fmt.Sprintf("OpenShift %s %s, %d iteration(s), created by oc-mirror-test init", answers.OCPVersion, strings.Join(answers.Content.Kinds(), "/"), answers.Iterations),
+			Registry:    answers.Registry,
+			Iterations:  answers.Iterations,
+			Workflow:    workflow,
+			SkipTLS:     answers.SkipTLS,
+			Content:     answers.Content,
+		}
+		if err := sc.Validate(); err != nil {
+			return nil, fmt.Errorf("generated scenario is invalid: %w", err)
+		}
+
+		var buf bytes.Buffer
+		buf.WriteString("# Generated by oc-mirror-test init\n")
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(sc); err != nil {
+			return nil, fmt.Errorf("failed to encode scenario: %w", err)
+		}
+		encoder.Close()
+
+		path := filepath.Join(dir, name+".yaml")
+		files[path] = buf.Bytes()
+		order = append(order, path)
+	}
+
+	imagesetPath := filepath.Join(dir, answers.Name+"-imageset.yaml")
+	order = append(order, imagesetPath)
+
+	if !force {
+		for _, path := range order {
+			if _, err := os.Stat(path); err == nil {
+				return nil, fmt.Errorf("%s already exists (use --force to overwrite)", path)
+			}
+		}
+	}
+
+	for _, path := range order[:len(order)-1] {
+		if err := os.WriteFile(path, files[path], 0644); err != nil {
+			return nil, fmt.Errorf("failed to write scenario file: %w", err)
+		}
+	}
+	if err := config.CreateImageSetConfigForContent(imagesetPath, "v2alpha1", answers.Content); err != nil {
+		return nil, fmt.Errorf("failed to write imageset configuration: %w", err)
+	}
+
+	return order, nil
+}